@@ -0,0 +1,45 @@
+package main
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/google/uuid"
+
+	"github.com/cesargomez89/navidrums/internal/logger"
+)
+
+// requestIDHeader carries the ID the access log records for a request back to the client.
+const requestIDHeader = "X-Request-ID"
+
+// accessLogMiddleware logs one structured line per request through log, so it follows
+// LOG_FORMAT. Each request gets a new ID, returned in the X-Request-ID header and stored
+// in the request context for handlers to log.
+func accessLogMiddleware(log *logger.Logger, clientIP clientIPResolver) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			start := time.Now()
+			id := uuid.NewString()
+			w.Header().Set(requestIDHeader, id)
+
+			ww := middleware.NewWrapResponseWriter(w, r.ProtoMajor)
+			defer func() {
+				status := ww.Status()
+				if status == 0 {
+					status = http.StatusOK
+				}
+				log.Info("HTTP request",
+					"request_id", id,
+					"method", r.Method,
+					"path", r.URL.Path,
+					"status", status,
+					"duration", time.Since(start),
+					"client_ip", clientIP.resolve(r),
+				)
+			}()
+
+			next.ServeHTTP(ww, r.WithContext(logger.WithRequestID(r.Context(), id)))
+		})
+	}
+}
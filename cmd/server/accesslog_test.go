@@ -0,0 +1,56 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/cesargomez89/navidrums/internal/logger"
+)
+
+func TestAccessLogMiddleware(t *testing.T) {
+	var buf bytes.Buffer
+	log := &logger.Logger{Logger: slog.New(slog.NewJSONHandler(&buf, nil))}
+
+	var ctxID string
+	handler := accessLogMiddleware(log, clientIPResolver{})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctxID = logger.RequestID(r.Context())
+		w.WriteHeader(http.StatusAccepted)
+	}))
+
+	r := httptest.NewRequest(http.MethodPost, "/htmx/download/track/1", nil)
+	r.RemoteAddr = "203.0.113.5:4000"
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, r)
+
+	id := rec.Header().Get(requestIDHeader)
+	if id == "" {
+		t.Fatalf("%s header missing", requestIDHeader)
+	}
+	if ctxID != id {
+		t.Errorf("context request ID = %q, want %q", ctxID, id)
+	}
+
+	var entry map[string]any
+	if err := json.Unmarshal(buf.Bytes(), &entry); err != nil {
+		t.Fatalf("access log is not a JSON line: %v: %s", err, buf.String())
+	}
+	want := map[string]any{
+		"request_id": id,
+		"method":     http.MethodPost,
+		"path":       "/htmx/download/track/1",
+		"status":     float64(http.StatusAccepted),
+		"client_ip":  "203.0.113.5",
+	}
+	for key, value := range want {
+		if entry[key] != value {
+			t.Errorf("log %s = %v, want %v", key, entry[key], value)
+		}
+	}
+	if _, ok := entry["duration"]; !ok {
+		t.Error("log is missing duration")
+	}
+}
@@ -0,0 +1,59 @@
+package main
+
+import (
+	"net"
+	"net/http"
+	"net/netip"
+	"slices"
+	"strings"
+)
+
+// clientIPResolver finds the client address of a request for rate limiting and the access
+// log. Forwarding headers are only honored on connections from a trusted proxy; with no
+// trusted proxies configured the client is always the direct peer.
+type clientIPResolver struct {
+	trusted []netip.Prefix
+}
+
+func (c clientIPResolver) resolve(r *http.Request) string {
+	peer := r.RemoteAddr
+	if host, _, err := net.SplitHostPort(peer); err == nil {
+		peer = host
+	}
+
+	if !c.isTrusted(peer) {
+		return peer
+	}
+
+	// Each proxy appends the address it received the request from, so walk the chain
+	// from the nearest hop and stop at the first address that is not one of ours.
+	if xff := r.Header.Get("X-Forwarded-For"); xff != "" {
+		hops := strings.Split(xff, ",")
+		for i := len(hops) - 1; i >= 0; i-- {
+			hop := strings.TrimSpace(hops[i])
+			if !c.isTrusted(hop) || i == 0 {
+				return hop
+			}
+		}
+	}
+	if xri := strings.TrimSpace(r.Header.Get("X-Real-IP")); xri != "" {
+		return xri
+	}
+	return peer
+}
+
+func (c clientIPResolver) isTrusted(ip string) bool {
+	return containsIP(c.trusted, ip)
+}
+
+// containsIP reports whether ip parses and falls in one of prefixes.
+func containsIP(prefixes []netip.Prefix, ip string) bool {
+	addr, err := netip.ParseAddr(ip)
+	if err != nil {
+		return false
+	}
+	addr = addr.Unmap()
+	return slices.ContainsFunc(prefixes, func(p netip.Prefix) bool {
+		return p.Contains(addr)
+	})
+}
@@ -0,0 +1,127 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestClientIPResolver(t *testing.T) {
+	trusted := []netip.Prefix{netip.MustParsePrefix("10.0.0.0/8")}
+
+	tests := []struct {
+		name       string
+		trusted    []netip.Prefix
+		remoteAddr string
+		xff        string
+		xri        string
+		want       string
+	}{
+		{"no proxies: forwarded header ignored", nil, "203.0.113.5:4000", "198.51.100.7, 10.0.0.2", "", "203.0.113.5"},
+		{"no proxies: real IP header ignored", nil, "203.0.113.5:4000", "", "198.51.100.7", "203.0.113.5"},
+		{"no proxies: peer without port", nil, "203.0.113.5:4000", "", "", "203.0.113.5"},
+		{"spoofed header from untrusted peer", trusted, "203.0.113.5:4000", "198.51.100.7", "198.51.100.8", "203.0.113.5"},
+		{"trusted proxy", trusted, "10.0.0.1:4000", "198.51.100.7", "", "198.51.100.7"},
+		{"spoofed hop before trusted proxy", trusted, "10.0.0.1:4000", "1.2.3.4, 198.51.100.7", "", "198.51.100.7"},
+		{"chain of trusted proxies", trusted, "10.0.0.1:4000", "198.51.100.7, 10.0.0.9", "", "198.51.100.7"},
+		{"trusted proxy with real IP header", trusted, "10.0.0.1:4000", "", "198.51.100.7", "198.51.100.7"},
+		{"trusted proxy without headers", trusted, "10.0.0.1:4000", "", "", "10.0.0.1"},
+		{"IPv6 peer", trusted, "[2001:db8::1]:4000", "198.51.100.7", "", "2001:db8::1"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, "/", nil)
+			r.RemoteAddr = tt.remoteAddr
+			if tt.xff != "" {
+				r.Header.Set("X-Forwarded-For", tt.xff)
+			}
+			if tt.xri != "" {
+				r.Header.Set("X-Real-IP", tt.xri)
+			}
+			if got := (clientIPResolver{trusted: tt.trusted}).resolve(r); got != tt.want {
+				t.Errorf("resolve() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRateLimitMiddleware_Exempt(t *testing.T) {
+	exempt := []netip.Prefix{netip.MustParsePrefix("192.168.0.0/16"), netip.MustParsePrefix("::1/128")}
+	handler := rateLimitMiddleware(1, time.Hour, 1, clientIPResolver{}, exempt)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	tests := []struct {
+		name       string
+		remoteAddr string
+		xff        string
+		want       []int
+	}{
+		{"limited client", "203.0.113.5:4000", "", []int{http.StatusOK, http.StatusTooManyRequests}},
+		{"exempt LAN client", "192.168.1.20:4000", "", []int{http.StatusOK, http.StatusOK, http.StatusOK}},
+		{"exempt loopback", "[::1]:4000", "", []int{http.StatusOK, http.StatusOK}},
+		{"forwarded exempt address", "203.0.113.6:4000", "192.168.1.20", []int{http.StatusOK, http.StatusTooManyRequests}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for i, want := range tt.want {
+				r := httptest.NewRequest(http.MethodGet, "/", nil)
+				r.RemoteAddr = tt.remoteAddr
+				if tt.xff != "" {
+					r.Header.Set("X-Forwarded-For", tt.xff)
+				}
+				rec := httptest.NewRecorder()
+				handler.ServeHTTP(rec, r)
+				if rec.Code != want {
+					t.Errorf("request %d: status = %d, want %d", i+1, rec.Code, want)
+				}
+			}
+		})
+	}
+}
+
+func TestRateLimitMiddleware_Headers(t *testing.T) {
+	handler := rateLimitMiddleware(1, time.Hour, 3, clientIPResolver{}, nil)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	tests := []struct {
+		wantCode      int
+		wantRemaining string
+	}{
+		{http.StatusOK, "2"},
+		{http.StatusOK, "1"},
+		{http.StatusOK, "0"},
+		{http.StatusTooManyRequests, "0"},
+	}
+	for i, tt := range tests {
+		r := httptest.NewRequest(http.MethodGet, "/", nil)
+		r.RemoteAddr = "203.0.113.5:4000"
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, r)
+
+		if rec.Code != tt.wantCode {
+			t.Errorf("request %d: status = %d, want %d", i+1, rec.Code, tt.wantCode)
+		}
+		if got := rec.Header().Get("X-RateLimit-Limit"); got != "3" {
+			t.Errorf("request %d: X-RateLimit-Limit = %q, want 3", i+1, got)
+		}
+		if got := rec.Header().Get("X-RateLimit-Remaining"); got != tt.wantRemaining {
+			t.Errorf("request %d: X-RateLimit-Remaining = %q, want %q", i+1, got, tt.wantRemaining)
+		}
+		retryAfter := rec.Header().Get("Retry-After")
+		if tt.wantCode == http.StatusTooManyRequests {
+			// One request an hour: the next token is close to an hour away.
+			if secs, err := strconv.Atoi(retryAfter); err != nil || secs < 3500 || secs > 3600 {
+				t.Errorf("request %d: Retry-After = %q, want about 3600", i+1, retryAfter)
+			}
+		} else if retryAfter != "" {
+			t.Errorf("request %d: Retry-After = %q on an allowed request", i+1, retryAfter)
+		}
+	}
+}
@@ -2,10 +2,12 @@ package main
 
 import (
 	"context"
-	"crypto/subtle"
+	"math"
 	"net/http"
+	"net/netip"
 	"os"
 	"os/signal"
+	"strconv"
 	"strings"
 	"sync"
 	"syscall"
@@ -22,6 +24,8 @@ import (
 	"github.com/cesargomez89/navidrums/internal/downloader"
 	httpapp "github.com/cesargomez89/navidrums/internal/http"
 	"github.com/cesargomez89/navidrums/internal/logger"
+	"github.com/cesargomez89/navidrums/internal/metrics"
+	"github.com/cesargomez89/navidrums/internal/storage"
 	"github.com/cesargomez89/navidrums/internal/store"
 	"github.com/cesargomez89/navidrums/web"
 )
@@ -40,6 +44,13 @@ func main() {
 		appLogger.Error("Configuration error", "error", err)
 		os.Exit(1)
 	}
+	if cfg.ConfigFile != "" {
+		appLogger.Info("Loaded config file", "path", cfg.ConfigFile)
+	}
+	if err := storage.EnsureWritableDirs(cfg.DownloadsDirs()); err != nil {
+		appLogger.Error("Configuration error", "error", err)
+		os.Exit(1)
+	}
 
 	// Initialize DB
 	db, err := store.NewSQLiteDB(cfg.DBPath)
@@ -57,31 +68,71 @@ func main() {
 	settingsRepo := store.NewSettingsRepo(db)
 
 	// Initialize Provider Manager (no system default — providers configured via UI)
-	providerManager := catalog.NewProviderManager(db, settingsRepo, cfg.CacheTTL, appLogger)
+	providerManager := catalog.NewProviderManager(db, settingsRepo, cfg.CacheTTL, cfg.CoverArtSize, appLogger).
+		WithTimeouts(catalog.Timeouts{Metadata: cfg.ProviderTimeout, Stream: cfg.StreamTimeout, StreamIdle: cfg.StreamIdleTimeout})
 
 	// Initialize Worker
 	w := downloader.NewWorker(db, settingsRepo, providerManager, cfg, appLogger)
 	w.Start()
-	defer w.Stop()
 
 	// Initialize Services
 	jobService := app.NewJobService(db, appLogger)
-	downloadsService := app.NewDownloadsService(db, appLogger)
+	jobService.Running = w.Running
+	jobService.Stats = w.Stats
+	downloadsService := app.NewDownloadsService(db, cfg, appLogger)
 	providersRepo := store.NewProvidersRepo(db)
 
-	// Initialize Router
-	r := chi.NewRouter()
-	r.Use(middleware.Logger)
-	r.Use(middleware.Recoverer)
+	// Metrics (unauthenticated; served on METRICS_ADDR when set, otherwise on the main listener)
+	var metricsSrv *http.Server
+	if cfg.MetricsEnabled {
+		if err := metrics.RegisterJobCounter(db); err != nil {
+			appLogger.Error("Failed to register job metrics", "error", err)
+		}
+	}
 
-	// Basic Auth Middleware (skip if SKIP_AUTH is set)
-	if cfg.Password != "" && !cfg.SkipAuth {
-		r.Use(basicAuthMiddleware(cfg.Username, cfg.Password))
+	// Forwarding headers are honored only from these proxies when resolving client
+	// addresses for access logs and rate limiting (checked by Validate).
+	var trusted []netip.Prefix
+	if cfg.TrustedProxies != "" {
+		trusted, _ = config.ParseCIDRs(cfg.TrustedProxies)
+	}
+	clientIP := clientIPResolver{trusted: trusted}
+
+	// Initialize Router
+	root := chi.NewRouter()
+	root.Use(accessLogMiddleware(appLogger.WithComponent("http"), clientIP))
+	root.Use(middleware.Recoverer)
+
+	if cfg.MetricsEnabled {
+		if cfg.MetricsAddr == "" {
+			root.Handle("/metrics", metrics.Handler())
+		} else {
+			metricsMux := http.NewServeMux()
+			metricsMux.Handle("/metrics", metrics.Handler())
+			metricsSrv = &http.Server{
+				Addr:              cfg.MetricsAddr,
+				Handler:           metricsMux,
+				ReadHeaderTimeout: 10 * time.Second,
+			}
+			go func() {
+				appLogger.Info("Metrics listening", "addr", metricsSrv.Addr)
+				if err := metricsSrv.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+					appLogger.Error("Metrics server error", "error", err)
+				}
+			}()
+		}
 	}
 
+	r := root.With()
+
 	// Rate Limiting Middleware (skip if DISABLE_RATE_LIMIT is set, useful when behind Cloudflare)
 	if !cfg.DisableRateLimit {
-		r.Use(rateLimitMiddleware(cfg.RateLimitRequests, cfg.RateLimitWindow, cfg.RateLimitBurst))
+		// The list was checked by Validate.
+		var exempt []netip.Prefix
+		if cfg.RateLimitExemptCIDRs != "" {
+			exempt, _ = config.ParseCIDRs(cfg.RateLimitExemptCIDRs)
+		}
+		r = r.With(rateLimitMiddleware(cfg.RateLimitRequests, cfg.RateLimitWindow, cfg.RateLimitBurst, clientIP, exempt))
 	}
 
 	// Serve Static Files from embedded filesystem
@@ -115,18 +166,25 @@ func main() {
 		http.Redirect(w, r, "/static/favicon.ico", http.StatusMovedPermanently)
 	})
 
-	// Routes
 	h := httpapp.NewHandler(jobService, downloadsService, providerManager, settingsRepo, providersRepo, cfg)
+	h.GenreMap = app.NewGenreMapService(settingsRepo, w.MusicBrainz)
+
+	// CSRF: mutating requests with a session cookie must echo the token cookie, which
+	// app.js adds to HTMX requests, fetch calls and forms.
+	r = r.With(httpapp.CSRFProtect)
+
+	// Auth: a session cookie from /login or Basic auth (skip if SKIP_AUTH is set). Static
+	// files and the login routes are registered outside it so the login page can render.
+	if cfg.Password != "" && !cfg.SkipAuth {
+		h.RegisterAuthRoutes(r)
+		r = r.With(h.RequireAuth)
+	}
+
+	// Routes
 	h.RegisterRoutes(r)
 
 	// Start Server
-	srv := &http.Server{
-		Addr:         ":" + cfg.Port,
-		Handler:      r,
-		ReadTimeout:  30 * time.Second,
-		WriteTimeout: 30 * time.Second,
-		IdleTimeout:  60 * time.Second,
-	}
+	srv := newHTTPServer(cfg, root)
 
 	go func() {
 		appLogger.Info("Server listening", "addr", srv.Addr)
@@ -147,22 +205,16 @@ func main() {
 	if err := srv.Shutdown(ctx); err != nil {
 		appLogger.Error("Server forced to shutdown", "error", err)
 	}
+	if metricsSrv != nil {
+		if err := metricsSrv.Shutdown(ctx); err != nil {
+			appLogger.Error("Metrics server forced to shutdown", "error", err)
+		}
+	}
 
-	appLogger.Info("Server exiting")
-}
+	// Let running downloads finish before the deferred DB close.
+	w.Shutdown(cfg.ShutdownDrainTimeout)
 
-func basicAuthMiddleware(username, password string) func(http.Handler) http.Handler {
-	return func(next http.Handler) http.Handler {
-		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-			user, pass, ok := r.BasicAuth()
-			if !ok || subtle.ConstantTimeCompare([]byte(user), []byte(username)) != 1 || subtle.ConstantTimeCompare([]byte(pass), []byte(password)) != 1 {
-				w.Header().Set("WWW-Authenticate", `Basic realm="Navidrums"`)
-				http.Error(w, "Unauthorized", http.StatusUnauthorized)
-				return
-			}
-			next.ServeHTTP(w, r)
-		})
-	}
+	appLogger.Info("Server exiting")
 }
 
 type ipLimiter struct {
@@ -170,11 +222,30 @@ type ipLimiter struct {
 	lastSeen time.Time
 }
 
-func (i *ipLimiter) Allow() bool {
-	return i.limiter.Allow()
+// Tokens returns the number of requests the client can make right now.
+func (i *ipLimiter) Tokens() float64 {
+	return i.limiter.Tokens()
 }
 
-func rateLimitMiddleware(requestsPerWindow int, window time.Duration, burst int) func(http.Handler) http.Handler {
+// Reserve takes a token for a request if one is available. Otherwise it takes nothing and
+// returns how long until one is.
+func (i *ipLimiter) Reserve() (bool, time.Duration) {
+	now := time.Now()
+	r := i.limiter.ReserveN(now, 1)
+	if !r.OK() {
+		return false, 0
+	}
+	if delay := r.DelayFrom(now); delay > 0 {
+		r.CancelAt(now)
+		return false, delay
+	}
+	return true, 0
+}
+
+// rateLimitMiddleware limits each client address to requestsPerWindow per window, except
+// addresses in exempt. Limited responses carry X-RateLimit-Limit, the burst a client can
+// make at once, and X-RateLimit-Remaining; a refused request also gets Retry-After.
+func rateLimitMiddleware(requestsPerWindow int, window time.Duration, burst int, clientIP clientIPResolver, exempt []netip.Prefix) func(http.Handler) http.Handler {
 	limiters := &sync.Map{}
 	cleanupInterval := 5 * time.Minute
 
@@ -195,7 +266,11 @@ func rateLimitMiddleware(requestsPerWindow int, window time.Duration, burst int)
 
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-			ip := getIP(r)
+			ip := clientIP.resolve(r)
+			if containsIP(exempt, ip) {
+				next.ServeHTTP(w, r)
+				return
+			}
 			l, exists := limiters.Load(ip)
 			if !exists {
 				l = &ipLimiter{
@@ -207,7 +282,13 @@ func rateLimitMiddleware(requestsPerWindow int, window time.Duration, burst int)
 			limiter := l.(*ipLimiter)
 			limiter.lastSeen = time.Now()
 
-			if !limiter.Allow() {
+			allowed, retryAfter := limiter.Reserve()
+			w.Header().Set("X-RateLimit-Limit", strconv.Itoa(burst))
+			w.Header().Set("X-RateLimit-Remaining", strconv.Itoa(max(0, int(limiter.Tokens()))))
+			if !allowed {
+				if retryAfter > 0 {
+					w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(retryAfter.Seconds()))))
+				}
 				http.Error(w, "Too Many Requests", http.StatusTooManyRequests)
 				return
 			}
@@ -215,16 +296,3 @@ func rateLimitMiddleware(requestsPerWindow int, window time.Duration, burst int)
 		})
 	}
 }
-
-func getIP(r *http.Request) string {
-	xff := r.Header.Get("X-Forwarded-For")
-	if xff != "" {
-		ips := strings.Split(xff, ",")
-		return strings.TrimSpace(ips[0])
-	}
-	xri := r.Header.Get("X-Real-IP")
-	if xri != "" {
-		return xri
-	}
-	return r.RemoteAddr
-}
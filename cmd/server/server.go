@@ -0,0 +1,19 @@
+package main
+
+import (
+	"net/http"
+
+	"github.com/cesargomez89/navidrums/internal/config"
+)
+
+// newHTTPServer builds the HTTP server for handler with the configured timeouts. Routes
+// that stream long responses lift the write timeout themselves.
+func newHTTPServer(cfg *config.Config, handler http.Handler) *http.Server {
+	return &http.Server{
+		Addr:         ":" + cfg.Port,
+		Handler:      handler,
+		ReadTimeout:  cfg.HTTPReadTimeout,
+		WriteTimeout: cfg.HTTPWriteTimeout,
+		IdleTimeout:  cfg.HTTPIdleTimeout,
+	}
+}
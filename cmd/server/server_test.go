@@ -0,0 +1,31 @@
+package main
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/cesargomez89/navidrums/internal/config"
+)
+
+func TestNewHTTPServer(t *testing.T) {
+	cfg := &config.Config{
+		Port:             "9090",
+		HTTPReadTimeout:  5 * time.Second,
+		HTTPWriteTimeout: 2 * time.Minute,
+		HTTPIdleTimeout:  90 * time.Second,
+	}
+	handler := http.NewServeMux()
+
+	srv := newHTTPServer(cfg, handler)
+
+	if srv.Addr != ":9090" {
+		t.Errorf("Addr = %q, want %q", srv.Addr, ":9090")
+	}
+	if srv.Handler != handler {
+		t.Error("Handler is not the one passed in")
+	}
+	if srv.ReadTimeout != 5*time.Second || srv.WriteTimeout != 2*time.Minute || srv.IdleTimeout != 90*time.Second {
+		t.Errorf("timeouts = %v/%v/%v, want 5s/2m0s/1m30s", srv.ReadTimeout, srv.WriteTimeout, srv.IdleTimeout)
+	}
+}
@@ -0,0 +1,58 @@
+package app
+
+import (
+	"fmt"
+
+	"github.com/cesargomez89/navidrums/internal/domain"
+)
+
+// ApplyAlbumArtistOverride credits track to albumArtist, the album artist set for its whole
+// album, so compilations and soundtracks group under one artist however the provider
+// credited each track. The album artist's sort name and IDs are dropped when they belonged
+// to another artist. An empty albumArtist leaves the track alone.
+func ApplyAlbumArtistOverride(track *domain.Track, albumArtist string) {
+	if albumArtist == "" {
+		return
+	}
+	if track.AlbumArtist != albumArtist {
+		track.AlbumArtistSort = ""
+		track.AlbumArtistIDs = nil
+	}
+	track.AlbumArtist = albumArtist
+	track.AlbumArtists = []string{albumArtist}
+	track.PathArtist = albumArtist
+}
+
+// GetAlbumArtistOverride returns the album artist set for an album, or "" when none is.
+func (s *DownloadsService) GetAlbumArtistOverride(albumID string) (string, error) {
+	return s.Repo.GetAlbumArtistOverride(albumID)
+}
+
+// SetAlbumArtistOverride sets the album artist of every track of an album, downloaded now
+// or later, and queues a sync-file job for each downloaded track so its tags and folder
+// follow. It returns the number of jobs queued. An empty albumArtist removes the override,
+// leaving tracks already synced with it as they are.
+func (s *DownloadsService) SetAlbumArtistOverride(albumID, albumArtist string) (int, error) {
+	if err := s.Repo.SetAlbumArtistOverride(albumID, albumArtist); err != nil {
+		return 0, fmt.Errorf("failed to save album artist override: %w", err)
+	}
+	if albumArtist == "" {
+		return 0, nil
+	}
+
+	tracks, err := s.Repo.ListCompletedTracksByAlbumID(albumID)
+	if err != nil {
+		return 0, fmt.Errorf("failed to list album tracks: %w", err)
+	}
+	count := 0
+	for _, track := range tracks {
+		if existing, _ := s.Repo.GetActiveJobBySourceID(track.ProviderID, domain.JobTypeSyncFile); existing != nil {
+			continue
+		}
+		if err := s.EnqueueSyncFileJob(track.ProviderID); err != nil {
+			return count, fmt.Errorf("failed to enqueue sync job: %w", err)
+		}
+		count++
+	}
+	return count, nil
+}
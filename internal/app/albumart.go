@@ -2,21 +2,35 @@ package app
 
 import (
 	"bytes"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
 	"net/url"
 	"path/filepath"
+	"slices"
+	"strings"
 
 	"github.com/cesargomez89/navidrums/internal/config"
 	"github.com/cesargomez89/navidrums/internal/constants"
 	"github.com/cesargomez89/navidrums/internal/domain"
+	"github.com/cesargomez89/navidrums/internal/imaging"
 	"github.com/cesargomez89/navidrums/internal/storage"
 )
 
+// Image download errors, matched with errors.Is. A URL that answers with something other
+// than an image, such as an HTML error page, or with more than MaxImageBytes, is refused
+// rather than embedded or saved as a cover.
+var (
+	ErrNotImage      = errors.New("response is not an image")
+	ErrImageTooLarge = errors.New("image is too large")
+)
+
 type AlbumArtService interface {
-	DownloadAndSaveAlbumArt(album *domain.Album, imageURL string) error
-	DownloadAndSavePlaylistImage(pl *domain.Playlist, imageURL string) error
+	DownloadAndSaveAlbumArt(album *domain.Album, provider, imageURL string) error
+	SaveAlbumArt(album *domain.Album, provider string, imageData []byte) error
+	DownloadAndSavePlaylistImage(pl *domain.Playlist, provider, imageURL string) error
+	DownloadAndSaveArtistImage(artist *domain.Artist, provider string) error
 	DownloadImage(url string) ([]byte, error)
 }
 
@@ -30,7 +44,7 @@ func NewAlbumArtService(cfg *config.Config) AlbumArtService {
 	}
 }
 
-func (s *albumArtService) DownloadAndSaveAlbumArt(album *domain.Album, imageURL string) error {
+func (s *albumArtService) DownloadAndSaveAlbumArt(album *domain.Album, provider, imageURL string) error {
 	if imageURL == "" {
 		return nil
 	}
@@ -39,7 +53,13 @@ func (s *albumArtService) DownloadAndSaveAlbumArt(album *domain.Album, imageURL
 	if err != nil {
 		return fmt.Errorf("failed to download album art: %w", err)
 	}
+	return s.SaveAlbumArt(album, provider, imageData)
+}
 
+// SaveAlbumArt writes already downloaded album art as the cover of the album's folder,
+// creating the folder. Tracks at different qualities can be mapped to different base
+// directories by DOWNLOADS_DIR_MAP, so the cover goes to the folder of each.
+func (s *albumArtService) SaveAlbumArt(album *domain.Album, provider string, imageData []byte) error {
 	// Generate album directory using the same template as tracks
 	// Use first track's metadata if available, otherwise use album metadata with defaults
 	artist := album.Artist
@@ -54,43 +74,147 @@ func (s *albumArtService) DownloadAndSaveAlbumArt(album *domain.Album, imageURL
 		}
 	}
 
-	// Build template data with sensible defaults for disc/track
-	templateData := storage.BuildPathTemplateData(
-		artist,
-		year,
-		album.Title,
-		1,       // Default to disc 1
-		1,       // Default to track 1 (for folder creation purposes)
-		"cover", // Placeholder title (won't be used since we just want the folder)
-	)
+	if len(imageData) > 0 && s.config.CoverArtForceJPEG {
+		var err error
+		if imageData, err = imaging.ToJPEG(imageData, s.config.CoverArtJPEGQuality); err != nil {
+			return fmt.Errorf("failed to convert album art: %w", err)
+		}
+	}
+
+	saved := make(map[string]bool)
+	for _, quality := range albumTrackQualities(album) {
+		// Build template data with sensible defaults for disc/track
+		templateData := storage.BuildPathTemplateData(
+			artist,
+			year,
+			album.Title,
+			1,       // Default to disc 1
+			1,       // Default to track 1 (for folder creation purposes)
+			"cover", // Placeholder title (won't be used since we just want the folder)
+		).WithDetails(storage.PathDetails{
+			Artist:  artist,
+			Genre:   album.Genre,
+			Label:   album.Label,
+			Quality: quality,
+			// TotalDiscs is left out so a disc subfolder never takes the album's cover.
+		})
 
-	// Get the full path and extract just the directory portion
-	fullPathNoExt, err := storage.BuildPath(s.config.SubdirTemplate, templateData)
-	if err != nil {
-		return fmt.Errorf("failed to build album path from template: %w", err)
+		// Get the full path and extract just the directory portion
+		fullPathNoExt, err := storage.BuildPath(s.config.SubdirTemplate, templateData)
+		if err != nil {
+			return fmt.Errorf("failed to build album path from template: %w", err)
+		}
+		albumDir := filepath.Dir(filepath.Join(s.config.DownloadsDirFor(provider, quality), fullPathNoExt))
+		if saved[albumDir] {
+			continue
+		}
+		saved[albumDir] = true
+		if err := s.saveCover(albumDir, imageData); err != nil {
+			return err
+		}
 	}
+	return nil
+}
 
-	fullPathNoExt = filepath.Join(s.config.DownloadsDir, fullPathNoExt)
-	albumDir := filepath.Dir(fullPathNoExt)
+// albumTrackQualities lists the distinct qualities of the album's tracks, which decide
+// their folders, or the album's own when it has no tracks.
+func albumTrackQualities(album *domain.Album) []string {
+	var qualities []string
+	for _, t := range album.Tracks {
+		q := coalesceString(t.AudioQuality, album.AudioQuality)
+		if !slices.Contains(qualities, q) {
+			qualities = append(qualities, q)
+		}
+	}
+	if len(qualities) == 0 {
+		qualities = []string{album.AudioQuality}
+	}
+	return qualities
+}
 
+func (s *albumArtService) saveCover(albumDir string, imageData []byte) error {
 	if err := storage.EnsureDir(albumDir); err != nil {
 		return fmt.Errorf("failed to create album directory: %w", err)
 	}
-
-	imagePath := filepath.Join(albumDir, "cover.jpg")
-	if len(imageData) > 0 {
-		if err := storage.EnsureDir(filepath.Dir(imagePath)); err != nil {
-			return fmt.Errorf("failed to create directory: %w", err)
+	if len(imageData) == 0 {
+		return nil
+	}
+	imagePath := storage.CoverPath(albumDir, imageData)
+	if err := storage.EnsureDir(filepath.Dir(imagePath)); err != nil {
+		return fmt.Errorf("failed to create directory: %w", err)
+	}
+	if err := storage.WriteFile(imagePath, imageData); err != nil {
+		return fmt.Errorf("failed to save album art: %w", err)
+	}
+	if s.config.SaveFolderArt {
+		if err := storage.WriteFile(storage.FolderArtPath(albumDir, imageData), imageData); err != nil {
+			return fmt.Errorf("failed to save folder art: %w", err)
 		}
-		if err := storage.WriteFile(imagePath, imageData); err != nil {
-			return fmt.Errorf("failed to save album art: %w", err)
+	}
+	return nil
+}
+
+// downloadRoots returns the distinct base directories files from provider at the given
+// qualities are saved under, or DOWNLOADS_DIR when there are none.
+func downloadRoots(cfg *config.Config, provider string, qualities []string) []string {
+	var roots []string
+	for _, q := range qualities {
+		if root := cfg.DownloadsDirFor(provider, q); !slices.Contains(roots, root) {
+			roots = append(roots, root)
 		}
 	}
+	if len(roots) == 0 {
+		roots = []string{cfg.DownloadsDir}
+	}
+	return roots
+}
+
+// DownloadAndSaveArtistImage saves the artist's picture as artist.jpg in their top-level
+// folder, under each base directory their top tracks and albums from provider are saved
+// to, when SAVE_ARTIST_ART is enabled. An existing image is kept and not re-downloaded.
+func (s *albumArtService) DownloadAndSaveArtistImage(artist *domain.Artist, provider string) error {
+	if !s.config.SaveArtistArt || artist == nil || artist.PictureURL == "" {
+		return nil
+	}
+
+	qualities := make([]string, 0, len(artist.TopTracks)+len(artist.Albums))
+	for _, t := range artist.TopTracks {
+		qualities = append(qualities, t.AudioQuality)
+	}
+	for _, a := range artist.Albums {
+		qualities = append(qualities, a.AudioQuality)
+	}
 
+	var imageData []byte
+	for _, root := range downloadRoots(s.config, provider, qualities) {
+		artistDir, err := storage.ArtistDir(root, s.config.SubdirTemplate, artist.Name)
+		if err != nil {
+			return fmt.Errorf("failed to build artist path from template: %w", err)
+		}
+		if artistDir == "" {
+			return nil
+		}
+
+		imagePath := filepath.Join(artistDir, constants.ArtistFileName)
+		if storage.FileExists(imagePath) {
+			continue
+		}
+
+		if imageData == nil {
+			if imageData, err = s.DownloadImage(artist.PictureURL); err != nil {
+				return fmt.Errorf("failed to download artist image: %w", err)
+			}
+		}
+		if _, err := storage.WriteImageIfMissing(imagePath, imageData); err != nil {
+			return fmt.Errorf("failed to save artist image: %w", err)
+		}
+	}
 	return nil
 }
 
-func (s *albumArtService) DownloadAndSavePlaylistImage(pl *domain.Playlist, imageURL string) error {
+// DownloadAndSavePlaylistImage saves the playlist's image in the playlists folder of each
+// base directory its tracks from provider are saved to, next to the playlist files.
+func (s *albumArtService) DownloadAndSavePlaylistImage(pl *domain.Playlist, provider, imageURL string) error {
 	if imageURL == "" {
 		return nil
 	}
@@ -100,16 +224,21 @@ func (s *albumArtService) DownloadAndSavePlaylistImage(pl *domain.Playlist, imag
 		return fmt.Errorf("failed to download playlist image: %w", err)
 	}
 
-	playlistsDir := filepath.Join(s.config.DownloadsDir, "playlists")
-
-	if err := storage.EnsureDir(playlistsDir); err != nil {
-		return fmt.Errorf("failed to create playlists directory: %w", err)
+	qualities := make([]string, 0, len(pl.Tracks))
+	for _, t := range pl.Tracks {
+		qualities = append(qualities, t.AudioQuality)
 	}
+	for _, root := range downloadRoots(s.config, provider, qualities) {
+		playlistsDir := filepath.Join(root, constants.PlaylistsDir)
+		if err := storage.EnsureDir(playlistsDir); err != nil {
+			return fmt.Errorf("failed to create playlists directory: %w", err)
+		}
 
-	imagePath := filepath.Join(playlistsDir, storage.Sanitize(pl.Title)+".jpg")
-	if len(imageData) > 0 {
-		if err := storage.WriteFile(imagePath, imageData); err != nil {
-			return fmt.Errorf("failed to save playlist image: %w", err)
+		imagePath := filepath.Join(playlistsDir, storage.Sanitize(pl.Title)+".jpg")
+		if len(imageData) > 0 {
+			if err := storage.WriteFile(imagePath, imageData); err != nil {
+				return fmt.Errorf("failed to save playlist image: %w", err)
+			}
 		}
 	}
 
@@ -130,7 +259,7 @@ func (s *albumArtService) DownloadImage(urlStr string) ([]byte, error) {
 		return nil, fmt.Errorf("invalid URL scheme: %s (only http/https allowed)", parsedURL.Scheme)
 	}
 
-	client := &http.Client{Timeout: constants.ImageHTTPTimeout}
+	client := &http.Client{Timeout: constants.ImageHTTPTimeout, CheckRedirect: checkImageRedirect}
 	req, err := http.NewRequest("GET", urlStr, nil)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create request: %w", err)
@@ -147,9 +276,50 @@ func (s *albumArtService) DownloadImage(urlStr string) ([]byte, error) {
 		return nil, fmt.Errorf("failed to download image: status %d (URL: %s)", resp.StatusCode, urlStr)
 	}
 
+	if resp.ContentLength > constants.MaxImageBytes {
+		return nil, fmt.Errorf("%w: %d bytes (URL: %s)", ErrImageTooLarge, resp.ContentLength, urlStr)
+	}
+
 	var buf bytes.Buffer
-	if _, err := io.Copy(&buf, resp.Body); err != nil {
+	if _, err := io.Copy(&buf, io.LimitReader(resp.Body, constants.MaxImageBytes+1)); err != nil {
 		return nil, fmt.Errorf("failed to read image data: %w", err)
 	}
-	return buf.Bytes(), nil
+	if buf.Len() > constants.MaxImageBytes {
+		return nil, fmt.Errorf("%w: over %d bytes (URL: %s)", ErrImageTooLarge, constants.MaxImageBytes, urlStr)
+	}
+
+	data := buf.Bytes()
+	if !isImage(resp.Header.Get("Content-Type"), data) {
+		return nil, fmt.Errorf("%w: got %s (URL: %s)", ErrNotImage, http.DetectContentType(data), urlStr)
+	}
+	return data, nil
+}
+
+// checkImageRedirect follows at most MaxImageRedirects redirects, and only to http or
+// https URLs.
+func checkImageRedirect(req *http.Request, via []*http.Request) error {
+	if len(via) > constants.MaxImageRedirects {
+		return fmt.Errorf("stopped after %d redirects", constants.MaxImageRedirects)
+	}
+	if req.URL.Scheme != "http" && req.URL.Scheme != "https" {
+		return fmt.Errorf("redirect to invalid URL scheme: %s", req.URL.Scheme)
+	}
+	return nil
+}
+
+// isImage reports whether a response body is an image. The bytes are sniffed first, since
+// servers often send images as application/octet-stream; the Content-Type is trusted for
+// formats the sniffer doesn't know, unless the body sniffs as HTML or plain text.
+func isImage(contentType string, data []byte) bool {
+	if len(data) == 0 {
+		return false
+	}
+	sniffed := http.DetectContentType(data)
+	if strings.HasPrefix(sniffed, "image/") {
+		return true
+	}
+	if strings.HasPrefix(sniffed, "text/") {
+		return false
+	}
+	return strings.HasPrefix(strings.ToLower(strings.TrimSpace(contentType)), "image/")
 }
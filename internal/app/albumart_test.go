@@ -0,0 +1,223 @@
+package app
+
+import (
+	"bytes"
+	"errors"
+	"image"
+	"image/png"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+
+	"github.com/cesargomez89/navidrums/internal/config"
+	"github.com/cesargomez89/navidrums/internal/constants"
+	"github.com/cesargomez89/navidrums/internal/domain"
+)
+
+func newImageServer(t *testing.T, hits *int) *httptest.Server {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		*hits++
+		_, _ = w.Write([]byte("\xff\xd8\xff\xe0image")) // JPEG magic bytes
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func TestAlbumArtService_FolderArt(t *testing.T) {
+	tests := []struct {
+		name       string
+		folderArt  bool
+		wantFolder bool
+	}{
+		{"disabled", false, false},
+		{"enabled", true, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var hits int
+			srv := newImageServer(t, &hits)
+			cfg := &config.Config{
+				DownloadsDir:   t.TempDir(),
+				SubdirTemplate: "{{.AlbumArtist}}/{{.Album}}/{{.Title}}",
+				SaveFolderArt:  tt.folderArt,
+			}
+			svc := NewAlbumArtService(cfg)
+
+			album := &domain.Album{Title: "Album", Artist: "Artist"}
+			if err := svc.DownloadAndSaveAlbumArt(album, "hifi", srv.URL+"/cover.jpg"); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			albumDir := filepath.Join(cfg.DownloadsDir, "Artist", "Album")
+			if _, err := os.Stat(filepath.Join(albumDir, constants.CoverFileName)); err != nil {
+				t.Fatalf("expected cover.jpg: %v", err)
+			}
+			_, err := os.Stat(filepath.Join(albumDir, constants.FolderFileName))
+			if gotFolder := err == nil; gotFolder != tt.wantFolder {
+				t.Errorf("folder.jpg present = %v, want %v", gotFolder, tt.wantFolder)
+			}
+			if hits != 1 {
+				t.Errorf("expected one image download, got %d", hits)
+			}
+		})
+	}
+}
+
+func TestAlbumArtService_ArtistImage(t *testing.T) {
+	tests := []struct {
+		name       string
+		artistArt  bool
+		wantArtist bool
+		wantHits   int
+	}{
+		{"disabled", false, false, 0},
+		{"enabled", true, true, 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var hits int
+			srv := newImageServer(t, &hits)
+			cfg := &config.Config{
+				DownloadsDir:   t.TempDir(),
+				SubdirTemplate: constants.DefaultSubdirTemplate,
+				SaveArtistArt:  tt.artistArt,
+			}
+			svc := NewAlbumArtService(cfg)
+
+			artist := &domain.Artist{Name: "Artist", PictureURL: srv.URL + "/artist.jpg"}
+			// The second call must reuse the saved image rather than download it again.
+			for range 2 {
+				if err := svc.DownloadAndSaveArtistImage(artist, "hifi"); err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+			}
+
+			_, err := os.Stat(filepath.Join(cfg.DownloadsDir, "Artist", constants.ArtistFileName))
+			if gotArtist := err == nil; gotArtist != tt.wantArtist {
+				t.Errorf("artist.jpg present = %v, want %v", gotArtist, tt.wantArtist)
+			}
+			if hits != tt.wantHits {
+				t.Errorf("expected %d image downloads, got %d", tt.wantHits, hits)
+			}
+		})
+	}
+}
+
+func TestAlbumArtService_PNGCover(t *testing.T) {
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, image.NewRGBA(image.Rect(0, 0, 4, 4))); err != nil {
+		t.Fatalf("failed to encode PNG: %v", err)
+	}
+	pngData := buf.Bytes()
+
+	tests := []struct {
+		name      string
+		forceJPEG bool
+		wantFile  string
+		wantType  string
+	}{
+		{"kept as png", false, "cover.png", constants.MimeTypePNG},
+		{"transcoded to jpeg", true, "cover.jpg", constants.MimeTypeJPEG},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				_, _ = w.Write(pngData)
+			}))
+			defer srv.Close()
+
+			cfg := &config.Config{
+				DownloadsDir:      t.TempDir(),
+				SubdirTemplate:    "{{.AlbumArtist}}/{{.Album}}/{{.Title}}",
+				SaveFolderArt:     true,
+				CoverArtForceJPEG: tt.forceJPEG,
+			}
+			svc := NewAlbumArtService(cfg)
+
+			album := &domain.Album{Title: "Album", Artist: "Artist"}
+			if err := svc.DownloadAndSaveAlbumArt(album, "hifi", srv.URL+"/cover"); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			albumDir := filepath.Join(cfg.DownloadsDir, "Artist", "Album")
+			data, err := os.ReadFile(filepath.Join(albumDir, tt.wantFile))
+			if err != nil {
+				t.Fatalf("expected %s: %v", tt.wantFile, err)
+			}
+			if got := http.DetectContentType(data); got != tt.wantType {
+				t.Errorf("%s content type = %s, want %s", tt.wantFile, got, tt.wantType)
+			}
+			folderFile := "folder" + filepath.Ext(tt.wantFile)
+			if _, err := os.Stat(filepath.Join(albumDir, folderFile)); err != nil {
+				t.Errorf("expected %s: %v", folderFile, err)
+			}
+		})
+	}
+}
+
+func TestAlbumArtService_DownloadImage(t *testing.T) {
+	tests := []struct {
+		name        string
+		contentType string
+		body        []byte
+		wantErr     error
+	}{
+		{"jpeg", "image/jpeg", []byte("\xff\xd8\xff\xe0image"), nil},
+		{"jpeg sent as octet-stream", "application/octet-stream", []byte("\xff\xd8\xff\xe0image"), nil},
+		{"html error page", "text/html", []byte("<!DOCTYPE html><html><body>Not found</body></html>"), ErrNotImage},
+		{"html sent as an image", "image/jpeg", []byte("<html><body>Rate limited</body></html>"), ErrNotImage},
+		{"empty body", "image/jpeg", nil, ErrNotImage},
+		{"oversized", "image/jpeg", append([]byte("\xff\xd8\xff\xe0"), make([]byte, constants.MaxImageBytes)...), ErrImageTooLarge},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Content-Type", tt.contentType)
+				_, _ = w.Write(tt.body)
+			}))
+			defer srv.Close()
+
+			data, err := NewAlbumArtService(&config.Config{}).DownloadImage(srv.URL + "/cover.jpg")
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("DownloadImage() error = %v, want %v", err, tt.wantErr)
+			}
+			if tt.wantErr == nil && !bytes.Equal(data, tt.body) {
+				t.Errorf("DownloadImage() = %d bytes, want %d", len(data), len(tt.body))
+			}
+		})
+	}
+
+	t.Run("oversized by Content-Length", func(t *testing.T) {
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Length", strconv.Itoa(constants.MaxImageBytes+1))
+			_, _ = w.Write([]byte("\xff\xd8\xff\xe0"))
+		}))
+		defer srv.Close()
+
+		if _, err := NewAlbumArtService(&config.Config{}).DownloadImage(srv.URL); !errors.Is(err, ErrImageTooLarge) {
+			t.Errorf("DownloadImage() error = %v, want ErrImageTooLarge", err)
+		}
+	})
+
+	t.Run("redirect", func(t *testing.T) {
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.URL.Path != "/final.jpg" {
+				http.Redirect(w, r, "/final.jpg", http.StatusFound)
+				return
+			}
+			_, _ = w.Write([]byte("\xff\xd8\xff\xe0image"))
+		}))
+		defer srv.Close()
+
+		if _, err := NewAlbumArtService(&config.Config{}).DownloadImage(srv.URL + "/cover.jpg"); err != nil {
+			t.Errorf("DownloadImage() error = %v, want the redirected image", err)
+		}
+	})
+}
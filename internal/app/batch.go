@@ -0,0 +1,175 @@
+package app
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+
+	"github.com/cesargomez89/navidrums/internal/domain"
+)
+
+// Batch statuses, reported per line by EnqueueBatch.
+const (
+	BatchQueued    = "queued"
+	BatchDuplicate = "duplicate"
+	BatchError     = "error"
+)
+
+// BatchResult is the outcome of one line of a pasted list.
+type BatchResult struct {
+	Line   string         `json:"line"`
+	Type   domain.JobType `json:"type,omitempty"`
+	ID     string         `json:"id,omitempty"`
+	Status string         `json:"status"`
+	JobID  string         `json:"job_id,omitempty"`
+	Error  string         `json:"error,omitempty"`
+}
+
+// sourceTypes maps the path segments of Tidal and Qobuz URLs, and the prefixes of
+// "type:id" lines, to the job they enqueue. Qobuz store pages name artists "interpreter".
+var sourceTypes = map[string]domain.JobType{
+	"track":       domain.JobTypeTrack,
+	"album":       domain.JobTypeAlbum,
+	"playlist":    domain.JobTypePlaylist,
+	"artist":      domain.JobTypeArtist,
+	"interpreter": domain.JobTypeArtist,
+	"discography": domain.JobTypeDiscography,
+}
+
+// IsBatchType reports whether t is a job type a pasted line can enqueue.
+func IsBatchType(t domain.JobType) bool {
+	for _, st := range sourceTypes {
+		if st == t {
+			return true
+		}
+	}
+	return false
+}
+
+// ParseSource reads one pasted line as a job type and provider ID. It accepts Tidal
+// (tidal.com/browse/album/1, listen.tidal.com/album/1/track/2) and Qobuz
+// (open.qobuz.com/album/x, www.qobuz.com/us-en/album/slug/x) URLs, "type:id", and a bare
+// ID, which is taken as defaultType. In a URL the last type segment wins, so a track
+// linked from its album page is the track.
+func ParseSource(line string, defaultType domain.JobType) (domain.JobType, string, error) {
+	line = strings.TrimSpace(line)
+	if line == "" {
+		return "", "", fmt.Errorf("empty line")
+	}
+
+	if strings.Contains(line, "/") {
+		raw := line
+		if !strings.Contains(raw, "://") {
+			raw = "https://" + raw
+		}
+		u, err := url.Parse(raw)
+		if err != nil {
+			return "", "", fmt.Errorf("invalid URL: %w", err)
+		}
+		segments := strings.FieldsFunc(u.Path, func(r rune) bool { return r == '/' })
+		for i := len(segments) - 2; i >= 0; i-- {
+			if jobType, ok := sourceTypes[strings.ToLower(segments[i])]; ok {
+				// Qobuz store pages put a slug between the type and the ID.
+				return jobType, segments[len(segments)-1], nil
+			}
+		}
+		return "", "", fmt.Errorf("no album, track, playlist or artist in URL")
+	}
+
+	if prefix, id, ok := strings.Cut(line, ":"); ok {
+		jobType, known := sourceTypes[strings.ToLower(strings.TrimSpace(prefix))]
+		id = strings.TrimSpace(id)
+		if !known {
+			return "", "", fmt.Errorf("unknown type %q", prefix)
+		}
+		if id == "" {
+			return "", "", fmt.Errorf("missing ID")
+		}
+		return jobType, id, nil
+	}
+
+	if strings.ContainsAny(line, " \t,") {
+		return "", "", fmt.Errorf("not a URL or ID")
+	}
+	return defaultType, line, nil
+}
+
+// EnqueueBatch parses each line of a pasted list with ParseSource and enqueues a job for
+// it, reporting every line on its own: a line that fails to parse or enqueue doesn't stop
+// the others. Blank lines and lines starting with # are skipped. A source repeated in the
+// list, already queued or running, or already downloaded (a completed track, or a
+// completed job for an album, playlist or artist) is reported as a duplicate.
+func (s *JobService) EnqueueBatch(lines []string, defaultType domain.JobType) []BatchResult {
+	var results []BatchResult
+	seen := make(map[string]bool)
+	for _, line := range lines {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		res := BatchResult{Line: line}
+		jobType, id, err := ParseSource(line, defaultType)
+		if err != nil {
+			res.Status, res.Error = BatchError, err.Error()
+			results = append(results, res)
+			continue
+		}
+		res.Type, res.ID = jobType, id
+
+		key := string(jobType) + ":" + id
+		if seen[key] {
+			res.Status, res.Error = BatchDuplicate, "repeated in the list"
+			results = append(results, res)
+			continue
+		}
+		seen[key] = true
+
+		res.Status, res.JobID, res.Error = s.enqueueBatchSource(jobType, id)
+		results = append(results, res)
+	}
+
+	queued := 0
+	for _, res := range results {
+		if res.Status == BatchQueued {
+			queued++
+		}
+	}
+	s.Logger.Info("Batch enqueued", "lines", len(results), "queued", queued)
+	return results
+}
+
+// enqueueBatchSource enqueues one parsed source unless it is already active or done,
+// returning its batch status, the job ID and the reason it wasn't queued.
+func (s *JobService) enqueueBatchSource(jobType domain.JobType, id string) (status, jobID, reason string) {
+	active, err := s.Repo.GetActiveJobBySourceID(id, jobType)
+	if err != nil {
+		return BatchError, "", fmt.Sprintf("failed to check for existing job: %v", err)
+	}
+	if active != nil {
+		return BatchDuplicate, active.ID, "already " + string(active.Status)
+	}
+
+	done, err := s.Repo.HasCompletedJob(id, jobType)
+	if err == nil && !done && jobType == domain.JobTypeTrack {
+		var track *domain.Track
+		track, err = s.Repo.GetTrackByProviderID(id)
+		if errors.Is(err, sql.ErrNoRows) {
+			err = nil
+		}
+		done = track != nil && track.Status == domain.TrackStatusCompleted && track.DeletedAt == nil
+	}
+	if err != nil {
+		return BatchError, "", fmt.Sprintf("failed to check for download: %v", err)
+	}
+	if done {
+		return BatchDuplicate, "", "already downloaded"
+	}
+
+	job, err := s.EnqueueJob(id, jobType)
+	if err != nil {
+		return BatchError, "", err.Error()
+	}
+	return BatchQueued, job.ID, ""
+}
@@ -0,0 +1,47 @@
+package app
+
+import (
+	"testing"
+
+	"github.com/cesargomez89/navidrums/internal/domain"
+)
+
+func TestParseSource(t *testing.T) {
+	tests := []struct {
+		name     string
+		line     string
+		wantType domain.JobType
+		wantID   string
+		wantErr  bool
+	}{
+		{"tidal browse album", "https://tidal.com/browse/album/123456", domain.JobTypeAlbum, "123456", false},
+		{"tidal track with query", "https://tidal.com/browse/track/987?u", domain.JobTypeTrack, "987", false},
+		{"tidal track on album page", "https://listen.tidal.com/album/123/track/456", domain.JobTypeTrack, "456", false},
+		{"tidal playlist", "https://tidal.com/playlist/1b2c-3d4e", domain.JobTypePlaylist, "1b2c-3d4e", false},
+		{"tidal artist without scheme", "tidal.com/browse/artist/42/", domain.JobTypeArtist, "42", false},
+		{"qobuz open album", "https://open.qobuz.com/album/0060254735180", domain.JobTypeAlbum, "0060254735180", false},
+		{"qobuz play track", "https://play.qobuz.com/track/12345", domain.JobTypeTrack, "12345", false},
+		{"qobuz store album", "https://www.qobuz.com/us-en/album/some-album-some-artist/abc123", domain.JobTypeAlbum, "abc123", false},
+		{"qobuz store artist", "https://www.qobuz.com/us-en/interpreter/some-artist/777", domain.JobTypeArtist, "777", false},
+		{"type prefix", "album:555", domain.JobTypeAlbum, "555", false},
+		{"type prefix with spaces", " Discography : 9 ", domain.JobTypeDiscography, "9", false},
+		{"bare id", "  31337  ", domain.JobTypeTrack, "31337", false},
+		{"unknown url", "https://example.com/foo/bar", "", "", true},
+		{"type without id", "https://tidal.com/browse/album", "", "", true},
+		{"unknown prefix", "video:1", "", "", true},
+		{"missing id", "track:", "", "", true},
+		{"words", "some album name", "", "", true},
+		{"empty", "   ", "", "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gotType, gotID, err := ParseSource(tt.line, domain.JobTypeTrack)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseSource(%q) error = %v, wantErr %v", tt.line, err, tt.wantErr)
+			}
+			if gotType != tt.wantType || gotID != tt.wantID {
+				t.Errorf("ParseSource(%q) = %q, %q, want %q, %q", tt.line, gotType, gotID, tt.wantType, tt.wantID)
+			}
+		})
+	}
+}
@@ -0,0 +1,85 @@
+package app
+
+import (
+	"slices"
+	"strings"
+
+	"github.com/cesargomez89/navidrums/internal/config"
+	"github.com/cesargomez89/navidrums/internal/constants"
+	"github.com/cesargomez89/navidrums/internal/domain"
+)
+
+// genericAlbumArtists are album artist names providers use for releases without a single
+// artist, compared case-insensitively.
+var genericAlbumArtists = []string{"various artists", "various", "va", "v.a.", "v/a"}
+
+// VariousArtistsName is the album artist written on compilations: VARIOUS_ARTISTS_NAME, or
+// "Various Artists" when unset.
+func VariousArtistsName(cfg *config.Config) string {
+	if cfg != nil && cfg.VariousArtistsName != "" {
+		return cfg.VariousArtistsName
+	}
+	return constants.DefaultVariousArtists
+}
+
+// IsGenericAlbumArtist reports whether an album artist names no one in particular: empty,
+// a "Various Artists" spelling, or the configured name.
+func IsGenericAlbumArtist(cfg *config.Config, name string) bool {
+	name = strings.ToLower(strings.TrimSpace(name))
+	return name == "" || name == strings.ToLower(VariousArtistsName(cfg)) || slices.Contains(genericAlbumArtists, name)
+}
+
+// DetectCompilation reports whether an album is a compilation: its album artist is generic
+// and its tracks have at least COMPILATION_MIN_ARTISTS distinct primary artists. An album
+// credited to a real artist is never a compilation, however many guests it has.
+func DetectCompilation(cfg *config.Config, album *domain.Album) bool {
+	if cfg == nil || !cfg.CompilationDetection || album == nil {
+		return false
+	}
+	if !IsGenericAlbumArtist(cfg, album.Artist) {
+		return false
+	}
+
+	minArtists := cfg.CompilationMinArtists
+	if minArtists == 0 {
+		minArtists = constants.DefaultCompilationArtists
+	}
+	artists := make(map[string]struct{})
+	for _, t := range album.Tracks {
+		if name := strings.ToLower(strings.TrimSpace(t.Artist)); name != "" {
+			artists[name] = struct{}{}
+		}
+	}
+	return len(artists) >= minArtists
+}
+
+// ApplyCompilation marks an album and its tracks as a compilation credited to
+// VariousArtistsName when DetectCompilation says it is one, reporting whether it did.
+func ApplyCompilation(cfg *config.Config, album *domain.Album) bool {
+	if !DetectCompilation(cfg, album) {
+		return false
+	}
+	name := VariousArtistsName(cfg)
+	album.Artist = name
+	album.Artists = []string{name}
+	for i := range album.Tracks {
+		t := &album.Tracks[i]
+		t.Compilation = true
+		t.AlbumArtist = name
+		t.AlbumArtists = []string{name}
+	}
+	return true
+}
+
+// NormalizeCompilationArtist credits a compilation track whose album artist is generic to
+// VariousArtistsName, so refreshing it from the provider does not undo ApplyCompilation.
+// A track not marked as a compilation, or credited to a real album artist, is left alone.
+func NormalizeCompilationArtist(cfg *config.Config, track *domain.Track) {
+	if cfg == nil || !cfg.CompilationDetection || !track.Compilation || !IsGenericAlbumArtist(cfg, track.AlbumArtist) {
+		return
+	}
+	name := VariousArtistsName(cfg)
+	track.AlbumArtist = name
+	track.AlbumArtists = []string{name}
+	track.PathArtist = name
+}
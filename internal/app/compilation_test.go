@@ -0,0 +1,105 @@
+package app
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/cesargomez89/navidrums/internal/config"
+	"github.com/cesargomez89/navidrums/internal/domain"
+)
+
+func albumWithArtists(albumArtist string, trackArtists ...string) *domain.Album {
+	album := &domain.Album{Title: "Album", Artist: albumArtist, Artists: []string{albumArtist}}
+	for _, artist := range trackArtists {
+		album.Tracks = append(album.Tracks, domain.CatalogTrack{
+			Title:        "Song",
+			Artist:       artist,
+			AlbumArtist:  albumArtist,
+			AlbumArtists: []string{albumArtist},
+		})
+	}
+	return album
+}
+
+func TestDetectCompilation(t *testing.T) {
+	enabled := &config.Config{CompilationDetection: true, CompilationMinArtists: 3}
+
+	tests := []struct {
+		name  string
+		cfg   *config.Config
+		album *domain.Album
+		want  bool
+	}{
+		{"multi-artist compilation", enabled, albumWithArtists("Various Artists", "A", "B", "C", "D"), true},
+		{"generic spelling", enabled, albumWithArtists("VA", "A", "B", "C"), true},
+		{"no album artist", enabled, albumWithArtists("", "A", "B", "C"), true},
+		{"single-artist album", enabled, albumWithArtists("Artist", "Artist", "Artist", "Artist"), false},
+		{"real album artist with guests", enabled, albumWithArtists("Artist", "Artist", "B", "C", "D"), false},
+		{"too few artists", enabled, albumWithArtists("Various Artists", "A", "B", "a"), false},
+		{"configured threshold", &config.Config{CompilationDetection: true, CompilationMinArtists: 2}, albumWithArtists("Various Artists", "A", "B"), true},
+		{"configured name", &config.Config{CompilationDetection: true, VariousArtistsName: "Sampler"}, albumWithArtists("Sampler", "A", "B", "C"), true},
+		{"disabled", &config.Config{CompilationMinArtists: 3}, albumWithArtists("Various Artists", "A", "B", "C"), false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := DetectCompilation(tt.cfg, tt.album); got != tt.want {
+				t.Errorf("DetectCompilation() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestApplyCompilation(t *testing.T) {
+	cfg := &config.Config{CompilationDetection: true, VariousArtistsName: "V.A. Collection"}
+
+	t.Run("compilation", func(t *testing.T) {
+		album := albumWithArtists("various artists", "A", "B", "C")
+		if !ApplyCompilation(cfg, album) {
+			t.Fatal("ApplyCompilation() = false, want true")
+		}
+		want := []string{"V.A. Collection"}
+		if album.Artist != "V.A. Collection" || !reflect.DeepEqual(album.Artists, want) {
+			t.Errorf("album artist = %q %v, want %q", album.Artist, album.Artists, "V.A. Collection")
+		}
+		for i, track := range album.Tracks {
+			if !track.Compilation || track.AlbumArtist != "V.A. Collection" || !reflect.DeepEqual(track.AlbumArtists, want) {
+				t.Errorf("track %d = %+v, want a compilation credited to V.A. Collection", i, track)
+			}
+		}
+	})
+
+	t.Run("single-artist album", func(t *testing.T) {
+		album := albumWithArtists("Artist", "Artist", "Artist", "Artist")
+		want := albumWithArtists("Artist", "Artist", "Artist", "Artist")
+		if ApplyCompilation(cfg, album) {
+			t.Error("ApplyCompilation() = true, want false")
+		}
+		if !reflect.DeepEqual(album, want) {
+			t.Errorf("album = %+v, want it unchanged", album)
+		}
+	})
+}
+
+func TestNormalizeCompilationArtist(t *testing.T) {
+	cfg := &config.Config{CompilationDetection: true}
+
+	tests := []struct {
+		name        string
+		track       domain.Track
+		wantArtist  string
+		wantPathArt string
+	}{
+		{"generic artist from provider", domain.Track{Compilation: true, AlbumArtist: "VA", PathArtist: "VA"}, "Various Artists", "Various Artists"},
+		{"edited album artist", domain.Track{Compilation: true, AlbumArtist: "DJ Mix", PathArtist: "DJ Mix"}, "DJ Mix", "DJ Mix"},
+		{"not a compilation", domain.Track{AlbumArtist: "VA", PathArtist: "VA"}, "VA", "VA"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			track := tt.track
+			NormalizeCompilationArtist(cfg, &track)
+			if track.AlbumArtist != tt.wantArtist || track.PathArtist != tt.wantPathArt {
+				t.Errorf("album artist = %q, path artist = %q, want %q, %q", track.AlbumArtist, track.PathArtist, tt.wantArtist, tt.wantPathArt)
+			}
+		})
+	}
+}
@@ -2,6 +2,7 @@ package app
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"io"
 	"log/slog"
@@ -16,7 +17,7 @@ import (
 )
 
 type Downloader interface {
-	Download(ctx context.Context, track *domain.Track, destPathNoExt string, quality string, logger *slog.Logger) (string, error)
+	Download(ctx context.Context, track *domain.Track, destPathNoExt string, qualities []string, logger *slog.Logger) (string, error)
 }
 
 type downloader struct {
@@ -31,9 +32,54 @@ func NewDownloader(pm *catalog.ProviderManager, cfg *config.Config) Downloader {
 	}
 }
 
-func (d *downloader) Download(ctx context.Context, track *domain.Track, destPathNoExt string, quality string, logger *slog.Logger) (string, error) {
+// Download fetches the track at the first quality in the preference list the provider
+// serves, falling back to the next tier once every attempt at the current one has failed.
+// A track the provider reports as unavailable is given up on at once, whatever the quality,
+// and one it doesn't know at a quality moves straight on to the next.
+func (d *downloader) Download(ctx context.Context, track *domain.Track, destPathNoExt string, qualities []string, logger *slog.Logger) (string, error) {
+	if len(qualities) == 0 {
+		qualities = []string{constants.DefaultQuality}
+	}
+
 	provider := d.providerManager.GetDownloadProvider()
 
+	var lastErr error
+	for i, quality := range qualities {
+		if i > 0 {
+			logger.Warn("Falling back to lower quality", "quality", quality, "previous_quality", qualities[i-1], "error", lastErr)
+		}
+		path, err := downloadAtQuality(ctx, provider, track, destPathNoExt, quality, FallbackExtension(d.config), logger)
+		if err == nil {
+			return path, nil
+		}
+		// A track missing at one quality may still be served at the next, so only a track
+		// the provider won't stream at all, or refused credentials, end the fallback.
+		if ctx.Err() != nil || errors.Is(err, catalog.ErrTrackUnavailable) || errors.Is(err, catalog.ErrUnauthorized) {
+			return "", err
+		}
+		lastErr = err
+	}
+	return "", lastErr
+}
+
+// IsQualityUpgrade reports whether re-downloading a track stored at stored quality would
+// get a better file now that the provider reports reported. The gain is capped at the top
+// of the preference list, and tracks with no known stored quality are never upgraded.
+func IsQualityUpgrade(stored, reported string, qualities []string) bool {
+	storedRank := config.QualityRank(stored)
+	if storedRank == 0 {
+		return false
+	}
+	best := config.QualityRank(reported)
+	if len(qualities) > 0 {
+		best = min(best, config.QualityRank(qualities[0]))
+	}
+	return best > storedRank
+}
+
+// downloadAtQuality saves the stream under destPathNoExt with the extension its content
+// type maps to, or fallbackExt when the type is unknown.
+func downloadAtQuality(ctx context.Context, provider catalog.Provider, track *domain.Track, destPathNoExt, quality, fallbackExt string, logger *slog.Logger) (string, error) {
 	shouldConvertToFLAC := quality == constants.QualityHiResLossless
 
 	var lastErr error
@@ -46,28 +92,32 @@ func (d *downloader) Download(ctx context.Context, track *domain.Track, destPath
 		}
 
 		stream, mimeType, err := provider.GetStream(ctx, track.ProviderID, track.ISRC, quality)
+		if isPermanentStreamError(err) {
+			return "", err
+		}
 		if err != nil {
 			lastErr = err
+			wait := streamRetryDelay(err, attempt)
 			logger.Error("Download attempt failed",
 				"attempt", attempt+1,
 				"total_attempts", constants.DefaultRetryCount,
 				"track_id", track.ID,
 				"track_title", track.Title,
 				"provider_id", track.ProviderID,
+				"retry_in", wait,
 				"error", err,
 			)
-			time.Sleep(time.Duration(attempt+1) * constants.DefaultRetryBase)
+			timer := time.NewTimer(wait)
+			select {
+			case <-ctx.Done():
+				timer.Stop()
+				return "", ctx.Err()
+			case <-timer.C:
+			}
 			continue
 		}
 
-		ext := constants.ExtFLAC
-		switch mimeType {
-		case constants.MimeTypeMP4:
-			ext = constants.ExtM4A
-		case constants.MimeTypeMP3:
-			ext = constants.ExtMP3
-		}
-
+		ext := ExtensionForMimeType(mimeType, fallbackExt)
 		downloadPath := destPathNoExt + ext
 
 		f, err := storage.CreateFile(downloadPath)
@@ -76,18 +126,37 @@ func (d *downloader) Download(ctx context.Context, track *domain.Track, destPath
 			continue
 		}
 
-		_, err = io.Copy(f, stream)
+		err = copyStream(ctx, f, stream)
 		_ = stream.Close()
 		_ = f.Close()
 
 		if err != nil {
 			lastErr = err
 			_ = storage.RemoveFile(downloadPath)
+			if ctx.Err() != nil {
+				return "", ctx.Err()
+			}
 			time.Sleep(time.Duration(attempt+1) * constants.DefaultRetryBase)
 			continue
 		}
 
-		if shouldConvertToFLAC && mimeType == constants.MimeTypeMP4 {
+		// A stream cut short still copies without error; catch it before it gets tagged.
+		if ext == constants.ExtFLAC {
+			if err := storage.VerifyFLAC(downloadPath); err != nil {
+				lastErr = err
+				logger.Warn("Downloaded FLAC failed verification",
+					"attempt", attempt+1,
+					"total_attempts", constants.DefaultRetryCount,
+					"track_id", track.ID,
+					"error", err,
+				)
+				_ = storage.RemoveFile(downloadPath)
+				time.Sleep(time.Duration(attempt+1) * constants.DefaultRetryBase)
+				continue
+			}
+		}
+
+		if shouldConvertToFLAC && ext == constants.ExtM4A {
 			flacPath, convErr := ffmpeg.ConvertToFLAC(ctx, downloadPath)
 			if convErr != nil {
 				lastErr = convErr
@@ -107,5 +176,42 @@ func (d *downloader) Download(ctx context.Context, track *domain.Track, destPath
 		return downloadPath, nil
 	}
 
-	return "", fmt.Errorf("download failed after %d attempts: %w", constants.DefaultRetryCount, lastErr)
+	return "", fmt.Errorf("download at %s failed after %d attempts: %w", quality, constants.DefaultRetryCount, lastErr)
+}
+
+// isPermanentStreamError reports whether a stream request failed in a way retrying can't
+// fix: the track is unavailable or unknown to the provider, or the provider refused the
+// credentials.
+func isPermanentStreamError(err error) bool {
+	return errors.Is(err, catalog.ErrTrackUnavailable) ||
+		errors.Is(err, catalog.ErrNotFound) ||
+		errors.Is(err, catalog.ErrUnauthorized)
+}
+
+// streamRetryDelay is how long to wait before retrying a failed stream request. A
+// rate-limited provider gets an exponential backoff, or the wait it asked for when that is
+// longer; other failures back off linearly.
+func streamRetryDelay(err error, attempt int) time.Duration {
+	if !errors.Is(err, catalog.ErrRateLimited) {
+		return time.Duration(attempt+1) * constants.DefaultRetryBase
+	}
+	wait := min(constants.DefaultRetryBase<<attempt, constants.StreamRetryMaxWait)
+	var statusErr *catalog.StatusError
+	if errors.As(err, &statusErr) {
+		wait = max(wait, statusErr.RetryAfter)
+	}
+	return wait
+}
+
+// copyStream copies stream to dst until it ends or ctx is cancelled. Cancelling closes the
+// stream, so a read blocked on a stalled connection returns at once.
+func copyStream(ctx context.Context, dst io.Writer, stream io.ReadCloser) error {
+	stop := context.AfterFunc(ctx, func() { _ = stream.Close() })
+	defer stop()
+
+	_, err := io.Copy(dst, stream)
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+	return err
 }
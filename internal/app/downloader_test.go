@@ -0,0 +1,182 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/cesargomez89/navidrums/internal/catalog"
+	"github.com/cesargomez89/navidrums/internal/constants"
+	"github.com/cesargomez89/navidrums/internal/domain"
+	"github.com/cesargomez89/navidrums/internal/logger"
+)
+
+func TestIsQualityUpgrade(t *testing.T) {
+	lossless := []string{constants.QualityLossless, constants.QualityHigh}
+	hiRes := []string{constants.QualityHiResLossless}
+
+	tests := []struct {
+		name      string
+		stored    string
+		reported  string
+		qualities []string
+		want      bool
+	}{
+		{"low to lossless", constants.QualityLow, constants.QualityLossless, lossless, true},
+		{"same quality", constants.QualityLossless, constants.QualityLossless, lossless, false},
+		{"provider reports less", constants.QualityLossless, constants.QualityHigh, lossless, false},
+		{"capped by preference", constants.QualityLossless, constants.QualityHiResLossless, lossless, false},
+		{"hi-res allowed", constants.QualityLossless, constants.QualityHiResLossless, hiRes, true},
+		{"unknown stored quality", "", constants.QualityHiResLossless, hiRes, false},
+		{"unknown reported quality", constants.QualityLow, "", hiRes, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := IsQualityUpgrade(tt.stored, tt.reported, tt.qualities); got != tt.want {
+				t.Errorf("IsQualityUpgrade(%q, %q) = %v, want %v", tt.stored, tt.reported, got, tt.want)
+			}
+		})
+	}
+}
+
+// stallingStream yields some audio and then blocks, like a stalled connection, until it
+// is closed.
+type stallingStream struct {
+	sent   bool
+	closed chan struct{}
+	once   sync.Once
+}
+
+func (s *stallingStream) Read(p []byte) (int, error) {
+	if !s.sent {
+		s.sent = true
+		return copy(p, "fLaC partial audio"), nil
+	}
+	<-s.closed
+	return 0, io.ErrClosedPipe
+}
+
+func (s *stallingStream) Close() error {
+	s.once.Do(func() { close(s.closed) })
+	return nil
+}
+
+// streamProvider serves a single stream; any other Provider method panics.
+type streamProvider struct {
+	catalog.Provider
+	stream io.ReadCloser
+}
+
+func (p *streamProvider) GetStream(ctx context.Context, trackID, isrc, quality string) (io.ReadCloser, string, error) {
+	return p.stream, constants.MimeTypeFLAC, nil
+}
+
+func TestDownloadAtQuality_Cancelled(t *testing.T) {
+	provider := &streamProvider{stream: &stallingStream{closed: make(chan struct{})}}
+	destNoExt := filepath.Join(t.TempDir(), "track")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(50*time.Millisecond, cancel)
+
+	start := time.Now()
+	_, err := downloadAtQuality(ctx, provider, &domain.Track{ProviderID: "t1"}, destNoExt, constants.QualityLossless, constants.ExtFLAC, logger.Default().Logger)
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("downloadAtQuality() error = %v, want context.Canceled", err)
+	}
+	if elapsed := time.Since(start); elapsed > 2*time.Second {
+		t.Errorf("downloadAtQuality() took %v after cancellation", elapsed)
+	}
+	if _, statErr := os.Stat(destNoExt + constants.ExtFLAC); !os.IsNotExist(statErr) {
+		t.Errorf("partial file was not removed: %v", statErr)
+	}
+}
+
+// unavailableProvider reports every track as unavailable, counting the requests.
+type unavailableProvider struct {
+	catalog.Provider
+	calls int
+}
+
+func (p *unavailableProvider) GetStream(ctx context.Context, trackID, isrc, quality string) (io.ReadCloser, string, error) {
+	p.calls++
+	return nil, "", fmt.Errorf("%w: no manifest found", catalog.ErrTrackUnavailable)
+}
+
+func TestDownloadAtQuality_Unavailable(t *testing.T) {
+	provider := &unavailableProvider{}
+	destNoExt := filepath.Join(t.TempDir(), "track")
+
+	_, err := downloadAtQuality(context.Background(), provider, &domain.Track{ProviderID: "t1"}, destNoExt, constants.QualityLossless, constants.ExtFLAC, logger.Default().Logger)
+	if !errors.Is(err, catalog.ErrTrackUnavailable) {
+		t.Fatalf("downloadAtQuality() error = %v, want ErrTrackUnavailable", err)
+	}
+	if provider.calls != 1 {
+		t.Errorf("GetStream called %d times, want 1 (no retries)", provider.calls)
+	}
+}
+
+// failingProvider fails every stream request with err, counting the requests.
+type failingProvider struct {
+	catalog.Provider
+	err   error
+	calls int
+}
+
+func (p *failingProvider) GetStream(ctx context.Context, trackID, isrc, quality string) (io.ReadCloser, string, error) {
+	p.calls++
+	return nil, "", p.err
+}
+
+func TestDownloadAtQuality_PermanentErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+	}{
+		{"not found", fmt.Errorf("API request failed: %w", catalog.ErrNotFound)},
+		{"unauthorized", fmt.Errorf("API request failed: %w", catalog.ErrUnauthorized)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			provider := &failingProvider{err: tt.err}
+			destNoExt := filepath.Join(t.TempDir(), "track")
+
+			_, err := downloadAtQuality(context.Background(), provider, &domain.Track{ProviderID: "t1"}, destNoExt, constants.QualityLossless, constants.ExtFLAC, logger.Default().Logger)
+			if !errors.Is(err, tt.err) {
+				t.Fatalf("downloadAtQuality() error = %v, want %v", err, tt.err)
+			}
+			if provider.calls != 1 {
+				t.Errorf("GetStream called %d times, want 1 (no retries)", provider.calls)
+			}
+		})
+	}
+}
+
+func TestStreamRetryDelay(t *testing.T) {
+	rateLimited := &catalog.StatusError{StatusCode: 429, Status: "429 Too Many Requests", RetryAfter: 20 * time.Second}
+
+	tests := []struct {
+		name    string
+		err     error
+		attempt int
+		want    time.Duration
+	}{
+		{"other failure", errors.New("connection reset"), 2, 3 * constants.DefaultRetryBase},
+		{"unavailable", fmt.Errorf("x: %w", catalog.ErrUnavailable), 0, constants.DefaultRetryBase},
+		{"rate limited backs off", fmt.Errorf("x: %w", catalog.ErrRateLimited), 3, 8 * constants.DefaultRetryBase},
+		{"rate limited backoff is capped", fmt.Errorf("x: %w", catalog.ErrRateLimited), 10, constants.StreamRetryMaxWait},
+		{"rate limited honours Retry-After", fmt.Errorf("x: %w", rateLimited), 0, 20 * time.Second},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := streamRetryDelay(tt.err, tt.attempt); got != tt.want {
+				t.Errorf("streamRetryDelay() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -1,15 +1,19 @@
 package app
 
 import (
+	"context"
 	"database/sql"
 	"errors"
 	"fmt"
+	"io"
 	"path/filepath"
 	"strings"
 	"time"
 
 	"github.com/google/uuid"
 
+	"github.com/cesargomez89/navidrums/internal/config"
+	"github.com/cesargomez89/navidrums/internal/constants"
 	"github.com/cesargomez89/navidrums/internal/domain"
 	"github.com/cesargomez89/navidrums/internal/logger"
 	"github.com/cesargomez89/navidrums/internal/storage"
@@ -25,44 +29,80 @@ type RecommendationSeeds struct {
 	ArtistID string
 }
 
+var ErrArchiveEmpty = errors.New("no completed tracks to archive")
+
 type DownloadsService struct {
 	Repo   *store.DB
+	Config *config.Config
 	Logger *logger.Logger
+
+	requestID string
+}
+
+func NewDownloadsService(repo *store.DB, cfg *config.Config, log *logger.Logger) *DownloadsService {
+	return &DownloadsService{Repo: repo, Config: cfg, Logger: log}
 }
 
-func NewDownloadsService(repo *store.DB, log *logger.Logger) *DownloadsService {
-	return &DownloadsService{Repo: repo, Logger: log}
+// ForRequest returns a copy of s for one HTTP request: it logs the request's ID, and the
+// jobs it queues record it.
+func (s *DownloadsService) ForRequest(ctx context.Context) *DownloadsService {
+	scoped := *s
+	scoped.Logger = s.Logger.WithRequest(ctx)
+	scoped.requestID = logger.RequestID(ctx)
+	return &scoped
 }
 
-func (s *DownloadsService) ListDownloads(page, pageSize int) ([]*domain.Track, int, error) {
+func (s *DownloadsService) ListDownloads(sort store.TrackSort, page, pageSize int) ([]*domain.Track, int, error) {
 	offset := (page - 1) * pageSize
 	total, err := s.Repo.CountCompletedTracks()
 	if err != nil {
 		return nil, 0, err
 	}
-	tracks, err := s.Repo.ListCompletedTracks(offset, pageSize)
+	tracks, err := s.Repo.ListCompletedTracks(sort, offset, pageSize)
 	return tracks, total, err
 }
 
-func (s *DownloadsService) SearchDownloads(query string, page, pageSize int) ([]*domain.Track, int, error) {
+func (s *DownloadsService) SearchDownloads(query string, sort store.TrackSort, page, pageSize int) ([]*domain.Track, int, error) {
 	offset := (page - 1) * pageSize
 	total, err := s.Repo.CountSearchTracks(query)
 	if err != nil {
 		return nil, 0, err
 	}
-	tracks, err := s.Repo.SearchTracks(query, offset, pageSize)
+	tracks, err := s.Repo.SearchTracks(query, sort, offset, pageSize)
+	return tracks, total, err
+}
+
+// ListFilteredDownloads lists completed downloads matching a search and/or audio quality and
+// file extension.
+func (s *DownloadsService) ListFilteredDownloads(filter store.TrackFilter, sort store.TrackSort, page, pageSize int) ([]*domain.Track, int, error) {
+	offset := (page - 1) * pageSize
+	total, err := s.Repo.CountCompletedTracksFiltered(filter)
+	if err != nil {
+		return nil, 0, err
+	}
+	tracks, err := s.Repo.ListCompletedTracksFiltered(filter, sort, offset, pageSize)
 	return tracks, total, err
 }
 
-func (s *DownloadsService) FilterDownloads(filter string, page, pageSize int) ([]*domain.Track, int, error) {
+func (s *DownloadsService) FilterDownloads(filter string, sort store.TrackSort, page, pageSize int) ([]*domain.Track, int, error) {
 	offset := (page - 1) * pageSize
 	switch {
+	case filter == "trash":
+		return s.ListTrash(sort, page, pageSize)
+	case filter == "missing_file" || filter == "unavailable" || filter == "skipped":
+		status := domain.TrackStatus(filter)
+		total, err := s.Repo.CountTracksByStatus(status)
+		if err != nil {
+			return nil, 0, err
+		}
+		tracks, err := s.Repo.ListTracksByStatus(status, sort, offset, pageSize)
+		return tracks, total, err
 	case filter == "no_genre":
 		total, err := s.Repo.CountCompletedTracksNoGenre()
 		if err != nil {
 			return nil, 0, err
 		}
-		tracks, err := s.Repo.ListCompletedTracksNoGenre(offset, pageSize)
+		tracks, err := s.Repo.ListCompletedTracksNoGenre(sort, offset, pageSize)
 		return tracks, total, err
 	case strings.HasPrefix(filter, "genre:"):
 		genre := strings.TrimPrefix(filter, "genre:")
@@ -70,13 +110,98 @@ func (s *DownloadsService) FilterDownloads(filter string, page, pageSize int) ([
 		if err != nil {
 			return nil, 0, err
 		}
-		tracks, err := s.Repo.ListCompletedTracksByGenre(genre, offset, pageSize)
+		tracks, err := s.Repo.ListCompletedTracksByGenre(genre, sort, offset, pageSize)
 		return tracks, total, err
 	default:
-		return s.ListDownloads(page, pageSize)
+		return s.ListDownloads(sort, page, pageSize)
 	}
 }
 
+// ExportDownloads walks all completed tracks in pages of pageSize and passes each page to fn.
+// Pages are fetched one at a time so the database connection is released between batches.
+func (s *DownloadsService) ExportDownloads(pageSize int, fn func([]*domain.Track) error) error {
+	lastID := 0
+	for {
+		tracks, err := s.Repo.ListCompletedTracksAfterID(lastID, pageSize)
+		if err != nil {
+			return fmt.Errorf("failed to list tracks: %w", err)
+		}
+		if len(tracks) == 0 {
+			return nil
+		}
+		if err := fn(tracks); err != nil {
+			return err
+		}
+		if len(tracks) < pageSize {
+			return nil
+		}
+		lastID = tracks[len(tracks)-1].ID
+	}
+}
+
+// ArchiveFiles resolves the completed track files of an album or playlist, plus the cover
+// image of every folder they live in. It returns a display name for the archive.
+func (s *DownloadsService) ArchiveFiles(kind, id string) (string, []string, error) {
+	var name string
+	var tracks []*domain.Track
+
+	switch kind {
+	case "album":
+		albumTracks, err := s.Repo.ListCompletedTracksByAlbumID(id)
+		if err != nil {
+			return "", nil, fmt.Errorf("failed to list album tracks: %w", err)
+		}
+		tracks = albumTracks
+		if len(tracks) > 0 {
+			name = tracks[0].Album
+		}
+	case "playlist":
+		playlist, err := s.Repo.GetPlaylistByProviderID(id)
+		if err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				return "", nil, ErrArchiveEmpty
+			}
+			return "", nil, fmt.Errorf("failed to get playlist: %w", err)
+		}
+		playlistTracks, err := s.Repo.GetTracksByPlaylistID(playlist.ID)
+		if err != nil {
+			return "", nil, fmt.Errorf("failed to list playlist tracks: %w", err)
+		}
+		for _, t := range playlistTracks {
+			if t.Status == domain.TrackStatusCompleted {
+				tracks = append(tracks, t)
+			}
+		}
+		name = playlist.Title
+	default:
+		return "", nil, fmt.Errorf("unsupported archive type: %s", kind)
+	}
+
+	if len(tracks) == 0 {
+		return "", nil, ErrArchiveEmpty
+	}
+
+	files := make([]string, 0, len(tracks)+1)
+	dirs := make(map[string]bool)
+	for _, t := range tracks {
+		if t.FilePath == "" {
+			continue
+		}
+		files = append(files, t.FilePath)
+		dirs[filepath.Dir(t.FilePath)] = true
+	}
+	for dir := range dirs {
+		if cover := storage.FindCover(dir); cover != "" {
+			files = append(files, cover)
+		}
+	}
+
+	if name == "" {
+		name = id
+	}
+	return name, files, nil
+}
+
 func (s *DownloadsService) GetAllGenres() ([]string, error) {
 	return s.Repo.GetAllGenres()
 }
@@ -89,6 +214,34 @@ func (s *DownloadsService) UpdateTrackPartial(id int, updates map[string]interfa
 	return s.Repo.UpdateTrackPartial(id, updates)
 }
 
+// CopyTrackMetadata copies the album-level fields of source (album, album artist, year,
+// release date, genre, label, disc number and disc count) onto target, then queues a
+// sync-file job to write them to target's file. Other fields, such as the title or track
+// number, are left alone.
+func (s *DownloadsService) CopyTrackMetadata(target, source *domain.Track) error {
+	if err := s.Repo.UpdateTrackPartial(target.ID, copiedTrackFields(source)); err != nil {
+		return fmt.Errorf("failed to update track: %w", err)
+	}
+	if err := s.EnqueueSyncFileJob(target.ProviderID); err != nil {
+		return fmt.Errorf("failed to enqueue sync job: %w", err)
+	}
+	return nil
+}
+
+// copiedTrackFields returns the columns CopyTrackMetadata takes from a track.
+func copiedTrackFields(t *domain.Track) map[string]interface{} {
+	return map[string]interface{}{
+		"album":        t.Album,
+		"album_artist": t.AlbumArtist,
+		"year":         t.Year,
+		"release_date": t.ReleaseDate,
+		"genre":        t.Genre,
+		"label":        t.Label,
+		"disc_number":  t.DiscNumber,
+		"total_discs":  t.TotalDiscs,
+	}
+}
+
 func (s *DownloadsService) GetDownloadByProviderID(providerID string) (*domain.Track, error) {
 	return s.Repo.GetDownloadedTrack(providerID)
 }
@@ -105,12 +258,74 @@ func (s *DownloadsService) EnqueueSyncHiFiJob(providerID string) error {
 	return s.enqueueSyncJob(providerID, domain.JobTypeSyncHiFi)
 }
 
+func (s *DownloadsService) EnqueueSyncLyricsJob(providerID string) error {
+	return s.enqueueSyncJob(providerID, domain.JobTypeSyncLyrics)
+}
+
+// EnqueueVerifyJob queues an integrity check of one album, or of the whole library
+// when albumID is empty. It is a no-op if the same check is already queued or running.
+func (s *DownloadsService) EnqueueVerifyJob(albumID string) error {
+	sourceID := albumID
+	if sourceID == "" {
+		sourceID = domain.VerifyLibrarySourceID
+	}
+
+	existing, err := s.Repo.GetActiveJobBySourceID(sourceID, domain.JobTypeVerify)
+	if err != nil {
+		return fmt.Errorf("failed to check active verify job: %w", err)
+	}
+	if existing != nil {
+		return nil
+	}
+
+	job := &domain.Job{
+		ID:        uuid.New().String(),
+		Type:      domain.JobTypeVerify,
+		Status:    domain.JobStatusQueued,
+		SourceID:  sql.NullString{String: sourceID, Valid: true},
+		RequestID: requestIDColumn(s.requestID),
+		CreatedAt: time.Now(),
+		UpdatedAt: time.Now(),
+	}
+	return s.Repo.CreateJob(job)
+}
+
+// PreviewReorganize lists the downloaded files whose paths don't match SUBDIR_TEMPLATE,
+// without moving anything.
+func (s *DownloadsService) PreviewReorganize() (*ReorganizePlan, error) {
+	return NewLibraryReorganizer(s.Repo, s.Config).Plan()
+}
+
+// EnqueueReorganizeJob queues a move of every downloaded file to its SUBDIR_TEMPLATE path.
+// It is a no-op if a reorganize job is already queued or running.
+func (s *DownloadsService) EnqueueReorganizeJob() error {
+	existing, err := s.Repo.GetActiveJobBySourceID(domain.ReorganizeLibrarySourceID, domain.JobTypeReorganize)
+	if err != nil {
+		return fmt.Errorf("failed to check active reorganize job: %w", err)
+	}
+	if existing != nil {
+		return nil
+	}
+
+	job := &domain.Job{
+		ID:        uuid.New().String(),
+		Type:      domain.JobTypeReorganize,
+		Status:    domain.JobStatusQueued,
+		SourceID:  sql.NullString{String: domain.ReorganizeLibrarySourceID, Valid: true},
+		RequestID: requestIDColumn(s.requestID),
+		CreatedAt: time.Now(),
+		UpdatedAt: time.Now(),
+	}
+	return s.Repo.CreateJob(job)
+}
+
 func (s *DownloadsService) enqueueSyncJob(providerID string, jobType domain.JobType) error {
 	job := &domain.Job{
 		ID:        uuid.New().String(),
 		Type:      jobType,
 		Status:    domain.JobStatusQueued,
 		SourceID:  sql.NullString{String: providerID, Valid: true},
+		RequestID: requestIDColumn(s.requestID),
 		CreatedAt: time.Now(),
 		UpdatedAt: time.Now(),
 	}
@@ -126,9 +341,15 @@ func (s *DownloadsService) DeleteDownload(providerID string) error {
 		return nil
 	}
 
-	if err := storage.RemoveFile(track.FilePath); err != nil {
+	root := s.Config.DownloadsRootOf(track.FilePath)
+	if _, err := storage.MoveToTrash(root, track.FilePath); err != nil {
+		if !storage.IsNotExist(err) {
+			return fmt.Errorf("failed to move file to trash: %w", err)
+		}
+	}
+	if _, err := storage.MoveToTrash(root, storage.LyricsSidecarPath(track.FilePath)); err != nil {
 		if !storage.IsNotExist(err) {
-			return fmt.Errorf("failed to delete file: %w", err)
+			return fmt.Errorf("failed to move lyrics to trash: %w", err)
 		}
 	}
 
@@ -147,14 +368,171 @@ func (s *DownloadsService) DeleteDownload(providerID string) error {
 		return fmt.Errorf("failed to clean up artist folder: %w", err)
 	}
 
-	if err := s.Repo.DeleteTrack(track.ID); err != nil {
-		return fmt.Errorf("failed to delete track record: %w", err)
+	if err := s.Repo.SoftDeleteTrack(track.ID); err != nil {
+		return fmt.Errorf("failed to trash track record: %w", err)
+	}
+
+	s.Logger.Info("Download moved to trash", "provider_id", providerID, "file_path", track.FilePath)
+	return nil
+}
+
+// RestoreDownload moves a trashed track back to its original location.
+func (s *DownloadsService) RestoreDownload(providerID string) error {
+	track, err := s.Repo.GetTrackByProviderID(providerID)
+	if err != nil {
+		return fmt.Errorf("failed to get track: %w", err)
+	}
+	if track.DeletedAt == nil {
+		return nil
+	}
+
+	root := s.Config.DownloadsRootOf(track.FilePath)
+	lyricsPath := storage.LyricsSidecarPath(track.FilePath)
+	if storage.FileExists(storage.TrashPath(root, lyricsPath)) {
+		if err := storage.RestoreFromTrash(root, lyricsPath); err != nil {
+			return fmt.Errorf("failed to restore lyrics: %w", err)
+		}
+	}
+	if err := storage.RestoreFromTrash(root, track.FilePath); err != nil {
+		return fmt.Errorf("failed to restore file: %w", err)
+	}
+
+	if err := s.Repo.RestoreTrack(track.ID); err != nil {
+		return fmt.Errorf("failed to restore track record: %w", err)
 	}
 
-	s.Logger.Info("Download deleted", "provider_id", providerID, "file_path", track.FilePath)
+	if track.AlbumID != "" {
+		_, _ = s.Repo.RecomputeAlbumState(track.AlbumID)
+	}
+
+	s.Logger.Info("Download restored", "provider_id", providerID, "file_path", track.FilePath)
 	return nil
 }
 
+// DataFixes lists the data fixes RunDataFix can re-run.
+func (s *DownloadsService) DataFixes() []store.DataFix {
+	return store.DataFixes()
+}
+
+// RunDataFix re-runs the named data fix and reports the rows it changed.
+func (s *DownloadsService) RunDataFix(name string) (*store.DataFixResult, error) {
+	result, err := s.Repo.RunDataFix(name)
+	if err != nil {
+		return nil, err
+	}
+	s.Logger.Info("Data fix applied", "name", name, "rows_affected", result.RowsAffected)
+	return result, nil
+}
+
+// OptimizeDatabase compacts the database and reports its size before and after.
+func (s *DownloadsService) OptimizeDatabase() (*store.OptimizeResult, error) {
+	result, err := s.Repo.Optimize()
+	if err != nil {
+		return nil, fmt.Errorf("failed to optimize database: %w", err)
+	}
+	s.Logger.Info("Database optimized", "size_before", result.SizeBefore, "size_after", result.SizeAfter)
+	return result, nil
+}
+
+// BackupDatabase writes a consistent snapshot of the database to w.
+func (s *DownloadsService) BackupDatabase(w io.Writer) error {
+	path, cleanup, err := storage.TempPath("navidrums.db")
+	if err != nil {
+		return fmt.Errorf("failed to back up database: %w", err)
+	}
+	defer cleanup()
+
+	if err := s.Repo.Backup(w, path); err != nil {
+		return fmt.Errorf("failed to back up database: %w", err)
+	}
+	return nil
+}
+
+// RecentDay holds the tracks that finished downloading on one day.
+type RecentDay struct {
+	Date   time.Time
+	Tracks []*domain.Track
+}
+
+// ListRecent returns the tracks completed in the last days days, today included, grouped
+// by the local date they finished downloading, most recent first. days below 1 means
+// constants.RecentDays.
+func (s *DownloadsService) ListRecent(days int) ([]RecentDay, error) {
+	if days < 1 {
+		days = constants.RecentDays
+	}
+	now := time.Now()
+	year, month, day := now.Date()
+	from := time.Date(year, month, day-(days-1), 0, 0, 0, 0, now.Location())
+
+	tracks, err := s.Repo.ListTracksCompletedBetween(from, now.Add(time.Second))
+	if err != nil {
+		return nil, fmt.Errorf("failed to list recent tracks: %w", err)
+	}
+
+	var groups []RecentDay
+	for _, t := range tracks {
+		y, m, d := t.CompletedAt.In(now.Location()).Date()
+		date := time.Date(y, m, d, 0, 0, 0, 0, now.Location())
+		if len(groups) == 0 || !groups[len(groups)-1].Date.Equal(date) {
+			groups = append(groups, RecentDay{Date: date})
+		}
+		groups[len(groups)-1].Tracks = append(groups[len(groups)-1].Tracks, t)
+	}
+	return groups, nil
+}
+
+// ListTrash returns trashed tracks, most recently deleted first unless sort says otherwise.
+func (s *DownloadsService) ListTrash(sort store.TrackSort, page, pageSize int) ([]*domain.Track, int, error) {
+	offset := (page - 1) * pageSize
+	total, err := s.Repo.CountDeletedTracks()
+	if err != nil {
+		return nil, 0, err
+	}
+	tracks, err := s.Repo.ListDeletedTracks(sort, offset, pageSize)
+	return tracks, total, err
+}
+
+// EmptyTrash permanently removes every trashed track and its file. The rows are deleted
+// in one transaction before the files, so a failure leaves both in place rather than rows
+// pointing at deleted files. Only the listed tracks' files are removed: a track trashed
+// meanwhile keeps both its row and its file.
+func (s *DownloadsService) EmptyTrash() (int, error) {
+	tracks, err := s.Repo.ListAllDeletedTracks()
+	if err != nil {
+		return 0, fmt.Errorf("failed to list trashed tracks: %w", err)
+	}
+
+	err = s.Repo.RunInTx(func(tx *store.DB) error {
+		for _, track := range tracks {
+			if err := tx.DeleteTrack(track.ID); err != nil {
+				return fmt.Errorf("failed to delete trashed track %d: %w", track.ID, err)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	count := len(tracks)
+
+	for _, track := range tracks {
+		if track.FilePath == "" {
+			continue
+		}
+		root := s.Config.DownloadsRootOf(track.FilePath)
+		if err := storage.DeleteFromTrash(root, storage.LyricsSidecarPath(track.FilePath)); err != nil {
+			return count, fmt.Errorf("failed to delete trashed lyrics: %w", err)
+		}
+		if err := storage.DeleteFromTrash(root, track.FilePath); err != nil {
+			return count, fmt.Errorf("failed to delete trashed file: %w", err)
+		}
+	}
+
+	s.Logger.Info("Trash emptied", "tracks", count)
+	return count, nil
+}
+
 func (s *DownloadsService) EnqueueSyncJobs() (int, error) {
 	return s.enqueueSyncJobsByType(domain.JobTypeSyncHiFi)
 }
@@ -163,7 +541,27 @@ func (s *DownloadsService) EnqueueSyncMetadataJobs() (int, error) {
 	return s.enqueueSyncJobsByType(domain.JobTypeSyncMusicBrainz)
 }
 
+// EnqueueRetagJobs queues a re-tag of every completed track from the metadata already in
+// the database, without contacting any metadata provider.
+func (s *DownloadsService) EnqueueRetagJobs() (int, error) {
+	return s.enqueueSyncJobsByType(domain.JobTypeRetag)
+}
+
+// EnqueueSyncLyricsJobs queues a lyrics refetch of every completed track that has neither
+// lyrics nor subtitles.
+func (s *DownloadsService) EnqueueSyncLyricsJobs() (int, error) {
+	return s.enqueueSyncJobsFor(domain.JobTypeSyncLyrics, func(track *domain.Track) bool {
+		return track.Lyrics == "" && track.Subtitles == ""
+	})
+}
+
 func (s *DownloadsService) enqueueSyncJobsByType(jobType domain.JobType) (int, error) {
+	return s.enqueueSyncJobsFor(jobType, nil)
+}
+
+// enqueueSyncJobsFor queues a job of jobType for each completed track include accepts, or
+// for all of them when include is nil.
+func (s *DownloadsService) enqueueSyncJobsFor(jobType domain.JobType, include func(*domain.Track) bool) (int, error) {
 	tracks, err := s.Repo.ListAllCompletedTracks()
 	if err != nil {
 		return 0, fmt.Errorf("failed to list tracks: %w", err)
@@ -171,6 +569,9 @@ func (s *DownloadsService) enqueueSyncJobsByType(jobType domain.JobType) (int, e
 
 	count := 0
 	for _, track := range tracks {
+		if include != nil && !include(track) {
+			continue
+		}
 		existing, _ := s.Repo.GetActiveJobBySourceID(track.ProviderID, jobType)
 		if existing != nil {
 			continue
@@ -181,6 +582,7 @@ func (s *DownloadsService) enqueueSyncJobsByType(jobType domain.JobType) (int, e
 			Type:      jobType,
 			Status:    domain.JobStatusQueued,
 			SourceID:  sql.NullString{String: track.ProviderID, Valid: true},
+			RequestID: requestIDColumn(s.requestID),
 			CreatedAt: time.Now(),
 			UpdatedAt: time.Now(),
 		}
@@ -2,14 +2,18 @@ package app
 
 import (
 	"database/sql"
+	"fmt"
 	"os"
 	"path/filepath"
 	"testing"
 	"time"
 
+	"github.com/cesargomez89/navidrums/internal/config"
 	"github.com/cesargomez89/navidrums/internal/constants"
 	"github.com/cesargomez89/navidrums/internal/domain"
 	"github.com/cesargomez89/navidrums/internal/logger"
+	"github.com/cesargomez89/navidrums/internal/storage"
+	"github.com/cesargomez89/navidrums/internal/store"
 )
 
 func TestDownloadsService_EnqueueSyncFileJob(t *testing.T) {
@@ -17,7 +21,7 @@ func TestDownloadsService_EnqueueSyncFileJob(t *testing.T) {
 	defer cleanup()
 
 	log := logger.Default()
-	svc := NewDownloadsService(db, log)
+	svc := NewDownloadsService(db, &config.Config{}, log)
 
 	track := &domain.Track{
 		ProviderID: "sync_file_test",
@@ -53,12 +57,80 @@ func TestDownloadsService_EnqueueSyncFileJob(t *testing.T) {
 	}
 }
 
+func TestDownloadsService_CopyTrackMetadata(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	svc := NewDownloadsService(db, &config.Config{}, logger.Default())
+
+	source := &domain.Track{
+		ProviderID: "copy_source", Title: "Right", Artist: "Artist", Album: "Album", AlbumArtist: "Artist",
+		Year: 2001, ReleaseDate: "2001-05-01", Genre: "rock", Label: "Label", DiscNumber: 2, TotalDiscs: 2,
+		TrackNumber: 7, ISRC: "USABC0000001", Lyrics: "Source lyrics",
+		Status: domain.TrackStatusCompleted, CreatedAt: time.Now(), UpdatedAt: time.Now(),
+	}
+	target := &domain.Track{
+		ProviderID: "copy_target", Title: "Odd One", Artist: "Guest", Album: "Album (Deluxe)", AlbumArtist: "Various",
+		Year: 2020, Genre: "pop", DiscNumber: 1, TotalDiscs: 1, TrackNumber: 3, ISRC: "USABC0000002", Lyrics: "Target lyrics",
+		Status: domain.TrackStatusCompleted, FilePath: "/path/odd.flac", CreatedAt: time.Now(), UpdatedAt: time.Now(),
+	}
+	for _, tr := range []*domain.Track{source, target} {
+		if err := db.CreateTrack(tr); err != nil {
+			t.Fatalf("CreateTrack failed: %v", err)
+		}
+	}
+
+	if err := svc.CopyTrackMetadata(target, source); err != nil {
+		t.Fatalf("CopyTrackMetadata failed: %v", err)
+	}
+
+	got, err := db.GetTrackByID(target.ID)
+	if err != nil {
+		t.Fatalf("GetTrackByID failed: %v", err)
+	}
+	tests := []struct {
+		field string
+		got   interface{}
+		want  interface{}
+	}{
+		{"album", got.Album, "Album"},
+		{"album artist", got.AlbumArtist, "Artist"},
+		{"year", got.Year, 2001},
+		{"release date", got.ReleaseDate, "2001-05-01"},
+		{"genre", got.Genre, "rock"},
+		{"label", got.Label, "Label"},
+		{"disc number", got.DiscNumber, 2},
+		{"total discs", got.TotalDiscs, 2},
+		{"title kept", got.Title, "Odd One"},
+		{"artist kept", got.Artist, "Guest"},
+		{"track number kept", got.TrackNumber, 3},
+		{"ISRC kept", got.ISRC, "USABC0000002"},
+		{"lyrics kept", got.Lyrics, "Target lyrics"},
+		{"file path kept", got.FilePath, "/path/odd.flac"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.field, func(t *testing.T) {
+			if tt.got != tt.want {
+				t.Errorf("%s = %v, want %v", tt.field, tt.got, tt.want)
+			}
+		})
+	}
+
+	job, err := db.GetActiveJobBySourceID("copy_target", domain.JobTypeSyncFile)
+	if err != nil {
+		t.Fatalf("GetActiveJobBySourceID failed: %v", err)
+	}
+	if job == nil {
+		t.Error("expected a sync-file job for the target track")
+	}
+}
+
 func TestDownloadsService_EnqueueSyncMetadataJob(t *testing.T) {
 	db, cleanup := setupTestDB(t)
 	defer cleanup()
 
 	log := logger.Default()
-	svc := NewDownloadsService(db, log)
+	svc := NewDownloadsService(db, &config.Config{}, log)
 
 	track := &domain.Track{
 		ProviderID: "sync_metadata_test",
@@ -100,7 +172,7 @@ func TestDownloadsService_ListDownloads(t *testing.T) {
 	defer cleanup()
 
 	log := logger.Default()
-	svc := NewDownloadsService(db, log)
+	svc := NewDownloadsService(db, &config.Config{}, log)
 
 	// Create completed tracks
 	tracks := []*domain.Track{
@@ -116,7 +188,7 @@ func TestDownloadsService_ListDownloads(t *testing.T) {
 	}
 
 	// Test ListDownloads - should only return completed
-	downloads, _, err := svc.ListDownloads(1, 10)
+	downloads, _, err := svc.ListDownloads(store.TrackSort{}, 1, 10)
 	if err != nil {
 		t.Fatalf("ListDownloads failed: %v", err)
 	}
@@ -125,12 +197,74 @@ func TestDownloadsService_ListDownloads(t *testing.T) {
 	}
 }
 
+func TestDownloadsService_ExportDownloads(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	log := logger.Default()
+	svc := NewDownloadsService(db, &config.Config{}, log)
+
+	for i := 0; i < 5; i++ {
+		tr := &domain.Track{
+			ProviderID: fmt.Sprintf("export_%d", i),
+			Title:      fmt.Sprintf("Export %d", i),
+			Status:     domain.TrackStatusCompleted,
+			FilePath:   fmt.Sprintf("/path/export_%d.flac", i),
+			CreatedAt:  time.Now(),
+			UpdatedAt:  time.Now(),
+		}
+		if err := db.CreateTrack(tr); err != nil {
+			t.Fatalf("CreateTrack failed: %v", err)
+		}
+	}
+	queued := &domain.Track{ProviderID: "export_queued", Title: "Queued", Status: domain.TrackStatusQueued, CreatedAt: time.Now(), UpdatedAt: time.Now()}
+	if err := db.CreateTrack(queued); err != nil {
+		t.Fatalf("CreateTrack failed: %v", err)
+	}
+
+	tests := []struct {
+		name      string
+		pageSize  int
+		wantPages int
+	}{
+		{"single page", 10, 1},
+		{"exact pages", 5, 1},
+		{"multiple pages", 2, 3},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pages := 0
+			seen := make(map[string]bool)
+			err := svc.ExportDownloads(tt.pageSize, func(tracks []*domain.Track) error {
+				pages++
+				for _, tr := range tracks {
+					seen[tr.ProviderID] = true
+				}
+				return nil
+			})
+			if err != nil {
+				t.Fatalf("ExportDownloads failed: %v", err)
+			}
+			if pages != tt.wantPages {
+				t.Errorf("Expected %d pages, got %d", tt.wantPages, pages)
+			}
+			if len(seen) != 5 {
+				t.Errorf("Expected 5 exported tracks, got %d", len(seen))
+			}
+			if seen["export_queued"] {
+				t.Error("Queued track should not be exported")
+			}
+		})
+	}
+}
+
 func TestDownloadsService_SearchDownloads(t *testing.T) {
 	db, cleanup := setupTestDB(t)
 	defer cleanup()
 
 	log := logger.Default()
-	svc := NewDownloadsService(db, log)
+	svc := NewDownloadsService(db, &config.Config{}, log)
 
 	// Create completed tracks
 	tracks := []*domain.Track{
@@ -146,7 +280,7 @@ func TestDownloadsService_SearchDownloads(t *testing.T) {
 	}
 
 	// Search by title
-	results, _, err := svc.SearchDownloads("Hello", 1, 10)
+	results, _, err := svc.SearchDownloads("Hello", store.TrackSort{}, 1, 10)
 	if err != nil {
 		t.Fatalf("SearchDownloads failed: %v", err)
 	}
@@ -155,7 +289,7 @@ func TestDownloadsService_SearchDownloads(t *testing.T) {
 	}
 
 	// Search by artist
-	results, _, err = svc.SearchDownloads("Artist B", 1, 10)
+	results, _, err = svc.SearchDownloads("Artist B", store.TrackSort{}, 1, 10)
 	if err != nil {
 		t.Fatalf("SearchDownloads failed: %v", err)
 	}
@@ -164,7 +298,7 @@ func TestDownloadsService_SearchDownloads(t *testing.T) {
 	}
 
 	// Search by album
-	results, _, err = svc.SearchDownloads("Album Two", 1, 10)
+	results, _, err = svc.SearchDownloads("Album Two", store.TrackSort{}, 1, 10)
 	if err != nil {
 		t.Fatalf("SearchDownloads failed: %v", err)
 	}
@@ -173,7 +307,7 @@ func TestDownloadsService_SearchDownloads(t *testing.T) {
 	}
 
 	// No results
-	results, _, err = svc.SearchDownloads("Nonexistent", 1, 10)
+	results, _, err = svc.SearchDownloads("Nonexistent", store.TrackSort{}, 1, 10)
 	if err != nil {
 		t.Fatalf("SearchDownloads failed: %v", err)
 	}
@@ -190,7 +324,7 @@ func TestDownloadsService_DeleteDownload(t *testing.T) {
 	tmpDir := t.TempDir()
 
 	log := logger.Default()
-	svc := NewDownloadsService(db, log)
+	svc := NewDownloadsService(db, &config.Config{DownloadsDir: tmpDir}, log)
 
 	// Create a test file
 	testFile := filepath.Join(tmpDir, "test.flac")
@@ -235,10 +369,23 @@ func TestDownloadsService_DeleteDownload(t *testing.T) {
 		t.Fatalf("DeleteDownload failed: %v", err)
 	}
 
-	// Verify track is deleted
-	deletedTrack, _ := db.GetTrackByProviderID("delete_test")
-	if deletedTrack != nil {
-		t.Error("Expected track to be deleted")
+	// Verify track is trashed and hidden from downloads
+	deletedTrack, err := db.GetTrackByProviderID("delete_test")
+	if err != nil {
+		t.Fatalf("GetTrackByProviderID failed: %v", err)
+	}
+	if deletedTrack.DeletedAt == nil {
+		t.Error("Expected track to be marked as deleted")
+	}
+	if _, err := os.Stat(storage.TrashPath(tmpDir, folderFile)); err != nil {
+		t.Errorf("Expected file in trash: %v", err)
+	}
+	downloads, _, err := svc.ListDownloads(store.TrackSort{}, 1, 10)
+	if err != nil {
+		t.Fatalf("ListDownloads failed: %v", err)
+	}
+	if len(downloads) != 0 {
+		t.Errorf("Expected trashed track to be hidden, got %d downloads", len(downloads))
 	}
 
 	// Test deleting non-existent provider - returns error from DB
@@ -254,7 +401,7 @@ func TestDownloadsService_DeleteDownload_CascadeCleanup(t *testing.T) {
 
 	tmpDir := t.TempDir()
 	log := logger.Default()
-	svc := NewDownloadsService(db, log)
+	svc := NewDownloadsService(db, &config.Config{DownloadsDir: tmpDir}, log)
 
 	artistDir := filepath.Join(tmpDir, "Artist", "2020 - Album")
 	if err := os.MkdirAll(artistDir, constants.DirPermissions); err != nil {
@@ -305,12 +452,240 @@ func TestDownloadsService_DeleteDownload_CascadeCleanup(t *testing.T) {
 	}
 }
 
+func TestDownloadsService_RestoreDownload(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	tmpDir := t.TempDir()
+	log := logger.Default()
+	svc := NewDownloadsService(db, &config.Config{DownloadsDir: tmpDir}, log)
+
+	albumDir := filepath.Join(tmpDir, "Artist", "2020 - Album")
+	if err := os.MkdirAll(albumDir, constants.DirPermissions); err != nil {
+		t.Fatalf("MkdirAll failed: %v", err)
+	}
+	trackFile := filepath.Join(albumDir, "1-01 Track.flac")
+	coverFile := filepath.Join(albumDir, constants.CoverFileName)
+	if err := os.WriteFile(trackFile, []byte("audio"), constants.FilePermissions); err != nil {
+		t.Fatalf("WriteFile failed: %v", err)
+	}
+	if err := os.WriteFile(coverFile, []byte("image"), constants.FilePermissions); err != nil {
+		t.Fatalf("WriteFile failed: %v", err)
+	}
+
+	track := &domain.Track{
+		ProviderID: "restore_test",
+		Title:      "Track",
+		Artist:     "Artist",
+		Album:      "Album",
+		Status:     domain.TrackStatusCompleted,
+		FilePath:   trackFile,
+		CreatedAt:  time.Now(),
+		UpdatedAt:  time.Now(),
+	}
+	if err := db.CreateTrack(track); err != nil {
+		t.Fatalf("CreateTrack failed: %v", err)
+	}
+
+	if err := svc.DeleteDownload("restore_test"); err != nil {
+		t.Fatalf("DeleteDownload failed: %v", err)
+	}
+	if _, err := os.Stat(trackFile); !os.IsNotExist(err) {
+		t.Fatal("Expected track file to be moved out of the library")
+	}
+
+	trashed, total, err := svc.FilterDownloads("trash", store.TrackSort{}, 1, 10)
+	if err != nil {
+		t.Fatalf("FilterDownloads failed: %v", err)
+	}
+	if total != 1 || len(trashed) != 1 {
+		t.Fatalf("Expected 1 trashed track, got %d (total %d)", len(trashed), total)
+	}
+
+	if err := svc.RestoreDownload("restore_test"); err != nil {
+		t.Fatalf("RestoreDownload failed: %v", err)
+	}
+
+	restored, err := db.GetTrackByProviderID("restore_test")
+	if err != nil {
+		t.Fatalf("GetTrackByProviderID failed: %v", err)
+	}
+	if restored.DeletedAt != nil {
+		t.Error("Expected DeletedAt to be cleared")
+	}
+	data, err := os.ReadFile(restored.FilePath)
+	if err != nil {
+		t.Fatalf("Expected restored file at %s: %v", restored.FilePath, err)
+	}
+	if string(data) != "audio" {
+		t.Errorf("Expected restored content %q, got %q", "audio", data)
+	}
+	if _, err := os.Stat(coverFile); err != nil {
+		t.Errorf("Expected cover to be restored: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(tmpDir, constants.TrashDir, "Artist")); !os.IsNotExist(err) {
+		t.Error("Expected trash folders to be pruned after restore")
+	}
+
+	downloads, _, err := svc.ListDownloads(store.TrackSort{}, 1, 10)
+	if err != nil {
+		t.Fatalf("ListDownloads failed: %v", err)
+	}
+	if len(downloads) != 1 {
+		t.Errorf("Expected restored track in downloads, got %d", len(downloads))
+	}
+}
+
+func TestDownloadsService_EmptyTrash(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	tmpDir := t.TempDir()
+	log := logger.Default()
+	svc := NewDownloadsService(db, &config.Config{DownloadsDir: tmpDir}, log)
+
+	trackFile := filepath.Join(tmpDir, "Artist", "Album", "track.flac")
+	if err := os.MkdirAll(filepath.Dir(trackFile), constants.DirPermissions); err != nil {
+		t.Fatalf("MkdirAll failed: %v", err)
+	}
+	if err := os.WriteFile(trackFile, []byte("audio"), constants.FilePermissions); err != nil {
+		t.Fatalf("WriteFile failed: %v", err)
+	}
+
+	track := &domain.Track{
+		ProviderID: "empty_trash_test",
+		Title:      "Track",
+		Status:     domain.TrackStatusCompleted,
+		FilePath:   trackFile,
+		CreatedAt:  time.Now(),
+		UpdatedAt:  time.Now(),
+	}
+	if err := db.CreateTrack(track); err != nil {
+		t.Fatalf("CreateTrack failed: %v", err)
+	}
+	if err := svc.DeleteDownload("empty_trash_test"); err != nil {
+		t.Fatalf("DeleteDownload failed: %v", err)
+	}
+
+	count, err := svc.EmptyTrash()
+	if err != nil {
+		t.Fatalf("EmptyTrash failed: %v", err)
+	}
+	if count != 1 {
+		t.Errorf("Expected 1 track removed, got %d", count)
+	}
+	if _, err := db.GetTrackByProviderID("empty_trash_test"); err == nil {
+		t.Error("Expected track record to be removed")
+	}
+	if _, err := os.Stat(filepath.Join(tmpDir, constants.TrashDir)); !os.IsNotExist(err) {
+		t.Error("Expected trash folder to be removed")
+	}
+}
+
+func TestDownloadsService_EmptyTrashKeepsUnlistedFiles(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	tmpDir := t.TempDir()
+	svc := NewDownloadsService(db, &config.Config{DownloadsDir: tmpDir}, logger.Default())
+
+	write := func(path string) {
+		t.Helper()
+		if err := os.MkdirAll(filepath.Dir(path), constants.DirPermissions); err != nil {
+			t.Fatalf("MkdirAll failed: %v", err)
+		}
+		if err := os.WriteFile(path, []byte("audio"), constants.FilePermissions); err != nil {
+			t.Fatalf("WriteFile failed: %v", err)
+		}
+	}
+	trackFile := filepath.Join(tmpDir, "Artist", "Album", "01.flac")
+	write(trackFile)
+	track := &domain.Track{ProviderID: "listed", Title: "Listed", Status: domain.TrackStatusCompleted, FilePath: trackFile}
+	if err := db.CreateTrack(track); err != nil {
+		t.Fatalf("CreateTrack failed: %v", err)
+	}
+	if err := svc.DeleteDownload("listed"); err != nil {
+		t.Fatalf("DeleteDownload failed: %v", err)
+	}
+	// A file trashed by a DeleteDownload racing with EmptyTrash, after the tracks were listed.
+	late := storage.TrashPath(tmpDir, filepath.Join(tmpDir, "Artist", "Album", "02.flac"))
+	write(late)
+
+	if _, err := svc.EmptyTrash(); err != nil {
+		t.Fatalf("EmptyTrash failed: %v", err)
+	}
+	if _, err := os.Stat(storage.TrashPath(tmpDir, trackFile)); !os.IsNotExist(err) {
+		t.Error("Expected the listed track's file to be removed")
+	}
+	if _, err := os.Stat(late); err != nil {
+		t.Errorf("Expected the unlisted trashed file to be kept: %v", err)
+	}
+}
+
+func TestDownloadsService_ListRecent(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	svc := NewDownloadsService(db, &config.Config{}, logger.Default())
+
+	now := time.Now()
+	year, month, day := now.Date()
+	today := time.Date(year, month, day, 0, 0, 0, 0, now.Location())
+	for i, completed := range []time.Time{
+		now,
+		today,
+		today.AddDate(0, 0, -1).Add(12 * time.Hour),
+		today.AddDate(0, 0, -3).Add(time.Hour),
+	} {
+		track := &domain.Track{
+			ProviderID:  fmt.Sprintf("recent_%d", i),
+			Status:      domain.TrackStatusCompleted,
+			CompletedAt: &completed,
+			CreatedAt:   now,
+			UpdatedAt:   now,
+		}
+		if err := db.CreateTrack(track); err != nil {
+			t.Fatalf("CreateTrack failed: %v", err)
+		}
+		if err := db.UpdateTrack(track); err != nil {
+			t.Fatalf("UpdateTrack failed: %v", err)
+		}
+	}
+
+	tests := []struct {
+		name  string
+		days  int
+		dates []time.Time
+		sizes []int
+	}{
+		{"today only", 1, []time.Time{today}, []int{2}},
+		{"two days", 2, []time.Time{today, today.AddDate(0, 0, -1)}, []int{2, 1}},
+		{"default window", 0, []time.Time{today, today.AddDate(0, 0, -1), today.AddDate(0, 0, -3)}, []int{2, 1, 1}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			groups, err := svc.ListRecent(tt.days)
+			if err != nil {
+				t.Fatalf("ListRecent failed: %v", err)
+			}
+			if len(groups) != len(tt.dates) {
+				t.Fatalf("got %d days, want %d", len(groups), len(tt.dates))
+			}
+			for i, g := range groups {
+				if !g.Date.Equal(tt.dates[i]) || len(g.Tracks) != tt.sizes[i] {
+					t.Errorf("day %d = %v with %d tracks, want %v with %d", i, g.Date, len(g.Tracks), tt.dates[i], tt.sizes[i])
+				}
+			}
+		})
+	}
+}
+
 func TestDownloadsService_EnqueueSyncHiFiJob(t *testing.T) {
 	db, cleanup := setupTestDB(t)
 	defer cleanup()
 
 	log := logger.Default()
-	svc := NewDownloadsService(db, log)
+	svc := NewDownloadsService(db, &config.Config{}, log)
 
 	err := svc.EnqueueSyncHiFiJob("hifi_test")
 	if err != nil {
@@ -334,7 +709,7 @@ func TestDownloadsService_EnqueueSyncJobs(t *testing.T) {
 	defer cleanup()
 
 	log := logger.Default()
-	svc := NewDownloadsService(db, log)
+	svc := NewDownloadsService(db, &config.Config{}, log)
 
 	// Create some completed tracks
 	tracks := []*domain.Track{
@@ -383,7 +758,7 @@ func TestDownloadsService_EnqueueSyncMetadataJobs(t *testing.T) {
 	defer cleanup()
 
 	log := logger.Default()
-	svc := NewDownloadsService(db, log)
+	svc := NewDownloadsService(db, &config.Config{}, log)
 
 	tracks := []*domain.Track{
 		{ProviderID: "m1", Title: "M1", Artist: "A", Album: "A", Status: domain.TrackStatusCompleted, FilePath: "/p/1", CreatedAt: time.Now(), UpdatedAt: time.Now()},
@@ -429,3 +804,43 @@ func TestDownloadsService_EnqueueSyncMetadataJobs(t *testing.T) {
 		t.Fatal("Existing MusicBrainz job for m1 should still exist")
 	}
 }
+
+func TestDownloadsService_EnqueueRetagJobs(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	svc := NewDownloadsService(db, &config.Config{}, logger.Default())
+
+	tracks := []*domain.Track{
+		{ProviderID: "r1", Title: "R1", Artist: "A", Album: "A", Status: domain.TrackStatusCompleted, FilePath: "/p/1", CreatedAt: time.Now(), UpdatedAt: time.Now()},
+		{ProviderID: "r2", Title: "R2", Artist: "A", Album: "A", Status: domain.TrackStatusCompleted, FilePath: "/p/2", CreatedAt: time.Now(), UpdatedAt: time.Now()},
+		{ProviderID: "r3", Title: "R3", Artist: "A", Album: "A", Status: domain.TrackStatusFailed, CreatedAt: time.Now(), UpdatedAt: time.Now()},
+	}
+	for _, tr := range tracks {
+		if err := db.CreateTrack(tr); err != nil {
+			t.Fatalf("CreateTrack failed: %v", err)
+		}
+	}
+
+	count, err := svc.EnqueueRetagJobs()
+	if err != nil {
+		t.Fatalf("EnqueueRetagJobs failed: %v", err)
+	}
+	if count != 2 {
+		t.Errorf("Expected 2 jobs enqueued, got %d", count)
+	}
+
+	// A second run must not duplicate jobs that are still queued.
+	count, err = svc.EnqueueRetagJobs()
+	if err != nil {
+		t.Fatalf("EnqueueRetagJobs failed: %v", err)
+	}
+	if count != 0 {
+		t.Errorf("Expected no new jobs while retags are queued, got %d", count)
+	}
+
+	job, _ := db.GetActiveJobBySourceID("r1", domain.JobTypeRetag)
+	if job == nil {
+		t.Fatal("Expected retag job for r1")
+	}
+}
@@ -0,0 +1,39 @@
+package app
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/cesargomez89/navidrums/internal/config"
+	"github.com/cesargomez89/navidrums/internal/domain"
+	"github.com/cesargomez89/navidrums/internal/store"
+)
+
+// FindDuplicates lists the groups of downloaded tracks that look like copies of one
+// recording. Each group's best copy comes first: the highest quality tier, then bit depth,
+// sample rate and bitrate, so the others are the ones worth deleting.
+func (s *DownloadsService) FindDuplicates() ([]store.DuplicateGroup, error) {
+	groups, err := s.Repo.ListDuplicateTracks()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list duplicate tracks: %w", err)
+	}
+	for _, g := range groups {
+		sort.SliceStable(g.Tracks, func(i, j int) bool {
+			return betterCopy(g.Tracks[i], g.Tracks[j])
+		})
+	}
+	return groups, nil
+}
+
+func betterCopy(a, b *domain.Track) bool {
+	if ra, rb := config.QualityRank(a.AudioQuality), config.QualityRank(b.AudioQuality); ra != rb {
+		return ra > rb
+	}
+	if a.BitDepth != b.BitDepth {
+		return a.BitDepth > b.BitDepth
+	}
+	if a.SampleRate != b.SampleRate {
+		return a.SampleRate > b.SampleRate
+	}
+	return a.Bitrate > b.Bitrate
+}
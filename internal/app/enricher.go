@@ -4,6 +4,7 @@ import (
 	"context"
 	"fmt"
 	"log/slog"
+	"math"
 	"strconv"
 	"strings"
 
@@ -66,15 +67,41 @@ func coalesceStringSlice(values ...[]string) []string {
 
 // -- Main Enrichment Logic --
 
+// EnrichComplete refreshes a track from the provider it came from, then MusicBrainz and
+// lyrics sources.
 func (e *MetadataEnricher) EnrichComplete(ctx context.Context, track *domain.Track, logger *slog.Logger) {
-	e.enrichComplete(ctx, track, e.providerManager.GetMetadataProvider(), logger)
+	e.enrichComplete(ctx, track, e.providerManager.GetSourceProvider(track.SourceProvider), logger)
 }
 
 func (e *MetadataEnricher) EnrichCompleteFromDownloadProvider(ctx context.Context, track *domain.Track, logger *slog.Logger) {
 	e.enrichComplete(ctx, track, e.providerManager.GetDownloadProvider(), logger)
 }
 
+// EnrichMetadata is EnrichComplete without the lyrics, for callers that fetch them
+// separately with FetchLyricsWithFallback.
+func (e *MetadataEnricher) EnrichMetadata(ctx context.Context, track *domain.Track, logger *slog.Logger) {
+	e.enrichMetadata(ctx, track, e.providerManager.GetSourceProvider(track.SourceProvider), true, logger)
+}
+
+// EnrichProviderMetadata is EnrichMetadata without the MusicBrainz lookup, for downloads
+// whose provider metadata is trusted as it is.
+func (e *MetadataEnricher) EnrichProviderMetadata(ctx context.Context, track *domain.Track, logger *slog.Logger) {
+	e.enrichMetadata(ctx, track, e.providerManager.GetSourceProvider(track.SourceProvider), false, logger)
+}
+
+// FetchLyricsWithFallback fills in missing lyrics from the track's source provider, then
+// the lyrics fallback.
+func (e *MetadataEnricher) FetchLyricsWithFallback(ctx context.Context, track *domain.Track, logger *slog.Logger) {
+	e.fetchLyricsWithFallback(ctx, track, e.providerManager.GetSourceProvider(track.SourceProvider), logger)
+}
+
 func (e *MetadataEnricher) enrichComplete(ctx context.Context, track *domain.Track, hifiProvider catalog.Provider, logger *slog.Logger) {
+	e.enrichMetadata(ctx, track, hifiProvider, true, logger)
+	e.fetchLyricsWithFallback(ctx, track, hifiProvider, logger)
+	logger.Debug("EnrichComplete: done", "track_year", track.Year)
+}
+
+func (e *MetadataEnricher) enrichMetadata(ctx context.Context, track *domain.Track, hifiProvider catalog.Provider, withMusicBrainz bool, logger *slog.Logger) {
 	logger.Debug("EnrichComplete: starting", "track_year", track.Year, "track_provider_id", track.ProviderID)
 	// 1. Hi-Fi metadata refresh
 	if err := e.enrichFromProvider(ctx, track, hifiProvider, logger); err != nil {
@@ -84,20 +111,15 @@ func (e *MetadataEnricher) enrichComplete(ctx context.Context, track *domain.Tra
 	logger.Debug("EnrichComplete: after Hi-Fi", "track_year", track.Year)
 
 	// 2. MusicBrainz Gap Fill
-	if err := e.EnrichTrack(ctx, track, logger); err != nil {
-		logger.Warn("MusicBrainz enrichment failed", "isrc", track.ISRC, "error", err)
+	if withMusicBrainz {
+		if err := e.EnrichTrack(ctx, track, logger); err != nil {
+			logger.Warn("MusicBrainz enrichment failed", "isrc", track.ISRC, "error", err)
+		}
 	}
 
 	logger.Debug("EnrichComplete: after MusicBrainz", "track_year", track.Year)
 
-	// 3. Lyrics
-	e.fetchLyrics(ctx, track, hifiProvider, logger)
-	if e.lyricsFallback != nil && (track.Lyrics == "" || track.Subtitles == "") {
-		e.lyricsFallback.Fetch(ctx, track, logger)
-	}
-	logger.Debug("EnrichComplete: done", "track_year", track.Year)
-
-	// 4. Final Fallbacks
+	// 3. Final Fallbacks
 	if track.AlbumArtist == "" && len(track.Artists) > 0 {
 		track.AlbumArtist = track.Artists[0]
 	}
@@ -112,8 +134,47 @@ func (e *MetadataEnricher) enrichComplete(ctx context.Context, track *domain.Tra
 	}
 }
 
+func (e *MetadataEnricher) fetchLyricsWithFallback(ctx context.Context, track *domain.Track, provider catalog.Provider, logger *slog.Logger) {
+	e.fetchLyrics(ctx, track, provider, logger)
+	if e.lyricsFallback != nil && (track.Lyrics == "" || track.Subtitles == "") {
+		e.lyricsFallback.Fetch(ctx, track, logger)
+	}
+}
+
 func (e *MetadataEnricher) FetchLyrics(ctx context.Context, track *domain.Track, logger *slog.Logger) {
-	e.fetchLyrics(ctx, track, e.providerManager.GetMetadataProvider(), logger)
+	e.fetchLyrics(ctx, track, e.providerManager.GetSourceProvider(track.SourceProvider), logger)
+}
+
+// RefetchLyrics asks the track's source provider for lyrics again, then the lyrics
+// fallback when the provider has none, and replaces the lyric fields with whatever comes
+// back. It reports whether the track changed; no other field is touched.
+func (e *MetadataEnricher) RefetchLyrics(ctx context.Context, track *domain.Track, logger *slog.Logger) bool {
+	return e.refetchLyrics(ctx, track, e.providerManager.GetSourceProvider(track.SourceProvider), logger)
+}
+
+func (e *MetadataEnricher) refetchLyrics(ctx context.Context, track *domain.Track, provider catalog.Provider, logger *slog.Logger) bool {
+	lyrics, subtitles, err := provider.GetLyrics(ctx, track.ProviderID)
+	if err != nil {
+		logger.Debug("Failed to fetch lyrics", "error", err)
+	}
+	if lyrics == "" && subtitles == "" && e.lyricsFallback != nil {
+		// The fallback only fills empty fields, so give it a copy without the old lyrics.
+		probe := *track
+		probe.Lyrics, probe.Subtitles = "", ""
+		e.lyricsFallback.Fetch(ctx, &probe, logger)
+		lyrics, subtitles = probe.Lyrics, probe.Subtitles
+	}
+
+	changed := false
+	if lyrics != "" && lyrics != track.Lyrics {
+		track.Lyrics = lyrics
+		changed = true
+	}
+	if subtitles != "" && subtitles != track.Subtitles {
+		track.Subtitles = subtitles
+		changed = true
+	}
+	return changed
 }
 
 func (e *MetadataEnricher) fetchLyrics(ctx context.Context, track *domain.Track, provider catalog.Provider, logger *slog.Logger) {
@@ -134,7 +195,7 @@ func (e *MetadataEnricher) fetchLyrics(ctx context.Context, track *domain.Track,
 }
 
 func (e *MetadataEnricher) EnrichFromHiFi(ctx context.Context, track *domain.Track, logger *slog.Logger) error {
-	return e.enrichFromProvider(ctx, track, e.providerManager.GetMetadataProvider(), logger)
+	return e.enrichFromProvider(ctx, track, e.providerManager.GetSourceProvider(track.SourceProvider), logger)
 }
 
 func (e *MetadataEnricher) enrichFromProvider(ctx context.Context, track *domain.Track, provider catalog.Provider, logger *slog.Logger) error {
@@ -165,8 +226,12 @@ func (e *MetadataEnricher) enrichFromProvider(ctx context.Context, track *domain
 		hasBasicMetadata = ct.TotalTracks > 0 && ct.TotalDiscs > 0 &&
 			ct.ReleaseDate != "" && ct.Genre != "" && ct.Label != ""
 	}
+	// A track queued by a container job takes its album-level fields from the album
+	// record, so siblings get identical album, album artist, totals, date and album gain
+	// tags however their own provider data varies; the album job cached the record.
+	inContainer := track.ParentJobID != ""
 
-	if albumID != "" && (needsAlbumArtist || !hasBasicMetadata) {
+	if albumID != "" && (needsAlbumArtist || !hasBasicMetadata || inContainer) {
 		album, err = provider.GetAlbum(ctx, albumID)
 		if err != nil {
 			logger.Debug("Failed to fetch album metadata", "album_id", albumID, "error", err)
@@ -178,6 +243,17 @@ func (e *MetadataEnricher) enrichFromProvider(ctx context.Context, track *domain
 	return nil
 }
 
+// AdvertisedQuality returns the audio quality the track's source provider currently
+// offers it at, or "" when the provider can't be asked.
+func (e *MetadataEnricher) AdvertisedQuality(ctx context.Context, track *domain.Track, logger *slog.Logger) string {
+	ct, err := e.providerManager.GetSourceProvider(track.SourceProvider).GetTrack(ctx, track.ProviderID)
+	if err != nil {
+		logger.Warn("Failed to fetch the provider's quality", "error", err)
+		return ""
+	}
+	return ct.AudioQuality
+}
+
 func (e *MetadataEnricher) UpdateTrackFromCatalog(track *domain.Track, ct *domain.CatalogTrack, logger *slog.Logger) {
 	e.mergeHiFi(track, ct, nil)
 }
@@ -207,6 +283,9 @@ func (e *MetadataEnricher) mergeHiFi(track *domain.Track, ct *domain.CatalogTrac
 	track.ReleaseDate = coalesceString(album.ReleaseDate, ct.ReleaseDate, track.ReleaseDate)
 	track.AlbumArtURL = coalesceString(album.AlbumArtURL, ct.AlbumArtURL, track.AlbumArtURL)
 	track.Barcode = coalesceString(album.UPC, track.Barcode)
+	if gain, peak, ok := albumReplayGain(album.Tracks); ok {
+		track.AlbumGain, track.AlbumPeak = gain, peak
+	}
 
 	// Infer year from release date before checking explicit years
 	e.setYearFromReleaseDate(track)
@@ -236,10 +315,13 @@ func (e *MetadataEnricher) mergeHiFi(track *domain.Track, ct *domain.CatalogTrac
 	track.Version = coalesceString(ct.Version, track.Version)
 	track.Description = coalesceString(ct.Description, track.Description)
 	track.URL = coalesceString(ct.URL, track.URL)
-	track.AudioQuality = coalesceString(ct.AudioQuality, track.AudioQuality)
-
-	if len(track.AudioModes) == 0 && len(ct.AudioModes) > 0 {
-		track.AudioModes = ct.AudioModes
+	// A downloaded file keeps the quality ReconcileAudioQuality read from it; the
+	// provider's is only what it advertises.
+	if track.FilePath == "" || DeliveredQuality(track.SampleRate, track.BitDepth, track.Bitrate) == "" {
+		track.AudioQuality = coalesceString(ct.AudioQuality, track.AudioQuality)
+		if len(track.AudioModes) == 0 && len(ct.AudioModes) > 0 {
+			track.AudioModes = ct.AudioModes
+		}
 	}
 	if !track.Explicit && ct.ExplicitLyrics {
 		track.Explicit = true
@@ -255,6 +337,26 @@ func (e *MetadataEnricher) mergeHiFi(track *domain.Track, ct *domain.CatalogTrac
 	}
 }
 
+// albumReplayGain derives an album's ReplayGain from its tracks': the gain of their
+// duration-weighted mean loudness, and the loudest peak. It needs the gain, peak and
+// duration of every track, since a missing one would skew the album value.
+func albumReplayGain(tracks []domain.CatalogTrack) (gain, peak float64, ok bool) {
+	if len(tracks) == 0 {
+		return 0, 0, false
+	}
+	var energy, total float64
+	for _, t := range tracks {
+		if t.ReplayGain == 0 || t.Peak == 0 || t.Duration <= 0 {
+			return 0, 0, false
+		}
+		d := float64(t.Duration)
+		energy += d * math.Pow(10, -t.ReplayGain/10)
+		total += d
+		peak = math.Max(peak, t.Peak)
+	}
+	return -10 * math.Log10(energy/total), peak, true
+}
+
 func (e *MetadataEnricher) EnrichTrack(ctx context.Context, track *domain.Track, logger *slog.Logger) error {
 	recordingID := ""
 	if track.RecordingID != nil {
@@ -299,12 +401,16 @@ func (e *MetadataEnricher) mergeMusicBrainz(track *domain.Track, mb *musicbrainz
 		logger.Debug("Setting year from MusicBrainz", "old_year", track.Year, "new_year", mb.Year)
 		track.Year = mb.Year
 	}
+	track.OriginalDate = coalesceString(track.OriginalDate, mb.OriginalDate)
+	track.OriginalYear = coalesceInt(track.OriginalYear, mb.OriginalYear)
 	track.Barcode = coalesceString(track.Barcode, mb.Barcode)
 	track.CatalogNumber = coalesceString(track.CatalogNumber, mb.CatalogNumber)
 	track.ReleaseType = coalesceString(track.ReleaseType, mb.ReleaseType)
 	track.ISRC = coalesceString(track.ISRC, mb.ISRC)
 	track.Label = coalesceString(track.Label, mb.Label)
 	track.ReleaseID = coalesceString(track.ReleaseID, mb.ReleaseID)
+	track.MBAlbumID = coalesceString(track.MBAlbumID, mb.AlbumID)
+	track.ReleaseTrackID = coalesceString(track.ReleaseTrackID, mb.ReleaseTrackID)
 	track.ArtistIDs = coalesceStringSlice(track.ArtistIDs, mb.ArtistIDs)
 	track.AlbumArtistIDs = coalesceStringSlice(track.AlbumArtistIDs, mb.AlbumArtistIDs)
 	track.AlbumArtists = coalesceStringSlice(track.AlbumArtists, mb.AlbumArtists)
@@ -315,7 +421,13 @@ func (e *MetadataEnricher) mergeMusicBrainz(track *domain.Track, mb *musicbrainz
 	if track.PathArtist == "" && len(mb.AlbumArtists) > 0 {
 		track.PathArtist = mb.AlbumArtists[0]
 	}
+	track.ArtistSort = coalesceString(track.ArtistSort, mb.ArtistSort)
+	track.AlbumArtistSort = coalesceString(track.AlbumArtistSort, mb.AlbumArtistSort)
 	track.Composer = coalesceString(track.Composer, mb.Composer)
+	track.Producer = coalesceString(track.Producer, strings.Join(mb.Producers, ", "))
+	track.Engineer = coalesceString(track.Engineer, strings.Join(mb.Engineers, ", "))
+	track.Mixer = coalesceString(track.Mixer, strings.Join(mb.Mixers, ", "))
+	track.Performers = coalesceStringSlice(track.Performers, mb.Performers)
 	track.Genre = coalesceString(track.Genre, mb.Genre)
 	if len(track.Tags) == 0 && len(mb.Tags) > 0 {
 		track.Tags = mb.Tags
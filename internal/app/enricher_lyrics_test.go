@@ -0,0 +1,80 @@
+package app
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/cesargomez89/navidrums/internal/catalog"
+	"github.com/cesargomez89/navidrums/internal/domain"
+	"github.com/cesargomez89/navidrums/internal/logger"
+)
+
+// lyricsProvider returns fixed lyrics; any other Provider method panics.
+type lyricsProvider struct {
+	catalog.Provider
+	lyrics    string
+	subtitles string
+}
+
+func (p *lyricsProvider) GetLyrics(ctx context.Context, trackID string) (string, string, error) {
+	return p.lyrics, p.subtitles, nil
+}
+
+func TestMetadataEnricher_RefetchLyrics(t *testing.T) {
+	now := time.Now()
+	base := domain.Track{
+		ID:           1,
+		ProviderID:   "t1",
+		Title:        "Title",
+		Artist:       "Artist",
+		Album:        "Album",
+		Genre:        "Rock",
+		Year:         2020,
+		ISRC:         "USABC0000001",
+		FilePath:     "/music/track.flac",
+		FileHash:     "abc",
+		AudioQuality: "LOSSLESS",
+		Status:       domain.TrackStatusCompleted,
+		CreatedAt:    now,
+		UpdatedAt:    now,
+	}
+	withLyrics := base
+	withLyrics.Lyrics = "Old lyrics"
+	withLyrics.Subtitles = "[00:00.00] Old lyrics"
+
+	tests := []struct {
+		name          string
+		track         domain.Track
+		lyrics        string
+		subtitles     string
+		wantChanged   bool
+		wantLyrics    string
+		wantSubtitles string
+	}{
+		{"adds missing lyrics", base, "New lyrics", "[00:00.00] New lyrics", true, "New lyrics", "[00:00.00] New lyrics"},
+		{"replaces changed lyrics", withLyrics, "New lyrics", "", true, "New lyrics", "[00:00.00] Old lyrics"},
+		{"same lyrics", withLyrics, "Old lyrics", "[00:00.00] Old lyrics", false, "Old lyrics", "[00:00.00] Old lyrics"},
+		{"provider has none", withLyrics, "", "", false, "Old lyrics", "[00:00.00] Old lyrics"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			enricher := NewMetadataEnricher(nil, nil, nil)
+			provider := &lyricsProvider{lyrics: tt.lyrics, subtitles: tt.subtitles}
+			track := tt.track
+
+			changed := enricher.refetchLyrics(context.Background(), &track, provider, logger.Default().Logger)
+			if changed != tt.wantChanged {
+				t.Errorf("refetchLyrics() = %v, want %v", changed, tt.wantChanged)
+			}
+
+			want := tt.track
+			want.Lyrics = tt.wantLyrics
+			want.Subtitles = tt.wantSubtitles
+			if !reflect.DeepEqual(track, want) {
+				t.Errorf("track = %+v, want %+v", track, want)
+			}
+		})
+	}
+}
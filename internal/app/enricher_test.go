@@ -196,3 +196,38 @@ func TestMetadataEnricher_EnrichTrack(t *testing.T) {
 		}
 	})
 }
+
+func TestMetadataEnricher_UpdateTrackFromCatalog_AudioQuality(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(os.Stdout, nil))
+	enricher := app.NewMetadataEnricher(nil, nil, nil)
+	ct := &domain.CatalogTrack{AudioQuality: "HI_RES_LOSSLESS", AudioModes: "DOLBY_ATMOS"}
+
+	tests := []struct {
+		name      string
+		track     domain.Track
+		wantQual  string
+		wantModes string
+	}{
+		{"not downloaded yet", domain.Track{}, "HI_RES_LOSSLESS", "DOLBY_ATMOS"},
+		{
+			"downloaded file keeps its quality",
+			domain.Track{FilePath: "/music/a.flac", SampleRate: 44100, BitDepth: 16, AudioQuality: "LOSSLESS", AudioModes: "STEREO"},
+			"LOSSLESS", "STEREO",
+		},
+		{
+			"file without audio properties",
+			domain.Track{FilePath: "/music/a.flac", AudioQuality: "LOSSLESS"},
+			"HI_RES_LOSSLESS", "DOLBY_ATMOS",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			track := tt.track
+			enricher.UpdateTrackFromCatalog(&track, ct, logger)
+			if track.AudioQuality != tt.wantQual || track.AudioModes != tt.wantModes {
+				t.Errorf("quality = %q %q, want %q %q", track.AudioQuality, track.AudioModes, tt.wantQual, tt.wantModes)
+			}
+		})
+	}
+}
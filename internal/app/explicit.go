@@ -0,0 +1,50 @@
+package app
+
+import (
+	"regexp"
+	"strings"
+
+	"github.com/cesargomez89/navidrums/internal/config"
+	"github.com/cesargomez89/navidrums/internal/domain"
+)
+
+// versionMarker matches the "(Clean)", "[Explicit]" or " - Clean Version" providers add to
+// the title of one version of a track.
+var versionMarker = regexp.MustCompile(`(?i)\s*(?:[(\[]\s*(?:clean|explicit|edited)(?:\s+version)?\s*[)\]]|-\s*(?:clean|explicit|edited)(?:\s+version)?\s*$)`)
+
+// versionKey identifies a song across its explicit and clean versions: the title and
+// version without clean/explicit markers, and the artist, compared without case.
+func versionKey(t domain.CatalogTrack) string {
+	title := versionMarker.ReplaceAllString(t.Title, "")
+	version := versionMarker.ReplaceAllString("("+t.Version+")", "")
+	if version == "()" {
+		version = ""
+	}
+	return strings.ToLower(strings.TrimSpace(title)) + "\x1f" +
+		strings.ToLower(strings.TrimSpace(version)) + "\x1f" +
+		strings.ToLower(strings.TrimSpace(t.Artist))
+}
+
+// PreferCleanTracks drops the explicit tracks of an album that also lists a clean version
+// of them, when PREFER_CLEAN_VERSIONS is set, returning the tracks kept in album order and
+// how many were dropped. Explicit tracks without a clean version are kept.
+func PreferCleanTracks(cfg *config.Config, tracks []domain.CatalogTrack) ([]domain.CatalogTrack, int) {
+	if cfg == nil || !cfg.PreferCleanVersions {
+		return tracks, 0
+	}
+	hasClean := make(map[string]bool)
+	for _, t := range tracks {
+		if !t.ExplicitLyrics {
+			hasClean[versionKey(t)] = true
+		}
+	}
+
+	kept := make([]domain.CatalogTrack, 0, len(tracks))
+	for _, t := range tracks {
+		if t.ExplicitLyrics && hasClean[versionKey(t)] {
+			continue
+		}
+		kept = append(kept, t)
+	}
+	return kept, len(tracks) - len(kept)
+}
@@ -0,0 +1,48 @@
+package app
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/cesargomez89/navidrums/internal/config"
+	"github.com/cesargomez89/navidrums/internal/domain"
+)
+
+func TestPreferCleanTracks(t *testing.T) {
+	track := func(id, title, version string, explicit bool) domain.CatalogTrack {
+		return domain.CatalogTrack{ID: id, Title: title, Version: version, Artist: "Artist", ExplicitLyrics: explicit}
+	}
+	album := []domain.CatalogTrack{
+		track("1", "Song", "", true),
+		track("2", "Other", "", true),
+		track("3", "Intro", "", false),
+		track("4", "song", "Clean", false),
+		track("5", "Ballad [Explicit]", "", true),
+		track("6", "Ballad (Clean Version)", "", false),
+		track("7", "Song", "Live", true),
+	}
+
+	tests := []struct {
+		name        string
+		cfg         *config.Config
+		wantIDs     []string
+		wantSkipped int
+	}{
+		{"disabled", &config.Config{}, []string{"1", "2", "3", "4", "5", "6", "7"}, 0},
+		{"nil config", nil, []string{"1", "2", "3", "4", "5", "6", "7"}, 0},
+		// The explicit Song and Ballad have clean versions; Other and the live Song don't.
+		{"prefer clean", &config.Config{PreferCleanVersions: true}, []string{"2", "3", "4", "6", "7"}, 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			kept, skipped := PreferCleanTracks(tt.cfg, album)
+			var ids []string
+			for _, tr := range kept {
+				ids = append(ids, tr.ID)
+			}
+			if !reflect.DeepEqual(ids, tt.wantIDs) || skipped != tt.wantSkipped {
+				t.Errorf("PreferCleanTracks() = %v, %d, want %v, %d", ids, skipped, tt.wantIDs, tt.wantSkipped)
+			}
+		})
+	}
+}
@@ -0,0 +1,56 @@
+package app
+
+import (
+	"strings"
+
+	"github.com/cesargomez89/navidrums/internal/config"
+	"github.com/cesargomez89/navidrums/internal/constants"
+)
+
+// mimeExtensions maps audio stream content types, including common non-standard aliases,
+// to file extensions.
+var mimeExtensions = map[string]string{
+	constants.MimeTypeFLAC: constants.ExtFLAC,
+	"audio/x-flac":         constants.ExtFLAC,
+	constants.MimeTypeMP4:  constants.ExtM4A,
+	"audio/m4a":            constants.ExtM4A,
+	"audio/x-m4a":          constants.ExtM4A,
+	constants.MimeTypeMP3:  constants.ExtMP3,
+	"audio/mp3":            constants.ExtMP3,
+	constants.MimeTypeWAV:  constants.ExtWAV,
+	"audio/x-wav":          constants.ExtWAV,
+	"audio/wave":           constants.ExtWAV,
+	constants.MimeTypeAIFF: constants.ExtAIFF,
+	"audio/x-aiff":         constants.ExtAIFF,
+}
+
+// ExtensionForMimeType returns the file extension for the content type of an audio
+// stream, ignoring parameters such as codecs, or fallback when the type is unknown.
+func ExtensionForMimeType(mimeType, fallback string) string {
+	mediaType, _, _ := strings.Cut(mimeType, ";")
+	if ext, ok := mimeExtensions[strings.ToLower(strings.TrimSpace(mediaType))]; ok {
+		return ext
+	}
+	return fallback
+}
+
+// TrackExtension normalizes an extension recorded on a track to have a leading dot,
+// returning fallback when none is recorded.
+func TrackExtension(ext, fallback string) string {
+	if ext == "" {
+		return fallback
+	}
+	if !strings.HasPrefix(ext, ".") {
+		ext = "." + ext
+	}
+	return ext
+}
+
+// FallbackExtension is the extension assumed when a stream's content type is unknown or a
+// track has none recorded: FALLBACK_EXTENSION, or .flac when unset.
+func FallbackExtension(cfg *config.Config) string {
+	if cfg != nil && cfg.FallbackExtension != "" {
+		return cfg.FallbackExtension
+	}
+	return constants.ExtFLAC
+}
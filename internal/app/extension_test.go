@@ -0,0 +1,58 @@
+package app
+
+import (
+	"testing"
+
+	"github.com/cesargomez89/navidrums/internal/constants"
+)
+
+func TestExtensionForMimeType(t *testing.T) {
+	tests := []struct {
+		mimeType string
+		want     string
+	}{
+		{constants.MimeTypeFLAC, constants.ExtFLAC},
+		{"audio/x-flac", constants.ExtFLAC},
+		{constants.MimeTypeMP4, constants.ExtM4A},
+		{"audio/mp4; codecs=\"mp4a.40.2\"", constants.ExtM4A},
+		{"audio/m4a", constants.ExtM4A},
+		{"audio/x-m4a", constants.ExtM4A},
+		{constants.MimeTypeMP3, constants.ExtMP3},
+		{"audio/mp3", constants.ExtMP3},
+		{constants.MimeTypeWAV, constants.ExtWAV},
+		{"audio/x-wav", constants.ExtWAV},
+		{"audio/wave", constants.ExtWAV},
+		{constants.MimeTypeAIFF, constants.ExtAIFF},
+		{"audio/x-aiff", constants.ExtAIFF},
+		{"Audio/FLAC", constants.ExtFLAC},
+		{"application/octet-stream", constants.ExtMP3},
+		{"", constants.ExtMP3},
+	}
+	for _, tt := range tests {
+		t.Run(tt.mimeType, func(t *testing.T) {
+			// An unusual fallback, so unknown types are told apart from FLAC.
+			if got := ExtensionForMimeType(tt.mimeType, constants.ExtMP3); got != tt.want {
+				t.Errorf("ExtensionForMimeType(%q) = %q, want %q", tt.mimeType, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTrackExtension(t *testing.T) {
+	tests := []struct {
+		name string
+		ext  string
+		want string
+	}{
+		{"with dot", ".m4a", ".m4a"},
+		{"without dot", "m4a", ".m4a"},
+		{"unset", "", constants.ExtFLAC},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := TrackExtension(tt.ext, constants.ExtFLAC); got != tt.want {
+				t.Errorf("TrackExtension(%q) = %q, want %q", tt.ext, got, tt.want)
+			}
+		})
+	}
+}
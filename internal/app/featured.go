@@ -0,0 +1,107 @@
+package app
+
+import (
+	"regexp"
+	"strings"
+
+	"github.com/cesargomez89/navidrums/internal/config"
+	"github.com/cesargomez89/navidrums/internal/domain"
+)
+
+// FEATURED_ARTISTS modes.
+const (
+	// FeaturedKeep leaves titles and artists as the provider sent them.
+	FeaturedKeep = "keep"
+	// FeaturedInArtists moves artists featured in the title into the track's artists.
+	FeaturedInArtists = "artists"
+	// FeaturedInTitle credits the track's secondary artists in the title.
+	FeaturedInTitle = "title"
+)
+
+var (
+	// bracketedFeature matches "(feat. X)", "[ft. X]" or "(featuring X)" anywhere in a title.
+	bracketedFeature = regexp.MustCompile(`(?i)\s*[(\[]\s*(?:feat\.?|ft\.?|featuring)\s+([^)\]]+)[)\]]`)
+	// trailingFeature matches an unbracketed "feat. X", "ft. X" or "featuring X" ending a
+	// title. The dot is required so titles like "A Feat of Strength" are left alone.
+	trailingFeature = regexp.MustCompile(`(?i)\s+(?:feat\.|ft\.|featuring)\s+(.+)$`)
+	// featureSeparator splits "X, Y & Z" into its names.
+	featureSeparator = regexp.MustCompile(`\s*[,&]\s*`)
+)
+
+// SplitFeaturedArtists returns the title without its "feat." credits and the artists
+// those credits name.
+func SplitFeaturedArtists(title string) (string, []string) {
+	var credits []string
+	clean := bracketedFeature.ReplaceAllStringFunc(title, func(m string) string {
+		credits = append(credits, bracketedFeature.FindStringSubmatch(m)[1])
+		return ""
+	})
+	if m := trailingFeature.FindStringSubmatchIndex(clean); m != nil {
+		credits = append(credits, clean[m[2]:m[3]])
+		clean = clean[:m[0]]
+	}
+
+	var names []string
+	for _, c := range credits {
+		for _, name := range featureSeparator.Split(c, -1) {
+			if name = strings.TrimSpace(name); name != "" {
+				names = append(names, name)
+			}
+		}
+	}
+	return strings.TrimSpace(clean), names
+}
+
+// NormalizeFeaturedArtists makes a track credit its featured artists one way, whichever way
+// the provider sent them: with FEATURED_ARTISTS=artists the title loses its "feat." credits
+// and the artists gain them; with FEATURED_ARTISTS=title the artists are complete and the
+// title ends with "(feat. X, Y)" naming all but the primary one. Applying it twice changes
+// nothing more.
+func NormalizeFeaturedArtists(cfg *config.Config, track *domain.Track) {
+	if cfg == nil || (cfg.FeaturedArtists != FeaturedInArtists && cfg.FeaturedArtists != FeaturedInTitle) {
+		return
+	}
+	title, featured := SplitFeaturedArtists(track.Title)
+	if title == "" {
+		return
+	}
+
+	artists := []string(track.Artists)
+	if len(artists) == 0 && track.Artist != "" {
+		artists = []string{track.Artist}
+	}
+	for _, name := range featured {
+		if !containsFold(artists, name) {
+			artists = append(artists, name)
+		}
+	}
+	if len(artists) > len(track.Artists) {
+		track.Artists = artists
+	}
+
+	if cfg.FeaturedArtists == FeaturedInTitle {
+		primary := track.Artist
+		if primary == "" && len(artists) > 0 {
+			primary = artists[0]
+		}
+		var guests []string
+		for _, a := range artists {
+			if !strings.EqualFold(a, primary) {
+				guests = append(guests, a)
+			}
+		}
+		if len(guests) > 0 {
+			title += " (feat. " + strings.Join(guests, ", ") + ")"
+		}
+	}
+	track.Title = title
+}
+
+func containsFold(list []string, s string) bool {
+	for _, v := range list {
+		if strings.EqualFold(v, s) {
+			return true
+		}
+	}
+	return false
+}
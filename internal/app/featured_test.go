@@ -0,0 +1,48 @@
+package app
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/cesargomez89/navidrums/internal/config"
+	"github.com/cesargomez89/navidrums/internal/domain"
+)
+
+func TestNormalizeFeaturedArtists(t *testing.T) {
+	tests := []struct {
+		name        string
+		mode        string
+		title       string
+		artists     []string
+		wantTitle   string
+		wantArtists []string
+	}{
+		{"feat. into artists", FeaturedInArtists, "Song (feat. Guest)", []string{"Main"}, "Song", []string{"Main", "Guest"}},
+		{"ft. into artists", FeaturedInArtists, "Song [ft. Guest & Other]", []string{"Main"}, "Song", []string{"Main", "Guest", "Other"}},
+		{"featuring into artists", FeaturedInArtists, "Song featuring Guest", []string{"Main"}, "Song", []string{"Main", "Guest"}},
+		{"already credited", FeaturedInArtists, "Song (feat. guest)", []string{"Main", "Guest"}, "Song", []string{"Main", "Guest"}},
+		{"no feature", FeaturedInArtists, "A Feat of Strength", []string{"Main"}, "A Feat of Strength", []string{"Main"}},
+		{"artists into feat.", FeaturedInTitle, "Song", []string{"Main", "Guest", "Other"}, "Song (feat. Guest, Other)", []string{"Main", "Guest", "Other"}},
+		{"ft. rewritten", FeaturedInTitle, "Song (ft. Guest)", []string{"Main"}, "Song (feat. Guest)", []string{"Main", "Guest"}},
+		{"featuring rewritten", FeaturedInTitle, "Song (Featuring Guest) (Live)", []string{"Main", "Guest"}, "Song (Live) (feat. Guest)", []string{"Main", "Guest"}},
+		{"solo", FeaturedInTitle, "Song", []string{"Main"}, "Song", []string{"Main"}},
+		{"keep", FeaturedKeep, "Song (feat. Guest)", []string{"Main"}, "Song (feat. Guest)", []string{"Main"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := &config.Config{FeaturedArtists: tt.mode}
+			track := &domain.Track{Title: tt.title, Artist: "Main", Artists: tt.artists}
+			NormalizeFeaturedArtists(cfg, track)
+			if track.Title != tt.wantTitle || !reflect.DeepEqual([]string(track.Artists), tt.wantArtists) {
+				t.Errorf("got %q %v, want %q %v", track.Title, track.Artists, tt.wantTitle, tt.wantArtists)
+			}
+
+			// A second pass, as a later sync does, changes nothing.
+			title, artists := track.Title, append([]string(nil), track.Artists...)
+			NormalizeFeaturedArtists(cfg, track)
+			if track.Title != title || !reflect.DeepEqual([]string(track.Artists), artists) {
+				t.Errorf("second pass gave %q %v, want %q %v", track.Title, track.Artists, title, artists)
+			}
+		})
+	}
+}
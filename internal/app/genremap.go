@@ -0,0 +1,82 @@
+package app
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/cesargomez89/navidrums/internal/musicbrainz"
+	"github.com/cesargomez89/navidrums/internal/store"
+)
+
+var (
+	// ErrMusicBrainzUnavailable reports an ISRC preview without a MusicBrainz client.
+	ErrMusicBrainzUnavailable = errors.New("MusicBrainz is not available")
+	// ErrMusicBrainzLookup reports that MusicBrainz could not return a recording's tags.
+	ErrMusicBrainzLookup = errors.New("MusicBrainz lookup failed")
+)
+
+// GenrePreview is the genre the genre map resolves for a set of tags.
+type GenrePreview struct {
+	ISRC  string                 `json:"isrc,omitempty"`
+	Genre string                 `json:"genre"`
+	Tags  []musicbrainz.TagCount `json:"tags"`
+}
+
+// GenreMapService previews the saved genre map against tags or a recording's
+// MusicBrainz tags.
+type GenreMapService struct {
+	Settings    *store.SettingsRepo
+	MusicBrainz *musicbrainz.Client
+}
+
+func NewGenreMapService(settings *store.SettingsRepo, mb *musicbrainz.Client) *GenreMapService {
+	return &GenreMapService{Settings: settings, MusicBrainz: mb}
+}
+
+// GenreMap returns the saved custom genre map, which replaces the default one as it does
+// during downloads, or the default map when none is saved.
+func (s *GenreMapService) GenreMap() (map[string]string, error) {
+	customMapJSON, err := s.Settings.Get(store.SettingGenreMap)
+	if err != nil {
+		return nil, err
+	}
+	if customMapJSON != "" {
+		var customMap map[string]string
+		if err := json.Unmarshal([]byte(customMapJSON), &customMap); err == nil {
+			return customMap, nil
+		}
+	}
+	return musicbrainz.DefaultGenreMap, nil
+}
+
+// PreviewTags maps tags, most voted first, through the genre map.
+func (s *GenreMapService) PreviewTags(tags []musicbrainz.TagCount) (*GenrePreview, error) {
+	genreMap, err := s.GenreMap()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get genre map: %w", err)
+	}
+	if tags == nil {
+		tags = []musicbrainz.TagCount{}
+	}
+	return &GenrePreview{Tags: tags, Genre: musicbrainz.MapGenre(tags, genreMap)}, nil
+}
+
+// PreviewISRC looks up the MusicBrainz tags of the recordings with isrc and maps them the
+// way a download would.
+func (s *GenreMapService) PreviewISRC(ctx context.Context, isrc string) (*GenrePreview, error) {
+	if s.MusicBrainz == nil {
+		return nil, ErrMusicBrainzUnavailable
+	}
+	tags, err := s.MusicBrainz.GetTagsByISRC(ctx, isrc)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrMusicBrainzLookup, err)
+	}
+	preview, err := s.PreviewTags(tags)
+	if err != nil {
+		return nil, err
+	}
+	preview.ISRC = isrc
+	return preview, nil
+}
@@ -0,0 +1,41 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/cesargomez89/navidrums/internal/musicbrainz"
+	"github.com/cesargomez89/navidrums/internal/store"
+)
+
+func TestGenreMapService_Preview(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+	settings := store.NewSettingsRepo(db)
+	svc := NewGenreMapService(settings, nil)
+
+	tags := []musicbrainz.TagCount{{Name: "shoegaze", Count: 2}, {Name: "indie rock", Count: 1}}
+	preview, err := svc.PreviewTags(tags)
+	if err != nil {
+		t.Fatalf("PreviewTags failed: %v", err)
+	}
+	if preview.Genre != "rock" {
+		t.Errorf("genre with the default map = %q, want %q", preview.Genre, "rock")
+	}
+
+	if err := settings.Set(store.SettingGenreMap, `{"shoegaze":"dream pop"}`); err != nil {
+		t.Fatalf("Set failed: %v", err)
+	}
+	preview, err = svc.PreviewTags(tags)
+	if err != nil {
+		t.Fatalf("PreviewTags failed: %v", err)
+	}
+	if preview.Genre != "dream pop" {
+		t.Errorf("genre with the custom map = %q, want %q", preview.Genre, "dream pop")
+	}
+
+	if _, err := svc.PreviewISRC(context.Background(), "USRC17607839"); !errors.Is(err, ErrMusicBrainzUnavailable) {
+		t.Errorf("PreviewISRC() error = %v, want ErrMusicBrainzUnavailable", err)
+	}
+}
@@ -0,0 +1,107 @@
+package app
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"io/fs"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/cesargomez89/navidrums/internal/config"
+	"github.com/cesargomez89/navidrums/internal/domain"
+)
+
+var (
+	ErrImportDisabled    = errors.New("importing is disabled: IMPORT_DIR is not set")
+	ErrImportOutsideRoot = errors.New("path is outside IMPORT_DIR")
+)
+
+// importExtensions are the formats whose tags can be read to seed an imported track.
+var importExtensions = []string{".flac", ".mp3", ".m4a", ".mp4"}
+
+// ResolveImportPath returns the absolute path of a file or folder inside IMPORT_DIR. A
+// relative path is taken from IMPORT_DIR. Symlinks are resolved before the check, so one
+// can't lead out of the folder.
+func ResolveImportPath(cfg *config.Config, path string) (string, error) {
+	if cfg.ImportDir == "" {
+		return "", ErrImportDisabled
+	}
+	root, err := filepath.EvalSymlinks(cfg.ImportDir)
+	if err != nil {
+		return "", fmt.Errorf("failed to resolve IMPORT_DIR: %w", err)
+	}
+	if root, err = filepath.Abs(root); err != nil {
+		return "", fmt.Errorf("failed to resolve IMPORT_DIR: %w", err)
+	}
+
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(root, path)
+	}
+	resolved, err := filepath.EvalSymlinks(path)
+	if err != nil {
+		return "", fmt.Errorf("failed to resolve import path: %w", err)
+	}
+	if resolved, err = filepath.Abs(resolved); err != nil {
+		return "", fmt.Errorf("failed to resolve import path: %w", err)
+	}
+	if resolved != root && !isUnder(root, resolved) {
+		return "", ErrImportOutsideRoot
+	}
+	return resolved, nil
+}
+
+// ImportFiles lists the FLAC, MP3 and MP4 files at path: the file itself, or every one
+// under the folder in lexical order.
+func ImportFiles(path string) ([]string, error) {
+	var files []string
+	err := filepath.WalkDir(path, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.Type().IsRegular() && isImportable(p) {
+			files = append(files, p)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return files, nil
+}
+
+func isImportable(path string) bool {
+	return slices.Contains(importExtensions, strings.ToLower(filepath.Ext(path)))
+}
+
+// EnqueueImportJob queues an import of the file or folder at path, which must be inside
+// IMPORT_DIR. It is a no-op if the same path is already queued or being imported.
+func (s *DownloadsService) EnqueueImportJob(path string) error {
+	resolved, err := ResolveImportPath(s.Config, path)
+	if err != nil {
+		return err
+	}
+
+	existing, err := s.Repo.GetActiveJobBySourceID(resolved, domain.JobTypeImport)
+	if err != nil {
+		return fmt.Errorf("failed to check active import job: %w", err)
+	}
+	if existing != nil {
+		return nil
+	}
+
+	job := &domain.Job{
+		ID:        uuid.New().String(),
+		Type:      domain.JobTypeImport,
+		Status:    domain.JobStatusQueued,
+		SourceID:  sql.NullString{String: resolved, Valid: true},
+		RequestID: requestIDColumn(s.requestID),
+		CreatedAt: time.Now(),
+		UpdatedAt: time.Now(),
+	}
+	return s.Repo.CreateJob(job)
+}
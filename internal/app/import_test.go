@@ -0,0 +1,60 @@
+package app
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/cesargomez89/navidrums/internal/config"
+)
+
+func TestResolveImportPath(t *testing.T) {
+	base, err := filepath.EvalSymlinks(t.TempDir())
+	if err != nil {
+		t.Fatalf("EvalSymlinks failed: %v", err)
+	}
+	root := filepath.Join(base, "import")
+	outside := filepath.Join(base, "elsewhere")
+	for _, dir := range []string{filepath.Join(root, "Artist"), outside} {
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			t.Fatalf("MkdirAll failed: %v", err)
+		}
+	}
+	if err := os.Symlink(outside, filepath.Join(root, "escape")); err != nil {
+		t.Fatalf("Symlink failed: %v", err)
+	}
+	cfg := &config.Config{ImportDir: root}
+
+	tests := []struct {
+		name    string
+		cfg     *config.Config
+		path    string
+		want    string
+		wantErr error
+	}{
+		{"relative", cfg, "Artist", filepath.Join(root, "Artist"), nil},
+		{"absolute", cfg, filepath.Join(root, "Artist"), filepath.Join(root, "Artist"), nil},
+		{"the root itself", cfg, root, root, nil},
+		{"dot dot", cfg, "../elsewhere", "", ErrImportOutsideRoot},
+		{"outside", cfg, outside, "", ErrImportOutsideRoot},
+		{"symlink out", cfg, "escape", "", ErrImportOutsideRoot},
+		{"missing", cfg, "Nobody", "", fs.ErrNotExist},
+		{"disabled", &config.Config{}, "Artist", "", ErrImportDisabled},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ResolveImportPath(tt.cfg, tt.path)
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Errorf("ResolveImportPath(%q) error = %v, want %v", tt.path, err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil || got != tt.want {
+				t.Errorf("ResolveImportPath(%q) = %q, %v, want %q", tt.path, got, err, tt.want)
+			}
+		})
+	}
+}
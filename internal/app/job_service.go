@@ -1,8 +1,11 @@
 package app
 
 import (
+	"context"
 	"database/sql"
+	"errors"
 	"fmt"
+	"strings"
 	"time"
 
 	"github.com/google/uuid"
@@ -13,16 +16,78 @@ import (
 	"github.com/cesargomez89/navidrums/internal/store"
 )
 
+var (
+	// ErrInvalidTrackSelection reports an album track selection that can't be queued.
+	ErrInvalidTrackSelection = errors.New("invalid track selection")
+	// ErrAlbumJobQueued reports that the album already has an active job, so a track
+	// selection for it was not applied.
+	ErrAlbumJobQueued = errors.New("album job already queued")
+)
+
 type JobService struct {
 	Repo   *store.DB
 	Logger *logger.Logger
+	// Running, when set, is the worker's registry of running jobs; cancelling a job also
+	// interrupts it there.
+	Running *RunningJobs
+	// Stats, when set, is the worker's live slot usage, reported by WorkerStatus.
+	Stats *WorkerStats
+
+	requestID string
 }
 
 func NewJobService(repo *store.DB, log *logger.Logger) *JobService {
 	return &JobService{Repo: repo, Logger: log}
 }
 
+// ForRequest returns a copy of s for one HTTP request: it logs the request's ID, and the
+// jobs it queues record it.
+func (s *JobService) ForRequest(ctx context.Context) *JobService {
+	scoped := *s
+	scoped.Logger = s.Logger.WithRequest(ctx)
+	scoped.requestID = logger.RequestID(ctx)
+	return &scoped
+}
+
+// requestIDColumn is the request_id of a job queued while serving the request id.
+func requestIDColumn(id string) sql.NullString {
+	return sql.NullString{String: id, Valid: id != ""}
+}
+
 func (s *JobService) EnqueueJob(sourceID string, jobType domain.JobType) (*domain.Job, error) {
+	return s.enqueueJob(sourceID, jobType, sql.NullInt64{}, sql.NullString{})
+}
+
+// EnqueueJobWithMinDuration enqueues a job like EnqueueJob, skipping the album, playlist or
+// artist tracks shorter than minDuration seconds instead of MIN_TRACK_DURATION_SECS. A job
+// already queued for the source keeps its own minimum.
+func (s *JobService) EnqueueJobWithMinDuration(sourceID string, jobType domain.JobType, minDuration int) (*domain.Job, error) {
+	return s.enqueueJob(sourceID, jobType, sql.NullInt64{Int64: int64(minDuration), Valid: true}, sql.NullString{})
+}
+
+// EnqueueAlbumTracks enqueues an album job that downloads only the given tracks of the
+// album, still saving its album art. An album job already active for the album is kept
+// as it is and returned with ErrAlbumJobQueued.
+func (s *JobService) EnqueueAlbumTracks(albumID string, trackIDs []string) (*domain.Job, error) {
+	if len(trackIDs) == 0 {
+		return nil, fmt.Errorf("%w: no tracks selected", ErrInvalidTrackSelection)
+	}
+	for _, id := range trackIDs {
+		if id == "" || strings.Contains(id, ",") {
+			return nil, fmt.Errorf("%w: invalid track id %q", ErrInvalidTrackSelection, id)
+		}
+	}
+	existing, err := s.Repo.GetActiveJobBySourceID(albumID, domain.JobTypeAlbum)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check for existing job: %w", err)
+	}
+	if existing != nil {
+		return existing, ErrAlbumJobQueued
+	}
+	return s.enqueueJob(albumID, domain.JobTypeAlbum, sql.NullInt64{}, sql.NullString{String: strings.Join(trackIDs, ","), Valid: true})
+}
+
+func (s *JobService) enqueueJob(sourceID string, jobType domain.JobType, minDuration sql.NullInt64, trackIDs sql.NullString) (*domain.Job, error) {
 	existing, err := s.Repo.GetActiveJobBySourceID(sourceID, jobType)
 	if err != nil {
 		return nil, fmt.Errorf("failed to check for existing job: %w", err)
@@ -34,12 +99,15 @@ func (s *JobService) EnqueueJob(sourceID string, jobType domain.JobType) (*domai
 
 	id := uuid.New().String()
 	job := &domain.Job{
-		ID:        id,
-		Type:      jobType,
-		Status:    domain.JobStatusQueued,
-		SourceID:  sql.NullString{String: sourceID, Valid: true},
-		CreatedAt: time.Now(),
-		UpdatedAt: time.Now(),
+		ID:          id,
+		Type:        jobType,
+		Status:      domain.JobStatusQueued,
+		SourceID:    sql.NullString{String: sourceID, Valid: true},
+		MinDuration: minDuration,
+		TrackIDs:    trackIDs,
+		RequestID:   requestIDColumn(s.requestID),
+		CreatedAt:   time.Now(),
+		UpdatedAt:   time.Now(),
 	}
 
 	if err := s.Repo.CreateJob(job); err != nil {
@@ -87,6 +155,9 @@ func (s *JobService) CancelJob(id string) error {
 		if err != nil {
 			return err
 		}
+		if s.Running != nil {
+			s.Running.Cancel(id)
+		}
 		s.Logger.Info("Job cancelled", "job_id", id)
 	}
 	return nil
@@ -103,14 +174,176 @@ func (s *JobService) RetryJob(id string) error {
 	if !job.CanRetry() {
 		return fmt.Errorf("job cannot be retried from status %s", job.Status)
 	}
-	err = s.Repo.ClearJobError(id)
+
+	requeued := 0
+	err = s.Repo.RunInTx(func(txDB *store.DB) error {
+		if job.IsContainer() {
+			requeued, err = retryChildJobs(txDB, job.ID)
+			if err != nil || requeued > 0 {
+				return err
+			}
+			// Nothing left to retry, or the job failed before decomposing: run it again.
+		}
+		if err := txDB.ClearJobError(id); err != nil {
+			return err
+		}
+		return requeueTrack(txDB, job)
+	})
 	if err != nil {
 		return err
 	}
+	if requeued > 0 {
+		s.Logger.Info("Container job retried", "job_id", id, "type", job.Type, "source_id", job.GetSourceID(), "requeued", requeued)
+		return nil
+	}
 	s.Logger.Info("Job retried", "job_id", id, "type", job.Type, "source_id", job.GetSourceID())
 	return nil
 }
 
+// RetryFailedTracks re-queues every failed track in the library, e.g. after a provider
+// outage, with a new track job for each. Tracks that already have a queued or running job
+// are skipped, and the failed jobs stay in the history. It returns the number of tracks
+// re-queued.
+func (s *JobService) RetryFailedTracks() (int, error) {
+	tracks, err := s.Repo.ListFailedTracks()
+	if err != nil {
+		return 0, fmt.Errorf("failed to list failed tracks: %w", err)
+	}
+
+	var jobs []*domain.Job
+	err = s.Repo.RunInTx(func(txDB *store.DB) error {
+		for _, track := range tracks {
+			active, err := txDB.IsTrackActive(track.ProviderID)
+			if err != nil {
+				return fmt.Errorf("failed to check for active job: %w", err)
+			}
+			if active {
+				continue
+			}
+			if err := txDB.RequeueTrack(track.ID); err != nil {
+				return fmt.Errorf("failed to requeue track %d: %w", track.ID, err)
+			}
+			jobs = append(jobs, &domain.Job{
+				ID:        uuid.New().String(),
+				Type:      domain.JobTypeTrack,
+				Status:    domain.JobStatusQueued,
+				SourceID:  sql.NullString{String: track.ProviderID, Valid: true},
+				RequestID: requestIDColumn(s.requestID),
+				CreatedAt: time.Now(),
+				UpdatedAt: time.Now(),
+			})
+		}
+		if len(jobs) == 0 {
+			return nil
+		}
+		if err := txDB.CreateJobBatch(jobs); err != nil {
+			return fmt.Errorf("failed to create track jobs: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	s.Logger.Info("Failed tracks retried", "failed", len(tracks), "requeued", len(jobs))
+	return len(jobs), nil
+}
+
+// retryChildJobs re-queues the failed and cancelled children of a decomposed container job,
+// recursing into child containers (the albums of a discography), and queues a new job for
+// any unfinished child track whose job is gone, e.g. after the history was cleared. The
+// container goes back to decomposed rather than queued, so it is not decomposed again. It
+// returns the number of jobs re-queued.
+func retryChildJobs(repo *store.DB, parentID string) (int, error) {
+	children, err := repo.ListJobsByParentID(parentID)
+	if err != nil {
+		return 0, fmt.Errorf("failed to list child jobs: %w", err)
+	}
+
+	requeued := 0
+	hasJob := make(map[string]bool, len(children))
+	for _, child := range children {
+		hasJob[child.GetSourceID()] = true
+
+		if child.IsContainer() {
+			n, err := retryChildJobs(repo, child.ID)
+			if err != nil {
+				return 0, err
+			}
+			requeued += n
+			if n > 0 || !child.CanRetry() {
+				continue
+			}
+		} else if !child.CanRetry() {
+			continue
+		}
+
+		if err := repo.ClearJobError(child.ID); err != nil {
+			return 0, fmt.Errorf("failed to requeue job %s: %w", child.ID, err)
+		}
+		if err := requeueTrack(repo, child); err != nil {
+			return 0, err
+		}
+		requeued++
+	}
+
+	tracks, err := repo.ListTracksByParentJobID(parentID)
+	if err != nil {
+		return 0, fmt.Errorf("failed to list child tracks: %w", err)
+	}
+	var orphans []*domain.Job
+	for _, track := range tracks {
+		if hasJob[track.ProviderID] || track.Status == domain.TrackStatusCompleted {
+			continue
+		}
+		if err := repo.RequeueTrack(track.ID); err != nil {
+			return 0, fmt.Errorf("failed to requeue track %d: %w", track.ID, err)
+		}
+		orphans = append(orphans, &domain.Job{
+			ID:          uuid.New().String(),
+			Type:        domain.JobTypeTrack,
+			Status:      domain.JobStatusQueued,
+			SourceID:    sql.NullString{String: track.ProviderID, Valid: true},
+			ParentJobID: sql.NullString{String: parentID, Valid: true},
+			CreatedAt:   time.Now(),
+			UpdatedAt:   time.Now(),
+		})
+	}
+	if len(orphans) > 0 {
+		if err := repo.CreateJobBatch(orphans); err != nil {
+			return 0, fmt.Errorf("failed to create track jobs: %w", err)
+		}
+		requeued += len(orphans)
+	}
+
+	if requeued > 0 {
+		total, pending, err := repo.CountJobsForParent(parentID)
+		if err != nil {
+			return 0, fmt.Errorf("failed to count child jobs: %w", err)
+		}
+		progress := float64(total-pending) / float64(total) * 100
+		if err := repo.UpdateJobStatus(parentID, domain.JobStatusDecomposed, progress); err != nil {
+			return 0, err
+		}
+	}
+	return requeued, nil
+}
+
+// requeueTrack resets the track of a retried track job to queued and clears its error. A
+// completed track (a failed upgrade) keeps its status.
+func requeueTrack(repo *store.DB, job *domain.Job) error {
+	if job.Type != domain.JobTypeTrack && job.Type != domain.JobTypeUpgrade {
+		return nil
+	}
+	track, err := repo.GetTrackByProviderID(job.GetSourceID())
+	if err != nil || track == nil || track.Status == domain.TrackStatusCompleted {
+		return nil
+	}
+	if err := repo.RequeueTrack(track.ID); err != nil {
+		return fmt.Errorf("failed to requeue track %d: %w", track.ID, err)
+	}
+	return nil
+}
+
 func (s *JobService) ListFinishedJobs(page, pageSize int) ([]*domain.Job, int, error) {
 	offset := (page - 1) * pageSize
 	total, err := s.Repo.CountFinishedJobs()
@@ -128,6 +361,16 @@ func (s *JobService) GetJobStats() (*store.JobStats, error) {
 	return s.Repo.GetJobStats()
 }
 
+// ListJobEvents returns the event timeline recorded for a job.
+func (s *JobService) ListJobEvents(id string) ([]*domain.JobEvent, error) {
+	return s.Repo.ListJobEvents(id)
+}
+
+// ListJobEventsAfter returns the job's events recorded after the one with ID afterID.
+func (s *JobService) ListJobEventsAfter(id string, afterID int) ([]*domain.JobEvent, error) {
+	return s.Repo.ListJobEventsAfter(id, afterID)
+}
+
 func (s *JobService) ClearFinishedJobs() error {
 	return s.Repo.ClearFinishedJobs()
 }
@@ -1,7 +1,9 @@
 package app
 
 import (
+	"context"
 	"database/sql"
+	"errors"
 	"os"
 	"testing"
 	"time"
@@ -66,6 +68,67 @@ func TestJobService_EnqueueJob(t *testing.T) {
 	}
 }
 
+func TestJobService_EnqueueJobWithMinDuration(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	svc := NewJobService(db, logger.Default())
+
+	job, err := svc.EnqueueJobWithMinDuration("album_123", domain.JobTypeAlbum, 45)
+	if err != nil {
+		t.Fatalf("EnqueueJobWithMinDuration failed: %v", err)
+	}
+	stored, err := db.GetJob(job.ID)
+	if err != nil {
+		t.Fatalf("GetJob failed: %v", err)
+	}
+	if !stored.MinDuration.Valid || stored.MinDuration.Int64 != 45 {
+		t.Errorf("MinDuration = %+v, want 45", stored.MinDuration)
+	}
+
+	plain, err := svc.EnqueueJob("album_456", domain.JobTypeAlbum)
+	if err != nil {
+		t.Fatalf("EnqueueJob failed: %v", err)
+	}
+	if stored, err = db.GetJob(plain.ID); err != nil {
+		t.Fatalf("GetJob failed: %v", err)
+	}
+	if stored.MinDuration.Valid {
+		t.Errorf("MinDuration = %+v, want NULL", stored.MinDuration)
+	}
+}
+
+func TestJobService_ForRequest(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	svc := NewJobService(db, logger.Default())
+	ctx := logger.WithRequestID(context.Background(), "req-1")
+
+	job, err := svc.ForRequest(ctx).EnqueueJob("album_123", domain.JobTypeAlbum)
+	if err != nil {
+		t.Fatalf("EnqueueJob failed: %v", err)
+	}
+	stored, err := db.GetJob(job.ID)
+	if err != nil {
+		t.Fatalf("GetJob failed: %v", err)
+	}
+	if stored.RequestID.String != "req-1" {
+		t.Errorf("RequestID = %+v, want req-1", stored.RequestID)
+	}
+
+	plain, err := svc.EnqueueJob("album_456", domain.JobTypeAlbum)
+	if err != nil {
+		t.Fatalf("EnqueueJob failed: %v", err)
+	}
+	if stored, err = db.GetJob(plain.ID); err != nil {
+		t.Fatalf("GetJob failed: %v", err)
+	}
+	if stored.RequestID.Valid {
+		t.Errorf("RequestID = %+v, want NULL", stored.RequestID)
+	}
+}
+
 func TestJobService_CancelJob(t *testing.T) {
 	db, cleanup := setupTestDB(t)
 	defer cleanup()
@@ -132,12 +195,26 @@ func TestJobService_CancelJobCancelsChildren(t *testing.T) {
 		}
 	}
 
+	svc.Running = NewRunningJobs()
+	childCtx, childDone := svc.Running.Start(context.Background(), "child-cancel-3", parentID)
+	defer childDone()
+	otherCtx, otherDone := svc.Running.Start(context.Background(), "unrelated", "")
+	defer otherDone()
+
 	// Cancel parent
 	err := svc.CancelJob(parentID)
 	if err != nil {
 		t.Fatalf("CancelJob failed: %v", err)
 	}
 
+	// Verify the running child was interrupted and other jobs were not
+	if childCtx.Err() == nil {
+		t.Error("Expected running child context to be cancelled")
+	}
+	if otherCtx.Err() != nil {
+		t.Error("Expected unrelated job context to stay live")
+	}
+
 	// Verify parent is cancelled
 	parent, _ := db.GetJob(parentID)
 	if parent.Status != domain.JobStatusCancelled {
@@ -243,6 +320,203 @@ func TestJobService_RetryJobClearsError(t *testing.T) {
 	}
 }
 
+func TestJobService_RetryJobRequeuesTrack(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	svc := NewJobService(db, logger.Default())
+
+	job := &domain.Job{ID: "retry-track", Type: domain.JobTypeTrack, Status: domain.JobStatusFailed, SourceID: sql.NullString{String: "t1", Valid: true}, CreatedAt: time.Now(), UpdatedAt: time.Now()}
+	if err := db.CreateJob(job); err != nil {
+		t.Fatalf("CreateJob failed: %v", err)
+	}
+	track := &domain.Track{ProviderID: "t1", Title: "Track", Status: domain.TrackStatusFailed, Error: "stream failed", CreatedAt: time.Now(), UpdatedAt: time.Now()}
+	if err := db.CreateTrack(track); err != nil {
+		t.Fatalf("CreateTrack failed: %v", err)
+	}
+
+	if err := svc.RetryJob("retry-track"); err != nil {
+		t.Fatalf("RetryJob failed: %v", err)
+	}
+
+	fetched, _ := db.GetTrackByProviderID("t1")
+	if fetched.Status != domain.TrackStatusQueued || fetched.Error != "" {
+		t.Errorf("Expected queued track without error, got status %s, error %q", fetched.Status, fetched.Error)
+	}
+}
+
+func TestJobService_RetryPartialAlbum(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	svc := NewJobService(db, logger.Default())
+
+	albumID := "album-retry"
+	parent := sql.NullString{String: albumID, Valid: true}
+	jobs := []*domain.Job{
+		{ID: albumID, Type: domain.JobTypeAlbum, Status: domain.JobStatusCancelled, SourceID: sql.NullString{String: "a1", Valid: true}, CreatedAt: time.Now(), UpdatedAt: time.Now()},
+		{ID: "job-done", Type: domain.JobTypeTrack, Status: domain.JobStatusCompleted, SourceID: sql.NullString{String: "t1", Valid: true}, ParentJobID: parent, CreatedAt: time.Now(), UpdatedAt: time.Now()},
+		{ID: "job-failed", Type: domain.JobTypeTrack, Status: domain.JobStatusFailed, SourceID: sql.NullString{String: "t2", Valid: true}, ParentJobID: parent, CreatedAt: time.Now(), UpdatedAt: time.Now()},
+		{ID: "job-cancelled", Type: domain.JobTypeTrack, Status: domain.JobStatusCancelled, SourceID: sql.NullString{String: "t3", Valid: true}, ParentJobID: parent, CreatedAt: time.Now(), UpdatedAt: time.Now()},
+	}
+	for _, j := range jobs {
+		if err := db.CreateJob(j); err != nil {
+			t.Fatalf("CreateJob failed: %v", err)
+		}
+	}
+	// t4's job was removed when the history was cleared.
+	for _, track := range []*domain.Track{
+		{ProviderID: "t1", Title: "Done", Status: domain.TrackStatusCompleted, ParentJobID: albumID, CreatedAt: time.Now(), UpdatedAt: time.Now()},
+		{ProviderID: "t2", Title: "Failed", Status: domain.TrackStatusFailed, Error: "stream failed", ParentJobID: albumID, CreatedAt: time.Now(), UpdatedAt: time.Now()},
+		{ProviderID: "t3", Title: "Cancelled", Status: domain.TrackStatusQueued, ParentJobID: albumID, CreatedAt: time.Now(), UpdatedAt: time.Now()},
+		{ProviderID: "t4", Title: "Orphaned", Status: domain.TrackStatusFailed, Error: "stream failed", ParentJobID: albumID, CreatedAt: time.Now(), UpdatedAt: time.Now()},
+	} {
+		if err := db.CreateTrack(track); err != nil {
+			t.Fatalf("CreateTrack failed: %v", err)
+		}
+	}
+
+	if err := svc.RetryJob(albumID); err != nil {
+		t.Fatalf("RetryJob failed: %v", err)
+	}
+
+	album, _ := db.GetJob(albumID)
+	if album.Status != domain.JobStatusDecomposed {
+		t.Errorf("Expected album status decomposed, got %s", album.Status)
+	}
+	if album.Progress != 25 {
+		t.Errorf("Expected album progress 25, got %v", album.Progress)
+	}
+
+	children, err := db.ListJobsByParentID(albumID)
+	if err != nil {
+		t.Fatalf("ListJobsByParentID failed: %v", err)
+	}
+	statuses := make(map[string][]domain.JobStatus)
+	for _, child := range children {
+		statuses[child.GetSourceID()] = append(statuses[child.GetSourceID()], child.Status)
+	}
+
+	tests := []struct {
+		sourceID    string
+		wantJob     domain.JobStatus
+		wantTrack   domain.TrackStatus
+		wantNoError bool
+	}{
+		{"t1", domain.JobStatusCompleted, domain.TrackStatusCompleted, true},
+		{"t2", domain.JobStatusQueued, domain.TrackStatusQueued, true},
+		{"t3", domain.JobStatusQueued, domain.TrackStatusQueued, true},
+		{"t4", domain.JobStatusQueued, domain.TrackStatusQueued, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.sourceID, func(t *testing.T) {
+			got := statuses[tt.sourceID]
+			if len(got) != 1 || got[0] != tt.wantJob {
+				t.Errorf("Expected one %s job, got %v", tt.wantJob, got)
+			}
+			track, _ := db.GetTrackByProviderID(tt.sourceID)
+			if track.Status != tt.wantTrack {
+				t.Errorf("Expected track status %s, got %s", tt.wantTrack, track.Status)
+			}
+			if tt.wantNoError && track.Error != "" {
+				t.Errorf("Expected track error cleared, got %q", track.Error)
+			}
+		})
+	}
+}
+
+func TestJobService_RetryUndecomposedAlbum(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	svc := NewJobService(db, logger.Default())
+
+	job := &domain.Job{ID: "album-fetch-failed", Type: domain.JobTypeAlbum, Status: domain.JobStatusFailed, SourceID: sql.NullString{String: "a1", Valid: true}, CreatedAt: time.Now(), UpdatedAt: time.Now()}
+	if err := db.CreateJob(job); err != nil {
+		t.Fatalf("CreateJob failed: %v", err)
+	}
+
+	if err := svc.RetryJob(job.ID); err != nil {
+		t.Fatalf("RetryJob failed: %v", err)
+	}
+
+	fetched, _ := db.GetJob(job.ID)
+	if fetched.Status != domain.JobStatusQueued {
+		t.Errorf("Expected status queued, got %s", fetched.Status)
+	}
+}
+
+func TestJobService_RetryFailedTracks(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	svc := NewJobService(db, logger.Default())
+
+	// t3 failed but was already queued again by hand.
+	for _, j := range []*domain.Job{
+		{ID: "job-t2", Type: domain.JobTypeTrack, Status: domain.JobStatusFailed, SourceID: sql.NullString{String: "t2", Valid: true}, CreatedAt: time.Now(), UpdatedAt: time.Now()},
+		{ID: "job-t3", Type: domain.JobTypeTrack, Status: domain.JobStatusQueued, SourceID: sql.NullString{String: "t3", Valid: true}, CreatedAt: time.Now(), UpdatedAt: time.Now()},
+	} {
+		if err := db.CreateJob(j); err != nil {
+			t.Fatalf("CreateJob failed: %v", err)
+		}
+	}
+	for _, track := range []*domain.Track{
+		{ProviderID: "t1", Title: "Done", Status: domain.TrackStatusCompleted, CreatedAt: time.Now(), UpdatedAt: time.Now()},
+		{ProviderID: "t2", Title: "Failed", Status: domain.TrackStatusFailed, Error: "stream failed", CreatedAt: time.Now(), UpdatedAt: time.Now()},
+		{ProviderID: "t3", Title: "Active", Status: domain.TrackStatusFailed, Error: "stream failed", CreatedAt: time.Now(), UpdatedAt: time.Now()},
+		{ProviderID: "t4", Title: "No job", Status: domain.TrackStatusFailed, Error: "stream failed", CreatedAt: time.Now(), UpdatedAt: time.Now()},
+	} {
+		if err := db.CreateTrack(track); err != nil {
+			t.Fatalf("CreateTrack failed: %v", err)
+		}
+	}
+
+	requeued, err := svc.RetryFailedTracks()
+	if err != nil {
+		t.Fatalf("RetryFailedTracks failed: %v", err)
+	}
+	if requeued != 2 {
+		t.Errorf("Expected 2 tracks requeued, got %d", requeued)
+	}
+
+	all, err := db.ListJobs(100)
+	if err != nil {
+		t.Fatalf("ListJobs failed: %v", err)
+	}
+	statuses := make(map[string][]domain.JobStatus)
+	for _, job := range all {
+		statuses[job.GetSourceID()] = append(statuses[job.GetSourceID()], job.Status)
+	}
+
+	tests := []struct {
+		sourceID  string
+		wantJobs  int
+		wantTrack domain.TrackStatus
+	}{
+		{"t1", 0, domain.TrackStatusCompleted},
+		{"t2", 2, domain.TrackStatusQueued},
+		{"t3", 1, domain.TrackStatusFailed},
+		{"t4", 1, domain.TrackStatusQueued},
+	}
+	for _, tt := range tests {
+		t.Run(tt.sourceID, func(t *testing.T) {
+			if got := statuses[tt.sourceID]; len(got) != tt.wantJobs {
+				t.Errorf("Expected %d jobs, got %v", tt.wantJobs, got)
+			}
+			track, _ := db.GetTrackByProviderID(tt.sourceID)
+			if track.Status != tt.wantTrack {
+				t.Errorf("Expected track status %s, got %s", tt.wantTrack, track.Status)
+			}
+		})
+	}
+
+	// Running it again finds nothing new: t2 and t4 are queued and t3 still has its job.
+	if requeued, err := svc.RetryFailedTracks(); err != nil || requeued != 0 {
+		t.Errorf("Expected no tracks requeued on the second run, got %d, %v", requeued, err)
+	}
+}
+
 func TestJobService_ListJobs(t *testing.T) {
 	db, cleanup := setupTestDB(t)
 	defer cleanup()
@@ -369,3 +643,112 @@ func TestJobService_GetJob(t *testing.T) {
 		t.Error("Expected error for non-existent job")
 	}
 }
+
+func TestJobService_WorkerStatus(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	now := time.Now()
+	jobs := []*domain.Job{
+		{ID: "queued-1", Type: domain.JobTypeTrack, Status: domain.JobStatusQueued, SourceID: sql.NullString{String: "t1", Valid: true}, CreatedAt: now, UpdatedAt: now},
+		{ID: "queued-2", Type: domain.JobTypeAlbum, Status: domain.JobStatusQueued, SourceID: sql.NullString{String: "a1", Valid: true}, CreatedAt: now, UpdatedAt: now},
+		{ID: "running-1", Type: domain.JobTypeTrack, Status: domain.JobStatusRunning, SourceID: sql.NullString{String: "t2", Valid: true}, CreatedAt: now, UpdatedAt: now},
+		{ID: "done-recent", Type: domain.JobTypeTrack, Status: domain.JobStatusCompleted, SourceID: sql.NullString{String: "t3", Valid: true}, CreatedAt: now, UpdatedAt: now.Add(-10 * time.Minute)},
+		{ID: "done-old", Type: domain.JobTypeTrack, Status: domain.JobStatusCompleted, SourceID: sql.NullString{String: "t4", Valid: true}, CreatedAt: now, UpdatedAt: now.Add(-2 * time.Hour)},
+		{ID: "failed", Type: domain.JobTypeTrack, Status: domain.JobStatusFailed, SourceID: sql.NullString{String: "t5", Valid: true}, CreatedAt: now, UpdatedAt: now},
+	}
+	for _, job := range jobs {
+		if err := db.CreateJob(job); err != nil {
+			t.Fatalf("CreateJob(%s) failed: %v", job.ID, err)
+		}
+	}
+
+	svc := NewJobService(db, logger.Default())
+	svc.Stats = NewWorkerStats()
+	svc.Stats.SetSlots(2, 1)
+	svc.Stats.JobStarted()
+
+	status, err := svc.WorkerStatus()
+	if err != nil {
+		t.Fatalf("WorkerStatus failed: %v", err)
+	}
+	want := WorkerStatus{ActiveSlots: 1, MaxConcurrent: 2, MaxMetadata: 1, Queued: 2, Running: 1, CompletedLastHour: 1}
+	if *status != want {
+		t.Errorf("WorkerStatus() = %+v, want %+v", *status, want)
+	}
+
+	t.Run("paused by a reorganize job", func(t *testing.T) {
+		reorganize := &domain.Job{ID: "reorganize", Type: domain.JobTypeReorganize, Status: domain.JobStatusQueued, SourceID: sql.NullString{String: "library", Valid: true}, CreatedAt: now, UpdatedAt: now}
+		if err := db.CreateJob(reorganize); err != nil {
+			t.Fatalf("CreateJob failed: %v", err)
+		}
+		status, err := svc.WorkerStatus()
+		if err != nil {
+			t.Fatalf("WorkerStatus failed: %v", err)
+		}
+		if !status.Paused || status.Queued != 3 {
+			t.Errorf("WorkerStatus() = %+v, want paused with 3 queued", *status)
+		}
+	})
+
+	t.Run("paused by a stopped worker", func(t *testing.T) {
+		svc := NewJobService(db, logger.Default())
+		svc.Stats = NewWorkerStats()
+		svc.Stats.Stop()
+		status, err := svc.WorkerStatus()
+		if err != nil {
+			t.Fatalf("WorkerStatus failed: %v", err)
+		}
+		if !status.Paused {
+			t.Error("WorkerStatus().Paused = false after Stop, want true")
+		}
+	})
+
+	t.Run("paused by the download window", func(t *testing.T) {
+		svc := NewJobService(db, logger.Default())
+		svc.Stats = NewWorkerStats()
+		opens := time.Date(2024, 5, 1, 22, 0, 0, 0, time.Local)
+		svc.Stats.SetWindowOpens(opens)
+		status, err := svc.WorkerStatus()
+		if err != nil {
+			t.Fatalf("WorkerStatus failed: %v", err)
+		}
+		if !status.Paused || status.NextWindowStart == nil || !status.NextWindowStart.Equal(opens) {
+			t.Errorf("WorkerStatus() = %+v, want paused until %v", *status, opens)
+		}
+	})
+}
+
+func TestJobService_EnqueueAlbumTracks(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	svc := NewJobService(db, logger.Default())
+
+	job, err := svc.EnqueueAlbumTracks("album_123", []string{"t1", "t2"})
+	if err != nil {
+		t.Fatalf("EnqueueAlbumTracks failed: %v", err)
+	}
+	stored, err := db.GetJob(job.ID)
+	if err != nil {
+		t.Fatalf("GetJob failed: %v", err)
+	}
+	if stored.Type != domain.JobTypeAlbum || stored.TrackIDs.String != "t1,t2" {
+		t.Errorf("stored job = %s with tracks %q, want an album job for t1,t2", stored.Type, stored.TrackIDs.String)
+	}
+
+	for _, ids := range [][]string{nil, {""}, {"a,b"}} {
+		if _, err := svc.EnqueueAlbumTracks("album_456", ids); !errors.Is(err, ErrInvalidTrackSelection) {
+			t.Errorf("EnqueueAlbumTracks(%q) error = %v, want ErrInvalidTrackSelection", ids, err)
+		}
+	}
+
+	// The queued job keeps its selection; a second one is refused rather than dropped silently.
+	again, err := svc.EnqueueAlbumTracks("album_123", []string{"t3"})
+	if !errors.Is(err, ErrAlbumJobQueued) {
+		t.Fatalf("EnqueueAlbumTracks() error = %v, want ErrAlbumJobQueued", err)
+	}
+	if again == nil || again.ID != job.ID {
+		t.Errorf("EnqueueAlbumTracks() job = %v, want the queued job %s", again, job.ID)
+	}
+}
@@ -3,8 +3,11 @@ package app
 import (
 	"fmt"
 	"path/filepath"
+	"slices"
+	"strings"
 
 	"github.com/cesargomez89/navidrums/internal/config"
+	"github.com/cesargomez89/navidrums/internal/constants"
 	"github.com/cesargomez89/navidrums/internal/domain"
 	"github.com/cesargomez89/navidrums/internal/storage"
 	"github.com/cesargomez89/navidrums/internal/store"
@@ -53,133 +56,156 @@ func (pg *playlistGenerator) GenerateFromDB(playlistID int64, lookup TrackLookup
 		}
 	}
 
-	filename := fmt.Sprintf("%s - %s.m3u", storage.Sanitize(playlist.Title), storage.Sanitize(playlist.ProviderID))
-	return pg.writePlaylist(filename, playlist.Title, catalogTracks, lookup)
+	name := fmt.Sprintf("%s - %s", storage.Sanitize(playlist.Title), storage.Sanitize(playlist.ProviderID))
+	return pg.writePlaylist(name, playlist.Title, catalogTracks, lookup)
 }
 
 func (pg *playlistGenerator) Generate(pl *domain.Playlist, lookup TrackLookupFunc) error {
-	var filename string
+	name := storage.Sanitize(pl.Title)
 	if pl.ProviderID != "" {
-		filename = fmt.Sprintf("%s - %s.m3u", storage.Sanitize(pl.Title), storage.Sanitize(pl.ProviderID))
-	} else {
-		filename = storage.Sanitize(pl.Title) + ".m3u"
+		name = fmt.Sprintf("%s - %s", storage.Sanitize(pl.Title), storage.Sanitize(pl.ProviderID))
 	}
-	return pg.writePlaylist(filename, pl.Title, pl.Tracks, lookup)
+	return pg.writePlaylist(name, pl.Title, pl.Tracks, lookup)
 }
 
 func (pg *playlistGenerator) GenerateFromTracks(artistName string, tracks []domain.CatalogTrack, lookup TrackLookupFunc) error {
-	filename := fmt.Sprintf("%s - Top Tracks.m3u", storage.Sanitize(artistName))
-	return pg.writePlaylist(filename, fmt.Sprintf("%s - Top Tracks", artistName), tracks, lookup)
+	name := fmt.Sprintf("%s - Top Tracks", storage.Sanitize(artistName))
+	return pg.writePlaylist(name, fmt.Sprintf("%s - Top Tracks", artistName), tracks, lookup)
 }
 
-func (pg *playlistGenerator) writePlaylist(filename string, title string, tracks []domain.CatalogTrack, lookup TrackLookupFunc) error {
+// playlistEntry is one track of a playlist. Path is empty when the track isn't downloaded.
+type playlistEntry struct {
+	Path     string
+	Artist   string
+	Title    string
+	Duration int
+}
+
+// writePlaylist writes the tracks to <name><ext> in the format chosen by PLAYLIST_FORMAT,
+// in the playlists folder of each base directory holding one of the downloaded tracks, or
+// of DOWNLOADS_DIR when none is downloaded yet.
+func (pg *playlistGenerator) writePlaylist(name string, title string, tracks []domain.CatalogTrack, lookup TrackLookupFunc) error {
 	if len(tracks) == 0 {
 		return nil
 	}
 
-	playlistsDir := filepath.Join(pg.config.DownloadsDir, "playlists")
+	found := make([]*domain.Track, len(tracks))
+	var roots []string
+	for i, t := range tracks {
+		found[i] = lookup(t.ID)
+		if track := found[i]; track != nil && track.Status == domain.TrackStatusCompleted && track.FilePath != "" {
+			if root := pg.config.DownloadsRootOf(track.FilePath); !slices.Contains(roots, root) {
+				roots = append(roots, root)
+			}
+		}
+	}
+	if len(roots) == 0 {
+		roots = []string{pg.config.DownloadsDir}
+	}
+
+	for _, root := range roots {
+		if err := pg.writePlaylistIn(filepath.Join(root, constants.PlaylistsDir), name, title, tracks, found); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (pg *playlistGenerator) writePlaylistIn(playlistsDir, name, title string, tracks []domain.CatalogTrack, found []*domain.Track) error {
 	if err := storage.EnsureDir(playlistsDir); err != nil {
 		return fmt.Errorf("failed to create playlists directory: %w", err)
 	}
 
-	playlistPath := filepath.Join(playlistsDir, filename)
+	entries := make([]playlistEntry, len(tracks))
+	for i, t := range tracks {
+		entries[i] = playlistEntry{
+			Path:     pg.entryPath(playlistsDir, found[i]),
+			Artist:   playlistText(t.Artist),
+			Title:    playlistText(t.Title),
+			Duration: t.Duration,
+		}
+	}
 
-	f, err := storage.CreateFile(playlistPath)
-	if err != nil {
-		return fmt.Errorf("failed to create playlist file: %w", err)
+	var content, ext string
+	switch pg.config.PlaylistFormat {
+	case constants.PlaylistFormatPLS:
+		content, ext = renderPLS(entries), constants.ExtPLS
+	case constants.PlaylistFormatM3U8:
+		content, ext = renderM3U(playlistText(title), entries), constants.ExtM3U8
+	default:
+		content, ext = renderM3U(playlistText(title), entries), constants.ExtM3U
 	}
-	writeErr := error(nil)
-	defer func() {
-		_ = f.Close()
-		if writeErr != nil {
-			_ = storage.RemoveFile(playlistPath)
-		}
-	}()
 
-	if _, err := f.WriteString("#EXTM3U\n"); err != nil {
-		writeErr = fmt.Errorf("failed to write playlist header: %w", err)
-		return writeErr
+	playlistPath := filepath.Join(playlistsDir, name+ext)
+	if err := storage.WriteFile(playlistPath, []byte(content)); err != nil {
+		return fmt.Errorf("failed to write playlist file: %w", err)
 	}
+	return nil
+}
 
+// renderM3U renders an extended M3U playlist. Tracks that aren't downloaded are kept as
+// comments so the gap is visible without breaking players.
+func renderM3U(title string, entries []playlistEntry) string {
+	var sb strings.Builder
+	sb.WriteString("#EXTM3U\n")
 	if title != "" {
-		if _, err := fmt.Fprintf(f, "#PLAYLIST:%s\n", title); err != nil {
-			writeErr = fmt.Errorf("failed to write playlist title: %w", err)
-			return writeErr
+		fmt.Fprintf(&sb, "#PLAYLIST:%s\n", title)
+	}
+	for _, e := range entries {
+		if e.Path == "" {
+			fmt.Fprintf(&sb, "# Not downloaded: %s - %s\n", e.Artist, e.Title)
+			continue
 		}
+		fmt.Fprintf(&sb, "#EXTINF:%d,%s - %s\n%s\n", e.Duration, e.Artist, e.Title, e.Path)
 	}
+	return sb.String()
+}
 
-	for _, t := range tracks {
-		var relPath string
-		var err error
-
-		dbTrack := lookup(t.ID)
-
-		if dbTrack != nil && dbTrack.Status == domain.TrackStatusCompleted && dbTrack.FilePath != "" {
-			// If track is already downloaded, use its exact file path relative to playlists dir
-			// FilePath is absolute. DownloadsDir is absolute.
-			// The playlist is in <DownloadsDir>/playlists/
-			// We can figure out the relative path easily:
-			rel, relErr := filepath.Rel(playlistsDir, dbTrack.FilePath)
-			if relErr == nil {
-				relPath = rel
-			}
+// renderPLS renders a PLS playlist. PLS has no comment syntax, so tracks that aren't
+// downloaded are left out and the remaining entries numbered consecutively.
+func renderPLS(entries []playlistEntry) string {
+	var sb strings.Builder
+	sb.WriteString("[playlist]\n")
+	n := 0
+	for _, e := range entries {
+		if e.Path == "" {
+			continue
 		}
+		n++
+		fmt.Fprintf(&sb, "File%d=%s\n", n, e.Path)
+		fmt.Fprintf(&sb, "Title%d=%s - %s\n", n, e.Artist, e.Title)
+		fmt.Fprintf(&sb, "Length%d=%d\n", n, e.Duration)
+	}
+	fmt.Fprintf(&sb, "NumberOfEntries=%d\n", n)
+	sb.WriteString("Version=2\n")
+	return sb.String()
+}
 
-		if relPath == "" {
-			var templateData *storage.PathTemplateData
-			if dbTrack != nil {
-				artistForFolder := dbTrack.PathArtist
-				if artistForFolder == "" {
-					artistForFolder = dbTrack.AlbumArtist
-				}
-				if artistForFolder == "" {
-					artistForFolder = dbTrack.Artist
-				}
-				templateData = storage.BuildPathTemplateData(
-					artistForFolder,
-					dbTrack.Year,
-					dbTrack.Album,
-					dbTrack.DiscNumber,
-					dbTrack.TrackNumber,
-					dbTrack.Title,
-				)
-			} else {
-				artistForFolder := t.AlbumArtist
-				if artistForFolder == "" {
-					artistForFolder = t.Artist
-				}
-				templateData = storage.BuildPathTemplateData(
-					artistForFolder,
-					t.Year,
-					t.Album,
-					t.DiscNumber,
-					t.TrackNumber,
-					t.Title,
-				)
-			}
-
-			relPath, err = storage.BuildPath(pg.config.SubdirTemplate, templateData)
-			if err != nil {
-				// Fallback to old behavior if template fails
-				folderName := fmt.Sprintf("%s - %s", storage.Sanitize(t.Artist), storage.Sanitize(t.Album))
-				trackFile := fmt.Sprintf("%02d - %s%s", t.TrackNumber, storage.Sanitize(t.Title), ".flac")
-				relPath = filepath.Join("..", folderName, trackFile)
-			} else {
-				ext := ".flac"
-				if dbTrack != nil && dbTrack.FileExtension != "" {
-					ext = dbTrack.FileExtension
-				}
-				// Format with path.Join to ensure forward slashes, but filepath.Join is what was used
-				relPath = filepath.Join("..", relPath+ext)
-			}
+// entryPath returns the path a playlist in playlistsDir should use for a downloaded track:
+// its stored file path, relative to the playlist unless PLAYLIST_ABSOLUTE_PATHS is set.
+// It returns "" for tracks without a completed download.
+func (pg *playlistGenerator) entryPath(playlistsDir string, track *domain.Track) string {
+	if track == nil || track.Status != domain.TrackStatusCompleted || track.FilePath == "" {
+		return ""
+	}
+	path := track.FilePath
+	if !filepath.IsAbs(path) {
+		if abs, err := filepath.Abs(path); err == nil {
+			path = abs
 		}
-
-		line := fmt.Sprintf("#EXTINF:%d,%s - %s\n%s\n", t.Duration, t.Artist, t.Title, filepath.ToSlash(relPath))
-		if _, err := f.WriteString(line); err != nil {
-			writeErr = fmt.Errorf("failed to write track to playlist: %w", err)
-			return writeErr
+	}
+	if !pg.config.PlaylistAbsolutePaths {
+		absDir, err := filepath.Abs(playlistsDir)
+		if err == nil {
+			if rel, relErr := filepath.Rel(absDir, path); relErr == nil {
+				path = rel
+			}
 		}
 	}
+	return filepath.ToSlash(path)
+}
 
-	return nil
+// playlistText flattens line breaks so a title can't spill onto a path line.
+func playlistText(s string) string {
+	return strings.Join(strings.Fields(s), " ")
 }
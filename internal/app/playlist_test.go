@@ -3,58 +3,78 @@ package app
 import (
 	"os"
 	"path/filepath"
-	"strings"
 	"testing"
 
 	"github.com/cesargomez89/navidrums/internal/config"
+	"github.com/cesargomez89/navidrums/internal/constants"
 	"github.com/cesargomez89/navidrums/internal/domain"
 )
 
 func TestPlaylistGenerator_Generate(t *testing.T) {
-	tmpDir := t.TempDir()
-	cfg := &config.Config{
-		DownloadsDir:   tmpDir,
-		SubdirTemplate: "{{.AlbumArtist}}/{{.Album}}/{{.Track}} {{.Title}}",
+	tests := []struct {
+		name          string
+		absolutePaths bool
+		wantEntry     func(downloadsDir string) string
+	}{
+		{"relative paths", false, func(string) string { return "../Artist A/2023 - Album 1/1-01 Track 1.flac" }},
+		{"absolute paths", true, func(dir string) string {
+			return filepath.ToSlash(filepath.Join(dir, "Artist A", "2023 - Album 1", "1-01 Track 1.flac"))
+		}},
 	}
 
-	pg := NewPlaylistGenerator(cfg, nil)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tmpDir := t.TempDir()
+			cfg := &config.Config{
+				DownloadsDir:          tmpDir,
+				SubdirTemplate:        "{{.AlbumArtist}}/{{.Album}}/{{.Track}} {{.Title}}",
+				PlaylistAbsolutePaths: tt.absolutePaths,
+			}
 
-	pl := &domain.Playlist{
-		Title: "Test Playlist",
-		Tracks: []domain.CatalogTrack{
-			{
-				ID:          "t1",
-				Title:       "Track 1",
-				Artist:      "Artist A",
-				Album:       "Album 1",
-				Year:        2023,
-				TrackNumber: 1,
-				Duration:    180,
-			},
-		},
-	}
+			pg := NewPlaylistGenerator(cfg, nil)
 
-	lookup := func(id string) *domain.Track {
-		return nil
-	}
+			pl := &domain.Playlist{
+				Title: "Test Playlist",
+				Tracks: []domain.CatalogTrack{
+					{ID: "t1", Title: "Track 1", Artist: "Artist A", Album: "Album 1", Duration: 180},
+					{ID: "t2", Title: "Track 2", Artist: "Artist B", Album: "Album 2", Duration: 200},
+					{ID: "t3", Title: "Track 3", Artist: "Artist C", Album: "Album 3", Duration: 220},
+				},
+			}
 
-	err := pg.Generate(pl, lookup)
-	if err != nil {
-		t.Fatalf("Generate failed: %v", err)
-	}
+			// Stored paths follow whatever template was active at download time,
+			// so the playlist must use them rather than the current template.
+			stored := map[string]*domain.Track{
+				"t1": {
+					Status:   domain.TrackStatusCompleted,
+					FilePath: filepath.Join(tmpDir, "Artist A", "2023 - Album 1", "1-01 Track 1.flac"),
+				},
+				"t2": {Status: domain.TrackStatusQueued},
+			}
+			lookup := func(id string) *domain.Track {
+				return stored[id]
+			}
 
-	playlistPath := filepath.Join(tmpDir, "playlists", "Test Playlist.m3u")
-	content, err := os.ReadFile(playlistPath) //nolint:gosec
-	if err != nil {
-		t.Fatalf("Failed to read playlist file: %v", err)
-	}
+			if err := pg.Generate(pl, lookup); err != nil {
+				t.Fatalf("Generate failed: %v", err)
+			}
 
-	sContent := string(content)
-	if !strings.HasPrefix(sContent, "#EXTM3U") {
-		t.Errorf("Missing M3U header")
-	}
-	if !strings.Contains(sContent, "Artist A/Album 1/01 Track 1.flac") {
-		t.Errorf("Expected relative path not found in playlist: %s", sContent)
+			playlistPath := filepath.Join(tmpDir, "playlists", "Test Playlist.m3u")
+			content, err := os.ReadFile(playlistPath) //nolint:gosec
+			if err != nil {
+				t.Fatalf("Failed to read playlist file: %v", err)
+			}
+
+			want := "#EXTM3U\n" +
+				"#PLAYLIST:Test Playlist\n" +
+				"#EXTINF:180,Artist A - Track 1\n" +
+				tt.wantEntry(tmpDir) + "\n" +
+				"# Not downloaded: Artist B - Track 2\n" +
+				"# Not downloaded: Artist C - Track 3\n"
+			if string(content) != want {
+				t.Errorf("playlist content =\n%s\nwant\n%s", content, want)
+			}
+		})
 	}
 }
 
@@ -91,3 +111,71 @@ func TestPlaylistGenerator_GenerateFromTracks(t *testing.T) {
 		t.Fatalf("Playlist file not created")
 	}
 }
+
+func TestPlaylistGenerator_Formats(t *testing.T) {
+	tests := []struct {
+		name     string
+		format   string
+		fileName string
+		want     string
+	}{
+		{
+			name:     "m3u8",
+			format:   constants.PlaylistFormatM3U8,
+			fileName: "Café Mix - Top Tracks.m3u8",
+			want: "#EXTM3U\n" +
+				"#PLAYLIST:Café Mix - Top Tracks\n" +
+				"#EXTINF:180,Björk - Jóga = Song\n" +
+				"../Björk/Homogenic/01 Jóga.flac\n" +
+				"# Not downloaded: Sigur Rós - Hoppípolla\n",
+		},
+		{
+			name:     "pls",
+			format:   constants.PlaylistFormatPLS,
+			fileName: "Café Mix - Top Tracks.pls",
+			want: "[playlist]\n" +
+				"File1=../Björk/Homogenic/01 Jóga.flac\n" +
+				"Title1=Björk - Jóga = Song\n" +
+				"Length1=180\n" +
+				"NumberOfEntries=1\n" +
+				"Version=2\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tmpDir := t.TempDir()
+			cfg := &config.Config{
+				DownloadsDir:   tmpDir,
+				PlaylistFormat: tt.format,
+			}
+			pg := NewPlaylistGenerator(cfg, nil)
+
+			tracks := []domain.CatalogTrack{
+				{ID: "t1", Title: "Jóga\n= Song", Artist: "Björk", Duration: 180},
+				{ID: "t2", Title: "Hoppípolla", Artist: "Sigur Rós", Duration: 270},
+			}
+			lookup := func(id string) *domain.Track {
+				if id != "t1" {
+					return nil
+				}
+				return &domain.Track{
+					Status:   domain.TrackStatusCompleted,
+					FilePath: filepath.Join(tmpDir, "Björk", "Homogenic", "01 Jóga.flac"),
+				}
+			}
+
+			if err := pg.GenerateFromTracks("Café Mix", tracks, lookup); err != nil {
+				t.Fatalf("GenerateFromTracks failed: %v", err)
+			}
+
+			content, err := os.ReadFile(filepath.Join(tmpDir, "playlists", tt.fileName)) //nolint:gosec
+			if err != nil {
+				t.Fatalf("Failed to read playlist file: %v", err)
+			}
+			if string(content) != tt.want {
+				t.Errorf("playlist content =\n%s\nwant\n%s", content, tt.want)
+			}
+		})
+	}
+}
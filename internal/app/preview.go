@@ -0,0 +1,187 @@
+package app
+
+import (
+	"fmt"
+	"path/filepath"
+
+	"github.com/cesargomez89/navidrums/internal/config"
+	"github.com/cesargomez89/navidrums/internal/constants"
+	"github.com/cesargomez89/navidrums/internal/domain"
+	"github.com/cesargomez89/navidrums/internal/storage"
+)
+
+// Preview statuses of a track, from least to most reason to skip it.
+const (
+	PreviewNew           = "new"
+	PreviewPathConflict  = "path-conflict"
+	PreviewDuplicateISRC = "duplicate-isrc"
+	PreviewDownloaded    = "downloaded"
+)
+
+// TrackPreview is where one album track would be downloaded and whether it already exists.
+type TrackPreview struct {
+	Existing       *domain.Track
+	Track          domain.CatalogTrack
+	Path           string
+	Status         string
+	EstimatedBytes int64
+}
+
+// AlbumPreview is a dry run of an album download: nothing is enqueued.
+type AlbumPreview struct {
+	Album      *domain.Album
+	Quality    string
+	Tracks     []TrackPreview
+	TotalBytes int64
+	NewCount   int
+}
+
+// EstimatedSize formats the estimated download size of the new tracks.
+func (p *AlbumPreview) EstimatedSize() string {
+	return formatSize(p.TotalBytes)
+}
+
+// EstimatedSize formats the track's estimated download size.
+func (t TrackPreview) EstimatedSize() string {
+	return formatSize(t.EstimatedBytes)
+}
+
+// TrackDownloadsDir is the base directory a track downloads to: the DOWNLOADS_DIR_MAP
+// entry for its quality or source provider, or DOWNLOADS_DIR.
+func TrackDownloadsDir(cfg *config.Config, track *domain.Track) string {
+	return cfg.DownloadsDirFor(track.SourceProvider, track.AudioQuality)
+}
+
+// BuildTrackPath resolves the destination of a track, without extension, from the
+// SUBDIR_TEMPLATE under the track's downloads directory. With MULTI_DISC_LAYOUT set to
+// subfolders, a track of a multi-disc release goes in a "Disc N" folder of the folder the
+// template gives it. Both the worker and the download preview use it.
+func BuildTrackPath(cfg *config.Config, track *domain.Track) (string, error) {
+	artistForFolder := track.PathArtist
+	if artistForFolder == "" {
+		artistForFolder = track.AlbumArtist
+	}
+	if artistForFolder == "" {
+		artistForFolder = track.Artist
+	}
+
+	templateData := storage.BuildPathTemplateData(
+		artistForFolder,
+		track.Year,
+		track.Album,
+		track.DiscNumber,
+		track.TrackNumber,
+		track.Title,
+	).WithDetails(storage.PathDetails{
+		Artist:     track.Artist,
+		Genre:      track.Genre,
+		Label:      track.Label,
+		Quality:    track.AudioQuality,
+		TotalDiscs: track.TotalDiscs,
+	})
+
+	relPath, err := storage.BuildPath(cfg.SubdirTemplate, templateData)
+	if err != nil {
+		return "", err
+	}
+	if cfg.MultiDiscLayout == constants.MultiDiscLayoutSubfolders && templateData.MultiDisc {
+		disc := fmt.Sprintf("Disc %d", max(track.DiscNumber, 1))
+		relPath = filepath.Join(filepath.Dir(relPath), disc, filepath.Base(relPath))
+	}
+	return filepath.Join(TrackDownloadsDir(cfg, track), relPath), nil
+}
+
+// PreviewAlbum resolves where each album track would be saved at the given quality and
+// flags tracks already in the library by provider ID or ISRC, and tracks whose paths
+// collide. Paths use provider metadata; MusicBrainz enrichment at download time can
+// still change the year or album artist.
+func (s *DownloadsService) PreviewAlbum(album *domain.Album, quality string) (*AlbumPreview, error) {
+	preview := &AlbumPreview{Album: album, Quality: quality}
+	seenPaths := make(map[string]bool)
+
+	for _, ct := range album.Tracks {
+		track := &domain.Track{
+			Title:        ct.Title,
+			Artist:       ct.Artist,
+			Album:        ct.Album,
+			AlbumArtist:  ct.AlbumArtist,
+			Year:         ct.Year,
+			DiscNumber:   ct.DiscNumber,
+			TrackNumber:  ct.TrackNumber,
+			AudioQuality: quality,
+		}
+		if track.Album == "" {
+			track.Album = album.Title
+		}
+		if track.AlbumArtist == "" {
+			track.AlbumArtist = album.Artist
+		}
+		if track.Year == 0 {
+			track.Year = album.Year
+		}
+
+		pathNoExt, err := BuildTrackPath(s.Config, track)
+		if err != nil {
+			return nil, fmt.Errorf("failed to build path for %q: %w", ct.Title, err)
+		}
+
+		tp := TrackPreview{
+			Track:          ct,
+			Path:           pathNoExt + previewExtension(quality),
+			Status:         PreviewNew,
+			EstimatedBytes: estimateTrackBytes(ct.Duration, quality),
+		}
+
+		if existing, err := s.Repo.GetTrackByProviderID(ct.ID); err == nil && existing.Status == domain.TrackStatusCompleted && existing.DeletedAt == nil {
+			tp.Status, tp.Existing, tp.Path = PreviewDownloaded, existing, existing.FilePath
+		} else if ct.ISRC != "" {
+			if existing, err := s.Repo.GetTrackByISRC(ct.ISRC); err == nil {
+				tp.Status, tp.Existing = PreviewDuplicateISRC, existing
+			}
+		}
+		if tp.Status == PreviewNew && seenPaths[pathNoExt] {
+			tp.Status = PreviewPathConflict
+		}
+		seenPaths[pathNoExt] = true
+
+		if tp.Status == PreviewNew {
+			preview.NewCount++
+			preview.TotalBytes += tp.EstimatedBytes
+		}
+		preview.Tracks = append(preview.Tracks, tp)
+	}
+
+	return preview, nil
+}
+
+// previewExtension guesses the file extension a quality tier is delivered in.
+func previewExtension(quality string) string {
+	switch quality {
+	case constants.QualityHigh, constants.QualityLow:
+		return constants.ExtM4A
+	}
+	return constants.ExtFLAC
+}
+
+// estimateTrackBytes estimates a track's size from its duration and the typical bitrate
+// of the quality tier.
+func estimateTrackBytes(durationSec int, quality string) int64 {
+	kbps := constants.EstimatedBitrateLossless
+	switch quality {
+	case constants.QualityHiResLossless:
+		kbps = constants.EstimatedBitrateHiRes
+	case constants.QualityHigh:
+		kbps = constants.EstimatedBitrateHigh
+	case constants.QualityLow:
+		kbps = constants.EstimatedBitrateLow
+	}
+	return int64(durationSec) * int64(kbps) * 1000 / 8
+}
+
+func formatSize(bytes int64) string {
+	const mb = 1000 * 1000
+	if bytes >= 1000*mb {
+		return fmt.Sprintf("%.1f GB", float64(bytes)/(1000*mb))
+	}
+	return fmt.Sprintf("%.0f MB", float64(bytes)/mb)
+}
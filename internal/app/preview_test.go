@@ -0,0 +1,157 @@
+package app
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/cesargomez89/navidrums/internal/config"
+	"github.com/cesargomez89/navidrums/internal/constants"
+	"github.com/cesargomez89/navidrums/internal/domain"
+	"github.com/cesargomez89/navidrums/internal/logger"
+)
+
+func TestTrackDownloadsDir(t *testing.T) {
+	cfg := &config.Config{DownloadsDir: "/music", SubdirTemplate: constants.DefaultSubdirTemplate}
+	if err := cfg.SetDownloadsDirMap("HI_RES_LOSSLESS=/music/hires,HIGH=/music/lossy"); err != nil {
+		t.Fatalf("SetDownloadsDirMap failed: %v", err)
+	}
+	tests := []struct {
+		name    string
+		quality string
+		want    string
+	}{
+		{"hi-res", constants.QualityHiResLossless, filepath.Join("/music/hires", "Artist", "2020 - Album", "01-01 Song")},
+		{"lossy", constants.QualityHigh, filepath.Join("/music/lossy", "Artist", "2020 - Album", "01-01 Song")},
+		{"unmapped", constants.QualityLossless, filepath.Join("/music", "Artist", "2020 - Album", "01-01 Song")},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			track := &domain.Track{
+				Title: "Song", Artist: "Artist", Album: "Album", Year: 2020, DiscNumber: 1, TrackNumber: 1,
+				SourceProvider: "hifi", AudioQuality: tt.quality,
+			}
+			got, err := BuildTrackPath(cfg, track)
+			if err != nil {
+				t.Fatalf("BuildTrackPath failed: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("path = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestBuildTrackPath_MultiDiscLayout(t *testing.T) {
+	tests := []struct {
+		name        string
+		layout      string
+		disc, total int
+		want        string
+	}{
+		{"flat single disc", constants.MultiDiscLayoutFlat, 1, 1, "Artist/2020 - Album/01-03 Song"},
+		{"flat multi-disc", constants.MultiDiscLayoutFlat, 2, 2, "Artist/2020 - Album/02-03 Song"},
+		{"unset is flat", "", 2, 2, "Artist/2020 - Album/02-03 Song"},
+		{"subfolders single disc", constants.MultiDiscLayoutSubfolders, 1, 1, "Artist/2020 - Album/01-03 Song"},
+		{"subfolders unknown total", constants.MultiDiscLayoutSubfolders, 1, 0, "Artist/2020 - Album/01-03 Song"},
+		{"subfolders first disc", constants.MultiDiscLayoutSubfolders, 1, 2, "Artist/2020 - Album/Disc 1/01-03 Song"},
+		{"subfolders second disc", constants.MultiDiscLayoutSubfolders, 2, 2, "Artist/2020 - Album/Disc 2/02-03 Song"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := &config.Config{DownloadsDir: "/music", SubdirTemplate: constants.DefaultSubdirTemplate, MultiDiscLayout: tt.layout}
+			track := &domain.Track{
+				Title: "Song", Artist: "Artist", Album: "Album", Year: 2020,
+				DiscNumber: tt.disc, TotalDiscs: tt.total, TrackNumber: 3,
+			}
+			got, err := BuildTrackPath(cfg, track)
+			if err != nil {
+				t.Fatalf("BuildTrackPath failed: %v", err)
+			}
+			if want := filepath.Join("/music", filepath.FromSlash(tt.want)); got != want {
+				t.Errorf("path = %q, want %q", got, want)
+			}
+		})
+	}
+}
+
+func TestDownloadsService_PreviewAlbum(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	downloadsDir := t.TempDir()
+	svc := NewDownloadsService(db, &config.Config{
+		DownloadsDir:   downloadsDir,
+		SubdirTemplate: constants.DefaultSubdirTemplate,
+	}, logger.Default())
+
+	now := time.Now()
+	for _, track := range []*domain.Track{
+		{ProviderID: "t2", Title: "Owned", Artist: "Artist", Status: domain.TrackStatusCompleted, FilePath: "/music/owned.flac", CompletedAt: &now, CreatedAt: now, UpdatedAt: now},
+		{ProviderID: "other", Title: "Single", Artist: "Artist", ISRC: "USABC0000003", Status: domain.TrackStatusCompleted, FilePath: "/music/single.flac", CompletedAt: &now, CreatedAt: now, UpdatedAt: now},
+	} {
+		if err := db.CreateTrack(track); err != nil {
+			t.Fatalf("CreateTrack failed: %v", err)
+		}
+	}
+
+	album := &domain.Album{
+		ID:     "a1",
+		Title:  "Album",
+		Artist: "Artist",
+		Year:   2020,
+		Tracks: []domain.CatalogTrack{
+			{ID: "t1", Title: "New", Artist: "Artist", TrackNumber: 1, DiscNumber: 1, Duration: 200},
+			{ID: "t2", Title: "Owned", Artist: "Artist", TrackNumber: 2, DiscNumber: 1, Duration: 200},
+			{ID: "t3", Title: "Single", Artist: "Artist", TrackNumber: 3, DiscNumber: 1, Duration: 200, ISRC: "USABC0000003"},
+			{ID: "t4", Title: "New", Artist: "Artist", TrackNumber: 1, DiscNumber: 1, Duration: 200},
+		},
+	}
+
+	preview, err := svc.PreviewAlbum(album, constants.QualityLossless)
+	if err != nil {
+		t.Fatalf("PreviewAlbum failed: %v", err)
+	}
+
+	tests := []struct {
+		name       string
+		wantStatus string
+		wantPath   string
+	}{
+		{"new", PreviewNew, filepath.Join(downloadsDir, "Artist", "2020 - Album", "01-01 New.flac")},
+		{"downloaded by provider ID", PreviewDownloaded, "/music/owned.flac"},
+		{"duplicate by ISRC", PreviewDuplicateISRC, filepath.Join(downloadsDir, "Artist", "2020 - Album", "01-03 Single.flac")},
+		{"path conflict", PreviewPathConflict, filepath.Join(downloadsDir, "Artist", "2020 - Album", "01-01 New.flac")},
+	}
+
+	if len(preview.Tracks) != len(tests) {
+		t.Fatalf("expected %d tracks, got %d", len(tests), len(preview.Tracks))
+	}
+	for i, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := preview.Tracks[i]
+			if got.Status != tt.wantStatus {
+				t.Errorf("Status = %q, want %q", got.Status, tt.wantStatus)
+			}
+			if got.Path != tt.wantPath {
+				t.Errorf("Path = %q, want %q", got.Path, tt.wantPath)
+			}
+		})
+	}
+
+	if preview.NewCount != 1 {
+		t.Errorf("NewCount = %d, want 1", preview.NewCount)
+	}
+	// 200s at the lossless estimate of 900 kbps.
+	if want := int64(200 * 900 * 1000 / 8); preview.TotalBytes != want {
+		t.Errorf("TotalBytes = %d, want %d", preview.TotalBytes, want)
+	}
+
+	jobs, err := db.ListJobs(10)
+	if err != nil {
+		t.Fatalf("ListJobs failed: %v", err)
+	}
+	if len(jobs) != 0 {
+		t.Errorf("expected no jobs to be enqueued, got %d", len(jobs))
+	}
+}
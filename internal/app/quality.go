@@ -0,0 +1,65 @@
+package app
+
+import (
+	"log/slog"
+
+	"github.com/cesargomez89/navidrums/internal/config"
+	"github.com/cesargomez89/navidrums/internal/constants"
+	"github.com/cesargomez89/navidrums/internal/domain"
+)
+
+// highMinBitrate is the lowest average bitrate, in kbps, a lossy file passes as HIGH.
+// Providers serve HIGH as 320 kbps AAC and LOW as 96 kbps, and VBR averages dip below
+// the nominal rate.
+const highMinBitrate = 256
+
+// DeliveredQuality is the quality tier of a saved file, judged from its audio properties
+// rather than what the provider advertised: a lossless file above 16-bit or 48 kHz is
+// HI_RES_LOSSLESS, other lossless files LOSSLESS, and lossy files HIGH or LOW by
+// bitrate. It returns "" when the properties weren't read.
+func DeliveredQuality(sampleRate, bitDepth, bitrate int) string {
+	switch {
+	case bitDepth > 16 || (bitDepth > 0 && sampleRate > 48000):
+		return constants.QualityHiResLossless
+	case bitDepth > 0:
+		return constants.QualityLossless
+	case bitrate >= highMinBitrate:
+		return constants.QualityHigh
+	case bitrate > 0:
+		return constants.QualityLow
+	default:
+		return ""
+	}
+}
+
+// ReconcileAudioQuality replaces a downloaded track's advertised quality and audio mode
+// with what its file holds, read into SampleRate, BitDepth, Bitrate and Channels. A
+// multichannel mode such as DOLBY_ATMOS is kept, since the file's channel count doesn't
+// name it. It logs when the file falls short of the track's TargetQuality.
+func ReconcileAudioQuality(track *domain.Track, logger *slog.Logger) {
+	delivered := DeliveredQuality(track.SampleRate, track.BitDepth, track.Bitrate)
+	if delivered == "" {
+		return
+	}
+	if delivered != track.AudioQuality && track.AudioQuality != "" {
+		logger.Info("Provider quality differs from the file", "advertised", track.AudioQuality, "delivered", delivered)
+	}
+	track.AudioQuality = delivered
+
+	switch track.Channels {
+	case 1:
+		track.AudioModes = "MONO"
+	case 2:
+		track.AudioModes = "STEREO"
+	}
+
+	if IsBelowQuality(delivered, track.TargetQuality) {
+		logger.Warn("Delivered quality is below the requested quality", "requested", track.TargetQuality, "delivered", delivered)
+	}
+}
+
+// IsBelowQuality reports whether delivered ranks under requested. Unknown qualities never do.
+func IsBelowQuality(delivered, requested string) bool {
+	d, r := config.QualityRank(delivered), config.QualityRank(requested)
+	return d > 0 && r > 0 && d < r
+}
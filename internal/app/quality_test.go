@@ -0,0 +1,76 @@
+package app
+
+import (
+	"encoding/binary"
+	"path/filepath"
+	"testing"
+
+	"github.com/go-flac/go-flac"
+
+	"github.com/cesargomez89/navidrums/internal/domain"
+	"github.com/cesargomez89/navidrums/internal/logger"
+	"github.com/cesargomez89/navidrums/internal/tagging"
+)
+
+func TestDeliveredQuality(t *testing.T) {
+	tests := []struct {
+		name       string
+		sampleRate int
+		bitDepth   int
+		bitrate    int
+		want       string
+	}{
+		{"24-bit", 96000, 24, 0, "HI_RES_LOSSLESS"},
+		{"16-bit high sample rate", 96000, 16, 0, "HI_RES_LOSSLESS"},
+		{"CD quality", 44100, 16, 0, "LOSSLESS"},
+		{"320 kbps", 44100, 0, 320, "HIGH"},
+		{"96 kbps", 44100, 0, 96, "LOW"},
+		{"unread", 0, 0, 0, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := DeliveredQuality(tt.sampleRate, tt.bitDepth, tt.bitrate); got != tt.want {
+				t.Errorf("DeliveredQuality() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestReconcileAudioQuality_FLAC(t *testing.T) {
+	// A STREAMINFO block for 44.1 kHz, 16-bit stereo: what a Hi-Res request gets when the
+	// provider only holds the CD master.
+	streamInfo := make([]byte, 34)
+	binary.BigEndian.PutUint64(streamInfo[10:], uint64(44100)<<44|uint64(1)<<41|uint64(15)<<36|44100*10)
+	path := filepath.Join(t.TempDir(), "track.flac")
+	f := &flac.File{
+		Meta:   []*flac.MetaDataBlock{{Type: flac.StreamInfo, Data: streamInfo}},
+		Frames: make([]byte, 1000),
+	}
+	if err := f.Save(path); err != nil {
+		t.Fatalf("failed to write FLAC: %v", err)
+	}
+
+	props, err := tagging.ReadAudioProperties(path)
+	if err != nil {
+		t.Fatalf("ReadAudioProperties failed: %v", err)
+	}
+	track := &domain.Track{
+		AudioQuality:  "HI_RES_LOSSLESS",
+		TargetQuality: "HI_RES_LOSSLESS",
+		SampleRate:    props.SampleRate,
+		BitDepth:      props.BitDepth,
+		Channels:      props.Channels,
+		Bitrate:       props.Bitrate,
+	}
+	ReconcileAudioQuality(track, logger.Default().Logger)
+
+	if track.AudioQuality != "LOSSLESS" || track.AudioModes != "STEREO" {
+		t.Errorf("quality = %q %q, want LOSSLESS STEREO", track.AudioQuality, track.AudioModes)
+	}
+	if !IsBelowQuality(track.AudioQuality, track.TargetQuality) {
+		t.Error("IsBelowQuality() = false, want true for LOSSLESS against a HI_RES_LOSSLESS request")
+	}
+	if IsBelowQuality(track.AudioQuality, "") {
+		t.Error("IsBelowQuality() = true, want false when nothing was requested")
+	}
+}
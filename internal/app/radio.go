@@ -0,0 +1,52 @@
+package app
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/cesargomez89/navidrums/internal/catalog"
+	"github.com/cesargomez89/navidrums/internal/domain"
+)
+
+// RadioPrefix marks a playlist source ID as a track radio: "radio:<track id>" is the radio
+// built from that track rather than a provider playlist.
+const RadioPrefix = "radio:"
+
+// RadioSeed returns the seed track ID of a radio source ID, and whether id is one.
+func RadioSeed(id string) (string, bool) {
+	seed, ok := strings.CutPrefix(id, RadioPrefix)
+	return seed, ok && seed != ""
+}
+
+// BuildRadio builds a playlist on the fly from a seed track: the seed followed by the
+// provider's similar tracks, without repeats. A provider without track radio gives a
+// playlist holding only the seed.
+func BuildRadio(ctx context.Context, provider catalog.Provider, seedID string) (*domain.Playlist, error) {
+	seed, err := provider.GetTrack(ctx, seedID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get seed track: %w", err)
+	}
+	similar, err := provider.GetSimilarTracks(ctx, seedID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get similar tracks: %w", err)
+	}
+
+	tracks := []domain.CatalogTrack{*seed}
+	seen := map[string]bool{seed.ID: true}
+	for _, t := range similar {
+		if t.ID == "" || seen[t.ID] {
+			continue
+		}
+		seen[t.ID] = true
+		tracks = append(tracks, t)
+	}
+
+	return &domain.Playlist{
+		ProviderID:  RadioPrefix + seedID,
+		Title:       seed.Title + " Radio",
+		Description: "Tracks like " + seed.Title + " by " + seed.Artist,
+		ImageURL:    seed.AlbumArtURL,
+		Tracks:      tracks,
+	}, nil
+}
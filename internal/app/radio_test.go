@@ -0,0 +1,65 @@
+package app
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/cesargomez89/navidrums/internal/catalog"
+	"github.com/cesargomez89/navidrums/internal/domain"
+)
+
+// radioProvider serves one seed track and its similar tracks; any other Provider method
+// panics.
+type radioProvider struct {
+	catalog.Provider
+	similar []domain.CatalogTrack
+}
+
+func (p *radioProvider) GetTrack(ctx context.Context, id string) (*domain.CatalogTrack, error) {
+	return &domain.CatalogTrack{ID: id, Title: "Seed", Artist: "Artist", AlbumArtURL: "http://img/seed.jpg"}, nil
+}
+
+func (p *radioProvider) GetSimilarTracks(ctx context.Context, id string) ([]domain.CatalogTrack, error) {
+	return p.similar, nil
+}
+
+func TestBuildRadio(t *testing.T) {
+	tests := []struct {
+		name    string
+		similar []domain.CatalogTrack
+		want    []string
+	}{
+		{
+			name:    "similar tracks follow the seed without repeats",
+			similar: []domain.CatalogTrack{{ID: "2"}, {ID: "1"}, {ID: "3"}, {ID: "2"}},
+			want:    []string{"1", "2", "3"},
+		},
+		{
+			name:    "no track radio",
+			similar: nil,
+			want:    []string{"1"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pl, err := BuildRadio(context.Background(), &radioProvider{similar: tt.similar}, "1")
+			if err != nil {
+				t.Fatalf("BuildRadio failed: %v", err)
+			}
+			var ids []string
+			for _, tr := range pl.Tracks {
+				ids = append(ids, tr.ID)
+			}
+			if !reflect.DeepEqual(ids, tt.want) {
+				t.Errorf("tracks = %v, want %v", ids, tt.want)
+			}
+			if pl.ProviderID != "radio:1" || pl.Title != "Seed Radio" || pl.ImageURL != "http://img/seed.jpg" {
+				t.Errorf("playlist = %q %q %q, want radio:1, Seed Radio and the seed's art", pl.ProviderID, pl.Title, pl.ImageURL)
+			}
+			if seed, ok := RadioSeed(pl.ProviderID); !ok || seed != "1" {
+				t.Errorf("RadioSeed(%q) = %q, %v", pl.ProviderID, seed, ok)
+			}
+		})
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"testing"
 	"time"
 
+	"github.com/cesargomez89/navidrums/internal/config"
 	"github.com/cesargomez89/navidrums/internal/domain"
 	"github.com/cesargomez89/navidrums/internal/logger"
 )
@@ -13,7 +14,7 @@ func TestDownloadsService_GetRecommendationSeeds(t *testing.T) {
 	defer cleanup()
 
 	log := logger.Default()
-	svc := NewDownloadsService(db, log)
+	svc := NewDownloadsService(db, &config.Config{}, log)
 
 	seeds, err := svc.GetRecommendationSeeds()
 	if err != nil {
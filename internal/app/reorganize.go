@@ -0,0 +1,192 @@
+package app
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"path/filepath"
+	"strings"
+
+	"github.com/cesargomez89/navidrums/internal/config"
+	"github.com/cesargomez89/navidrums/internal/domain"
+	"github.com/cesargomez89/navidrums/internal/storage"
+	"github.com/cesargomez89/navidrums/internal/store"
+)
+
+// ReorganizeMove is a downloaded track whose file is not where SUBDIR_TEMPLATE puts it.
+type ReorganizeMove struct {
+	Track    *domain.Track
+	From     string
+	To       string
+	Conflict bool // another file already occupies To, so the track is left in place
+}
+
+// ReorganizePlan is a dry run of a library reorganization: nothing is moved.
+type ReorganizePlan struct {
+	Moves     []ReorganizeMove
+	Conflicts int
+}
+
+// ReorganizeSummary reports the outcome of a library reorganization.
+type ReorganizeSummary struct {
+	Moved     int
+	Conflicts int
+	Failed    int
+}
+
+// LibraryReorganizer moves downloaded files to the paths the current SUBDIR_TEMPLATE
+// gives them, so changing the template doesn't leave the library split across layouts.
+type LibraryReorganizer struct {
+	Repo   *store.DB
+	Config *config.Config
+}
+
+func NewLibraryReorganizer(repo *store.DB, cfg *config.Config) *LibraryReorganizer {
+	return &LibraryReorganizer{Repo: repo, Config: cfg}
+}
+
+// Plan lists the completed tracks that would move. A move is a conflict when its
+// destination is already taken by a file on disk or by an earlier track in the plan.
+func (r *LibraryReorganizer) Plan() (*ReorganizePlan, error) {
+	tracks, err := r.Repo.ListAllCompletedTracks()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list tracks: %w", err)
+	}
+
+	plan := &ReorganizePlan{}
+	claimed := make(map[string]bool)
+	for _, track := range tracks {
+		if track.FilePath == "" {
+			continue
+		}
+		to, err := ExpectedTrackPath(r.Config, track)
+		if err != nil {
+			return nil, fmt.Errorf("failed to build path for track %d: %w", track.ID, err)
+		}
+		if to == track.FilePath {
+			claimed[to] = true
+			continue
+		}
+		plan.Moves = append(plan.Moves, ReorganizeMove{Track: track, From: track.FilePath, To: to})
+	}
+
+	for i := range plan.Moves {
+		move := &plan.Moves[i]
+		if claimed[move.To] || storage.FileExists(move.To) {
+			move.Conflict = true
+			plan.Conflicts++
+		}
+		claimed[move.To] = true
+	}
+	return plan, nil
+}
+
+// Reorganize applies the plan, skipping conflicts. A track that fails to move is counted
+// and skipped; only a failure to record a moved file stops the run. onProgress, when
+// set, is called after each move with the number handled so far.
+func (r *LibraryReorganizer) Reorganize(ctx context.Context, logger *slog.Logger, onProgress func(done, total int)) (*ReorganizeSummary, error) {
+	plan, err := r.Plan()
+	if err != nil {
+		return nil, err
+	}
+
+	summary := &ReorganizeSummary{}
+	for i, move := range plan.Moves {
+		if err := ctx.Err(); err != nil {
+			return summary, err
+		}
+
+		if move.Conflict {
+			summary.Conflicts++
+			logger.Warn("Destination already exists, leaving track in place", "track_id", move.Track.ID, "from", move.From, "to", move.To)
+		} else if err := RelocateTrackFile(r.Config, move.Track, move.From, logger); err != nil {
+			summary.Failed++
+		} else {
+			if err := r.Repo.UpdateTrack(move.Track); err != nil {
+				return summary, fmt.Errorf("failed to update track %d: %w", move.Track.ID, err)
+			}
+			summary.Moved++
+		}
+
+		if onProgress != nil {
+			onProgress(i+1, len(plan.Moves))
+		}
+	}
+	return summary, nil
+}
+
+// ExpectedTrackPath is where SUBDIR_TEMPLATE puts a downloaded track, keeping the
+// extension of its current file when none is recorded.
+func ExpectedTrackPath(cfg *config.Config, track *domain.Track) (string, error) {
+	pathNoExt, err := BuildTrackPath(cfg, track)
+	if err != nil {
+		return "", err
+	}
+	return pathNoExt + TrackExtension(track.FileExtension, filepath.Ext(track.FilePath)), nil
+}
+
+// RelocateTrackFile moves a track's file from oldFilePath to the path SUBDIR_TEMPLATE
+// gives it, along with its lyrics sidecar and a copy of the folder cover, then removes
+// the folders the move left empty. track.FilePath is updated; saving it is up to the
+// caller.
+func RelocateTrackFile(cfg *config.Config, track *domain.Track, oldFilePath string, logger *slog.Logger) error {
+	if oldFilePath == "" {
+		return nil
+	}
+
+	oldDir := filepath.Dir(oldFilePath)
+
+	expectedPathNoExt, err := BuildTrackPath(cfg, track)
+	if err != nil {
+		logger.Error("Failed to build expected path", "error", err)
+		return err
+	}
+	expectedPath := expectedPathNoExt + TrackExtension(track.FileExtension, filepath.Ext(oldFilePath))
+
+	if oldFilePath == expectedPath {
+		return nil
+	}
+
+	track.FilePath = expectedPath
+	newDir := filepath.Dir(track.FilePath)
+
+	if err := storage.EnsureDir(newDir); err != nil {
+		logger.Error("Failed to create new directory", "dir", newDir, "error", err)
+		return err
+	}
+
+	if err := storage.MoveFile(oldFilePath, track.FilePath); err != nil {
+		logger.Error("Failed to move audio file", "old", oldFilePath, "new", track.FilePath, "error", err)
+		track.FilePath = oldFilePath
+		return err
+	}
+
+	oldLyricsPath := storage.LyricsSidecarPath(oldFilePath)
+	if storage.FileExists(oldLyricsPath) {
+		newLyricsPath := storage.LyricsSidecarPath(track.FilePath)
+		if err := storage.MoveFile(oldLyricsPath, newLyricsPath); err != nil {
+			logger.Warn("Failed to move lyrics sidecar", "old", oldLyricsPath, "new", newLyricsPath, "error", err)
+		}
+	}
+
+	if oldCoverPath := storage.FindCover(oldDir); oldCoverPath != "" && storage.FindCover(newDir) == "" {
+		newCoverPath := filepath.Join(newDir, filepath.Base(oldCoverPath))
+		if err := storage.CopyFile(oldCoverPath, newCoverPath); err != nil {
+			logger.Warn("Failed to copy cover file", "old", oldCoverPath, "new", newCoverPath, "error", err)
+		}
+	}
+
+	// Walk up from the old folder, so album and artist folders that only held art go too.
+	root := filepath.Clean(TrackDownloadsDir(cfg, track))
+	for dir := oldDir; strings.HasPrefix(dir, root+string(filepath.Separator)); dir = filepath.Dir(dir) {
+		if err := storage.DeleteFolderWithCover(dir); err != nil {
+			logger.Warn("Failed to clean up old directory", "dir", dir, "error", err)
+			break
+		}
+		if storage.FileExists(dir) {
+			break
+		}
+	}
+
+	return nil
+}
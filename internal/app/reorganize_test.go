@@ -0,0 +1,169 @@
+package app
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/cesargomez89/navidrums/internal/config"
+	"github.com/cesargomez89/navidrums/internal/constants"
+	"github.com/cesargomez89/navidrums/internal/domain"
+	"github.com/cesargomez89/navidrums/internal/logger"
+	"github.com/cesargomez89/navidrums/internal/storage"
+)
+
+func TestLibraryReorganizer(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	dir := t.TempDir()
+	cfg := &config.Config{DownloadsDir: dir, SubdirTemplate: "{{.AlbumArtist}} - {{.Album}}/{{.Track}} {{.Title}}"}
+
+	writeFile := func(rel string) string {
+		path := filepath.Join(dir, rel)
+		if err := os.MkdirAll(filepath.Dir(path), 0o750); err != nil {
+			t.Fatalf("MkdirAll failed: %v", err)
+		}
+		if err := os.WriteFile(path, []byte(rel), 0o600); err != nil {
+			t.Fatalf("WriteFile failed: %v", err)
+		}
+		return path
+	}
+	newTrack := func(providerID, artist, album, title string, number int, path string) *domain.Track {
+		track := &domain.Track{
+			ProviderID:    providerID,
+			Title:         title,
+			Artist:        artist,
+			AlbumArtist:   artist,
+			Album:         album,
+			TrackNumber:   number,
+			FilePath:      path,
+			FileExtension: ".flac",
+			Status:        domain.TrackStatusCompleted,
+			CreatedAt:     time.Now(),
+			UpdatedAt:     time.Now(),
+		}
+		if err := db.CreateTrack(track); err != nil {
+			t.Fatalf("CreateTrack failed: %v", err)
+		}
+		return track
+	}
+
+	// Old layout: Artist/Album/Title, with folder art and a lyrics sidecar.
+	moved := newTrack("moved", "Artist", "Album", "One", 1, writeFile("Artist/Album/One.flac"))
+	writeFile("Artist/Album/One.lrc")
+	writeFile("Artist/Album/cover.jpg")
+	writeFile("Artist/artist.jpg")
+	// Its destination is taken by a file the library doesn't know about.
+	conflict := newTrack("conflict", "Other", "Record", "Two", 2, writeFile("Other/Record/Two.flac"))
+	writeFile("Other - Record/02 Two.flac")
+	// Already in the new layout.
+	inPlace := newTrack("in-place", "Third", "Disc", "Three", 3, writeFile("Third - Disc/03 Three.flac"))
+
+	reorganizer := NewLibraryReorganizer(db, cfg)
+
+	plan, err := reorganizer.Plan()
+	if err != nil {
+		t.Fatalf("Plan failed: %v", err)
+	}
+	wantMoves := map[string]bool{"moved": false, "conflict": true}
+	if len(plan.Moves) != len(wantMoves) || plan.Conflicts != 1 {
+		t.Fatalf("Plan() = %d moves with %d conflicts, want 2 with 1", len(plan.Moves), plan.Conflicts)
+	}
+	for _, move := range plan.Moves {
+		wantConflict, ok := wantMoves[move.Track.ProviderID]
+		if !ok {
+			t.Errorf("Plan() moves unexpected track %s", move.Track.ProviderID)
+		} else if move.Conflict != wantConflict {
+			t.Errorf("move of %s: Conflict = %v, want %v", move.Track.ProviderID, move.Conflict, wantConflict)
+		}
+	}
+	if !storage.FileExists(moved.FilePath) {
+		t.Fatal("Plan() moved a file")
+	}
+
+	summary, err := reorganizer.Reorganize(context.Background(), logger.Default().Logger, nil)
+	if err != nil {
+		t.Fatalf("Reorganize failed: %v", err)
+	}
+	if summary.Moved != 1 || summary.Conflicts != 1 || summary.Failed != 0 {
+		t.Errorf("summary = %+v, want 1 moved and 1 conflict", summary)
+	}
+
+	tests := []struct {
+		name       string
+		providerID string
+		wantPath   string
+	}{
+		{"moved", moved.ProviderID, filepath.Join(dir, "Artist - Album", "01 One.flac")},
+		{"conflict stays", conflict.ProviderID, conflict.FilePath},
+		{"already in place", inPlace.ProviderID, inPlace.FilePath},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			track, err := db.GetTrackByProviderID(tt.providerID)
+			if err != nil {
+				t.Fatalf("GetTrackByProviderID failed: %v", err)
+			}
+			if track.FilePath != tt.wantPath {
+				t.Errorf("FilePath = %q, want %q", track.FilePath, tt.wantPath)
+			}
+			if !storage.FileExists(tt.wantPath) {
+				t.Errorf("%s does not exist", tt.wantPath)
+			}
+		})
+	}
+
+	for _, rel := range []string{"Artist - Album/01 One.lrc", "Artist - Album/cover.jpg"} {
+		if !storage.FileExists(filepath.Join(dir, rel)) {
+			t.Errorf("%s was not moved along with the track", rel)
+		}
+	}
+	if storage.FileExists(filepath.Join(dir, "Artist")) {
+		t.Error("emptied artist folder was not removed")
+	}
+	if !storage.FileExists(filepath.Join(dir, "Other", "Record", "Two.flac")) {
+		t.Error("conflicting track was moved")
+	}
+}
+
+func TestRelocateTrackFile_MultiDiscLayout(t *testing.T) {
+	tests := []struct {
+		name   string
+		layout string
+		from   string
+		want   string
+	}{
+		{"subfolders in place", constants.MultiDiscLayoutSubfolders, "Artist/2020 - Album/Disc 2/02-01 Song.flac", "Artist/2020 - Album/Disc 2/02-01 Song.flac"},
+		{"flat in place", constants.MultiDiscLayoutFlat, "Artist/2020 - Album/02-01 Song.flac", "Artist/2020 - Album/02-01 Song.flac"},
+		{"into a disc folder", constants.MultiDiscLayoutSubfolders, "Artist/2020 - Album/02-01 Song.flac", "Artist/2020 - Album/Disc 2/02-01 Song.flac"},
+		{"out of a disc folder", constants.MultiDiscLayoutFlat, "Artist/2020 - Album/Disc 2/02-01 Song.flac", "Artist/2020 - Album/02-01 Song.flac"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			cfg := &config.Config{DownloadsDir: dir, SubdirTemplate: constants.DefaultSubdirTemplate, MultiDiscLayout: tt.layout}
+			from := filepath.Join(dir, filepath.FromSlash(tt.from))
+			if err := os.MkdirAll(filepath.Dir(from), 0o750); err != nil {
+				t.Fatalf("MkdirAll failed: %v", err)
+			}
+			if err := os.WriteFile(from, []byte("audio"), 0o600); err != nil {
+				t.Fatalf("WriteFile failed: %v", err)
+			}
+			track := &domain.Track{
+				Title: "Song", Artist: "Artist", Album: "Album", Year: 2020, DiscNumber: 2, TotalDiscs: 2,
+				TrackNumber: 1, FilePath: from, FileExtension: ".flac",
+			}
+
+			if err := RelocateTrackFile(cfg, track, from, logger.Default().Logger); err != nil {
+				t.Fatalf("RelocateTrackFile failed: %v", err)
+			}
+			want := filepath.Join(dir, filepath.FromSlash(tt.want))
+			if track.FilePath != want || !storage.FileExists(want) {
+				t.Errorf("file at %q (exists: %v), want %q", track.FilePath, storage.FileExists(want), want)
+			}
+		})
+	}
+}
@@ -0,0 +1,37 @@
+package app
+
+import (
+	"math"
+	"testing"
+
+	"github.com/cesargomez89/navidrums/internal/domain"
+)
+
+func TestAlbumReplayGain(t *testing.T) {
+	tests := []struct {
+		name     string
+		tracks   []domain.CatalogTrack
+		wantGain float64
+		wantPeak float64
+		wantOK   bool
+	}{
+		{"no tracks", nil, 0, 0, false},
+		{"single track", []domain.CatalogTrack{{Duration: 180, ReplayGain: -6.5, Peak: 0.8}}, -6.5, 0.8, true},
+		{"louder track weighs more", []domain.CatalogTrack{
+			{Duration: 200, ReplayGain: -8, Peak: 0.9},
+			{Duration: 100, ReplayGain: -5, Peak: 0.99},
+		}, -7.21, 0.99, true},
+		{"a track without gain", []domain.CatalogTrack{
+			{Duration: 200, ReplayGain: -8, Peak: 0.9},
+			{Duration: 100, Peak: 0.99},
+		}, 0, 0, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gain, peak, ok := albumReplayGain(tt.tracks)
+			if ok != tt.wantOK || math.Abs(gain-tt.wantGain) > 0.005 || peak != tt.wantPeak {
+				t.Errorf("albumReplayGain() = %.3f, %v, %v, want %.2f, %v, %v", gain, peak, ok, tt.wantGain, tt.wantPeak, tt.wantOK)
+			}
+		})
+	}
+}
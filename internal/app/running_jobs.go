@@ -0,0 +1,55 @@
+package app
+
+import (
+	"context"
+	"sync"
+)
+
+// RunningJobs holds the cancel functions of the jobs the worker is running, so cancelling
+// a job interrupts it mid-download instead of at its next cancellation check.
+type RunningJobs struct {
+	jobs map[string]runningJob
+	mu   sync.Mutex
+}
+
+type runningJob struct {
+	cancel   context.CancelFunc
+	parentID string
+}
+
+func NewRunningJobs() *RunningJobs {
+	return &RunningJobs{jobs: make(map[string]runningJob)}
+}
+
+// Start derives the context a job runs under. The returned function must be called when
+// the job returns.
+func (r *RunningJobs) Start(ctx context.Context, jobID, parentID string) (context.Context, func()) {
+	jobCtx, cancel := context.WithCancel(ctx)
+
+	r.mu.Lock()
+	r.jobs[jobID] = runningJob{cancel: cancel, parentID: parentID}
+	r.mu.Unlock()
+
+	return jobCtx, func() {
+		r.mu.Lock()
+		delete(r.jobs, jobID)
+		r.mu.Unlock()
+		cancel()
+	}
+}
+
+// Cancel cancels a running job and its running child jobs, returning how many were
+// interrupted.
+func (r *RunningJobs) Cancel(jobID string) int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	n := 0
+	for id, job := range r.jobs {
+		if id == jobID || job.parentID == jobID {
+			job.cancel()
+			n++
+		}
+	}
+	return n
+}
@@ -0,0 +1,65 @@
+package app
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"strings"
+)
+
+var (
+	ErrNoTrackFile     = errors.New("track has no file")
+	ErrPathNotRevealed = errors.New("track file is outside the downloads directories")
+)
+
+// TrackFilePath is where a downloaded track's file is on disk.
+type TrackFilePath struct {
+	Path   string `json:"path"`
+	Folder string `json:"folder"`
+}
+
+// TrackFilePath returns the absolute path of a track's file and the folder holding it. A
+// path outside DOWNLOADS_DIR and the DOWNLOADS_DIR_MAP directories is never revealed, so
+// a tampered file_path can't be used to probe the rest of the filesystem.
+func (s *DownloadsService) TrackFilePath(id int) (*TrackFilePath, error) {
+	track, err := s.Repo.GetTrackByID(id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get track: %w", err)
+	}
+	if track.FilePath == "" {
+		return nil, ErrNoTrackFile
+	}
+	path := s.RevealedPath(track.FilePath)
+	if path == "" {
+		return nil, ErrPathNotRevealed
+	}
+	return &TrackFilePath{Path: path, Folder: filepath.Dir(path)}, nil
+}
+
+// RevealedPath returns the absolute form of a track's file path when it may be shown: the
+// file is under DOWNLOADS_DIR or a DOWNLOADS_DIR_MAP directory. Otherwise it returns "".
+func (s *DownloadsService) RevealedPath(filePath string) string {
+	if filePath == "" {
+		return ""
+	}
+	path, err := filepath.Abs(filePath)
+	if err != nil {
+		return ""
+	}
+	for _, root := range s.Config.DownloadsDirs() {
+		if isUnder(root, path) {
+			return path
+		}
+	}
+	return ""
+}
+
+// isUnder reports whether path is inside dir, not dir itself.
+func isUnder(dir, path string) bool {
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return false
+	}
+	rel, err := filepath.Rel(dir, path)
+	return err == nil && rel != "." && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
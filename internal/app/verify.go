@@ -0,0 +1,125 @@
+package app
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/cesargomez89/navidrums/internal/domain"
+	"github.com/cesargomez89/navidrums/internal/storage"
+	"github.com/cesargomez89/navidrums/internal/store"
+)
+
+const (
+	verifyErrChanged = "File changed on disk: hash mismatch"
+	verifyErrMissing = "File missing on disk"
+)
+
+// LibraryVerifier re-hashes downloaded files and flags the ones that no longer match.
+type LibraryVerifier struct {
+	Repo *store.DB
+}
+
+func NewLibraryVerifier(repo *store.DB) *LibraryVerifier {
+	return &LibraryVerifier{Repo: repo}
+}
+
+// Verify checks every completed track in scope, which is either an album ID or
+// domain.VerifyLibrarySourceID for the whole library. onProgress, when set, is called
+// after each track with the number checked so far.
+func (v *LibraryVerifier) Verify(ctx context.Context, scope string, onProgress func(done, total int)) (*domain.VerifySummary, error) {
+	var tracks []*domain.Track
+	var err error
+	if scope == "" || scope == domain.VerifyLibrarySourceID {
+		scope = domain.VerifyLibrarySourceID
+		tracks, err = v.Repo.ListAllCompletedTracks()
+	} else {
+		tracks, err = v.Repo.ListCompletedTracksByAlbumID(scope)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to list tracks: %w", err)
+	}
+
+	summary := &domain.VerifySummary{Scope: scope}
+	for i, track := range tracks {
+		if err := ctx.Err(); err != nil {
+			return summary, err
+		}
+
+		if err := v.verifyTrack(track, summary); err != nil {
+			return summary, err
+		}
+
+		if onProgress != nil {
+			onProgress(i+1, len(tracks))
+		}
+	}
+
+	summary.FinishedAt = time.Now()
+	return summary, nil
+}
+
+func (v *LibraryVerifier) verifyTrack(track *domain.Track, summary *domain.VerifySummary) error {
+	hash, err := storage.HashFile(track.FilePath)
+	switch {
+	case storage.IsNotExist(err):
+		summary.Missing++
+		return v.mark(track, domain.TrackStatusMissingFile, track.FileHash, verifyErrMissing)
+	case err != nil:
+		// Unreadable files are reported as missing rather than aborting the whole run.
+		summary.Missing++
+		return v.mark(track, domain.TrackStatusFailed, track.FileHash, fmt.Sprintf("File unreadable: %v", err))
+	case track.FileHash != "" && hash != track.FileHash:
+		summary.Changed++
+		return v.mark(track, domain.TrackStatusFailed, track.FileHash, verifyErrChanged)
+	default:
+		// Tracks downloaded before hashing was introduced adopt the current hash.
+		summary.OK++
+		return v.mark(track, domain.TrackStatusCompleted, hash, "")
+	}
+}
+
+func (v *LibraryVerifier) mark(track *domain.Track, status domain.TrackStatus, hash, errMsg string) error {
+	if err := v.Repo.MarkTrackVerified(track.ID, status, hash, errMsg); err != nil {
+		return fmt.Errorf("failed to update track %d: %w", track.ID, err)
+	}
+	if status != domain.TrackStatusCompleted && track.AlbumID != "" {
+		_, _ = v.Repo.RecomputeAlbumState(track.AlbumID)
+	}
+	return nil
+}
+
+// SweepMissingFiles stats every completed track's file, pageSize rows at a time, and
+// flags the ones that no longer exist as domain.TrackStatusMissingFile. Only a stat
+// is done per track, so the sweep stays cheap on large libraries.
+func (v *LibraryVerifier) SweepMissingFiles(ctx context.Context, pageSize int) (int, error) {
+	lastID := 0
+	flagged := 0
+	for {
+		tracks, err := v.Repo.ListCompletedTracksAfterID(lastID, pageSize)
+		if err != nil {
+			return flagged, fmt.Errorf("failed to list tracks: %w", err)
+		}
+
+		for _, track := range tracks {
+			if err := ctx.Err(); err != nil {
+				return flagged, err
+			}
+			if storage.FileExists(track.FilePath) {
+				continue
+			}
+			if err := v.Repo.MarkTrackMissingFile(track.ID); err != nil {
+				return flagged, fmt.Errorf("failed to flag track %d: %w", track.ID, err)
+			}
+			if track.AlbumID != "" {
+				_, _ = v.Repo.RecomputeAlbumState(track.AlbumID)
+			}
+			flagged++
+		}
+
+		if len(tracks) < pageSize {
+			return flagged, nil
+		}
+		lastID = tracks[len(tracks)-1].ID
+	}
+}
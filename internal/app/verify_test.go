@@ -0,0 +1,175 @@
+package app
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/cesargomez89/navidrums/internal/constants"
+	"github.com/cesargomez89/navidrums/internal/domain"
+	"github.com/cesargomez89/navidrums/internal/storage"
+)
+
+func TestLibraryVerifier_Verify(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	tmpDir := t.TempDir()
+	writeFile := func(name, content string) string {
+		path := filepath.Join(tmpDir, name)
+		if err := os.WriteFile(path, []byte(content), constants.FilePermissions); err != nil {
+			t.Fatalf("WriteFile failed: %v", err)
+		}
+		return path
+	}
+
+	okPath := writeFile("ok.flac", "original")
+	okHash, err := storage.HashFile(okPath)
+	if err != nil {
+		t.Fatalf("HashFile failed: %v", err)
+	}
+	changedPath := writeFile("changed.flac", "tampered")
+	legacyPath := writeFile("legacy.flac", "no hash yet")
+
+	tracks := []*domain.Track{
+		{ProviderID: "verify_ok", Title: "OK", AlbumID: "album_a", FilePath: okPath, FileHash: okHash},
+		{ProviderID: "verify_changed", Title: "Changed", AlbumID: "album_a", FilePath: changedPath, FileHash: okHash},
+		{ProviderID: "verify_missing", Title: "Missing", AlbumID: "album_b", FilePath: filepath.Join(tmpDir, "gone.flac"), FileHash: okHash},
+		{ProviderID: "verify_legacy", Title: "Legacy", AlbumID: "album_b", FilePath: legacyPath},
+	}
+	for _, tr := range tracks {
+		tr.Status = domain.TrackStatusCompleted
+		tr.CreatedAt = time.Now()
+		tr.UpdatedAt = time.Now()
+		if err := db.CreateTrack(tr); err != nil {
+			t.Fatalf("CreateTrack failed: %v", err)
+		}
+	}
+
+	verifier := NewLibraryVerifier(db)
+
+	albumSummary, err := verifier.Verify(context.Background(), "album_a", nil)
+	if err != nil {
+		t.Fatalf("Verify album failed: %v", err)
+	}
+	if albumSummary.OK != 1 || albumSummary.Changed != 1 || albumSummary.Missing != 0 {
+		t.Errorf("Unexpected album summary: %+v", albumSummary)
+	}
+
+	var progressCalls int
+	summary, err := verifier.Verify(context.Background(), "", func(done, total int) { progressCalls++ })
+	if err != nil {
+		t.Fatalf("Verify library failed: %v", err)
+	}
+	if summary.Scope != domain.VerifyLibrarySourceID {
+		t.Errorf("Expected scope %q, got %q", domain.VerifyLibrarySourceID, summary.Scope)
+	}
+	// The changed track was flagged by the album run and is no longer completed.
+	if summary.OK != 2 || summary.Changed != 0 || summary.Missing != 1 {
+		t.Errorf("Unexpected library summary: %+v", summary)
+	}
+	if progressCalls != 3 {
+		t.Errorf("Expected 3 progress calls, got %d", progressCalls)
+	}
+
+	tests := []struct {
+		providerID string
+		status     domain.TrackStatus
+		wantError  bool
+	}{
+		{"verify_ok", domain.TrackStatusCompleted, false},
+		{"verify_changed", domain.TrackStatusFailed, true},
+		{"verify_missing", domain.TrackStatusMissingFile, true},
+		{"verify_legacy", domain.TrackStatusCompleted, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.providerID, func(t *testing.T) {
+			track, err := db.GetTrackByProviderID(tt.providerID)
+			if err != nil {
+				t.Fatalf("GetTrackByProviderID failed: %v", err)
+			}
+			if track.Status != tt.status {
+				t.Errorf("Expected status %s, got %s", tt.status, track.Status)
+			}
+			if (track.Error != "") != tt.wantError {
+				t.Errorf("Unexpected error message %q", track.Error)
+			}
+			if track.LastVerifiedAt == nil {
+				t.Error("Expected LastVerifiedAt to be set")
+			}
+			if track.FileHash == "" {
+				t.Error("Expected FileHash to be set")
+			}
+		})
+	}
+}
+
+func TestLibraryVerifier_SweepMissingFiles(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	tmpDir := t.TempDir()
+	presentPath := filepath.Join(tmpDir, "present.flac")
+	if err := os.WriteFile(presentPath, []byte("audio"), constants.FilePermissions); err != nil {
+		t.Fatalf("WriteFile failed: %v", err)
+	}
+
+	now := time.Now()
+	tracks := []*domain.Track{
+		{ProviderID: "sweep_present", FilePath: presentPath},
+		{ProviderID: "sweep_gone_1", FilePath: filepath.Join(tmpDir, "gone1.flac")},
+		{ProviderID: "sweep_gone_2", FilePath: filepath.Join(tmpDir, "gone2.flac")},
+		{ProviderID: "sweep_trashed", FilePath: filepath.Join(tmpDir, "trashed.flac"), DeletedAt: &now},
+	}
+	for _, tr := range tracks {
+		tr.Title = tr.ProviderID
+		tr.Status = domain.TrackStatusCompleted
+		tr.CreatedAt = now
+		tr.UpdatedAt = now
+		if err := db.CreateTrack(tr); err != nil {
+			t.Fatalf("CreateTrack failed: %v", err)
+		}
+	}
+
+	verifier := NewLibraryVerifier(db)
+
+	// A page size of 1 exercises paging across every row.
+	flagged, err := verifier.SweepMissingFiles(context.Background(), 1)
+	if err != nil {
+		t.Fatalf("SweepMissingFiles failed: %v", err)
+	}
+	if flagged != 2 {
+		t.Errorf("Expected 2 flagged tracks, got %d", flagged)
+	}
+
+	tests := []struct {
+		providerID string
+		status     domain.TrackStatus
+	}{
+		{"sweep_present", domain.TrackStatusCompleted},
+		{"sweep_gone_1", domain.TrackStatusMissingFile},
+		{"sweep_gone_2", domain.TrackStatusMissingFile},
+		{"sweep_trashed", domain.TrackStatusCompleted},
+	}
+	for _, tt := range tests {
+		t.Run(tt.providerID, func(t *testing.T) {
+			track, err := db.GetTrackByProviderID(tt.providerID)
+			if err != nil {
+				t.Fatalf("GetTrackByProviderID failed: %v", err)
+			}
+			if track.Status != tt.status {
+				t.Errorf("Expected status %s, got %s", tt.status, track.Status)
+			}
+		})
+	}
+
+	count, err := db.CountTracksByStatus(domain.TrackStatusMissingFile)
+	if err != nil {
+		t.Fatalf("CountTracksByStatus failed: %v", err)
+	}
+	if count != 2 {
+		t.Errorf("Expected 2 missing-file tracks, got %d", count)
+	}
+}
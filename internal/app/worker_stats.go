@@ -0,0 +1,115 @@
+package app
+
+import (
+	"fmt"
+	"sync/atomic"
+	"time"
+
+	"github.com/cesargomez89/navidrums/internal/domain"
+)
+
+// WorkerStats is the worker's live state: its slots and how many jobs hold one. The worker
+// updates it as jobs start and finish, so the status endpoint can report it without
+// reaching into the worker.
+type WorkerStats struct {
+	slots         atomic.Int32
+	metadataSlots atomic.Int32
+	active        atomic.Int32
+	stopped       atomic.Bool
+	windowOpens   atomic.Int64 // unix nanoseconds; 0 while the download window is open
+}
+
+func NewWorkerStats() *WorkerStats {
+	return &WorkerStats{}
+}
+
+// SetSlots records how many download and metadata jobs the worker runs at once.
+func (s *WorkerStats) SetSlots(slots, metadataSlots int) {
+	s.slots.Store(int32(slots))                 //nolint:gosec // slot counts are small
+	s.metadataSlots.Store(int32(metadataSlots)) //nolint:gosec // slot counts are small
+}
+
+// JobStarted and JobFinished bracket every job the worker runs.
+func (s *WorkerStats) JobStarted()  { s.active.Add(1) }
+func (s *WorkerStats) JobFinished() { s.active.Add(-1) }
+
+// Stop records that the worker no longer starts jobs.
+func (s *WorkerStats) Stop() { s.stopped.Store(true) }
+
+// Active returns how many jobs are running in the worker.
+func (s *WorkerStats) Active() int { return int(s.active.Load()) }
+
+// SetWindowOpens records when the download window next opens while the worker waits for
+// it, or the zero time once it is open.
+func (s *WorkerStats) SetWindowOpens(t time.Time) {
+	if t.IsZero() {
+		s.windowOpens.Store(0)
+		return
+	}
+	s.windowOpens.Store(t.UnixNano())
+}
+
+// WindowOpens returns when the download window next opens, or the zero time while it is
+// open.
+func (s *WorkerStats) WindowOpens() time.Time {
+	if ns := s.windowOpens.Load(); ns != 0 {
+		return time.Unix(0, ns)
+	}
+	return time.Time{}
+}
+
+// WorkerStatus is a snapshot of the worker and the queue it drains.
+type WorkerStatus struct {
+	ActiveSlots       int  `json:"active_slots"`
+	MaxConcurrent     int  `json:"max_concurrent"`
+	MaxMetadata       int  `json:"max_metadata"`
+	Queued            int  `json:"queued"`
+	Running           int  `json:"running"`
+	CompletedLastHour int  `json:"completed_last_hour"`
+	Paused            bool `json:"paused"`
+	// NextWindowStart is when new jobs start again, while the queue waits for the
+	// download window.
+	NextWindowStart *time.Time `json:"next_window_start,omitempty"`
+}
+
+// WorkerStatus reports the worker's slots alongside the queued and running jobs and how
+// many jobs completed in the last hour. The queue counts as paused when the worker has
+// stopped, while a reorganize job holds it, or outside the download window. Without Stats only the store's counts are
+// reported.
+func (s *JobService) WorkerStatus() (*WorkerStatus, error) {
+	counts, err := s.Repo.CountJobsByStatus()
+	if err != nil {
+		return nil, fmt.Errorf("failed to count jobs: %w", err)
+	}
+	completed, err := s.Repo.CountJobsCompletedSince(time.Now().Add(-time.Hour))
+	if err != nil {
+		return nil, fmt.Errorf("failed to count completed jobs: %w", err)
+	}
+	reorganize := []domain.JobType{domain.JobTypeReorganize}
+	reorganizing, err := s.Repo.CountRunningJobs(reorganize, false)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check for reorganize jobs: %w", err)
+	}
+	waiting, err := s.Repo.ListQueuedJobs(reorganize, false, 1)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check for reorganize jobs: %w", err)
+	}
+
+	status := &WorkerStatus{
+		Queued:            counts[string(domain.JobStatusQueued)],
+		Running:           counts[string(domain.JobStatusRunning)],
+		CompletedLastHour: completed,
+		Paused:            reorganizing > 0 || len(waiting) > 0,
+	}
+	if s.Stats != nil {
+		status.ActiveSlots = s.Stats.Active()
+		status.MaxConcurrent = int(s.Stats.slots.Load())
+		status.MaxMetadata = int(s.Stats.metadataSlots.Load())
+		status.Paused = status.Paused || s.Stats.stopped.Load()
+		if opens := s.Stats.WindowOpens(); !opens.IsZero() {
+			status.NextWindowStart = &opens
+			status.Paused = true
+		}
+	}
+	return status, nil
+}
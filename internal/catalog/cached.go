@@ -31,8 +31,8 @@ func NewCachedProvider(provider Provider, cache Cache, cacheTTL time.Duration) *
 	}
 }
 
-func (c *CachedProvider) Search(ctx context.Context, query string, searchType string) (*domain.SearchResult, error) {
-	cacheKey := fmt.Sprintf("search:%s:%s", searchType, query)
+func (c *CachedProvider) Search(ctx context.Context, query string, searchType string, offset, limit int) (*domain.SearchResult, error) {
+	cacheKey := fmt.Sprintf("search:%s:%d:%d:%s", searchType, offset, limit, query)
 
 	data, err := c.cache.GetCache(cacheKey)
 	if err != nil {
@@ -45,10 +45,14 @@ func (c *CachedProvider) Search(ctx context.Context, query string, searchType st
 		}
 	}
 
-	result, err := c.provider.Search(ctx, query, searchType)
+	result, err := searchWithNormalizedFallback(ctx, c.provider, query, searchType, offset, limit)
 	if err != nil {
 		return nil, err
 	}
+	// Partial results are served but not cached, so the failed categories are retried.
+	if len(result.FailedTypes) > 0 {
+		return result, nil
+	}
 
 	if data, marshalErr := json.Marshal(result); marshalErr == nil {
 		_ = c.cache.SetCache(cacheKey, data, c.cacheTTL)
@@ -249,6 +253,34 @@ func (c *CachedProvider) GetRecommendations(ctx context.Context, id string) ([]d
 	return tracks, nil
 }
 
+func (c *CachedProvider) GetSimilarTracks(ctx context.Context, id string) ([]domain.CatalogTrack, error) {
+	cacheKey := fmt.Sprintf("similar-tracks:%s", id)
+
+	data, err := c.cache.GetCache(cacheKey)
+	if err != nil {
+		return nil, err
+	}
+	if data != nil {
+		var tracks []domain.CatalogTrack
+		if unmarshalErr := json.Unmarshal(data, &tracks); unmarshalErr == nil {
+			return tracks, nil
+		}
+	}
+
+	tracks, err := c.provider.GetSimilarTracks(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(tracks) > 0 {
+		if data, marshalErr := json.Marshal(tracks); marshalErr == nil {
+			_ = c.cache.SetCache(cacheKey, data, c.cacheTTL)
+		}
+	}
+
+	return tracks, nil
+}
+
 func (c *CachedProvider) GetLyrics(ctx context.Context, trackID string) (string, string, error) {
 	return c.provider.GetLyrics(ctx, trackID)
 }
@@ -259,16 +291,20 @@ func (c *CachedProvider) ClearCache() error {
 
 var _ Provider = (*CachedProvider)(nil)
 
+// storeCache keeps responses in the database cache table. Keys are prefixed with the
+// provider type, since Hi-Fi and Qobuz IDs overlap and switching providers must not serve
+// the other catalog's album or artist.
 type storeCache struct {
-	store *store.DB
+	store  *store.DB
+	prefix string
 }
 
 func (s *storeCache) GetCache(key string) ([]byte, error) {
-	return s.store.GetCache(key)
+	return s.store.GetCache(s.prefix + key)
 }
 
 func (s *storeCache) SetCache(key string, data []byte, ttl time.Duration) error {
-	return s.store.SetCache(key, data, ttl)
+	return s.store.SetCache(s.prefix+key, data, ttl)
 }
 
 func (s *storeCache) ClearCache() error {
@@ -3,12 +3,27 @@ package catalog
 import (
 	"context"
 	"errors"
+	"path/filepath"
 	"testing"
 	"time"
 
 	"github.com/cesargomez89/navidrums/internal/domain"
+	"github.com/cesargomez89/navidrums/internal/store"
 )
 
+func setupTestDB(t *testing.T) (*store.DB, func()) {
+	db, err := store.NewSQLiteDB(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("Failed to open db: %v", err)
+	}
+	cleanup := func() {
+		if cErr := db.Close(); cErr != nil {
+			t.Logf("db.Close error: %v", cErr)
+		}
+	}
+	return db, cleanup
+}
+
 func TestCachedProvider_Search(t *testing.T) {
 	inner := &mockProvider{}
 	cache := &mockCache{data: make(map[string][]byte)}
@@ -17,7 +32,7 @@ func TestCachedProvider_Search(t *testing.T) {
 	ctx := context.Background()
 
 	// 1. First call - should call inner provider
-	res, err := cp.Search(ctx, "query", "artist")
+	res, err := cp.Search(ctx, "query", "artist", 0, 0)
 	if err != nil {
 		t.Fatalf("Search failed: %v", err)
 	}
@@ -29,7 +44,7 @@ func TestCachedProvider_Search(t *testing.T) {
 	}
 
 	// 2. Second call - should hit cache
-	res2, err := cp.Search(ctx, "query", "artist")
+	res2, err := cp.Search(ctx, "query", "artist", 0, 0)
 	if err != nil {
 		t.Fatalf("Second Search failed: %v", err)
 	}
@@ -42,7 +57,7 @@ func TestCachedProvider_Search(t *testing.T) {
 
 	// 3. Clear cache - should call inner again
 	_ = cp.ClearCache()
-	_, _ = cp.Search(ctx, "query", "artist")
+	_, _ = cp.Search(ctx, "query", "artist", 0, 0)
 	if inner.searchCalled != 2 {
 		t.Errorf("Expected inner provider to be called again after clear, got %d", inner.searchCalled)
 	}
@@ -53,7 +68,7 @@ func TestCachedProvider_Error(t *testing.T) {
 	cache := &mockCache{err: errors.New("cache error")}
 	cp := NewCachedProvider(inner, cache, time.Hour)
 
-	_, err := cp.Search(context.Background(), "q", "a")
+	_, err := cp.Search(context.Background(), "q", "a", 0, 0)
 	if err == nil {
 		t.Error("Expected error from cache to propagate")
 	}
@@ -62,9 +77,21 @@ func TestCachedProvider_Error(t *testing.T) {
 type mockProvider struct {
 	Provider
 	searchCalled int
+	albumCalled  int
+	artistCalled int
+}
+
+func (m *mockProvider) GetAlbum(ctx context.Context, id string) (*domain.Album, error) {
+	m.albumCalled++
+	return &domain.Album{ID: id, Title: "Album"}, nil
 }
 
-func (m *mockProvider) Search(ctx context.Context, query string, searchType string) (*domain.SearchResult, error) {
+func (m *mockProvider) GetArtist(ctx context.Context, id string) (*domain.Artist, error) {
+	m.artistCalled++
+	return &domain.Artist{ID: id, Name: "Artist"}, nil
+}
+
+func (m *mockProvider) Search(ctx context.Context, query string, searchType string, offset, limit int) (*domain.SearchResult, error) {
 	m.searchCalled++
 	return &domain.SearchResult{
 		Artists: []domain.Artist{{Name: "Result"}},
@@ -105,6 +132,11 @@ func (m *mockProvider) GetRecommendations(ctx context.Context, id string) ([]dom
 	return []domain.CatalogTrack{{Title: "Rec Track"}}, nil
 }
 
+func (m *mockProvider) GetSimilarTracks(ctx context.Context, id string) ([]domain.CatalogTrack, error) {
+	m.searchCalled++
+	return []domain.CatalogTrack{{Title: "Radio Track"}}, nil
+}
+
 func TestCachedProvider_AllMethods(t *testing.T) {
 	inner := &mockProvider{}
 	cache := &mockCache{data: make(map[string][]byte)}
@@ -118,6 +150,7 @@ func TestCachedProvider_AllMethods(t *testing.T) {
 		{name: "GetSimilarAlbums", call: func() (interface{}, error) { return cp.GetSimilarAlbums(ctx, "1") }},
 		{name: "GetSimilarArtists", call: func() (interface{}, error) { return cp.GetSimilarArtists(ctx, "1") }},
 		{name: "GetRecommendations", call: func() (interface{}, error) { return cp.GetRecommendations(ctx, "1") }},
+		{name: "GetSimilarTracks", call: func() (interface{}, error) { return cp.GetSimilarTracks(ctx, "1") }},
 	}
 
 	for _, tt := range tests {
@@ -136,3 +169,43 @@ func TestCachedProvider_AllMethods(t *testing.T) {
 		})
 	}
 }
+
+func TestCachedProvider_AlbumAndArtist(t *testing.T) {
+	inner := &mockProvider{}
+	cache := &mockCache{data: make(map[string][]byte)}
+	cp := NewCachedProvider(inner, cache, time.Hour)
+	ctx := context.Background()
+
+	for i := 0; i < 2; i++ {
+		album, err := cp.GetAlbum(ctx, "1")
+		if err != nil || album.Title != "Album" {
+			t.Fatalf("GetAlbum() = %+v, %v", album, err)
+		}
+		artist, err := cp.GetArtist(ctx, "1")
+		if err != nil || artist.Name != "Artist" {
+			t.Fatalf("GetArtist() = %+v, %v", artist, err)
+		}
+	}
+	if inner.albumCalled != 1 || inner.artistCalled != 1 {
+		t.Errorf("provider called %d times for the album and %d for the artist, want once each", inner.albumCalled, inner.artistCalled)
+	}
+}
+
+func TestProviderManager_CacheIsPerProvider(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	m := NewProviderManager(db, store.NewSettingsRepo(db), time.Hour, "", nil)
+	hifi := m.buildChain(ProviderTypeHifi).cache
+	qobuz := m.buildChain(ProviderTypeQobuz).cache
+
+	if err := hifi.SetCache("album:1", []byte("hifi"), time.Hour); err != nil {
+		t.Fatalf("SetCache failed: %v", err)
+	}
+	if data, err := qobuz.GetCache("album:1"); err != nil || data != nil {
+		t.Errorf("qobuz GetCache() = %q, %v, want nothing cached", data, err)
+	}
+	if data, err := hifi.GetCache("album:1"); err != nil || string(data) != "hifi" {
+		t.Errorf("hifi GetCache() = %q, %v, want %q", data, err, "hifi")
+	}
+}
@@ -26,7 +26,7 @@ func (r APIArtistAggregationResponse) ToAlbums(artistID, artistName string, p *H
 			Title:        item.Title,
 			ArtistID:     artistID,
 			Artist:       artistName,
-			AlbumArtURL:  p.ensureAbsoluteURL(item.Cover, "640x640"),
+			AlbumArtURL:  p.ensureAbsoluteURL(item.Cover),
 			AudioQuality: resolveAudioQuality(item.AudioQuality, item.MediaMetadata.Tags),
 		})
 	}
@@ -46,7 +46,7 @@ func (r APIArtistAggregationResponse) ToTopTracks(p *HifiProvider) []domain.Cata
 			TrackNumber:  item.TrackNumber,
 			Duration:     item.Duration,
 			AudioQuality: resolveAudioQuality(item.AudioQuality, item.MediaMetadata.Tags),
-			AlbumArtURL:  p.ensureAbsoluteURL(item.Album.Cover, "640x640"),
+			AlbumArtURL:  p.ensureAbsoluteURL(item.Album.Cover),
 		})
 		if item.Version != nil {
 			tracks[len(tracks)-1].Version = *item.Version
@@ -61,7 +61,7 @@ func (r APIAlbumResponse) ToDomain(p *HifiProvider) *domain.Album {
 
 	albumArtURL := ""
 	if len(data.Cover) > 0 {
-		albumArtURL = p.ensureAbsoluteURL(data.Cover[0], "640x640")
+		albumArtURL = p.ensureAbsoluteURL(data.Cover[0])
 	}
 
 	var albumArtists []string
@@ -200,7 +200,7 @@ func (r APIPlaylistResponse) ToDomain(p *HifiProvider) *domain.Playlist {
 
 		albumArtURL := ""
 		if len(item.Album.Cover) > 0 {
-			albumArtURL = p.ensureAbsoluteURL(item.Album.Cover[0], "640x640")
+			albumArtURL = p.ensureAbsoluteURL(item.Album.Cover[0])
 		}
 
 		albumArtist := artists[0]
@@ -251,7 +251,7 @@ func (r APITrackInfoResponse) ToDomain(p *HifiProvider) *domain.CatalogTrack {
 
 	albumArtURL := ""
 	if len(data.Album.Cover) > 0 {
-		albumArtURL = p.ensureAbsoluteURL(data.Album.Cover[0], "640x640")
+		albumArtURL = p.ensureAbsoluteURL(data.Album.Cover[0])
 	}
 
 	audioModes := ""
@@ -341,7 +341,7 @@ func (r APISimilarAlbumsResponse) ToDomain(p *HifiProvider) []domain.Album {
 			ArtistID:     artistID,
 			Artist:       artistName,
 			AudioQuality: resolveAudioQuality("", item.MediaTags),
-			AlbumArtURL:  p.ensureAbsoluteURL(item.Cover, "640x640"),
+			AlbumArtURL:  p.ensureAbsoluteURL(item.Cover),
 		})
 	}
 	return albums
@@ -385,7 +385,7 @@ func (r APIRecommendationsResponse) ToDomain(p *HifiProvider) []domain.CatalogTr
 			TrackNumber:    item.Track.TrackNumber,
 			Duration:       item.Track.Duration,
 			AudioQuality:   resolveAudioQuality(item.Track.AudioQuality, item.Track.MediaTags),
-			AlbumArtURL:    p.ensureAbsoluteURL(item.Track.Album.Cover[0], "640x640"),
+			AlbumArtURL:    p.ensureAbsoluteURL(item.Track.Album.Cover[0]),
 			BPM:            item.Track.BPM,
 			Key:            item.Track.Key,
 			KeyScale:       item.Track.KeyScale,
@@ -427,7 +427,7 @@ func (r APIAlbumsSearchResponse) ToDomain(p *HifiProvider) []domain.Album {
 			Title:        item.Title,
 			Artist:       artist,
 			AudioQuality: resolveAudioQuality(item.AudioQuality, item.MediaMetadata.Tags),
-			AlbumArtURL:  p.ensureAbsoluteURL(item.Cover, "640x640"),
+			AlbumArtURL:  p.ensureAbsoluteURL(item.Cover),
 		})
 	}
 	return albums
@@ -473,7 +473,7 @@ func (r APITracksSearchResponse) ToDomain(p *HifiProvider) []domain.CatalogTrack
 			TrackNumber:    item.TrackNumber,
 			Duration:       item.Duration,
 			AudioQuality:   resolveAudioQuality(item.AudioQuality, item.MediaMetadata.Tags),
-			AlbumArtURL:    p.ensureAbsoluteURL(item.Album.Cover, "640x640"),
+			AlbumArtURL:    p.ensureAbsoluteURL(item.Album.Cover),
 		})
 		if item.Version != nil {
 			tracks[len(tracks)-1].Version = *item.Version
@@ -29,6 +29,32 @@ func TestAPIArtistWithPicture_ToDomain(t *testing.T) {
 	}
 }
 
+func TestHifiProvider_EnsureAbsoluteURL(t *testing.T) {
+	tests := []struct {
+		name      string
+		coverSize string
+		urlOrID   string
+		size      []string
+		want      string
+	}{
+		{"default cover size", "", "ab-cd", nil, "https://resources.tidal.com/images/ab/cd/640x640.jpg"},
+		{"small cover", constants.ImageSizeSmall, "ab-cd", nil, "https://resources.tidal.com/images/ab/cd/320x320.jpg"},
+		{"medium cover", constants.ImageSizeMedium, "ab-cd", nil, "https://resources.tidal.com/images/ab/cd/640x640.jpg"},
+		{"large cover", constants.ImageSizeLarge, "ab-cd", nil, "https://resources.tidal.com/images/ab/cd/1280x1280.jpg"},
+		{"explicit size wins", constants.ImageSizeLarge, "ab-cd", []string{"320x320"}, "https://resources.tidal.com/images/ab/cd/320x320.jpg"},
+		{"absolute URL kept", constants.ImageSizeLarge, "https://example.com/a.jpg", nil, "https://example.com/a.jpg"},
+		{"empty ID", constants.ImageSizeLarge, "", nil, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := (&HifiProvider{}).WithCoverSize(tt.coverSize)
+			if got := p.ensureAbsoluteURL(tt.urlOrID, tt.size...); got != tt.want {
+				t.Errorf("ensureAbsoluteURL(%q) = %q, want %q", tt.urlOrID, got, tt.want)
+			}
+		})
+	}
+}
+
 func TestAPIArtistAggregationResponse_ToAlbums(t *testing.T) {
 	p := &HifiProvider{}
 	resp := APIArtistAggregationResponse{}
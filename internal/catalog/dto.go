@@ -321,14 +321,17 @@ type APISearchPlaylistItem struct {
 type APISearchResponse struct {
 	Data struct {
 		Artists struct {
-			Items []APISearchArtistItem `json:"items"`
+			Items              []APISearchArtistItem `json:"items"`
+			TotalNumberOfItems int                   `json:"totalNumberOfItems"`
 		} `json:"artists"`
 		Albums struct {
-			Items []APISearchAlbumItem `json:"items"`
+			Items              []APISearchAlbumItem `json:"items"`
+			TotalNumberOfItems int                  `json:"totalNumberOfItems"`
 		} `json:"albums"`
 		Items     []APISearchTrackItem `json:"items"`
 		Playlists struct {
-			Items []APISearchPlaylistItem `json:"items"`
+			Items              []APISearchPlaylistItem `json:"items"`
+			TotalNumberOfItems int                     `json:"totalNumberOfItems"`
 		} `json:"playlists"`
 	} `json:"data"`
 }
@@ -336,7 +339,8 @@ type APISearchResponse struct {
 type APIArtistsSearchResponse struct {
 	Data struct {
 		Artists struct {
-			Items []APISearchArtistItem `json:"items"`
+			Items              []APISearchArtistItem `json:"items"`
+			TotalNumberOfItems int                   `json:"totalNumberOfItems"`
 		} `json:"artists"`
 	} `json:"data"`
 }
@@ -344,21 +348,24 @@ type APIArtistsSearchResponse struct {
 type APIAlbumsSearchResponse struct {
 	Data struct {
 		Albums struct {
-			Items []APISearchAlbumItem `json:"items"`
+			Items              []APISearchAlbumItem `json:"items"`
+			TotalNumberOfItems int                  `json:"totalNumberOfItems"`
 		} `json:"albums"`
 	} `json:"data"`
 }
 
 type APITracksSearchResponse struct {
 	Data struct {
-		Items []APISearchTrackItem `json:"items"`
+		Items              []APISearchTrackItem `json:"items"`
+		TotalNumberOfItems int                  `json:"totalNumberOfItems"`
 	} `json:"data"`
 }
 
 type APIPlaylistsSearchResponse struct {
 	Data struct {
 		Playlists struct {
-			Items []APISearchPlaylistItem `json:"items"`
+			Items              []APISearchPlaylistItem `json:"items"`
+			TotalNumberOfItems int                     `json:"totalNumberOfItems"`
 		} `json:"playlists"`
 	} `json:"data"`
 }
@@ -0,0 +1,59 @@
+package catalog
+
+import (
+	"errors"
+	"net/http"
+	"time"
+)
+
+// Provider errors, matched with errors.Is. They tell the worker whether a failed request is
+// worth retrying: not found and unauthorized never are, rate limited is after backing off,
+// and unavailable usually is once the provider recovers.
+var (
+	ErrNotFound     = errors.New("not found")
+	ErrRateLimited  = errors.New("rate limited")
+	ErrUnauthorized = errors.New("unauthorized")
+	ErrUnavailable  = errors.New("provider unavailable")
+)
+
+// StatusError is a provider response with an unexpected HTTP status. Its message is the
+// status line, and it unwraps to the provider error the status maps to, when there is one.
+type StatusError struct {
+	StatusCode int
+	Status     string
+	// RetryAfter is how long a rate-limited or unavailable provider asked to be left alone,
+	// or 0 when it didn't say.
+	RetryAfter time.Duration
+}
+
+func (e *StatusError) Error() string { return e.Status }
+
+func (e *StatusError) Unwrap() error { return errorForStatus(e.StatusCode) }
+
+// statusError describes a response whose status the caller didn't expect. The body is left
+// for the caller to close.
+func statusError(resp *http.Response) *StatusError {
+	e := &StatusError{StatusCode: resp.StatusCode, Status: resp.Status}
+	if errors.Is(e, ErrRateLimited) || errors.Is(e, ErrUnavailable) {
+		if header := resp.Header.Get("Retry-After"); header != "" {
+			e.RetryAfter = retryAfterDelay(header, 1, time.Now())
+		}
+	}
+	return e
+}
+
+// errorForStatus maps an HTTP status to a provider error, or nil when none fits.
+func errorForStatus(status int) error {
+	switch {
+	case status == http.StatusNotFound || status == http.StatusGone:
+		return ErrNotFound
+	case status == http.StatusTooManyRequests:
+		return ErrRateLimited
+	case status == http.StatusUnauthorized || status == http.StatusForbidden:
+		return ErrUnauthorized
+	case status >= http.StatusInternalServerError:
+		return ErrUnavailable
+	default:
+		return nil
+	}
+}
@@ -0,0 +1,69 @@
+package catalog
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestStatusError(t *testing.T) {
+	tests := []struct {
+		name       string
+		status     int
+		retryAfter string
+		want       error
+		wantWait   time.Duration
+	}{
+		{"not found", http.StatusNotFound, "", ErrNotFound, 0},
+		{"gone", http.StatusGone, "", ErrNotFound, 0},
+		{"rate limited", http.StatusTooManyRequests, "7", ErrRateLimited, 7 * time.Second},
+		{"unauthorized", http.StatusUnauthorized, "", ErrUnauthorized, 0},
+		{"forbidden", http.StatusForbidden, "", ErrUnauthorized, 0},
+		{"bad gateway", http.StatusBadGateway, "", ErrUnavailable, 0},
+		{"service unavailable", http.StatusServiceUnavailable, "2", ErrUnavailable, 2 * time.Second},
+		{"bad request", http.StatusBadRequest, "", nil, 0},
+	}
+	providers := []struct {
+		name string
+		get  func(baseURL string) error
+	}{
+		{"hifi", func(baseURL string) error {
+			_, err := NewHifiProvider(baseURL).GetAlbum(context.Background(), "1")
+			return err
+		}},
+		{"qobuz", func(baseURL string) error {
+			_, err := NewQobuzProvider(baseURL).GetAlbum(context.Background(), "1")
+			return err
+		}},
+	}
+	for _, p := range providers {
+		for _, tt := range tests {
+			t.Run(p.name+"/"+tt.name, func(t *testing.T) {
+				srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+					if tt.retryAfter != "" {
+						w.Header().Set("Retry-After", tt.retryAfter)
+					}
+					w.WriteHeader(tt.status)
+				}))
+				defer srv.Close()
+
+				err := p.get(srv.URL)
+				var statusErr *StatusError
+				if !errors.As(err, &statusErr) {
+					t.Fatalf("error = %v, want a StatusError", err)
+				}
+				if statusErr.StatusCode != tt.status || statusErr.RetryAfter != tt.wantWait {
+					t.Errorf("StatusError = %d with retry after %v, want %d with %v", statusErr.StatusCode, statusErr.RetryAfter, tt.status, tt.wantWait)
+				}
+				for _, kind := range []error{ErrNotFound, ErrRateLimited, ErrUnauthorized, ErrUnavailable} {
+					if got := errors.Is(err, kind); got != (kind == tt.want) {
+						t.Errorf("errors.Is(%v, %v) = %v", err, kind, got)
+					}
+				}
+			})
+		}
+	}
+}
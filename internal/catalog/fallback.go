@@ -33,7 +33,7 @@ func (f *FallbackProvider) getProviders() []Provider {
 	if f.manager != nil && f.manager.providers != nil {
 		storeProviders, _ := f.manager.providers.ListByType(string(f.providerType))
 		for _, p := range storeProviders {
-			providers = append(providers, NewProvider(f.providerType, p.URL))
+			providers = append(providers, NewProvider(f.providerType, p.URL, f.manager.coverArtSize, p.Headers, f.manager.timeouts))
 		}
 	}
 
@@ -58,8 +58,10 @@ func fallbackWith[T any](f *FallbackProvider, opName string, op func(Provider) (
 	return zero, fmt.Errorf("no providers available for %s", opName)
 }
 
-func (f *FallbackProvider) Search(ctx context.Context, query string, searchType string) (*domain.SearchResult, error) {
-	return fallbackWith(f, "Search", func(p Provider) (*domain.SearchResult, error) { return p.Search(ctx, query, searchType) })
+func (f *FallbackProvider) Search(ctx context.Context, query string, searchType string, offset, limit int) (*domain.SearchResult, error) {
+	return fallbackWith(f, "Search", func(p Provider) (*domain.SearchResult, error) {
+		return p.Search(ctx, query, searchType, offset, limit)
+	})
 }
 
 func (f *FallbackProvider) GetArtist(ctx context.Context, id string) (*domain.Artist, error) {
@@ -120,4 +122,8 @@ func (f *FallbackProvider) GetRecommendations(ctx context.Context, id string) ([
 	return fallbackWith(f, "GetRecommendations", func(p Provider) ([]domain.CatalogTrack, error) { return p.GetRecommendations(ctx, id) })
 }
 
+func (f *FallbackProvider) GetSimilarTracks(ctx context.Context, id string) ([]domain.CatalogTrack, error) {
+	return fallbackWith(f, "GetSimilarTracks", func(p Provider) ([]domain.CatalogTrack, error) { return p.GetSimilarTracks(ctx, id) })
+}
+
 var _ Provider = (*FallbackProvider)(nil)
@@ -0,0 +1,22 @@
+package catalog
+
+import (
+	"net/http"
+	"net/url"
+)
+
+// setCustomHeaders adds a provider's configured headers to req when it goes to the
+// provider's own host. Stream URLs on a third-party CDN don't get them, so an API key for
+// a self-hosted proxy never leaves it.
+func setCustomHeaders(req *http.Request, baseURL string, headers map[string]string) {
+	if len(headers) == 0 {
+		return
+	}
+	base, err := url.Parse(baseURL)
+	if err != nil || req.URL.Host != base.Host {
+		return
+	}
+	for name, value := range headers {
+		req.Header.Set(name, value)
+	}
+}
@@ -0,0 +1,86 @@
+package catalog
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+)
+
+func TestHifiProvider_CustomHeaders(t *testing.T) {
+	var mu sync.Mutex
+	seen := map[string]string{}
+	record := func(r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		seen[r.Host+r.URL.Path] = r.Header.Get("X-Api-Key")
+	}
+
+	cdn := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		record(r)
+		_, _ = w.Write([]byte("audio"))
+	}))
+	defer cdn.Close()
+
+	var api *httptest.Server
+	api = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		record(r)
+		switch r.URL.Path {
+		case "/info/":
+			_, _ = w.Write([]byte(`{"data":{"id":1,"title":"Song"}}`))
+		case "/track/":
+			// The proxy serves one track itself and leaves the other on a third-party CDN.
+			streamURL := api.URL + "/audio"
+			if r.URL.Query().Get("id") == "2" {
+				streamURL = cdn.URL + "/audio"
+			}
+			manifest := base64.StdEncoding.EncodeToString([]byte(fmt.Sprintf(`{"mimeType":"audio/flac","urls":[%q]}`, streamURL)))
+			_, _ = fmt.Fprintf(w, `{"data":{"manifest":%q,"manifestMimeType":"application/vnd.tidal.bts"}}`, manifest)
+		default:
+			_, _ = w.Write([]byte("audio"))
+		}
+	}))
+	defer api.Close()
+
+	provider := NewProvider(ProviderTypeHifi, api.URL, "", map[string]string{"X-Api-Key": "secret"}, Timeouts{})
+	ctx := context.Background()
+	if _, err := provider.GetTrack(ctx, "1"); err != nil {
+		t.Fatalf("GetTrack failed: %v", err)
+	}
+	for _, id := range []string{"1", "2"} {
+		stream, _, err := provider.GetStream(ctx, id, "", "LOSSLESS")
+		if err != nil {
+			t.Fatalf("GetStream(%s) failed: %v", id, err)
+		}
+		_, _ = io.Copy(io.Discard, stream)
+		_ = stream.Close()
+	}
+
+	apiHost := api.Listener.Addr().String()
+	cdnHost := cdn.Listener.Addr().String()
+	tests := []struct {
+		name    string
+		request string
+		want    string
+	}{
+		{"metadata request", apiHost + "/info/", "secret"},
+		{"stream manifest request", apiHost + "/track/", "secret"},
+		{"stream served by the provider", apiHost + "/audio", "secret"},
+		{"stream on another host", cdnHost + "/audio", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := seen[tt.request]
+			if !ok {
+				t.Fatalf("no request to %s", tt.request)
+			}
+			if got != tt.want {
+				t.Errorf("X-Api-Key = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -11,24 +11,44 @@ import (
 	"strings"
 	"time"
 
+	"github.com/cesargomez89/navidrums/internal/constants"
 	"github.com/cesargomez89/navidrums/internal/domain"
 	"github.com/cesargomez89/navidrums/internal/httpclient"
+	"github.com/cesargomez89/navidrums/internal/metrics"
 )
 
 type HifiProvider struct {
-	client  *httpclient.Client
-	BaseURL string
+	client    *httpclient.Client // API calls, bounded by Timeouts.Metadata
+	stream    *httpclient.Client // stream and segment fetches, bounded by Timeouts.Stream
+	BaseURL   string
+	coverSize string
+	headers   map[string]string
 }
 
 const defaultProviderUserAgent = "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/124.0.0.0 Safari/537.36"
 
 func NewHifiProvider(baseURL string) *HifiProvider {
-	return &HifiProvider{
-		BaseURL: baseURL,
-		client: httpclient.NewClient(&http.Client{
-			Timeout: 20 * time.Second,
-		}, 500*time.Millisecond),
-	}
+	p := &HifiProvider{BaseURL: baseURL}
+	return p.WithTimeouts(Timeouts{})
+}
+
+// WithTimeouts replaces the provider's clients with ones bounded by t.
+func (p *HifiProvider) WithTimeouts(t Timeouts) *HifiProvider {
+	p.client, p.stream = t.clients(ProviderTypeHifi)
+	return p
+}
+
+// WithCoverSize sets the size album covers are requested at, one of
+// constants.CoverArtSizes. Artist pictures and playlist images keep their fixed sizes.
+func (p *HifiProvider) WithCoverSize(size string) *HifiProvider {
+	p.coverSize = size
+	return p
+}
+
+// WithHeaders sets extra headers, such as an API key, sent with requests to BaseURL's host.
+func (p *HifiProvider) WithHeaders(headers map[string]string) *HifiProvider {
+	p.headers = headers
+	return p
 }
 
 func (p *HifiProvider) setRequestHeaders(req *http.Request) {
@@ -43,6 +63,7 @@ func (p *HifiProvider) setRequestHeaders(req *http.Request) {
 	}
 	req.Header.Set("Referer", "https://listen.tidal.com/")
 	req.Header.Set("Origin", "https://listen.tidal.com")
+	setCustomHeaders(req, p.BaseURL, p.headers)
 }
 
 func (p *HifiProvider) ensureAbsoluteURL(urlOrID string, size ...string) string {
@@ -52,7 +73,10 @@ func (p *HifiProvider) ensureAbsoluteURL(urlOrID string, size ...string) string
 	if strings.HasPrefix(urlOrID, "http://") || strings.HasPrefix(urlOrID, "https://") {
 		return urlOrID
 	}
-	imgSize := "640x640"
+	imgSize := p.coverSize
+	if imgSize == "" {
+		imgSize = constants.ImageSizeMedium
+	}
 	if len(size) > 0 {
 		imgSize = size[0]
 	}
@@ -119,7 +143,8 @@ func (p *HifiProvider) GetStream(ctx context.Context, trackID string, isrc strin
 	}
 
 	if resp.Data.Manifest == "" {
-		return nil, "", fmt.Errorf("no manifest found")
+		// Tidal answers without a manifest for tracks that can't be streamed in its region.
+		return nil, "", fmt.Errorf("%w: no manifest found", ErrTrackUnavailable)
 	}
 
 	decoded, err := base64.StdEncoding.DecodeString(resp.Data.Manifest)
@@ -140,18 +165,13 @@ func (p *HifiProvider) GetStream(ctx context.Context, trackID string, isrc strin
 		}
 
 		streamUrl := manifest.Urls[0]
-		req, err := http.NewRequestWithContext(ctx, http.MethodGet, streamUrl, nil)
-		if err != nil {
-			return nil, "", err
-		}
-		p.setRequestHeaders(req)
-		sResp, err := p.client.Do(ctx, req)
+		sResp, err := fetchStream(ctx, p.streamDoer(ctx), streamUrl, p.setRequestHeaders)
 		if err != nil {
 			return nil, "", err
 		}
 		if sResp.StatusCode != http.StatusOK {
 			_ = sResp.Body.Close()
-			return nil, "", fmt.Errorf("stream fetch failed: %s", sResp.Status)
+			return nil, "", fmt.Errorf("stream fetch failed: %w", statusError(sResp))
 		}
 
 		mimeType := "audio/flac"
@@ -179,18 +199,13 @@ func (p *HifiProvider) GetStream(ctx context.Context, trackID string, isrc strin
 			return nil, "", fmt.Errorf("no BaseURL found in DASH manifest")
 		}
 
-		req, err := http.NewRequestWithContext(ctx, http.MethodGet, streamUrl, nil)
-		if err != nil {
-			return nil, "", err
-		}
-		p.setRequestHeaders(req)
-		sResp, err := p.client.Do(ctx, req)
+		sResp, err := fetchStream(ctx, p.streamDoer(ctx), streamUrl, p.setRequestHeaders)
 		if err != nil {
 			return nil, "", err
 		}
 		if sResp.StatusCode != http.StatusOK {
 			_ = sResp.Body.Close()
-			return nil, "", fmt.Errorf("stream fetch failed: %s", sResp.Status)
+			return nil, "", fmt.Errorf("stream fetch failed: %w", statusError(sResp))
 		}
 		mimeType := "audio/flac"
 		contentType := sResp.Header.Get("Content-Type")
@@ -203,6 +218,13 @@ func (p *HifiProvider) GetStream(ctx context.Context, trackID string, isrc strin
 	return nil, "", fmt.Errorf("unsupported manifest type: %s", resp.Data.ManifestMimeType)
 }
 
+// streamDoer sends stream requests through the provider's rate-limited stream client.
+func (p *HifiProvider) streamDoer(ctx context.Context) streamDoer {
+	return func(req *http.Request) (*http.Response, error) {
+		return p.stream.Do(ctx, req)
+	}
+}
+
 func (p *HifiProvider) handleSegmentedDash(ctx context.Context, manifest string) (io.ReadCloser, string, error) {
 	initRe := regexp.MustCompile(`initialization="([^"]+)"`)
 	mediaRe := regexp.MustCompile(`media="([^"]+)"`)
@@ -247,7 +269,7 @@ func (p *HifiProvider) handleSegmentedDash(ctx context.Context, manifest string)
 
 	return &multiSegmentReader{
 		urls:   urls,
-		client: p.client.GetUnderlyingClient(),
+		client: p.stream.GetUnderlyingClient(),
 		ctx:    ctx,
 	}, "audio/mp4", nil
 }
@@ -292,7 +314,24 @@ func (p *HifiProvider) GetRecommendations(ctx context.Context, id string) ([]dom
 	return resp.ToDomain(p), nil
 }
 
-func (p *HifiProvider) get(ctx context.Context, url string, target interface{}) error {
+// radioTrackLimit is the number of tracks GetSimilarTracks asks for.
+const radioTrackLimit = 50
+
+// GetSimilarTracks returns the track's radio: its recommendations, fetched at radio length
+// rather than the handful GetRecommendations shows.
+func (p *HifiProvider) GetSimilarTracks(ctx context.Context, id string) ([]domain.CatalogTrack, error) {
+	u := fmt.Sprintf("%s/recommendations/?id=%s&limit=%d", p.BaseURL, id, radioTrackLimit)
+	var resp APIRecommendationsResponse
+	if err := p.get(ctx, u, &resp); err != nil {
+		return nil, err
+	}
+	return resp.ToDomain(p), nil
+}
+
+func (p *HifiProvider) get(ctx context.Context, url string, target interface{}) (err error) {
+	start := time.Now()
+	defer func() { metrics.ObserveProviderRequest(string(ProviderTypeHifi), start, err) }()
+
 	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
 	if err != nil {
 		return err
@@ -308,7 +347,7 @@ func (p *HifiProvider) get(ctx context.Context, url string, target interface{})
 	}()
 
 	if resp.StatusCode != http.StatusOK {
-		return fmt.Errorf("API request failed: %s", resp.Status)
+		return fmt.Errorf("API request failed: %w", statusError(resp))
 	}
 
 	decoder := json.NewDecoder(resp.Body)
@@ -317,11 +356,14 @@ func (p *HifiProvider) get(ctx context.Context, url string, target interface{})
 	return err
 }
 
-func NewProvider(providerType ProviderType, baseURL string) Provider {
+// NewProvider builds a provider of the given type that sends headers with its requests
+// and is bounded by timeouts. coverArtSize applies to Hi-Fi only; Qobuz returns fixed-size
+// cover URLs.
+func NewProvider(providerType ProviderType, baseURL, coverArtSize string, headers map[string]string, timeouts Timeouts) Provider {
 	switch providerType {
 	case ProviderTypeQobuz:
-		return NewQobuzProvider(baseURL)
+		return NewQobuzProvider(baseURL).WithHeaders(headers).WithTimeouts(timeouts)
 	default:
-		return NewHifiProvider(baseURL)
+		return NewHifiProvider(baseURL).WithCoverSize(coverArtSize).WithHeaders(headers).WithTimeouts(timeouts)
 	}
 }
@@ -15,31 +15,41 @@ type Logger interface {
 }
 
 type ProviderManager struct {
-	logger    Logger
-	providers *store.ProvidersRepo
-	settings  *store.SettingsRepo
-	cacheTTL  time.Duration
-	db        *store.DB
+	logger       Logger
+	providers    *store.ProvidersRepo
+	settings     *store.SettingsRepo
+	cacheTTL     time.Duration
+	coverArtSize string
+	timeouts     Timeouts
+	db           *store.DB
 
 	chains map[ProviderType]*CachedProvider
 	mu     sync.RWMutex
 }
 
-func NewProviderManager(db *store.DB, settings *store.SettingsRepo, cacheTTL time.Duration, logger Logger) *ProviderManager {
+func NewProviderManager(db *store.DB, settings *store.SettingsRepo, cacheTTL time.Duration, coverArtSize string, logger Logger) *ProviderManager {
 	var providersRepo *store.ProvidersRepo
 	if db != nil {
 		providersRepo = store.NewProvidersRepo(db)
 	}
 
 	return &ProviderManager{
-		logger:    logger,
-		providers: providersRepo,
-		settings:  settings,
-		cacheTTL:  cacheTTL,
-		db:        db,
+		logger:       logger,
+		providers:    providersRepo,
+		settings:     settings,
+		cacheTTL:     cacheTTL,
+		coverArtSize: coverArtSize,
+		db:           db,
 	}
 }
 
+// WithTimeouts bounds the requests of the providers the manager builds, and returns it.
+// Call it before the first provider is used.
+func (m *ProviderManager) WithTimeouts(t Timeouts) *ProviderManager {
+	m.timeouts = t
+	return m
+}
+
 func (m *ProviderManager) readSetting(key string) ProviderType {
 	if m.settings == nil {
 		return ProviderTypeHifi
@@ -59,7 +69,7 @@ func (m *ProviderManager) buildChain(pt ProviderType) *CachedProvider {
 	fb := &FallbackProvider{manager: m, providerType: pt}
 	var cacheStore *storeCache
 	if m.db != nil {
-		cacheStore = &storeCache{store: m.db}
+		cacheStore = &storeCache{store: m.db, prefix: string(pt) + ":"}
 	}
 	return NewCachedProvider(fb, cacheStore, m.cacheTTL)
 }
@@ -88,6 +98,23 @@ func (m *ProviderManager) GetMetadataProvider() Provider {
 	return m.GetProvider(m.readSetting(store.SettingActiveMetadataProvider))
 }
 
+// MetadataProviderType is the provider type whose catalog IDs search and browse return,
+// recorded on tracks as their source provider.
+func (m *ProviderManager) MetadataProviderType() ProviderType {
+	return m.readSetting(store.SettingActiveMetadataProvider)
+}
+
+// GetSourceProvider returns the provider a track's ID belongs to, falling back to the
+// metadata provider for tracks recorded before the source was stored.
+func (m *ProviderManager) GetSourceProvider(source string) Provider {
+	switch pt := ProviderType(source); pt {
+	case ProviderTypeHifi, ProviderTypeQobuz:
+		return m.GetProvider(pt)
+	default:
+		return m.GetMetadataProvider()
+	}
+}
+
 func (m *ProviderManager) GetDownloadProvider() Provider {
 	return m.GetProvider(m.readSetting(store.SettingActiveDownloadProvider))
 }
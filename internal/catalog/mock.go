@@ -14,7 +14,8 @@ func NewMockProvider() *MockProvider {
 	return &MockProvider{}
 }
 
-func (p *MockProvider) Search(ctx context.Context, query string, searchType string) (*domain.SearchResult, error) {
+// Search ignores offset and limit: the mock has a single page of results.
+func (p *MockProvider) Search(ctx context.Context, query string, searchType string, offset, limit int) (*domain.SearchResult, error) {
 	res := &domain.SearchResult{
 		Artists: []domain.Artist{{ID: "1", Name: "Mock Artist"}},
 		Albums:  []domain.Album{{ID: "1", Title: "Mock Album", Artist: "Mock Artist"}},
@@ -33,6 +34,8 @@ func (p *MockProvider) Search(ctx context.Context, query string, searchType stri
 		resFiltered.Albums = res.Albums
 	case "track":
 		resFiltered.Tracks = res.Tracks
+	case "all":
+		return res, nil
 	default:
 		resFiltered.Albums = res.Albums
 	}
@@ -0,0 +1,43 @@
+package catalog
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"time"
+)
+
+// probeTimeout bounds a connectivity check, so a dead provider fails fast instead of
+// waiting out the client's retries.
+const probeTimeout = 5 * time.Second
+
+// ProbeResult reports whether a provider answered a connectivity check.
+type ProbeResult struct {
+	OK         bool   `json:"ok"`
+	StatusCode int    `json:"status_code,omitempty"`
+	Error      string `json:"error,omitempty"`
+	LatencyMS  int64  `json:"latency_ms"`
+}
+
+// Probe checks that baseURL serves the provider API by running a one-result search
+// against it with headers. It builds its own provider, so the configured chain and its
+// cache are left alone.
+func Probe(ctx context.Context, providerType ProviderType, baseURL string, headers map[string]string) ProbeResult {
+	ctx, cancel := context.WithTimeout(ctx, probeTimeout)
+	defer cancel()
+
+	start := time.Now()
+	_, err := NewProvider(providerType, baseURL, "", headers, Timeouts{}).Search(ctx, "test", "track", 0, 1)
+	result := ProbeResult{LatencyMS: time.Since(start).Milliseconds()}
+	if err != nil {
+		result.Error = err.Error()
+		var statusErr *StatusError
+		if errors.As(err, &statusErr) {
+			result.StatusCode = statusErr.StatusCode
+		}
+		return result
+	}
+	result.OK = true
+	result.StatusCode = http.StatusOK
+	return result
+}
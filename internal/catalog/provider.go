@@ -2,13 +2,20 @@ package catalog
 
 import (
 	"context"
+	"errors"
 	"io"
 
 	"github.com/cesargomez89/navidrums/internal/domain"
 )
 
+// ErrTrackUnavailable is returned by GetStream when the provider has no stream for a track,
+// typically because it is region-locked or withdrawn. Retrying does not help.
+var ErrTrackUnavailable = errors.New("track is not available for streaming")
+
 type Provider interface {
-	Search(ctx context.Context, query string, searchType string) (*domain.SearchResult, error)
+	// Search returns up to limit results per category starting at offset. A limit of 0
+	// leaves paging to the provider's default; providers without paging return the first page.
+	Search(ctx context.Context, query string, searchType string, offset, limit int) (*domain.SearchResult, error)
 	GetArtist(ctx context.Context, id string) (*domain.Artist, error)
 	GetAlbum(ctx context.Context, id string) (*domain.Album, error)
 	GetPlaylist(ctx context.Context, id string) (*domain.Playlist, error)
@@ -18,6 +25,9 @@ type Provider interface {
 	GetSimilarArtists(ctx context.Context, id string) ([]domain.Artist, error)
 	GetLyrics(ctx context.Context, trackID string) (string, string, error)
 	GetRecommendations(ctx context.Context, id string) ([]domain.CatalogTrack, error)
+	// GetSimilarTracks returns a radio-length list of tracks like the given one. Providers
+	// without track radio return no tracks and no error.
+	GetSimilarTracks(ctx context.Context, id string) ([]domain.CatalogTrack, error)
 }
 
 type ProviderType string
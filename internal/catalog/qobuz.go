@@ -13,26 +13,40 @@ import (
 
 	"github.com/cesargomez89/navidrums/internal/domain"
 	"github.com/cesargomez89/navidrums/internal/httpclient"
+	"github.com/cesargomez89/navidrums/internal/metrics"
 )
 
 var ErrQobuzNotSupported = errors.New("qobuz provider does not support this operation")
 
 type QobuzProvider struct {
-	client  *httpclient.Client
+	client  *httpclient.Client // API calls, bounded by Timeouts.Metadata
+	stream  *httpclient.Client // stream fetches, bounded by Timeouts.Stream
 	BaseURL string
+	headers map[string]string
 }
 
 func NewQobuzProvider(baseURL string) *QobuzProvider {
-	return &QobuzProvider{
-		BaseURL: baseURL,
-		client: httpclient.NewClient(&http.Client{
-			Timeout: 20 * time.Second,
-		}, 500*time.Millisecond),
-	}
+	p := &QobuzProvider{BaseURL: baseURL}
+	return p.WithTimeouts(Timeouts{})
+}
+
+// WithTimeouts replaces the provider's clients with ones bounded by t.
+func (p *QobuzProvider) WithTimeouts(t Timeouts) *QobuzProvider {
+	p.client, p.stream = t.clients(ProviderTypeQobuz)
+	return p
 }
 
-func (p *QobuzProvider) Search(ctx context.Context, query string, searchType string) (*domain.SearchResult, error) {
-	searchURL := fmt.Sprintf("%s/get-music?q=%s&offset=0", p.BaseURL, url.QueryEscape(query))
+// WithHeaders sets extra headers, such as an API key, sent with requests to BaseURL's host.
+func (p *QobuzProvider) WithHeaders(headers map[string]string) *QobuzProvider {
+	p.headers = headers
+	return p
+}
+
+func (p *QobuzProvider) Search(ctx context.Context, query string, searchType string, offset, limit int) (*domain.SearchResult, error) {
+	searchURL := fmt.Sprintf("%s/get-music?q=%s&offset=%d", p.BaseURL, url.QueryEscape(query), offset)
+	if limit > 0 {
+		searchURL += fmt.Sprintf("&limit=%d", limit)
+	}
 	var resp QobuzSearchResponse
 	if err := p.get(ctx, searchURL, &resp); err != nil {
 		return nil, fmt.Errorf("qobuz search failed: %w", err)
@@ -50,18 +64,22 @@ func (p *QobuzProvider) Search(ctx context.Context, query string, searchType str
 			result.Albums = nil
 			result.Tracks = nil
 			result.Playlists = nil
+			result.Total = resp.Data.Artists.Total
 		case "album":
 			result.Artists = nil
 			result.Tracks = nil
 			result.Playlists = nil
+			result.Total = resp.Data.Albums.Total
 		case "track":
 			result.Albums = nil
 			result.Artists = nil
 			result.Playlists = nil
+			result.Total = resp.Data.Tracks.Total
 		case "playlist":
 			result.Albums = nil
 			result.Artists = nil
 			result.Tracks = nil
+			result.Total = resp.Data.Playlists.Total
 		}
 	}
 
@@ -79,7 +97,7 @@ func (p *QobuzProvider) GetArtist(ctx context.Context, id string) (*domain.Artis
 		return nil, fmt.Errorf("qobuz get artist failed: %w", err)
 	}
 	if !resp.Success {
-		return nil, fmt.Errorf("qobuz artist not found: %s", id)
+		return nil, fmt.Errorf("qobuz artist %s: %w", id, ErrNotFound)
 	}
 	return resp.Data.ToDomain(), nil
 }
@@ -91,7 +109,7 @@ func (p *QobuzProvider) GetAlbum(ctx context.Context, id string) (*domain.Album,
 		return nil, fmt.Errorf("qobuz get album failed: %w", err)
 	}
 	if !wrapper.Success || wrapper.Data == nil {
-		return nil, fmt.Errorf("qobuz album not found: %s", id)
+		return nil, fmt.Errorf("qobuz album %s: %w", id, ErrNotFound)
 	}
 	return wrapper.Data.ToDomain(), nil
 }
@@ -111,7 +129,7 @@ func (p *QobuzProvider) GetTrack(ctx context.Context, id string) (*domain.Catalo
 		return nil, fmt.Errorf("qobuz get track failed: %w", err)
 	}
 	if !wrapper.Success || wrapper.Data == nil {
-		return nil, fmt.Errorf("qobuz track not found: %s", id)
+		return nil, fmt.Errorf("qobuz track %s: %w", id, ErrNotFound)
 	}
 	track := wrapper.Data.ToDomain()
 	return &track, nil
@@ -143,15 +161,16 @@ func (p *QobuzProvider) GetStream(ctx context.Context, trackID string, isrc stri
 	if err != nil {
 		return nil, "", fmt.Errorf("failed to create stream request: %w", err)
 	}
+	setCustomHeaders(req, p.BaseURL, p.headers)
 
-	resp, err := p.client.GetUnderlyingClient().Do(req)
+	resp, err := p.stream.GetUnderlyingClient().Do(req)
 	if err != nil {
 		return nil, "", fmt.Errorf("failed to fetch stream: %w", err)
 	}
 
 	if resp.StatusCode != http.StatusOK {
 		_ = resp.Body.Close()
-		return nil, "", fmt.Errorf("stream fetch failed: %s", resp.Status)
+		return nil, "", fmt.Errorf("stream fetch failed: %w", statusError(resp))
 	}
 
 	mime := resp.Header.Get("Content-Type")
@@ -170,7 +189,7 @@ func (p *QobuzProvider) resolveTrackID(ctx context.Context, trackID string, isrc
 			return 0, fmt.Errorf("qobuz isrc lookup failed: %w", err)
 		}
 		if !lookupResp.Success || lookupResp.Data == nil || lookupResp.Data.ID == 0 {
-			return 0, fmt.Errorf("qobuz track not found for isrc: %s", isrc)
+			return 0, fmt.Errorf("qobuz track for isrc %s: %w", isrc, ErrNotFound)
 		}
 		return lookupResp.Data.ID, nil
 	}
@@ -198,7 +217,15 @@ func (p *QobuzProvider) GetRecommendations(ctx context.Context, id string) ([]do
 	return nil, ErrQobuzNotSupported
 }
 
-func (p *QobuzProvider) get(ctx context.Context, targetURL string, result interface{}) error {
+// GetSimilarTracks returns no tracks: Qobuz has no track radio.
+func (p *QobuzProvider) GetSimilarTracks(ctx context.Context, id string) ([]domain.CatalogTrack, error) {
+	return nil, nil
+}
+
+func (p *QobuzProvider) get(ctx context.Context, targetURL string, result interface{}) (err error) {
+	start := time.Now()
+	defer func() { metrics.ObserveProviderRequest(string(ProviderTypeQobuz), start, err) }()
+
 	req, err := http.NewRequestWithContext(ctx, http.MethodGet, targetURL, nil)
 	if err != nil {
 		return err
@@ -211,6 +238,10 @@ func (p *QobuzProvider) get(ctx context.Context, targetURL string, result interf
 	}
 	defer func() { _ = resp.Body.Close() }()
 
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("API request failed: %w", statusError(resp))
+	}
+
 	decoder := json.NewDecoder(resp.Body)
 	return decoder.Decode(result)
 }
@@ -218,6 +249,7 @@ func (p *QobuzProvider) get(ctx context.Context, targetURL string, result interf
 func (p *QobuzProvider) setHeaders(req *http.Request) {
 	req.Header.Set("User-Agent", "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/120.0.0.0 Safari/537.36")
 	req.Header.Set("Accept", "application/json")
+	setCustomHeaders(req, p.BaseURL, p.headers)
 }
 
 func qobuzQualityCode(quality string) int {
@@ -22,6 +22,7 @@ func (r *QobuzSearchData) ToDomain() *domain.SearchResult {
 		Tracks:    make([]domain.CatalogTrack, 0),
 		Artists:   make([]domain.Artist, 0),
 		Playlists: make([]domain.Playlist, 0),
+		Total:     max(r.Albums.Total, r.Tracks.Total, r.Artists.Total, r.Playlists.Total),
 	}
 
 	for _, item := range r.Albums.Items {
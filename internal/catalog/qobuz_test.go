@@ -207,7 +207,7 @@ func TestQobuzSearch_RejectsFailedResponse(t *testing.T) {
 
 	p := NewQobuzProvider(srv.URL)
 
-	_, err := p.Search(context.Background(), "test", "all")
+	_, err := p.Search(context.Background(), "test", "all", 0, 0)
 	if err == nil {
 		t.Fatal("expected error for unsuccessful search response")
 	}
@@ -264,7 +264,7 @@ func TestQobuzSearch_Success(t *testing.T) {
 
 	p := NewQobuzProvider(srv.URL)
 
-	result, err := p.Search(context.Background(), "test", "all")
+	result, err := p.Search(context.Background(), "test", "all", 0, 0)
 	if err != nil {
 		t.Fatalf("Search failed: %v", err)
 	}
@@ -423,7 +423,7 @@ func TestQobuzSearch_FiltersByType(t *testing.T) {
 	p := NewQobuzProvider(srv.URL)
 
 	t.Run("filter by album", func(t *testing.T) {
-		result, err := p.Search(context.Background(), "test", "album")
+		result, err := p.Search(context.Background(), "test", "album", 0, 0)
 		if err != nil {
 			t.Fatalf("Search failed: %v", err)
 		}
@@ -439,7 +439,7 @@ func TestQobuzSearch_FiltersByType(t *testing.T) {
 	})
 
 	t.Run("filter by track", func(t *testing.T) {
-		result, err := p.Search(context.Background(), "test", "track")
+		result, err := p.Search(context.Background(), "test", "track", 0, 0)
 		if err != nil {
 			t.Fatalf("Search failed: %v", err)
 		}
@@ -2,91 +2,159 @@ package catalog
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"net/url"
+	"strings"
+
+	"golang.org/x/sync/errgroup"
 
 	"github.com/cesargomez89/navidrums/internal/domain"
 )
 
-func (p *HifiProvider) Search(ctx context.Context, query string, searchType string) (*domain.SearchResult, error) {
+// searchPunctuation is replaced by spaces when retrying a search that found nothing,
+// so "d-charged" also finds "D Charged".
+var searchPunctuation = strings.NewReplacer("-", " ", "_", " ", ".", " ")
+
+// normalizeSearchQuery replaces punctuation with spaces and collapses whitespace.
+func normalizeSearchQuery(query string) string {
+	return strings.Join(strings.Fields(searchPunctuation.Replace(query)), " ")
+}
+
+// searchWithNormalizedFallback searches for query as typed and, only when that finds
+// nothing, retries once with the normalized query.
+func searchWithNormalizedFallback(ctx context.Context, p Provider, query, searchType string, offset, limit int) (*domain.SearchResult, error) {
+	res, err := p.Search(ctx, query, searchType, offset, limit)
+	if err != nil || !res.IsEmpty() {
+		return res, err
+	}
+	normalized := normalizeSearchQuery(query)
+	if normalized == "" || normalized == query {
+		return res, nil
+	}
+	return p.Search(ctx, normalized, searchType, offset, limit)
+}
+
+func (p *HifiProvider) Search(ctx context.Context, query string, searchType string, offset, limit int) (*domain.SearchResult, error) {
 	res := &domain.SearchResult{}
 
 	if searchType == "" {
 		searchType = "album"
 	}
 
+	var err error
 	switch searchType {
 	case "artist":
-		artists, err := p.searchArtists(ctx, query)
-		if err != nil {
-			return nil, err
-		}
-		res.Artists = artists
+		res.Artists, res.Total, err = p.searchArtists(ctx, query, offset, limit)
 	case "album":
-		albums, err := p.searchAlbums(ctx, query)
-		if err != nil {
-			return nil, err
-		}
-		res.Albums = albums
+		res.Albums, res.Total, err = p.searchAlbums(ctx, query, offset, limit)
 	case "track":
-		tracks, err := p.searchTracks(ctx, query)
-		if err != nil {
-			return nil, err
-		}
-		res.Tracks = tracks
+		res.Tracks, res.Total, err = p.searchTracks(ctx, query, offset, limit)
 	case "playlist":
-		playlists, err := p.searchPlaylists(ctx, query)
-		if err != nil {
-			return nil, err
-		}
-		res.Playlists = playlists
+		res.Playlists, res.Total, err = p.searchPlaylists(ctx, query, offset, limit)
+	case "all":
+		return p.searchAll(ctx, query, offset, limit)
 	default:
-		albums, err := p.searchAlbums(ctx, query)
-		if err != nil {
-			return nil, err
-		}
-		res.Albums = albums
+		res.Albums, res.Total, err = p.searchAlbums(ctx, query, offset, limit)
+	}
+	if err != nil {
+		return nil, err
 	}
 
 	return res, nil
 }
 
-func (p *HifiProvider) searchArtists(ctx context.Context, query string) ([]domain.Artist, error) {
-	u := fmt.Sprintf("%s/search/?a=%s", p.BaseURL, url.QueryEscape(query))
+// searchAll runs every search type concurrently and merges what comes back. A category
+// that fails is listed in FailedTypes; an error is returned only when all of them fail.
+func (p *HifiProvider) searchAll(ctx context.Context, query string, offset, limit int) (*domain.SearchResult, error) {
+	res := &domain.SearchResult{}
+	var artistsErr, albumsErr, tracksErr, playlistsErr error
+	var artistsTotal, albumsTotal, tracksTotal, playlistsTotal int
+
+	var g errgroup.Group
+	g.Go(func() error {
+		res.Artists, artistsTotal, artistsErr = p.searchArtists(ctx, query, offset, limit)
+		return nil
+	})
+	g.Go(func() error {
+		res.Albums, albumsTotal, albumsErr = p.searchAlbums(ctx, query, offset, limit)
+		return nil
+	})
+	g.Go(func() error {
+		res.Tracks, tracksTotal, tracksErr = p.searchTracks(ctx, query, offset, limit)
+		return nil
+	})
+	g.Go(func() error {
+		res.Playlists, playlistsTotal, playlistsErr = p.searchPlaylists(ctx, query, offset, limit)
+		return nil
+	})
+	_ = g.Wait()
+
+	var errs []error
+	for _, c := range []struct {
+		name  string
+		err   error
+		total int
+	}{
+		{"artist", artistsErr, artistsTotal},
+		{"album", albumsErr, albumsTotal},
+		{"track", tracksErr, tracksTotal},
+		{"playlist", playlistsErr, playlistsTotal},
+	} {
+		if c.err != nil {
+			res.FailedTypes = append(res.FailedTypes, c.name)
+			errs = append(errs, c.err)
+		}
+		res.Total = max(res.Total, c.total)
+	}
+	if len(errs) == 4 {
+		return nil, errors.Join(errs...)
+	}
+	return res, nil
+}
+
+// searchURL builds a search request for the given parameter, adding paging only when a
+// limit is set so unpaged calls keep the API's default page.
+func (p *HifiProvider) searchURL(param, query string, offset, limit int) string {
+	u := fmt.Sprintf("%s/search/?%s=%s", p.BaseURL, param, url.QueryEscape(query))
+	if limit > 0 {
+		u += fmt.Sprintf("&offset=%d&limit=%d", offset, limit)
+	}
+	return u
+}
+
+func (p *HifiProvider) searchArtists(ctx context.Context, query string, offset, limit int) ([]domain.Artist, int, error) {
 	var resp APIArtistsSearchResponse
-	if err := p.get(ctx, u, &resp); err != nil {
-		return nil, err
+	if err := p.get(ctx, p.searchURL("a", query, offset, limit), &resp); err != nil {
+		return nil, 0, err
 	}
 
-	return resp.ToDomain(p), nil
+	return resp.ToDomain(p), resp.Data.Artists.TotalNumberOfItems, nil
 }
 
-func (p *HifiProvider) searchAlbums(ctx context.Context, query string) ([]domain.Album, error) {
-	u := fmt.Sprintf("%s/search/?al=%s", p.BaseURL, url.QueryEscape(query))
+func (p *HifiProvider) searchAlbums(ctx context.Context, query string, offset, limit int) ([]domain.Album, int, error) {
 	var resp APIAlbumsSearchResponse
-	if err := p.get(ctx, u, &resp); err != nil {
-		return nil, err
+	if err := p.get(ctx, p.searchURL("al", query, offset, limit), &resp); err != nil {
+		return nil, 0, err
 	}
 
-	return resp.ToDomain(p), nil
+	return resp.ToDomain(p), resp.Data.Albums.TotalNumberOfItems, nil
 }
 
-func (p *HifiProvider) searchTracks(ctx context.Context, query string) ([]domain.CatalogTrack, error) {
-	u := fmt.Sprintf("%s/search/?s=%s", p.BaseURL, url.QueryEscape(query))
+func (p *HifiProvider) searchTracks(ctx context.Context, query string, offset, limit int) ([]domain.CatalogTrack, int, error) {
 	var resp APITracksSearchResponse
-	if err := p.get(ctx, u, &resp); err != nil {
-		return nil, err
+	if err := p.get(ctx, p.searchURL("s", query, offset, limit), &resp); err != nil {
+		return nil, 0, err
 	}
 
-	return resp.ToDomain(p), nil
+	return resp.ToDomain(p), resp.Data.TotalNumberOfItems, nil
 }
 
-func (p *HifiProvider) searchPlaylists(ctx context.Context, query string) ([]domain.Playlist, error) {
-	u := fmt.Sprintf("%s/search/?p=%s", p.BaseURL, url.QueryEscape(query))
+func (p *HifiProvider) searchPlaylists(ctx context.Context, query string, offset, limit int) ([]domain.Playlist, int, error) {
 	var resp APIPlaylistsSearchResponse
-	if err := p.get(ctx, u, &resp); err != nil {
-		return nil, err
+	if err := p.get(ctx, p.searchURL("p", query, offset, limit), &resp); err != nil {
+		return nil, 0, err
 	}
 
-	return resp.ToDomain(p), nil
+	return resp.ToDomain(p), resp.Data.Playlists.TotalNumberOfItems, nil
 }
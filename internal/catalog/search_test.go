@@ -7,6 +7,8 @@ import (
 	"net/http/httptest"
 	"strings"
 	"testing"
+
+	"github.com/cesargomez89/navidrums/internal/domain"
 )
 
 func TestHifiProviderSearch_ReturnsErrorOnUpstreamFailure(t *testing.T) {
@@ -20,7 +22,7 @@ func TestHifiProviderSearch_ReturnsErrorOnUpstreamFailure(t *testing.T) {
 
 	for _, searchType := range types {
 		t.Run(searchType, func(t *testing.T) {
-			res, err := provider.Search(context.Background(), "test", searchType)
+			res, err := provider.Search(context.Background(), "test", searchType, 0, 0)
 			if err == nil {
 				t.Fatalf("expected error for search type %q, got nil", searchType)
 			}
@@ -33,3 +35,129 @@ func TestHifiProviderSearch_ReturnsErrorOnUpstreamFailure(t *testing.T) {
 		})
 	}
 }
+
+func TestHifiProviderSearch_All(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		q := r.URL.Query()
+		switch {
+		case q.Has("a"):
+			_, _ = w.Write([]byte(`{"data":{"artists":{"items":[{"id":1,"name":"Artist"}]}}}`))
+		case q.Has("al"):
+			_, _ = w.Write([]byte(`{"data":{"albums":{"items":[{"id":2,"title":"Album"}]}}}`))
+		case q.Has("s"):
+			_, _ = w.Write([]byte(`{"data":{"items":[{"id":3,"title":"Track"}]}}`))
+		default:
+			http.Error(w, "upstream failure", http.StatusBadGateway)
+		}
+	}))
+	defer srv.Close()
+
+	provider := NewHifiProvider(srv.URL)
+	res, err := provider.Search(context.Background(), "test", "all", 0, 0)
+	if err != nil {
+		t.Fatalf("expected partial results, got error: %v", err)
+	}
+	if len(res.Artists) != 1 || len(res.Albums) != 1 || len(res.Tracks) != 1 {
+		t.Fatalf("expected one artist, album and track, got %+v", res)
+	}
+	if len(res.Playlists) != 0 {
+		t.Fatalf("expected no playlists, got %d", len(res.Playlists))
+	}
+	if len(res.FailedTypes) != 1 || res.FailedTypes[0] != "playlist" {
+		t.Fatalf("expected playlist to be reported as failed, got %v", res.FailedTypes)
+	}
+}
+
+func TestHifiProviderSearch_AllFailing(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "upstream failure", http.StatusBadGateway)
+	}))
+	defer srv.Close()
+
+	provider := NewHifiProvider(srv.URL)
+	res, err := provider.Search(context.Background(), "test", "all", 0, 0)
+	if err == nil {
+		t.Fatal("expected error when every category fails")
+	}
+	if res != nil {
+		t.Fatal("expected nil result when every category fails")
+	}
+}
+
+func TestHifiProviderSearch_Paging(t *testing.T) {
+	var gotOffset, gotLimit string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotOffset = r.URL.Query().Get("offset")
+		gotLimit = r.URL.Query().Get("limit")
+		_, _ = w.Write([]byte(`{"data":{"albums":{"items":[{"id":2,"title":"Album"}],"totalNumberOfItems":95}}}`))
+	}))
+	defer srv.Close()
+
+	provider := NewHifiProvider(srv.URL)
+	res, err := provider.Search(context.Background(), "test", "album", 60, 30)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if gotOffset != "60" || gotLimit != "30" {
+		t.Fatalf("expected offset=60 limit=30, got offset=%q limit=%q", gotOffset, gotLimit)
+	}
+	if res.Total != 95 {
+		t.Fatalf("expected total 95, got %d", res.Total)
+	}
+
+	if _, err := provider.Search(context.Background(), "test", "album", 0, 0); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if gotOffset != "" || gotLimit != "" {
+		t.Fatalf("expected no paging params without a limit, got offset=%q limit=%q", gotOffset, gotLimit)
+	}
+}
+
+type queryRecordingProvider struct {
+	Provider
+	hits    map[string]bool
+	queries []string
+}
+
+func (p *queryRecordingProvider) Search(ctx context.Context, query string, searchType string, offset, limit int) (*domain.SearchResult, error) {
+	p.queries = append(p.queries, query)
+	if p.hits[query] {
+		return &domain.SearchResult{Albums: []domain.Album{{Title: query}}}, nil
+	}
+	return &domain.SearchResult{}, nil
+}
+
+func TestSearchWithNormalizedFallback(t *testing.T) {
+	tests := []struct {
+		name        string
+		query       string
+		hits        []string
+		wantQueries []string
+		wantResults int
+	}{
+		{"exact match wins", "d-charged", []string{"d-charged", "d charged"}, []string{"d-charged"}, 1},
+		{"falls back when empty", "d-charged", []string{"d charged"}, []string{"d-charged", "d charged"}, 1},
+		{"collapses punctuation and spaces", "mr._brightside  -  live", []string{"mr brightside live"}, []string{"mr._brightside  -  live", "mr brightside live"}, 1},
+		{"no retry without punctuation", "nothing here", nil, []string{"nothing here"}, 0},
+		{"both empty", "no-match", nil, []string{"no-match", "no match"}, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := &queryRecordingProvider{hits: make(map[string]bool)}
+			for _, h := range tt.hits {
+				p.hits[h] = true
+			}
+
+			res, err := searchWithNormalizedFallback(context.Background(), p, tt.query, "album", 0, 0)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if len(res.Albums) != tt.wantResults {
+				t.Errorf("expected %d results, got %d", tt.wantResults, len(res.Albums))
+			}
+			if strings.Join(p.queries, "|") != strings.Join(tt.wantQueries, "|") {
+				t.Errorf("queries = %q, want %q", p.queries, tt.wantQueries)
+			}
+		})
+	}
+}
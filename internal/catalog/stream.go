@@ -0,0 +1,65 @@
+package catalog
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/cesargomez89/navidrums/internal/constants"
+)
+
+// streamDoer sends a single stream request.
+type streamDoer func(req *http.Request) (*http.Response, error)
+
+// fetchStream GETs url, backing off and retrying while the CDN answers 429 or 503.
+// The final response is returned whatever its status, so callers keep their own
+// status handling; only transport errors and cancellation are returned as errors.
+func fetchStream(ctx context.Context, do streamDoer, url string, prepare func(*http.Request)) (*http.Response, error) {
+	for attempt := 1; ; attempt++ {
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+		if err != nil {
+			return nil, err
+		}
+		if prepare != nil {
+			prepare(req)
+		}
+		resp, err := do(req)
+		if err != nil {
+			return nil, err
+		}
+		if !isThrottled(resp.StatusCode) || attempt >= constants.StreamRetryAttempts {
+			return resp, nil
+		}
+
+		wait := retryAfterDelay(resp.Header.Get("Retry-After"), attempt, time.Now())
+		_ = resp.Body.Close()
+
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return nil, ctx.Err()
+		case <-timer.C:
+		}
+	}
+}
+
+func isThrottled(status int) bool {
+	return status == http.StatusTooManyRequests || status == http.StatusServiceUnavailable
+}
+
+// retryAfterDelay reads a Retry-After header given in seconds or as an HTTP date,
+// falling back to exponential backoff when it is absent. The wait is capped so a
+// misbehaving CDN can't stall a download indefinitely.
+func retryAfterDelay(header string, attempt int, now time.Time) time.Duration {
+	wait := constants.StreamRetryBase << (attempt - 1)
+	if header != "" {
+		if secs, err := strconv.Atoi(header); err == nil && secs >= 0 {
+			wait = time.Duration(secs) * time.Second
+		} else if at, err := http.ParseTime(header); err == nil {
+			wait = max(at.Sub(now), 0)
+		}
+	}
+	return min(wait, constants.StreamRetryMaxWait)
+}
@@ -0,0 +1,96 @@
+package catalog
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/cesargomez89/navidrums/internal/constants"
+)
+
+func TestMultiSegmentReader_RetriesThrottledSegment(t *testing.T) {
+	var firstHits int
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/seg1" {
+			firstHits++
+			if firstHits == 1 {
+				w.Header().Set("Retry-After", "0")
+				http.Error(w, "slow down", http.StatusTooManyRequests)
+				return
+			}
+		}
+		_, _ = w.Write([]byte(r.URL.Path))
+	}))
+	defer srv.Close()
+
+	r := &multiSegmentReader{
+		urls:   []string{srv.URL + "/seg1", srv.URL + "/seg2"},
+		client: srv.Client(),
+		ctx:    context.Background(),
+	}
+	defer func() { _ = r.Close() }()
+
+	got, err := io.ReadAll(r)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if string(got) != "/seg1/seg2" {
+		t.Fatalf("expected both segments, got %q", got)
+	}
+	if firstHits != 2 {
+		t.Fatalf("expected throttled segment to be fetched twice, got %d", firstHits)
+	}
+}
+
+func TestMultiSegmentReader_GivesUpAfterRetries(t *testing.T) {
+	var hits int
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits++
+		w.Header().Set("Retry-After", "0")
+		http.Error(w, "unavailable", http.StatusServiceUnavailable)
+	}))
+	defer srv.Close()
+
+	r := &multiSegmentReader{
+		urls:   []string{srv.URL + "/seg1"},
+		client: srv.Client(),
+		ctx:    context.Background(),
+	}
+
+	if _, err := io.ReadAll(r); err == nil {
+		t.Fatal("expected error once retries are exhausted")
+	}
+	if hits != constants.StreamRetryAttempts {
+		t.Fatalf("expected %d attempts, got %d", constants.StreamRetryAttempts, hits)
+	}
+}
+
+func TestRetryAfterDelay(t *testing.T) {
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name     string
+		header   string
+		attempt  int
+		expected time.Duration
+	}{
+		{"seconds", "5", 1, 5 * time.Second},
+		{"zero seconds", "0", 1, 0},
+		{"http date", now.Add(3 * time.Second).Format(http.TimeFormat), 1, 3 * time.Second},
+		{"date in the past", now.Add(-time.Minute).Format(http.TimeFormat), 1, 0},
+		{"missing uses backoff", "", 3, 4 * constants.StreamRetryBase},
+		{"garbage uses backoff", "soon", 1, constants.StreamRetryBase},
+		{"capped", "3600", 1, constants.StreamRetryMaxWait},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := retryAfterDelay(tt.header, tt.attempt, now)
+			if got != tt.expected {
+				t.Errorf("retryAfterDelay(%q, %d) = %v, want %v", tt.header, tt.attempt, got, tt.expected)
+			}
+		})
+	}
+}
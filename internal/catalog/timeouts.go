@@ -0,0 +1,109 @@
+package catalog
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/cesargomez89/navidrums/internal/constants"
+	"github.com/cesargomez89/navidrums/internal/httpclient"
+)
+
+// providerRequestInterval is the minimum gap between a provider's requests.
+const providerRequestInterval = 500 * time.Millisecond
+
+// errStreamStalled fails a stream that sent nothing for Timeouts.StreamIdle.
+var errStreamStalled = errors.New("stream stalled")
+
+// Timeouts bound a provider's requests. API calls return small JSON documents and should
+// fail fast, while a Hi-Res stream can take many minutes on a slow link, so each gets
+// its own limit.
+type Timeouts struct {
+	// Metadata bounds each API call, body included; 0 uses constants.DefaultProviderTimeout.
+	Metadata time.Duration
+	// Stream bounds a whole stream or segment fetch, body included; 0 leaves it to the
+	// caller's context.
+	Stream time.Duration
+	// StreamIdle fails a stream or segment fetch that receives nothing for this long,
+	// waiting for the response included; 0 turns the check off.
+	StreamIdle time.Duration
+}
+
+// clients builds the metadata and stream clients for a provider. They share one rate
+// limit, so a provider sees at most one request per interval whichever client sends it,
+// and the default transport, so streams reuse the API's connections where the host is
+// the same.
+func (t Timeouts) clients(pt ProviderType) (metadata, stream *httpclient.Client) {
+	metaTimeout := t.Metadata
+	if metaTimeout <= 0 {
+		metaTimeout = constants.DefaultProviderTimeout
+	}
+	metadata = httpclient.NewClient(&http.Client{Timeout: metaTimeout}, providerRequestInterval).WithName(string(pt))
+	streamClient := &http.Client{Timeout: max(t.Stream, 0)}
+	if t.StreamIdle > 0 {
+		streamClient.Transport = &idleTimeoutTransport{base: http.DefaultTransport, idle: t.StreamIdle}
+	}
+	stream = metadata.Sharing(streamClient)
+	return metadata, stream
+}
+
+// idleTimeoutTransport cancels a request once idle passes without a response or, after
+// it, without a read of the body returning data.
+type idleTimeoutTransport struct {
+	base http.RoundTripper
+	idle time.Duration
+}
+
+func (t *idleTimeoutTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	ctx, cancel := context.WithCancelCause(req.Context())
+	timer := time.AfterFunc(t.idle, func() {
+		cancel(fmt.Errorf("%w: no data for %s", errStreamStalled, t.idle))
+	})
+	resp, err := t.base.RoundTrip(req.WithContext(ctx))
+	if err != nil {
+		timer.Stop()
+		err = stalledError(ctx, err)
+		cancel(nil)
+		return nil, err
+	}
+	resp.Body = &idleTimeoutBody{ReadCloser: resp.Body, ctx: ctx, cancel: cancel, timer: timer, idle: t.idle}
+	return resp, nil
+}
+
+// idleTimeoutBody restarts its transport's idle timer whenever a read returns data.
+type idleTimeoutBody struct {
+	io.ReadCloser
+	ctx    context.Context
+	cancel context.CancelCauseFunc
+	timer  *time.Timer
+	idle   time.Duration
+}
+
+func (b *idleTimeoutBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	if n > 0 {
+		b.timer.Reset(b.idle)
+	}
+	if err != nil && err != io.EOF {
+		err = stalledError(b.ctx, err)
+	}
+	return n, err
+}
+
+func (b *idleTimeoutBody) Close() error {
+	b.timer.Stop()
+	err := b.ReadCloser.Close()
+	b.cancel(nil)
+	return err
+}
+
+// stalledError reports err as a stall when the idle timer is what cancelled ctx.
+func stalledError(ctx context.Context, err error) error {
+	if cause := context.Cause(ctx); errors.Is(cause, errStreamStalled) {
+		return cause
+	}
+	return err
+}
@@ -0,0 +1,137 @@
+package catalog
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestHifiProvider_Timeouts(t *testing.T) {
+	const chunks = 5
+	var api *httptest.Server
+	api = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/info/":
+			// A provider that hangs on metadata.
+			select {
+			case <-r.Context().Done():
+			case <-time.After(5 * time.Second):
+			}
+		case "/track/":
+			audio := api.URL + "/audio"
+			if r.URL.Query().Get("id") == "stall" {
+				audio = api.URL + "/stall"
+			}
+			manifest := base64.StdEncoding.EncodeToString([]byte(fmt.Sprintf(`{"mimeType":"audio/flac","urls":[%q]}`, audio)))
+			_, _ = fmt.Fprintf(w, `{"data":{"manifest":%q,"manifestMimeType":"application/vnd.tidal.bts"}}`, manifest)
+		case "/audio":
+			// A slow link: the body takes longer than the metadata timeout.
+			flusher, _ := w.(http.Flusher)
+			for i := 0; i < chunks; i++ {
+				select {
+				case <-r.Context().Done():
+					return
+				case <-time.After(60 * time.Millisecond):
+				}
+				_, _ = w.Write([]byte("a"))
+				flusher.Flush()
+			}
+		case "/stall":
+			// A connection that stops sending partway through.
+			_, _ = w.Write([]byte("a"))
+			w.(http.Flusher).Flush()
+			select {
+			case <-r.Context().Done():
+			case <-time.After(5 * time.Second):
+			}
+		}
+	}))
+	defer api.Close()
+
+	timeouts := Timeouts{Metadata: 100 * time.Millisecond, StreamIdle: 200 * time.Millisecond}
+
+	t.Run("metadata call times out", func(t *testing.T) {
+		provider := NewProvider(ProviderTypeHifi, api.URL, "", nil, timeouts)
+		start := time.Now()
+		if _, err := provider.GetTrack(context.Background(), "1"); err == nil {
+			t.Fatal("GetTrack succeeded, want a timeout")
+		}
+		if elapsed := time.Since(start); elapsed > 2*time.Second {
+			t.Errorf("GetTrack took %v, want about %v", elapsed, timeouts.Metadata)
+		}
+	})
+
+	t.Run("stream outlives the metadata timeout", func(t *testing.T) {
+		provider := NewProvider(ProviderTypeHifi, api.URL, "", nil, timeouts)
+		stream, _, err := provider.GetStream(context.Background(), "1", "", "LOSSLESS")
+		if err != nil {
+			t.Fatalf("GetStream failed: %v", err)
+		}
+		defer func() { _ = stream.Close() }()
+		data, err := io.ReadAll(stream)
+		if err != nil || len(data) != chunks {
+			t.Errorf("read %d bytes, err %v; want %d bytes", len(data), err, chunks)
+		}
+	})
+
+	t.Run("stalled stream fails after the idle timeout", func(t *testing.T) {
+		provider := NewProvider(ProviderTypeHifi, api.URL, "", nil, timeouts)
+		stream, _, err := provider.GetStream(context.Background(), "stall", "", "LOSSLESS")
+		if err != nil {
+			t.Fatalf("GetStream failed: %v", err)
+		}
+		defer func() { _ = stream.Close() }()
+		start := time.Now()
+		data, err := io.ReadAll(stream)
+		if !errors.Is(err, errStreamStalled) || len(data) != 1 {
+			t.Errorf("read %d bytes, err %v; want 1 byte then a stall", len(data), err)
+		}
+		if elapsed := time.Since(start); elapsed > 2*time.Second {
+			t.Errorf("stall detected after %v, want about %v", elapsed, timeouts.StreamIdle)
+		}
+	})
+
+	t.Run("stream stops at the context deadline", func(t *testing.T) {
+		provider := NewProvider(ProviderTypeHifi, api.URL, "", nil, timeouts)
+		ctx, cancel := context.WithTimeout(context.Background(), 150*time.Millisecond)
+		defer cancel()
+		stream, _, err := provider.GetStream(ctx, "1", "", "LOSSLESS")
+		if err != nil {
+			return // the deadline hit before the stream started
+		}
+		defer func() { _ = stream.Close() }()
+		if data, err := io.ReadAll(stream); err == nil {
+			t.Errorf("read %d bytes without error, want the context deadline to stop it", len(data))
+		}
+	})
+}
+
+func TestTimeouts_ClientsShareRateLimit(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer server.Close()
+
+	metadata, stream := Timeouts{}.clients(ProviderTypeHifi)
+	start := time.Now()
+	for _, client := range []interface {
+		Do(context.Context, *http.Request) (*http.Response, error)
+	}{metadata, stream} {
+		req, err := http.NewRequest(http.MethodGet, server.URL, nil)
+		if err != nil {
+			t.Fatalf("NewRequest failed: %v", err)
+		}
+		resp, err := client.Do(context.Background(), req)
+		if err != nil {
+			t.Fatalf("Do failed: %v", err)
+		}
+		_ = resp.Body.Close()
+	}
+	if elapsed := time.Since(start); elapsed < providerRequestInterval-50*time.Millisecond {
+		t.Errorf("a metadata and a stream request took %v, want the stream to wait %v", elapsed, providerRequestInterval)
+	}
+}
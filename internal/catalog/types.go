@@ -95,19 +95,14 @@ func (r *multiSegmentReader) Read(p []byte) (n int, err error) {
 		}
 
 		// Fetch next segment
-		var req *http.Request
-		req, err = http.NewRequestWithContext(r.ctx, "GET", r.urls[r.currIdx], nil)
-		if err != nil {
-			return 0, err
-		}
 		var resp *http.Response
-		resp, err = r.client.Do(req)
+		resp, err = fetchStream(r.ctx, r.client.Do, r.urls[r.currIdx], nil)
 		if err != nil {
 			return 0, err
 		}
 		if resp.StatusCode != http.StatusOK {
 			_ = resp.Body.Close()
-			return 0, fmt.Errorf("segment fetch failed (%d): %s", r.currIdx, resp.Status)
+			return 0, fmt.Errorf("segment fetch failed (%d): %w", r.currIdx, statusError(resp))
 		}
 		r.currBody = resp.Body
 		r.currIdx++
@@ -117,6 +112,10 @@ func (r *multiSegmentReader) Read(p []byte) (n int, err error) {
 	if err == io.EOF {
 		_ = r.currBody.Close()
 		r.currBody = nil
+		// A final read can return data alongside EOF; hand it back before moving on.
+		if n > 0 {
+			return n, nil
+		}
 		// Check context before recursive call
 		select {
 		case <-r.ctx.Done():
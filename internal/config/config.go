@@ -1,9 +1,12 @@
 package config
 
 import (
+	"errors"
 	"fmt"
+	"net/netip"
 	"os"
 	"path/filepath"
+	"slices"
 	"strconv"
 	"strings"
 	"text/template"
@@ -14,67 +17,210 @@ import (
 
 // Config holds all application configuration
 type Config struct {
-	Port                  string
-	DBPath                string
-	DownloadsDir          string
-	Quality               string
-	PlayQuality           string
-	LogLevel              string
-	LogFormat             string
-	Username              string
-	Password              string
-	SubdirTemplate        string
-	MusicBrainzURL        string
-	FFmpegPath            string
-	FFprobePath           string
-	Theme                 string
-	CacheTTL              time.Duration
-	MusicBrainzCacheTTL   time.Duration
-	RateLimitWindow       time.Duration
-	RateLimitRequests     int
-	RateLimitBurst        int
-	SkipAuth              bool
-	DisableRateLimit      bool
-	LyricsFallbackEnabled bool
-	LyricsFallbackURL     string
-}
-
-// Load loads configuration from environment variables with defaults
+	Port                        string
+	DBPath                      string
+	DownloadsDir                string
+	DownloadsDirMap             string
+	ImportDir                   string
+	Quality                     string
+	PlayQuality                 string
+	LogLevel                    string
+	LogFormat                   string
+	Username                    string
+	Password                    string
+	SubdirTemplate              string
+	MultiDiscLayout             string
+	MusicBrainzURL              string
+	MusicBrainzUserAgent        string
+	MusicBrainzReleaseCountry   string
+	MusicBrainzSkipProviders    string
+	NavidromeURL                string
+	NavidromeUsername           string
+	NavidromePassword           string
+	FFmpegPath                  string
+	FFprobePath                 string
+	Theme                       string
+	CacheTTL                    time.Duration
+	MusicBrainzCacheTTL         time.Duration
+	MusicBrainzRateLimit        time.Duration
+	RateLimitWindow             time.Duration
+	RateLimitRequests           int
+	RateLimitBurst              int
+	MetadataConcurrency         int
+	SkipAuth                    bool
+	DisableRateLimit            bool
+	TrustedProxies              string
+	SessionSecret               string
+	SessionTTL                  time.Duration
+	RateLimitExemptCIDRs        string
+	LyricsFallbackEnabled       bool
+	LyricsFallbackURL           string
+	MetricsEnabled              bool
+	PreferOriginalReleaseDate   bool
+	EnableMusicBrainzEnrichment bool
+	DedupeAgainstNavidrome      bool
+	MetricsAddr                 string
+	MissingFileSweepInterval    time.Duration
+	SaveFolderArt               bool
+	SaveArtistArt               bool
+	WriteLrcSidecar             bool
+	EmbedSyncedLyrics           bool
+	TagMergeStrategy            string
+	ArtistTagMode               string
+	FeaturedArtists             string
+	CoverArtFilename            string
+	CoverArtForceJPEG           bool
+	CoverArtJPEGQuality         int
+	CoverArtSize                string
+	FallbackExtension           string
+	SkipDuplicateISRC           bool
+	MinTrackDurationSecs        int
+	UpgradeQualityOnSync        bool
+	PlaylistAbsolutePaths       bool
+	PlaylistFormat              string
+	DiscographyReleaseTypes     string
+	CompilationDetection        bool
+	CompilationMinArtists       int
+	VariousArtistsName          string
+	ShutdownDrainTimeout        time.Duration
+	EmbedExtraPictures          bool
+	OnExistingFile              string
+	CreditsTagging              bool
+	EmbedCoverArt               bool
+	OriginalDateTagging         bool
+	GaplessTags                 bool
+	ID3Version                  string
+	PreferCleanVersions         bool
+	HTTPReadTimeout             time.Duration
+	HTTPWriteTimeout            time.Duration
+	HTTPIdleTimeout             time.Duration
+	ProviderTimeout             time.Duration
+	StreamTimeout               time.Duration
+	StreamIdleTimeout           time.Duration
+	DownloadWindowStart         string
+	DownloadWindowEnd           string
+	ConfigFile                  string
+
+	// configFileErr is reported by Validate so a broken config file fails startup.
+	configFileErr error
+	// downloadsDirs is DownloadsDirMap parsed once; an invalid map is reported by Validate
+	// and leaves it empty.
+	downloadsDirs map[string]string
+}
+
+// Load loads configuration from environment variables, then the optional config file
+// (CONFIG_FILE, or config.yaml in the working directory), then defaults.
 func Load() *Config {
 	home, _ := os.UserHomeDir()
 	defaultDownload := filepath.Join(home, "Downloads/navidrums")
 
-	return &Config{
-		Port:                  getEnv("PORT", constants.DefaultPort),
-		DBPath:                getEnv("DB_PATH", constants.DefaultDBPath),
-		DownloadsDir:          getEnv("DOWNLOADS_DIR", defaultDownload),
-		Quality:               getEnv("QUALITY", constants.DefaultQuality),
-		PlayQuality:           getEnv("PLAY_QUALITY", "HIGH"),
-		LogLevel:              getEnv("LOG_LEVEL", "info"),
-		LogFormat:             getEnv("LOG_FORMAT", "text"),
-		Username:              getEnv("NAVIDRUMS_USERNAME", constants.DefaultUsername),
-		Password:              getEnv("NAVIDRUMS_PASSWORD", ""),
-		SubdirTemplate:        getEnv("SUBDIR_TEMPLATE", constants.DefaultSubdirTemplate),
-		CacheTTL:              getEnvDuration("CACHE_TTL", constants.DefaultCacheTTL),
-		MusicBrainzCacheTTL:   getEnvDuration("MUSICBRAINZ_CACHE_TTL", constants.DefaultMusicBrainzCacheTTL),
-		MusicBrainzURL:        getEnv("MUSICBRAINZ_URL", "https://musicbrainz.org/ws/2"),
-		RateLimitRequests:     getEnvInt("RATE_LIMIT_REQUESTS", 200),
-		RateLimitWindow:       getEnvDuration("RATE_LIMIT_WINDOW", time.Minute),
-		RateLimitBurst:        getEnvInt("RATE_LIMIT_BURST", 10),
-		SkipAuth:              getEnvBool("SKIP_AUTH", false),
-		DisableRateLimit:      getEnvBool("DISABLE_RATE_LIMIT", false),
-		Theme:                 getEnv("THEME", "golden"),
-		FFmpegPath:            getEnv("FFMPEG_PATH", ""),
-		FFprobePath:           getEnv("FFPROBE_PATH", ""),
-		LyricsFallbackEnabled: getEnvBool("LYRICS_FALLBACK_ENABLED", true),
-		LyricsFallbackURL:     getEnv("LYRICS_FALLBACK_URL", "https://lrclib.net/api/get"),
+	configFile := configFilePath()
+	file, fileErr := loadFile(configFile)
+	if fileErr != nil {
+		file = &fileValues{}
+	}
+
+	cfg := &Config{
+		ConfigFile:                  configFile,
+		configFileErr:               fileErr,
+		Port:                        file.getEnv("PORT", constants.DefaultPort),
+		DBPath:                      file.getEnv("DB_PATH", constants.DefaultDBPath),
+		DownloadsDir:                file.getEnv("DOWNLOADS_DIR", defaultDownload),
+		DownloadsDirMap:             file.getEnv("DOWNLOADS_DIR_MAP", ""),
+		ImportDir:                   file.getEnv("IMPORT_DIR", ""),
+		Quality:                     file.getEnv("QUALITY", constants.DefaultQuality),
+		PlayQuality:                 file.getEnv("PLAY_QUALITY", "HIGH"),
+		LogLevel:                    file.getEnv("LOG_LEVEL", "info"),
+		LogFormat:                   file.getEnv("LOG_FORMAT", "text"),
+		Username:                    file.getEnv("NAVIDRUMS_USERNAME", constants.DefaultUsername),
+		Password:                    file.getEnv("NAVIDRUMS_PASSWORD", ""),
+		SubdirTemplate:              file.getEnv("SUBDIR_TEMPLATE", constants.DefaultSubdirTemplate),
+		MultiDiscLayout:             file.getEnv("MULTI_DISC_LAYOUT", constants.MultiDiscLayoutFlat),
+		CacheTTL:                    file.getEnvDuration("CACHE_TTL", constants.DefaultCacheTTL),
+		MusicBrainzCacheTTL:         file.getEnvDuration("MUSICBRAINZ_CACHE_TTL", constants.DefaultMusicBrainzCacheTTL),
+		MusicBrainzURL:              file.getEnv("MUSICBRAINZ_URL", "https://musicbrainz.org/ws/2"),
+		MusicBrainzRateLimit:        file.getEnvDuration("MUSICBRAINZ_RATE_LIMIT", constants.DefaultMusicBrainzRateLimit),
+		MusicBrainzUserAgent:        file.getEnv("MUSICBRAINZ_USER_AGENT", constants.DefaultMusicBrainzUserAgent),
+		MusicBrainzReleaseCountry:   file.getEnv("MUSICBRAINZ_RELEASE_COUNTRY", ""),
+		EnableMusicBrainzEnrichment: file.getEnvBool("ENABLE_MUSICBRAINZ_ENRICHMENT", true),
+		MusicBrainzSkipProviders:    file.getEnv("MUSICBRAINZ_SKIP_PROVIDERS", ""),
+		DedupeAgainstNavidrome:      file.getEnvBool("DEDUPE_AGAINST_NAVIDROME", false),
+		NavidromeURL:                file.getEnv("NAVIDROME_URL", ""),
+		NavidromeUsername:           file.getEnv("NAVIDROME_USERNAME", ""),
+		NavidromePassword:           file.getEnv("NAVIDROME_PASSWORD", ""),
+		PreferOriginalReleaseDate:   file.getEnvBool("PREFER_ORIGINAL_RELEASE_DATE", true),
+		RateLimitRequests:           file.getEnvInt("RATE_LIMIT_REQUESTS", 200),
+		RateLimitWindow:             file.getEnvDuration("RATE_LIMIT_WINDOW", time.Minute),
+		RateLimitBurst:              file.getEnvInt("RATE_LIMIT_BURST", 10),
+		MetadataConcurrency:         file.getEnvInt("METADATA_CONCURRENCY", constants.DefaultMetadataConcurrency),
+		SkipAuth:                    file.getEnvBool("SKIP_AUTH", false),
+		DisableRateLimit:            file.getEnvBool("DISABLE_RATE_LIMIT", false),
+		TrustedProxies:              file.getEnv("TRUSTED_PROXIES", ""),
+		SessionSecret:               file.getEnv("SESSION_SECRET", ""),
+		SessionTTL:                  file.getEnvDuration("SESSION_TTL", constants.DefaultSessionTTL),
+		RateLimitExemptCIDRs:        file.getEnv("RATE_LIMIT_EXEMPT_CIDRS", ""),
+		Theme:                       file.getEnv("THEME", "golden"),
+		FFmpegPath:                  file.getEnv("FFMPEG_PATH", ""),
+		FFprobePath:                 file.getEnv("FFPROBE_PATH", ""),
+		LyricsFallbackEnabled:       file.getEnvBool("LYRICS_FALLBACK_ENABLED", true),
+		LyricsFallbackURL:           file.getEnv("LYRICS_FALLBACK_URL", "https://lrclib.net/api/get"),
+		MetricsEnabled:              file.getEnvBool("METRICS_ENABLED", false),
+		MetricsAddr:                 file.getEnv("METRICS_ADDR", ""),
+		MissingFileSweepInterval:    file.getEnvDuration("MISSING_FILE_SWEEP_INTERVAL", constants.DefaultMissingFileSweep),
+		SaveFolderArt:               file.getEnvBool("SAVE_FOLDER_ART", false),
+		SaveArtistArt:               file.getEnvBool("SAVE_ARTIST_ART", false),
+		WriteLrcSidecar:             file.getEnvBool("WRITE_LRC_SIDECAR", false),
+		EmbedSyncedLyrics:           file.getEnvBool("EMBED_SYNCED_LYRICS", true),
+		TagMergeStrategy:            file.getEnv("TAG_MERGE_STRATEGY", "overwrite"),
+		ArtistTagMode:               file.getEnv("ARTIST_TAG_MODE", "all"),
+		FeaturedArtists:             file.getEnv("FEATURED_ARTISTS", "keep"),
+		CoverArtFilename:            file.getEnv("COVER_ART_FILENAME", constants.CoverFileName),
+		CoverArtForceJPEG:           file.getEnvBool("COVER_ART_FORCE_JPEG", false),
+		CoverArtJPEGQuality:         file.getEnvInt("COVER_ART_JPEG_QUALITY", constants.DefaultCoverJPEGQuality),
+		CoverArtSize:                file.getEnv("COVER_ART_SIZE", constants.ImageSizeMedium),
+		FallbackExtension:           file.getEnv("FALLBACK_EXTENSION", constants.ExtFLAC),
+		SkipDuplicateISRC:           file.getEnvBool("SKIP_DUPLICATE_ISRC", false),
+		MinTrackDurationSecs:        file.getEnvInt("MIN_TRACK_DURATION_SECS", 0),
+		UpgradeQualityOnSync:        file.getEnvBool("UPGRADE_QUALITY_ON_SYNC", false),
+		PlaylistAbsolutePaths:       file.getEnvBool("PLAYLIST_ABSOLUTE_PATHS", false),
+		PlaylistFormat:              file.getEnv("PLAYLIST_FORMAT", constants.PlaylistFormatM3U),
+		DiscographyReleaseTypes:     file.getEnv("DISCOGRAPHY_RELEASE_TYPES", ""),
+		CompilationDetection:        file.getEnvBool("COMPILATION_DETECTION", false),
+		CompilationMinArtists:       file.getEnvInt("COMPILATION_MIN_ARTISTS", constants.DefaultCompilationArtists),
+		VariousArtistsName:          file.getEnv("VARIOUS_ARTISTS_NAME", constants.DefaultVariousArtists),
+		ShutdownDrainTimeout:        file.getEnvDuration("SHUTDOWN_DRAIN_TIMEOUT", constants.DefaultShutdownDrain),
+		EmbedExtraPictures:          file.getEnvBool("EMBED_EXTRA_PICTURES", false),
+		OnExistingFile:              file.getEnv("ON_EXISTING_FILE", constants.ExistingFileVerifyHash),
+		CreditsTagging:              file.getEnvBool("CREDITS_TAGGING", false),
+		EmbedCoverArt:               file.getEnvBool("EMBED_COVER_ART", true),
+		OriginalDateTagging:         file.getEnvBool("ORIGINAL_DATE_TAGGING", true),
+		GaplessTags:                 file.getEnvBool("GAPLESS_TAGS", false),
+		ID3Version:                  file.getEnv("ID3_VERSION", "2.4"),
+		PreferCleanVersions:         file.getEnvBool("PREFER_CLEAN_VERSIONS", false),
+		HTTPReadTimeout:             file.getEnvDuration("HTTP_READ_TIMEOUT", constants.DefaultHTTPReadTimeout),
+		HTTPWriteTimeout:            file.getEnvDuration("HTTP_WRITE_TIMEOUT", constants.DefaultHTTPWriteTimeout),
+		HTTPIdleTimeout:             file.getEnvDuration("HTTP_IDLE_TIMEOUT", constants.DefaultHTTPIdleTimeout),
+		ProviderTimeout:             file.getEnvDuration("PROVIDER_TIMEOUT", constants.DefaultProviderTimeout),
+		StreamTimeout:               file.getEnvDuration("STREAM_TIMEOUT", 0),
+		StreamIdleTimeout:           file.getEnvDuration("STREAM_IDLE_TIMEOUT", constants.DefaultStreamIdleTimeout),
+		DownloadWindowStart:         file.getEnv("DOWNLOAD_WINDOW_START", ""),
+		DownloadWindowEnd:           file.getEnv("DOWNLOAD_WINDOW_END", ""),
 	}
+	_ = cfg.SetDownloadsDirMap(cfg.DownloadsDirMap)
+	if len(file.errs) > 0 {
+		cfg.configFileErr = errors.Join(file.errs...)
+	}
+	return cfg
 }
 
 // Validate validates the configuration and returns detailed errors.
 func (c *Config) Validate() error {
 	var errors []string
 
+	if c.configFileErr != nil {
+		errors = append(errors, fmt.Sprintf("CONFIG_FILE: %v", c.configFileErr))
+	}
+
 	// Validate Port
 	if c.Port == "" {
 		errors = append(errors, "PORT cannot be empty")
@@ -97,16 +243,21 @@ func (c *Config) Validate() error {
 		errors = append(errors, "DOWNLOADS_DIR cannot be empty")
 	}
 
-	// Validate Quality
-	validQualities := map[string]bool{
-		constants.QualityLossless:      true,
-		constants.QualityHiResLossless: true,
-		constants.QualityHigh:          true,
-		constants.QualityLow:           true,
+	// Validate DownloadsDirMap (unset downloads everything to DOWNLOADS_DIR)
+	if c.DownloadsDirMap != "" {
+		if _, err := ParseDownloadsDirMap(c.DownloadsDirMap); err != nil {
+			errors = append(errors, fmt.Sprintf("DOWNLOADS_DIR_MAP %v", err))
+		}
 	}
-	if !validQualities[c.Quality] {
-		errors = append(errors, fmt.Sprintf("QUALITY must be one of: %s, %s, %s, %s, got: %s",
-			constants.QualityLossless, constants.QualityHiResLossless, constants.QualityHigh, constants.QualityLow, c.Quality))
+
+	// Validate DownloadWindowStart and DownloadWindowEnd (unset starts jobs at any time)
+	if _, err := ParseDownloadWindow(c.DownloadWindowStart, c.DownloadWindowEnd); err != nil {
+		errors = append(errors, fmt.Sprintf("DOWNLOAD_WINDOW_START/DOWNLOAD_WINDOW_END %v", err))
+	}
+
+	// Validate Quality (a single tier or a comma-separated preference list)
+	if _, err := ParseQualities(c.Quality); err != nil {
+		errors = append(errors, fmt.Sprintf("QUALITY %v", err))
 	}
 
 	// Validate LogLevel
@@ -145,6 +296,14 @@ func (c *Config) Validate() error {
 		}
 	}
 
+	// Validate MultiDiscLayout (unset means flat)
+	switch c.MultiDiscLayout {
+	case "", constants.MultiDiscLayoutFlat, constants.MultiDiscLayoutSubfolders:
+	default:
+		errors = append(errors, fmt.Sprintf("MULTI_DISC_LAYOUT must be one of: %s, %s, got: %s",
+			constants.MultiDiscLayoutFlat, constants.MultiDiscLayoutSubfolders, c.MultiDiscLayout))
+	}
+
 	// Validate CacheTTL
 	if c.CacheTTL <= 0 {
 		errors = append(errors, "CACHE_TTL must be greater than 0")
@@ -155,6 +314,16 @@ func (c *Config) Validate() error {
 		errors = append(errors, "MUSICBRAINZ_CACHE_TTL must be greater than 0")
 	}
 
+	// Validate MusicBrainzRateLimit (0 disables throttling, e.g. for a local mirror)
+	if c.MusicBrainzRateLimit < 0 {
+		errors = append(errors, "MUSICBRAINZ_RATE_LIMIT cannot be negative")
+	}
+
+	// Validate MusicBrainzUserAgent (MusicBrainz rejects anonymous clients; unset uses the default)
+	if c.MusicBrainzUserAgent != "" && strings.TrimSpace(c.MusicBrainzUserAgent) == "" {
+		errors = append(errors, "MUSICBRAINZ_USER_AGENT cannot be blank")
+	}
+
 	// Validate RateLimitRequests
 	if c.RateLimitRequests <= 0 {
 		errors = append(errors, "RATE_LIMIT_REQUESTS must be greater than 0")
@@ -170,6 +339,159 @@ func (c *Config) Validate() error {
 		errors = append(errors, "RATE_LIMIT_BURST must be greater than 0")
 	}
 
+	// Validate MetadataConcurrency (0 falls back to the default)
+	if c.MetadataConcurrency < 0 {
+		errors = append(errors, fmt.Sprintf("METADATA_CONCURRENCY cannot be negative, got: %d", c.MetadataConcurrency))
+	}
+
+	// Validate MissingFileSweepInterval (0 disables the sweep)
+	if c.MissingFileSweepInterval < 0 {
+		errors = append(errors, "MISSING_FILE_SWEEP_INTERVAL cannot be negative")
+	}
+
+	// Validate TagMergeStrategy (unset means overwrite)
+	if c.TagMergeStrategy != "" && c.TagMergeStrategy != "overwrite" && c.TagMergeStrategy != "fill-missing" {
+		errors = append(errors, fmt.Sprintf("TAG_MERGE_STRATEGY must be one of: overwrite, fill-missing, got: %s", c.TagMergeStrategy))
+	}
+
+	// Validate ArtistTagMode (unset means all)
+	if c.ArtistTagMode != "" && c.ArtistTagMode != "all" && c.ArtistTagMode != "primary" && c.ArtistTagMode != "primary-plus-features" {
+		errors = append(errors, fmt.Sprintf("ARTIST_TAG_MODE must be one of: all, primary, primary-plus-features, got: %s", c.ArtistTagMode))
+	}
+
+	// Validate ID3Version (unset means 2.4)
+	if c.ID3Version != "" && c.ID3Version != "2.3" && c.ID3Version != "2.4" {
+		errors = append(errors, fmt.Sprintf("ID3_VERSION must be one of: 2.3, 2.4, got: %s", c.ID3Version))
+	}
+
+	// Validate FeaturedArtists (unset means keep)
+	if c.FeaturedArtists != "" && c.FeaturedArtists != "keep" && c.FeaturedArtists != "artists" && c.FeaturedArtists != "title" {
+		errors = append(errors, fmt.Sprintf("FEATURED_ARTISTS must be one of: keep, artists, title, got: %s", c.FeaturedArtists))
+	}
+
+	// Validate CoverArtFilename (unset means cover.jpg; must be a bare file name)
+	if strings.ContainsAny(c.CoverArtFilename, `/\`) || c.CoverArtFilename == "." || c.CoverArtFilename == ".." {
+		errors = append(errors, fmt.Sprintf("COVER_ART_FILENAME must be a file name without directories, got: %s", c.CoverArtFilename))
+	}
+
+	// Validate CoverArtJPEGQuality (0 falls back to the default)
+	if c.CoverArtJPEGQuality < 0 || c.CoverArtJPEGQuality > 100 {
+		errors = append(errors, fmt.Sprintf("COVER_ART_JPEG_QUALITY must be between 1 and 100, got: %d", c.CoverArtJPEGQuality))
+	}
+
+	// Validate CoverArtSize (unset means 640x640)
+	if c.CoverArtSize != "" && !slices.Contains(constants.CoverArtSizes, c.CoverArtSize) {
+		errors = append(errors, fmt.Sprintf("COVER_ART_SIZE must be one of: %s, got: %s", strings.Join(constants.CoverArtSizes, ", "), c.CoverArtSize))
+	}
+
+	// Validate FallbackExtension (unset means .flac)
+	if c.FallbackExtension != "" && !slices.Contains(constants.AudioExtensions, c.FallbackExtension) {
+		errors = append(errors, fmt.Sprintf("FALLBACK_EXTENSION must be one of: %s, got: %s", strings.Join(constants.AudioExtensions, ", "), c.FallbackExtension))
+	}
+
+	// Validate PlaylistFormat (unset means m3u)
+	switch c.PlaylistFormat {
+	case "", constants.PlaylistFormatM3U, constants.PlaylistFormatM3U8, constants.PlaylistFormatPLS:
+	default:
+		errors = append(errors, fmt.Sprintf("PLAYLIST_FORMAT must be one of: %s, %s, %s, got: %s",
+			constants.PlaylistFormatM3U, constants.PlaylistFormatM3U8, constants.PlaylistFormatPLS, c.PlaylistFormat))
+	}
+
+	// Validate OnExistingFile (unset means verify-hash)
+	switch c.OnExistingFile {
+	case "", constants.ExistingFileVerifyHash, constants.ExistingFileSkip, constants.ExistingFileOverwrite:
+	default:
+		errors = append(errors, fmt.Sprintf("ON_EXISTING_FILE must be one of: %s, %s, %s, got: %s",
+			constants.ExistingFileVerifyHash, constants.ExistingFileSkip, constants.ExistingFileOverwrite, c.OnExistingFile))
+	}
+
+	// Validate MusicBrainzSkipProviders (unset enriches downloads from every provider)
+	if c.MusicBrainzSkipProviders != "" {
+		if _, err := ParseProviderTypes(c.MusicBrainzSkipProviders); err != nil {
+			errors = append(errors, fmt.Sprintf("MUSICBRAINZ_SKIP_PROVIDERS %v", err))
+		}
+	}
+
+	// Validate the Navidrome server (only needed by DedupeAgainstNavidrome)
+	if c.DedupeAgainstNavidrome {
+		if !strings.HasPrefix(c.NavidromeURL, "http://") && !strings.HasPrefix(c.NavidromeURL, "https://") {
+			errors = append(errors, fmt.Sprintf("NAVIDROME_URL must be an http(s) URL when DEDUPE_AGAINST_NAVIDROME is on, got: %q", c.NavidromeURL))
+		}
+		if c.NavidromeUsername == "" {
+			errors = append(errors, "NAVIDROME_USERNAME is required when DEDUPE_AGAINST_NAVIDROME is on")
+		}
+	}
+
+	// Validate DiscographyReleaseTypes (unset downloads every release)
+	if c.DiscographyReleaseTypes != "" {
+		if _, err := ParseReleaseTypes(c.DiscographyReleaseTypes); err != nil {
+			errors = append(errors, fmt.Sprintf("DISCOGRAPHY_RELEASE_TYPES %v", err))
+		}
+	}
+
+	// Validate CompilationMinArtists (0 falls back to the default)
+	if c.CompilationMinArtists < 0 || c.CompilationMinArtists == 1 {
+		errors = append(errors, fmt.Sprintf("COMPILATION_MIN_ARTISTS must be at least 2, got: %d", c.CompilationMinArtists))
+	}
+
+	// Validate MinTrackDurationSecs (0 keeps every track)
+	if c.MinTrackDurationSecs < 0 {
+		errors = append(errors, fmt.Sprintf("MIN_TRACK_DURATION_SECS cannot be negative, got: %d", c.MinTrackDurationSecs))
+	}
+
+	// Validate ShutdownDrainTimeout (0 interrupts running jobs at once)
+	if c.ShutdownDrainTimeout < 0 {
+		errors = append(errors, fmt.Sprintf("SHUTDOWN_DRAIN_TIMEOUT cannot be negative, got: %v", c.ShutdownDrainTimeout))
+	}
+
+	// Validate HTTP server timeouts (0 means no timeout)
+	for _, t := range []struct {
+		name  string
+		value time.Duration
+	}{
+		{"HTTP_READ_TIMEOUT", c.HTTPReadTimeout},
+		{"HTTP_WRITE_TIMEOUT", c.HTTPWriteTimeout},
+		{"HTTP_IDLE_TIMEOUT", c.HTTPIdleTimeout},
+	} {
+		if t.value < 0 {
+			errors = append(errors, fmt.Sprintf("%s cannot be negative, got: %v", t.name, t.value))
+		}
+	}
+
+	// Validate ProviderTimeout (0 falls back to the default)
+	if c.ProviderTimeout < 0 {
+		errors = append(errors, fmt.Sprintf("PROVIDER_TIMEOUT cannot be negative, got: %v", c.ProviderTimeout))
+	}
+
+	// Validate StreamTimeout (0 leaves downloads to the job's cancellation)
+	if c.StreamTimeout < 0 {
+		errors = append(errors, fmt.Sprintf("STREAM_TIMEOUT cannot be negative, got: %v", c.StreamTimeout))
+	}
+
+	// Validate StreamIdleTimeout (0 turns off stall detection)
+	if c.StreamIdleTimeout < 0 {
+		errors = append(errors, fmt.Sprintf("STREAM_IDLE_TIMEOUT cannot be negative, got: %v", c.StreamIdleTimeout))
+	}
+
+	// Validate SessionTTL (0 falls back to the default)
+	if c.SessionTTL < 0 {
+		errors = append(errors, fmt.Sprintf("SESSION_TTL cannot be negative, got: %v", c.SessionTTL))
+	}
+
+	// Validate TrustedProxies (unset trusts forwarding headers from every client)
+	if c.TrustedProxies != "" {
+		if _, err := ParseCIDRs(c.TrustedProxies); err != nil {
+			errors = append(errors, fmt.Sprintf("TRUSTED_PROXIES %v", err))
+		}
+	}
+
+	// Validate RateLimitExemptCIDRs (unset rate-limits every client)
+	if c.RateLimitExemptCIDRs != "" {
+		if _, err := ParseCIDRs(c.RateLimitExemptCIDRs); err != nil {
+			errors = append(errors, fmt.Sprintf("RATE_LIMIT_EXEMPT_CIDRS %v", err))
+		}
+	}
+
 	if len(errors) > 0 {
 		return fmt.Errorf("configuration validation failed:\n  - %s", strings.Join(errors, "\n  - "))
 	}
@@ -177,6 +499,199 @@ func (c *Config) Validate() error {
 	return nil
 }
 
+// qualityTiers are the download qualities every provider understands, best first.
+var qualityTiers = []string{
+	constants.QualityHiResLossless,
+	constants.QualityLossless,
+	constants.QualityHigh,
+	constants.QualityLow,
+}
+
+// QualityRank orders download qualities from LOW (1) to HI_RES_LOSSLESS (4). Unknown
+// qualities rank 0.
+func QualityRank(q string) int {
+	if i := slices.Index(qualityTiers, q); i >= 0 {
+		return len(qualityTiers) - i
+	}
+	return 0
+}
+
+// ParseQualities splits a quality preference list such as "LOSSLESS,HIGH,LOW" into its
+// tiers. Downloads try each tier in order until the provider serves a stream.
+func ParseQualities(s string) ([]string, error) {
+	var tiers []string
+	seen := make(map[string]bool)
+	for _, part := range strings.Split(s, ",") {
+		tier := strings.TrimSpace(part)
+		if !slices.Contains(qualityTiers, tier) {
+			return nil, fmt.Errorf("must be one or more of: %s, got: %q", strings.Join(qualityTiers, ", "), tier)
+		}
+		if seen[tier] {
+			return nil, fmt.Errorf("lists %s more than once", tier)
+		}
+		seen[tier] = true
+		tiers = append(tiers, tier)
+	}
+	return tiers, nil
+}
+
+// providerTypes are the catalog providers tracks can be downloaded from.
+var providerTypes = []string{"hifi", "qobuz"}
+
+// ParseProviderTypes splits a comma-separated list of provider types such as "hifi,qobuz"
+// into lowercase types.
+func ParseProviderTypes(s string) ([]string, error) {
+	var types []string
+	for _, part := range strings.Split(s, ",") {
+		t := strings.ToLower(strings.TrimSpace(part))
+		if !slices.Contains(providerTypes, t) {
+			return nil, fmt.Errorf("must be one or more of: %s, got: %q", strings.Join(providerTypes, ", "), t)
+		}
+		if !slices.Contains(types, t) {
+			types = append(types, t)
+		}
+	}
+	return types, nil
+}
+
+// ParseDownloadsDirMap splits a mapping such as "HI_RES_LOSSLESS=/music/hires,qobuz=/music/qobuz"
+// into base download directories keyed by quality tier or lowercase provider type.
+func ParseDownloadsDirMap(s string) (map[string]string, error) {
+	dirs := make(map[string]string)
+	for _, part := range strings.Split(s, ",") {
+		key, dir, ok := strings.Cut(part, "=")
+		key, dir = strings.TrimSpace(key), strings.TrimSpace(dir)
+		if !ok || dir == "" {
+			return nil, fmt.Errorf("entries must look like KEY=/path, got: %q", strings.TrimSpace(part))
+		}
+		if !slices.Contains(qualityTiers, key) {
+			key = strings.ToLower(key)
+			if !slices.Contains(providerTypes, key) {
+				return nil, fmt.Errorf("keys must be a quality (%s) or a provider (%s), got: %q",
+					strings.Join(qualityTiers, ", "), strings.Join(providerTypes, ", "), key)
+			}
+		}
+		if _, dup := dirs[key]; dup {
+			return nil, fmt.Errorf("maps %s more than once", key)
+		}
+		dirs[key] = dir
+	}
+	return dirs, nil
+}
+
+// SetDownloadsDirMap sets and parses DOWNLOADS_DIR_MAP; Load calls it, and a Config built
+// another way must too for the map to apply.
+func (c *Config) SetDownloadsDirMap(s string) error {
+	if s == "" {
+		c.DownloadsDirMap, c.downloadsDirs = "", nil
+		return nil
+	}
+	dirs, err := ParseDownloadsDirMap(s)
+	if err != nil {
+		return err
+	}
+	c.DownloadsDirMap, c.downloadsDirs = s, dirs
+	return nil
+}
+
+// DownloadsDirFor returns the base directory for a track from provider at quality: the
+// DOWNLOADS_DIR_MAP entry for the quality, else the one for the provider, else DOWNLOADS_DIR.
+func (c *Config) DownloadsDirFor(provider, quality string) string {
+	if dir, ok := c.downloadsDirs[quality]; ok {
+		return dir
+	}
+	if dir, ok := c.downloadsDirs[strings.ToLower(provider)]; ok {
+		return dir
+	}
+	return c.DownloadsDir
+}
+
+// DownloadsDirs returns DOWNLOADS_DIR followed by the other directories DOWNLOADS_DIR_MAP
+// names, without repeats.
+func (c *Config) DownloadsDirs() []string {
+	roots := []string{c.DownloadsDir}
+	mapped := make([]string, 0, len(c.downloadsDirs))
+	for _, dir := range c.downloadsDirs {
+		mapped = append(mapped, dir)
+	}
+	slices.Sort(mapped)
+	for _, dir := range mapped {
+		if !slices.Contains(roots, dir) {
+			roots = append(roots, dir)
+		}
+	}
+	return roots
+}
+
+// DownloadsRootOf returns the innermost configured download directory holding path, or
+// DOWNLOADS_DIR when none does.
+func (c *Config) DownloadsRootOf(path string) string {
+	best := c.DownloadsDir
+	found := false
+	for _, root := range c.DownloadsDirs() {
+		rel, err := filepath.Rel(root, path)
+		if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+			continue
+		}
+		if !found || len(root) > len(best) {
+			best, found = root, true
+		}
+	}
+	return best
+}
+
+// MusicBrainzEnrichmentFor reports whether downloads from a provider are enriched from
+// MusicBrainz: ENABLE_MUSICBRAINZ_ENRICHMENT is on and the provider isn't listed in
+// MUSICBRAINZ_SKIP_PROVIDERS. On-demand MusicBrainz syncs run either way.
+func (c *Config) MusicBrainzEnrichmentFor(provider string) bool {
+	if !c.EnableMusicBrainzEnrichment {
+		return false
+	}
+	if c.MusicBrainzSkipProviders == "" {
+		return true
+	}
+	skip, err := ParseProviderTypes(c.MusicBrainzSkipProviders)
+	return err != nil || !slices.Contains(skip, strings.ToLower(provider))
+}
+
+// releaseTypes are the album types providers report for an artist's releases.
+var releaseTypes = []string{"album", "ep", "single", "compilation"}
+
+// ParseReleaseTypes splits a comma-separated list of release types such as "album,ep"
+// into lowercase types. Discography downloads only enqueue releases of these types.
+func ParseReleaseTypes(s string) ([]string, error) {
+	var types []string
+	for _, part := range strings.Split(s, ",") {
+		t := strings.ToLower(strings.TrimSpace(part))
+		if !slices.Contains(releaseTypes, t) {
+			return nil, fmt.Errorf("must be one or more of: %s, got: %q", strings.Join(releaseTypes, ", "), t)
+		}
+		if !slices.Contains(types, t) {
+			types = append(types, t)
+		}
+	}
+	return types, nil
+}
+
+// ParseCIDRs splits a comma-separated list of CIDR ranges such as "10.0.0.0/8,::1". A bare
+// address is a range of that address alone.
+func ParseCIDRs(s string) ([]netip.Prefix, error) {
+	var prefixes []netip.Prefix
+	for _, part := range strings.Split(s, ",") {
+		part = strings.TrimSpace(part)
+		if addr, err := netip.ParseAddr(part); err == nil {
+			prefixes = append(prefixes, netip.PrefixFrom(addr, addr.BitLen()))
+			continue
+		}
+		prefix, err := netip.ParsePrefix(part)
+		if err != nil {
+			return nil, fmt.Errorf("must be a list of IP addresses or CIDR ranges, got: %q", part)
+		}
+		prefixes = append(prefixes, prefix.Masked())
+	}
+	return prefixes, nil
+}
+
 // getEnv retrieves an environment variable with a fallback default
 func getEnv(key, fallback string) string {
 	if value, ok := os.LookupEnv(key); ok {
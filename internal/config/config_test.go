@@ -1,8 +1,10 @@
 package config
 
 import (
+	"maps"
 	"os"
 	"path/filepath"
+	"slices"
 	"testing"
 	"time"
 
@@ -25,6 +27,26 @@ func TestLoad(t *testing.T) {
 		t.Errorf("Expected Quality to be %s, got %s", constants.DefaultQuality, cfg.Quality)
 	}
 
+	if cfg.MusicBrainzRateLimit != constants.DefaultMusicBrainzRateLimit {
+		t.Errorf("Expected MusicBrainzRateLimit to be %v, got %v", constants.DefaultMusicBrainzRateLimit, cfg.MusicBrainzRateLimit)
+	}
+
+	if cfg.MusicBrainzUserAgent != constants.DefaultMusicBrainzUserAgent {
+		t.Errorf("Expected MusicBrainzUserAgent to be %s, got %s", constants.DefaultMusicBrainzUserAgent, cfg.MusicBrainzUserAgent)
+	}
+
+	if !cfg.PreferOriginalReleaseDate {
+		t.Error("Expected PreferOriginalReleaseDate to default to true")
+	}
+
+	if cfg.MetricsEnabled {
+		t.Error("Expected MetricsEnabled to default to false")
+	}
+
+	if cfg.CompilationDetection {
+		t.Error("Expected CompilationDetection to default to false")
+	}
+
 	// Check DownloadsDir is not empty (depends on user's home dir)
 	if cfg.DownloadsDir == "" {
 		t.Error("Expected DownloadsDir to not be empty")
@@ -69,6 +91,109 @@ func TestLoadWithEnvVars(t *testing.T) {
 	}
 }
 
+func TestLoadFromFile(t *testing.T) {
+	tests := []struct {
+		name     string
+		fileName string
+		content  string
+	}{
+		{
+			name:     "yaml",
+			fileName: "config.yaml",
+			content: `# navidrums settings
+port: "9191"
+quality: HIGH
+subdir_template: "{{.Artist}}/{{.Album}}/{{.Title}}"
+cache_ttl: 2h
+save_folder_art: true
+rate_limit_burst: 25
+`,
+		},
+		{
+			name:     "yml with env-style keys",
+			fileName: "config.yml",
+			content: `PORT: "9191"
+QUALITY: HIGH
+SUBDIR_TEMPLATE: "{{.Artist}}/{{.Album}}/{{.Title}}" # folder layout
+CACHE_TTL: 2h
+SAVE_FOLDER_ART: 1
+rate-limit-burst: 25
+`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), tt.fileName)
+			if err := os.WriteFile(path, []byte(tt.content), 0o600); err != nil {
+				t.Fatalf("WriteFile failed: %v", err)
+			}
+			t.Setenv("CONFIG_FILE", path)
+			// Environment variables take precedence over the file.
+			t.Setenv("QUALITY", "LOSSLESS")
+
+			cfg := Load()
+			if err := cfg.Validate(); err != nil {
+				t.Fatalf("Validate() error = %v", err)
+			}
+
+			if cfg.Port != "9191" {
+				t.Errorf("Port = %s, want 9191 from file", cfg.Port)
+			}
+			if cfg.Quality != "LOSSLESS" {
+				t.Errorf("Quality = %s, want LOSSLESS from env", cfg.Quality)
+			}
+			if cfg.SubdirTemplate != "{{.Artist}}/{{.Album}}/{{.Title}}" {
+				t.Errorf("SubdirTemplate = %s, want value from file", cfg.SubdirTemplate)
+			}
+			if cfg.CacheTTL != 2*time.Hour {
+				t.Errorf("CacheTTL = %v, want 2h from file", cfg.CacheTTL)
+			}
+			if !cfg.SaveFolderArt {
+				t.Error("SaveFolderArt = false, want true from file")
+			}
+			if cfg.RateLimitBurst != 25 {
+				t.Errorf("RateLimitBurst = %d, want 25 from file", cfg.RateLimitBurst)
+			}
+			if cfg.DBPath != constants.DefaultDBPath {
+				t.Errorf("DBPath = %s, want default %s", cfg.DBPath, constants.DefaultDBPath)
+			}
+		})
+	}
+}
+
+func TestLoadFromFile_Invalid(t *testing.T) {
+	tests := []struct {
+		name     string
+		fileName string
+		content  string
+	}{
+		{"missing file", "absent.yaml", ""},
+		{"unsupported extension", "config.json", `{"port": "9191"}`},
+		{"yaml nested value", "config.yaml", "quality:\n  default: HIGH\n"},
+		{"toml file", "config.toml", `port = "9191"`},
+		{"invalid integer", "config.yaml", "rate_limit_burst: lots\n"},
+		{"invalid duration", "config.yaml", "cache_ttl: 2 hours\n"},
+		{"invalid boolean", "config.yaml", "save_folder_art: maybe\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), tt.fileName)
+			if tt.content != "" {
+				if err := os.WriteFile(path, []byte(tt.content), 0o600); err != nil {
+					t.Fatalf("WriteFile failed: %v", err)
+				}
+			}
+			t.Setenv("CONFIG_FILE", path)
+
+			if err := Load().Validate(); err == nil {
+				t.Error("Validate() succeeded, want config file error")
+			}
+		})
+	}
+}
+
 func TestValidate(t *testing.T) {
 	tests := []struct {
 		name    string
@@ -166,6 +291,25 @@ func TestValidate(t *testing.T) {
 			},
 			wantErr: true,
 		},
+		{
+			name: "quality preference list",
+			config: Config{
+				Port:         "8080",
+				DBPath:       "test.db",
+				DownloadsDir: "/tmp/downloads",
+
+				Quality:             "LOSSLESS,HIGH,LOW",
+				LogLevel:            "info",
+				LogFormat:           "text",
+				SubdirTemplate:      "{{.AlbumArtist}}/{{.Album}}/{{.Title}}",
+				CacheTTL:            12 * time.Hour,
+				MusicBrainzCacheTTL: 7 * 24 * time.Hour,
+				RateLimitRequests:   60,
+				RateLimitWindow:     time.Minute,
+				RateLimitBurst:      10,
+			},
+			wantErr: false,
+		},
 		{
 			name: "invalid log level",
 			config: Config{
@@ -222,13 +366,730 @@ func TestValidate(t *testing.T) {
 			},
 			wantErr: true,
 		},
-	}
+		{
+			name: "blank musicbrainz user agent",
+			config: Config{
+				Port:         "8080",
+				DBPath:       "test.db",
+				DownloadsDir: "/tmp/downloads",
 
-	for _, tt := range tests {
-		t.Run(tt.name, func(t *testing.T) {
-			err := tt.config.Validate()
-			if (err != nil) != tt.wantErr {
-				t.Errorf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+				Quality:              "LOSSLESS",
+				LogLevel:             "info",
+				LogFormat:            "text",
+				SubdirTemplate:       "{{.AlbumArtist}}/{{.Album}}/{{.Title}}",
+				CacheTTL:             12 * time.Hour,
+				MusicBrainzCacheTTL:  7 * 24 * time.Hour,
+				RateLimitRequests:    60,
+				RateLimitWindow:      time.Minute,
+				RateLimitBurst:       10,
+				MusicBrainzUserAgent: "   ",
+			},
+			wantErr: true,
+		},
+		{
+			name: "negative musicbrainz rate limit",
+			config: Config{
+				Port:         "8080",
+				DBPath:       "test.db",
+				DownloadsDir: "/tmp/downloads",
+
+				Quality:              "LOSSLESS",
+				LogLevel:             "info",
+				LogFormat:            "text",
+				SubdirTemplate:       "{{.AlbumArtist}}/{{.Album}}/{{.Title}}",
+				CacheTTL:             12 * time.Hour,
+				MusicBrainzCacheTTL:  7 * 24 * time.Hour,
+				RateLimitRequests:    60,
+				RateLimitWindow:      time.Minute,
+				RateLimitBurst:       10,
+				MusicBrainzRateLimit: -time.Second,
+			},
+			wantErr: true,
+		},
+		{
+			name: "negative missing file sweep interval",
+			config: Config{
+				Port:         "8080",
+				DBPath:       "test.db",
+				DownloadsDir: "/tmp/downloads",
+
+				Quality:                  "LOSSLESS",
+				LogLevel:                 "info",
+				LogFormat:                "text",
+				SubdirTemplate:           "{{.AlbumArtist}}/{{.Album}}/{{.Title}}",
+				CacheTTL:                 12 * time.Hour,
+				MusicBrainzCacheTTL:      7 * 24 * time.Hour,
+				RateLimitRequests:        60,
+				RateLimitWindow:          time.Minute,
+				RateLimitBurst:           10,
+				MissingFileSweepInterval: -time.Minute,
+			},
+			wantErr: true,
+		},
+		{
+			name: "invalid tag merge strategy",
+			config: Config{
+				Port:         "8080",
+				DBPath:       "test.db",
+				DownloadsDir: "/tmp/downloads",
+
+				Quality:             "LOSSLESS",
+				LogLevel:            "info",
+				LogFormat:           "text",
+				SubdirTemplate:      "{{.AlbumArtist}}/{{.Album}}/{{.Title}}",
+				CacheTTL:            12 * time.Hour,
+				MusicBrainzCacheTTL: 7 * 24 * time.Hour,
+				RateLimitRequests:   60,
+				RateLimitWindow:     time.Minute,
+				RateLimitBurst:      10,
+				TagMergeStrategy:    "merge",
+			},
+			wantErr: true,
+		},
+		{
+			name: "invalid artist tag mode",
+			config: Config{
+				Port:                "8080",
+				DBPath:              "test.db",
+				DownloadsDir:        "/tmp/downloads",
+				Quality:             "LOSSLESS",
+				LogLevel:            "info",
+				LogFormat:           "text",
+				SubdirTemplate:      "{{.AlbumArtist}}/{{.Album}}/{{.Title}}",
+				CacheTTL:            12 * time.Hour,
+				MusicBrainzCacheTTL: 7 * 24 * time.Hour,
+				RateLimitRequests:   60,
+				RateLimitWindow:     time.Minute,
+				RateLimitBurst:      10,
+				ArtistTagMode:       "features",
+			},
+			wantErr: true,
+		},
+		{
+			name: "invalid featured artists mode",
+			config: Config{
+				Port:                "8080",
+				DBPath:              "test.db",
+				DownloadsDir:        "/tmp/downloads",
+				Quality:             "LOSSLESS",
+				LogLevel:            "info",
+				LogFormat:           "text",
+				SubdirTemplate:      "{{.AlbumArtist}}/{{.Album}}/{{.Title}}",
+				CacheTTL:            12 * time.Hour,
+				MusicBrainzCacheTTL: 7 * 24 * time.Hour,
+				RateLimitRequests:   60,
+				RateLimitWindow:     time.Minute,
+				RateLimitBurst:      10,
+				FeaturedArtists:     "both",
+			},
+			wantErr: true,
+		},
+		{
+			name: "negative minimum track duration",
+			config: Config{
+				Port:                 "8080",
+				DBPath:               "test.db",
+				DownloadsDir:         "/tmp/downloads",
+				Quality:              "LOSSLESS",
+				LogLevel:             "info",
+				LogFormat:            "text",
+				SubdirTemplate:       "{{.AlbumArtist}}/{{.Album}}/{{.Title}}",
+				CacheTTL:             12 * time.Hour,
+				MusicBrainzCacheTTL:  7 * 24 * time.Hour,
+				RateLimitRequests:    60,
+				RateLimitWindow:      time.Minute,
+				RateLimitBurst:       10,
+				MinTrackDurationSecs: -1,
+			},
+			wantErr: true,
+		},
+		{
+			name: "unknown MusicBrainz skip provider",
+			config: Config{
+				Port:                     "8080",
+				DBPath:                   "test.db",
+				DownloadsDir:             "/tmp/downloads",
+				Quality:                  "LOSSLESS",
+				LogLevel:                 "info",
+				LogFormat:                "text",
+				SubdirTemplate:           "{{.AlbumArtist}}/{{.Album}}/{{.Title}}",
+				CacheTTL:                 12 * time.Hour,
+				MusicBrainzCacheTTL:      7 * 24 * time.Hour,
+				RateLimitRequests:        60,
+				RateLimitWindow:          time.Minute,
+				RateLimitBurst:           10,
+				MusicBrainzSkipProviders: "hifi,tidal",
+			},
+			wantErr: true,
+		},
+		{
+			name: "navidrome dedupe without a server",
+			config: Config{
+				Port:                   "8080",
+				DBPath:                 "test.db",
+				DownloadsDir:           "/tmp/downloads",
+				Quality:                "LOSSLESS",
+				LogLevel:               "info",
+				LogFormat:              "text",
+				SubdirTemplate:         "{{.AlbumArtist}}/{{.Album}}/{{.Title}}",
+				CacheTTL:               12 * time.Hour,
+				MusicBrainzCacheTTL:    7 * 24 * time.Hour,
+				RateLimitRequests:      60,
+				RateLimitWindow:        time.Minute,
+				RateLimitBurst:         10,
+				DedupeAgainstNavidrome: true,
+				NavidromeUsername:      "admin",
+			},
+			wantErr: true,
+		},
+		{
+			name: "navidrome dedupe with a server",
+			config: Config{
+				Port:                   "8080",
+				DBPath:                 "test.db",
+				DownloadsDir:           "/tmp/downloads",
+				Quality:                "LOSSLESS",
+				LogLevel:               "info",
+				LogFormat:              "text",
+				SubdirTemplate:         "{{.AlbumArtist}}/{{.Album}}/{{.Title}}",
+				CacheTTL:               12 * time.Hour,
+				MusicBrainzCacheTTL:    7 * 24 * time.Hour,
+				RateLimitRequests:      60,
+				RateLimitWindow:        time.Minute,
+				RateLimitBurst:         10,
+				DedupeAgainstNavidrome: true,
+				NavidromeURL:           "http://navidrome:4533",
+				NavidromeUsername:      "admin",
+			},
+			wantErr: false,
+		},
+		{
+			name: "cover art filename with directory",
+			config: Config{
+				Port:         "8080",
+				DBPath:       "test.db",
+				DownloadsDir: "/tmp/downloads",
+
+				Quality:             "LOSSLESS",
+				LogLevel:            "info",
+				LogFormat:           "text",
+				SubdirTemplate:      "{{.AlbumArtist}}/{{.Album}}/{{.Title}}",
+				CacheTTL:            12 * time.Hour,
+				MusicBrainzCacheTTL: 7 * 24 * time.Hour,
+				RateLimitRequests:   60,
+				RateLimitWindow:     time.Minute,
+				RateLimitBurst:      10,
+				CoverArtFilename:    "art/cover.jpg",
+			},
+			wantErr: true,
+		},
+		{
+			name: "unsupported cover art size",
+			config: Config{
+				Port:         "8080",
+				DBPath:       "test.db",
+				DownloadsDir: "/tmp/downloads",
+
+				Quality:             "LOSSLESS",
+				LogLevel:            "info",
+				LogFormat:           "text",
+				SubdirTemplate:      "{{.AlbumArtist}}/{{.Album}}/{{.Title}}",
+				CacheTTL:            12 * time.Hour,
+				MusicBrainzCacheTTL: 7 * 24 * time.Hour,
+				RateLimitRequests:   60,
+				RateLimitWindow:     time.Minute,
+				RateLimitBurst:      10,
+				CoverArtSize:        "1000x1000",
+			},
+			wantErr: true,
+		},
+		{
+			name: "unsupported fallback extension",
+			config: Config{
+				Port:         "8080",
+				DBPath:       "test.db",
+				DownloadsDir: "/tmp/downloads",
+
+				Quality:             "LOSSLESS",
+				LogLevel:            "info",
+				LogFormat:           "text",
+				SubdirTemplate:      "{{.AlbumArtist}}/{{.Album}}/{{.Title}}",
+				CacheTTL:            12 * time.Hour,
+				MusicBrainzCacheTTL: 7 * 24 * time.Hour,
+				RateLimitRequests:   60,
+				RateLimitWindow:     time.Minute,
+				RateLimitBurst:      10,
+				FallbackExtension:   "flac",
+			},
+			wantErr: true,
+		},
+		{
+			name: "negative metadata concurrency",
+			config: Config{
+				Port:         "8080",
+				DBPath:       "test.db",
+				DownloadsDir: "/tmp/downloads",
+
+				Quality:             "LOSSLESS",
+				LogLevel:            "info",
+				LogFormat:           "text",
+				SubdirTemplate:      "{{.AlbumArtist}}/{{.Album}}/{{.Title}}",
+				CacheTTL:            12 * time.Hour,
+				MusicBrainzCacheTTL: 7 * 24 * time.Hour,
+				RateLimitRequests:   60,
+				RateLimitWindow:     time.Minute,
+				RateLimitBurst:      10,
+				MetadataConcurrency: -1,
+			},
+			wantErr: true,
+		},
+		{
+			name: "invalid playlist format",
+			config: Config{
+				Port:         "8080",
+				DBPath:       "test.db",
+				DownloadsDir: "/tmp/downloads",
+
+				Quality:             "LOSSLESS",
+				LogLevel:            "info",
+				LogFormat:           "text",
+				SubdirTemplate:      "{{.AlbumArtist}}/{{.Album}}/{{.Title}}",
+				CacheTTL:            12 * time.Hour,
+				MusicBrainzCacheTTL: 7 * 24 * time.Hour,
+				RateLimitRequests:   60,
+				RateLimitWindow:     time.Minute,
+				RateLimitBurst:      10,
+				PlaylistFormat:      "xspf",
+			},
+			wantErr: true,
+		},
+		{
+			name: "compilation min artists of one",
+			config: Config{
+				Port:         "8080",
+				DBPath:       "test.db",
+				DownloadsDir: "/tmp/downloads",
+
+				Quality:               "LOSSLESS",
+				LogLevel:              "info",
+				LogFormat:             "text",
+				SubdirTemplate:        "{{.AlbumArtist}}/{{.Album}}/{{.Title}}",
+				CacheTTL:              12 * time.Hour,
+				MusicBrainzCacheTTL:   7 * 24 * time.Hour,
+				RateLimitRequests:     60,
+				RateLimitWindow:       time.Minute,
+				RateLimitBurst:        10,
+				CompilationMinArtists: 1,
+			},
+			wantErr: true,
+		},
+		{
+			name: "cover art JPEG quality above 100",
+			config: Config{
+				Port:         "8080",
+				DBPath:       "test.db",
+				DownloadsDir: "/tmp/downloads",
+
+				Quality:             "LOSSLESS",
+				LogLevel:            "info",
+				LogFormat:           "text",
+				SubdirTemplate:      "{{.AlbumArtist}}/{{.Album}}/{{.Title}}",
+				CacheTTL:            12 * time.Hour,
+				MusicBrainzCacheTTL: 7 * 24 * time.Hour,
+				RateLimitRequests:   60,
+				RateLimitWindow:     time.Minute,
+				RateLimitBurst:      10,
+				CoverArtJPEGQuality: 101,
+			},
+			wantErr: true,
+		},
+		{
+			name: "negative HTTP write timeout",
+			config: Config{
+				Port:         "8080",
+				DBPath:       "test.db",
+				DownloadsDir: "/tmp/downloads",
+
+				Quality:             "LOSSLESS",
+				LogLevel:            "info",
+				LogFormat:           "text",
+				SubdirTemplate:      "{{.AlbumArtist}}/{{.Album}}/{{.Title}}",
+				CacheTTL:            12 * time.Hour,
+				MusicBrainzCacheTTL: 7 * 24 * time.Hour,
+				RateLimitRequests:   60,
+				RateLimitWindow:     time.Minute,
+				RateLimitBurst:      10,
+				HTTPWriteTimeout:    -time.Second,
+			},
+			wantErr: true,
+		},
+		{
+			name: "negative shutdown drain timeout",
+			config: Config{
+				Port:         "8080",
+				DBPath:       "test.db",
+				DownloadsDir: "/tmp/downloads",
+
+				Quality:              "LOSSLESS",
+				LogLevel:             "info",
+				LogFormat:            "text",
+				SubdirTemplate:       "{{.AlbumArtist}}/{{.Album}}/{{.Title}}",
+				CacheTTL:             12 * time.Hour,
+				MusicBrainzCacheTTL:  7 * 24 * time.Hour,
+				RateLimitRequests:    60,
+				RateLimitWindow:      time.Minute,
+				RateLimitBurst:       10,
+				ShutdownDrainTimeout: -time.Second,
+			},
+			wantErr: true,
+		},
+		{
+			name: "negative stream timeout",
+			config: Config{
+				Port:         "8080",
+				DBPath:       "test.db",
+				DownloadsDir: "/tmp/downloads",
+
+				Quality:             "LOSSLESS",
+				LogLevel:            "info",
+				LogFormat:           "text",
+				SubdirTemplate:      "{{.AlbumArtist}}/{{.Album}}/{{.Title}}",
+				CacheTTL:            12 * time.Hour,
+				MusicBrainzCacheTTL: 7 * 24 * time.Hour,
+				RateLimitRequests:   60,
+				RateLimitWindow:     time.Minute,
+				RateLimitBurst:      10,
+				StreamTimeout:       -time.Second,
+			},
+			wantErr: true,
+		},
+		{
+			name: "negative stream idle timeout",
+			config: Config{
+				Port:         "8080",
+				DBPath:       "test.db",
+				DownloadsDir: "/tmp/downloads",
+
+				Quality:             "LOSSLESS",
+				LogLevel:            "info",
+				LogFormat:           "text",
+				SubdirTemplate:      "{{.AlbumArtist}}/{{.Album}}/{{.Title}}",
+				CacheTTL:            12 * time.Hour,
+				MusicBrainzCacheTTL: 7 * 24 * time.Hour,
+				RateLimitRequests:   60,
+				RateLimitWindow:     time.Minute,
+				RateLimitBurst:      10,
+				StreamIdleTimeout:   -time.Second,
+			},
+			wantErr: true,
+		},
+		{
+			name: "download window without an end",
+			config: Config{
+				Port:         "8080",
+				DBPath:       "test.db",
+				DownloadsDir: "/tmp/downloads",
+
+				Quality:             "LOSSLESS",
+				LogLevel:            "info",
+				LogFormat:           "text",
+				SubdirTemplate:      "{{.AlbumArtist}}/{{.Album}}/{{.Title}}",
+				CacheTTL:            12 * time.Hour,
+				MusicBrainzCacheTTL: 7 * 24 * time.Hour,
+				RateLimitRequests:   60,
+				RateLimitWindow:     time.Minute,
+				RateLimitBurst:      10,
+				DownloadWindowStart: "22:00",
+			},
+			wantErr: true,
+		},
+		{
+			name: "invalid ID3 version",
+			config: Config{
+				Port:         "8080",
+				DBPath:       "test.db",
+				DownloadsDir: "/tmp/downloads",
+
+				Quality:             "LOSSLESS",
+				LogLevel:            "info",
+				LogFormat:           "text",
+				SubdirTemplate:      "{{.AlbumArtist}}/{{.Album}}/{{.Title}}",
+				CacheTTL:            12 * time.Hour,
+				MusicBrainzCacheTTL: 7 * 24 * time.Hour,
+				RateLimitRequests:   60,
+				RateLimitWindow:     time.Minute,
+				RateLimitBurst:      10,
+				ID3Version:          "2.2",
+			},
+			wantErr: true,
+		},
+		{
+			name: "invalid multi-disc layout",
+			config: Config{
+				Port:         "8080",
+				DBPath:       "test.db",
+				DownloadsDir: "/tmp/downloads",
+
+				Quality:             "LOSSLESS",
+				LogLevel:            "info",
+				LogFormat:           "text",
+				SubdirTemplate:      "{{.AlbumArtist}}/{{.Album}}/{{.Title}}",
+				CacheTTL:            12 * time.Hour,
+				MusicBrainzCacheTTL: 7 * 24 * time.Hour,
+				RateLimitRequests:   60,
+				RateLimitWindow:     time.Minute,
+				RateLimitBurst:      10,
+				MultiDiscLayout:     "nested",
+			},
+			wantErr: true,
+		},
+		{
+			name: "invalid on existing file mode",
+			config: Config{
+				Port:         "8080",
+				DBPath:       "test.db",
+				DownloadsDir: "/tmp/downloads",
+
+				Quality:             "LOSSLESS",
+				LogLevel:            "info",
+				LogFormat:           "text",
+				SubdirTemplate:      "{{.AlbumArtist}}/{{.Album}}/{{.Title}}",
+				CacheTTL:            12 * time.Hour,
+				MusicBrainzCacheTTL: 7 * 24 * time.Hour,
+				RateLimitRequests:   60,
+				RateLimitWindow:     time.Minute,
+				RateLimitBurst:      10,
+				OnExistingFile:      "keep",
+			},
+			wantErr: true,
+		},
+		{
+			name: "downloads dir map with unknown key",
+			config: Config{
+				Port:         "8080",
+				DBPath:       "test.db",
+				DownloadsDir: "/tmp/downloads",
+
+				Quality:             "LOSSLESS",
+				LogLevel:            "info",
+				LogFormat:           "text",
+				SubdirTemplate:      "{{.AlbumArtist}}/{{.Album}}/{{.Title}}",
+				CacheTTL:            12 * time.Hour,
+				MusicBrainzCacheTTL: 7 * 24 * time.Hour,
+				RateLimitRequests:   60,
+				RateLimitWindow:     time.Minute,
+				RateLimitBurst:      10,
+				DownloadsDirMap:     "spotify=/music/spotify",
+			},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.config.Validate()
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestParseQualities(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   string
+		want    []string
+		wantErr bool
+	}{
+		{"single tier", "LOSSLESS", []string{"LOSSLESS"}, false},
+		{"preference list", "LOSSLESS, HIGH,LOW", []string{"LOSSLESS", "HIGH", "LOW"}, false},
+		{"unknown tier", "LOSSLESS,MEDIUM", nil, true},
+		{"lowercase tier", "lossless", nil, true},
+		{"duplicate tier", "HIGH,HIGH", nil, true},
+		{"empty", "", nil, true},
+		{"trailing comma", "HIGH,", nil, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseQualities(tt.input)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseQualities(%q) error = %v, wantErr %v", tt.input, err, tt.wantErr)
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("ParseQualities(%q) = %v, want %v", tt.input, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseReleaseTypes(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   string
+		want    []string
+		wantErr bool
+	}{
+		{"single type", "album", []string{"album"}, false},
+		{"list, any case", "Album, EP,single", []string{"album", "ep", "single"}, false},
+		{"repeated type", "ep,EP", []string{"ep"}, false},
+		{"unknown type", "album,live", nil, true},
+		{"trailing comma", "album,", nil, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseReleaseTypes(tt.input)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseReleaseTypes(%q) error = %v, wantErr %v", tt.input, err, tt.wantErr)
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("ParseReleaseTypes(%q) = %v, want %v", tt.input, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMusicBrainzEnrichmentFor(t *testing.T) {
+	tests := []struct {
+		name     string
+		cfg      Config
+		provider string
+		want     bool
+	}{
+		{"enabled", Config{EnableMusicBrainzEnrichment: true}, "hifi", true},
+		{"disabled", Config{}, "hifi", false},
+		{"skipped provider", Config{EnableMusicBrainzEnrichment: true, MusicBrainzSkipProviders: "Hifi"}, "hifi", false},
+		{"other provider", Config{EnableMusicBrainzEnrichment: true, MusicBrainzSkipProviders: "hifi"}, "qobuz", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.cfg.MusicBrainzEnrichmentFor(tt.provider); got != tt.want {
+				t.Errorf("MusicBrainzEnrichmentFor(%q) = %v, want %v", tt.provider, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseDownloadsDirMap(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   string
+		want    map[string]string
+		wantErr bool
+	}{
+		{"quality and provider", "HI_RES_LOSSLESS=/music/hires, Qobuz=/music/qobuz",
+			map[string]string{"HI_RES_LOSSLESS": "/music/hires", "qobuz": "/music/qobuz"}, false},
+		{"unknown key", "MEDIUM=/music/medium", nil, true},
+		{"missing path", "HIGH=", nil, true},
+		{"missing separator", "/music/hires", nil, true},
+		{"repeated key", "hifi=/a,HIFI=/b", nil, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseDownloadsDirMap(tt.input)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseDownloadsDirMap(%q) error = %v, wantErr %v", tt.input, err, tt.wantErr)
+			}
+			if !maps.Equal(got, tt.want) {
+				t.Errorf("ParseDownloadsDirMap(%q) = %v, want %v", tt.input, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseDownloadWindow(t *testing.T) {
+	tests := []struct {
+		name    string
+		start   string
+		end     string
+		wantSet bool
+		wantErr bool
+	}{
+		{"unset", "", "", false, false},
+		{"daytime", "09:00", "17:30", true, false},
+		{"crosses midnight", "22:00", "06:00", true, false},
+		{"only a start", "22:00", "", false, true},
+		{"not a time", "10pm", "06:00", false, true},
+		{"out of range", "22:00", "24:00", false, true},
+		{"empty window", "06:00", "06:00", false, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseDownloadWindow(tt.start, tt.end)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseDownloadWindow(%q, %q) error = %v, wantErr %v", tt.start, tt.end, err, tt.wantErr)
+			}
+			if got.IsSet() != tt.wantSet {
+				t.Errorf("IsSet() = %v, want %v", got.IsSet(), tt.wantSet)
+			}
+		})
+	}
+}
+
+func TestDownloadsDirFor(t *testing.T) {
+	cfg := Config{DownloadsDir: "/music"}
+	if err := cfg.SetDownloadsDirMap("HI_RES_LOSSLESS=/music/hires,HIGH=/music/lossy,qobuz=/music/qobuz"); err != nil {
+		t.Fatalf("SetDownloadsDirMap failed: %v", err)
+	}
+	tests := []struct {
+		name     string
+		provider string
+		quality  string
+		want     string
+	}{
+		{"hi-res", "hifi", "HI_RES_LOSSLESS", "/music/hires"},
+		{"lossy", "hifi", "HIGH", "/music/lossy"},
+		{"quality wins over provider", "qobuz", "HI_RES_LOSSLESS", "/music/hires"},
+		{"provider", "qobuz", "LOSSLESS", "/music/qobuz"},
+		{"unmapped", "hifi", "LOSSLESS", "/music"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := cfg.DownloadsDirFor(tt.provider, tt.quality); got != tt.want {
+				t.Errorf("DownloadsDirFor(%q, %q) = %q, want %q", tt.provider, tt.quality, got, tt.want)
+			}
+		})
+	}
+
+	wantDirs := []string{"/music", "/music/hires", "/music/lossy", "/music/qobuz"}
+	if got := cfg.DownloadsDirs(); !slices.Equal(got, wantDirs) {
+		t.Errorf("DownloadsDirs() = %v, want %v", got, wantDirs)
+	}
+	if got := cfg.DownloadsRootOf("/music/lossy/Artist/Album/01 Song.m4a"); got != "/music/lossy" {
+		t.Errorf("DownloadsRootOf() = %q, want /music/lossy", got)
+	}
+}
+
+func TestParseCIDRs(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   string
+		want    []string
+		wantErr bool
+	}{
+		{"ranges", "10.0.0.0/8, 192.168.1.0/24", []string{"10.0.0.0/8", "192.168.1.0/24"}, false},
+		{"bare addresses", "127.0.0.1,::1", []string{"127.0.0.1/32", "::1/128"}, false},
+		{"host bits are masked", "172.16.5.4/12", []string{"172.16.0.0/12"}, false},
+		{"invalid range", "10.0.0.0/33", nil, true},
+		{"hostname", "localhost", nil, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			prefixes, err := ParseCIDRs(tt.input)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseCIDRs(%q) error = %v, wantErr %v", tt.input, err, tt.wantErr)
+			}
+			var got []string
+			for _, p := range prefixes {
+				got = append(got, p.String())
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("ParseCIDRs(%q) = %v, want %v", tt.input, got, tt.want)
 			}
 		})
 	}
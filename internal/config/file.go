@@ -0,0 +1,131 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// defaultConfigFiles are looked up in the working directory when CONFIG_FILE is unset.
+var defaultConfigFiles = []string{"config.yaml", "config.yml"}
+
+// fileValues holds the settings read from a config file, keyed by their environment
+// variable name. Env vars override them, and they override the built-in defaults.
+type fileValues struct {
+	values map[string]string
+	// errs collects values that don't parse as their setting's type.
+	errs []error
+}
+
+// configFilePath returns the config file to load: CONFIG_FILE when set, otherwise the
+// first default file present in the working directory, or "" when there is none.
+func configFilePath() string {
+	if path, ok := os.LookupEnv("CONFIG_FILE"); ok {
+		return path
+	}
+	for _, name := range defaultConfigFiles {
+		if info, err := os.Stat(name); err == nil && !info.IsDir() {
+			return name
+		}
+	}
+	return ""
+}
+
+// loadFile reads a flat YAML config file. Keys are the environment variable names in any
+// case, e.g. `downloads_dir: /music`.
+func loadFile(path string) (*fileValues, error) {
+	if path == "" {
+		return &fileValues{}, nil
+	}
+	data, err := os.ReadFile(path) //nolint:gosec // path comes from the operator
+	if err != nil {
+		return nil, fmt.Errorf("failed to read config file: %w", err)
+	}
+
+	if ext := strings.ToLower(filepath.Ext(path)); ext != ".yaml" && ext != ".yml" {
+		return nil, fmt.Errorf("unsupported config file type %q (use .yaml or .yml)", filepath.Ext(path))
+	}
+	raw, err := parseYAML(data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse config file %s: %w", path, err)
+	}
+
+	values := make(map[string]string, len(raw))
+	for k, v := range raw {
+		values[strings.ToUpper(strings.ReplaceAll(k, "-", "_"))] = v
+	}
+	return &fileValues{values: values}, nil
+}
+
+// invalid records a value that doesn't parse, so Validate fails instead of the setting
+// silently keeping its default.
+func (f *fileValues) invalid(key, value, want string) {
+	f.errs = append(f.errs, fmt.Errorf("%s: %q is not a valid %s", key, value, want))
+}
+
+func parseYAML(data []byte) (map[string]string, error) {
+	var doc map[string]yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return nil, err
+	}
+	out := make(map[string]string, len(doc))
+	for key, node := range doc {
+		if node.Kind != yaml.ScalarNode {
+			return nil, fmt.Errorf("%s: value must be a scalar", key)
+		}
+		if node.Tag == "!!null" {
+			continue
+		}
+		out[key] = node.Value
+	}
+	return out, nil
+}
+
+// getEnv returns the environment variable, else the file value, else fallback.
+func (f *fileValues) getEnv(key, fallback string) string {
+	if v, ok := f.values[key]; ok {
+		fallback = v
+	}
+	return getEnv(key, fallback)
+}
+
+func (f *fileValues) getEnvInt(key string, fallback int) int {
+	if v, ok := f.values[key]; ok {
+		if n, err := strconv.Atoi(v); err == nil {
+			fallback = n
+		} else {
+			f.invalid(key, v, "integer")
+		}
+	}
+	return getEnvInt(key, fallback)
+}
+
+func (f *fileValues) getEnvBool(key string, fallback bool) bool {
+	if v, ok := f.values[key]; ok {
+		switch strings.ToLower(v) {
+		case "true", "1":
+			fallback = true
+		case "false", "0":
+			fallback = false
+		default:
+			f.invalid(key, v, "boolean")
+		}
+	}
+	return getEnvBool(key, fallback)
+}
+
+func (f *fileValues) getEnvDuration(key string, fallback time.Duration) time.Duration {
+	if v, ok := f.values[key]; ok {
+		if d, err := time.ParseDuration(v); err == nil {
+			fallback = d
+		} else {
+			f.invalid(key, v, "duration")
+		}
+	}
+	return getEnvDuration(key, fallback)
+}
@@ -0,0 +1,87 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+// DownloadWindow is the time of day the worker starts new jobs in: from
+// DOWNLOAD_WINDOW_START up to DOWNLOAD_WINDOW_END, in local time. A window whose end comes
+// before its start crosses midnight. The zero value is always open.
+type DownloadWindow struct {
+	start, end time.Duration // since midnight
+	set        bool
+}
+
+// ParseDownloadWindow builds a DownloadWindow from two HH:MM times. Both empty means no
+// window; otherwise both must be set and differ.
+func ParseDownloadWindow(start, end string) (DownloadWindow, error) {
+	if start == "" && end == "" {
+		return DownloadWindow{}, nil
+	}
+	if start == "" || end == "" {
+		return DownloadWindow{}, errors.New("both ends must be set, got only one")
+	}
+	from, err := parseClock(start)
+	if err != nil {
+		return DownloadWindow{}, err
+	}
+	to, err := parseClock(end)
+	if err != nil {
+		return DownloadWindow{}, err
+	}
+	if from == to {
+		return DownloadWindow{}, fmt.Errorf("start and end must differ, got: %s", start)
+	}
+	return DownloadWindow{start: from, end: to, set: true}, nil
+}
+
+func parseClock(s string) (time.Duration, error) {
+	t, err := time.Parse("15:04", s)
+	if err != nil {
+		return 0, fmt.Errorf("times must be HH:MM, got: %q", s)
+	}
+	return time.Duration(t.Hour())*time.Hour + time.Duration(t.Minute())*time.Minute, nil
+}
+
+// IsSet reports whether the window limits when jobs start.
+func (w DownloadWindow) IsSet() bool { return w.set }
+
+// Contains reports whether jobs may start at t.
+func (w DownloadWindow) Contains(t time.Time) bool {
+	if !w.set {
+		return true
+	}
+	now := sinceMidnight(t)
+	if w.start < w.end {
+		return now >= w.start && now < w.end
+	}
+	return now >= w.start || now < w.end
+}
+
+// Next returns when the window next opens: t itself while it is open.
+func (w DownloadWindow) Next(t time.Time) time.Time {
+	if w.Contains(t) {
+		return t
+	}
+	opens := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location()).Add(w.start)
+	if opens.Before(t) {
+		opens = time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, t.Location()).Add(w.start)
+	}
+	return opens
+}
+
+func sinceMidnight(t time.Time) time.Duration {
+	return time.Duration(t.Hour())*time.Hour + time.Duration(t.Minute())*time.Minute + time.Duration(t.Second())*time.Second
+}
+
+// DownloadWindow returns the window DOWNLOAD_WINDOW_START and DOWNLOAD_WINDOW_END set, or
+// the zero value when they aren't set or are invalid.
+func (c *Config) DownloadWindow() DownloadWindow {
+	w, err := ParseDownloadWindow(c.DownloadWindowStart, c.DownloadWindowEnd)
+	if err != nil {
+		return DownloadWindow{}
+	}
+	return w
+}
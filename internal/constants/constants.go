@@ -5,19 +5,41 @@ import "time"
 
 // Application defaults
 const (
-	DefaultPort                = "8080"
-	DefaultDBPath              = "navidrums.db"
-	DefaultQuality             = "LOSSLESS"
-	DefaultConcurrency         = 2
-	DefaultPollInterval        = 2 * time.Second
-	DefaultHTTPTimeout         = 1 * time.Minute
-	ImageHTTPTimeout           = 30 * time.Second
-	DefaultRetryCount          = 8
-	DefaultRetryBase           = 1 * time.Second
-	DefaultUsername            = "navidrums"
-	DefaultSubdirTemplate      = "{{.AlbumArtist}}/{{.OriginalYear}} - {{.Album}}/{{.Disc}}-{{.Track}} {{.Title}}"
-	DefaultCacheTTL            = 12 * time.Hour
-	DefaultMusicBrainzCacheTTL = 7 * 24 * time.Hour
+	DefaultPort                 = "8080"
+	DefaultDBPath               = "navidrums.db"
+	DefaultQuality              = "LOSSLESS"
+	DefaultConcurrency          = 2
+	DefaultMetadataConcurrency  = 1 // MusicBrainz serializes requests, so more slots only wait
+	DefaultPollInterval         = 2 * time.Second
+	DefaultHTTPTimeout          = 1 * time.Minute
+	DefaultProviderTimeout      = 20 * time.Second // each provider API call; streams are bounded by STREAM_TIMEOUT
+	DefaultStreamIdleTimeout    = 1 * time.Minute  // a stream that sends nothing this long has stalled
+	ImageHTTPTimeout            = 30 * time.Second
+	MaxImageBytes               = 20 << 20 // larger downloads are refused rather than read into memory
+	MaxImageRedirects           = 5
+	DefaultRetryCount           = 8
+	DefaultRetryBase            = 1 * time.Second
+	StreamRetryAttempts         = 4 // stream fetches attempted while the CDN throttles (429/503)
+	StreamRetryBase             = 1 * time.Second
+	StreamRetryMaxWait          = 30 * time.Second
+	DefaultUsername             = "navidrums"
+	DefaultSubdirTemplate       = "{{.AlbumArtist}}/{{.OriginalYear}} - {{.Album}}/{{.Disc}}-{{.Track}} {{.Title}}"
+	DefaultCacheTTL             = 12 * time.Hour
+	DefaultMusicBrainzCacheTTL  = 7 * 24 * time.Hour
+	DefaultMusicBrainzRateLimit = 1250 * time.Millisecond
+	DefaultMusicBrainzUserAgent = "navidrums/1.0 (https://github.com/cesargomez89/navidrums)"
+	VerifyProgressInterval      = 25 // tracks between verify job progress updates
+	ReorganizeProgressInterval  = 25 // moves between reorganize job progress updates
+	DefaultMissingFileSweep     = 6 * time.Hour
+	DefaultSessionTTL           = 30 * 24 * time.Hour
+	DefaultShutdownDrain        = 30 * time.Second // running downloads get this long to finish on shutdown
+	DefaultHTTPReadTimeout      = 30 * time.Second
+	DefaultHTTPWriteTimeout     = 30 * time.Second
+	DefaultHTTPIdleTimeout      = 60 * time.Second
+	MissingFileSweepPageSize    = 500
+	DefaultCoverJPEGQuality     = 90 // used when cover art is re-encoded as JPEG
+	DefaultVariousArtists       = "Various Artists"
+	DefaultCompilationArtists   = 3 // distinct track artists that mark an album as a compilation
 )
 
 // Quality levels
@@ -28,6 +50,14 @@ const (
 	QualityLow           = "LOW"
 )
 
+// Typical bitrates (kbps) of each quality tier, used to estimate download sizes
+const (
+	EstimatedBitrateHiRes    = 2500
+	EstimatedBitrateLossless = 900
+	EstimatedBitrateHigh     = 320
+	EstimatedBitrateLow      = 96
+)
+
 // Image sizes
 const (
 	ImageSizeSmall  = "320x320"
@@ -35,6 +65,9 @@ const (
 	ImageSizeLarge  = "1280x1280"
 )
 
+// CoverArtSizes are the Tidal album cover sizes COVER_ART_SIZE accepts.
+var CoverArtSizes = []string{ImageSizeSmall, ImageSizeMedium, ImageSizeLarge}
+
 // Tidal CDN URLs
 const (
 	TidalImageExt = ".jpg"
@@ -47,7 +80,12 @@ const (
 	MimeTypeFLAC    = "audio/flac"
 	MimeTypeMP3     = "audio/mpeg"
 	MimeTypeMP4     = "audio/mp4"
+	MimeTypeWAV     = "audio/wav"
+	MimeTypeAIFF    = "audio/aiff"
 	MimeTypeJPEG    = "image/jpeg"
+	MimeTypePNG     = "image/png"
+	MimeTypeWebP    = "image/webp"
+	MimeTypeAVIF    = "image/avif"
 )
 
 // Database
@@ -64,13 +102,49 @@ const (
 	ExtMP4  = ".mp4"
 	ExtM4A  = ".m4a"
 	ExtM3U  = ".m3u"
+	ExtM3U8 = ".m3u8"
+	ExtPLS  = ".pls"
+	ExtLRC  = ".lrc"
+	ExtWAV  = ".wav"
+	ExtAIFF = ".aiff"
+	ExtAIF  = ".aif"
 	ExtJPG  = ".jpg"
+	ExtJPEG = ".jpeg"
+	ExtPNG  = ".png"
+	ExtWebP = ".webp"
+	ExtAVIF = ".avif"
+)
+
+// AudioExtensions are the extensions a downloaded track can be saved with.
+var AudioExtensions = []string{ExtFLAC, ExtM4A, ExtMP3, ExtWAV, ExtAIFF}
+
+// Playlist formats
+const (
+	PlaylistFormatM3U  = "m3u"
+	PlaylistFormatM3U8 = "m3u8"
+	PlaylistFormatPLS  = "pls"
+)
+
+// Existing file modes: what a download does with a file already on disk for the track
+const (
+	ExistingFileVerifyHash = "verify-hash" // keep it only if it matches the recorded hash
+	ExistingFileSkip       = "skip"        // always keep it
+	ExistingFileOverwrite  = "overwrite"   // always download again
+)
+
+// Multi-disc layouts: where the tracks of a release with more than one disc go
+const (
+	MultiDiscLayoutFlat       = "flat"       // in the album folder, as SUBDIR_TEMPLATE puts them
+	MultiDiscLayoutSubfolders = "subfolders" // in a "Disc N" folder of the album folder
 )
 
 // File Names
 const (
-	PlaylistsDir  = "playlists"
-	CoverFileName = "cover.jpg"
+	PlaylistsDir   = "playlists"
+	CoverFileName  = "cover.jpg"
+	FolderFileName = "folder.jpg"
+	ArtistFileName = "artist.jpg"
+	TrashDir       = ".trash"
 )
 
 // File Permissions
@@ -92,6 +166,10 @@ const (
 const (
 	MaxHistoryItems     = 20
 	MaxSearchResults    = 30
+	RecentDays          = 30 // days the "Recently added" view covers by default
+	ExportPageSize      = 500
+	MaxJobEvents        = 200                    // per job; older events are pruned
+	JobLogPollInterval  = 500 * time.Millisecond // how often a live job log checks for new events
 	ProgressUpdateFreq  = 2 * time.Second
 	ProgressUpdateBytes = 1024 * 1024 // 1MB
 )
@@ -83,6 +83,8 @@ func TestMimeTypes(t *testing.T) {
 		MimeTypeFLAC,
 		MimeTypeMP3,
 		MimeTypeMP4,
+		MimeTypeWAV,
+		MimeTypeAIFF,
 		MimeTypeJPEG,
 	}
 
@@ -100,7 +102,15 @@ func TestFileExtensions(t *testing.T) {
 		ExtMP4,
 		ExtM4A,
 		ExtM3U,
+		ExtM3U8,
+		ExtPLS,
+		ExtLRC,
+		ExtWAV,
+		ExtAIFF,
+		ExtAIF,
 		ExtJPG,
+		ExtJPEG,
+		ExtPNG,
 	}
 
 	for _, ext := range extensions {
@@ -2,6 +2,7 @@ package domain
 
 import (
 	"database/sql"
+	"strconv"
 	"strings"
 	"time"
 )
@@ -17,8 +18,25 @@ const (
 	JobTypeSyncFile        JobType = "sync_file"
 	JobTypeSyncMusicBrainz JobType = "sync_musicbrainz"
 	JobTypeSyncHiFi        JobType = "sync_hifi"
+	JobTypeSyncLyrics      JobType = "sync_lyrics"
+	JobTypeRetag           JobType = "retag"
+	JobTypeVerify          JobType = "verify"
+	JobTypeUpgrade         JobType = "upgrade"
+	JobTypeReorganize      JobType = "reorganize"
+	JobTypeImport          JobType = "import"
 )
 
+// VerifyLibrarySourceID is the source ID of a verify job covering the whole library.
+const VerifyLibrarySourceID = "library"
+
+// ReorganizeLibrarySourceID is the source ID of a reorganize job, which always covers
+// the whole library.
+const ReorganizeLibrarySourceID = "library"
+
+// ImportSourceProvider is the source provider of a track imported from a local file
+// instead of downloaded. The source ID of an import job is the file or folder's path.
+const ImportSourceProvider = "import"
+
 type JobStatus string
 
 const (
@@ -43,6 +61,35 @@ type Job struct {
 	Status      JobStatus      `json:"status" db:"status"`
 	SourceID    sql.NullString `json:"source_id" db:"source_id"`
 	Error       *string        `json:"error,omitempty" db:"error"`
+	// MinDuration overrides MIN_TRACK_DURATION_SECS for the tracks of a container job;
+	// NULL uses the configured minimum.
+	MinDuration sql.NullInt64 `json:"min_duration" db:"min_duration"`
+	// TrackIDs limits an album job to these comma-separated catalog track IDs; NULL
+	// downloads the whole album.
+	TrackIDs sql.NullString `json:"track_ids" db:"track_ids"`
+	// Quality is the quality an upgrade job downloads the track at; NULL uses the
+	// download quality preference.
+	Quality sql.NullString `json:"quality" db:"quality"`
+	// RequestID is the X-Request-ID of the HTTP request that queued the job, or of its
+	// parent's; NULL for jobs queued by the server itself.
+	RequestID sql.NullString `json:"request_id" db:"request_id"`
+}
+
+type JobEventLevel string
+
+const (
+	JobEventInfo  JobEventLevel = "info"
+	JobEventWarn  JobEventLevel = "warn"
+	JobEventError JobEventLevel = "error"
+)
+
+// JobEvent is a timestamped entry in a job's log, kept for diagnosing failed jobs.
+type JobEvent struct {
+	CreatedAt time.Time     `json:"created_at" db:"created_at"`
+	JobID     string        `json:"job_id" db:"job_id"`
+	Level     JobEventLevel `json:"level" db:"level"`
+	Message   string        `json:"message" db:"message"`
+	ID        int           `json:"id" db:"id"`
 }
 
 func (j *Job) GetParentJobID() string {
@@ -59,6 +106,14 @@ func (j *Job) GetSourceID() string {
 	return ""
 }
 
+// GetTrackIDs returns the album tracks the job is limited to, or nil for the whole album.
+func (j *Job) GetTrackIDs() []string {
+	if !j.TrackIDs.Valid || j.TrackIDs.String == "" {
+		return nil
+	}
+	return strings.Split(j.TrackIDs.String, ",")
+}
+
 func (j *Job) IsValidTransition(newStatus JobStatus) bool {
 	switch j.Status {
 	case JobStatusQueued:
@@ -100,68 +155,116 @@ const (
 	TrackStatusProcessing  TrackStatus = "processing"
 	TrackStatusCompleted   TrackStatus = "completed"
 	TrackStatusFailed      TrackStatus = "failed"
+	TrackStatusMissingFile TrackStatus = "missing_file"
+	TrackStatusUnavailable TrackStatus = "unavailable"
+	TrackStatusSkipped     TrackStatus = "skipped"
 )
 
 // Track represents a track with full metadata for downloading
 type Track struct { //nolint:govet // field ordering prioritizes readability over memory alignment
-	ID             int         `json:"id" db:"id"`
-	ProviderID     string      `json:"provider_id" db:"provider_id"`
-	Title          string      `json:"title" db:"title"`
-	Artist         string      `json:"artist" db:"artist"`
-	Artists        StringSlice `json:"artists" db:"artists"`
-	Album          string      `json:"album" db:"album"`
-	AlbumID        string      `json:"album_id,omitempty" db:"album_id"`
-	AlbumArtist    string      `json:"album_artist" db:"album_artist"`
-	AlbumArtists   StringSlice `json:"album_artists" db:"album_artists"`
-	PathArtist     string      `json:"path_artist" db:"path_artist"`
-	TrackNumber    int         `json:"track_number" db:"track_number"`
-	DiscNumber     int         `json:"disc_number" db:"disc_number"`
-	TotalTracks    int         `json:"total_tracks" db:"total_tracks"`
-	TotalDiscs     int         `json:"total_discs" db:"total_discs"`
-	Year           int         `json:"year" db:"year"`
-	Genre          string      `json:"genre" db:"genre"`
-	Mood           string      `json:"mood,omitempty" db:"mood"`
-	Language       string      `json:"language,omitempty" db:"language"`
-	Duration       int         `json:"duration" db:"duration"`
-	Label          string      `json:"label" db:"label"`
-	ISRC           string      `json:"isrc" db:"isrc"`
-	Copyright      string      `json:"copyright" db:"copyright"`
-	Composer       string      `json:"composer" db:"composer"`
-	Explicit       bool        `json:"explicit" db:"explicit"`
-	Compilation    bool        `json:"compilation" db:"compilation"`
-	AlbumArtURL    string      `json:"album_art_url" db:"album_art_url"`
-	Lyrics         string      `json:"lyrics" db:"lyrics"`
-	Subtitles      string      `json:"subtitles" db:"subtitles"`
-	BPM            int         `json:"bpm,omitempty" db:"bpm"`
-	Key            string      `json:"key,omitempty" db:"key_name"`
-	KeyScale       string      `json:"key_scale,omitempty" db:"key_scale"`
-	ReplayGain     float64     `json:"replay_gain,omitempty" db:"replay_gain"`
-	Peak           float64     `json:"peak,omitempty" db:"peak"`
-	Version        string      `json:"version,omitempty" db:"version"`
-	Description    string      `json:"description,omitempty" db:"description"`
-	URL            string      `json:"url,omitempty" db:"url"`
-	AudioQuality   string      `json:"audio_quality,omitempty" db:"audio_quality"`
-	AudioModes     string      `json:"audio_modes,omitempty" db:"audio_modes"`
-	ReleaseDate    string      `json:"release_date,omitempty" db:"release_date"`
-	Barcode        string      `json:"barcode,omitempty" db:"barcode"`
-	CatalogNumber  string      `json:"catalog_number,omitempty" db:"catalog_number"`
-	ReleaseType    string      `json:"release_type,omitempty" db:"release_type"`
-	ReleaseID      string      `json:"release_id,omitempty" db:"release_id"`
-	RecordingID    *string     `json:"recording_id,omitempty" db:"recording_id"`
-	Tags           StringSlice `json:"tags,omitempty" db:"tags"`
-	Status         TrackStatus `json:"status" db:"status"`
-	Error          string      `json:"error,omitempty" db:"error"`
-	ParentJobID    string      `json:"parent_job_id" db:"parent_job_id"`
-	FilePath       string      `json:"file_path" db:"file_path"`
-	FileExtension  string      `json:"file_extension" db:"file_extension"`
-	FileHash       string      `json:"file_hash,omitempty" db:"file_hash"`
-	ETag           string      `json:"etag,omitempty" db:"etag"`
-	CreatedAt      time.Time   `json:"created_at" db:"created_at"`
-	UpdatedAt      time.Time   `json:"updated_at" db:"updated_at"`
-	CompletedAt    *time.Time  `json:"completed_at,omitempty" db:"completed_at"`
-	LastVerifiedAt *time.Time  `json:"last_verified_at,omitempty" db:"last_verified_at"`
-	ArtistIDs      StringSlice `json:"artist_ids,omitempty" db:"artist_ids"`
-	AlbumArtistIDs StringSlice `json:"album_artist_ids,omitempty" db:"album_artist_ids"`
+	ID              int         `json:"id" db:"id"`
+	ProviderID      string      `json:"provider_id" db:"provider_id"`
+	SourceProvider  string      `json:"source_provider,omitempty" db:"source_provider"`
+	Title           string      `json:"title" db:"title"`
+	Artist          string      `json:"artist" db:"artist"`
+	Artists         StringSlice `json:"artists" db:"artists"`
+	Album           string      `json:"album" db:"album"`
+	AlbumID         string      `json:"album_id,omitempty" db:"album_id"`
+	AlbumArtist     string      `json:"album_artist" db:"album_artist"`
+	AlbumArtists    StringSlice `json:"album_artists" db:"album_artists"`
+	ArtistSort      string      `json:"artist_sort,omitempty" db:"artist_sort"`
+	AlbumArtistSort string      `json:"album_artist_sort,omitempty" db:"album_artist_sort"`
+	PathArtist      string      `json:"path_artist" db:"path_artist"`
+	TrackNumber     int         `json:"track_number" db:"track_number"`
+	DiscNumber      int         `json:"disc_number" db:"disc_number"`
+	TotalTracks     int         `json:"total_tracks" db:"total_tracks"`
+	TotalDiscs      int         `json:"total_discs" db:"total_discs"`
+	Year            int         `json:"year" db:"year"`
+	Genre           string      `json:"genre" db:"genre"`
+	Mood            string      `json:"mood,omitempty" db:"mood"`
+	Language        string      `json:"language,omitempty" db:"language"`
+	Duration        int         `json:"duration" db:"duration"`
+	Label           string      `json:"label" db:"label"`
+	ISRC            string      `json:"isrc" db:"isrc"`
+	Copyright       string      `json:"copyright" db:"copyright"`
+	Composer        string      `json:"composer" db:"composer"`
+	Producer        string      `json:"producer,omitempty" db:"producer"`
+	Engineer        string      `json:"engineer,omitempty" db:"engineer"`
+	Mixer           string      `json:"mixer,omitempty" db:"mixer"`
+	Performers      StringSlice `json:"performers,omitempty" db:"performers"`
+	Explicit        bool        `json:"explicit" db:"explicit"`
+	Compilation     bool        `json:"compilation" db:"compilation"`
+	AlbumArtURL     string      `json:"album_art_url" db:"album_art_url"`
+	Lyrics          string      `json:"lyrics" db:"lyrics"`
+	Subtitles       string      `json:"subtitles" db:"subtitles"`
+	BPM             int         `json:"bpm,omitempty" db:"bpm"`
+	Key             string      `json:"key,omitempty" db:"key_name"`
+	KeyScale        string      `json:"key_scale,omitempty" db:"key_scale"`
+	ReplayGain      float64     `json:"replay_gain,omitempty" db:"replay_gain"`
+	Peak            float64     `json:"peak,omitempty" db:"peak"`
+	AlbumGain       float64     `json:"album_gain,omitempty" db:"album_gain"`
+	AlbumPeak       float64     `json:"album_peak,omitempty" db:"album_peak"`
+	Version         string      `json:"version,omitempty" db:"version"`
+	Description     string      `json:"description,omitempty" db:"description"`
+	URL             string      `json:"url,omitempty" db:"url"`
+	AudioQuality    string      `json:"audio_quality,omitempty" db:"audio_quality"`
+	AudioModes      string      `json:"audio_modes,omitempty" db:"audio_modes"`
+	TargetQuality   string      `json:"target_quality,omitempty" db:"target_quality"`
+	ReleaseDate     string      `json:"release_date,omitempty" db:"release_date"`
+	OriginalDate    string      `json:"original_date,omitempty" db:"original_date"`
+	OriginalYear    int         `json:"original_year,omitempty" db:"original_year"`
+	Barcode         string      `json:"barcode,omitempty" db:"barcode"`
+	CatalogNumber   string      `json:"catalog_number,omitempty" db:"catalog_number"`
+	ReleaseType     string      `json:"release_type,omitempty" db:"release_type"`
+	ReleaseID       string      `json:"release_id,omitempty" db:"release_id"`
+	RecordingID     *string     `json:"recording_id,omitempty" db:"recording_id"`
+	MBAlbumID       string      `json:"musicbrainz_album_id,omitempty" db:"musicbrainz_album_id"`
+	ReleaseTrackID  string      `json:"release_track_id,omitempty" db:"release_track_id"`
+	Tags            StringSlice `json:"tags,omitempty" db:"tags"`
+	Status          TrackStatus `json:"status" db:"status"`
+	Locked          bool        `json:"locked" db:"locked"` // set by hand; UpdateTrack leaves it alone
+	Error           string      `json:"error,omitempty" db:"error"`
+	ParentJobID     string      `json:"parent_job_id" db:"parent_job_id"`
+	FilePath        string      `json:"file_path" db:"file_path"`
+	FileExtension   string      `json:"file_extension" db:"file_extension"`
+	FileHash        string      `json:"file_hash,omitempty" db:"file_hash"`
+	SampleRate      int         `json:"sample_rate,omitempty" db:"sample_rate"`
+	BitDepth        int         `json:"bit_depth,omitempty" db:"bit_depth"`
+	Channels        int         `json:"channels,omitempty" db:"channels"`
+	Bitrate         int         `json:"bitrate,omitempty" db:"bitrate"`
+	ETag            string      `json:"etag,omitempty" db:"etag"`
+	CreatedAt       time.Time   `json:"created_at" db:"created_at"`
+	UpdatedAt       time.Time   `json:"updated_at" db:"updated_at"`
+	CompletedAt     *time.Time  `json:"completed_at,omitempty" db:"completed_at"`
+	LastVerifiedAt  *time.Time  `json:"last_verified_at,omitempty" db:"last_verified_at"`
+	DeletedAt       *time.Time  `json:"deleted_at,omitempty" db:"deleted_at"`
+	ArtistIDs       StringSlice `json:"artist_ids,omitempty" db:"artist_ids"`
+	AlbumArtistIDs  StringSlice `json:"album_artist_ids,omitempty" db:"album_artist_ids"`
+}
+
+// AudioFormat describes the file's audio properties, e.g. "24-bit / 96 kHz, stereo, 2950 kbps",
+// or "" when they were never read.
+func (t *Track) AudioFormat() string {
+	if t.SampleRate == 0 {
+		return ""
+	}
+	parts := []string{strconv.FormatFloat(float64(t.SampleRate)/1000, 'f', -1, 64) + " kHz"}
+	if t.BitDepth > 0 {
+		parts[0] = strconv.Itoa(t.BitDepth) + "-bit / " + parts[0]
+	}
+	switch t.Channels {
+	case 0:
+	case 1:
+		parts = append(parts, "mono")
+	case 2:
+		parts = append(parts, "stereo")
+	default:
+		parts = append(parts, strconv.Itoa(t.Channels)+" channels")
+	}
+	if t.Bitrate > 0 {
+		parts = append(parts, strconv.Itoa(t.Bitrate)+" kbps")
+	}
+	return strings.Join(parts, ", ")
 }
 
 // Normalize ensures the track data is consistent.
@@ -260,4 +363,41 @@ type SearchResult struct {
 	Albums    []Album        `json:"albums"`
 	Playlists []Playlist     `json:"playlists"`
 	Tracks    []CatalogTrack `json:"tracks"`
+	// FailedTypes lists the categories of an "all" search that could not be fetched.
+	FailedTypes []string `json:"failed_types,omitempty"`
+	// Total is the provider-reported number of matches in the largest category, or 0
+	// when the provider does not report one.
+	Total int `json:"total,omitempty"`
+}
+
+// IsEmpty reports whether the search found nothing in any category.
+func (r *SearchResult) IsEmpty() bool {
+	return len(r.Artists) == 0 && len(r.Albums) == 0 && len(r.Playlists) == 0 && len(r.Tracks) == 0
+}
+
+// APIKey is a credential for the REST API. Only a hash of the key is stored; Prefix is the
+// start of the key, kept so keys can be told apart.
+type APIKey struct {
+	CreatedAt time.Time `json:"created_at"`
+	ID        string    `json:"id"`
+	Name      string    `json:"name"`
+	Prefix    string    `json:"prefix"`
+	Hash      string    `json:"hash,omitempty"`
+}
+
+// SyncUpgradeSummary counts the Hi-Fi syncs since the last bulk sync that queued a
+// quality upgrade and those that left the track as it was.
+type SyncUpgradeSummary struct {
+	StartedAt time.Time `json:"started_at"`
+	Upgraded  int       `json:"upgraded"`
+	Unchanged int       `json:"unchanged"`
+}
+
+// VerifySummary reports the outcome of a library integrity check.
+type VerifySummary struct {
+	FinishedAt time.Time `json:"finished_at"`
+	Scope      string    `json:"scope"`
+	OK         int       `json:"ok"`
+	Changed    int       `json:"changed"`
+	Missing    int       `json:"missing"`
 }
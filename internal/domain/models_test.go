@@ -103,6 +103,9 @@ func TestJob_IsContainer(t *testing.T) {
 		{JobTypeSyncFile, false},
 		{JobTypeSyncMusicBrainz, false},
 		{JobTypeSyncHiFi, false},
+		{JobTypeSyncLyrics, false},
+		{JobTypeUpgrade, false},
+		{JobTypeReorganize, false},
 	}
 
 	for _, tt := range tests {
@@ -340,3 +343,23 @@ func TestTrack_Normalize(t *testing.T) {
 		t.Errorf("Normalize() changed Genre to %q, want %q", tr.Genre, "metal")
 	}
 }
+
+func TestTrack_AudioFormat(t *testing.T) {
+	tests := []struct {
+		name  string
+		track Track
+		want  string
+	}{
+		{"unknown", Track{}, ""},
+		{"hi-res flac", Track{SampleRate: 96000, BitDepth: 24, Channels: 2, Bitrate: 2950}, "24-bit / 96 kHz, stereo, 2950 kbps"},
+		{"cd flac", Track{SampleRate: 44100, BitDepth: 16, Channels: 2}, "16-bit / 44.1 kHz, stereo"},
+		{"lossy mono", Track{SampleRate: 44100, Channels: 1, Bitrate: 320}, "44.1 kHz, mono, 320 kbps"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.track.AudioFormat(); got != tt.want {
+				t.Errorf("AudioFormat() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,65 @@
+package downloader
+
+import (
+	"context"
+	"log/slog"
+	"strings"
+
+	"github.com/cesargomez89/navidrums/internal/domain"
+	"github.com/cesargomez89/navidrums/internal/store"
+)
+
+// jobEventHandler tees a job's log records at info level and above into its event log,
+// so the queue page can show why a job failed without reading server logs. The event log
+// keeps info records even when LOG_LEVEL hides them from the server log.
+type jobEventHandler struct {
+	slog.Handler
+	repo  *store.DB
+	jobID string
+}
+
+func newJobEventHandler(next slog.Handler, repo *store.DB, jobID string) *jobEventHandler {
+	return &jobEventHandler{Handler: next, repo: repo, jobID: jobID}
+}
+
+func (h *jobEventHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	return level >= slog.LevelInfo || h.Handler.Enabled(ctx, level)
+}
+
+func (h *jobEventHandler) Handle(ctx context.Context, r slog.Record) error {
+	if r.Level >= slog.LevelInfo {
+		var sb strings.Builder
+		sb.WriteString(r.Message)
+		r.Attrs(func(a slog.Attr) bool {
+			sb.WriteByte(' ')
+			sb.WriteString(a.Key)
+			sb.WriteByte('=')
+			sb.WriteString(a.Value.Resolve().String())
+			return true
+		})
+		_ = h.repo.AddJobEvent(h.jobID, eventLevel(r.Level), sb.String())
+	}
+	if !h.Handler.Enabled(ctx, r.Level) {
+		return nil
+	}
+	return h.Handler.Handle(ctx, r)
+}
+
+func (h *jobEventHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return &jobEventHandler{Handler: h.Handler.WithAttrs(attrs), repo: h.repo, jobID: h.jobID}
+}
+
+func (h *jobEventHandler) WithGroup(name string) slog.Handler {
+	return &jobEventHandler{Handler: h.Handler.WithGroup(name), repo: h.repo, jobID: h.jobID}
+}
+
+func eventLevel(level slog.Level) domain.JobEventLevel {
+	switch {
+	case level >= slog.LevelError:
+		return domain.JobEventError
+	case level >= slog.LevelWarn:
+		return domain.JobEventWarn
+	default:
+		return domain.JobEventInfo
+	}
+}
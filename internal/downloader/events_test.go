@@ -0,0 +1,58 @@
+package downloader
+
+import (
+	"bytes"
+	"database/sql"
+	"log/slog"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/cesargomez89/navidrums/internal/domain"
+)
+
+func TestJobEventHandler(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	job := &domain.Job{ID: "j1", Type: domain.JobTypeTrack, Status: domain.JobStatusRunning, SourceID: sql.NullString{String: "t1", Valid: true}, CreatedAt: time.Now(), UpdatedAt: time.Now()}
+	if err := db.CreateJob(job); err != nil {
+		t.Fatalf("CreateJob failed: %v", err)
+	}
+
+	// The server log only shows warnings, as with LOG_LEVEL=warn.
+	var out bytes.Buffer
+	next := slog.NewTextHandler(&out, &slog.HandlerOptions{Level: slog.LevelWarn})
+	log := slog.New(newJobEventHandler(next, db, job.ID)).With("job_id", job.ID)
+
+	log.Debug("Resolving track")
+	log.Info("Downloading track", "quality", "LOSSLESS")
+	log.Warn("Lyrics not found")
+
+	events, err := db.ListJobEvents(job.ID)
+	if err != nil {
+		t.Fatalf("ListJobEvents failed: %v", err)
+	}
+	want := []struct {
+		level   domain.JobEventLevel
+		message string
+	}{
+		{domain.JobEventInfo, "Downloading track quality=LOSSLESS"},
+		{domain.JobEventWarn, "Lyrics not found"},
+	}
+	if len(events) != len(want) {
+		t.Fatalf("got %d events, want %d", len(events), len(want))
+	}
+	for i, w := range want {
+		if events[i].Level != w.level || events[i].Message != w.message {
+			t.Errorf("event %d = %s %q, want %s %q", i, events[i].Level, events[i].Message, w.level, w.message)
+		}
+	}
+
+	if strings.Contains(out.String(), "Downloading track") {
+		t.Errorf("server log has the info record: %s", out.String())
+	}
+	if !strings.Contains(out.String(), "Lyrics not found") {
+		t.Errorf("server log is missing the warning: %s", out.String())
+	}
+}
@@ -2,22 +2,32 @@ package downloader
 
 import (
 	"context"
+	"crypto/sha256"
 	"database/sql"
+	"encoding/hex"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"log/slog"
 	"os"
 	"path/filepath"
+	"slices"
+	"strings"
 	"sync"
 	"time"
 
 	"github.com/google/uuid"
 
+	"golang.org/x/sync/errgroup"
+
 	"github.com/cesargomez89/navidrums/internal/app"
 	"github.com/cesargomez89/navidrums/internal/catalog"
 	"github.com/cesargomez89/navidrums/internal/config"
 	"github.com/cesargomez89/navidrums/internal/constants"
 	"github.com/cesargomez89/navidrums/internal/domain"
+	"github.com/cesargomez89/navidrums/internal/imaging"
+	"github.com/cesargomez89/navidrums/internal/metrics"
+	"github.com/cesargomez89/navidrums/internal/navidrome"
 	"github.com/cesargomez89/navidrums/internal/storage"
 	"github.com/cesargomez89/navidrums/internal/store"
 	"github.com/cesargomez89/navidrums/internal/tagging"
@@ -33,7 +43,10 @@ type TrackJobHandler struct {
 	AlbumArtService   app.AlbumArtService
 	PlaylistGenerator app.PlaylistGenerator
 	Enricher          *app.MetadataEnricher
-	m3uLocks          sync.Map
+	// Navidrome, when set, is checked before each download so tracks the library already
+	// has are skipped (DEDUPE_AGAINST_NAVIDROME).
+	Navidrome *navidrome.Library
+	m3uLocks  sync.Map
 }
 
 func (h *TrackJobHandler) Handle(ctx context.Context, job *domain.Job, logger *slog.Logger) error {
@@ -51,7 +64,11 @@ func (h *TrackJobHandler) Handle(ctx context.Context, job *domain.Job, logger *s
 		return nil
 	}
 
-	finalPath, err := h.executeDownload(ctx, job, track, destPath, logger)
+	qualities := h.jobQualities(job)
+	finalPath, err := h.executeDownload(ctx, job, track, destPath, qualities, logger)
+	if errors.Is(err, ErrJobCancelled) || errors.Is(err, ErrJobInterrupted) {
+		return nil
+	}
 	if err != nil {
 		return err
 	}
@@ -60,15 +77,16 @@ func (h *TrackJobHandler) Handle(ctx context.Context, job *domain.Job, logger *s
 		logger.Warn("Post-processing had issues", "error", err)
 	}
 
-	h.finalizeTrackDownload(job, track, finalPath, logger)
+	h.finalizeTrackDownload(job, track, finalPath, qualities, logger)
 	return nil
 }
 
 func (h *TrackJobHandler) prepareTrackDownload(ctx context.Context, job *domain.Job, logger *slog.Logger) (*domain.Track, string, bool, error) {
-	forceDownload := h.isForceDownload()
+	// An upgrade job replaces a completed track, like a forced download.
+	forceDownload := h.isForceDownload() || job.Type == domain.JobTypeUpgrade
 
 	existingTrack, _ := h.Repo.GetTrackByProviderID(job.GetSourceID())
-	if existingTrack != nil && existingTrack.Status == domain.TrackStatusCompleted && !forceDownload {
+	if existingTrack != nil && existingTrack.Status == domain.TrackStatusCompleted && existingTrack.DeletedAt == nil && !forceDownload {
 		logger.Info("Track already downloaded", "file_path", existingTrack.FilePath)
 		_ = h.Repo.UpdateJobStatus(job.ID, domain.JobStatusCompleted, 100)
 		return nil, "", true, nil
@@ -79,15 +97,20 @@ func (h *TrackJobHandler) prepareTrackDownload(ctx context.Context, job *domain.
 		track = existingTrack
 	} else {
 		track = &domain.Track{
-			ProviderID:  job.GetSourceID(),
-			Status:      domain.TrackStatusMissing,
-			ParentJobID: job.ID,
-			CreatedAt:   time.Now(),
-			UpdatedAt:   time.Now(),
+			ProviderID:     job.GetSourceID(),
+			SourceProvider: string(h.ProviderManager.MetadataProviderType()),
+			Status:         domain.TrackStatusMissing,
+			ParentJobID:    job.ID,
+			CreatedAt:      time.Now(),
+			UpdatedAt:      time.Now(),
 		}
 	}
 
-	h.Enricher.EnrichComplete(ctx, track, logger)
+	// Lyrics are fetched after the download, alongside the album art.
+	h.enrichMetadata(ctx, track, logger)
+	app.NormalizeFeaturedArtists(h.Config, track)
+	app.NormalizeCompilationArtist(h.Config, track)
+	applyAlbumOverride(h.Repo, track, logger)
 
 	if track.Title == "" && track.Artist == "" {
 		err := fmt.Errorf("failed to fetch primary track metadata")
@@ -108,24 +131,17 @@ func (h *TrackJobHandler) prepareTrackDownload(ctx context.Context, job *domain.
 		}
 	}
 
-	artistForFolder := track.PathArtist
-	if artistForFolder == "" {
-		artistForFolder = track.AlbumArtist
-	}
-	if artistForFolder == "" {
-		artistForFolder = track.Artist
+	if track.Status != domain.TrackStatusCompleted && !forceDownload && h.inNavidrome(ctx, track, logger) {
+		logger.Info("Track already in Navidrome, skipping download")
+		_ = h.Repo.MarkTrackSkipped(track.ID, "Already in the Navidrome library")
+		_ = h.Repo.UpdateJobStatus(job.ID, domain.JobStatusCompleted, 100)
+		if track.ParentJobID != "" {
+			updateParentJobProgress(h.Repo, track.ParentJobID, logger)
+		}
+		return nil, "", true, nil
 	}
 
-	templateData := storage.BuildPathTemplateData(
-		artistForFolder,
-		track.Year,
-		track.Album,
-		track.DiscNumber,
-		track.TrackNumber,
-		track.Title,
-	)
-
-	fullPathNoExt, err := storage.BuildPath(h.Config.SubdirTemplate, templateData)
+	fullPathNoExt, err := app.BuildTrackPath(h.Config, track)
 	if err != nil {
 		logger.Error("Failed to build path from template", "error", err)
 		_ = h.Repo.MarkTrackFailed(track.ID, fmt.Sprintf("Failed to build path: %v", err))
@@ -133,13 +149,7 @@ func (h *TrackJobHandler) prepareTrackDownload(ctx context.Context, job *domain.
 		return nil, "", false, err
 	}
 
-	fullPathNoExt = filepath.Join(h.Config.DownloadsDir, fullPathNoExt)
-
-	ext := track.FileExtension
-	if ext == "" {
-		ext = ".flac"
-	}
-	predictedPath := fullPathNoExt + ext
+	predictedPath := fullPathNoExt + app.TrackExtension(track.FileExtension, app.FallbackExtension(h.Config))
 
 	if track.Status == domain.TrackStatusCompleted && !forceDownload {
 		exists := false
@@ -153,29 +163,21 @@ func (h *TrackJobHandler) prepareTrackDownload(ctx context.Context, job *domain.
 		}
 
 		if exists {
-			match := false
-			if track.FileHash != "" {
-				verified, _ := storage.VerifyFile(predictedPath, track.FileHash)
-				if verified {
-					match = true
-				}
-			} else {
-				newHash, hashErr := storage.HashFile(predictedPath)
-				if hashErr == nil {
-					track.FileHash = newHash
+			mode := constants.ExistingFileVerifyHash
+			if h.Config != nil && h.Config.OnExistingFile != "" {
+				mode = h.Config.OnExistingFile
+			}
+			storedHash := track.FileHash
+			if keepExistingFile(mode, track, predictedPath) {
+				if track.FileHash != storedHash {
 					_ = h.Repo.UpdateTrack(track)
-					match = true
 				}
-			}
-
-			if match {
-				logger.Info("Track already exists and verified, skipping download", "path", predictedPath)
+				logger.Info("Track already exists, skipping download", "path", predictedPath, "on_existing_file", mode)
 				_ = h.Repo.UpdateJobStatus(job.ID, domain.JobStatusCompleted, 100)
 				return nil, "", true, nil
-			} else {
-				logger.Info("Track exists but hash mismatch, redownloading", "path", predictedPath)
-				_ = storage.RemoveFile(predictedPath)
 			}
+			logger.Info("Track exists but will be replaced, redownloading", "path", predictedPath, "on_existing_file", mode)
+			_ = storage.RemoveFile(predictedPath)
 		}
 	} else if track.Status == domain.TrackStatusCompleted && forceDownload {
 		logger.Info("Force download enabled, deleting existing file", "path", predictedPath)
@@ -188,7 +190,29 @@ func (h *TrackJobHandler) prepareTrackDownload(ctx context.Context, job *domain.
 	return track, fullPathNoExt, false, nil
 }
 
-func (h *TrackJobHandler) executeDownload(ctx context.Context, job *domain.Job, track *domain.Track, destPath string, logger *slog.Logger) (string, error) {
+// keepExistingFile reports whether the file already at path stands in for a download of
+// track under an ON_EXISTING_FILE mode. verify-hash keeps it when it matches the recorded
+// hash; skip keeps it whatever its content, trusting the filesystem; overwrite never does.
+// A kept file's hash is recorded on track when it had none or, under skip, when it changed,
+// so an integrity check doesn't flag the file as changed.
+func keepExistingFile(mode string, track *domain.Track, path string) bool {
+	if mode == constants.ExistingFileOverwrite {
+		return false
+	}
+	if track.FileHash != "" && mode != constants.ExistingFileSkip {
+		verified, _ := storage.VerifyFile(path, track.FileHash)
+		return verified
+	}
+	newHash, err := storage.HashFile(path)
+	if err != nil {
+		// An unreadable file is replaced, whatever the mode.
+		return false
+	}
+	track.FileHash = newHash
+	return true
+}
+
+func (h *TrackJobHandler) executeDownload(ctx context.Context, job *domain.Job, track *domain.Track, destPath string, qualities []string, logger *slog.Logger) (string, error) {
 	if updateErr := h.Repo.UpdateTrackStatus(track.ID, domain.TrackStatusDownloading, ""); updateErr != nil {
 		logger.Error("Failed to update track status to downloading", "error", updateErr)
 		return "", updateErr
@@ -202,8 +226,33 @@ func (h *TrackJobHandler) executeDownload(ctx context.Context, job *domain.Job,
 		return "", dirErr
 	}
 
-	quality := h.getQuality()
-	finalPath, err := h.Downloader.Download(ctx, track, destPath, quality, logger)
+	finalPath, err := h.Downloader.Download(ctx, track, destPath, qualities, logger)
+	if err != nil && errors.Is(err, context.Canceled) && h.isCancelled(job.ID) {
+		// The downloader has removed the partial file; the job is already cancelled.
+		logger.Info("Job cancelled during download")
+		_ = h.Repo.UpdateTrackStatus(track.ID, domain.TrackStatusMissing, "")
+		return "", ErrJobCancelled
+	}
+	if err != nil && ctx.Err() != nil {
+		// The worker stopped before the download finished. The downloader has removed the
+		// partial file; queue the track again so it restarts with the worker.
+		logger.Warn("Download interrupted by shutdown")
+		_ = h.Repo.UpdateTrackStatus(track.ID, domain.TrackStatusQueued, "")
+		_ = h.Repo.UpdateJobStatus(job.ID, domain.JobStatusQueued, 0)
+		return "", ErrJobInterrupted
+	}
+	if errors.Is(err, catalog.ErrTrackUnavailable) || errors.Is(err, catalog.ErrNotFound) {
+		// Retrying won't bring a region-locked or withdrawn track back, so it isn't marked
+		// failed.
+		logger.Warn("Track unavailable from provider", "error", err)
+		msg := "Not available from the provider (it may be region-locked)"
+		if errors.Is(err, catalog.ErrNotFound) {
+			msg = "Not found at the provider (it may have been withdrawn)"
+		}
+		_ = h.Repo.MarkTrackUnavailable(track.ID, msg)
+		_ = h.Repo.UpdateJobError(job.ID, msg)
+		return "", err
+	}
 	if err != nil {
 		logger.Error("Download failed", "error", err)
 		_ = h.Repo.MarkTrackFailed(track.ID, err.Error())
@@ -224,21 +273,10 @@ func (h *TrackJobHandler) postProcessTrack(ctx context.Context, track *domain.Tr
 		logger.Error("Failed to update track status to processing", "error", statusErr)
 	}
 
-	var albumArtData []byte
 	finalDir := filepath.Dir(finalPath)
-	artPath := filepath.Join(finalDir, "cover.jpg")
+	albumArtData, artPath, pictures := h.fetchTagAssets(ctx, track, finalDir, logger)
 
-	if data, err := os.ReadFile(artPath); err == nil && len(data) > 0 { //nolint:gosec
-		albumArtData = data
-	} else if track.AlbumArtURL != "" {
-		var err error
-		albumArtData, err = h.AlbumArtService.DownloadImage(track.AlbumArtURL)
-		if err != nil {
-			logger.Error("Failed to download album art for tagging", "error", err)
-		}
-	}
-
-	if tagErr := tagging.TagFile(finalPath, track, albumArtData); tagErr != nil {
+	if tagErr := tagging.TagFile(finalPath, track, tagOptions(h.Config, albumArtData, pictures)); tagErr != nil {
 		if errors.Is(tagErr, tagging.ErrUnsupportedFormat) {
 			logger.Warn("Tagging skipped: unsupported format", "file_path", finalPath, "error", tagErr)
 		} else {
@@ -246,37 +284,127 @@ func (h *TrackJobHandler) postProcessTrack(ctx context.Context, track *domain.Tr
 		}
 	}
 
+	if h.Config != nil && h.Config.WriteLrcSidecar {
+		if lrcErr := tagging.WriteLyricsSidecar(finalPath, track); lrcErr != nil {
+			logger.Error("Failed to write lyrics sidecar", "file_path", finalPath, "error", lrcErr)
+		}
+	}
+
 	if len(albumArtData) > 0 {
-		if _, artStatErr := os.Stat(artPath); os.IsNotExist(artStatErr) {
+		if artPath == "" {
+			artPath = storage.CoverPath(finalDir, albumArtData)
 			if writeErr := storage.WriteFile(artPath, albumArtData); writeErr != nil {
 				logger.Error("Failed to save album art", "path", artPath, "error", writeErr)
 			} else {
 				logger.Info("Saved album art", "path", artPath)
 			}
 		}
+		if h.Config != nil && h.Config.SaveFolderArt {
+			folderPath := storage.FolderArtPath(finalDir, albumArtData)
+			if _, writeErr := storage.WriteImageIfMissing(folderPath, albumArtData); writeErr != nil {
+				logger.Error("Failed to save folder art", "path", folderPath, "error", writeErr)
+			}
+		}
 	}
 
 	logger.Info("File finalized", "original_path", finalPath)
 	return nil
 }
 
-func (h *TrackJobHandler) finalizeTrackDownload(job *domain.Job, track *domain.Track, finalPath string, logger *slog.Logger) {
+// tagOptions returns the tagging options the configuration asks for, embedding art and
+// pictures unless EMBED_COVER_ART is off.
+func tagOptions(cfg *config.Config, art []byte, pictures []tagging.Picture) tagging.TagOptions {
+	opts := tagging.TagOptions{Art: art, Pictures: pictures}
+	if cfg != nil {
+		opts.MergeStrategy = tagging.MergeStrategy(cfg.TagMergeStrategy)
+		opts.ArtistMode = tagging.ArtistMode(cfg.ArtistTagMode)
+		opts.SkipArt = !cfg.EmbedCoverArt
+		opts.SkipSyncedLyrics = !cfg.EmbedSyncedLyrics
+		opts.SkipOriginalDate = !cfg.OriginalDateTagging
+		opts.Gapless = cfg.GaplessTags
+		opts.ID3v23 = cfg.ID3Version == "2.3"
+	}
+	return opts
+}
+
+// fetchTagAssets gets the album art to embed and fills in the track's lyrics. The art
+// download and the lyrics lookup hit different services, so they run concurrently and a
+// failure of one doesn't hold up the other. artPath is the existing cover the art was
+// read from, or "" when it was downloaded.
+func (h *TrackJobHandler) fetchTagAssets(ctx context.Context, track *domain.Track, finalDir string, logger *slog.Logger) (albumArtData []byte, artPath string, pictures []tagging.Picture) {
+	artPath = storage.FindCover(finalDir)
+	if artPath != "" {
+		if data, err := os.ReadFile(artPath); err == nil && len(data) > 0 { //nolint:gosec
+			albumArtData = data
+		}
+	}
+
+	var g errgroup.Group
+	downloadArt := len(albumArtData) == 0 && track.AlbumArtURL != ""
+	if downloadArt {
+		artURL := track.AlbumArtURL
+		g.Go(func() error {
+			data, err := h.AlbumArtService.DownloadImage(artURL)
+			albumArtData = data
+			return err
+		})
+	}
+	g.Go(func() error {
+		h.Enricher.FetchLyricsWithFallback(ctx, track, logger)
+		return nil
+	})
+	g.Go(func() error {
+		pictures = fetchExtraPictures(ctx, h.Config, h.ProviderManager, h.AlbumArtService, track, logger)
+		return nil
+	})
+	if err := g.Wait(); err != nil {
+		logArtDownloadError(logger, track.AlbumArtURL, err)
+	}
+
+	albumArtData = embeddableArt(h.Config, albumArtData, downloadArt && h.Config != nil && h.Config.CoverArtForceJPEG, logger)
+	if downloadArt {
+		artPath = ""
+	}
+	return albumArtData, artPath, pictures
+}
+
+// embeddableArt converts cover art players can't show when embedded, WebP and AVIF, to
+// JPEG, and any art when force is set. Art that fails to convert is returned unchanged.
+func embeddableArt(cfg *config.Config, data []byte, force bool, logger *slog.Logger) []byte {
+	if len(data) == 0 || (!force && !imaging.NeedsConversion(data)) {
+		return data
+	}
+	quality := 0
+	if cfg != nil {
+		quality = cfg.CoverArtJPEGQuality
+	}
+	converted, err := imaging.ToJPEG(data, quality)
+	if err != nil {
+		logger.Warn("Failed to convert album art to JPEG", "error", err)
+		return data
+	}
+	return converted
+}
+
+func (h *TrackJobHandler) finalizeTrackDownload(job *domain.Job, track *domain.Track, finalPath string, qualities []string, logger *slog.Logger) {
 	fileHash, err := storage.HashFile(finalPath)
 	if err != nil {
 		logger.Error("Failed to hash file", "error", err)
 	}
 
-	ext := filepath.Ext(finalPath)
-	if ext == "" {
-		ext = ".flac"
+	track.FileExtension = app.TrackExtension(filepath.Ext(finalPath), app.FallbackExtension(h.Config))
+	readAudioProperties(track, finalPath, logger)
+	if len(qualities) > 0 {
+		track.TargetQuality = qualities[0]
 	}
-	track.FileExtension = ext
+	app.ReconcileAudioQuality(track, logger)
 	track.Status = domain.TrackStatusCompleted
 	track.FilePath = finalPath
 	track.FileHash = fileHash
 	now := time.Now()
 	track.CompletedAt = &now
 	track.LastVerifiedAt = &now
+	track.DeletedAt = nil
 	track.UpdatedAt = time.Now()
 
 	if err := h.Repo.UpdateTrack(track); err != nil {
@@ -291,6 +419,11 @@ func (h *TrackJobHandler) finalizeTrackDownload(job *domain.Job, track *domain.T
 		logger.Error("Failed to update final job status", "error", err)
 	}
 
+	metrics.DownloadsCompleted.Inc()
+	if info, statErr := os.Stat(finalPath); statErr == nil {
+		metrics.DownloadBytes.Add(float64(info.Size()))
+	}
+
 	if track.ParentJobID != "" {
 		parentJob, err := h.Repo.GetJob(track.ParentJobID)
 		if err == nil && parentJob != nil && parentJob.Type == domain.JobTypePlaylist {
@@ -302,13 +435,29 @@ func (h *TrackJobHandler) finalizeTrackDownload(job *domain.Job, track *domain.T
 			}
 		}
 
-		h.updateParentJobProgress(track.ParentJobID, logger)
+		updateParentJobProgress(h.Repo, track.ParentJobID, logger)
 		h.triggerPlaylistGenerationIfComplete(track.ParentJobID, logger)
 	}
 
 	logger.Info("Job completed successfully")
 }
 
+// readAudioProperties records the sample rate, bit depth, channels and bitrate of the
+// file at path on track.
+func readAudioProperties(track *domain.Track, path string, logger *slog.Logger) {
+	props, err := tagging.ReadAudioProperties(path)
+	if err != nil {
+		if !errors.Is(err, tagging.ErrUnsupportedFormat) {
+			logger.Warn("Failed to read audio properties", "error", err)
+		}
+		return
+	}
+	track.SampleRate = props.SampleRate
+	track.BitDepth = props.BitDepth
+	track.Channels = props.Channels
+	track.Bitrate = props.Bitrate
+}
+
 func (h *TrackJobHandler) triggerPlaylistGenerationIfComplete(parentJobID string, logger *slog.Logger) {
 	parentJob, err := h.Repo.GetJob(parentJobID)
 	if err != nil || parentJob == nil {
@@ -335,11 +484,19 @@ func (h *TrackJobHandler) triggerPlaylistGenerationIfComplete(parentJobID string
 
 	defer h.m3uLocks.Delete(parentJobID)
 
+	// Tracks skipped as ISRC duplicates stay in the playlist as the copy already in the
+	// library.
+	duplicates, err := h.Repo.ListJobDuplicateTracks(parentJobID)
+	if err != nil {
+		logger.Error("Failed to list duplicate tracks", "parent_job", parentJobID, "error", err)
+	}
+	lookup := lookupTrackOrDuplicate(h.Repo, duplicates)
+
 	switch parentJob.Type {
 	case domain.JobTypePlaylist:
 		playlist, err := h.Repo.GetPlaylistByProviderID(parentJob.GetSourceID())
 		if err == nil && playlist != nil {
-			if genErr := h.PlaylistGenerator.GenerateFromDB(playlist.ID, lookupTrack(h.Repo)); genErr != nil {
+			if genErr := h.PlaylistGenerator.GenerateFromDB(playlist.ID, lookup); genErr != nil {
 				logger.Error("Failed to generate complete playlist from DB", "error", genErr)
 			} else {
 				logger.Info("Successfully generated complete playlist from DB", "playlist_id", playlist.ID)
@@ -347,23 +504,27 @@ func (h *TrackJobHandler) triggerPlaylistGenerationIfComplete(parentJobID string
 		}
 	case domain.JobTypeArtist:
 		tracks, err := h.Repo.ListTracksByParentJobID(parentJobID)
-		if err == nil && len(tracks) > 0 {
-			catalogTracks := make([]domain.CatalogTrack, len(tracks))
-			for i, t := range tracks {
-				catalogTracks[i] = domain.CatalogTrack{
-					ID:          t.ProviderID,
-					Title:       t.Title,
-					Artist:      t.Artist,
-					Album:       t.Album,
-					AlbumArtist: t.AlbumArtist,
-					Duration:    t.Duration,
-				}
+		if err == nil && len(tracks)+len(duplicates) > 0 {
+			// The duplicates follow the job's own tracks, keyed by the catalog IDs the job
+			// skipped so the lookup resolves them.
+			ids := make([]string, 0, len(duplicates))
+			for id := range duplicates {
+				ids = append(ids, id)
 			}
-			artistName := tracks[0].AlbumArtist
+			slices.Sort(ids)
+			catalogTracks := make([]domain.CatalogTrack, 0, len(tracks)+len(ids))
+			for _, t := range tracks {
+				catalogTracks = append(catalogTracks, artistPlaylistEntry(t.ProviderID, t))
+			}
+			for _, id := range ids {
+				catalogTracks = append(catalogTracks, artistPlaylistEntry(id, duplicates[id]))
+			}
+			first := catalogTracks[0]
+			artistName := first.AlbumArtist
 			if artistName == "" {
-				artistName = tracks[0].Artist
+				artistName = first.Artist
 			}
-			if genErr := h.PlaylistGenerator.GenerateFromTracks(artistName, catalogTracks, lookupTrack(h.Repo)); genErr != nil {
+			if genErr := h.PlaylistGenerator.GenerateFromTracks(artistName, catalogTracks, lookup); genErr != nil {
 				logger.Error("Failed to generate complete artist playlist", "error", genErr)
 			} else {
 				logger.Info("Successfully generated complete artist playlist", "artist", artistName)
@@ -372,6 +533,17 @@ func (h *TrackJobHandler) triggerPlaylistGenerationIfComplete(parentJobID string
 	}
 }
 
+func artistPlaylistEntry(id string, t *domain.Track) domain.CatalogTrack {
+	return domain.CatalogTrack{
+		ID:          id,
+		Title:       t.Title,
+		Artist:      t.Artist,
+		Album:       t.Album,
+		AlbumArtist: t.AlbumArtist,
+		Duration:    t.Duration,
+	}
+}
+
 func lookupTrack(repo *store.DB) func(string) *domain.Track {
 	return func(trackID string) *domain.Track {
 		t, _ := repo.GetTrackByProviderID(trackID)
@@ -379,8 +551,27 @@ func lookupTrack(repo *store.DB) func(string) *domain.Track {
 	}
 }
 
-func (h *TrackJobHandler) updateParentJobProgress(parentJobID string, logger *slog.Logger) {
-	total, pending, err := h.Repo.CountJobsForParent(parentJobID)
+// lookupTrackOrDuplicate resolves tracks skipped as ISRC duplicates to the existing track,
+// so playlists reference the file already in the library.
+func lookupTrackOrDuplicate(repo *store.DB, duplicates map[string]*domain.Track) func(string) *domain.Track {
+	lookup := lookupTrack(repo)
+	return func(trackID string) *domain.Track {
+		if t, ok := duplicates[trackID]; ok {
+			return t
+		}
+		return lookup(trackID)
+	}
+}
+
+// updateParentJobProgress recomputes a container job's progress from its children and
+// completes it once none are pending, cascading to its own parent (e.g. an album job
+// enqueued by a discography job).
+func updateParentJobProgress(repo *store.DB, parentJobID string, logger *slog.Logger) {
+	// A parent that was cancelled or failed keeps its status.
+	if parent, err := repo.GetJob(parentJobID); err == nil && parent.IsTerminal() {
+		return
+	}
+	total, pending, err := repo.CountJobsForParent(parentJobID)
 	if err != nil {
 		logger.Error("Failed to count jobs for parent", "parent_job", parentJobID, "error", err)
 		return
@@ -391,15 +582,54 @@ func (h *TrackJobHandler) updateParentJobProgress(parentJobID string, logger *sl
 	}
 
 	progress := float64(total-pending) / float64(total) * 100
-	if err := h.Repo.UpdateJobProgress(parentJobID, progress); err != nil {
+	if err := repo.UpdateJobProgress(parentJobID, progress); err != nil {
 		logger.Error("Failed to update parent job progress", "parent_job", parentJobID, "error", err)
 	}
 
 	if pending == 0 {
-		if err := h.Repo.UpdateJobStatus(parentJobID, domain.JobStatusCompleted, 100); err != nil {
-			logger.Error("Failed to mark parent job as completed", "parent_job", parentJobID, "error", err)
-		}
+		completeContainerJob(repo, parentJobID, logger)
+	}
+}
+
+// completeContainerJob marks a container job completed and updates its parent, if any.
+func completeContainerJob(repo *store.DB, jobID string, logger *slog.Logger) {
+	if err := repo.UpdateJobStatus(jobID, domain.JobStatusCompleted, 100); err != nil {
+		logger.Error("Failed to mark parent job as completed", "parent_job", jobID, "error", err)
+		return
+	}
+	if job, err := repo.GetJob(jobID); err == nil && job.GetParentJobID() != "" {
+		updateParentJobProgress(repo, job.GetParentJobID(), logger)
+	}
+}
+
+// enrichMetadata refreshes a track about to be downloaded from its source provider, and
+// from MusicBrainz unless ENABLE_MUSICBRAINZ_ENRICHMENT or MUSICBRAINZ_SKIP_PROVIDERS
+// turns that off for the provider.
+func (h *TrackJobHandler) enrichMetadata(ctx context.Context, track *domain.Track, logger *slog.Logger) {
+	source := track.SourceProvider
+	if source == "" && h.ProviderManager != nil {
+		source = string(h.ProviderManager.MetadataProviderType())
+	}
+	if h.Config != nil && !h.Config.MusicBrainzEnrichmentFor(source) {
+		logger.Debug("Skipping MusicBrainz enrichment", "provider", source)
+		h.Enricher.EnrichProviderMetadata(ctx, track, logger)
+		return
 	}
+	h.Enricher.EnrichMetadata(ctx, track, logger)
+}
+
+// inNavidrome reports whether the Navidrome library already has track. When Navidrome
+// can't be reached the track is downloaded anyway.
+func (h *TrackJobHandler) inNavidrome(ctx context.Context, track *domain.Track, logger *slog.Logger) bool {
+	if h.Navidrome == nil {
+		return false
+	}
+	found, err := h.Navidrome.Has(ctx, track)
+	if err != nil {
+		logger.Warn("Failed to check the Navidrome library", "error", err)
+		return false
+	}
+	return found
 }
 
 func (h *TrackJobHandler) isCancelled(id string) bool {
@@ -414,6 +644,7 @@ func (h *TrackJobHandler) isCancelled(id string) bool {
 type ContainerJobHandler struct {
 	Repo              *store.DB
 	SettingsRepo      *store.SettingsRepo
+	Config            *config.Config
 	ProviderManager   *catalog.ProviderManager
 	AlbumArtService   app.AlbumArtService
 	PlaylistGenerator app.PlaylistGenerator
@@ -449,25 +680,57 @@ func (h *ContainerJobHandler) processAlbumJob(ctx context.Context, job *domain.J
 		return ErrNoTracksFound
 	}
 
+	// Fetch the cover once for the whole album: it is saved next to the tracks from the
+	// worker's image cache, and the track jobs embed it from there or from the saved file.
+	prefetchImages(ctx, h.AlbumArtService, albumImageURLs(album)...)
 	if album.AlbumArtURL != "" {
-		if err := h.AlbumArtService.DownloadAndSaveAlbumArt(album, album.AlbumArtURL); err != nil {
+		if err := h.AlbumArtService.DownloadAndSaveAlbumArt(album, string(h.ProviderManager.MetadataProviderType()), album.AlbumArtURL); err != nil {
 			logger.Error("Failed to save album art", "error", err)
 		}
 	}
 
-	logger.Info("Creating track jobs", "track_count", len(album.Tracks))
-	createdCount := h.createTracksAndJobs(job.ID, album.Tracks, logger)
+	if app.ApplyCompilation(h.Config, album) {
+		logger.Info("Detected compilation album", "album_artist", album.Artist)
+	}
+
+	tracks := album.Tracks
+	if ids := job.GetTrackIDs(); ids != nil {
+		tracks = selectTracks(tracks, ids)
+		logger.Info("Downloading selected album tracks", "selected", len(ids), "found", len(tracks))
+	} else {
+		var explicit int
+		if tracks, explicit = app.PreferCleanTracks(h.Config, tracks); explicit > 0 {
+			logger.Info("Skipping explicit tracks with a clean version", "skipped", explicit)
+		}
+	}
+	minSecs := h.minTrackDuration(job)
+	tracks, short := skipShortTracks(tracks, minSecs)
+	reportShortTracks(h.Repo, job.ID, short, minSecs)
+	logger.Info("Creating track jobs", "track_count", len(tracks), "skipped_short", short)
+	createdCount, _ := h.createTracksAndJobs(job, tracks, logger)
 
 	if err := h.Repo.UpdateJobStatus(job.ID, domain.JobStatusDecomposed, 0); err != nil {
 		logger.Error("Failed to update job status to decomposed", "error", err)
 	}
+	// With no track jobs nothing would ever complete the album job (or a discography
+	// job waiting on it).
+	if createdCount == 0 {
+		completeContainerJob(h.Repo, job.ID, logger)
+	}
 
-	logger.Info("Album job completed", "tracks_created", createdCount)
+	logger.Info("Album job completed", "tracks_created", createdCount, "skipped_short", short)
 	return nil
 }
 
 func (h *ContainerJobHandler) processPlaylistJob(ctx context.Context, job *domain.Job, logger *slog.Logger) error {
-	pl, err := h.ProviderManager.GetMetadataProvider().GetPlaylist(ctx, job.GetSourceID())
+	provider := h.ProviderManager.GetMetadataProvider()
+	var pl *domain.Playlist
+	var err error
+	if seed, ok := app.RadioSeed(job.GetSourceID()); ok {
+		pl, err = app.BuildRadio(ctx, provider, seed)
+	} else {
+		pl, err = provider.GetPlaylist(ctx, job.GetSourceID())
+	}
 	if err != nil {
 		logger.Error("Failed to fetch playlist", "error", err)
 		_ = h.Repo.UpdateJobError(job.ID, fmt.Sprintf("Failed to fetch playlist: %v", err))
@@ -481,7 +744,7 @@ func (h *ContainerJobHandler) processPlaylistJob(ctx context.Context, job *domai
 	}
 
 	if pl.ImageURL != "" {
-		if imgErr := h.AlbumArtService.DownloadAndSavePlaylistImage(pl, pl.ImageURL); imgErr != nil {
+		if imgErr := h.AlbumArtService.DownloadAndSavePlaylistImage(pl, string(h.ProviderManager.MetadataProviderType()), pl.ImageURL); imgErr != nil {
 			logger.Error("Failed to save playlist image", "error", imgErr)
 		}
 	}
@@ -509,20 +772,35 @@ func (h *ContainerJobHandler) processPlaylistJob(ctx context.Context, job *domai
 		}
 	}
 
-	logger.Info("Creating track jobs", "track_count", len(pl.Tracks))
-	createdCount := h.createTracksAndJobs(job.ID, pl.Tracks, logger)
+	minSecs := h.minTrackDuration(job)
+	tracks, short := skipShortTracks(pl.Tracks, minSecs)
+	reportShortTracks(h.Repo, job.ID, short, minSecs)
+	logger.Info("Creating track jobs", "track_count", len(tracks), "skipped_short", short)
+	createdCount, duplicates := h.createTracksAndJobs(job, tracks, logger)
+
+	// Duplicates are already in the library under another provider ID; link the existing
+	// tracks so the generated playlist points at their files.
+	if playlist.ID != 0 {
+		for _, t := range pl.Tracks {
+			if existing, ok := duplicates[t.ID]; ok {
+				if err := h.Repo.AddTrackToPlaylist(playlist.ID, existing.ID, t.TrackNumber); err != nil {
+					logger.Warn("Failed to add track to playlist", "error", err)
+				}
+			}
+		}
+	}
 
 	if err := h.Repo.UpdateJobStatus(job.ID, domain.JobStatusDecomposed, 0); err != nil {
 		logger.Error("Failed to update job status to decomposed", "error", err)
 	}
 
 	if createdCount == 0 {
-		if genErr := h.PlaylistGenerator.Generate(pl, lookupTrack(h.Repo)); genErr != nil {
+		if genErr := h.PlaylistGenerator.Generate(pl, lookupTrackOrDuplicate(h.Repo, duplicates)); genErr != nil {
 			logger.Error("Failed to generate playlist file", "error", genErr)
 		}
 	}
 
-	logger.Info("Playlist job completed", "tracks_created", createdCount)
+	logger.Info("Playlist job completed", "tracks_created", createdCount, "skipped_short", short)
 	return nil
 }
 
@@ -540,8 +818,15 @@ func (h *ContainerJobHandler) processArtistJob(ctx context.Context, job *domain.
 		return ErrNoTracksFound
 	}
 
-	logger.Info("Creating track jobs", "track_count", len(artist.TopTracks))
-	createdCount := h.createTracksAndJobs(job.ID, artist.TopTracks, logger)
+	if imgErr := h.AlbumArtService.DownloadAndSaveArtistImage(artist, string(h.ProviderManager.MetadataProviderType())); imgErr != nil {
+		logger.Warn("Failed to save artist image", "error", imgErr)
+	}
+
+	minSecs := h.minTrackDuration(job)
+	tracks, short := skipShortTracks(artist.TopTracks, minSecs)
+	reportShortTracks(h.Repo, job.ID, short, minSecs)
+	logger.Info("Creating track jobs", "track_count", len(tracks), "skipped_short", short)
+	createdCount, duplicates := h.createTracksAndJobs(job, tracks, logger)
 
 	if err := h.Repo.UpdateJobStatus(job.ID, domain.JobStatusDecomposed, 0); err != nil {
 		logger.Error("Failed to update job status to decomposed", "error", err)
@@ -550,12 +835,12 @@ func (h *ContainerJobHandler) processArtistJob(ctx context.Context, job *domain.
 	if createdCount == 0 {
 		catalogTracks := make([]domain.CatalogTrack, len(artist.TopTracks))
 		copy(catalogTracks, artist.TopTracks)
-		if genErr := h.PlaylistGenerator.GenerateFromTracks(artist.Name, catalogTracks, lookupTrack(h.Repo)); genErr != nil {
+		if genErr := h.PlaylistGenerator.GenerateFromTracks(artist.Name, catalogTracks, lookupTrackOrDuplicate(h.Repo, duplicates)); genErr != nil {
 			logger.Error("Failed to generate playlist file", "error", genErr)
 		}
 	}
 
-	logger.Info("Artist job completed", "tracks_created", createdCount)
+	logger.Info("Artist job completed", "tracks_created", createdCount, "skipped_short", short)
 	return nil
 }
 
@@ -572,90 +857,261 @@ func (h *ContainerJobHandler) processDiscographyJob(ctx context.Context, job *do
 		return ErrNoTracksFound
 	}
 
+	if imgErr := h.AlbumArtService.DownloadAndSaveArtistImage(artist, string(h.ProviderManager.MetadataProviderType())); imgErr != nil {
+		logger.Warn("Failed to save artist image", "error", imgErr)
+	}
+
+	var releaseTypes []string
+	if h.Config != nil && h.Config.DiscographyReleaseTypes != "" {
+		releaseTypes, _ = config.ParseReleaseTypes(h.Config.DiscographyReleaseTypes)
+	}
+
 	logger.Info("Processing discography", "album_count", len(artist.Albums))
+	seen := make(map[string]bool)
+	var albumJobs []*domain.Job
+	filtered, owned := 0, 0
 	for _, album := range artist.Albums {
-		albumJob := &domain.Job{
-			Type:     domain.JobTypeAlbum,
-			SourceID: sql.NullString{String: album.ID, Valid: true},
+		if seen[album.ID] {
+			continue
 		}
-		if err := h.processAlbumJob(ctx, albumJob, logger); err != nil {
-			logger.Error("Failed to process album", "album_id", album.ID, "error", err)
+		seen[album.ID] = true
+
+		if len(releaseTypes) > 0 && album.AlbumType != "" && !slices.Contains(releaseTypes, strings.ToLower(album.AlbumType)) {
+			filtered++
 			continue
 		}
+		// Only an album with every one of its catalog tracks downloaded is owned; a
+		// single or a track selection from it isn't.
+		if album.TotalTracks > 0 {
+			if tracks, err := h.Repo.ListCompletedTracksByAlbumID(album.ID); err == nil && len(tracks) >= album.TotalTracks {
+				owned++
+				continue
+			}
+		}
+		if active, err := h.Repo.GetActiveJobBySourceID(album.ID, domain.JobTypeAlbum); err == nil && active != nil {
+			owned++
+			continue
+		}
+
+		albumJobs = append(albumJobs, &domain.Job{
+			ID:          uuid.New().String(),
+			Type:        domain.JobTypeAlbum,
+			Status:      domain.JobStatusQueued,
+			SourceID:    sql.NullString{String: album.ID, Valid: true},
+			ParentJobID: sql.NullString{String: job.ID, Valid: true},
+			MinDuration: job.MinDuration,
+			RequestID:   job.RequestID,
+			CreatedAt:   time.Now(),
+			UpdatedAt:   time.Now(),
+		})
+	}
+
+	if len(albumJobs) > 0 {
+		if err := h.Repo.CreateJobBatch(albumJobs); err != nil {
+			logger.Error("Failed to create album jobs", "error", err)
+			_ = h.Repo.UpdateJobError(job.ID, fmt.Sprintf("Failed to create album jobs: %v", err))
+			return err
+		}
 	}
 
 	if err := h.Repo.UpdateJobStatus(job.ID, domain.JobStatusDecomposed, 0); err != nil {
 		logger.Error("Failed to update job status to decomposed", "error", err)
 	}
-	logger.Info("Discography job completed")
+	if len(albumJobs) == 0 {
+		completeContainerJob(h.Repo, job.ID, logger)
+	}
+
+	reportDiscography(h.Repo, job.ID, len(albumJobs), owned, filtered)
+	logger.Debug("Discography job completed",
+		"albums_enqueued", len(albumJobs),
+		"already_downloaded_or_queued", owned,
+		"filtered_by_release_type", filtered,
+	)
 	return nil
 }
 
-func (h *ContainerJobHandler) createTracksAndJobs(parentJobID string, catalogTracks []domain.CatalogTrack, logger *slog.Logger) int {
-	createdCount := 0
-	forceDownload := h.isForceDownload()
+// logArtDownloadError logs why the album art for tagging couldn't be downloaded. A URL that
+// answered with something other than a usable image is a provider quirk, not a failure, so
+// it is only a warning; the track is tagged without a cover either way.
+func logArtDownloadError(logger *slog.Logger, artURL string, err error) {
+	switch {
+	case errors.Is(err, app.ErrNotImage):
+		logger.Warn("Album art URL did not return an image, tagging without cover", "url", artURL, "error", err)
+	case errors.Is(err, app.ErrImageTooLarge):
+		logger.Warn("Album art is too large to embed, tagging without cover", "url", artURL, "error", err)
+	default:
+		logger.Error("Failed to download album art for tagging", "url", artURL, "error", err)
+	}
+}
 
-	var tracksToCreate []*domain.Track
-	var jobsToCreate []*domain.Job
+// minTrackDuration is the length in seconds below which a container job's tracks are
+// skipped: the job's own minimum when it was queued with one, else MIN_TRACK_DURATION_SECS.
+func (h *ContainerJobHandler) minTrackDuration(job *domain.Job) int {
+	if job.MinDuration.Valid {
+		return int(job.MinDuration.Int64)
+	}
+	if h.Config != nil {
+		return h.Config.MinTrackDurationSecs
+	}
+	return 0
+}
 
-	for _, catalogTrack := range catalogTracks {
-		if downloaded, _ := h.Repo.IsTrackDownloaded(catalogTrack.ID); downloaded && !forceDownload {
-			continue
+// selectTracks keeps the tracks whose IDs are in ids, in album order. IDs not on the album
+// are ignored.
+func selectTracks(tracks []domain.CatalogTrack, ids []string) []domain.CatalogTrack {
+	selected := make([]domain.CatalogTrack, 0, len(ids))
+	for _, t := range tracks {
+		if slices.Contains(ids, t.ID) {
+			selected = append(selected, t)
 		}
+	}
+	return selected
+}
 
-		if active, _ := h.Repo.IsTrackActive(catalogTrack.ID); active && !forceDownload {
+// skipShortTracks drops the tracks shorter than minSecs seconds, returning the rest and how
+// many were dropped. Tracks of unknown length are kept, and minSecs 0 keeps every track.
+func skipShortTracks(tracks []domain.CatalogTrack, minSecs int) ([]domain.CatalogTrack, int) {
+	if minSecs <= 0 {
+		return tracks, 0
+	}
+	kept := make([]domain.CatalogTrack, 0, len(tracks))
+	for _, t := range tracks {
+		if t.Duration > 0 && t.Duration < minSecs {
 			continue
 		}
+		kept = append(kept, t)
+	}
+	return kept, len(tracks) - len(kept)
+}
+
+// reportShortTracks records in the job's log how many tracks were skipped for being shorter
+// than minSecs seconds, so the queue shows why the job has fewer tracks than its source.
+func reportShortTracks(repo *store.DB, jobID string, short, minSecs int) {
+	if short == 0 {
+		return
+	}
+	noun := "tracks"
+	if short == 1 {
+		noun = "track"
+	}
+	_ = repo.AddJobEvent(jobID, domain.JobEventWarn, fmt.Sprintf("Skipped %d %s shorter than %d seconds", short, noun, minSecs))
+}
+
+// reportDiscography records in the job's log how many album jobs a discography job queued
+// and how many releases it passed over, so the queue and the job's log socket show what
+// the download will fetch.
+func reportDiscography(repo *store.DB, jobID string, enqueued, owned, filtered int) {
+	noun := "albums"
+	if enqueued == 1 {
+		noun = "album"
+	}
+	msg := fmt.Sprintf("Queued %d %s; %d already downloaded or queued, %d filtered by release type", enqueued, noun, owned, filtered)
+	_ = repo.AddJobEvent(jobID, domain.JobEventInfo, msg)
+}
+
+// createTracksAndJobs queues a track and job for every catalog track not already in the
+// library. The dedup checks and inserts run in one transaction, so a large container job
+// takes the SQLite write lock once and its tracks and jobs commit together. It returns the
+// number of tracks created and, when SKIP_DUPLICATE_ISRC is enabled, the existing tracks
+// that were skipped as duplicates, keyed by the catalog track ID. The track jobs carry
+// the parent's request ID.
+func (h *ContainerJobHandler) createTracksAndJobs(parent *domain.Job, catalogTracks []domain.CatalogTrack, logger *slog.Logger) (int, map[string]*domain.Track) {
+	parentJobID := parent.ID
+	createdCount := 0
+	forceDownload := h.isForceDownload()
+	skipDuplicates := h.Config != nil && h.Config.SkipDuplicateISRC && !forceDownload
+	// The catalog tracks were listed by the metadata provider, so their IDs are its IDs.
+	source := string(h.ProviderManager.MetadataProviderType())
+
+	var duplicates map[string]*domain.Track
+
+	err := h.Repo.RunInTx(func(txDB *store.DB) error {
+		var tracksToCreate []*domain.Track
+		var jobsToCreate []*domain.Job
+		duplicates = make(map[string]*domain.Track)
+
+		for _, catalogTrack := range catalogTracks {
+			if downloaded, _ := txDB.IsTrackDownloaded(catalogTrack.ID); downloaded && !forceDownload {
+				continue
+			}
+
+			if active, _ := txDB.IsTrackActive(catalogTrack.ID); active && !forceDownload {
+				continue
+			}
+
+			if skipDuplicates && catalogTrack.ISRC != "" {
+				if existing, err := txDB.GetTrackByISRC(catalogTrack.ISRC); err == nil && existing != nil {
+					logger.Debug("Skipping duplicate track", "track_id", catalogTrack.ID, "isrc", catalogTrack.ISRC, "existing_track_id", existing.ID)
+					duplicates[catalogTrack.ID] = existing
+					if err := txDB.AddJobDuplicateTrack(parentJobID, catalogTrack.ID, existing.ID); err != nil {
+						return fmt.Errorf("failed to record duplicate track: %w", err)
+					}
+					continue
+				}
+			}
 
-		track := &domain.Track{
-			ProviderID: catalogTrack.ID,
+			track := &domain.Track{
+				ProviderID:     catalogTrack.ID,
+				SourceProvider: source,
+			}
+			h.Enricher.UpdateTrackFromCatalog(track, &catalogTrack, logger)
+			app.NormalizeFeaturedArtists(h.Config, track)
+			track.Status = domain.TrackStatusQueued
+			track.ParentJobID = parentJobID
+			track.CreatedAt = time.Now()
+			track.UpdatedAt = time.Now()
+			tracksToCreate = append(tracksToCreate, track)
+
+			job := &domain.Job{
+				ID:          uuid.New().String(),
+				Type:        domain.JobTypeTrack,
+				Status:      domain.JobStatusQueued,
+				SourceID:    sql.NullString{String: catalogTrack.ID, Valid: true},
+				ParentJobID: sql.NullString{String: parentJobID, Valid: true},
+				RequestID:   parent.RequestID,
+				CreatedAt:   time.Now(),
+				UpdatedAt:   time.Now(),
+			}
+			jobsToCreate = append(jobsToCreate, job)
 		}
-		h.Enricher.UpdateTrackFromCatalog(track, &catalogTrack, logger)
-		track.Status = domain.TrackStatusQueued
-		track.ParentJobID = parentJobID
-		track.CreatedAt = time.Now()
-		track.UpdatedAt = time.Now()
-		tracksToCreate = append(tracksToCreate, track)
 
-		job := &domain.Job{
-			ID:          uuid.New().String(),
-			Type:        domain.JobTypeTrack,
-			Status:      domain.JobStatusQueued,
-			SourceID:    sql.NullString{String: catalogTrack.ID, Valid: true},
-			ParentJobID: sql.NullString{String: parentJobID, Valid: true},
-			CreatedAt:   time.Now(),
-			UpdatedAt:   time.Now(),
+		if len(tracksToCreate) > 0 {
+			n, err := txDB.CreateTrackBatch(tracksToCreate)
+			if err != nil {
+				return fmt.Errorf("failed to create tracks batch: %w", err)
+			}
+			createdCount = n
 		}
-		jobsToCreate = append(jobsToCreate, job)
-	}
 
-	if len(tracksToCreate) > 0 {
-		n, err := h.Repo.CreateTrackBatch(tracksToCreate)
-		if err != nil {
-			logger.Error("Failed to create tracks batch", "error", err)
-		} else {
-			createdCount += n
+		if len(jobsToCreate) > 0 {
+			if err := txDB.CreateJobBatch(jobsToCreate); err != nil {
+				return fmt.Errorf("failed to create jobs batch: %w", err)
+			}
 		}
+		return nil
+	})
+	if err != nil {
+		logger.Error("Failed to queue tracks", "error", err)
+		return 0, duplicates
 	}
 
-	if len(jobsToCreate) > 0 {
-		if err := h.Repo.CreateJobBatch(jobsToCreate); err != nil {
-			logger.Error("Failed to create jobs batch", "error", err)
-			_ = h.Repo.DeletePendingTracksByParentJobID(parentJobID)
-			return 0
-		}
+	if len(duplicates) > 0 {
+		logger.Info("Skipped tracks already downloaded from another release", "duplicates", len(duplicates))
 	}
 
-	return createdCount
+	return createdCount, duplicates
 }
 
 // SyncJobHandler handles all metadata resyncs (Hi-Fi, MusicBrainz, File).
 type SyncJobHandler struct {
 	Repo            *store.DB
+	SettingsRepo    *store.SettingsRepo
 	Config          *config.Config
 	ProviderManager *catalog.ProviderManager
 	AlbumArtService app.AlbumArtService
 	Enricher        *app.MetadataEnricher
+	// MergeStrategy, when set, overrides TAG_MERGE_STRATEGY for the files it re-tags.
+	MergeStrategy tagging.MergeStrategy
 }
 
 func (h *SyncJobHandler) Handle(ctx context.Context, job *domain.Job, logger *slog.Logger) error {
@@ -664,7 +1120,9 @@ func (h *SyncJobHandler) Handle(ctx context.Context, job *domain.Job, logger *sl
 		return h.processSyncMusicBrainzJob(ctx, job, logger)
 	case domain.JobTypeSyncHiFi:
 		return h.processSyncHiFiJob(ctx, job, logger)
-	case domain.JobTypeSyncFile:
+	case domain.JobTypeSyncLyrics:
+		return h.processSyncLyricsJob(ctx, job, logger)
+	case domain.JobTypeSyncFile, domain.JobTypeRetag:
 		return h.processSyncFileJob(ctx, job, logger)
 	default:
 		return ErrUnknownJobType
@@ -673,24 +1131,94 @@ func (h *SyncJobHandler) Handle(ctx context.Context, job *domain.Job, logger *sl
 
 func (h *SyncJobHandler) processSyncHiFiJob(ctx context.Context, job *domain.Job, logger *slog.Logger) error {
 	track, ok := h.getTrackForSync(job, logger)
-	if !ok {
+	if !ok || h.retagLocked(ctx, job, track, logger) {
 		return nil
 	}
 
+	storedQuality := track.AudioQuality
 	h.Enricher.EnrichComplete(ctx, track, logger)
+	app.NormalizeFeaturedArtists(h.Config, track)
 
 	if h.isCancelled(job.ID) {
 		logger.Info("Job cancelled")
 		return nil
 	}
 
-	h.completeSyncBasic(job, track, logger, "Sync Hi-Fi job completed")
+	h.completeSyncBasic(ctx, job, track, logger, "Sync Hi-Fi job completed")
+
+	if h.Config != nil && h.Config.UpgradeQualityOnSync {
+		h.maybeUpgradeQuality(track, storedQuality, h.Enricher.AdvertisedQuality(ctx, track, logger), logger)
+	}
+	return nil
+}
+
+// maybeUpgradeQuality queues a re-download of the track when the provider now reports a
+// better quality than the one it was stored at, and counts the outcome. A provider that
+// over-advertises a track is only taken at its word once: after an upgrade to a quality
+// completed, the track isn't queued for that quality again.
+func (h *SyncJobHandler) maybeUpgradeQuality(track *domain.Track, storedQuality, reported string, logger *slog.Logger) {
+	qualities := downloadQualities(h.SettingsRepo, h.Config)
+	upgraded := app.IsQualityUpgrade(storedQuality, reported, qualities)
+	if upgraded {
+		// The provider's quality, capped at the preferred one.
+		target := reported
+		if len(qualities) > 0 && config.QualityRank(qualities[0]) < config.QualityRank(target) {
+			target = qualities[0]
+		}
+		if tried, _ := h.Repo.HasCompletedUpgrade(track.ProviderID, target); tried {
+			logger.Info("Skipped quality upgrade the provider already failed to deliver", "stored", storedQuality, "reported", reported)
+			upgraded = false
+		} else if active, _ := h.Repo.IsTrackActive(track.ProviderID); !active {
+			job := &domain.Job{
+				ID:        uuid.New().String(),
+				Type:      domain.JobTypeUpgrade,
+				Status:    domain.JobStatusQueued,
+				SourceID:  sql.NullString{String: track.ProviderID, Valid: true},
+				Quality:   sql.NullString{String: target, Valid: true},
+				CreatedAt: time.Now(),
+				UpdatedAt: time.Now(),
+			}
+			if err := h.Repo.CreateJob(job); err != nil {
+				logger.Error("Failed to queue quality upgrade", "error", err)
+				return
+			}
+			logger.Info("Queued quality upgrade", "from", storedQuality, "to", target)
+		}
+	}
+
+	if h.SettingsRepo != nil {
+		if err := h.SettingsRepo.RecordSyncUpgrade(upgraded); err != nil {
+			logger.Warn("Failed to record sync upgrade", "error", err)
+		}
+	}
+}
+
+// processSyncLyricsJob refetches only the lyrics of a track and re-tags the file when
+// they changed.
+func (h *SyncJobHandler) processSyncLyricsJob(ctx context.Context, job *domain.Job, logger *slog.Logger) error {
+	track, ok := h.getTrackForSync(job, logger)
+	if !ok || h.retagLocked(ctx, job, track, logger) {
+		return nil
+	}
+
+	if !h.Enricher.RefetchLyrics(ctx, track, logger) {
+		_ = h.Repo.UpdateJobStatus(job.ID, domain.JobStatusCompleted, 100)
+		logger.Info("No new lyrics found")
+		return nil
+	}
+
+	if h.isCancelled(job.ID) {
+		logger.Info("Job cancelled")
+		return nil
+	}
+
+	h.completeSyncBasic(ctx, job, track, logger, "Sync lyrics job completed")
 	return nil
 }
 
 func (h *SyncJobHandler) processSyncMusicBrainzJob(ctx context.Context, job *domain.Job, logger *slog.Logger) error {
 	track, ok := h.getTrackForSync(job, logger)
-	if !ok {
+	if !ok || h.retagLocked(ctx, job, track, logger) {
 		return nil
 	}
 
@@ -703,7 +1231,7 @@ func (h *SyncJobHandler) processSyncFileJob(ctx context.Context, job *domain.Job
 	if !ok {
 		return nil
 	}
-	h.completeSyncBasic(job, track, logger, "Sync file job completed")
+	h.completeSyncBasic(ctx, job, track, logger, "Sync file job completed")
 	return nil
 }
 
@@ -726,6 +1254,17 @@ func (h *SyncJobHandler) getTrackForSync(job *domain.Job, logger *slog.Logger) (
 	return track, true
 }
 
+// retagLocked re-tags a locked track from its stored metadata instead of fetching any, so a
+// sync doesn't undo edits made by hand. It reports whether the track was locked.
+func (h *SyncJobHandler) retagLocked(ctx context.Context, job *domain.Job, track *domain.Track, logger *slog.Logger) bool {
+	if !track.Locked {
+		return false
+	}
+	logger.Info("Track is locked, skipping metadata fetch")
+	h.completeSyncBasic(ctx, job, track, logger, "Sync job completed from locked metadata")
+	return true
+}
+
 func (h *SyncJobHandler) isCancelled(id string) bool {
 	job, err := h.Repo.GetJob(id)
 	if err != nil {
@@ -734,10 +1273,10 @@ func (h *SyncJobHandler) isCancelled(id string) bool {
 	return job.Status == domain.JobStatusCancelled
 }
 
-func (h *SyncJobHandler) completeSyncBasic(job *domain.Job, track *domain.Track, logger *slog.Logger, successMsg string) {
+func (h *SyncJobHandler) completeSyncBasic(ctx context.Context, job *domain.Job, track *domain.Track, logger *slog.Logger, successMsg string) {
 	oldFilePath := track.FilePath
 
-	if err := h.reTagTrack(track, logger); err != nil {
+	if err := h.reTagTrack(ctx, track, logger); err != nil {
 		_ = h.Repo.UpdateJobError(job.ID, fmt.Sprintf("Failed to tag file: %v", err))
 		return
 	}
@@ -770,7 +1309,7 @@ func (h *SyncJobHandler) completeSyncWithEnrichment(ctx context.Context, job *do
 		return
 	}
 
-	if err := h.reTagTrack(track, logger); err != nil {
+	if err := h.reTagTrack(ctx, track, logger); err != nil {
 		_ = h.Repo.UpdateJobError(job.ID, fmt.Sprintf("Failed to tag file: %v", err))
 		return
 	}
@@ -791,14 +1330,17 @@ func (h *SyncJobHandler) completeSyncWithEnrichment(ctx context.Context, job *do
 	logger.Info(successMsg)
 }
 
-func (h *SyncJobHandler) reTagTrack(track *domain.Track, logger *slog.Logger) error {
+func (h *SyncJobHandler) reTagTrack(ctx context.Context, track *domain.Track, logger *slog.Logger) error {
+	applyAlbumOverride(h.Repo, track, logger)
+
 	var albumArtData []byte
 
 	if track.FilePath != "" {
 		albumDir := filepath.Dir(track.FilePath)
-		coverPath := filepath.Join(albumDir, "cover.jpg")
-		if data, err := os.ReadFile(coverPath); err == nil && len(data) > 0 { //nolint:gosec
-			albumArtData = data
+		if coverPath := storage.FindCover(albumDir); coverPath != "" {
+			if data, err := os.ReadFile(coverPath); err == nil && len(data) > 0 { //nolint:gosec
+				albumArtData = data
+			}
 		}
 	}
 
@@ -806,11 +1348,16 @@ func (h *SyncJobHandler) reTagTrack(track *domain.Track, logger *slog.Logger) er
 		var err error
 		albumArtData, err = h.AlbumArtService.DownloadImage(track.AlbumArtURL)
 		if err != nil {
-			logger.Error("Failed to download album art for tagging", "error", err)
+			logArtDownloadError(logger, track.AlbumArtURL, err)
 		}
 	}
 
-	if tagErr := tagging.TagFile(track.FilePath, track, albumArtData); tagErr != nil {
+	pictures := fetchExtraPictures(ctx, h.Config, h.ProviderManager, h.AlbumArtService, track, logger)
+	opts := tagOptions(h.Config, albumArtData, pictures)
+	if h.MergeStrategy != "" {
+		opts.MergeStrategy = h.MergeStrategy
+	}
+	if tagErr := tagging.TagFile(track.FilePath, track, opts); tagErr != nil {
 		if errors.Is(tagErr, tagging.ErrUnsupportedFormat) {
 			logger.Warn("Tagging skipped: unsupported format", "file_path", track.FilePath, "error", tagErr)
 			return nil
@@ -818,94 +1365,293 @@ func (h *SyncJobHandler) reTagTrack(track *domain.Track, logger *slog.Logger) er
 		logger.Error("Failed to tag file", "error", tagErr)
 		return tagErr
 	}
+
+	if h.Config != nil && h.Config.WriteLrcSidecar {
+		if lrcErr := tagging.WriteLyricsSidecar(track.FilePath, track); lrcErr != nil {
+			logger.Error("Failed to write lyrics sidecar", "file_path", track.FilePath, "error", lrcErr)
+		}
+	}
 	return nil
 }
 
+// applyAlbumOverride credits track to the album artist set for its album, if one is.
+func applyAlbumOverride(repo *store.DB, track *domain.Track, logger *slog.Logger) {
+	if repo == nil || track.AlbumID == "" {
+		return
+	}
+	albumArtist, err := repo.GetAlbumArtistOverride(track.AlbumID)
+	if err != nil {
+		logger.Warn("Failed to get album artist override", "album_id", track.AlbumID, "error", err)
+		return
+	}
+	app.ApplyAlbumArtistOverride(track, albumArtist)
+}
+
 func (h *SyncJobHandler) maybeMoveTrackFile(track *domain.Track, oldFilePath string, logger *slog.Logger) error {
-	if oldFilePath == "" {
-		return nil
+	return app.RelocateTrackFile(h.Config, track, oldFilePath, logger)
+}
+
+func (h *TrackJobHandler) isForceDownload() bool {
+	if h.SettingsRepo == nil {
+		return false
 	}
+	val, err := h.SettingsRepo.Get(store.SettingForceDownload)
+	return err == nil && val == "true"
+}
 
-	oldDir := filepath.Dir(oldFilePath)
+func (h *TrackJobHandler) getQualities() []string {
+	return downloadQualities(h.SettingsRepo, h.Config)
+}
+
+// jobQualities returns the qualities to try for the job, best first: for an upgrade job,
+// the quality it targets and then the preferred qualities below it.
+func (h *TrackJobHandler) jobQualities(job *domain.Job) []string {
+	qualities := h.getQualities()
+	if !job.Quality.Valid || job.Quality.String == "" {
+		return qualities
+	}
+	target := job.Quality.String
+	rank := config.QualityRank(target)
+	lower := slices.DeleteFunc(slices.Clone(qualities), func(q string) bool { return config.QualityRank(q) >= rank })
+	return append([]string{target}, lower...)
+}
 
-	artistForFolder := track.PathArtist
-	if artistForFolder == "" {
-		artistForFolder = track.AlbumArtist
+// downloadQualities returns the download quality preference list: the runtime setting when
+// set and valid, otherwise QUALITY.
+func downloadQualities(settings *store.SettingsRepo, cfg *config.Config) []string {
+	if settings != nil {
+		if val, err := settings.Get(store.SettingQuality); err == nil && val != "" {
+			if qualities, parseErr := config.ParseQualities(val); parseErr == nil {
+				return qualities
+			}
+		}
 	}
-	if artistForFolder == "" {
-		artistForFolder = track.Artist
+	qualities, _ := config.ParseQualities(cfg.Quality)
+	return qualities
+}
+
+func (h *ContainerJobHandler) isForceDownload() bool {
+	if h.SettingsRepo == nil {
+		return false
 	}
+	val, err := h.SettingsRepo.Get(store.SettingForceDownload)
+	return err == nil && val == "true"
+}
 
-	templateData := storage.BuildPathTemplateData(
-		artistForFolder,
-		track.Year,
-		track.Album,
-		track.DiscNumber,
-		track.TrackNumber,
-		track.Title,
-	)
+// VerifyJobHandler re-hashes downloaded files to detect bit rot or external changes.
+type VerifyJobHandler struct {
+	Repo         *store.DB
+	SettingsRepo *store.SettingsRepo
+	Verifier     *app.LibraryVerifier
+}
+
+func (h *VerifyJobHandler) Handle(ctx context.Context, job *domain.Job, logger *slog.Logger) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
 
-	expectedPath, err := storage.BuildFullPath(h.Config.DownloadsDir, h.Config.SubdirTemplate, templateData, track.FileExtension)
+	onProgress := func(done, total int) {
+		if done%constants.VerifyProgressInterval != 0 && done != total {
+			return
+		}
+		if h.isCancelled(job.ID) {
+			cancel()
+			return
+		}
+		_ = h.Repo.UpdateJobProgress(job.ID, float64(done)/float64(total)*100)
+	}
+
+	summary, err := h.Verifier.Verify(ctx, job.GetSourceID(), onProgress)
 	if err != nil {
-		logger.Error("Failed to build expected path", "error", err)
+		if errors.Is(err, context.Canceled) {
+			logger.Info("Job cancelled")
+			return nil
+		}
+		_ = h.Repo.UpdateJobError(job.ID, fmt.Sprintf("Verification failed: %v", err))
 		return err
 	}
 
-	if oldFilePath == expectedPath {
-		return nil
+	if data, err := json.Marshal(summary); err == nil {
+		if err := h.SettingsRepo.Set(store.SettingVerifySummary, string(data)); err != nil {
+			logger.Warn("Failed to save verify summary", "error", err)
+		}
 	}
 
-	track.FilePath = expectedPath
-	newDir := filepath.Dir(track.FilePath)
+	_ = h.Repo.UpdateJobStatus(job.ID, domain.JobStatusCompleted, 100)
+	logger.Info("Verify job completed", "scope", summary.Scope, "ok", summary.OK, "changed", summary.Changed, "missing", summary.Missing)
+	return nil
+}
 
-	if err := os.MkdirAll(newDir, constants.DirPermissions); err != nil {
-		logger.Error("Failed to create new directory", "dir", newDir, "error", err)
-		return err
+func (h *VerifyJobHandler) isCancelled(id string) bool {
+	job, err := h.Repo.GetJob(id)
+	if err != nil {
+		return false
 	}
+	return job.Status == domain.JobStatusCancelled
+}
 
-	if err := storage.MoveFile(oldFilePath, track.FilePath); err != nil {
-		logger.Error("Failed to move audio file", "old", oldFilePath, "new", track.FilePath, "error", err)
-		return err
-	}
+// ReorganizeJobHandler moves downloaded files to the paths the current SUBDIR_TEMPLATE
+// gives them. The worker runs it with the rest of the queue paused.
+type ReorganizeJobHandler struct {
+	Repo        *store.DB
+	Reorganizer *app.LibraryReorganizer
+}
+
+func (h *ReorganizeJobHandler) Handle(ctx context.Context, job *domain.Job, logger *slog.Logger) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
 
-	oldCoverPath := filepath.Join(oldDir, "cover.jpg")
-	newCoverPath := filepath.Join(newDir, "cover.jpg")
-	if _, err := os.Stat(oldCoverPath); err == nil {
-		if err := storage.CopyFile(oldCoverPath, newCoverPath); err != nil {
-			logger.Warn("Failed to copy cover file", "old", oldCoverPath, "new", newCoverPath, "error", err)
+	onProgress := func(done, total int) {
+		if done%constants.ReorganizeProgressInterval != 0 && done != total {
+			return
+		}
+		if h.isCancelled(job.ID) {
+			cancel()
+			return
 		}
+		_ = h.Repo.UpdateJobProgress(job.ID, float64(done)/float64(total)*100)
 	}
 
-	if err := storage.DeleteFolderWithCover(oldDir); err != nil {
-		logger.Warn("Failed to clean up old directory", "dir", oldDir, "error", err)
+	summary, err := h.Reorganizer.Reorganize(ctx, logger, onProgress)
+	if err != nil {
+		if errors.Is(err, context.Canceled) {
+			logger.Info("Job cancelled")
+			return nil
+		}
+		_ = h.Repo.UpdateJobError(job.ID, fmt.Sprintf("Reorganize failed: %v", err))
+		return err
 	}
 
+	_ = h.Repo.UpdateJobStatus(job.ID, domain.JobStatusCompleted, 100)
+	logger.Info("Reorganize job completed", "moved", summary.Moved, "conflicts", summary.Conflicts, "failed", summary.Failed)
 	return nil
 }
 
-func (h *TrackJobHandler) isForceDownload() bool {
-	if h.SettingsRepo == nil {
+func (h *ReorganizeJobHandler) isCancelled(id string) bool {
+	job, err := h.Repo.GetJob(id)
+	if err != nil {
 		return false
 	}
-	val, err := h.SettingsRepo.Get(store.SettingForceDownload)
-	return err == nil && val == "true"
+	return job.Status == domain.JobStatusCancelled
 }
 
-func (h *TrackJobHandler) getQuality() string {
-	if h.SettingsRepo == nil {
-		return h.Config.Quality
+// ImportJobHandler brings existing local files under management without downloading them.
+// Each file's tags seed a track, which is enriched from MusicBrainz by its ISRC or
+// recording ID, re-tagged in place the way a sync re-tags downloads, and recorded as a
+// completed download.
+type ImportJobHandler struct {
+	Repo   *store.DB
+	Config *config.Config
+	// Sync re-tags the imported files. Its MergeStrategy should be fill-missing, so tags
+	// a track has no field for survive the import.
+	Sync *SyncJobHandler
+}
+
+func (h *ImportJobHandler) Handle(ctx context.Context, job *domain.Job, logger *slog.Logger) error {
+	files, err := app.ImportFiles(job.GetSourceID())
+	if err != nil {
+		_ = h.Repo.UpdateJobError(job.ID, fmt.Sprintf("Import failed: %v", err))
+		return err
 	}
-	val, err := h.SettingsRepo.Get(store.SettingQuality)
-	if err == nil && val != "" {
-		return val
+
+	var imported, skipped, failed int
+	for i, path := range files {
+		if h.Sync.isCancelled(job.ID) {
+			logger.Info("Job cancelled")
+			return nil
+		}
+		ok, err := h.importFile(ctx, path, logger)
+		switch {
+		case err != nil:
+			logger.Warn("Failed to import file", "file_path", path, "error", err)
+			failed++
+		case ok:
+			imported++
+		default:
+			skipped++
+		}
+		_ = h.Repo.UpdateJobProgress(job.ID, float64(i+1)/float64(len(files))*100)
 	}
-	return h.Config.Quality
+
+	if imported == 0 && failed > 0 {
+		err := fmt.Errorf("none of the %d files could be imported", failed)
+		_ = h.Repo.UpdateJobError(job.ID, fmt.Sprintf("Import failed: %v", err))
+		return err
+	}
+
+	_ = h.Repo.UpdateJobStatus(job.ID, domain.JobStatusCompleted, 100)
+	logger.Info("Import job completed", "imported", imported, "skipped", skipped, "failed", failed)
+	return nil
 }
 
-func (h *ContainerJobHandler) isForceDownload() bool {
-	if h.SettingsRepo == nil {
-		return false
+// importFile records the file at path as a completed track. A file a download already
+// put in the library is left alone; one imported before is updated, from its stored
+// metadata when the track is locked. It reports whether the file was imported.
+func (h *ImportJobHandler) importFile(ctx context.Context, path string, logger *slog.Logger) (bool, error) {
+	existing, err := h.Repo.GetTrackByFilePath(path)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return false, fmt.Errorf("failed to look up track: %w", err)
 	}
-	val, err := h.SettingsRepo.Get(store.SettingForceDownload)
-	return err == nil && val == "true"
+	if existing != nil && existing.SourceProvider != domain.ImportSourceProvider {
+		logger.Debug("File is already in the library", "file_path", path)
+		return false, nil
+	}
+
+	track := existing
+	if track == nil || !track.Locked {
+		if track, err = tagging.ReadTags(path); err != nil {
+			return false, fmt.Errorf("failed to read tags: %w", err)
+		}
+		if track.Title == "" {
+			track.Title = strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+		}
+		track.ProviderID = importProviderID(path)
+		track.SourceProvider = domain.ImportSourceProvider
+		track.FilePath = path
+		if existing != nil {
+			track.ID = existing.ID
+			track.CreatedAt = existing.CreatedAt
+		}
+
+		if h.Config != nil && h.Config.MusicBrainzEnrichmentFor(domain.ImportSourceProvider) {
+			if err := h.Sync.Enricher.EnrichTrack(ctx, track, logger); err != nil {
+				logger.Warn("MusicBrainz enrichment failed, continuing with the file's tags", "file_path", path, "error", err)
+			}
+		}
+	}
+
+	if err := h.Sync.reTagTrack(ctx, track, logger); err != nil {
+		return false, fmt.Errorf("failed to tag file: %w", err)
+	}
+
+	fileHash, err := storage.HashFile(path)
+	if err != nil {
+		logger.Error("Failed to hash file", "error", err)
+	}
+	track.FileExtension = app.TrackExtension(filepath.Ext(path), app.FallbackExtension(h.Config))
+	readAudioProperties(track, path, logger)
+	app.ReconcileAudioQuality(track, logger)
+	track.Status = domain.TrackStatusCompleted
+	track.FileHash = fileHash
+	now := time.Now()
+	track.CompletedAt = &now
+	track.LastVerifiedAt = &now
+	track.DeletedAt = nil
+	track.UpdatedAt = now
+
+	if track.ID == 0 {
+		err = h.Repo.CreateTrack(track)
+	} else {
+		err = h.Repo.UpdateTrack(track)
+	}
+	if err != nil {
+		return false, fmt.Errorf("failed to save track: %w", err)
+	}
+	return true, nil
+}
+
+// importProviderID derives an imported track's provider ID from its path, so importing
+// the same file again updates its track.
+func importProviderID(path string) string {
+	sum := sha256.Sum256([]byte(path))
+	return domain.ImportSourceProvider + ":" + hex.EncodeToString(sum[:8])
 }
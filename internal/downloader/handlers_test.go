@@ -0,0 +1,1276 @@
+package downloader
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"image"
+	"image/png"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/go-flac/flacpicture"
+	"github.com/go-flac/flacvorbis"
+	"github.com/go-flac/go-flac"
+
+	"github.com/cesargomez89/navidrums/internal/app"
+	"github.com/cesargomez89/navidrums/internal/catalog"
+	"github.com/cesargomez89/navidrums/internal/config"
+	"github.com/cesargomez89/navidrums/internal/constants"
+	"github.com/cesargomez89/navidrums/internal/domain"
+	"github.com/cesargomez89/navidrums/internal/logger"
+	"github.com/cesargomez89/navidrums/internal/musicbrainz"
+	"github.com/cesargomez89/navidrums/internal/storage"
+	"github.com/cesargomez89/navidrums/internal/store"
+	"github.com/cesargomez89/navidrums/internal/tagging"
+)
+
+func setupTestDB(t *testing.T) (*store.DB, func()) {
+	db, err := store.NewSQLiteDB(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("Failed to open db: %v", err)
+	}
+	cleanup := func() {
+		if cErr := db.Close(); cErr != nil {
+			t.Logf("db.Close error: %v", cErr)
+		}
+	}
+	return db, cleanup
+}
+
+// rendezvous lets two fetches check they overlap: each signals its start and waits a
+// while for the other's.
+type rendezvous struct {
+	art, lyrics chan struct{}
+}
+
+func (r *rendezvous) meet(mine, theirs chan struct{}) bool {
+	close(mine)
+	select {
+	case <-theirs:
+		return true
+	case <-time.After(2 * time.Second):
+		return false
+	}
+}
+
+// artService returns fixed album art; any other AlbumArtService method panics.
+type artService struct {
+	app.AlbumArtService
+	data       []byte
+	err        error
+	meet       *rendezvous
+	overlapped bool
+}
+
+func (s *artService) DownloadImage(url string) ([]byte, error) {
+	if s.meet != nil {
+		s.overlapped = s.meet.meet(s.meet.art, s.meet.lyrics)
+	}
+	return s.data, s.err
+}
+
+type lyricsSource struct {
+	lyrics     string
+	err        error
+	meet       *rendezvous
+	overlapped bool
+}
+
+func (s *lyricsSource) GetLyrics(ctx context.Context, track, artist, album string, duration int) (string, string, error) {
+	if s.meet != nil {
+		s.overlapped = s.meet.meet(s.meet.lyrics, s.meet.art)
+	}
+	return s.lyrics, "", s.err
+}
+
+func (s *lyricsSource) Name() string { return "test" }
+
+func TestTrackJobHandler_FetchTagAssets(t *testing.T) {
+	art := []byte("art")
+	tests := []struct {
+		name       string
+		artErr     error
+		lyricsErr  error
+		concurrent bool
+		wantArt    []byte
+		wantLyrics string
+	}{
+		{"fetched concurrently", nil, nil, true, art, "Lyrics"},
+		{"art fails", errors.New("cover unavailable"), nil, false, nil, "Lyrics"},
+		{"lyrics fail", nil, errors.New("lyrics unavailable"), false, art, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var meet *rendezvous
+			if tt.concurrent {
+				meet = &rendezvous{art: make(chan struct{}), lyrics: make(chan struct{})}
+			}
+			artSvc := &artService{err: tt.artErr, meet: meet}
+			if tt.artErr == nil {
+				artSvc.data = art
+			}
+			lyrics := &lyricsSource{err: tt.lyricsErr, meet: meet}
+			if tt.lyricsErr == nil {
+				lyrics.lyrics = "Lyrics"
+			}
+
+			log := logger.Default()
+			// No catalog providers are configured, so lyrics come from the fallback.
+			pm := catalog.NewProviderManager(nil, nil, 0, "", log)
+			h := &TrackJobHandler{
+				Config:          &config.Config{},
+				AlbumArtService: artSvc,
+				Enricher:        app.NewMetadataEnricher(nil, pm, app.NewLyricsFallback(true, []catalog.LyricsProvider{lyrics})),
+			}
+			track := &domain.Track{ProviderID: "1", Title: "Song", Artist: "Artist", AlbumArtURL: "https://example.com/cover.jpg"}
+
+			gotArt, artPath, _ := h.fetchTagAssets(context.Background(), track, t.TempDir(), log.Logger)
+			if string(gotArt) != string(tt.wantArt) {
+				t.Errorf("album art = %q, want %q", gotArt, tt.wantArt)
+			}
+			if artPath != "" {
+				t.Errorf("artPath = %q, want empty for downloaded art", artPath)
+			}
+			if track.Lyrics != tt.wantLyrics {
+				t.Errorf("Lyrics = %q, want %q", track.Lyrics, tt.wantLyrics)
+			}
+			if tt.concurrent && (!artSvc.overlapped || !lyrics.overlapped) {
+				t.Error("album art and lyrics were fetched one after the other")
+			}
+		})
+	}
+}
+
+// unavailableDownloader fails every download the way a region-locked track does.
+type unavailableDownloader struct{}
+
+func (unavailableDownloader) Download(ctx context.Context, track *domain.Track, destPathNoExt string, qualities []string, logger *slog.Logger) (string, error) {
+	return "", fmt.Errorf("all providers failed for GetStream: %w", catalog.ErrTrackUnavailable)
+}
+
+func TestTrackJobHandler_ExecuteDownloadUnavailable(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	track := &domain.Track{ProviderID: "t1", Title: "Song", Status: domain.TrackStatusQueued}
+	if err := db.CreateTrack(track); err != nil {
+		t.Fatalf("CreateTrack failed: %v", err)
+	}
+	job := &domain.Job{ID: "j1", Type: domain.JobTypeTrack, Status: domain.JobStatusRunning, SourceID: sql.NullString{String: "t1", Valid: true}, CreatedAt: time.Now(), UpdatedAt: time.Now()}
+	if err := db.CreateJob(job); err != nil {
+		t.Fatalf("CreateJob failed: %v", err)
+	}
+
+	h := &TrackJobHandler{Repo: db, Downloader: unavailableDownloader{}, Config: &config.Config{}}
+	_, err := h.executeDownload(context.Background(), job, track, filepath.Join(t.TempDir(), "Song"), nil, logger.Default().Logger)
+	if !errors.Is(err, catalog.ErrTrackUnavailable) {
+		t.Fatalf("executeDownload() error = %v, want ErrTrackUnavailable", err)
+	}
+
+	got, err := db.GetTrackByID(track.ID)
+	if err != nil {
+		t.Fatalf("GetTrackByID failed: %v", err)
+	}
+	if got.Status != domain.TrackStatusUnavailable || got.Error == "" {
+		t.Errorf("track status = %q, error = %q, want unavailable with a message", got.Status, got.Error)
+	}
+
+	failed, err := db.ListFailedTracks()
+	if err != nil {
+		t.Fatalf("ListFailedTracks failed: %v", err)
+	}
+	if len(failed) != 0 {
+		t.Errorf("ListFailedTracks() = %d tracks, want unavailable tracks left out of retries", len(failed))
+	}
+}
+
+func TestKeepExistingFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "Song.flac")
+	if err := os.WriteFile(path, []byte("retagged by hand"), 0o644); err != nil {
+		t.Fatalf("WriteFile failed: %v", err)
+	}
+	current, err := storage.HashFile(path)
+	if err != nil {
+		t.Fatalf("HashFile failed: %v", err)
+	}
+
+	tests := []struct {
+		name     string
+		mode     string
+		hash     string
+		want     bool
+		wantHash string
+	}{
+		{"verify-hash keeps a matching file", constants.ExistingFileVerifyHash, current, true, current},
+		{"verify-hash replaces a changed file", constants.ExistingFileVerifyHash, "stale", false, "stale"},
+		{"verify-hash records a missing hash", constants.ExistingFileVerifyHash, "", true, current},
+		{"skip keeps a changed file", constants.ExistingFileSkip, "stale", true, current},
+		{"skip keeps a matching file", constants.ExistingFileSkip, current, true, current},
+		{"overwrite replaces a matching file", constants.ExistingFileOverwrite, current, false, current},
+		{"overwrite replaces an unhashed file", constants.ExistingFileOverwrite, "", false, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			track := &domain.Track{FileHash: tt.hash}
+			if got := keepExistingFile(tt.mode, track, path); got != tt.want {
+				t.Errorf("keepExistingFile() = %v, want %v", got, tt.want)
+			}
+			if track.FileHash != tt.wantHash {
+				t.Errorf("FileHash = %q, want %q", track.FileHash, tt.wantHash)
+			}
+		})
+	}
+}
+
+func TestTrackJobHandler_PostProcessTrackEmbedCoverArt(t *testing.T) {
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, image.NewGray(image.Rect(0, 0, 4, 4))); err != nil {
+		t.Fatalf("failed to encode PNG: %v", err)
+	}
+	art := buf.Bytes()
+	tests := []struct {
+		name         string
+		embed        bool
+		wantPictures int
+	}{
+		{"embedded", true, 1},
+		{"external only", false, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			path := filepath.Join(dir, "Song.flac")
+			f := &flac.File{
+				Meta:   []*flac.MetaDataBlock{{Type: flac.StreamInfo, Data: make([]byte, 34)}},
+				Frames: []byte{0xFF, 0xF8, 0x00, 0x00},
+			}
+			if err := f.Save(path); err != nil {
+				t.Fatalf("failed to write FLAC: %v", err)
+			}
+
+			db, cleanup := setupTestDB(t)
+			defer cleanup()
+
+			log := logger.Default()
+			pm := catalog.NewProviderManager(nil, nil, 0, "", log)
+			h := &TrackJobHandler{
+				Repo:            db,
+				Config:          &config.Config{EmbedCoverArt: tt.embed},
+				AlbumArtService: &artService{data: art},
+				Enricher:        app.NewMetadataEnricher(nil, pm, app.NewLyricsFallback(false, nil)),
+			}
+			track := &domain.Track{ProviderID: "1", Title: "Song", Artist: "Artist", AlbumArtURL: "https://example.com/cover.png"}
+			if err := db.CreateTrack(track); err != nil {
+				t.Fatalf("CreateTrack failed: %v", err)
+			}
+			if err := h.postProcessTrack(context.Background(), track, path, log.Logger); err != nil {
+				t.Fatalf("postProcessTrack failed: %v", err)
+			}
+
+			parsed, err := flac.ParseFile(path)
+			if err != nil {
+				t.Fatalf("failed to parse FLAC: %v", err)
+			}
+			pictures := 0
+			for _, b := range parsed.Meta {
+				if b.Type == flac.Picture {
+					pictures++
+				}
+			}
+			if pictures != tt.wantPictures {
+				t.Errorf("PICTURE blocks = %d, want %d", pictures, tt.wantPictures)
+			}
+			if cover := storage.FindCover(dir); cover == "" {
+				t.Error("external cover was not written")
+			}
+		})
+	}
+}
+
+func TestTrackJobHandler_PostProcessTrackWebPCover(t *testing.T) {
+	if _, err := exec.LookPath("ffmpeg"); err != nil {
+		t.Skip("ffmpeg not installed")
+	}
+	webp, err := base64.StdEncoding.DecodeString("UklGRhoAAABXRUJQVlA4TA0AAAAvAAAAEAcQERGIiP4HAA==")
+	if err != nil {
+		t.Fatalf("failed to decode WebP: %v", err)
+	}
+
+	dir := t.TempDir()
+	path := filepath.Join(dir, "Song.flac")
+	f := &flac.File{
+		Meta:   []*flac.MetaDataBlock{{Type: flac.StreamInfo, Data: make([]byte, 34)}},
+		Frames: []byte{0xFF, 0xF8, 0x00, 0x00},
+	}
+	if err := f.Save(path); err != nil {
+		t.Fatalf("failed to write FLAC: %v", err)
+	}
+
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	log := logger.Default()
+	pm := catalog.NewProviderManager(nil, nil, 0, "", log)
+	h := &TrackJobHandler{
+		Repo:            db,
+		Config:          &config.Config{EmbedCoverArt: true},
+		AlbumArtService: &artService{data: webp},
+		Enricher:        app.NewMetadataEnricher(nil, pm, app.NewLyricsFallback(false, nil)),
+	}
+	track := &domain.Track{ProviderID: "1", Title: "Song", Artist: "Artist", AlbumArtURL: "https://example.com/cover.webp"}
+	if err := db.CreateTrack(track); err != nil {
+		t.Fatalf("CreateTrack failed: %v", err)
+	}
+	if err := h.postProcessTrack(context.Background(), track, path, log.Logger); err != nil {
+		t.Fatalf("postProcessTrack failed: %v", err)
+	}
+
+	parsed, err := flac.ParseFile(path)
+	if err != nil {
+		t.Fatalf("failed to parse FLAC: %v", err)
+	}
+	var pic *flacpicture.MetadataBlockPicture
+	for _, b := range parsed.Meta {
+		if b.Type == flac.Picture {
+			if pic, err = flacpicture.ParseFromMetaDataBlock(*b); err != nil {
+				t.Fatalf("failed to parse PICTURE: %v", err)
+			}
+		}
+	}
+	if pic == nil {
+		t.Fatal("no PICTURE block embedded")
+	}
+	if pic.MIME != constants.MimeTypeJPEG {
+		t.Errorf("embedded MIME = %q, want %q", pic.MIME, constants.MimeTypeJPEG)
+	}
+	if _, format, err := image.Decode(bytes.NewReader(pic.ImageData)); err != nil || format != "jpeg" {
+		t.Errorf("embedded picture format = %q, error %v, want a valid JPEG", format, err)
+	}
+}
+
+func TestEmbeddableArt(t *testing.T) {
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, image.NewGray(image.Rect(0, 0, 4, 4))); err != nil {
+		t.Fatalf("failed to encode PNG: %v", err)
+	}
+	art := buf.Bytes()
+	log := logger.Default().Logger
+
+	if got := embeddableArt(&config.Config{}, art, false, log); !bytes.Equal(got, art) {
+		t.Error("embeddableArt() converted a PNG without force")
+	}
+	got := embeddableArt(&config.Config{CoverArtJPEGQuality: 80}, art, true, log)
+	if mime := http.DetectContentType(got); mime != constants.MimeTypeJPEG {
+		t.Errorf("embeddableArt() with force gave %s, want %s", mime, constants.MimeTypeJPEG)
+	}
+}
+
+func TestSkipShortTracks(t *testing.T) {
+	tracks := []domain.CatalogTrack{
+		{ID: "intro", Duration: 8},
+		{ID: "song", Duration: 215},
+		{ID: "skit", Duration: 29},
+		{ID: "unknown"},
+		{ID: "edge", Duration: 30},
+	}
+
+	tests := []struct {
+		name        string
+		minSecs     int
+		wantIDs     []string
+		wantSkipped int
+	}{
+		{"disabled", 0, []string{"intro", "song", "skit", "unknown", "edge"}, 0},
+		{"short tracks excluded", 30, []string{"song", "unknown", "edge"}, 2},
+		{"everything with a length too short", 300, []string{"unknown"}, 4},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			kept, skipped := skipShortTracks(tracks, tt.minSecs)
+			var ids []string
+			for _, k := range kept {
+				ids = append(ids, k.ID)
+			}
+			if fmt.Sprint(ids) != fmt.Sprint(tt.wantIDs) || skipped != tt.wantSkipped {
+				t.Errorf("skipShortTracks() = %v, %d skipped, want %v, %d", ids, skipped, tt.wantIDs, tt.wantSkipped)
+			}
+		})
+	}
+}
+
+func TestContainerJobHandler_MinTrackDuration(t *testing.T) {
+	h := &ContainerJobHandler{Config: &config.Config{MinTrackDurationSecs: 60}}
+
+	tests := []struct {
+		name string
+		job  *domain.Job
+		want int
+	}{
+		{"configured minimum", &domain.Job{}, 60},
+		{"job override", &domain.Job{MinDuration: sql.NullInt64{Int64: 20, Valid: true}}, 20},
+		{"job override keeps everything", &domain.Job{MinDuration: sql.NullInt64{Valid: true}}, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := h.minTrackDuration(tt.job); got != tt.want {
+				t.Errorf("minTrackDuration() = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestContainerJobHandler_ProcessAlbumJobSelectedTracks(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	// The album is served from the provider cache, so the job never reaches the network.
+	album := domain.Album{ID: "album1", Title: "Album", Artist: "Artist"}
+	for _, id := range []string{"t1", "t2", "t3"} {
+		album.Tracks = append(album.Tracks, domain.CatalogTrack{ID: id, Title: "Song " + id, Artist: "Artist", AlbumID: "album1"})
+	}
+	data, err := json.Marshal(album)
+	if err != nil {
+		t.Fatalf("Marshal failed: %v", err)
+	}
+	if err := db.SetCache("hifi:album:album1", data, time.Hour); err != nil {
+		t.Fatalf("SetCache failed: %v", err)
+	}
+
+	log := logger.Default()
+	pm := catalog.NewProviderManager(db, store.NewSettingsRepo(db), time.Hour, "", log)
+	h := &ContainerJobHandler{
+		Repo:            db,
+		Config:          &config.Config{},
+		ProviderManager: pm,
+		Enricher:        app.NewMetadataEnricher(nil, pm, app.NewLyricsFallback(false, nil)),
+	}
+
+	job := &domain.Job{
+		ID:        "job1",
+		Type:      domain.JobTypeAlbum,
+		Status:    domain.JobStatusRunning,
+		SourceID:  sql.NullString{String: "album1", Valid: true},
+		TrackIDs:  sql.NullString{String: "t3,t1,missing", Valid: true},
+		CreatedAt: time.Now(),
+		UpdatedAt: time.Now(),
+	}
+	if err := db.CreateJob(job); err != nil {
+		t.Fatalf("CreateJob failed: %v", err)
+	}
+	if err := h.processAlbumJob(context.Background(), job, log.Logger); err != nil {
+		t.Fatalf("processAlbumJob failed: %v", err)
+	}
+
+	children, err := db.ListJobsByParentID(job.ID)
+	if err != nil {
+		t.Fatalf("ListJobsByParentID failed: %v", err)
+	}
+	var got []string
+	for _, child := range children {
+		got = append(got, child.GetSourceID())
+	}
+	slices.Sort(got)
+	if want := []string{"t1", "t3"}; !slices.Equal(got, want) {
+		t.Errorf("track jobs = %v, want %v", got, want)
+	}
+}
+
+func TestContainerJobHandler_ProcessAlbumJobReportsShortTracks(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	album := domain.Album{ID: "album1", Title: "Album", Artist: "Artist"}
+	for i, d := range []int{10, 200, 15} {
+		album.Tracks = append(album.Tracks, domain.CatalogTrack{ID: fmt.Sprintf("t%d", i+1), Title: "Song", Artist: "Artist", AlbumID: "album1", Duration: d})
+	}
+	data, err := json.Marshal(album)
+	if err != nil {
+		t.Fatalf("Marshal failed: %v", err)
+	}
+	if err := db.SetCache("hifi:album:album1", data, time.Hour); err != nil {
+		t.Fatalf("SetCache failed: %v", err)
+	}
+
+	log := logger.Default()
+	pm := catalog.NewProviderManager(db, store.NewSettingsRepo(db), time.Hour, "", log)
+	h := &ContainerJobHandler{
+		Repo:            db,
+		Config:          &config.Config{MinTrackDurationSecs: 30},
+		ProviderManager: pm,
+		Enricher:        app.NewMetadataEnricher(nil, pm, app.NewLyricsFallback(false, nil)),
+	}
+
+	job := &domain.Job{ID: "job1", Type: domain.JobTypeAlbum, Status: domain.JobStatusRunning, SourceID: sql.NullString{String: "album1", Valid: true}, CreatedAt: time.Now(), UpdatedAt: time.Now()}
+	if err := db.CreateJob(job); err != nil {
+		t.Fatalf("CreateJob failed: %v", err)
+	}
+	if err := h.processAlbumJob(context.Background(), job, log.Logger); err != nil {
+		t.Fatalf("processAlbumJob failed: %v", err)
+	}
+
+	events, err := db.ListJobEvents(job.ID)
+	if err != nil {
+		t.Fatalf("ListJobEvents failed: %v", err)
+	}
+	want := "Skipped 2 tracks shorter than 30 seconds"
+	if !slices.ContainsFunc(events, func(e *domain.JobEvent) bool { return e.Level == domain.JobEventWarn && e.Message == want }) {
+		t.Errorf("job events = %v, want a warning %q", events, want)
+	}
+}
+
+func TestContainerJobHandler_ProcessDiscographyJobReportsEnqueued(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	artist := domain.Artist{ID: "artist1", Name: "Artist", Albums: []domain.Album{
+		{ID: "a1", Title: "One", AlbumType: "ALBUM"},
+		{ID: "a2", Title: "Two", AlbumType: "ALBUM"},
+		{ID: "a3", Title: "Three", AlbumType: "SINGLE"},
+		{ID: "a4", Title: "Four", AlbumType: "ALBUM"},
+		{ID: "a5", Title: "Five", AlbumType: "ALBUM", TotalTracks: 2},
+		{ID: "a6", Title: "Six", AlbumType: "ALBUM", TotalTracks: 1},
+	}}
+	data, err := json.Marshal(artist)
+	if err != nil {
+		t.Fatalf("Marshal failed: %v", err)
+	}
+	if err := db.SetCache("hifi:artist:artist1", data, time.Hour); err != nil {
+		t.Fatalf("SetCache failed: %v", err)
+	}
+	queued := &domain.Job{ID: "queued", Type: domain.JobTypeAlbum, Status: domain.JobStatusQueued, SourceID: sql.NullString{String: "a4", Valid: true}, CreatedAt: time.Now(), UpdatedAt: time.Now()}
+	if err := db.CreateJob(queued); err != nil {
+		t.Fatalf("CreateJob failed: %v", err)
+	}
+
+	// One track of a5 came as a single; all of a6 is downloaded.
+	for _, tr := range []*domain.Track{
+		{ProviderID: "a5t1", AlbumID: "a5", Status: domain.TrackStatusCompleted, FilePath: "/music/a5t1.flac"},
+		{ProviderID: "a6t1", AlbumID: "a6", Status: domain.TrackStatusCompleted, FilePath: "/music/a6t1.flac"},
+	} {
+		if err := db.CreateTrack(tr); err != nil {
+			t.Fatalf("CreateTrack failed: %v", err)
+		}
+	}
+
+	cfg := &config.Config{DownloadsDir: t.TempDir(), DiscographyReleaseTypes: "album"}
+	log := logger.Default()
+	h := &ContainerJobHandler{
+		Repo:            db,
+		Config:          cfg,
+		ProviderManager: catalog.NewProviderManager(db, store.NewSettingsRepo(db), time.Hour, "", log),
+		AlbumArtService: app.NewAlbumArtService(cfg),
+	}
+
+	job := &domain.Job{ID: "job1", Type: domain.JobTypeDiscography, Status: domain.JobStatusRunning, SourceID: sql.NullString{String: "artist1", Valid: true}, CreatedAt: time.Now(), UpdatedAt: time.Now()}
+	if err := db.CreateJob(job); err != nil {
+		t.Fatalf("CreateJob failed: %v", err)
+	}
+	if err := h.processDiscographyJob(context.Background(), job, log.Logger); err != nil {
+		t.Fatalf("processDiscographyJob failed: %v", err)
+	}
+
+	events, err := db.ListJobEvents(job.ID)
+	if err != nil {
+		t.Fatalf("ListJobEvents failed: %v", err)
+	}
+	want := "Queued 3 albums; 2 already downloaded or queued, 1 filtered by release type"
+	if !slices.ContainsFunc(events, func(e *domain.JobEvent) bool { return e.Level == domain.JobEventInfo && e.Message == want }) {
+		t.Errorf("job events = %v, want %q", events, want)
+	}
+}
+
+func TestContainerJobHandler_ProcessAlbumJobFetchesCoverOnce(t *testing.T) {
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, image.NewGray(image.Rect(0, 0, 4, 4))); err != nil {
+		t.Fatalf("failed to encode PNG: %v", err)
+	}
+	var mu sync.Mutex
+	hits := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		hits++
+		mu.Unlock()
+		w.Header().Set("Content-Type", "image/png")
+		_, _ = w.Write(buf.Bytes())
+	}))
+	defer srv.Close()
+
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	coverURL := srv.URL + "/cover.png"
+	album := domain.Album{ID: "album1", Title: "Album", Artist: "Artist", Year: 2020, AlbumArtURL: coverURL}
+	for _, id := range []string{"t1", "t2", "t3"} {
+		album.Tracks = append(album.Tracks, domain.CatalogTrack{ID: id, Title: "Song " + id, Artist: "Artist", AlbumID: "album1", AlbumArtURL: coverURL})
+	}
+	data, err := json.Marshal(album)
+	if err != nil {
+		t.Fatalf("Marshal failed: %v", err)
+	}
+	if err := db.SetCache("hifi:album:album1", data, time.Hour); err != nil {
+		t.Fatalf("SetCache failed: %v", err)
+	}
+
+	cfg := &config.Config{DownloadsDir: t.TempDir(), SubdirTemplate: constants.DefaultSubdirTemplate}
+	art := newImageCache(app.NewAlbumArtService(cfg))
+	log := logger.Default()
+	pm := catalog.NewProviderManager(db, store.NewSettingsRepo(db), time.Hour, "", log)
+	h := &ContainerJobHandler{
+		Repo:            db,
+		Config:          cfg,
+		ProviderManager: pm,
+		AlbumArtService: art,
+		Enricher:        app.NewMetadataEnricher(nil, pm, app.NewLyricsFallback(false, nil)),
+	}
+	job := &domain.Job{
+		ID:        "job1",
+		Type:      domain.JobTypeAlbum,
+		Status:    domain.JobStatusRunning,
+		SourceID:  sql.NullString{String: "album1", Valid: true},
+		CreatedAt: time.Now(),
+		UpdatedAt: time.Now(),
+	}
+	if err := db.CreateJob(job); err != nil {
+		t.Fatalf("CreateJob failed: %v", err)
+	}
+	if err := h.processAlbumJob(context.Background(), job, log.Logger); err != nil {
+		t.Fatalf("processAlbumJob failed: %v", err)
+	}
+
+	albumDir := filepath.Join(cfg.DownloadsDir, "Artist", "2020 - Album")
+	if storage.FindCover(albumDir) == "" {
+		t.Fatalf("cover was not saved in %s", albumDir)
+	}
+
+	th := &TrackJobHandler{
+		Config:          cfg,
+		AlbumArtService: art,
+		Enricher:        h.Enricher,
+	}
+	// One track lands next to the saved cover; the others in folders without one, as
+	// when the track's metadata moves it, so their art comes from the cache.
+	dirs := []string{albumDir, t.TempDir(), t.TempDir()}
+	for i, ct := range album.Tracks {
+		track := &domain.Track{ProviderID: ct.ID, Title: ct.Title, Artist: ct.Artist, AlbumArtURL: ct.AlbumArtURL}
+		gotArt, _, _ := th.fetchTagAssets(context.Background(), track, dirs[i], log.Logger)
+		if !bytes.Equal(gotArt, buf.Bytes()) {
+			t.Errorf("track %s: album art = %d bytes, want the cover", ct.ID, len(gotArt))
+		}
+	}
+
+	if hits != 1 {
+		t.Errorf("cover fetched %d times, want once per album", hits)
+	}
+}
+
+func TestTrackJobHandler_SiblingAlbumTags(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	// The provider's per-track data disagrees on album-level fields; the album record is
+	// what every track should be tagged with.
+	cache := map[string]interface{}{
+		"hifi:album:album1": domain.Album{ID: "album1", Title: "Album", Artist: "Artist", Artists: []string{"Artist"}, ReleaseDate: "2020-03-01", Label: "Label", Genre: "pop", TotalTracks: 2, TotalDiscs: 1, Tracks: []domain.CatalogTrack{
+			{ID: "t1", Duration: 200, ReplayGain: -8, Peak: 0.9},
+			{ID: "t2", Duration: 100, ReplayGain: -5, Peak: 0.99},
+		}},
+		"hifi:track:t1": domain.CatalogTrack{ID: "t1", Title: "One", Artist: "Artist", AlbumID: "album1", Album: "Album", AlbumArtist: "Artist", AlbumArtists: []string{"Artist"}, ReleaseDate: "2019-01-01", Label: "Other", Genre: "pop", TotalTracks: 12, TotalDiscs: 1, TrackNumber: 1},
+		"hifi:track:t2": domain.CatalogTrack{ID: "t2", Title: "Two", Artist: "Artist", AlbumID: "album1", Album: "Album (Deluxe)", AlbumArtist: "Artist", AlbumArtists: []string{"Artist"}, ReleaseDate: "2020-03-01", Label: "Label", Genre: "rock", TotalTracks: 2, TotalDiscs: 2, TrackNumber: 2},
+	}
+	for key, v := range cache {
+		data, err := json.Marshal(v)
+		if err != nil {
+			t.Fatalf("Marshal failed: %v", err)
+		}
+		if err := db.SetCache(key, data, time.Hour); err != nil {
+			t.Fatalf("SetCache failed: %v", err)
+		}
+	}
+
+	log := logger.Default()
+	pm := catalog.NewProviderManager(db, store.NewSettingsRepo(db), time.Hour, "", log)
+	enricher := app.NewMetadataEnricher(nil, pm, app.NewLyricsFallback(false, nil))
+	opts := tagOptions(&config.Config{GaplessTags: true}, nil, nil)
+
+	albumTags := []string{"ALBUM", "ALBUMARTIST", "TRACKTOTAL", "TOTALTRACKS", "DISCTOTAL", "TOTALDISCS", "DATE", "RELEASEDATE", "LABEL", "GENRE", "GAPLESS", "REPLAYGAIN_ALBUM_GAIN", "REPLAYGAIN_ALBUM_PEAK"}
+	var first map[string][]string
+	for _, id := range []string{"t1", "t2"} {
+		track := &domain.Track{ProviderID: id, SourceProvider: string(catalog.ProviderTypeHifi), ParentJobID: "album-job"}
+		if err := enricher.EnrichFromHiFi(context.Background(), track, log.Logger); err != nil {
+			t.Fatalf("EnrichFromHiFi failed: %v", err)
+		}
+		path := filepath.Join(t.TempDir(), id+".flac")
+		f := &flac.File{
+			Meta:   []*flac.MetaDataBlock{{Type: flac.StreamInfo, Data: make([]byte, 34)}},
+			Frames: []byte{0xFF, 0xF8, 0x00, 0x00},
+		}
+		if err := f.Save(path); err != nil {
+			t.Fatalf("failed to write FLAC: %v", err)
+		}
+		if err := tagging.TagFile(path, track, opts); err != nil {
+			t.Fatalf("TagFile failed: %v", err)
+		}
+
+		comments := flacComments(t, path)
+		got := make(map[string][]string)
+		for _, name := range albumTags {
+			got[name] = comments[name]
+		}
+		if first == nil {
+			first = got
+			if !slices.Equal(got["GAPLESS"], []string{"1"}) || !slices.Equal(got["LABEL"], []string{"Label"}) {
+				t.Errorf("%s: GAPLESS = %v, LABEL = %v, want 1 and the album's label", id, got["GAPLESS"], got["LABEL"])
+			}
+			if !slices.Equal(got["REPLAYGAIN_ALBUM_GAIN"], []string{"-7.21 dB"}) || !slices.Equal(got["REPLAYGAIN_ALBUM_PEAK"], []string{"0.990000"}) {
+				t.Errorf("%s: album gain = %v, peak = %v, want -7.21 dB and 0.990000", id, got["REPLAYGAIN_ALBUM_GAIN"], got["REPLAYGAIN_ALBUM_PEAK"])
+			}
+			continue
+		}
+		for _, name := range albumTags {
+			if !slices.Equal(got[name], first[name]) {
+				t.Errorf("%s: %s = %v, want %v like its sibling", id, name, got[name], first[name])
+			}
+		}
+	}
+}
+
+func TestSyncJobHandler_AlbumArtistOverride(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	cfg := &config.Config{DownloadsDir: t.TempDir(), SubdirTemplate: constants.DefaultSubdirTemplate}
+	// Each track of the soundtrack came credited to its own artist; the other album's
+	// track shows the override stays with its album.
+	tracks := []*domain.Track{
+		{ProviderID: "t1", Title: "Theme", Artist: "Composer", AlbumArtist: "Composer", AlbumArtistSort: "Composer, The", Album: "Soundtrack", AlbumID: "ost", TrackNumber: 1},
+		{ProviderID: "t2", Title: "Song", Artist: "Band", AlbumArtist: "Band", Album: "Soundtrack", AlbumID: "ost", TrackNumber: 2},
+		{ProviderID: "t3", Title: "Other", Artist: "Band", AlbumArtist: "Band", Album: "Record", AlbumID: "record", TrackNumber: 1},
+	}
+	for _, tr := range tracks {
+		tr.Status = domain.TrackStatusCompleted
+		tr.FilePath = filepath.Join(cfg.DownloadsDir, tr.AlbumArtist, tr.Album, tr.Title+".flac")
+		if err := os.MkdirAll(filepath.Dir(tr.FilePath), 0o755); err != nil {
+			t.Fatalf("MkdirAll failed: %v", err)
+		}
+		f := &flac.File{
+			Meta:   []*flac.MetaDataBlock{{Type: flac.StreamInfo, Data: make([]byte, 34)}},
+			Frames: []byte{0xFF, 0xF8, 0x00, 0x00},
+		}
+		if err := f.Save(tr.FilePath); err != nil {
+			t.Fatalf("failed to write FLAC: %v", err)
+		}
+		if err := db.CreateTrack(tr); err != nil {
+			t.Fatalf("CreateTrack failed: %v", err)
+		}
+	}
+
+	svc := app.NewDownloadsService(db, cfg, logger.Default())
+	queued, err := svc.SetAlbumArtistOverride("ost", "Various Artists")
+	if err != nil {
+		t.Fatalf("SetAlbumArtistOverride failed: %v", err)
+	}
+	if queued != 2 {
+		t.Errorf("queued = %d, want 2 sync jobs", queued)
+	}
+
+	h := &SyncJobHandler{Repo: db, Config: cfg}
+	for _, tr := range tracks {
+		job := &domain.Job{
+			ID:        "sync_" + tr.ProviderID,
+			Type:      domain.JobTypeSyncFile,
+			Status:    domain.JobStatusRunning,
+			SourceID:  sql.NullString{String: tr.ProviderID, Valid: true},
+			CreatedAt: time.Now(),
+			UpdatedAt: time.Now(),
+		}
+		if err := db.CreateJob(job); err != nil {
+			t.Fatalf("CreateJob failed: %v", err)
+		}
+		if err := h.Handle(context.Background(), job, logger.Default().Logger); err != nil {
+			t.Fatalf("Handle failed: %v", err)
+		}
+	}
+
+	tests := []struct {
+		providerID      string
+		wantAlbumArtist string
+		wantArtistDir   string
+	}{
+		{"t1", "Various Artists", filepath.Join(cfg.DownloadsDir, "Various Artists")},
+		{"t2", "Various Artists", filepath.Join(cfg.DownloadsDir, "Various Artists")},
+		{"t3", "Band", filepath.Join(cfg.DownloadsDir, "Band")},
+	}
+	for _, tt := range tests {
+		t.Run(tt.providerID, func(t *testing.T) {
+			track, err := db.GetTrackByProviderID(tt.providerID)
+			if err != nil {
+				t.Fatalf("GetTrackByProviderID failed: %v", err)
+			}
+			if track.AlbumArtist != tt.wantAlbumArtist {
+				t.Errorf("AlbumArtist = %q, want %q", track.AlbumArtist, tt.wantAlbumArtist)
+			}
+			if dir := filepath.Dir(filepath.Dir(track.FilePath)); dir != tt.wantArtistDir {
+				t.Errorf("file moved under %s, want %s", dir, tt.wantArtistDir)
+			}
+			comments := flacComments(t, track.FilePath)
+			if got := comments["ALBUMARTIST"]; !slices.Equal(got, []string{tt.wantAlbumArtist}) {
+				t.Errorf("ALBUMARTIST tag = %v, want [%s]", got, tt.wantAlbumArtist)
+			}
+			if tt.providerID == "t1" && len(comments["ALBUMARTISTSORT"]) > 0 {
+				t.Errorf("ALBUMARTISTSORT tag = %v, want the old artist's sort name dropped", comments["ALBUMARTISTSORT"])
+			}
+		})
+	}
+}
+
+func TestSyncJobHandler_LockedTrack(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	provided := domain.CatalogTrack{ID: "t1", Title: "Provider Title", Artist: "Artist", Album: "Album", AlbumArtist: "Artist", Genre: "pop", TrackNumber: 1}
+	data, err := json.Marshal(provided)
+	if err != nil {
+		t.Fatalf("Marshal failed: %v", err)
+	}
+	if err := db.SetCache("hifi:track:t1", data, time.Hour); err != nil {
+		t.Fatalf("SetCache failed: %v", err)
+	}
+
+	cfg := &config.Config{DownloadsDir: t.TempDir(), SubdirTemplate: constants.DefaultSubdirTemplate}
+	track := &domain.Track{
+		ProviderID: "t1", SourceProvider: string(catalog.ProviderTypeHifi), Title: "Fixed Title", Artist: "Artist",
+		AlbumArtist: "Artist", Album: "Album", Genre: "jazz", TrackNumber: 1, Status: domain.TrackStatusCompleted,
+	}
+	track.FilePath = filepath.Join(cfg.DownloadsDir, "Artist", "Album", "track.flac")
+	if err := os.MkdirAll(filepath.Dir(track.FilePath), 0o755); err != nil {
+		t.Fatalf("MkdirAll failed: %v", err)
+	}
+	f := &flac.File{
+		Meta:   []*flac.MetaDataBlock{{Type: flac.StreamInfo, Data: make([]byte, 34)}},
+		Frames: []byte{0xFF, 0xF8, 0x00, 0x00},
+	}
+	if err := f.Save(track.FilePath); err != nil {
+		t.Fatalf("failed to write FLAC: %v", err)
+	}
+	if err := db.CreateTrack(track); err != nil {
+		t.Fatalf("CreateTrack failed: %v", err)
+	}
+	if err := db.UpdateTrackPartial(track.ID, map[string]interface{}{"locked": true}); err != nil {
+		t.Fatalf("UpdateTrackPartial failed: %v", err)
+	}
+
+	log := logger.Default()
+	pm := catalog.NewProviderManager(db, store.NewSettingsRepo(db), time.Hour, "", log)
+	h := &SyncJobHandler{Repo: db, Config: cfg, ProviderManager: pm, Enricher: app.NewMetadataEnricher(nil, pm, app.NewLyricsFallback(false, nil))}
+	jobs := 0
+	handle := func(jobType domain.JobType) {
+		t.Helper()
+		jobs++
+		job := &domain.Job{
+			ID:        fmt.Sprintf("job%d", jobs),
+			Type:      jobType,
+			Status:    domain.JobStatusRunning,
+			SourceID:  sql.NullString{String: "t1", Valid: true},
+			CreatedAt: time.Now(),
+			UpdatedAt: time.Now(),
+		}
+		if err := db.CreateJob(job); err != nil {
+			t.Fatalf("CreateJob failed: %v", err)
+		}
+		if err := h.Handle(context.Background(), job, log.Logger); err != nil {
+			t.Fatalf("Handle failed: %v", err)
+		}
+		if got, _ := db.GetJob(job.ID); got.Status != domain.JobStatusCompleted {
+			t.Errorf("%s job status = %s, want completed", jobType, got.Status)
+		}
+	}
+
+	handle(domain.JobTypeSyncHiFi)
+	got, err := db.GetTrackByProviderID("t1")
+	if err != nil {
+		t.Fatalf("GetTrackByProviderID failed: %v", err)
+	}
+	if got.Title != "Fixed Title" || got.Genre != "jazz" || !got.Locked {
+		t.Errorf("locked track = %q %q locked=%v, want its edits kept and still locked", got.Title, got.Genre, got.Locked)
+	}
+
+	// A locked track can still be re-tagged from what was saved for it.
+	if err := db.UpdateTrackPartial(got.ID, map[string]interface{}{"genre": "blues"}); err != nil {
+		t.Fatalf("UpdateTrackPartial failed: %v", err)
+	}
+	handle(domain.JobTypeSyncFile)
+	comments := flacComments(t, got.FilePath)
+	if !slices.Equal(comments["TITLE"], []string{"Fixed Title"}) || !slices.Equal(comments["GENRE"], []string{"blues"}) {
+		t.Errorf("tags = TITLE %v GENRE %v, want the stored values", comments["TITLE"], comments["GENRE"])
+	}
+
+	// Unlocked, the same sync takes the provider's metadata.
+	if err := db.UpdateTrackPartial(got.ID, map[string]interface{}{"locked": false}); err != nil {
+		t.Fatalf("UpdateTrackPartial failed: %v", err)
+	}
+	handle(domain.JobTypeSyncHiFi)
+	if got, _ = db.GetTrackByProviderID("t1"); got.Title != "Provider Title" {
+		t.Errorf("unlocked track title = %q, want the provider's", got.Title)
+	}
+}
+
+// flacComments returns the Vorbis comments of the FLAC file at path by field name.
+func flacComments(t *testing.T, path string) map[string][]string {
+	t.Helper()
+	f, err := flac.ParseFile(path)
+	if err != nil {
+		t.Fatalf("failed to parse FLAC: %v", err)
+	}
+	out := make(map[string][]string)
+	for _, b := range f.Meta {
+		if b.Type != flac.VorbisComment {
+			continue
+		}
+		vc, err := flacvorbis.ParseFromMetaDataBlock(*b)
+		if err != nil {
+			t.Fatalf("failed to parse Vorbis comment: %v", err)
+		}
+		for _, c := range vc.Comments {
+			name, value, _ := strings.Cut(c, "=")
+			out[name] = append(out[name], value)
+		}
+	}
+	return out
+}
+
+// countingMBClient counts the MusicBrainz lookups made through it.
+type countingMBClient struct {
+	musicbrainz.ClientInterface
+	calls int
+}
+
+func (c *countingMBClient) GetRecording(ctx context.Context, recordingID, isrc, albumName string) (*musicbrainz.RecordingMetadata, error) {
+	c.calls++
+	return nil, nil
+}
+
+func TestTrackJobHandler_EnrichMetadataMusicBrainz(t *testing.T) {
+	tests := []struct {
+		name      string
+		cfg       *config.Config
+		wantCalls int
+	}{
+		{"enabled", &config.Config{EnableMusicBrainzEnrichment: true}, 1},
+		{"disabled", &config.Config{}, 0},
+		{"skipped for the provider", &config.Config{EnableMusicBrainzEnrichment: true, MusicBrainzSkipProviders: "hifi"}, 0},
+		{"skipped for another provider", &config.Config{EnableMusicBrainzEnrichment: true, MusicBrainzSkipProviders: "qobuz"}, 1},
+	}
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			log := logger.Default()
+			mb := &countingMBClient{}
+			// No providers are configured, so only MusicBrainz is asked for metadata.
+			pm := catalog.NewProviderManager(db, nil, 0, "", log)
+			h := &TrackJobHandler{
+				Config:          tt.cfg,
+				ProviderManager: pm,
+				Enricher:        app.NewMetadataEnricher(mb, pm, app.NewLyricsFallback(false, nil)),
+			}
+
+			track := &domain.Track{ProviderID: "1", SourceProvider: "hifi", Title: "Song", ISRC: "USRC17607839"}
+			h.enrichMetadata(context.Background(), track, log.Logger)
+			if mb.calls != tt.wantCalls {
+				t.Errorf("MusicBrainz called %d times, want %d", mb.calls, tt.wantCalls)
+			}
+		})
+	}
+}
+
+// recordingMBClient answers every lookup with one recording.
+type recordingMBClient struct {
+	musicbrainz.ClientInterface
+	meta *musicbrainz.RecordingMetadata
+}
+
+func (c *recordingMBClient) GetRecording(ctx context.Context, recordingID, isrc, albumName string) (*musicbrainz.RecordingMetadata, error) {
+	return c.meta, nil
+}
+
+func TestImportJobHandler(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	importDir, err := filepath.EvalSymlinks(t.TempDir())
+	if err != nil {
+		t.Fatalf("EvalSymlinks failed: %v", err)
+	}
+	albumDir := filepath.Join(importDir, "Artist", "Album")
+	if err := os.MkdirAll(albumDir, 0o755); err != nil {
+		t.Fatalf("MkdirAll failed: %v", err)
+	}
+	writeFLAC := func(name string, comments ...string) string {
+		t.Helper()
+		vc := flacvorbis.New()
+		vc.Comments = comments
+		vcMeta := vc.Marshal()
+		f := &flac.File{
+			Meta:   []*flac.MetaDataBlock{{Type: flac.StreamInfo, Data: make([]byte, 34)}, &vcMeta},
+			Frames: []byte{0xFF, 0xF8, 0x00, 0x00},
+		}
+		path := filepath.Join(albumDir, name)
+		if err := f.Save(path); err != nil {
+			t.Fatalf("failed to write FLAC: %v", err)
+		}
+		return path
+	}
+	imported := writeFLAC("01 Song.flac", "TITLE=Song", "ARTIST=Artist", "ALBUM=Album", "TRACKNUMBER=1/2",
+		"GENRE=jazz", "ISRC=USRC17607839", "COMMENT=ripped from CD")
+	downloaded := writeFLAC("02 Other.flac", "TITLE=Other")
+	if err := os.WriteFile(filepath.Join(albumDir, "notes.txt"), []byte("not audio"), 0o600); err != nil {
+		t.Fatalf("WriteFile failed: %v", err)
+	}
+	if err := db.CreateTrack(&domain.Track{
+		ProviderID: "t2", SourceProvider: string(catalog.ProviderTypeHifi), Title: "Other",
+		Status: domain.TrackStatusCompleted, FilePath: downloaded,
+	}); err != nil {
+		t.Fatalf("CreateTrack failed: %v", err)
+	}
+
+	cfg := &config.Config{
+		DownloadsDir: t.TempDir(), ImportDir: importDir, SubdirTemplate: constants.DefaultSubdirTemplate,
+		EnableMusicBrainzEnrichment: true,
+	}
+	log := logger.Default()
+	pm := catalog.NewProviderManager(db, nil, 0, "", log)
+	mb := &recordingMBClient{meta: &musicbrainz.RecordingMetadata{RecordingID: "rec-mbid", Label: "Label", Genre: "pop"}}
+	h := &ImportJobHandler{Repo: db, Config: cfg, Sync: &SyncJobHandler{
+		Repo: db, Config: cfg, Enricher: app.NewMetadataEnricher(mb, pm, app.NewLyricsFallback(false, nil)),
+		MergeStrategy: tagging.MergeFillMissing,
+	}}
+
+	svc := app.NewDownloadsService(db, cfg, log)
+	runImport := func() {
+		t.Helper()
+		if err := svc.EnqueueImportJob("Artist"); err != nil {
+			t.Fatalf("EnqueueImportJob failed: %v", err)
+		}
+		job, err := db.GetActiveJobBySourceID(filepath.Join(importDir, "Artist"), domain.JobTypeImport)
+		if err != nil || job == nil {
+			t.Fatalf("import job not queued: %v", err)
+		}
+		if err := h.Handle(context.Background(), job, log.Logger); err != nil {
+			t.Fatalf("Handle failed: %v", err)
+		}
+		if got, _ := db.GetJob(job.ID); got.Status != domain.JobStatusCompleted {
+			t.Errorf("import job status = %s, want completed", got.Status)
+		}
+	}
+	runImport()
+
+	track, err := db.GetTrackByFilePath(imported)
+	if err != nil {
+		t.Fatalf("imported track not recorded: %v", err)
+	}
+	if track.Status != domain.TrackStatusCompleted || track.SourceProvider != domain.ImportSourceProvider || track.FileHash == "" {
+		t.Errorf("track status %s source %q hash %q, want a completed import with a hash", track.Status, track.SourceProvider, track.FileHash)
+	}
+	if track.Title != "Song" || track.TrackNumber != 1 || track.TotalTracks != 2 || track.Genre != "jazz" {
+		t.Errorf("track = %q #%d/%d genre %q, want the file's tags", track.Title, track.TrackNumber, track.TotalTracks, track.Genre)
+	}
+	if track.Label != "Label" || track.RecordingID == nil || *track.RecordingID != "rec-mbid" {
+		t.Errorf("label %q recording %v, want MusicBrainz's", track.Label, track.RecordingID)
+	}
+
+	comments := flacComments(t, imported)
+	for name, want := range map[string]string{
+		"LABEL":               "Label",
+		"MUSICBRAINZ_TRACKID": "rec-mbid",
+		"GENRE":               "jazz",
+		"COMMENT":             "ripped from CD",
+	} {
+		if got := comments[name]; !slices.Equal(got, []string{want}) {
+			t.Errorf("%s tag = %v, want [%s]", name, got, want)
+		}
+	}
+
+	if other, _ := db.GetTrackByFilePath(downloaded); other.ProviderID != "t2" || other.SourceProvider != string(catalog.ProviderTypeHifi) {
+		t.Errorf("downloaded track = %s from %q, want it left alone", other.ProviderID, other.SourceProvider)
+	}
+
+	// Importing the folder again updates the track instead of adding another.
+	runImport()
+	again, err := db.GetTrackByFilePath(imported)
+	if err != nil {
+		t.Fatalf("GetTrackByFilePath failed: %v", err)
+	}
+	if again.ID != track.ID {
+		t.Errorf("re-import recorded track %d, want %d updated", again.ID, track.ID)
+	}
+}
+
+func TestTrackJobHandler_FinalPlaylistKeepsDuplicates(t *testing.T) {
+	tests := []struct {
+		name    string
+		jobType domain.JobType
+	}{
+		{"playlist", domain.JobTypePlaylist},
+		{"artist", domain.JobTypeArtist},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, cleanup := setupTestDB(t)
+			defer cleanup()
+
+			dir := t.TempDir()
+			cfg := &config.Config{DownloadsDir: dir, SkipDuplicateISRC: true, PlaylistAbsolutePaths: true}
+			log := logger.Default()
+			existing := &domain.Track{
+				ProviderID: "other-release", Title: "Dup", Artist: "Artist", ISRC: "ISRC1",
+				Status: domain.TrackStatusCompleted, FilePath: filepath.Join(dir, "dup.flac"),
+				CreatedAt: time.Now(), UpdatedAt: time.Now(),
+			}
+			if err := db.CreateTrack(existing); err != nil {
+				t.Fatalf("CreateTrack failed: %v", err)
+			}
+			parent := &domain.Job{
+				ID: "parent", Type: tt.jobType, Status: domain.JobStatusDecomposed,
+				SourceID:  sql.NullString{String: "pl1", Valid: true},
+				CreatedAt: time.Now(), UpdatedAt: time.Now(),
+			}
+			if err := db.CreateJob(parent); err != nil {
+				t.Fatalf("CreateJob failed: %v", err)
+			}
+			playlist := &domain.Playlist{ProviderID: "pl1", Title: "Mix", CreatedAt: time.Now(), UpdatedAt: time.Now()}
+			if err := db.CreatePlaylist(playlist); err != nil {
+				t.Fatalf("CreatePlaylist failed: %v", err)
+			}
+
+			pm := catalog.NewProviderManager(db, store.NewSettingsRepo(db), time.Hour, "", log)
+			container := &ContainerJobHandler{
+				Repo: db, Config: cfg, ProviderManager: pm,
+				Enricher: app.NewMetadataEnricher(nil, pm, app.NewLyricsFallback(false, nil)),
+			}
+			created, _ := container.createTracksAndJobs(parent, []domain.CatalogTrack{
+				{ID: "new", Title: "Dup", Artist: "Artist", ISRC: "ISRC1", TrackNumber: 1},
+				{ID: "t2", Title: "Other", Artist: "Artist", ISRC: "ISRC2", TrackNumber: 2},
+			}, log.Logger)
+			if created != 1 {
+				t.Fatalf("created %d tracks, want 1", created)
+			}
+			if err := db.AddTrackToPlaylist(playlist.ID, existing.ID, 1); err != nil {
+				t.Fatalf("AddTrackToPlaylist failed: %v", err)
+			}
+
+			// The only track job finishes; the regeneration runs after it.
+			track, err := db.GetTrackByProviderID("t2")
+			if err != nil {
+				t.Fatalf("GetTrackByProviderID failed: %v", err)
+			}
+			track.Status = domain.TrackStatusCompleted
+			track.FilePath = filepath.Join(dir, "other.flac")
+			if err := db.UpdateTrack(track); err != nil {
+				t.Fatalf("UpdateTrack failed: %v", err)
+			}
+			if err := db.AddTrackToPlaylist(playlist.ID, track.ID, 2); err != nil {
+				t.Fatalf("AddTrackToPlaylist failed: %v", err)
+			}
+			children, err := db.ListJobsByParentID(parent.ID)
+			if err != nil || len(children) != 1 {
+				t.Fatalf("ListJobsByParentID = %v, %v, want one job", children, err)
+			}
+			if err := db.UpdateJobStatus(children[0].ID, domain.JobStatusCompleted, 100); err != nil {
+				t.Fatalf("UpdateJobStatus failed: %v", err)
+			}
+
+			h := &TrackJobHandler{Repo: db, Config: cfg, PlaylistGenerator: app.NewPlaylistGenerator(cfg, db)}
+			h.triggerPlaylistGenerationIfComplete(parent.ID, log.Logger)
+
+			files, err := filepath.Glob(filepath.Join(dir, constants.PlaylistsDir, "*"+constants.ExtM3U))
+			if err != nil || len(files) != 1 {
+				t.Fatalf("playlists = %v, %v, want one", files, err)
+			}
+			data, err := os.ReadFile(files[0])
+			if err != nil {
+				t.Fatalf("ReadFile failed: %v", err)
+			}
+			for _, want := range []string{existing.FilePath, track.FilePath} {
+				if !strings.Contains(string(data), "\n"+filepath.ToSlash(want)+"\n") {
+					t.Errorf("playlist missing %s:\n%s", want, data)
+				}
+			}
+		})
+	}
+}
+
+func TestTrackJobHandler_JobQualities(t *testing.T) {
+	h := &TrackJobHandler{Config: &config.Config{Quality: "HI_RES_LOSSLESS,LOSSLESS,HIGH"}}
+
+	tests := []struct {
+		name    string
+		quality sql.NullString
+		want    []string
+	}{
+		{"download preference", sql.NullString{}, []string{"HI_RES_LOSSLESS", "LOSSLESS", "HIGH"}},
+		{"upgrade target", sql.NullString{String: "LOSSLESS", Valid: true}, []string{"LOSSLESS", "HIGH"}},
+		{"target outside the preference", sql.NullString{String: "LOW", Valid: true}, []string{"LOW"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := h.jobQualities(&domain.Job{Type: domain.JobTypeUpgrade, Quality: tt.quality})
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("jobQualities() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSyncJobHandler_MaybeUpgradeQuality(t *testing.T) {
+	tests := []struct {
+		name       string
+		preference string
+		reported   string
+		want       string
+	}{
+		{"provider quality", "HI_RES_LOSSLESS,LOSSLESS", "LOSSLESS", "LOSSLESS"},
+		{"capped at the preference", "LOSSLESS,HIGH", "HI_RES_LOSSLESS", "LOSSLESS"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, cleanup := setupTestDB(t)
+			defer cleanup()
+
+			h := &SyncJobHandler{Repo: db, Config: &config.Config{Quality: tt.preference}}
+			track := &domain.Track{ProviderID: "t1", AudioQuality: "HIGH"}
+			h.maybeUpgradeQuality(track, "HIGH", tt.reported, logger.Default().Logger)
+
+			job, err := db.GetActiveJobBySourceID("t1", domain.JobTypeUpgrade)
+			if err != nil || job == nil {
+				t.Fatalf("GetActiveJobBySourceID() = %v, %v, want the upgrade job", job, err)
+			}
+			if job.Quality.String != tt.want {
+				t.Errorf("upgrade job quality = %q, want %q", job.Quality.String, tt.want)
+			}
+
+			// The upgrade delivered less than advertised; the next sync leaves it be.
+			if err := db.UpdateJobStatus(job.ID, domain.JobStatusCompleted, 100); err != nil {
+				t.Fatalf("UpdateJobStatus failed: %v", err)
+			}
+			h.maybeUpgradeQuality(track, "HIGH", tt.reported, logger.Default().Logger)
+			if again, _ := db.GetActiveJobBySourceID("t1", domain.JobTypeUpgrade); again != nil {
+				t.Errorf("queued the upgrade to %s again", tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,169 @@
+package downloader
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"golang.org/x/sync/errgroup"
+
+	"github.com/cesargomez89/navidrums/internal/app"
+	"github.com/cesargomez89/navidrums/internal/domain"
+)
+
+const (
+	// imageCacheTTL is how long a downloaded image is kept for the other tracks of its
+	// album, which may wait in the queue behind other jobs.
+	imageCacheTTL = 30 * time.Minute
+	// imageCacheBytes caps the total size of the images kept; the oldest are dropped
+	// first to make room.
+	imageCacheBytes = 64 << 20
+	// prefetchConcurrency caps the images prefetchImages downloads at once.
+	prefetchConcurrency = 4
+)
+
+// imageCache is an AlbumArtService that keeps the images it downloads for a while, so the
+// tracks of an album embed the cover the album job fetched, and share one download of a
+// back cover or artist photo, instead of each downloading them again. Concurrent requests
+// for one URL wait on a single download. Failed downloads aren't kept, nor are images
+// larger than the whole cache.
+type imageCache struct {
+	app.AlbumArtService
+
+	mu       sync.Mutex
+	entries  map[string]*cachedImage
+	size     int // bytes held by finished downloads
+	maxBytes int
+	now      func() time.Time
+}
+
+type cachedImage struct {
+	done    chan struct{}
+	data    []byte
+	err     error
+	fetched time.Time // zero while the download is in flight
+}
+
+func newImageCache(art app.AlbumArtService) *imageCache {
+	return &imageCache{
+		AlbumArtService: art,
+		entries:         make(map[string]*cachedImage),
+		maxBytes:        imageCacheBytes,
+		now:             time.Now,
+	}
+}
+
+// DownloadImage returns the image at url from the cache, downloading it on a miss.
+func (c *imageCache) DownloadImage(url string) ([]byte, error) {
+	if url == "" {
+		return nil, nil
+	}
+
+	c.mu.Lock()
+	if e, ok := c.entries[url]; ok {
+		if e.fetched.IsZero() || c.now().Sub(e.fetched) < imageCacheTTL {
+			c.mu.Unlock()
+			<-e.done
+			return e.data, e.err
+		}
+		c.drop(url)
+	}
+	e := &cachedImage{done: make(chan struct{})}
+	c.entries[url] = e
+	c.mu.Unlock()
+
+	e.data, e.err = c.AlbumArtService.DownloadImage(url)
+
+	c.mu.Lock()
+	if e.err != nil || len(e.data) > c.maxBytes {
+		delete(c.entries, url)
+	} else {
+		e.fetched = c.now()
+		c.size += len(e.data)
+		c.prune()
+	}
+	c.mu.Unlock()
+	close(e.done)
+	return e.data, e.err
+}
+
+// DownloadAndSaveAlbumArt saves the album's cover, taking it from the cache when it was
+// prefetched.
+func (c *imageCache) DownloadAndSaveAlbumArt(album *domain.Album, provider, imageURL string) error {
+	if imageURL == "" {
+		return nil
+	}
+	data, err := c.DownloadImage(imageURL)
+	if err != nil {
+		return fmt.Errorf("failed to download album art: %w", err)
+	}
+	return c.SaveAlbumArt(album, provider, data)
+}
+
+// prune drops expired images, then the oldest ones until the cache is within its size.
+// The caller holds c.mu.
+func (c *imageCache) prune() {
+	now := c.now()
+	var kept []string
+	for url, e := range c.entries {
+		if e.fetched.IsZero() {
+			continue
+		}
+		if now.Sub(e.fetched) >= imageCacheTTL {
+			c.drop(url)
+			continue
+		}
+		kept = append(kept, url)
+	}
+	if c.size <= c.maxBytes {
+		return
+	}
+	sort.Slice(kept, func(i, j int) bool {
+		return c.entries[kept[i]].fetched.Before(c.entries[kept[j]].fetched)
+	})
+	for _, url := range kept {
+		if c.size <= c.maxBytes {
+			break
+		}
+		c.drop(url)
+	}
+}
+
+// drop removes a finished download from the cache. The caller holds c.mu.
+func (c *imageCache) drop(url string) {
+	c.size -= len(c.entries[url].data)
+	delete(c.entries, url)
+}
+
+// prefetchImages downloads the images at urls concurrently through art, skipping empty
+// and repeated URLs. With the worker's imageCache this warms the cache for the tracks
+// that embed them. Failures are ignored: whoever needs the image retries and reports it.
+func prefetchImages(ctx context.Context, art app.AlbumArtService, urls ...string) {
+	var g errgroup.Group
+	g.SetLimit(prefetchConcurrency)
+	seen := make(map[string]bool, len(urls))
+	for _, url := range urls {
+		if url == "" || seen[url] {
+			continue
+		}
+		seen[url] = true
+		g.Go(func() error {
+			if ctx.Err() == nil {
+				_, _ = art.DownloadImage(url)
+			}
+			return nil
+		})
+	}
+	_ = g.Wait()
+}
+
+// albumImageURLs lists the album's cover followed by its tracks' art, repeats included.
+func albumImageURLs(album *domain.Album) []string {
+	urls := []string{album.AlbumArtURL}
+	for _, t := range album.Tracks {
+		urls = append(urls, t.AlbumArtURL)
+	}
+	return urls
+}
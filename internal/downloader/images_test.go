@@ -0,0 +1,59 @@
+package downloader
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/cesargomez89/navidrums/internal/app"
+)
+
+// countingArt serves images of the size named by their URL and counts the downloads.
+type countingArt struct {
+	app.AlbumArtService
+	hits map[string]int
+}
+
+func (a *countingArt) DownloadImage(url string) ([]byte, error) {
+	a.hits[url]++
+	return []byte(strings.Repeat("x", len(url))), nil
+}
+
+func TestImageCache_MaxBytes(t *testing.T) {
+	art := &countingArt{hits: make(map[string]int)}
+	c := newImageCache(art)
+	c.maxBytes = 10
+	now := time.Now()
+	c.now = func() time.Time { return now }
+
+	get := func(url string) {
+		t.Helper()
+		now = now.Add(time.Second)
+		data, err := c.DownloadImage(url)
+		if err != nil {
+			t.Fatalf("DownloadImage failed: %v", err)
+		}
+		if len(data) != len(url) {
+			t.Errorf("DownloadImage(%q) = %d bytes, want %d", url, len(data), len(url))
+		}
+	}
+
+	get("aaaa")
+	get("bbbb")
+	get("aaaa") // cached
+	get("cccc") // 12 bytes: drops aaaa, the oldest
+	get("bbbb") // cached
+	get("aaaa")
+	get("ddddddddddddddd") // larger than the cache, never kept
+	get("ddddddddddddddd")
+
+	want := map[string]int{"aaaa": 2, "bbbb": 1, "cccc": 1, "ddddddddddddddd": 2}
+	for url, n := range want {
+		if art.hits[url] != n {
+			t.Errorf("%q downloaded %d times, want %d", url, art.hits[url], n)
+		}
+	}
+	if c.size > c.maxBytes {
+		t.Errorf("cache holds %d bytes, want at most %d", c.size, c.maxBytes)
+	}
+}
@@ -0,0 +1,66 @@
+package downloader
+
+import (
+	"context"
+	"log/slog"
+	"net/url"
+
+	"golang.org/x/sync/errgroup"
+
+	"github.com/cesargomez89/navidrums/internal/app"
+	"github.com/cesargomez89/navidrums/internal/catalog"
+	"github.com/cesargomez89/navidrums/internal/config"
+	"github.com/cesargomez89/navidrums/internal/domain"
+	"github.com/cesargomez89/navidrums/internal/tagging"
+)
+
+// coverArtArchiveURL serves the back covers of MusicBrainz releases.
+const coverArtArchiveURL = "https://coverartarchive.org"
+
+// fetchExtraPictures downloads the pictures EMBED_EXTRA_PICTURES embeds after the front
+// cover: the back cover of the track's MusicBrainz release and a photo of its first
+// artist from its source provider. A picture that can't be found is left out, and none are
+// fetched when EMBED_COVER_ART is off.
+func fetchExtraPictures(ctx context.Context, cfg *config.Config, pm *catalog.ProviderManager, art app.AlbumArtService, track *domain.Track, logger *slog.Logger) []tagging.Picture {
+	if cfg == nil || !cfg.EmbedExtraPictures || !cfg.EmbedCoverArt {
+		return nil
+	}
+
+	var back, artist []byte
+	var g errgroup.Group
+	if track.MBAlbumID != "" {
+		g.Go(func() error {
+			data, err := art.DownloadImage(coverArtArchiveURL + "/release/" + url.PathEscape(track.MBAlbumID) + "/back-500")
+			if err != nil {
+				logger.Debug("No back cover to embed", "release_id", track.MBAlbumID, "error", err)
+			}
+			back = data
+			return nil
+		})
+	}
+	if pm != nil && len(track.ArtistIDs) > 0 {
+		g.Go(func() error {
+			a, err := pm.GetSourceProvider(track.SourceProvider).GetArtist(ctx, track.ArtistIDs[0])
+			if err != nil || a == nil || a.PictureURL == "" {
+				logger.Debug("No artist picture to embed", "artist_id", track.ArtistIDs[0], "error", err)
+				return nil
+			}
+			data, err := art.DownloadImage(a.PictureURL)
+			if err != nil {
+				logger.Debug("Failed to download artist picture", "error", err)
+			}
+			artist = data
+			return nil
+		})
+	}
+	_ = g.Wait()
+
+	var pictures []tagging.Picture
+	if len(back) > 0 {
+		pictures = append(pictures, tagging.Picture{Type: tagging.PictureBackCover, Data: embeddableArt(cfg, back, false, logger)})
+	}
+	if len(artist) > 0 {
+		pictures = append(pictures, tagging.Picture{Type: tagging.PictureArtist, Data: embeddableArt(cfg, artist, false, logger)})
+	}
+	return pictures
+}
@@ -5,8 +5,10 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
-	"path/filepath"
+	"log/slog"
+	"slices"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/cesargomez89/navidrums/internal/app"
@@ -15,7 +17,9 @@ import (
 	"github.com/cesargomez89/navidrums/internal/constants"
 	"github.com/cesargomez89/navidrums/internal/domain"
 	"github.com/cesargomez89/navidrums/internal/logger"
+	"github.com/cesargomez89/navidrums/internal/metrics"
 	"github.com/cesargomez89/navidrums/internal/musicbrainz"
+	"github.com/cesargomez89/navidrums/internal/navidrome"
 	"github.com/cesargomez89/navidrums/internal/storage"
 	"github.com/cesargomez89/navidrums/internal/store"
 	"github.com/cesargomez89/navidrums/internal/tagging"
@@ -23,6 +27,7 @@ import (
 
 var (
 	ErrJobCancelled   = errors.New("job was cancelled")
+	ErrJobInterrupted = errors.New("job was interrupted by shutdown")
 	ErrDownloadFailed = errors.New("download failed after retries")
 	ErrNoTracksFound  = errors.New("no tracks found")
 )
@@ -39,14 +44,37 @@ type Worker struct {
 	Logger            *logger.Logger
 	musicBrainzClient musicbrainz.ClientInterface
 	enricher          *app.MetadataEnricher
+	verifier          *app.LibraryVerifier
+	reorganizer       *app.LibraryReorganizer
 	dispatcher        *Dispatcher
+	Running           *app.RunningJobs    // contexts of in-flight jobs, for per-job cancellation
+	Stats             *app.WorkerStats    // slot usage, for the status endpoint
+	MusicBrainz       *musicbrainz.Client // uncached client, shared so lookups outside jobs keep its rate limit
 	cancel            context.CancelFunc
-	wg                sync.WaitGroup
+	pollCtx           context.Context // cancelled when the worker stops starting jobs
+	stopPolling       context.CancelFunc
+	loops             sync.WaitGroup // the poll loop and the missing file sweep
+	jobs              sync.WaitGroup
+	drained           atomic.Int32 // jobs that finished after Shutdown stopped new ones
+	interrupted       atomic.Int32 // jobs still running when Shutdown cancelled them
 	MaxConcurrent     int
+	MaxMetadata       int // slots for metadata sync jobs, apart from MaxConcurrent
+	now               func() time.Time
+	waitingForWindow  bool // only touched by the poll loop
 }
 
+// metadataJobTypes are the sync jobs that run in the metadata lane. They spend most of
+// their time waiting on the MusicBrainz rate limit, so sharing slots with downloads would
+// leave those slots idle while a bulk sync drains.
+var metadataJobTypes = []domain.JobType{domain.JobTypeSyncMusicBrainz, domain.JobTypeSyncHiFi}
+
+// exclusiveJobTypes move files other jobs may be writing, so they run alone: the queue
+// holds new jobs while one is waiting for running jobs to drain or is running itself.
+var exclusiveJobTypes = []domain.JobType{domain.JobTypeReorganize}
+
 func NewWorker(repo *store.DB, settingsRepo *store.SettingsRepo, pm *catalog.ProviderManager, cfg *config.Config, log *logger.Logger) *Worker {
 	ctx, cancel := context.WithCancel(context.Background())
+	pollCtx, stopPolling := context.WithCancel(ctx)
 
 	if log == nil {
 		log = logger.Default()
@@ -58,16 +86,32 @@ func NewWorker(repo *store.DB, settingsRepo *store.SettingsRepo, pm *catalog.Pro
 		ProviderManager: pm,
 		Config:          cfg,
 		MaxConcurrent:   constants.DefaultConcurrency,
+		MaxMetadata:     constants.DefaultMetadataConcurrency,
+		Running:         app.NewRunningJobs(),
+		Stats:           app.NewWorkerStats(),
 		Logger:          log.WithComponent("worker"),
+		now:             time.Now,
 		ctx:             ctx,
 		cancel:          cancel,
+		pollCtx:         pollCtx,
+		stopPolling:     stopPolling,
+	}
+
+	if cfg.MetadataConcurrency > 0 {
+		worker.MaxMetadata = cfg.MetadataConcurrency
 	}
 
 	worker.downloader = app.NewDownloader(pm, cfg)
 	worker.playlistGenerator = app.NewPlaylistGenerator(cfg, repo)
-	worker.albumArtService = app.NewAlbumArtService(cfg)
-
-	baseMBClient := musicbrainz.NewClient(cfg.MusicBrainzURL)
+	worker.albumArtService = newImageCache(app.NewAlbumArtService(cfg))
+
+	baseMBClient := musicbrainz.NewClient(cfg.MusicBrainzURL, cfg.MusicBrainzUserAgent, cfg.MusicBrainzRateLimit)
+	baseMBClient.SetReleasePreference(musicbrainz.ReleasePreference{
+		PreferOriginal: cfg.PreferOriginalReleaseDate,
+		Country:        cfg.MusicBrainzReleaseCountry,
+	})
+	baseMBClient.SetIncludeCredits(cfg.CreditsTagging)
+	worker.MusicBrainz = baseMBClient
 	worker.musicBrainzClient = musicbrainz.NewCachedClient(baseMBClient, repo, cfg.MusicBrainzCacheTTL)
 
 	var lyricsFallback *app.LyricsFallback
@@ -91,10 +135,14 @@ func NewWorker(repo *store.DB, settingsRepo *store.SettingsRepo, pm *catalog.Pro
 		PlaylistGenerator: worker.playlistGenerator,
 		Enricher:          worker.enricher,
 	}
+	if cfg.DedupeAgainstNavidrome {
+		trackHandler.Navidrome = navidrome.NewLibrary(navidrome.NewClient(cfg.NavidromeURL, cfg.NavidromeUsername, cfg.NavidromePassword))
+	}
 
 	containerHandler := &ContainerJobHandler{
 		Repo:              repo,
 		SettingsRepo:      settingsRepo,
+		Config:            cfg,
 		ProviderManager:   pm,
 		AlbumArtService:   worker.albumArtService,
 		PlaylistGenerator: worker.playlistGenerator,
@@ -102,13 +150,44 @@ func NewWorker(repo *store.DB, settingsRepo *store.SettingsRepo, pm *catalog.Pro
 
 	syncHandler := &SyncJobHandler{
 		Repo:            repo,
+		SettingsRepo:    settingsRepo,
 		Config:          cfg,
 		ProviderManager: pm,
 		AlbumArtService: worker.albumArtService,
 		Enricher:        worker.enricher,
 	}
 
+	worker.verifier = app.NewLibraryVerifier(repo)
+
+	verifyHandler := &VerifyJobHandler{
+		Repo:         repo,
+		SettingsRepo: settingsRepo,
+		Verifier:     worker.verifier,
+	}
+
+	worker.reorganizer = app.NewLibraryReorganizer(repo, cfg)
+
+	reorganizeHandler := &ReorganizeJobHandler{
+		Repo:        repo,
+		Reorganizer: worker.reorganizer,
+	}
+
+	importHandler := &ImportJobHandler{
+		Repo:   repo,
+		Config: cfg,
+		Sync: &SyncJobHandler{
+			Repo:            repo,
+			SettingsRepo:    settingsRepo,
+			Config:          cfg,
+			ProviderManager: pm,
+			AlbumArtService: worker.albumArtService,
+			Enricher:        worker.enricher,
+			MergeStrategy:   tagging.MergeFillMissing,
+		},
+	}
+
 	worker.dispatcher.Register(domain.JobTypeTrack, trackHandler)
+	worker.dispatcher.Register(domain.JobTypeUpgrade, trackHandler)
 	worker.dispatcher.Register(domain.JobTypeAlbum, containerHandler)
 	worker.dispatcher.Register(domain.JobTypePlaylist, containerHandler)
 	worker.dispatcher.Register(domain.JobTypeArtist, containerHandler)
@@ -116,9 +195,15 @@ func NewWorker(repo *store.DB, settingsRepo *store.SettingsRepo, pm *catalog.Pro
 	worker.dispatcher.Register(domain.JobTypeSyncFile, syncHandler)
 	worker.dispatcher.Register(domain.JobTypeSyncMusicBrainz, syncHandler)
 	worker.dispatcher.Register(domain.JobTypeSyncHiFi, syncHandler)
+	worker.dispatcher.Register(domain.JobTypeSyncLyrics, syncHandler)
+	worker.dispatcher.Register(domain.JobTypeRetag, syncHandler)
+	worker.dispatcher.Register(domain.JobTypeVerify, verifyHandler)
+	worker.dispatcher.Register(domain.JobTypeReorganize, reorganizeHandler)
+	worker.dispatcher.Register(domain.JobTypeImport, importHandler)
 
 	worker.loadGenreMap()
 	worker.loadGenreSeparator()
+	storage.SetCoverFileName(cfg.CoverArtFilename)
 
 	return worker
 }
@@ -132,8 +217,16 @@ func (w *Worker) Start() {
 
 	w.recoverInterruptedTracks()
 
-	w.wg.Add(1)
+	metrics.WorkerSlots.Set(float64(w.MaxConcurrent))
+	w.Stats.SetSlots(w.MaxConcurrent, w.MaxMetadata)
+
+	w.loops.Add(1)
 	go w.processJobs()
+
+	if w.Config.MissingFileSweepInterval > 0 {
+		w.loops.Add(1)
+		go w.sweepMissingFiles()
+	}
 }
 
 func (w *Worker) loadGenreMap() {
@@ -180,29 +273,11 @@ func (w *Worker) recoverInterruptedTracks() {
 
 		// Attempt to clean up potential partial files
 		// We need to reconstruct the path since it might not be saved in DB yet
-		artistForFolder := t.PathArtist
-		if artistForFolder == "" {
-			artistForFolder = t.AlbumArtist
-		}
-		if artistForFolder == "" {
-			artistForFolder = t.Artist
-		}
-
-		templateData := storage.BuildPathTemplateData(
-			artistForFolder,
-			t.Year,
-			t.Album,
-			t.DiscNumber,
-			t.TrackNumber,
-			t.Title,
-		)
-
-		fullPathNoExt, err := storage.BuildPath(w.Config.SubdirTemplate, templateData)
+		fullPathNoExt, err := app.BuildTrackPath(w.Config, t)
 		if err == nil {
-			fullPathNoExt = filepath.Join(w.Config.DownloadsDir, fullPathNoExt)
 			// Remove known extensions if they exist
 			// This is best-effort
-			for _, ext := range []string{".flac", ".mp3", ".m4a"} {
+			for _, ext := range constants.AudioExtensions {
 				_ = storage.RemoveFile(fullPathNoExt + ext)
 			}
 		}
@@ -213,54 +288,86 @@ func (w *Worker) recoverInterruptedTracks() {
 	}
 }
 
+// sweepMissingFiles periodically flags completed tracks whose files were deleted outside the app.
+func (w *Worker) sweepMissingFiles() {
+	defer w.loops.Done()
+	ticker := time.NewTicker(w.Config.MissingFileSweepInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-w.pollCtx.Done():
+			return
+		case <-ticker.C:
+			flagged, err := w.verifier.SweepMissingFiles(w.pollCtx, constants.MissingFileSweepPageSize)
+			if err != nil && w.pollCtx.Err() == nil {
+				w.Logger.Error("Missing file sweep failed", "error", err)
+				continue
+			}
+			if flagged > 0 {
+				w.Logger.Info("Flagged tracks with missing files", "count", flagged)
+			}
+		}
+	}
+}
+
+// Stop cancels running jobs immediately and waits for them to return.
 func (w *Worker) Stop() {
-	w.Logger.Info("Stopping worker")
+	w.Shutdown(0)
+}
+
+// Shutdown stops the worker starting jobs, gives the running ones up to drain to finish,
+// then cancels the rest and waits for them to return. An interrupted download removes its
+// partial file and is queued again for the next start. It returns how many running jobs
+// finished within the drain period and how many were interrupted.
+func (w *Worker) Shutdown(drain time.Duration) (drained, interrupted int) {
+	w.Logger.Info("Stopping worker", "drain_timeout", drain)
+	w.stopPolling()
+	w.Stats.Stop()
+	w.loops.Wait()
+
+	done := make(chan struct{})
+	go func() {
+		w.jobs.Wait()
+		close(done)
+	}()
+
+	if drain > 0 {
+		timer := time.NewTimer(drain)
+		select {
+		case <-done:
+		case <-timer.C:
+		}
+		timer.Stop()
+	}
+
 	w.cancel()
-	w.wg.Wait()
+	<-done
+	drained, interrupted = int(w.drained.Load()), int(w.interrupted.Load())
+	w.Logger.Info("Worker stopped", "drained", drained, "interrupted", interrupted)
+	return drained, interrupted
 }
 
 func (w *Worker) processJobs() {
-	defer w.wg.Done()
+	defer w.loops.Done()
 	ticker := time.NewTicker(constants.DefaultPollInterval)
 	defer ticker.Stop()
 
 	sem := make(chan struct{}, w.MaxConcurrent)
+	metadataSem := make(chan struct{}, w.MaxMetadata)
 
 	for {
 		select {
-		case <-w.ctx.Done():
+		case <-w.pollCtx.Done():
 			return
 		case <-ticker.C:
-			jobs, err := w.Repo.ListActiveJobs(0, 50)
+			jobs, err := w.nextJobs()
 			if err != nil {
 				w.Logger.Error("Failed to list jobs", "error", err)
 				continue
 			}
 
-			if len(jobs) == 0 {
-				continue
-			}
-
-			activeCount := 0
-			queuedJobs := []*domain.Job{}
-
-			for _, j := range jobs {
-				switch j.Status {
-				case domain.JobStatusRunning:
-					activeCount++
-				case domain.JobStatusQueued:
-					queuedJobs = append(queuedJobs, j)
-				}
-			}
-
-			toStart := w.MaxConcurrent - activeCount
-			if toStart <= 0 || len(queuedJobs) == 0 {
-				continue
-			}
-
-			for i := 0; i < toStart && i < len(queuedJobs); i++ {
-				job := queuedJobs[i]
-
+			for _, job := range jobs {
 				current, err := w.Repo.GetJob(job.ID)
 				if err != nil {
 					w.Logger.Error("Failed to get job before starting", "job_id", job.ID, "error", err)
@@ -270,18 +377,144 @@ func (w *Worker) processJobs() {
 					continue
 				}
 
-				sem <- struct{}{}
-				w.wg.Add(1)
-				go func(j *domain.Job) {
-					defer w.wg.Done()
-					defer func() { <-sem }()
-					w.runJob(w.ctx, j)
-				}(job)
+				slots := sem
+				if slices.Contains(metadataJobTypes, job.Type) {
+					slots = metadataSem
+				}
+				if !w.startJob(job, slots) {
+					return
+				}
 			}
 		}
 	}
 }
 
+// startJob runs job in its own goroutine once one of slots is free. It returns false
+// without starting the job if the worker stops taking jobs while it waits.
+func (w *Worker) startJob(job *domain.Job, slots chan struct{}) bool {
+	select {
+	case <-w.pollCtx.Done():
+		return false
+	default:
+	}
+	select {
+	case slots <- struct{}{}:
+	case <-w.pollCtx.Done():
+		return false
+	}
+
+	w.jobs.Add(1)
+	go func() {
+		defer w.jobs.Done()
+		defer func() { <-slots }()
+		metrics.WorkerActiveSlots.Inc()
+		defer metrics.WorkerActiveSlots.Dec()
+		w.Stats.JobStarted()
+		defer w.Stats.JobFinished()
+		w.runJob(w.ctx, job)
+
+		switch {
+		case w.ctx.Err() != nil:
+			w.interrupted.Add(1)
+		case w.pollCtx.Err() != nil:
+			w.drained.Add(1)
+		}
+	}()
+	return true
+}
+
+// nextJobs picks the queued jobs to start, oldest first, up to the free slots of each lane:
+// MaxConcurrent for downloads and other jobs, MaxMetadata for metadata syncs. Each lane is
+// queried separately, so a backlog in one never hides the other's jobs. A queued
+// exclusive job pauses both lanes and starts once nothing else is running. Outside the
+// download window no job starts; running ones carry on.
+func (w *Worker) nextJobs() ([]*domain.Job, error) {
+	if !w.inDownloadWindow() {
+		return nil, nil
+	}
+	exclusive, err := w.Repo.ListQueuedJobs(exclusiveJobTypes, false, 1)
+	if err != nil {
+		return nil, err
+	}
+	running, err := w.Repo.CountRunningJobs(nil, true)
+	if err != nil {
+		return nil, err
+	}
+	if len(exclusive) > 0 {
+		if running > 0 {
+			return nil, nil
+		}
+		return exclusive, nil
+	}
+	if running > 0 {
+		runningExclusive, err := w.Repo.CountRunningJobs(exclusiveJobTypes, false)
+		if err != nil {
+			return nil, err
+		}
+		if runningExclusive > 0 {
+			return nil, nil
+		}
+	}
+
+	lanes := []struct {
+		exclude bool
+		slots   int
+	}{
+		{exclude: true, slots: w.MaxConcurrent},
+		{exclude: false, slots: w.MaxMetadata},
+	}
+
+	var next []*domain.Job
+	for _, lane := range lanes {
+		running, err := w.Repo.CountRunningJobs(metadataJobTypes, lane.exclude)
+		if err != nil {
+			return nil, err
+		}
+		free := lane.slots - running
+		if free <= 0 {
+			continue
+		}
+		queued, err := w.Repo.ListQueuedJobs(metadataJobTypes, lane.exclude, free)
+		if err != nil {
+			return nil, err
+		}
+		next = append(next, queued...)
+	}
+	return next, nil
+}
+
+// inDownloadWindow reports whether new jobs may start now, logging when the queue pauses
+// for the download window and when it resumes.
+func (w *Worker) inDownloadWindow() bool {
+	if w.Config == nil || !w.Config.DownloadWindow().IsSet() {
+		return true
+	}
+	now := time.Now
+	if w.now != nil {
+		now = w.now
+	}
+	t := now()
+	opens := w.Config.DownloadWindow().Next(t)
+	open := !opens.After(t)
+
+	if open == w.waitingForWindow {
+		if open {
+			w.Logger.Info("Download window open, resuming queue")
+		} else {
+			w.Logger.Info("Outside the download window, pausing queue", "resumes_at", opens.Format(time.DateTime))
+		}
+		w.waitingForWindow = !open
+	}
+	if w.Stats != nil {
+		if open {
+			w.Stats.SetWindowOpens(time.Time{})
+		} else {
+			w.Stats.SetWindowOpens(opens)
+		}
+	}
+	return open
+}
+
 func (w *Worker) runJob(ctx context.Context, job *domain.Job) {
 	defer func() {
 		if r := recover(); r != nil {
@@ -289,15 +522,19 @@ func (w *Worker) runJob(ctx context.Context, job *domain.Job) {
 				"job_id", job.ID,
 				"panic", r,
 			)
+			_ = w.Repo.AddJobEvent(job.ID, domain.JobEventError, fmt.Sprintf("Panic: %v", r))
 			_ = w.Repo.UpdateJobError(job.ID, fmt.Sprintf("Panic: %v", r))
 		}
 	}()
 
-	logger := w.Logger.With(
+	logger := slog.New(newJobEventHandler(w.Logger.Handler(), w.Repo, job.ID)).With(
 		"job_id", job.ID,
 		"job_type", job.Type,
 		"source_id", job.GetSourceID(),
 	)
+	if job.RequestID.Valid {
+		logger = logger.With("request_id", job.RequestID.String)
+	}
 	logger.Info("Running job")
 
 	// Mark job as running
@@ -306,18 +543,37 @@ func (w *Worker) runJob(ctx context.Context, job *domain.Job) {
 		return
 	}
 
+	jobCtx, done := w.Running.Start(ctx, job.ID, job.GetParentJobID())
+	defer done()
+
+	// Checked after registering, so a cancel that lands before Start is not missed.
 	if w.isCancelled(job.ID) {
 		logger.Info("Job cancelled before processing")
 		return
 	}
 
 	// Dispatch based on job type
-	if err := w.dispatcher.Dispatch(ctx, job, logger); err != nil {
+	if err := w.dispatcher.Dispatch(jobCtx, job, logger); err != nil {
 		logger.Error("Job processing failed", "error", err)
 		if err == ErrUnknownJobType {
 			_ = w.Repo.UpdateJobError(job.ID, "Unknown job type")
 		}
 	}
+
+	w.recordJobOutcome(job, logger)
+}
+
+func (w *Worker) recordJobOutcome(job *domain.Job, logger *slog.Logger) {
+	final, err := w.Repo.GetJob(job.ID)
+	if err != nil || final == nil || !final.IsTerminal() {
+		return
+	}
+	metrics.JobsFinished.WithLabelValues(string(final.Type), string(final.Status)).Inc()
+	// Completed children update their parent themselves; a failed one would otherwise
+	// leave it waiting.
+	if final.Status == domain.JobStatusFailed && final.GetParentJobID() != "" {
+		updateParentJobProgress(w.Repo, final.GetParentJobID(), logger)
+	}
 }
 
 func (w *Worker) isCancelled(id string) bool {
@@ -0,0 +1,308 @@
+package downloader
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log/slog"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/cesargomez89/navidrums/internal/app"
+	"github.com/cesargomez89/navidrums/internal/config"
+	"github.com/cesargomez89/navidrums/internal/domain"
+	"github.com/cesargomez89/navidrums/internal/logger"
+	"github.com/cesargomez89/navidrums/internal/store"
+)
+
+func TestWorker_NextJobs(t *testing.T) {
+	tests := []struct {
+		name          string
+		runningSync   int
+		runningTracks int
+		wantTracks    int
+		wantSyncs     int
+	}{
+		{"both lanes free", 0, 0, 1, 1},
+		{"metadata lane busy", 1, 0, 1, 0},
+		{"download lane busy", 0, 2, 0, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, cleanup := setupTestDB(t)
+			defer cleanup()
+
+			// A bulk sync backlog queued well before the download, larger than any page
+			// of active jobs.
+			start := time.Now().Add(-time.Hour)
+			var jobs []*domain.Job
+			newJob := func(id string, jobType domain.JobType, status domain.JobStatus, at time.Time) {
+				jobs = append(jobs, &domain.Job{ID: id, Type: jobType, Status: status, SourceID: sql.NullString{String: id, Valid: true}, CreatedAt: at, UpdatedAt: at})
+			}
+			for i := range 200 {
+				newJob(fmt.Sprintf("sync-%d", i), domain.JobTypeSyncMusicBrainz, domain.JobStatusQueued, start.Add(time.Duration(i)*time.Second))
+			}
+			for i := range tt.runningSync {
+				newJob(fmt.Sprintf("running-sync-%d", i), domain.JobTypeSyncHiFi, domain.JobStatusRunning, start)
+			}
+			for i := range tt.runningTracks {
+				newJob(fmt.Sprintf("running-track-%d", i), domain.JobTypeTrack, domain.JobStatusRunning, start)
+			}
+			newJob("download", domain.JobTypeTrack, domain.JobStatusQueued, time.Now())
+			if err := db.CreateJobBatch(jobs); err != nil {
+				t.Fatalf("CreateJobBatch failed: %v", err)
+			}
+
+			w := &Worker{Repo: db, MaxConcurrent: 2, MaxMetadata: 1}
+			next, err := w.nextJobs()
+			if err != nil {
+				t.Fatalf("nextJobs failed: %v", err)
+			}
+
+			tracks, syncs := 0, 0
+			for _, j := range next {
+				switch j.Type {
+				case domain.JobTypeTrack:
+					tracks++
+				case domain.JobTypeSyncMusicBrainz:
+					syncs++
+					if j.ID != "sync-0" {
+						t.Errorf("Expected the oldest sync job, got %s", j.ID)
+					}
+				}
+			}
+			if tracks != tt.wantTracks || syncs != tt.wantSyncs {
+				t.Errorf("nextJobs() started %d download and %d sync jobs, want %d and %d", tracks, syncs, tt.wantTracks, tt.wantSyncs)
+			}
+		})
+	}
+}
+
+func TestWorker_NextJobsDownloadWindow(t *testing.T) {
+	day := func(hour, minute int) time.Time {
+		return time.Date(2024, 5, 1, hour, minute, 0, 0, time.Local)
+	}
+	tests := []struct {
+		name      string
+		start     string
+		end       string
+		now       time.Time
+		wantJobs  int
+		wantOpens time.Time
+	}{
+		{"no window", "", "", day(12, 0), 1, time.Time{}},
+		{"inside a daytime window", "09:00", "17:00", day(12, 0), 1, time.Time{}},
+		{"after a daytime window", "09:00", "17:00", day(18, 0), 0, time.Date(2024, 5, 2, 9, 0, 0, 0, time.Local)},
+		{"overnight, before midnight", "22:00", "06:00", day(23, 30), 1, time.Time{}},
+		{"overnight, after midnight", "22:00", "06:00", day(5, 59), 1, time.Time{}},
+		{"overnight, daytime", "22:00", "06:00", day(6, 0), 0, day(22, 0)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, cleanup := setupTestDB(t)
+			defer cleanup()
+
+			job := &domain.Job{ID: "download", Type: domain.JobTypeTrack, Status: domain.JobStatusQueued, SourceID: sql.NullString{String: "t1", Valid: true}, CreatedAt: tt.now, UpdatedAt: tt.now}
+			if err := db.CreateJob(job); err != nil {
+				t.Fatalf("CreateJob failed: %v", err)
+			}
+
+			w := &Worker{
+				Repo:          db,
+				Config:        &config.Config{DownloadWindowStart: tt.start, DownloadWindowEnd: tt.end},
+				Logger:        logger.Default(),
+				Stats:         app.NewWorkerStats(),
+				MaxConcurrent: 2,
+				MaxMetadata:   1,
+				now:           func() time.Time { return tt.now },
+			}
+			next, err := w.nextJobs()
+			if err != nil {
+				t.Fatalf("nextJobs failed: %v", err)
+			}
+			if len(next) != tt.wantJobs {
+				t.Errorf("nextJobs() started %d jobs, want %d", len(next), tt.wantJobs)
+			}
+			if got := w.Stats.WindowOpens(); !got.Equal(tt.wantOpens) {
+				t.Errorf("WindowOpens() = %v, want %v", got, tt.wantOpens)
+			}
+		})
+	}
+}
+
+func TestWorker_NextJobsReorganize(t *testing.T) {
+	tests := []struct {
+		name       string
+		reorganize domain.JobStatus
+		running    bool
+		want       []string
+	}{
+		{"waits for running jobs", domain.JobStatusQueued, true, nil},
+		{"starts alone once idle", domain.JobStatusQueued, false, []string{"reorganize"}},
+		{"holds the queue while running", domain.JobStatusRunning, false, nil},
+		{"queue resumes when done", domain.JobStatusCompleted, false, []string{"download", "sync"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, cleanup := setupTestDB(t)
+			defer cleanup()
+
+			start := time.Now().Add(-time.Hour)
+			newJob := func(id string, jobType domain.JobType, status domain.JobStatus, at time.Time) *domain.Job {
+				return &domain.Job{ID: id, Type: jobType, Status: status, SourceID: sql.NullString{String: id, Valid: true}, CreatedAt: at, UpdatedAt: at}
+			}
+			jobs := []*domain.Job{
+				newJob("reorganize", domain.JobTypeReorganize, tt.reorganize, start.Add(time.Minute)),
+				newJob("download", domain.JobTypeTrack, domain.JobStatusQueued, start.Add(2*time.Minute)),
+				newJob("sync", domain.JobTypeSyncMusicBrainz, domain.JobStatusQueued, start.Add(3*time.Minute)),
+			}
+			if tt.running {
+				jobs = append(jobs, newJob("running", domain.JobTypeTrack, domain.JobStatusRunning, start))
+			}
+			if err := db.CreateJobBatch(jobs); err != nil {
+				t.Fatalf("CreateJobBatch failed: %v", err)
+			}
+
+			w := &Worker{Repo: db, MaxConcurrent: 2, MaxMetadata: 1}
+			next, err := w.nextJobs()
+			if err != nil {
+				t.Fatalf("nextJobs failed: %v", err)
+			}
+
+			var got []string
+			for _, j := range next {
+				got = append(got, j.ID)
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("nextJobs() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+// drainHandler completes the jobs in finish as soon as the worker starts shutting down and
+// runs any other job until its context is cancelled.
+type drainHandler struct {
+	worker  *Worker
+	started chan string
+	finish  map[string]bool
+}
+
+func (h *drainHandler) Handle(ctx context.Context, job *domain.Job, logger *slog.Logger) error {
+	h.started <- job.ID
+	if h.finish[job.ID] {
+		<-h.worker.pollCtx.Done()
+		return h.worker.Repo.UpdateJobStatus(job.ID, domain.JobStatusCompleted, 100)
+	}
+	<-ctx.Done()
+	return ctx.Err()
+}
+
+func TestWorker_Shutdown(t *testing.T) {
+	tests := []struct {
+		name            string
+		drain           time.Duration
+		finish          []string
+		wantDrained     int
+		wantInterrupted int
+	}{
+		{"running jobs finish within the drain", 10 * time.Second, []string{"a", "b"}, 2, 0},
+		{"deadline interrupts a slow job", 50 * time.Millisecond, []string{"a"}, 1, 1},
+		{"no drain period", 0, nil, 0, 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, cleanup := setupTestDB(t)
+			defer cleanup()
+
+			var jobs []*domain.Job
+			for _, id := range []string{"a", "b", "c"} {
+				jobs = append(jobs, &domain.Job{ID: id, Type: domain.JobTypeVerify, Status: domain.JobStatusQueued, SourceID: sql.NullString{String: id, Valid: true}, CreatedAt: time.Now(), UpdatedAt: time.Now()})
+			}
+			if err := db.CreateJobBatch(jobs); err != nil {
+				t.Fatalf("CreateJobBatch failed: %v", err)
+			}
+
+			ctx, cancel := context.WithCancel(context.Background())
+			pollCtx, stopPolling := context.WithCancel(ctx)
+			w := &Worker{
+				Repo:        db,
+				Logger:      logger.Default(),
+				Running:     app.NewRunningJobs(),
+				Stats:       app.NewWorkerStats(),
+				dispatcher:  NewDispatcher(),
+				ctx:         ctx,
+				cancel:      cancel,
+				pollCtx:     pollCtx,
+				stopPolling: stopPolling,
+			}
+			handler := &drainHandler{worker: w, started: make(chan string, len(jobs)), finish: make(map[string]bool)}
+			for _, id := range tt.finish {
+				handler.finish[id] = true
+			}
+			w.dispatcher.Register(domain.JobTypeVerify, handler)
+
+			slots := make(chan struct{}, 2)
+			for _, job := range jobs[:2] {
+				if !w.startJob(job, slots) {
+					t.Fatalf("startJob(%s) = false before shutdown", job.ID)
+				}
+			}
+			<-handler.started
+			<-handler.started
+			if got := w.Stats.Active(); got != 2 {
+				t.Errorf("active jobs = %d while running, want 2", got)
+			}
+
+			drained, interrupted := w.Shutdown(tt.drain)
+			if drained != tt.wantDrained || interrupted != tt.wantInterrupted {
+				t.Errorf("Shutdown() = %d drained, %d interrupted, want %d, %d", drained, interrupted, tt.wantDrained, tt.wantInterrupted)
+			}
+			if w.startJob(jobs[2], make(chan struct{}, 1)) {
+				t.Error("startJob() = true after shutdown, want false")
+			}
+			if got := w.Stats.Active(); got != 0 {
+				t.Errorf("active jobs = %d after shutdown, want 0", got)
+			}
+			for _, id := range tt.finish {
+				if job, _ := db.GetJob(id); job.Status != domain.JobStatusCompleted {
+					t.Errorf("job %s status = %s, want completed", id, job.Status)
+				}
+			}
+		})
+	}
+}
+
+// failHandler fails every job the way the container handlers do.
+type failHandler struct{ repo *store.DB }
+
+func (h failHandler) Handle(ctx context.Context, job *domain.Job, logger *slog.Logger) error {
+	_ = h.repo.UpdateJobError(job.ID, "Failed to fetch album")
+	return errors.New("failed to fetch album")
+}
+
+func TestWorker_RunJobFailedChildUpdatesParent(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	now := time.Now()
+	parent := &domain.Job{ID: "disco", Type: domain.JobTypeDiscography, Status: domain.JobStatusDecomposed, SourceID: sql.NullString{String: "artist1", Valid: true}, CreatedAt: now, UpdatedAt: now}
+	done := &domain.Job{ID: "a1", Type: domain.JobTypeAlbum, Status: domain.JobStatusCompleted, SourceID: sql.NullString{String: "a1", Valid: true}, ParentJobID: sql.NullString{String: "disco", Valid: true}, CreatedAt: now, UpdatedAt: now}
+	child := &domain.Job{ID: "a2", Type: domain.JobTypeAlbum, Status: domain.JobStatusQueued, SourceID: sql.NullString{String: "a2", Valid: true}, ParentJobID: sql.NullString{String: "disco", Valid: true}, CreatedAt: now, UpdatedAt: now}
+	if err := db.CreateJobBatch([]*domain.Job{parent, done, child}); err != nil {
+		t.Fatalf("CreateJobBatch failed: %v", err)
+	}
+
+	w := &Worker{Repo: db, Logger: logger.Default(), Running: app.NewRunningJobs(), dispatcher: NewDispatcher()}
+	w.dispatcher.Register(domain.JobTypeAlbum, failHandler{repo: db})
+	w.runJob(context.Background(), child)
+
+	if got, _ := db.GetJob(child.ID); got.Status != domain.JobStatusFailed {
+		t.Fatalf("child status = %s, want failed", got.Status)
+	}
+	if got, _ := db.GetJob(parent.ID); got.Status != domain.JobStatusCompleted {
+		t.Errorf("parent status = %s, want completed once no child is pending", got.Status)
+	}
+}
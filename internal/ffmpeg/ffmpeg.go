@@ -1,34 +1,39 @@
 package ffmpeg
 
 import (
+	"bytes"
 	"context"
 	"fmt"
 	"os"
 	"os/exec"
 	"path/filepath"
+	"strconv"
 	"time"
 )
 
 type Metadata struct {
-	Custom       map[string]string
-	Lyrics       string
-	Title        string
-	Album        string
-	Genre        string
-	Mood         string
-	Language     string
-	Composer     string
-	Copyright    string
-	CoverMime    string
-	AlbumArtists []string
-	CoverArt     []byte
-	Artists      []string
-	Year         int
-	TrackTotal   int
-	DiscNum      int
-	DiscTotal    int
-	BPM          int
-	TrackNum     int
+	Custom          map[string]string
+	Lyrics          string
+	Title           string
+	Album           string
+	AlbumSort       string
+	ArtistSort      string
+	AlbumArtistSort string
+	Genre           string
+	Mood            string
+	Language        string
+	Composer        string
+	Copyright       string
+	CoverMime       string
+	AlbumArtists    []string
+	CoverArt        []byte
+	Artists         []string
+	Year            int
+	TrackTotal      int
+	DiscNum         int
+	DiscTotal       int
+	BPM             int
+	TrackNum        int
 }
 
 var (
@@ -98,6 +103,15 @@ func buildArgs(inputPath string, meta *Metadata, coverPath string) []string {
 	if len(meta.AlbumArtists) > 0 {
 		args = append(args, "-metadata", fmt.Sprintf("album_artist=%s", joinArtists(meta.AlbumArtists)))
 	}
+	if meta.ArtistSort != "" {
+		args = append(args, "-metadata", fmt.Sprintf("sort_artist=%s", meta.ArtistSort))
+	}
+	if meta.AlbumArtistSort != "" {
+		args = append(args, "-metadata", fmt.Sprintf("sort_album_artist=%s", meta.AlbumArtistSort))
+	}
+	if meta.AlbumSort != "" {
+		args = append(args, "-metadata", fmt.Sprintf("sort_album=%s", meta.AlbumSort))
+	}
 	if meta.TrackNum > 0 {
 		trackStr := fmt.Sprintf("%d", meta.TrackNum)
 		if meta.TrackTotal > 0 {
@@ -187,3 +201,41 @@ func ConvertToFLAC(ctx context.Context, inputPath string) (string, error) {
 
 	return outputPath, nil
 }
+
+// ImageToJPEG converts an image in any format ffmpeg decodes, such as WebP or AVIF, to
+// JPEG at quality (1-100, mapped to ffmpeg's 31-2 qscale range).
+func ImageToJPEG(ctx context.Context, data []byte, quality int) ([]byte, error) {
+	tmpFile, err := os.CreateTemp("", "navidrums_image_*")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create temp image: %w", err)
+	}
+	defer func() { _ = os.Remove(tmpFile.Name()) }()
+	_, err = tmpFile.Write(data)
+	if closeErr := tmpFile.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to write temp image: %w", err)
+	}
+
+	qscale := 2 + (100-quality)*29/99
+	args := []string{
+		"-hide_banner", "-loglevel", "error",
+		"-i", tmpFile.Name(),
+		"-frames:v", "1",
+		"-c:v", "mjpeg",
+		"-q:v", strconv.Itoa(qscale),
+		"-f", "image2pipe",
+		"pipe:1",
+	}
+
+	// #nosec G204 - variable used to specify ffmpeg binary path from config
+	cmd := exec.CommandContext(ctx, ffmpegBin, args...)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	output, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("ffmpeg image conversion failed: %w, output: %s", err, stderr.String())
+	}
+	return output, nil
+}
@@ -0,0 +1,51 @@
+package httpapp
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/go-chi/chi/v5"
+
+	"github.com/cesargomez89/navidrums/internal/app"
+)
+
+// AlbumArtistOverrideHTMX shows the album artist override form of an album.
+func (h *Handler) AlbumArtistOverrideHTMX(w http.ResponseWriter, r *http.Request) {
+	albumID := chi.URLParam(r, "id")
+	albumArtist, err := h.DownloadsService.GetAlbumArtistOverride(albumID)
+	if err != nil {
+		h.log(r).Error("Failed to get album artist override", "album_id", albumID, "error", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+	h.renderAlbumArtistOverride(w, albumID, albumArtist, false, 0)
+}
+
+// SaveAlbumArtistOverrideHTMX sets or clears the album artist override of an album from the
+// album_artist form value and queues the album's downloaded tracks for re-tagging.
+func (h *Handler) SaveAlbumArtistOverrideHTMX(w http.ResponseWriter, r *http.Request) {
+	albumID := chi.URLParam(r, "id")
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, "Failed to parse form", http.StatusBadRequest)
+		return
+	}
+	albumArtist := strings.TrimSpace(r.PostForm.Get("album_artist"))
+
+	queued, err := h.DownloadsService.SetAlbumArtistOverride(albumID, albumArtist)
+	if err != nil {
+		h.log(r).Error("Failed to set album artist override", "album_id", albumID, "error", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+	h.renderAlbumArtistOverride(w, albumID, albumArtist, true, queued)
+}
+
+func (h *Handler) renderAlbumArtistOverride(w http.ResponseWriter, albumID, albumArtist string, saved bool, queued int) {
+	h.RenderFragment(w, "album_artist_override.html", map[string]interface{}{
+		"AlbumID":        albumID,
+		"AlbumArtist":    albumArtist,
+		"VariousArtists": app.VariousArtistsName(h.Config),
+		"Saved":          saved,
+		"Queued":         queued,
+	})
+}
@@ -0,0 +1,99 @@
+package httpapp
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/go-chi/chi/v5"
+
+	"github.com/cesargomez89/navidrums/internal/domain"
+	"github.com/cesargomez89/navidrums/internal/store"
+)
+
+// createdAPIKey is the response to creating a key: the only time the key itself is shown.
+type createdAPIKey struct {
+	domain.APIKey
+	Key string `json:"key"`
+}
+
+// requireHumanAuth rejects requests authenticated with an API key, so a leaked key cannot
+// mint or revoke other keys.
+func (h *Handler) requireHumanAuth(w http.ResponseWriter, r *http.Request) bool {
+	if authenticatedByAPIKey(r) {
+		http.Error(w, "API keys cannot manage API keys", http.StatusForbidden)
+		return false
+	}
+	return true
+}
+
+// ListAPIKeysAPI lists API keys without their hashes.
+func (h *Handler) ListAPIKeysAPI(w http.ResponseWriter, r *http.Request) {
+	if !h.requireHumanAuth(w, r) {
+		return
+	}
+	keys, err := h.SettingsRepo.ListAPIKeys()
+	if err != nil {
+		h.log(r).Error("Failed to list API keys", "error", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+	for i := range keys {
+		keys[i].Hash = ""
+	}
+	if keys == nil {
+		keys = []domain.APIKey{}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(keys); err != nil {
+		h.log(r).Error("Failed to encode API keys", "error", err)
+	}
+}
+
+// CreateAPIKeyAPI creates a key named by the name parameter and returns it once.
+func (h *Handler) CreateAPIKeyAPI(w http.ResponseWriter, r *http.Request) {
+	if !h.requireHumanAuth(w, r) {
+		return
+	}
+	name := strings.TrimSpace(r.FormValue("name"))
+	if name == "" {
+		http.Error(w, "name is required", http.StatusBadRequest)
+		return
+	}
+
+	key, apiKey, err := h.SettingsRepo.CreateAPIKey(name)
+	if err != nil {
+		h.log(r).Error("Failed to create API key", "error", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+	h.log(r).Info("API key created", "id", apiKey.ID, "name", apiKey.Name)
+
+	apiKey.Hash = ""
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	if err := json.NewEncoder(w).Encode(createdAPIKey{APIKey: *apiKey, Key: key}); err != nil {
+		h.log(r).Error("Failed to encode API key", "error", err)
+	}
+}
+
+// RevokeAPIKeyAPI deletes a key; requests using it are rejected from then on.
+func (h *Handler) RevokeAPIKeyAPI(w http.ResponseWriter, r *http.Request) {
+	if !h.requireHumanAuth(w, r) {
+		return
+	}
+	id := chi.URLParam(r, "id")
+	if err := h.SettingsRepo.RevokeAPIKey(id); err != nil {
+		if errors.Is(err, store.ErrAPIKeyNotFound) {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
+		h.log(r).Error("Failed to revoke API key", "id", id, "error", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+	h.log(r).Info("API key revoked", "id", id)
+	w.WriteHeader(http.StatusNoContent)
+}
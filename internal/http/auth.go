@@ -0,0 +1,246 @@
+package httpapp
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+
+	"github.com/cesargomez89/navidrums/internal/constants"
+	"github.com/cesargomez89/navidrums/internal/domain"
+)
+
+// sessionCookieName is the cookie holding a signed login session.
+const sessionCookieName = "navidrums_session"
+
+// SessionSigner issues and checks stateless session cookies: the username and an expiry,
+// signed with HMAC-SHA256. Nothing is stored server side, so changing the secret ends
+// every session.
+type SessionSigner struct {
+	secret []byte
+	ttl    time.Duration
+}
+
+// NewSessionSigner returns a signer for sessions lasting ttl, or DefaultSessionTTL when ttl
+// is not positive. An empty secret is replaced by a random one, which ends every session
+// when the process restarts.
+func NewSessionSigner(secret string, ttl time.Duration) *SessionSigner {
+	key := []byte(secret)
+	if len(key) == 0 {
+		key = make([]byte, 32)
+		_, _ = rand.Read(key)
+	}
+	if ttl <= 0 {
+		ttl = constants.DefaultSessionTTL
+	}
+	return &SessionSigner{secret: key, ttl: ttl}
+}
+
+// Sign returns a cookie value for username that expires ttl after now.
+func (s *SessionSigner) Sign(username string, now time.Time) string {
+	expires := strconv.FormatInt(now.Add(s.ttl).Unix(), 10)
+	payload := base64.RawURLEncoding.EncodeToString([]byte(expires + "|" + username))
+	return payload + "." + s.mac(payload)
+}
+
+// Verify returns the username of a cookie value that is correctly signed and not expired.
+func (s *SessionSigner) Verify(value string, now time.Time) (string, bool) {
+	payload, sig, ok := strings.Cut(value, ".")
+	if !ok || !hmac.Equal([]byte(sig), []byte(s.mac(payload))) {
+		return "", false
+	}
+	raw, err := base64.RawURLEncoding.DecodeString(payload)
+	if err != nil {
+		return "", false
+	}
+	expires, username, ok := strings.Cut(string(raw), "|")
+	if !ok {
+		return "", false
+	}
+	unix, err := strconv.ParseInt(expires, 10, 64)
+	if err != nil || !now.Before(time.Unix(unix, 0)) {
+		return "", false
+	}
+	return username, true
+}
+
+func (s *SessionSigner) mac(payload string) string {
+	m := hmac.New(sha256.New, s.secret)
+	m.Write([]byte(payload))
+	return base64.RawURLEncoding.EncodeToString(m.Sum(nil))
+}
+
+// authEnabled reports whether requests must be authenticated.
+func (h *Handler) authEnabled() bool {
+	return h.Config.Password != "" && !h.Config.SkipAuth
+}
+
+// checkCredentials compares a username and password with the configured ones in constant time.
+func (h *Handler) checkCredentials(username, password string) bool {
+	userOK := subtle.ConstantTimeCompare([]byte(username), []byte(h.Config.Username)) == 1
+	passOK := subtle.ConstantTimeCompare([]byte(password), []byte(h.Config.Password)) == 1
+	return userOK && passOK
+}
+
+// hasSession reports whether the request carries a valid session for the configured user.
+func (h *Handler) hasSession(r *http.Request) bool {
+	cookie, err := r.Cookie(sessionCookieName)
+	if err != nil {
+		return false
+	}
+	username, ok := h.sessions.Verify(cookie.Value, time.Now())
+	return ok && subtle.ConstantTimeCompare([]byte(username), []byte(h.Config.Username)) == 1
+}
+
+// apiKeyContextKey marks requests authenticated with an API key.
+type apiKeyContextKey struct{}
+
+// bearerToken returns the token of an "Authorization: Bearer" header on an /api/ route.
+func bearerToken(r *http.Request) string {
+	if !strings.HasPrefix(r.URL.Path, "/api/") {
+		return ""
+	}
+	auth := r.Header.Get("Authorization")
+	if len(auth) < 7 || !strings.EqualFold(auth[:7], "Bearer ") {
+		return ""
+	}
+	return strings.TrimSpace(auth[7:])
+}
+
+// authenticatedByAPIKey reports whether RequireAuth let the request in with an API key.
+func authenticatedByAPIKey(r *http.Request) bool {
+	_, ok := r.Context().Value(apiKeyContextKey{}).(*domain.APIKey)
+	return ok
+}
+
+// RequireAuth lets through requests with a valid session cookie or Basic auth credentials,
+// and /api/ requests with a valid API key as a Bearer token. Other page loads are
+// redirected to the login page, HTMX requests are told to go there, and API clients get a
+// Basic auth challenge.
+func (h *Handler) RequireAuth(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if token := bearerToken(r); token != "" {
+			apiKey, err := h.SettingsRepo.VerifyAPIKey(token)
+			if err != nil {
+				h.log(r).Error("Failed to verify API key", "error", err)
+				http.Error(w, "Internal server error", http.StatusInternalServerError)
+				return
+			}
+			if apiKey == nil {
+				http.Error(w, "Invalid API key", http.StatusUnauthorized)
+				return
+			}
+			next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), apiKeyContextKey{}, apiKey)))
+			return
+		}
+		if h.hasSession(r) {
+			next.ServeHTTP(w, r)
+			return
+		}
+		if user, pass, ok := r.BasicAuth(); ok && h.checkCredentials(user, pass) {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		switch {
+		case r.Header.Get("HX-Request") == "true":
+			w.Header().Set("HX-Redirect", "/login")
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		case r.Method == http.MethodGet && strings.Contains(r.Header.Get("Accept"), "text/html"):
+			http.Redirect(w, r, "/login?next="+url.QueryEscape(r.URL.RequestURI()), http.StatusSeeOther)
+		default:
+			w.Header().Set("WWW-Authenticate", `Basic realm="Navidrums"`)
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		}
+	})
+}
+
+// RegisterAuthRoutes registers the login and logout routes, which must be reachable without
+// authentication.
+func (h *Handler) RegisterAuthRoutes(r chi.Router) {
+	r.Get("/login", h.LoginPage)
+	r.Post("/login", h.Login)
+	r.Post("/logout", h.Logout)
+}
+
+func (h *Handler) LoginPage(w http.ResponseWriter, r *http.Request) {
+	next := safeRedirect(r.URL.Query().Get("next"))
+	if h.hasSession(r) {
+		http.Redirect(w, r, next, http.StatusSeeOther)
+		return
+	}
+	h.RenderPage(w, "login.html", map[string]interface{}{
+		"ActivePage": "login",
+		"Next":       next,
+		"CSRFToken":  CSRFToken(r),
+	})
+}
+
+func (h *Handler) Login(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, "Invalid form", http.StatusBadRequest)
+		return
+	}
+	username := r.PostForm.Get("username")
+	next := safeRedirect(r.PostForm.Get("next"))
+
+	if !h.checkCredentials(username, r.PostForm.Get("password")) {
+		h.log(r).Warn("Failed login attempt", "username", username, "remote_addr", r.RemoteAddr)
+		w.WriteHeader(http.StatusUnauthorized)
+		h.RenderPage(w, "login.html", map[string]interface{}{
+			"ActivePage": "login",
+			"Next":       next,
+			"Username":   username,
+			"Error":      "Invalid username or password",
+			"CSRFToken":  CSRFToken(r),
+		})
+		return
+	}
+
+	http.SetCookie(w, &http.Cookie{
+		Name:     sessionCookieName,
+		Value:    h.sessions.Sign(username, time.Now()),
+		Path:     "/",
+		MaxAge:   int(h.sessions.ttl.Seconds()),
+		HttpOnly: true,
+		Secure:   isHTTPS(r),
+		SameSite: http.SameSiteLaxMode,
+	})
+	http.Redirect(w, r, next, http.StatusSeeOther)
+}
+
+func (h *Handler) Logout(w http.ResponseWriter, r *http.Request) {
+	http.SetCookie(w, &http.Cookie{
+		Name:     sessionCookieName,
+		Value:    "",
+		Path:     "/",
+		MaxAge:   -1,
+		HttpOnly: true,
+		Secure:   isHTTPS(r),
+		SameSite: http.SameSiteLaxMode,
+	})
+	http.Redirect(w, r, "/login", http.StatusSeeOther)
+}
+
+// safeRedirect returns next when it is a path on this site, and "/" otherwise, so the
+// login form cannot be used to redirect to another host.
+func safeRedirect(next string) string {
+	if !strings.HasPrefix(next, "/") || strings.HasPrefix(next, "//") || strings.HasPrefix(next, "/\\") {
+		return "/"
+	}
+	return next
+}
+
+// isHTTPS reports whether the client reached us over TLS, directly or through a proxy.
+func isHTTPS(r *http.Request) bool {
+	return r.TLS != nil || strings.EqualFold(r.Header.Get("X-Forwarded-Proto"), "https")
+}
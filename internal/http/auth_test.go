@@ -0,0 +1,253 @@
+package httpapp
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/cesargomez89/navidrums/internal/config"
+	"github.com/cesargomez89/navidrums/internal/logger"
+	"github.com/cesargomez89/navidrums/internal/store"
+)
+
+func setupAuthHandler(t *testing.T) (*Handler, func()) {
+	db, cleanup := setupTestDB(t)
+	h := &Handler{
+		Config:       &config.Config{Username: "admin", Password: "secret", Theme: "golden"},
+		SettingsRepo: store.NewSettingsRepo(db),
+		Logger:       logger.Default(),
+		sessions:     NewSessionSigner("test-secret", time.Hour),
+	}
+	return h, cleanup
+}
+
+func TestSessionSigner(t *testing.T) {
+	signer := NewSessionSigner("test-secret", time.Hour)
+	now := time.Now()
+	valid := signer.Sign("admin", now)
+	payload, sig, _ := strings.Cut(valid, ".")
+	flipped := "A"
+	if strings.HasSuffix(sig, "A") {
+		flipped = "B"
+	}
+
+	tests := []struct {
+		name   string
+		signer *SessionSigner
+		value  string
+		at     time.Time
+		wantOK bool
+	}{
+		{"valid", signer, valid, now, true},
+		{"expired", signer, valid, now.Add(2 * time.Hour), false},
+		{"tampered payload", signer, payload + "x." + sig, now, false},
+		{"tampered signature", signer, payload + "." + sig[:len(sig)-1] + flipped, now, false},
+		{"other secret", NewSessionSigner("other-secret", time.Hour), valid, now, false},
+		{"unsigned", signer, payload, now, false},
+		{"empty", signer, "", now, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			username, ok := tt.signer.Verify(tt.value, tt.at)
+			if ok != tt.wantOK {
+				t.Fatalf("Verify() ok = %v, want %v", ok, tt.wantOK)
+			}
+			if ok && username != "admin" {
+				t.Errorf("Verify() username = %q, want admin", username)
+			}
+		})
+	}
+}
+
+func TestHandler_Login(t *testing.T) {
+	h, cleanup := setupAuthHandler(t)
+	defer cleanup()
+
+	tests := []struct {
+		name         string
+		username     string
+		password     string
+		next         string
+		wantStatus   int
+		wantLocation string
+		wantCookie   bool
+	}{
+		{"success", "admin", "secret", "/downloads?q=x", http.StatusSeeOther, "/downloads?q=x", true},
+		{"success ignores off-site next", "admin", "secret", "//evil.example", http.StatusSeeOther, "/", true},
+		{"wrong password", "admin", "nope", "/", http.StatusUnauthorized, "", false},
+		{"wrong username", "root", "secret", "/", http.StatusUnauthorized, "", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			form := url.Values{"username": {tt.username}, "password": {tt.password}, "next": {tt.next}}
+			r := httptest.NewRequest(http.MethodPost, "/login", strings.NewReader(form.Encode()))
+			r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+			rec := httptest.NewRecorder()
+			h.Login(rec, r)
+
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d", rec.Code, tt.wantStatus)
+			}
+			if loc := rec.Header().Get("Location"); loc != tt.wantLocation {
+				t.Errorf("Location = %q, want %q", loc, tt.wantLocation)
+			}
+			if !tt.wantCookie && !strings.Contains(rec.Body.String(), "Invalid username or password") {
+				t.Errorf("expected the login page with an error, got %q", rec.Body.String())
+			}
+
+			var session *http.Cookie
+			for _, c := range rec.Result().Cookies() {
+				if c.Name == sessionCookieName {
+					session = c
+				}
+			}
+			if (session != nil) != tt.wantCookie {
+				t.Fatalf("session cookie set = %v, want %v", session != nil, tt.wantCookie)
+			}
+			if session != nil {
+				if !session.HttpOnly {
+					t.Error("session cookie should be HttpOnly")
+				}
+				if _, ok := h.sessions.Verify(session.Value, time.Now()); !ok {
+					t.Error("session cookie does not verify")
+				}
+			}
+		})
+	}
+}
+
+func TestHandler_RequireAuth(t *testing.T) {
+	h, cleanup := setupAuthHandler(t)
+	defer cleanup()
+
+	protected := h.RequireAuth(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	otherUser := h.sessions.Sign("someone-else", time.Now())
+
+	tests := []struct {
+		name       string
+		setup      func(r *http.Request)
+		wantStatus int
+		wantHeader [2]string
+	}{
+		{"session cookie", func(r *http.Request) {
+			r.AddCookie(&http.Cookie{Name: sessionCookieName, Value: h.sessions.Sign("admin", time.Now())})
+		}, http.StatusOK, [2]string{}},
+		{"basic auth", func(r *http.Request) { r.SetBasicAuth("admin", "secret") }, http.StatusOK, [2]string{}},
+		{"session for another user", func(r *http.Request) {
+			r.AddCookie(&http.Cookie{Name: sessionCookieName, Value: otherUser})
+		}, http.StatusUnauthorized, [2]string{"WWW-Authenticate", `Basic realm="Navidrums"`}},
+		{"wrong basic auth", func(r *http.Request) { r.SetBasicAuth("admin", "nope") }, http.StatusUnauthorized, [2]string{"WWW-Authenticate", `Basic realm="Navidrums"`}},
+		{"browser redirected to login", func(r *http.Request) { r.Header.Set("Accept", "text/html") }, http.StatusSeeOther, [2]string{"Location", "/login?next=%2Fdownloads"}},
+		{"htmx told to redirect", func(r *http.Request) { r.Header.Set("HX-Request", "true") }, http.StatusUnauthorized, [2]string{"HX-Redirect", "/login"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, "/downloads", nil)
+			tt.setup(r)
+			rec := httptest.NewRecorder()
+			protected.ServeHTTP(rec, r)
+
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d", rec.Code, tt.wantStatus)
+			}
+			if tt.wantHeader[0] != "" && rec.Header().Get(tt.wantHeader[0]) != tt.wantHeader[1] {
+				t.Errorf("%s = %q, want %q", tt.wantHeader[0], rec.Header().Get(tt.wantHeader[0]), tt.wantHeader[1])
+			}
+		})
+	}
+}
+
+func TestHandler_RequireAuth_APIKey(t *testing.T) {
+	h, cleanup := setupAuthHandler(t)
+	defer cleanup()
+
+	protected := h.RequireAuth(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !authenticatedByAPIKey(r) {
+			w.WriteHeader(http.StatusTeapot)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	key, _, err := h.SettingsRepo.CreateAPIKey("script")
+	if err != nil {
+		t.Fatalf("CreateAPIKey failed: %v", err)
+	}
+	revokedKey, revoked, err := h.SettingsRepo.CreateAPIKey("old")
+	if err != nil {
+		t.Fatalf("CreateAPIKey failed: %v", err)
+	}
+	if err := h.SettingsRepo.RevokeAPIKey(revoked.ID); err != nil {
+		t.Fatalf("RevokeAPIKey failed: %v", err)
+	}
+
+	tests := []struct {
+		name       string
+		path       string
+		auth       string
+		wantStatus int
+	}{
+		{"valid key", "/api/v1/verify", "Bearer " + key, http.StatusOK},
+		{"lowercase scheme", "/api/v1/verify", "bearer " + key, http.StatusOK},
+		{"invalid key", "/api/v1/verify", "Bearer ndk_nope", http.StatusUnauthorized},
+		{"revoked key", "/api/v1/verify", "Bearer " + revokedKey, http.StatusUnauthorized},
+		{"key outside the API", "/downloads", "Bearer " + key, http.StatusUnauthorized},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, tt.path, nil)
+			r.Header.Set("Authorization", tt.auth)
+			rec := httptest.NewRecorder()
+			protected.ServeHTTP(rec, r)
+			if rec.Code != tt.wantStatus {
+				t.Errorf("status = %d, want %d", rec.Code, tt.wantStatus)
+			}
+		})
+	}
+}
+
+func TestHandler_RequireAuth_CSRF(t *testing.T) {
+	h, cleanup := setupAuthHandler(t)
+	defer cleanup()
+
+	// The middleware order of cmd/server: CSRFProtect, then RequireAuth.
+	protected := CSRFProtect(h.RequireAuth(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})))
+	key, _, err := h.SettingsRepo.CreateAPIKey("script")
+	if err != nil {
+		t.Fatalf("CreateAPIKey failed: %v", err)
+	}
+
+	tests := []struct {
+		name       string
+		setup      func(r *http.Request)
+		wantStatus int
+	}{
+		{"API key", func(r *http.Request) { r.Header.Set("Authorization", "Bearer "+key) }, http.StatusOK},
+		{"basic auth", func(r *http.Request) { r.SetBasicAuth("admin", "secret") }, http.StatusOK},
+		{"session without token", func(r *http.Request) {
+			r.AddCookie(&http.Cookie{Name: sessionCookieName, Value: h.sessions.Sign("admin", time.Now())})
+		}, http.StatusForbidden},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodPost, "/api/v1/jobs/batch", nil)
+			tt.setup(r)
+			rec := httptest.NewRecorder()
+			protected.ServeHTTP(rec, r)
+			if rec.Code != tt.wantStatus {
+				t.Errorf("status = %d, want %d", rec.Code, tt.wantStatus)
+			}
+		})
+	}
+}
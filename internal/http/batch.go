@@ -0,0 +1,80 @@
+package httpapp
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/cesargomez89/navidrums/internal/app"
+	"github.com/cesargomez89/navidrums/internal/domain"
+)
+
+// maxBatchBody caps a plain-text list posted to EnqueueBatchAPI.
+const maxBatchBody = 1 << 20
+
+// batchRequest reads a pasted list: the urls form field, or else the request body as
+// plain text, one URL or ID per line. type (default track) is the job type of bare IDs.
+func batchRequest(w http.ResponseWriter, r *http.Request) ([]string, domain.JobType, error) {
+	if err := r.ParseForm(); err != nil {
+		return nil, "", fmt.Errorf("invalid form")
+	}
+	defaultType := domain.JobTypeTrack
+	if t := r.FormValue("type"); t != "" {
+		defaultType = domain.JobType(t)
+	}
+	if !app.IsBatchType(defaultType) {
+		return nil, "", fmt.Errorf("type must be track, album, playlist, artist or discography")
+	}
+
+	text := r.FormValue("urls")
+	if text == "" {
+		body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxBatchBody))
+		if err != nil {
+			return nil, "", fmt.Errorf("request body too large")
+		}
+		text = string(body)
+	}
+	if strings.TrimSpace(text) == "" {
+		return nil, "", fmt.Errorf("paste at least one URL or ID")
+	}
+	return strings.Split(text, "\n"), defaultType, nil
+}
+
+// EnqueueBatchAPI enqueues a job for each line of a pasted list of provider URLs or IDs
+// and returns how each line went.
+func (h *Handler) EnqueueBatchAPI(w http.ResponseWriter, r *http.Request) {
+	lines, defaultType, err := batchRequest(w, r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	results := h.jobs(r).EnqueueBatch(lines, defaultType)
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(results); err != nil {
+		h.log(r).Error("Failed to encode batch results", "error", err)
+	}
+}
+
+// EnqueueBatchHTMX is EnqueueBatchAPI for the queue page's paste box.
+func (h *Handler) EnqueueBatchHTMX(w http.ResponseWriter, r *http.Request) {
+	lines, defaultType, err := batchRequest(w, r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	results := h.jobs(r).EnqueueBatch(lines, defaultType)
+
+	queued := 0
+	for _, res := range results {
+		if res.Status == app.BatchQueued {
+			queued++
+		}
+	}
+	h.RenderFragment(w, "batch_results.html", map[string]interface{}{
+		"Results": results,
+		"Queued":  queued,
+	})
+}
@@ -0,0 +1,97 @@
+package httpapp
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/cesargomez89/navidrums/internal/app"
+	"github.com/cesargomez89/navidrums/internal/domain"
+	"github.com/cesargomez89/navidrums/internal/logger"
+)
+
+func TestHandler_EnqueueBatch(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	log := logger.Default()
+	jobs := app.NewJobService(db, log)
+	active, err := jobs.EnqueueJob("200", domain.JobTypeAlbum)
+	if err != nil {
+		t.Fatalf("EnqueueJob failed: %v", err)
+	}
+	if err := db.CreateTrack(&domain.Track{
+		ProviderID: "300", Title: "Done", Status: domain.TrackStatusCompleted,
+		CreatedAt: time.Now(), UpdatedAt: time.Now(),
+	}); err != nil {
+		t.Fatalf("CreateTrack failed: %v", err)
+	}
+	h := &Handler{JobService: jobs, Logger: log}
+
+	body := strings.Join([]string{
+		"https://tidal.com/browse/album/100",
+		"",
+		"# wishlist",
+		"https://example.com/not/a/release",
+		"album:100",
+		"https://listen.tidal.com/album/200",
+		"300",
+		"https://open.qobuz.com/track/400",
+	}, "\n")
+	rec := httptest.NewRecorder()
+	h.EnqueueBatchAPI(rec, httptest.NewRequest(http.MethodPost, "/api/v1/jobs/batch", strings.NewReader(body)))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, want %d: %s", rec.Code, http.StatusOK, rec.Body.String())
+	}
+
+	var results []app.BatchResult
+	if err := json.Unmarshal(rec.Body.Bytes(), &results); err != nil {
+		t.Fatalf("failed to decode results: %v", err)
+	}
+	want := []struct {
+		status string
+		id     string
+	}{
+		{app.BatchQueued, "100"},
+		{app.BatchError, ""},
+		{app.BatchDuplicate, "100"},
+		{app.BatchDuplicate, "200"},
+		{app.BatchDuplicate, "300"},
+		{app.BatchQueued, "400"},
+	}
+	if len(results) != len(want) {
+		t.Fatalf("got %d results, want %d: %+v", len(results), len(want), results)
+	}
+	for i, w := range want {
+		if results[i].Status != w.status || results[i].ID != w.id {
+			t.Errorf("result %d = %s %q, want %s %q", i, results[i].Status, results[i].ID, w.status, w.id)
+		}
+	}
+	if results[1].Error == "" {
+		t.Error("unparsable line has no error")
+	}
+	if results[3].JobID != active.ID {
+		t.Errorf("active duplicate job_id = %q, want %q", results[3].JobID, active.ID)
+	}
+
+	for _, tc := range []struct {
+		source  string
+		jobType domain.JobType
+	}{{"100", domain.JobTypeAlbum}, {"400", domain.JobTypeTrack}} {
+		job, err := db.GetActiveJobBySourceID(tc.source, tc.jobType)
+		if err != nil || job == nil {
+			t.Errorf("no %s job queued for %s: %v", tc.jobType, tc.source, err)
+		}
+	}
+
+	t.Run("empty list", func(t *testing.T) {
+		rec := httptest.NewRecorder()
+		h.EnqueueBatchAPI(rec, httptest.NewRequest(http.MethodPost, "/api/v1/jobs/batch", strings.NewReader("\n  \n")))
+		if rec.Code != http.StatusBadRequest {
+			t.Errorf("status = %d, want %d", rec.Code, http.StatusBadRequest)
+		}
+	})
+}
@@ -0,0 +1,132 @@
+package httpapp
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+
+	"github.com/cesargomez89/navidrums/internal/app"
+	"github.com/cesargomez89/navidrums/internal/catalog"
+	"github.com/cesargomez89/navidrums/internal/config"
+	"github.com/cesargomez89/navidrums/internal/domain"
+	"github.com/cesargomez89/navidrums/internal/logger"
+	"github.com/cesargomez89/navidrums/internal/store"
+)
+
+func TestHandler_AlbumPageETag(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	// A cached album is served without reaching the provider.
+	data, err := json.Marshal(domain.Album{ID: "1", Title: "Cached Album", Artist: "Artist"})
+	if err != nil {
+		t.Fatalf("Marshal failed: %v", err)
+	}
+	if err := db.SetCache("hifi:album:1", data, time.Hour); err != nil {
+		t.Fatalf("SetCache failed: %v", err)
+	}
+
+	settings := store.NewSettingsRepo(db)
+	h := &Handler{
+		ProviderManager: catalog.NewProviderManager(db, settings, time.Hour, "", logger.Default()),
+		SettingsRepo:    settings,
+		Config:          &config.Config{},
+		Logger:          logger.Default(),
+	}
+	get := func(etag string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/album/1", nil)
+		if etag != "" {
+			req.Header.Set("If-None-Match", etag)
+		}
+		rctx := chi.NewRouteContext()
+		rctx.URLParams.Add("id", "1")
+		req = req.WithContext(context.WithValue(req.Context(), chi.RouteCtxKey, rctx))
+		rec := httptest.NewRecorder()
+		h.AlbumPage(rec, req)
+		return rec
+	}
+
+	first := get("")
+	if first.Code != http.StatusOK || !strings.Contains(first.Body.String(), "Cached Album") {
+		t.Fatalf("status = %d, want 200 with the album: %s", first.Code, first.Body.String())
+	}
+	etag := first.Header().Get("ETag")
+	if etag == "" {
+		t.Fatal("no ETag on the album page")
+	}
+
+	tests := []struct {
+		name string
+		etag string
+		want int
+	}{
+		{"matching ETag", etag, http.StatusNotModified},
+		{"stale ETag", `"stale"`, http.StatusOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := get(tt.etag)
+			if rec.Code != tt.want {
+				t.Errorf("status = %d, want %d", rec.Code, tt.want)
+			}
+			if rec.Code == http.StatusNotModified && rec.Body.Len() != 0 {
+				t.Errorf("304 carried a body of %d bytes", rec.Body.Len())
+			}
+		})
+	}
+}
+
+func TestHandler_DownloadAlbumTracksHTMX(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	data, err := json.Marshal(domain.Album{ID: "1", Title: "Cached Album", Tracks: []domain.CatalogTrack{{ID: "t1"}, {ID: "t2"}, {ID: "t3"}}})
+	if err != nil {
+		t.Fatalf("Marshal failed: %v", err)
+	}
+	if err := db.SetCache("hifi:album:1", data, time.Hour); err != nil {
+		t.Fatalf("SetCache failed: %v", err)
+	}
+
+	settings := store.NewSettingsRepo(db)
+	h := &Handler{
+		ProviderManager: catalog.NewProviderManager(db, settings, time.Hour, "", logger.Default()),
+		JobService:      app.NewJobService(db, logger.Default()),
+		SettingsRepo:    settings,
+		Config:          &config.Config{},
+		Logger:          logger.Default(),
+	}
+
+	tests := []struct {
+		name     string
+		form     string
+		wantCode int
+		wantBody string
+	}{
+		{"nothing selected", "", http.StatusBadRequest, "Select at least one track"},
+		{"track from another album", "track_id=t1&track_id=t9", http.StatusBadRequest, "Track t9 is not on this album"},
+		{"queued", "track_id=t1&track_id=t2", http.StatusOK, "Download of 2 tracks started!"},
+		{"album already queued", "track_id=t3", http.StatusConflict, "already queued"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/htmx/download/album/1/tracks", strings.NewReader(tt.form))
+			req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+			rctx := chi.NewRouteContext()
+			rctx.URLParams.Add("id", "1")
+			req = req.WithContext(context.WithValue(req.Context(), chi.RouteCtxKey, rctx))
+			rec := httptest.NewRecorder()
+			h.DownloadAlbumTracksHTMX(rec, req)
+
+			if rec.Code != tt.wantCode || !strings.Contains(rec.Body.String(), tt.wantBody) {
+				t.Errorf("response = %d %q, want %d containing %q", rec.Code, rec.Body.String(), tt.wantCode, tt.wantBody)
+			}
+		})
+	}
+}
@@ -0,0 +1,100 @@
+package httpapp
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+func TestCSRFProtect(t *testing.T) {
+	handler := CSRFProtect(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(CSRFToken(r)))
+	}))
+
+	// A safe request issues the token cookie.
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/downloads", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("GET status = %d, want 200", rec.Code)
+	}
+	var token string
+	for _, c := range rec.Result().Cookies() {
+		if c.Name == csrfCookieName {
+			token = c.Value
+		}
+	}
+	if token == "" || rec.Body.String() != token {
+		t.Fatalf("expected the issued token in the cookie and context, got cookie %q body %q", token, rec.Body.String())
+	}
+	cookie := &http.Cookie{Name: csrfCookieName, Value: token}
+	session := &http.Cookie{Name: sessionCookieName, Value: "session"}
+
+	tests := []struct {
+		name       string
+		method     string
+		session    bool
+		cookie     *http.Cookie
+		header     string
+		form       string
+		wantStatus int
+	}{
+		{"no token", http.MethodPost, true, cookie, "", "", http.StatusForbidden},
+		{"no cookie", http.MethodPost, true, nil, token, "", http.StatusForbidden},
+		{"wrong token", http.MethodDelete, true, cookie, newCSRFToken(), "", http.StatusForbidden},
+		{"header token", http.MethodPost, true, cookie, token, "", http.StatusOK},
+		{"form token", http.MethodPost, true, cookie, "", token, http.StatusOK},
+		{"delete with header", http.MethodDelete, true, cookie, token, "", http.StatusOK},
+		{"safe method without token", http.MethodGet, true, cookie, "", "", http.StatusOK},
+		{"no session", http.MethodPost, false, nil, "", "", http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var body *strings.Reader
+			if tt.form != "" {
+				body = strings.NewReader(url.Values{csrfFieldName: {tt.form}}.Encode())
+			} else {
+				body = strings.NewReader("")
+			}
+			r := httptest.NewRequest(tt.method, "/htmx/downloads/bulk-delete", body)
+			if tt.form != "" {
+				r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+			}
+			if tt.session {
+				r.AddCookie(session)
+			}
+			if tt.cookie != nil {
+				r.AddCookie(tt.cookie)
+			}
+			if tt.header != "" {
+				r.Header.Set(csrfHeaderName, tt.header)
+			}
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, r)
+			if rec.Code != tt.wantStatus {
+				t.Errorf("status = %d, want %d", rec.Code, tt.wantStatus)
+			}
+		})
+	}
+
+	// API-key requests are exempt even from a browser with a session; the key is checked
+	// by RequireAuth.
+	r := httptest.NewRequest(http.MethodPost, "/api/v1/verify", nil)
+	r.AddCookie(session)
+	r.Header.Set("Authorization", "Bearer ndk_key")
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, r)
+	if rec.Code != http.StatusOK {
+		t.Errorf("Bearer API request status = %d, want 200", rec.Code)
+	}
+	r = httptest.NewRequest(http.MethodPost, "/htmx/downloads/bulk-delete", nil)
+	r.AddCookie(session)
+	r.Header.Set("Authorization", "Bearer ndk_key")
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, r)
+	if rec.Code != http.StatusForbidden {
+		t.Errorf("Bearer non-API request status = %d, want 403", rec.Code)
+	}
+}
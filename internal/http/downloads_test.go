@@ -0,0 +1,121 @@
+package httpapp
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/cesargomez89/navidrums/internal/app"
+	"github.com/cesargomez89/navidrums/internal/config"
+	"github.com/cesargomez89/navidrums/internal/domain"
+	"github.com/cesargomez89/navidrums/internal/logger"
+)
+
+func TestHandler_RecentDownloads(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	cfg := &config.Config{}
+	h := &Handler{
+		DownloadsService: app.NewDownloadsService(db, cfg, logger.Default()),
+		Config:           cfg,
+		Logger:           logger.Default(),
+	}
+	now := time.Now()
+	old := now.AddDate(0, 0, -10)
+	for _, track := range []*domain.Track{
+		{ProviderID: "recent", Title: "New Song", Status: domain.TrackStatusCompleted, CompletedAt: &now},
+		{ProviderID: "older", Title: "Old Song", Status: domain.TrackStatusCompleted, CompletedAt: &old},
+	} {
+		if err := db.CreateTrack(track); err != nil {
+			t.Fatalf("CreateTrack failed: %v", err)
+		}
+		if err := db.UpdateTrack(track); err != nil {
+			t.Fatalf("UpdateTrack failed: %v", err)
+		}
+	}
+
+	tests := []struct {
+		name    string
+		query   string
+		want    []string
+		notWant string
+	}{
+		{"default window", "?filter=recent", []string{now.Format("Monday, Jan 02, 2006"), "New Song", "Old Song", "Last 30 days"}, ""},
+		{"custom window", "?filter=recent&days=7", []string{"New Song", "Last 7 days"}, "Old Song"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			h.DownloadsHTMX(rec, httptest.NewRequest(http.MethodGet, "/htmx/downloads"+tt.query, nil))
+
+			if rec.Code != http.StatusOK {
+				t.Fatalf("status = %d, want 200: %s", rec.Code, rec.Body.String())
+			}
+			body := rec.Body.String()
+			for _, want := range tt.want {
+				if !strings.Contains(body, want) {
+					t.Errorf("body does not contain %q", want)
+				}
+			}
+			if tt.notWant != "" && strings.Contains(body, tt.notWant) {
+				t.Errorf("body contains %q", tt.notWant)
+			}
+		})
+	}
+}
+
+func TestHandler_Duplicates(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	cfg := &config.Config{DownloadsDir: "/music"}
+	h := &Handler{
+		DownloadsService: app.NewDownloadsService(db, cfg, logger.Default()),
+		Config:           cfg,
+		Logger:           logger.Default(),
+	}
+	for _, track := range []*domain.Track{
+		{ProviderID: "mp3", Title: "Song", Artist: "Artist", Album: "Album", ISRC: "USABC1111111", AudioQuality: "HIGH", FilePath: "/srv/private/Song.mp3", Status: domain.TrackStatusCompleted},
+		{ProviderID: "flac", Title: "Song", Artist: "Artist", Album: "Album (Deluxe)", ISRC: "USABC1111111", AudioQuality: "LOSSLESS", FilePath: "/music/Song.flac", Status: domain.TrackStatusCompleted},
+		{ProviderID: "other", Title: "Other", Artist: "Artist", Album: "Album", ISRC: "USABC2222222", FilePath: "/music/Other.flac", Status: domain.TrackStatusCompleted},
+	} {
+		if err := db.CreateTrack(track); err != nil {
+			t.Fatalf("CreateTrack failed: %v", err)
+		}
+	}
+
+	rec := httptest.NewRecorder()
+	h.DuplicatesAPI(rec, httptest.NewRequest(http.MethodGet, "/api/v1/duplicates", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, want 200: %s", rec.Code, rec.Body.String())
+	}
+	var groups []struct {
+		Kind   string `json:"kind"`
+		Tracks []struct {
+			FilePath string `json:"file_path"`
+		} `json:"tracks"`
+	}
+	if err := json.NewDecoder(rec.Body).Decode(&groups); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if len(groups) != 1 || groups[0].Kind != "isrc" || len(groups[0].Tracks) != 2 {
+		t.Fatalf("groups = %+v, want one ISRC group of two tracks", groups)
+	}
+	if got := groups[0].Tracks[0].FilePath; got != "/music/Song.flac" {
+		t.Errorf("best copy = %q, want the lossless /music/Song.flac", got)
+	}
+
+	rec = httptest.NewRecorder()
+	h.DuplicatesHTMX(rec, httptest.NewRequest(http.MethodGet, "/htmx/downloads/duplicates", nil))
+	body := rec.Body.String()
+	if !strings.Contains(body, "Best copy") || !strings.Contains(body, "deleteDuplicate('mp3')") || strings.Contains(body, "/music/Other.flac") {
+		t.Errorf("report = %s, want the MP3 offered for deletion and Other left out", body)
+	}
+	if !strings.Contains(body, "/music/Song.flac") || strings.Contains(body, "/srv/private") {
+		t.Errorf("report = %s, want only the path inside the downloads dir shown", body)
+	}
+}
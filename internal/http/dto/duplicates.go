@@ -0,0 +1,27 @@
+package dto
+
+import (
+	"strings"
+
+	"github.com/cesargomez89/navidrums/internal/store"
+)
+
+type DuplicateGroupResponse struct {
+	Kind   string          `json:"kind"`
+	Key    string          `json:"key"`
+	Tracks []TrackResponse `json:"tracks"`
+}
+
+// NewDuplicateGroupResponse lists a group's tracks best copy first, as the report
+// sorted them. A title key's fields are joined with " / ".
+func NewDuplicateGroupResponse(g store.DuplicateGroup) DuplicateGroupResponse {
+	resp := DuplicateGroupResponse{
+		Kind:   g.Kind,
+		Key:    strings.ReplaceAll(g.Key, "\x1f", " / "),
+		Tracks: make([]TrackResponse, 0, len(g.Tracks)),
+	}
+	for _, t := range g.Tracks {
+		resp.Tracks = append(resp.Tracks, NewTrackResponse(t))
+	}
+	return resp
+}
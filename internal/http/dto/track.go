@@ -46,6 +46,7 @@ type TrackUpdateRequest struct {
 	Peak        *float64 `form:"peak"`
 	Compilation *bool    `form:"compilation"`
 	Explicit    *bool    `form:"explicit"`
+	Locked      *bool    `form:"locked"`
 }
 
 func (r *TrackUpdateRequest) Validate() []ValidationError {
@@ -197,6 +198,9 @@ func (r *TrackUpdateRequest) ToUpdates() map[string]interface{} {
 	if r.Explicit != nil {
 		updates["explicit"] = *r.Explicit
 	}
+	if r.Locked != nil {
+		updates["locked"] = *r.Locked
+	}
 	if r.Language != nil {
 		updates["language"] = *r.Language
 	}
@@ -222,6 +226,7 @@ type TrackResponse struct {
 	FilePath       string     `json:"file_path"`
 	Status         string     `json:"status"`
 	ProviderID     string     `json:"provider_id"`
+	SourceProvider string     `json:"source_provider"`
 	AlbumID        string     `json:"album_id"`
 	ReleaseID      string     `json:"release_id"`
 	Composer       string     `json:"composer"`
@@ -253,9 +258,15 @@ type TrackResponse struct {
 	Year           int        `json:"year"`
 	DiscNumber     int        `json:"disc_number"`
 	TrackNumber    int        `json:"track_number"`
+	SampleRate     int        `json:"sample_rate"`
+	BitDepth       int        `json:"bit_depth"`
+	Channels       int        `json:"channels"`
+	Bitrate        int        `json:"bitrate"`
 	Compilation    bool       `json:"compilation"`
 	Explicit       bool       `json:"explicit"`
+	Locked         bool       `json:"locked"`
 	Language       string     `json:"language"`
+	TargetQuality  string     `json:"target_quality,omitempty"`
 }
 
 func NewTrackResponse(t *domain.Track) TrackResponse {
@@ -274,6 +285,7 @@ func NewTrackResponse(t *domain.Track) TrackResponse {
 		FilePath:       t.FilePath,
 		Status:         string(t.Status),
 		ProviderID:     t.ProviderID,
+		SourceProvider: t.SourceProvider,
 		AlbumID:        t.AlbumID,
 		ReleaseID:      t.ReleaseID,
 		Composer:       t.Composer,
@@ -284,6 +296,7 @@ func NewTrackResponse(t *domain.Track) TrackResponse {
 		URL:            t.URL,
 		AudioQuality:   t.AudioQuality,
 		AudioModes:     t.AudioModes,
+		TargetQuality:  t.TargetQuality,
 		Lyrics:         t.Lyrics,
 		Subtitles:      t.Subtitles,
 		Barcode:        t.Barcode,
@@ -297,11 +310,16 @@ func NewTrackResponse(t *domain.Track) TrackResponse {
 		Peak:           t.Peak,
 		Compilation:    t.Compilation,
 		Explicit:       t.Explicit,
+		Locked:         t.Locked,
 		Language:       t.Language,
 		TotalTracks:    t.TotalTracks,
 		TotalDiscs:     t.TotalDiscs,
 		AlbumArtURL:    t.AlbumArtURL,
 		FileExtension:  t.FileExtension,
+		SampleRate:     t.SampleRate,
+		BitDepth:       t.BitDepth,
+		Channels:       t.Channels,
+		Bitrate:        t.Bitrate,
 		Artists:        t.Artists,
 		AlbumArtists:   t.AlbumArtists,
 		PathArtist:     t.PathArtist,
@@ -0,0 +1,58 @@
+package httpapp
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+
+	"github.com/cesargomez89/navidrums/internal/http/dto"
+)
+
+// DuplicatesAPI returns the groups of downloaded tracks sharing an ISRC, or a title, artist
+// and album, with the best copy of each first.
+func (h *Handler) DuplicatesAPI(w http.ResponseWriter, r *http.Request) {
+	groups, err := h.DownloadsService.FindDuplicates()
+	if err != nil {
+		h.log(r).Error("Failed to find duplicates", "error", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	resp := make([]dto.DuplicateGroupResponse, 0, len(groups))
+	for _, g := range groups {
+		resp = append(resp, dto.NewDuplicateGroupResponse(g))
+	}
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(resp); err != nil {
+		h.log(r).Error("Failed to encode duplicates", "error", err)
+	}
+}
+
+// DuplicatesHTMX shows the duplicate report in place of the downloads list.
+func (h *Handler) DuplicatesHTMX(w http.ResponseWriter, r *http.Request) {
+	groups, err := h.DownloadsService.FindDuplicates()
+	if err != nil {
+		h.log(r).Error("Failed to find duplicates", "error", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+	// As with TrackPathAPI, a path outside the download directories isn't shown.
+	for _, g := range groups {
+		for _, t := range g.Tracks {
+			t.FilePath = h.DownloadsService.RevealedPath(t.FilePath)
+		}
+	}
+	h.RenderFragment(w, "duplicates.html", groups)
+}
+
+// DeleteDuplicateHTMX moves one copy to the trash and shows the report again.
+func (h *Handler) DeleteDuplicateHTMX(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+	if err := h.DownloadsService.DeleteDownload(id); err != nil {
+		h.log(r).Error("Failed to delete duplicate", "provider_id", id, "error", err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	h.DuplicatesHTMX(w, r)
+}
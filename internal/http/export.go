@@ -0,0 +1,159 @@
+package httpapp
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+
+	"github.com/cesargomez89/navidrums/internal/app"
+	"github.com/cesargomez89/navidrums/internal/constants"
+	"github.com/cesargomez89/navidrums/internal/domain"
+	"github.com/cesargomez89/navidrums/internal/http/dto"
+	"github.com/cesargomez89/navidrums/internal/storage"
+)
+
+var exportCSVHeader = []string{
+	"id", "title", "artist", "album", "album_artist", "year", "genre",
+	"isrc", "duration", "file_path", "audio_quality",
+	"sample_rate", "bit_depth", "channels", "bitrate",
+}
+
+func (h *Handler) ExportDownloadsAPI(w http.ResponseWriter, r *http.Request) {
+	format := r.URL.Query().Get("format")
+	if format == "" {
+		format = "json"
+	}
+	if format != "json" && format != "csv" {
+		http.Error(w, "format must be csv or json", http.StatusBadRequest)
+		return
+	}
+
+	filename := fmt.Sprintf("navidrums-downloads-%s.%s", time.Now().Format("20060102"), format)
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename))
+
+	var err error
+	if format == "csv" {
+		err = h.exportCSV(w)
+	} else {
+		err = h.exportJSON(w)
+	}
+	if err != nil {
+		// Headers are already sent at this point, so the best we can do is log.
+		h.log(r).Error("Failed to export downloads", "format", format, "error", err)
+	}
+}
+
+func (h *Handler) exportCSV(w http.ResponseWriter) error {
+	w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+
+	cw := csv.NewWriter(w)
+	if err := cw.Write(exportCSVHeader); err != nil {
+		return err
+	}
+
+	err := h.DownloadsService.ExportDownloads(constants.ExportPageSize, func(tracks []*domain.Track) error {
+		for _, t := range tracks {
+			record := []string{
+				strconv.Itoa(t.ID),
+				t.Title,
+				t.Artist,
+				t.Album,
+				t.AlbumArtist,
+				strconv.Itoa(t.Year),
+				t.Genre,
+				t.ISRC,
+				strconv.Itoa(t.Duration),
+				t.FilePath,
+				t.AudioQuality,
+				strconv.Itoa(t.SampleRate),
+				strconv.Itoa(t.BitDepth),
+				strconv.Itoa(t.Channels),
+				strconv.Itoa(t.Bitrate),
+			}
+			if err := cw.Write(record); err != nil {
+				return err
+			}
+		}
+		cw.Flush()
+		return cw.Error()
+	})
+	if err != nil {
+		return err
+	}
+
+	cw.Flush()
+	return cw.Error()
+}
+
+func (h *Handler) exportJSON(w http.ResponseWriter) error {
+	w.Header().Set("Content-Type", "application/json")
+
+	if _, err := w.Write([]byte("[")); err != nil {
+		return err
+	}
+
+	first := true
+	err := h.DownloadsService.ExportDownloads(constants.ExportPageSize, func(tracks []*domain.Track) error {
+		for _, t := range tracks {
+			data, err := json.Marshal(dto.NewTrackResponse(t))
+			if err != nil {
+				return err
+			}
+			if !first {
+				if _, err := w.Write([]byte(",")); err != nil {
+					return err
+				}
+			}
+			first = false
+			if _, err := w.Write(data); err != nil {
+				return err
+			}
+		}
+		if f, ok := w.(http.Flusher); ok {
+			f.Flush()
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	_, err = w.Write([]byte("]\n"))
+	return err
+}
+
+func (h *Handler) DownloadZip(w http.ResponseWriter, r *http.Request) {
+	kind := chi.URLParam(r, "type")
+	id := chi.URLParam(r, "id")
+	if kind != "album" && kind != "playlist" {
+		http.Error(w, "type must be album or playlist", http.StatusBadRequest)
+		return
+	}
+
+	name, files, err := h.DownloadsService.ArchiveFiles(kind, id)
+	if err != nil {
+		if errors.Is(err, app.ErrArchiveEmpty) {
+			http.Error(w, "No completed tracks found", http.StatusNotFound)
+			return
+		}
+		h.log(r).Error("Failed to resolve archive files", "type", kind, "id", id, "error", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	filename := storage.Sanitize(name) + ".zip"
+	w.Header().Set("Content-Type", "application/zip")
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename))
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+
+	if _, err := storage.WriteZip(w, h.Config.DownloadsDirs(), files); err != nil {
+		h.log(r).Error("Failed to stream zip", "type", kind, "id", id, "error", err)
+	}
+}
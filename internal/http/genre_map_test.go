@@ -0,0 +1,60 @@
+package httpapp
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/cesargomez89/navidrums/internal/app"
+	"github.com/cesargomez89/navidrums/internal/store"
+)
+
+func TestHandler_PreviewGenreMap(t *testing.T) {
+	tests := []struct {
+		name       string
+		customMap  string
+		query      string
+		wantStatus int
+		wantGenre  string
+	}{
+		{"default map", "", "?tag=indie+rock", http.StatusOK, "rock"},
+		{"first mapped tag wins", "", "?tag=shoegaze&tag=indie+pop&tag=indie+rock", http.StatusOK, "pop"},
+		{"unmapped tag", "", "?tag=shoegaze", http.StatusOK, "shoegaze"},
+		{"custom map replaces default", `{"shoegaze":"dream pop"}`, "?tag=indie+rock&tag=shoegaze", http.StatusOK, "dream pop"},
+		{"no tag or isrc", "", "", http.StatusBadRequest, ""},
+		{"isrc without musicbrainz", "", "?isrc=USRC17607839", http.StatusServiceUnavailable, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h, cleanup := setupAuthHandler(t)
+			defer cleanup()
+			h.GenreMap = app.NewGenreMapService(h.SettingsRepo, nil)
+			if tt.customMap != "" {
+				if err := h.SettingsRepo.Set(store.SettingGenreMap, tt.customMap); err != nil {
+					t.Fatalf("Set() error = %v", err)
+				}
+			}
+
+			rec := httptest.NewRecorder()
+			h.PreviewGenreMapHTMX(rec, httptest.NewRequest(http.MethodGet, "/htmx/genre-map/preview"+tt.query, nil))
+
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d", rec.Code, tt.wantStatus)
+			}
+			if tt.wantStatus != http.StatusOK {
+				return
+			}
+			var resp struct {
+				Genre string `json:"genre"`
+			}
+			if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+				t.Fatalf("decode error = %v", err)
+			}
+			if resp.Genre != tt.wantGenre {
+				t.Errorf("genre = %q, want %q", resp.Genre, tt.wantGenre)
+			}
+		})
+	}
+}
@@ -1,6 +1,9 @@
 package httpapp
 
 import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
 	"html/template"
 	"net/http"
 	"path/filepath"
@@ -27,11 +30,14 @@ type Handler struct {
 	SettingsRepo     *store.SettingsRepo
 	ProvidersRepo    *store.ProvidersRepo
 	Config           *config.Config
+	GenreMap         *app.GenreMapService
 	Templates        *template.Template
 	Logger           *logger.Logger
 	FormDecoder      *form.Decoder
 	cachedRecs       *RecommendationsData
+	sessions         *SessionSigner
 	recsMutex        sync.RWMutex
+	jobLogPoll       time.Duration // constants.JobLogPollInterval when zero
 }
 
 func NewHandler(js *app.JobService, ds *app.DownloadsService, pm *catalog.ProviderManager, sr *store.SettingsRepo, pr *store.ProvidersRepo, cfg *config.Config) *Handler {
@@ -44,11 +50,27 @@ func NewHandler(js *app.JobService, ds *app.DownloadsService, pm *catalog.Provid
 		Config:           cfg,
 		Logger:           logger.Default(),
 		FormDecoder:      form.NewDecoder(),
+		sessions:         NewSessionSigner(cfg.SessionSecret, cfg.SessionTTL),
 	}
 	h.ParseTemplates()
 	return h
 }
 
+// log is the handler logger for r, tagged with its request ID.
+func (h *Handler) log(r *http.Request) *logger.Logger {
+	return h.Logger.WithRequest(r.Context())
+}
+
+// jobs is the job service for r: the jobs it queues record r's request ID.
+func (h *Handler) jobs(r *http.Request) *app.JobService {
+	return h.JobService.ForRequest(r.Context())
+}
+
+// downloads is the downloads service for r: the jobs it queues record r's request ID.
+func (h *Handler) downloads(r *http.Request) *app.DownloadsService {
+	return h.DownloadsService.ForRequest(r.Context())
+}
+
 func (h *Handler) ParseTemplates() {
 	// Not used globally anymore
 }
@@ -60,29 +82,70 @@ func (h *Handler) RegisterRoutes(r chi.Router) {
 	r.Get("/artist/{id}", h.ArtistPage)
 	r.Get("/album/{id}", h.AlbumPage)
 	r.Get("/htmx/album/{id}/similar", h.SimilarAlbumsHTMX)
+	r.Get("/htmx/album/{id}/preview", h.AlbumPreviewHTMX)
+	r.Get("/htmx/album/{id}/album-artist", h.AlbumArtistOverrideHTMX)
+	r.Post("/htmx/album/{id}/album-artist", h.SaveAlbumArtistOverrideHTMX)
 	r.Get("/htmx/artist/{id}/similar", h.SimilarArtistsHTMX)
 	r.Get("/playlist/{id}", h.PlaylistPage)
+	r.Get("/radio/{id}", h.RadioPage)
+	r.Get("/htmx/radio/{id}", h.RadioHTMX)
 
 	r.Post("/htmx/download/{type}/{id}", h.DownloadHTMX)
+	r.Post("/htmx/download/album/{id}/tracks", h.DownloadAlbumTracksHTMX)
 	r.Get("/queue", h.QueuePage)
 	r.Get("/htmx/queue/active", h.QueueActiveHTMX)
 	r.Get("/htmx/queue/history", h.QueueHistoryHTMX)
+	r.Get("/htmx/queue/status", h.QueueStatusHTMX)
+	r.Get("/htmx/queue/{id}/log", h.JobLogHTMX)
 	r.Post("/htmx/cancel/{id}", h.CancelJobHTMX)
 	r.Post("/htmx/retry/{id}", h.RetryJobHTMX)
 	r.Post("/htmx/history/clear", h.ClearHistoryHTMX)
+	r.Post("/htmx/queue/batch", h.EnqueueBatchHTMX)
 	r.Get("/settings", h.SettingsPage)
 
 	r.Get("/downloads", h.DownloadsPage)
 	r.Get("/htmx/downloads", h.DownloadsHTMX)
 	r.Post("/htmx/downloads/sync", h.SyncAllHTMX)
+	r.Post("/htmx/downloads/retag-all", h.RetagAllHTMX)
 	r.Post("/htmx/downloads/bulk-delete", h.BulkDeleteHTMX)
 	r.Post("/htmx/downloads/bulk-sync", h.BulkSyncHTMX)
 	r.Post("/htmx/downloads/enrich-hifi", h.BulkEnrichHiFiHTMX)
 	r.Post("/htmx/downloads/enrich-musicbrainz", h.BulkEnrichMusicBrainzHTMX)
+	r.Post("/htmx/downloads/refetch-lyrics", h.BulkRefetchLyricsHTMX)
 	r.Post("/htmx/downloads/bulk-genre", h.BulkUpdateGenreHTMX)
 	r.Delete("/htmx/download/{id}", h.DeleteDownloadHTMX)
+	r.Post("/htmx/downloads/restore/{id}", h.RestoreDownloadHTMX)
+	r.Post("/htmx/downloads/empty-trash", h.EmptyTrashHTMX)
+	r.Post("/htmx/downloads/verify", h.VerifyLibraryHTMX)
+	r.Get("/htmx/downloads/reorganize", h.ReorganizePreviewHTMX)
+	r.Post("/htmx/downloads/reorganize", h.ReorganizeLibraryHTMX)
+	r.Get("/htmx/downloads/duplicates", h.DuplicatesHTMX)
+	r.Delete("/htmx/downloads/duplicates/{id}", h.DeleteDuplicateHTMX)
+	r.Post("/htmx/downloads/redownload/{id}", h.RedownloadHTMX)
+	r.Post("/htmx/downloads/retry-failed", h.RetryFailedHTMX)
 
-	r.Get("/stream/{id}", h.StreamTrack)
+	// Archives, exports, backups, audio streams and live logs outlive HTTP_WRITE_TIMEOUT.
+	long := r.With(noWriteTimeout)
+	long.Get("/download-zip/{type}/{id}", h.DownloadZip)
+	long.Get("/api/v1/downloads/export", h.ExportDownloadsAPI)
+	long.Get("/ws/jobs/{id}/logs", h.JobLogWS)
+	r.Post("/api/v1/verify", h.VerifyAPI)
+	r.Get("/api/v1/verify", h.VerifySummaryAPI)
+	r.Post("/api/v1/import", h.ImportAPI)
+	r.Get("/api/v1/sync/upgrades", h.SyncUpgradesAPI)
+	r.Post("/api/v1/jobs/batch", h.EnqueueBatchAPI)
+	r.Get("/api/v1/worker/status", h.WorkerStatusAPI)
+	r.Get("/api/v1/duplicates", h.DuplicatesAPI)
+	r.Get("/api/v1/track/{id}/path", h.TrackPathAPI)
+	r.Post("/api/v1/maintenance/optimize", h.OptimizeAPI)
+	r.Get("/api/v1/maintenance/data-fixes", h.DataFixesAPI)
+	r.Post("/api/v1/maintenance/data-fixes/{name}", h.RunDataFixAPI)
+	long.Get("/api/v1/backup", h.BackupAPI)
+	r.Get("/api/v1/keys", h.ListAPIKeysAPI)
+	r.Post("/api/v1/keys", h.CreateAPIKeyAPI)
+	r.Delete("/api/v1/keys/{id}", h.RevokeAPIKeyAPI)
+
+	long.Get("/stream/{id}", h.StreamTrack)
 
 	r.Get("/track/{id}", h.TrackPage)
 	r.Get("/htmx/track/{id}", h.TrackHTMX)
@@ -90,9 +153,12 @@ func (h *Handler) RegisterRoutes(r chi.Router) {
 	r.Post("/htmx/track/{id}/sync", h.SyncTrackHTMX)
 	r.Post("/htmx/track/{id}/enrich", h.EnrichTrackHTMX)
 	r.Post("/htmx/track/{id}/enrich-hifi", h.EnrichHiFiHTMX)
+	r.Post("/htmx/track/{id}/lyrics", h.RefetchLyricsHTMX)
+	r.Post("/htmx/track/{id}/copy-from/{sourceId}", h.CopyTrackMetadataHTMX)
 
 	r.Get("/htmx/providers", h.GetProvidersHTMX)
 	r.Post("/htmx/providers/reorder", h.ReorderProvidersHTMX)
+	r.Post("/htmx/providers/test", h.TestProviderHTMX)
 	r.Post("/htmx/provider", h.AddProviderHTMX)
 	r.Delete("/htmx/provider", h.RemoveProviderHTMX)
 
@@ -102,6 +168,7 @@ func (h *Handler) RegisterRoutes(r chi.Router) {
 	r.Get("/htmx/genre-map", h.GetGenreMapHTMX)
 	r.Post("/htmx/genre-map", h.SetGenreMapHTMX)
 	r.Post("/htmx/genre-map/reset", h.ResetGenreMapHTMX)
+	r.Get("/htmx/genre-map/preview", h.PreviewGenreMapHTMX)
 
 	r.Get("/htmx/mood-list", h.GetMoodListHTMX)
 	r.Post("/htmx/mood-list", h.SetMoodListHTMX)
@@ -129,9 +196,68 @@ func (h *Handler) RegisterRoutes(r chi.Router) {
 	r.Get("/htmx/languages", h.GetLanguagesHTMX)
 }
 
+// noWriteTimeout lifts the server's write deadline for responses that stream for as long
+// as they need, so HTTP_WRITE_TIMEOUT doesn't cut them off.
+func noWriteTimeout(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_ = http.NewResponseController(w).SetWriteDeadline(time.Time{})
+		next.ServeHTTP(w, r)
+	})
+}
+
 func (h *Handler) RenderPage(w http.ResponseWriter, pageTmpl string, data interface{}) {
+	tmpl, err := h.pageTemplate(pageTmpl, data)
+	if err != nil {
+		http.Error(w, err.Error(), 500)
+		return
+	}
+
+	if err := tmpl.ExecuteTemplate(w, "base.html", data); err != nil {
+		http.Error(w, err.Error(), 500)
+	}
+}
+
+// RenderCachedPage renders a page like RenderPage, tagged with an ETag of its content. A
+// request whose If-None-Match carries that ETag gets a 304 instead of the page, so the
+// browser reuses its copy until the page would change.
+func (h *Handler) RenderCachedPage(w http.ResponseWriter, r *http.Request, pageTmpl string, data interface{}) {
+	tmpl, err := h.pageTemplate(pageTmpl, data)
+	if err != nil {
+		http.Error(w, err.Error(), 500)
+		return
+	}
+
+	var buf bytes.Buffer
+	if err := tmpl.ExecuteTemplate(&buf, "base.html", data); err != nil {
+		http.Error(w, err.Error(), 500)
+		return
+	}
+
+	sum := sha256.Sum256(buf.Bytes())
+	etag := `"` + hex.EncodeToString(sum[:16]) + `"`
+	w.Header().Set("ETag", etag)
+	w.Header().Set("Cache-Control", "no-cache")
+	if match := r.Header.Get("If-None-Match"); match != "" && strings.Contains(match, etag) {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	_, _ = w.Write(buf.Bytes())
+}
+
+// templateFuncs are the functions pages and fragments can call.
+var templateFuncs = template.FuncMap{
+	"join": strings.Join,
+	// belowQuality flags a download whose file fell short of the quality requested.
+	"belowQuality": app.IsBelowQuality,
+}
+
+// pageTemplate parses a page with the base layout and components, and adds the theme and
+// auth state every page shows to its data.
+func (h *Handler) pageTemplate(pageTmpl string, data interface{}) (*template.Template, error) {
 	// Register template functions before parsing
-	tmpl := template.New("base").Funcs(template.FuncMap{"join": strings.Join})
+	tmpl := template.New("base").Funcs(templateFuncs)
 	tmpl, err := tmpl.ParseFS(web.Files,
 		"templates/base.html",
 		"templates/"+pageTmpl,
@@ -139,8 +265,7 @@ func (h *Handler) RenderPage(w http.ResponseWriter, pageTmpl string, data interf
 		"templates/components/*.html",
 	)
 	if err != nil {
-		http.Error(w, err.Error(), 500)
-		return
+		return nil, err
 	}
 
 	// Inject global theme if not already set in data
@@ -152,18 +277,16 @@ func (h *Handler) RenderPage(w http.ResponseWriter, pageTmpl string, data interf
 			}
 			m["Theme"] = theme
 		}
+		m["AuthEnabled"] = h.authEnabled()
 	}
-
-	if err := tmpl.ExecuteTemplate(w, "base.html", data); err != nil {
-		http.Error(w, err.Error(), 500)
-	}
+	return tmpl, nil
 }
 
 func (h *Handler) RenderFragment(w http.ResponseWriter, fragTmpl string, data interface{}) {
 	patterns := []string{"templates/components/*.html", "templates/" + fragTmpl}
 
 	// Register functions before parsing
-	tmpl := template.New("frag").Funcs(template.FuncMap{"join": strings.Join})
+	tmpl := template.New("frag").Funcs(templateFuncs)
 	tmpl, err := tmpl.ParseFS(web.Files, patterns...)
 	if err != nil {
 		http.Error(w, err.Error(), 500)
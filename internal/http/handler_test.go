@@ -0,0 +1,65 @@
+package httpapp
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/cesargomez89/navidrums/internal/store"
+)
+
+func setupTestDB(t *testing.T) (*store.DB, func()) {
+	db, err := store.NewSQLiteDB(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("Failed to open db: %v", err)
+	}
+	cleanup := func() {
+		if cErr := db.Close(); cErr != nil {
+			t.Logf("db.Close error: %v", cErr)
+		}
+	}
+	return db, cleanup
+}
+
+func TestNoWriteTimeout(t *testing.T) {
+	slow := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(200 * time.Millisecond)
+		_, _ = w.Write([]byte("done"))
+	})
+
+	tests := []struct {
+		name    string
+		handler http.Handler
+		wantErr bool
+	}{
+		{"cut off by the write timeout", slow, true},
+		{"exempt", noWriteTimeout(slow), false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := httptest.NewUnstartedServer(tt.handler)
+			srv.Config.WriteTimeout = 50 * time.Millisecond
+			srv.Start()
+			defer srv.Close()
+
+			resp, err := http.Get(srv.URL)
+			var body []byte
+			if err == nil {
+				body, err = io.ReadAll(resp.Body)
+				_ = resp.Body.Close()
+			}
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("got %q, want the response cut off", body)
+				}
+				return
+			}
+			if err != nil || string(body) != "done" {
+				t.Errorf("got %q, %v, want %q", body, err, "done")
+			}
+		})
+	}
+}
@@ -0,0 +1,41 @@
+package httpapp
+
+import (
+	"errors"
+	"io/fs"
+	"net/http"
+
+	"github.com/cesargomez89/navidrums/internal/app"
+)
+
+// ImportAPI enqueues an import of the local file or folder named by path, absolute or
+// relative to IMPORT_DIR.
+func (h *Handler) ImportAPI(w http.ResponseWriter, r *http.Request) {
+	path := r.FormValue("path")
+	if path == "" {
+		http.Error(w, "path is required", http.StatusBadRequest)
+		return
+	}
+
+	err := h.downloads(r).EnqueueImportJob(path)
+	switch {
+	case errors.Is(err, app.ErrImportDisabled):
+		http.Error(w, "Importing is disabled: IMPORT_DIR is not set", http.StatusNotFound)
+		return
+	case errors.Is(err, app.ErrImportOutsideRoot):
+		h.log(r).Warn("Refused to import a path outside the import directory", "path", path)
+		http.Error(w, "Path is outside the import directory", http.StatusForbidden)
+		return
+	case errors.Is(err, fs.ErrNotExist):
+		http.Error(w, "Path not found", http.StatusNotFound)
+		return
+	case err != nil:
+		h.log(r).Error("Failed to enqueue import job", "path", path, "error", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusAccepted)
+	_, _ = w.Write([]byte(`{"success":true}`))
+}
@@ -0,0 +1,110 @@
+package httpapp
+
+import (
+	"database/sql"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/gorilla/websocket"
+
+	"github.com/cesargomez89/navidrums/internal/constants"
+)
+
+// jobLogWriteWait bounds each write to a live log socket, so a client that stops reading
+// doesn't hold the handler.
+const jobLogWriteWait = 10 * time.Second
+
+// jobLogUpgrader's default origin check refuses pages from other sites, which could
+// otherwise open the socket with the user's session cookie.
+var jobLogUpgrader = websocket.Upgrader{}
+
+// JobLogWS streams a job's log over a WebSocket: the events recorded so far, then each
+// one the worker writes, as JSON messages. Once the job has finished and its last events
+// are sent, the socket is closed normally with the job's status as the reason.
+func (h *Handler) JobLogWS(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+	if _, err := h.JobService.GetJob(id); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			http.Error(w, "Job not found", http.StatusNotFound)
+			return
+		}
+		h.log(r).Error("Failed to get job", "job_id", id, "error", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	conn, err := jobLogUpgrader.Upgrade(w, r, nil)
+	if err != nil {
+		// Upgrade has already replied with the error.
+		h.log(r).Warn("Failed to upgrade job log connection", "job_id", id, "error", err)
+		return
+	}
+	defer func() { _ = conn.Close() }()
+
+	// The client sends nothing, but reading answers its pings and notices when it leaves.
+	gone := make(chan struct{})
+	go func() {
+		defer close(gone)
+		for {
+			if _, _, err := conn.ReadMessage(); err != nil {
+				return
+			}
+		}
+	}()
+
+	poll := h.jobLogPoll
+	if poll == 0 {
+		poll = constants.JobLogPollInterval
+	}
+	ticker := time.NewTicker(poll)
+	defer ticker.Stop()
+
+	closeWith := func(code int, reason string) {
+		msg := websocket.FormatCloseMessage(code, reason)
+		if err := conn.WriteControl(websocket.CloseMessage, msg, time.Now().Add(jobLogWriteWait)); err != nil {
+			return
+		}
+		// Give the client a moment to answer before the connection is dropped.
+		select {
+		case <-gone:
+		case <-time.After(jobLogWriteWait):
+		}
+	}
+
+	lastID := 0
+	for {
+		// The status is read before the events, so a job that finishes in between still
+		// has its last events sent before the socket closes.
+		job, err := h.JobService.GetJob(id)
+		if err != nil {
+			h.log(r).Error("Failed to get job", "job_id", id, "error", err)
+			closeWith(websocket.CloseInternalServerErr, "failed to read job")
+			return
+		}
+		events, err := h.JobService.ListJobEventsAfter(id, lastID)
+		if err != nil {
+			h.log(r).Error("Failed to list job events", "job_id", id, "error", err)
+			closeWith(websocket.CloseInternalServerErr, "failed to read job log")
+			return
+		}
+		for _, e := range events {
+			_ = conn.SetWriteDeadline(time.Now().Add(jobLogWriteWait))
+			if err := conn.WriteJSON(e); err != nil {
+				return
+			}
+			lastID = e.ID
+		}
+		if job.IsTerminal() {
+			closeWith(websocket.CloseNormalClosure, string(job.Status))
+			return
+		}
+
+		select {
+		case <-ticker.C:
+		case <-gone:
+			return
+		}
+	}
+}
@@ -0,0 +1,98 @@
+package httpapp
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/gorilla/websocket"
+
+	"github.com/cesargomez89/navidrums/internal/app"
+	"github.com/cesargomez89/navidrums/internal/domain"
+	"github.com/cesargomez89/navidrums/internal/logger"
+)
+
+func TestHandler_JobLogWS(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	log := logger.Default()
+	jobs := app.NewJobService(db, log)
+	h := &Handler{JobService: jobs, Logger: log, jobLogPoll: 10 * time.Millisecond}
+	r := chi.NewRouter()
+	r.Get("/ws/jobs/{id}/logs", h.JobLogWS)
+	srv := httptest.NewServer(r)
+	defer srv.Close()
+	wsURL := "ws" + strings.TrimPrefix(srv.URL, "http")
+
+	t.Run("unknown job", func(t *testing.T) {
+		_, resp, err := websocket.DefaultDialer.Dial(wsURL+"/ws/jobs/missing/logs", nil)
+		if err == nil {
+			t.Fatal("expected the handshake to fail")
+		}
+		if resp == nil || resp.StatusCode != http.StatusNotFound {
+			t.Errorf("response = %v, want status %d", resp, http.StatusNotFound)
+		}
+	})
+
+	t.Run("streams events until the job finishes", func(t *testing.T) {
+		job, err := jobs.EnqueueJob("100", domain.JobTypeTrack)
+		if err != nil {
+			t.Fatalf("EnqueueJob failed: %v", err)
+		}
+		if err := db.AddJobEvent(job.ID, domain.JobEventInfo, "event 0"); err != nil {
+			t.Fatalf("AddJobEvent failed: %v", err)
+		}
+
+		conn, _, err := websocket.DefaultDialer.Dial(wsURL+"/ws/jobs/"+job.ID+"/logs", nil)
+		if err != nil {
+			t.Fatalf("Dial failed: %v", err)
+		}
+		defer func() { _ = conn.Close() }()
+		_ = conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+
+		read := func(want string) {
+			t.Helper()
+			var event domain.JobEvent
+			if err := conn.ReadJSON(&event); err != nil {
+				t.Fatalf("ReadJSON failed: %v", err)
+			}
+			if event.Message != want || event.JobID != job.ID {
+				t.Errorf("event = %q for job %q, want %q for job %q", event.Message, event.JobID, want, job.ID)
+			}
+		}
+
+		// The worker writes events while the socket is open.
+		read("event 0")
+		for i := 1; i <= 3; i++ {
+			msg := fmt.Sprintf("event %d", i)
+			if err := db.AddJobEvent(job.ID, domain.JobEventWarn, msg); err != nil {
+				t.Fatalf("AddJobEvent failed: %v", err)
+			}
+			read(msg)
+		}
+
+		// The last event and the job finishing land between two polls.
+		if err := db.AddJobEvent(job.ID, domain.JobEventError, "event 4"); err != nil {
+			t.Fatalf("AddJobEvent failed: %v", err)
+		}
+		if err := db.UpdateJobStatus(job.ID, domain.JobStatusFailed, 0); err != nil {
+			t.Fatalf("UpdateJobStatus failed: %v", err)
+		}
+		read("event 4")
+
+		_, _, err = conn.ReadMessage()
+		var closeErr *websocket.CloseError
+		if !errors.As(err, &closeErr) {
+			t.Fatalf("ReadMessage error = %v, want a close", err)
+		}
+		if closeErr.Code != websocket.CloseNormalClosure || closeErr.Text != string(domain.JobStatusFailed) {
+			t.Errorf("close = %d %q, want %d %q", closeErr.Code, closeErr.Text, websocket.CloseNormalClosure, domain.JobStatusFailed)
+		}
+	})
+}
@@ -0,0 +1,67 @@
+package httpapp
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+
+	"github.com/cesargomez89/navidrums/internal/store"
+)
+
+// OptimizeAPI compacts the database and returns its size before and after.
+func (h *Handler) OptimizeAPI(w http.ResponseWriter, r *http.Request) {
+	result, err := h.DownloadsService.OptimizeDatabase()
+	if err != nil {
+		h.log(r).Error("Failed to optimize database", "error", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(result); err != nil {
+		h.log(r).Error("Failed to encode optimize result", "error", err)
+	}
+}
+
+// DataFixesAPI lists the data fixes RunDataFixAPI can re-run.
+func (h *Handler) DataFixesAPI(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(h.DownloadsService.DataFixes()); err != nil {
+		h.log(r).Error("Failed to encode data fixes", "error", err)
+	}
+}
+
+// RunDataFixAPI re-runs one data fix and returns the rows it changed.
+func (h *Handler) RunDataFixAPI(w http.ResponseWriter, r *http.Request) {
+	result, err := h.DownloadsService.RunDataFix(chi.URLParam(r, "name"))
+	if errors.Is(err, store.ErrUnknownDataFix) {
+		http.Error(w, "Data fix not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		h.log(r).Error("Failed to run data fix", "error", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(result); err != nil {
+		h.log(r).Error("Failed to encode data fix result", "error", err)
+	}
+}
+
+// BackupAPI streams a snapshot of the database as a timestamped attachment.
+func (h *Handler) BackupAPI(w http.ResponseWriter, r *http.Request) {
+	filename := fmt.Sprintf("navidrums-%s.db", time.Now().Format("20060102-150405"))
+	w.Header().Set("Content-Type", "application/vnd.sqlite3")
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename))
+
+	if err := h.DownloadsService.BackupDatabase(w); err != nil {
+		// Headers may already be sent at this point, so the best we can do is log.
+		h.log(r).Error("Failed to back up database", "error", err)
+	}
+}
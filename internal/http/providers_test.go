@@ -0,0 +1,107 @@
+package httpapp
+
+import (
+	"encoding/json"
+	"maps"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/cesargomez89/navidrums/internal/catalog"
+	"github.com/cesargomez89/navidrums/internal/logger"
+	"github.com/cesargomez89/navidrums/internal/store"
+)
+
+func TestHandler_TestProvider(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	// The provider answers searches only with the API key saved for it.
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-API-Key") != "secret" {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		_, _ = w.Write([]byte(`{"data":{"items":[]}}`))
+	}))
+	defer srv.Close()
+
+	providers := store.NewProvidersRepo(db)
+	id, err := providers.Create("hifi", srv.URL, "Local")
+	if err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+	if err := providers.SetHeaders(id, store.ProviderHeaders{"X-API-Key": "secret"}); err != nil {
+		t.Fatalf("SetHeaders failed: %v", err)
+	}
+	h := &Handler{ProvidersRepo: providers, Logger: logger.Default()}
+
+	tests := []struct {
+		name       string
+		query      url.Values
+		wantCode   int
+		wantOK     bool
+		wantStatus int
+	}{
+		{"saved provider", url.Values{"url": {srv.URL}}, http.StatusOK, true, http.StatusOK},
+		{"unsaved path without the key", url.Values{"url": {srv.URL + "/other"}}, http.StatusOK, false, http.StatusUnauthorized},
+		{"missing url", url.Values{}, http.StatusBadRequest, false, 0},
+		{"unknown type", url.Values{"url": {srv.URL}, "type": {"spotify"}}, http.StatusBadRequest, false, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			req := httptest.NewRequest(http.MethodPost, "/htmx/providers/test", strings.NewReader(tt.query.Encode()))
+			req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+			h.TestProviderHTMX(rec, req)
+			if rec.Code != tt.wantCode {
+				t.Fatalf("status = %d, want %d: %s", rec.Code, tt.wantCode, rec.Body.String())
+			}
+			if tt.wantCode != http.StatusOK {
+				return
+			}
+			var result catalog.ProbeResult
+			if err := json.Unmarshal(rec.Body.Bytes(), &result); err != nil {
+				t.Fatalf("failed to decode result: %v", err)
+			}
+			if result.OK != tt.wantOK || result.StatusCode != tt.wantStatus {
+				t.Errorf("result = %+v, want ok=%v status_code=%d", result, tt.wantOK, tt.wantStatus)
+			}
+			if !result.OK && result.Error == "" {
+				t.Error("failed result has no error message")
+			}
+		})
+	}
+}
+
+func TestParseProviderHeaders(t *testing.T) {
+	tests := []struct {
+		name    string
+		text    string
+		want    store.ProviderHeaders
+		wantErr bool
+	}{
+		{"name and value", "x-api-key: secret\n\nAccept: application/json", store.ProviderHeaders{"X-Api-Key": "secret", "Accept": "application/json"}, false},
+		{"token characters", "X-Client_ID.v2: a b", store.ProviderHeaders{"X-Client_id.v2": "a b"}, false},
+		{"no colon", "X-API-Key secret", nil, true},
+		{"empty name", ": secret", nil, true},
+		{"space in name", "X API Key: secret", nil, true},
+		{"separator in name", "X-Key(1): secret", nil, true},
+		{"markup in name", "<img src=x onerror=alert(1)>: x", nil, true},
+		{"non-ASCII name", "X-Clé: secret", nil, true},
+		{"control character in value", "X-API-Key: se\x00cret", nil, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseProviderHeaders(tt.text)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseProviderHeaders() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && !maps.Equal(got, tt.want) {
+				t.Errorf("parseProviderHeaders() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
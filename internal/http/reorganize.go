@@ -0,0 +1,34 @@
+package httpapp
+
+import (
+	"net/http"
+
+	"github.com/cesargomez89/navidrums/internal/constants"
+	"github.com/cesargomez89/navidrums/internal/store"
+)
+
+// ReorganizePreviewHTMX shows which downloaded files would move to match SUBDIR_TEMPLATE.
+func (h *Handler) ReorganizePreviewHTMX(w http.ResponseWriter, r *http.Request) {
+	plan, err := h.DownloadsService.PreviewReorganize()
+	if err != nil {
+		h.log(r).Error("Failed to preview reorganize", "error", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+	h.RenderFragment(w, "reorganize_preview.html", plan)
+}
+
+// ReorganizeLibraryHTMX enqueues the moves the preview listed.
+func (h *Handler) ReorganizeLibraryHTMX(w http.ResponseWriter, r *http.Request) {
+	if err := h.downloads(r).EnqueueReorganizeJob(); err != nil {
+		h.log(r).Error("Failed to enqueue reorganize job", "error", err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	tracks, _, _ := h.DownloadsService.ListDownloads(store.TrackSort{}, 1, constants.MaxSearchResults)
+	h.RenderFragment(w, "components/downloads_list.html", map[string]interface{}{
+		"Downloads":          tracks,
+		"ReorganizeEnqueued": true,
+	})
+}
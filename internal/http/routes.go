@@ -1,16 +1,25 @@
 package httpapp
 
 import (
+	"database/sql"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"html"
 	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
 	"strings"
 	"sync"
 	"time"
 
 	"github.com/go-chi/chi/v5"
+	"golang.org/x/net/http/httpguts"
 
 	"github.com/cesargomez89/navidrums/internal/app"
+	"github.com/cesargomez89/navidrums/internal/catalog"
+	"github.com/cesargomez89/navidrums/internal/config"
 	"github.com/cesargomez89/navidrums/internal/constants"
 	"github.com/cesargomez89/navidrums/internal/domain"
 	"github.com/cesargomez89/navidrums/internal/http/dto"
@@ -36,15 +45,31 @@ func (h *Handler) SearchHTMX(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	page := 1
+	if p := r.URL.Query().Get("page"); p != "" {
+		_, _ = fmt.Sscanf(p, "%d", &page)
+	}
+	if page < 1 {
+		page = 1
+	}
+
 	provider := h.ProviderManager.GetMetadataProvider()
 
-	results, err := provider.Search(r.Context(), query, searchType)
+	offset := (page - 1) * constants.MaxSearchResults
+	results, err := provider.Search(r.Context(), query, searchType, offset, constants.MaxSearchResults)
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
 
-	h.RenderFragment(w, "search_results.html", results)
+	// Providers that don't report a total can't be paged, so they stay on page 1.
+	extraParams := url.Values{"q": {query}, "type": {searchType}}.Encode()
+	pagination := dto.NewPagination(page, constants.MaxSearchResults, results.Total, "/htmx/search", "#results", extraParams)
+
+	h.RenderFragment(w, "search_results.html", map[string]interface{}{
+		"Results":    results,
+		"Pagination": pagination,
+	})
 }
 
 type RecommendationsData struct {
@@ -92,13 +117,13 @@ func (h *Handler) LuckyHTMX(w http.ResponseWriter, r *http.Request) {
 		wg.Add(1)
 		go func() {
 			defer wg.Done()
-			h.Logger.Info("Fetching track recommendations", "track_id", seeds.TrackID)
+			h.log(r).Info("Fetching track recommendations", "track_id", seeds.TrackID)
 			tracks, err := provider.GetRecommendations(r.Context(), seeds.TrackID)
 			if err != nil {
 				trackErr = err
 				return
 			}
-			h.Logger.Info("Track recommendations response", "track_id", seeds.TrackID, "count", len(tracks))
+			h.log(r).Info("Track recommendations response", "track_id", seeds.TrackID, "count", len(tracks))
 			var iface []interface{}
 			for i := range tracks {
 				iface = append(iface, tracks[i])
@@ -111,13 +136,13 @@ func (h *Handler) LuckyHTMX(w http.ResponseWriter, r *http.Request) {
 		wg.Add(1)
 		go func() {
 			defer wg.Done()
-			h.Logger.Info("Fetching album recommendations", "album_id", seeds.AlbumID)
+			h.log(r).Info("Fetching album recommendations", "album_id", seeds.AlbumID)
 			albums, err := provider.GetSimilarAlbums(r.Context(), seeds.AlbumID)
 			if err != nil {
 				albumErr = err
 				return
 			}
-			h.Logger.Info("Album recommendations response", "album_id", seeds.AlbumID, "count", len(albums))
+			h.log(r).Info("Album recommendations response", "album_id", seeds.AlbumID, "count", len(albums))
 			var iface []interface{}
 			for i := range albums {
 				iface = append(iface, albums[i])
@@ -130,13 +155,13 @@ func (h *Handler) LuckyHTMX(w http.ResponseWriter, r *http.Request) {
 		wg.Add(1)
 		go func() {
 			defer wg.Done()
-			h.Logger.Info("Fetching artist recommendations", "artist_id", seeds.ArtistID)
+			h.log(r).Info("Fetching artist recommendations", "artist_id", seeds.ArtistID)
 			artists, err := provider.GetSimilarArtists(r.Context(), seeds.ArtistID)
 			if err != nil {
 				artistErr = err
 				return
 			}
-			h.Logger.Info("Artist recommendations response", "artist_id", seeds.ArtistID, "count", len(artists))
+			h.log(r).Info("Artist recommendations response", "artist_id", seeds.ArtistID, "count", len(artists))
 			var iface []interface{}
 			for i := range artists {
 				iface = append(iface, artists[i])
@@ -148,7 +173,7 @@ func (h *Handler) LuckyHTMX(w http.ResponseWriter, r *http.Request) {
 	wg.Wait()
 
 	if trackErr != nil || albumErr != nil || artistErr != nil {
-		h.Logger.Error("Failed to get recommendations", "track_error", trackErr, "album_error", albumErr, "artist_error", artistErr)
+		h.log(r).Error("Failed to get recommendations", "track_error", trackErr, "album_error", albumErr, "artist_error", artistErr)
 	}
 
 	h.recsMutex.Lock()
@@ -174,7 +199,7 @@ func (h *Handler) ArtistPage(w http.ResponseWriter, r *http.Request) {
 		"ActivePage": "search",
 		"Artist":     artist,
 	}
-	h.RenderPage(w, "artist.html", data)
+	h.RenderCachedPage(w, r, "artist.html", data)
 }
 
 func (h *Handler) AlbumPage(w http.ResponseWriter, r *http.Request) {
@@ -188,7 +213,7 @@ func (h *Handler) AlbumPage(w http.ResponseWriter, r *http.Request) {
 		"ActivePage": "search",
 		"Album":      album,
 	}
-	h.RenderPage(w, "album.html", data)
+	h.RenderCachedPage(w, r, "album.html", data)
 }
 
 func (h *Handler) PlaylistPage(w http.ResponseWriter, r *http.Request) {
@@ -209,7 +234,18 @@ func (h *Handler) DownloadHTMX(w http.ResponseWriter, r *http.Request) {
 	jobType := chi.URLParam(r, "type")
 	id := chi.URLParam(r, "id")
 
-	_, err := h.JobService.EnqueueJob(id, domain.JobType(jobType))
+	// min_duration overrides MIN_TRACK_DURATION_SECS for this album, playlist or artist.
+	var err error
+	if v := r.URL.Query().Get("min_duration"); v != "" {
+		minDuration, convErr := strconv.Atoi(v)
+		if convErr != nil || minDuration < 0 {
+			http.Error(w, "min_duration must be a non-negative number of seconds", http.StatusBadRequest)
+			return
+		}
+		_, err = h.jobs(r).EnqueueJobWithMinDuration(id, domain.JobType(jobType), minDuration)
+	} else {
+		_, err = h.jobs(r).EnqueueJob(id, domain.JobType(jobType))
+	}
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
@@ -219,6 +255,57 @@ func (h *Handler) DownloadHTMX(w http.ResponseWriter, r *http.Request) {
 	_, _ = w.Write([]byte("<div class='alert alert-success'>Download started!</div>"))
 }
 
+// DownloadAlbumTracksHTMX queues an album job limited to the track_id values posted from the
+// album page.
+func (h *Handler) DownloadAlbumTracksHTMX(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, "Invalid form", http.StatusBadRequest)
+		return
+	}
+	trackIDs := r.Form["track_id"]
+	if len(trackIDs) == 0 {
+		selectionAlert(w, http.StatusBadRequest, "alert-error", "Select at least one track")
+		return
+	}
+
+	album, err := h.ProviderManager.GetMetadataProvider().GetAlbum(r.Context(), id)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	for _, trackID := range trackIDs {
+		if !slices.ContainsFunc(album.Tracks, func(t domain.CatalogTrack) bool { return t.ID == trackID }) {
+			selectionAlert(w, http.StatusBadRequest, "alert-error", fmt.Sprintf("Track %s is not on this album", trackID))
+			return
+		}
+	}
+
+	_, err = h.jobs(r).EnqueueAlbumTracks(id, trackIDs)
+	switch {
+	case errors.Is(err, app.ErrInvalidTrackSelection):
+		selectionAlert(w, http.StatusBadRequest, "alert-error", err.Error())
+		return
+	case errors.Is(err, app.ErrAlbumJobQueued):
+		selectionAlert(w, http.StatusConflict, "alert-warning", "This album is already queued, so the selection was not applied. Wait for it to finish, or cancel it first.")
+		return
+	case err != nil:
+		h.log(r).Error("Failed to enqueue album tracks", "album_id", id, "error", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	_, _ = fmt.Fprintf(w, "<div class='alert alert-success'>Download of %d tracks started!</div>", len(trackIDs))
+}
+
+// selectionAlert answers the album track form with an alert; the form swaps error
+// statuses in too, so the message shows next to the selection.
+func selectionAlert(w http.ResponseWriter, status int, class, msg string) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.WriteHeader(status)
+	_, _ = fmt.Fprintf(w, "<div class='alert %s'>%s</div>", class, html.EscapeString(msg))
+}
+
 func (h *Handler) SettingsPage(w http.ResponseWriter, r *http.Request) {
 	h.RenderPage(w, "settings.html", map[string]interface{}{
 		"ActivePage": "settings",
@@ -239,7 +326,7 @@ func (h *Handler) QueueActiveHTMX(w http.ResponseWriter, r *http.Request) {
 
 	jobs, total, err := h.JobService.ListActiveJobs(page, constants.MaxSearchResults)
 	if err != nil {
-		h.Logger.Error("Failed to list active jobs", "error", err)
+		h.log(r).Error("Failed to list active jobs", "error", err)
 	}
 
 	pagination := dto.NewPagination(page, constants.MaxSearchResults, total, "/htmx/queue/active", "#tab-content", "")
@@ -258,13 +345,13 @@ func (h *Handler) QueueHistoryHTMX(w http.ResponseWriter, r *http.Request) {
 
 	jobs, total, err := h.JobService.ListFinishedJobs(page, constants.MaxHistoryItems)
 	if err != nil {
-		h.Logger.Error("Failed to list finished jobs", "error", err)
+		h.log(r).Error("Failed to list finished jobs", "error", err)
 		return
 	}
 
 	stats, err := h.JobService.GetJobStats()
 	if err != nil {
-		h.Logger.Error("Failed to get job stats", "error", err)
+		h.log(r).Error("Failed to get job stats", "error", err)
 	}
 
 	pagination := dto.NewPagination(page, constants.MaxHistoryItems, total, "/htmx/queue/history", "#tab-content", "")
@@ -276,6 +363,20 @@ func (h *Handler) QueueHistoryHTMX(w http.ResponseWriter, r *http.Request) {
 	})
 }
 
+func (h *Handler) JobLogHTMX(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+	events, err := h.JobService.ListJobEvents(id)
+	if err != nil {
+		h.log(r).Error("Failed to list job events", "job_id", id, "error", err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	h.RenderFragment(w, "components/job_log.html", map[string]interface{}{
+		"Events": events,
+	})
+}
+
 func (h *Handler) CancelJobHTMX(w http.ResponseWriter, r *http.Request) {
 	id := chi.URLParam(r, "id")
 	if err := h.JobService.CancelJob(id); err != nil {
@@ -285,7 +386,7 @@ func (h *Handler) CancelJobHTMX(w http.ResponseWriter, r *http.Request) {
 
 	jobs, _, err := h.JobService.ListActiveJobs(1, constants.MaxSearchResults)
 	if err != nil {
-		h.Logger.Error("Failed to list active jobs", "error", err)
+		h.log(r).Error("Failed to list active jobs", "error", err)
 	}
 	h.RenderFragment(w, "components/active_tab.html", map[string]interface{}{
 		"ActiveJobs": jobs,
@@ -301,7 +402,7 @@ func (h *Handler) RetryJobHTMX(w http.ResponseWriter, r *http.Request) {
 
 	jobs, _, err := h.JobService.ListActiveJobs(1, constants.MaxSearchResults)
 	if err != nil {
-		h.Logger.Error("Failed to list active jobs", "error", err)
+		h.log(r).Error("Failed to list active jobs", "error", err)
 	}
 	h.RenderFragment(w, "components/active_tab.html", map[string]interface{}{
 		"ActiveJobs": jobs,
@@ -311,18 +412,25 @@ func (h *Handler) RetryJobHTMX(w http.ResponseWriter, r *http.Request) {
 func (h *Handler) GetProvidersHTMX(w http.ResponseWriter, r *http.Request) {
 	hifiProviders, err := h.ProvidersRepo.ListByType("hifi")
 	if err != nil {
-		h.Logger.Error("Failed to list hifi providers", "error", err)
+		h.log(r).Error("Failed to list hifi providers", "error", err)
 		http.Error(w, "Internal server error", http.StatusInternalServerError)
 		return
 	}
 
 	qobuzProviders, err := h.ProvidersRepo.ListByType("qobuz")
 	if err != nil {
-		h.Logger.Error("Failed to list qobuz providers", "error", err)
+		h.log(r).Error("Failed to list qobuz providers", "error", err)
 		http.Error(w, "Internal server error", http.StatusInternalServerError)
 		return
 	}
 
+	// Header values are usually credentials; only their names are shown.
+	for _, providers := range [][]store.ProviderRecord{hifiProviders, qobuzProviders} {
+		for i := range providers {
+			providers[i].Headers = providers[i].Headers.Masked()
+		}
+	}
+
 	response := map[string]interface{}{
 		"hifi":  hifiProviders,
 		"qobuz": qobuzProviders,
@@ -330,7 +438,7 @@ func (h *Handler) GetProvidersHTMX(w http.ResponseWriter, r *http.Request) {
 
 	w.Header().Set("Content-Type", "application/json")
 	if err := json.NewEncoder(w).Encode(response); err != nil {
-		h.Logger.Error("Failed to encode providers response", "error", err)
+		h.log(r).Error("Failed to encode providers response", "error", err)
 		http.Error(w, "Internal server error", http.StatusInternalServerError)
 		return
 	}
@@ -359,7 +467,7 @@ func (h *Handler) ReorderProvidersHTMX(w http.ResponseWriter, r *http.Request) {
 	}
 
 	if err := h.ProvidersRepo.Reorder(intIDs); err != nil {
-		h.Logger.Error("Failed to reorder providers", "error", err)
+		h.log(r).Error("Failed to reorder providers", "error", err)
 		http.Error(w, "Internal server error", http.StatusInternalServerError)
 		return
 	}
@@ -378,9 +486,16 @@ func (h *Handler) AddProviderHTMX(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	id, err := h.ProvidersRepo.Create(providerType, url, name)
+	// Headers come in the body so API keys stay out of URLs and access logs.
+	headers, err := parseProviderHeaders(r.PostFormValue("headers"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	id, err := h.ProvidersRepo.CreateWithHeaders(providerType, url, name, headers)
 	if err != nil || id == 0 {
-		h.Logger.Error("Failed to create provider", "error", err)
+		h.log(r).Error("Failed to create provider", "error", err)
 		http.Error(w, "Internal server error", http.StatusInternalServerError)
 		return
 	}
@@ -390,6 +505,67 @@ func (h *Handler) AddProviderHTMX(w http.ResponseWriter, r *http.Request) {
 	_, _ = w.Write([]byte(`{"success":true}`))
 }
 
+// TestProviderHTMX checks that a provider URL answers a search, without saving it or
+// changing the active provider. Headers saved for the URL are sent along.
+func (h *Handler) TestProviderHTMX(w http.ResponseWriter, r *http.Request) {
+	url := r.FormValue("url")
+	if url == "" {
+		http.Error(w, "url is required", http.StatusBadRequest)
+		return
+	}
+	providerType := catalog.ProviderType(r.FormValue("type"))
+	if providerType == "" {
+		providerType = catalog.ProviderTypeHifi
+	}
+	if providerType != catalog.ProviderTypeHifi && providerType != catalog.ProviderTypeQobuz {
+		http.Error(w, "type must be hifi or qobuz", http.StatusBadRequest)
+		return
+	}
+
+	providers, err := h.ProvidersRepo.ListByType(string(providerType))
+	if err != nil {
+		h.log(r).Error("Failed to list providers", "error", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+	var headers store.ProviderHeaders
+	for _, p := range providers {
+		if p.URL == url {
+			headers = p.Headers
+			break
+		}
+	}
+
+	result := catalog.Probe(r.Context(), providerType, url, headers)
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(result); err != nil {
+		h.log(r).Error("Failed to encode provider test result", "error", err)
+	}
+}
+
+// parseProviderHeaders reads one "Name: value" header per line, ignoring blank lines. Names
+// and values must be valid in an HTTP request, since they are sent on every provider call.
+func parseProviderHeaders(text string) (store.ProviderHeaders, error) {
+	headers := store.ProviderHeaders{}
+	for _, line := range strings.Split(text, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+		name, value, ok := strings.Cut(line, ":")
+		name = strings.TrimSpace(name)
+		if !ok || !httpguts.ValidHeaderFieldName(name) {
+			return nil, fmt.Errorf("invalid header line %q, expected \"Name: value\"", line)
+		}
+		value = strings.TrimSpace(value)
+		if !httpguts.ValidHeaderFieldValue(value) {
+			return nil, fmt.Errorf("invalid value for header %q", name)
+		}
+		headers[http.CanonicalHeaderKey(name)] = value
+	}
+	return headers, nil
+}
+
 func (h *Handler) RemoveProviderHTMX(w http.ResponseWriter, r *http.Request) {
 	idStr := r.URL.Query().Get("id")
 	if idStr == "" {
@@ -404,7 +580,7 @@ func (h *Handler) RemoveProviderHTMX(w http.ResponseWriter, r *http.Request) {
 	}
 
 	if err := h.ProvidersRepo.Delete(id); err != nil {
-		h.Logger.Error("Failed to delete provider", "error", err)
+		h.log(r).Error("Failed to delete provider", "error", err)
 		http.Error(w, "Internal server error", http.StatusInternalServerError)
 		return
 	}
@@ -433,7 +609,7 @@ func (h *Handler) GetDefaultAPIsHTMX(w http.ResponseWriter, r *http.Request) {
 
 	w.Header().Set("Content-Type", "application/json")
 	if err := json.NewEncoder(w).Encode(response); err != nil {
-		h.Logger.Error("Failed to encode default APIs response", "error", err)
+		h.log(r).Error("Failed to encode default APIs response", "error", err)
 		http.Error(w, "Internal server error", http.StatusInternalServerError)
 	}
 }
@@ -464,7 +640,7 @@ func (h *Handler) SetDefaultAPIHTMX(w http.ResponseWriter, r *http.Request) {
 	}
 
 	if err := h.SettingsRepo.Set(body.Key, body.Value); err != nil {
-		h.Logger.Error("Failed to save default API setting", "error", err)
+		h.log(r).Error("Failed to save default API setting", "error", err)
 		http.Error(w, "Internal server error", http.StatusInternalServerError)
 		return
 	}
@@ -477,7 +653,7 @@ func (h *Handler) SetDefaultAPIHTMX(w http.ResponseWriter, r *http.Request) {
 func (h *Handler) GetGenreMapHTMX(w http.ResponseWriter, r *http.Request) {
 	customMapJSON, err := h.SettingsRepo.Get(store.SettingGenreMap)
 	if err != nil {
-		h.Logger.Error("Failed to get genre map", "error", err)
+		h.log(r).Error("Failed to get genre map", "error", err)
 		http.Error(w, "Internal server error", http.StatusInternalServerError)
 		return
 	}
@@ -490,7 +666,7 @@ func (h *Handler) GetGenreMapHTMX(w http.ResponseWriter, r *http.Request) {
 	if customMapJSON != "" {
 		var customMap map[string]string
 		if unmarshalErr := json.Unmarshal([]byte(customMapJSON), &customMap); unmarshalErr != nil {
-			h.Logger.Error("Failed to unmarshal custom genre map", "error", unmarshalErr)
+			h.log(r).Error("Failed to unmarshal custom genre map", "error", unmarshalErr)
 		} else {
 			response["custom"] = customMap
 		}
@@ -498,7 +674,7 @@ func (h *Handler) GetGenreMapHTMX(w http.ResponseWriter, r *http.Request) {
 
 	w.Header().Set("Content-Type", "application/json")
 	if err := json.NewEncoder(w).Encode(response); err != nil {
-		h.Logger.Error("Failed to encode genre map response", "error", err)
+		h.log(r).Error("Failed to encode genre map response", "error", err)
 		http.Error(w, "Internal server error", http.StatusInternalServerError)
 	}
 }
@@ -515,13 +691,13 @@ func (h *Handler) SetGenreMapHTMX(w http.ResponseWriter, r *http.Request) {
 
 	genreMapJSON, err := json.Marshal(req.GenreMap)
 	if err != nil {
-		h.Logger.Error("Failed to marshal genre map", "error", err)
+		h.log(r).Error("Failed to marshal genre map", "error", err)
 		http.Error(w, "Internal server error", http.StatusInternalServerError)
 		return
 	}
 
 	if err := h.SettingsRepo.Set(store.SettingGenreMap, string(genreMapJSON)); err != nil {
-		h.Logger.Error("Failed to save genre map", "error", err)
+		h.log(r).Error("Failed to save genre map", "error", err)
 		http.Error(w, "Internal server error", http.StatusInternalServerError)
 		return
 	}
@@ -531,7 +707,7 @@ func (h *Handler) SetGenreMapHTMX(w http.ResponseWriter, r *http.Request) {
 
 func (h *Handler) ResetGenreMapHTMX(w http.ResponseWriter, r *http.Request) {
 	if err := h.SettingsRepo.Delete(store.SettingGenreMap); err != nil {
-		h.Logger.Error("Failed to reset genre map", "error", err)
+		h.log(r).Error("Failed to reset genre map", "error", err)
 		http.Error(w, "Internal server error", http.StatusInternalServerError)
 		return
 	}
@@ -539,10 +715,55 @@ func (h *Handler) ResetGenreMapHTMX(w http.ResponseWriter, r *http.Request) {
 	_, _ = w.Write([]byte(`{"success":true}`))
 }
 
+// PreviewGenreMapHTMX resolves a genre through the saved genre map, or the default one when
+// none is saved. With tag parameters it maps those tags, treating the first as the most
+// voted; with isrc it looks up the recording's MusicBrainz tags and maps them the way a
+// download would.
+func (h *Handler) PreviewGenreMapHTMX(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	isrc := strings.TrimSpace(query.Get("isrc"))
+	var preview *app.GenrePreview
+	var err error
+	switch {
+	case isrc != "":
+		preview, err = h.GenreMap.PreviewISRC(r.Context(), isrc)
+	case len(query["tag"]) > 0:
+		var tags []musicbrainz.TagCount
+		for i, name := range query["tag"] {
+			if name = strings.ToLower(strings.TrimSpace(name)); name != "" {
+				tags = append(tags, musicbrainz.TagCount{Name: name, Count: len(query["tag"]) - i})
+			}
+		}
+		preview, err = h.GenreMap.PreviewTags(tags)
+	default:
+		http.Error(w, "tag or isrc is required", http.StatusBadRequest)
+		return
+	}
+	switch {
+	case errors.Is(err, app.ErrMusicBrainzUnavailable):
+		http.Error(w, "MusicBrainz is not available", http.StatusServiceUnavailable)
+		return
+	case errors.Is(err, app.ErrMusicBrainzLookup):
+		h.log(r).Error("Failed to get MusicBrainz tags", "isrc", isrc, "error", err)
+		http.Error(w, "Failed to get MusicBrainz tags", http.StatusBadGateway)
+		return
+	case err != nil:
+		h.log(r).Error("Failed to preview genre map", "error", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(preview); err != nil {
+		h.log(r).Error("Failed to encode genre map preview", "error", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+	}
+}
+
 func (h *Handler) GetMoodListHTMX(w http.ResponseWriter, r *http.Request) {
 	custom, err := h.SettingsRepo.Get(store.SettingMoodList)
 	if err != nil {
-		h.Logger.Error("Failed to get mood list", "error", err)
+		h.log(r).Error("Failed to get mood list", "error", err)
 		http.Error(w, "Internal server error", http.StatusInternalServerError)
 		return
 	}
@@ -555,7 +776,7 @@ func (h *Handler) GetMoodListHTMX(w http.ResponseWriter, r *http.Request) {
 	if custom != "" {
 		var list []string
 		if err := json.Unmarshal([]byte(custom), &list); err != nil {
-			h.Logger.Error("Failed to unmarshal custom mood list", "error", err)
+			h.log(r).Error("Failed to unmarshal custom mood list", "error", err)
 		} else {
 			result["custom"] = list
 		}
@@ -563,7 +784,7 @@ func (h *Handler) GetMoodListHTMX(w http.ResponseWriter, r *http.Request) {
 
 	w.Header().Set("Content-Type", "application/json")
 	if err := json.NewEncoder(w).Encode(result); err != nil {
-		h.Logger.Error("Failed to encode mood list response", "error", err)
+		h.log(r).Error("Failed to encode mood list response", "error", err)
 		http.Error(w, "Internal server error", http.StatusInternalServerError)
 	}
 }
@@ -580,13 +801,13 @@ func (h *Handler) SetMoodListHTMX(w http.ResponseWriter, r *http.Request) {
 
 	data, err := json.Marshal(body.MoodList)
 	if err != nil {
-		h.Logger.Error("Failed to marshal mood list", "error", err)
+		h.log(r).Error("Failed to marshal mood list", "error", err)
 		http.Error(w, "Internal server error", http.StatusInternalServerError)
 		return
 	}
 
 	if err := h.SettingsRepo.Set(store.SettingMoodList, string(data)); err != nil {
-		h.Logger.Error("Failed to save mood list", "error", err)
+		h.log(r).Error("Failed to save mood list", "error", err)
 		http.Error(w, "Internal server error", http.StatusInternalServerError)
 		return
 	}
@@ -596,7 +817,7 @@ func (h *Handler) SetMoodListHTMX(w http.ResponseWriter, r *http.Request) {
 
 func (h *Handler) ResetMoodListHTMX(w http.ResponseWriter, r *http.Request) {
 	if err := h.SettingsRepo.Delete(store.SettingMoodList); err != nil {
-		h.Logger.Error("Failed to reset mood list", "error", err)
+		h.log(r).Error("Failed to reset mood list", "error", err)
 		http.Error(w, "Internal server error", http.StatusInternalServerError)
 		return
 	}
@@ -607,7 +828,7 @@ func (h *Handler) ResetMoodListHTMX(w http.ResponseWriter, r *http.Request) {
 func (h *Handler) GetLanguageListHTMX(w http.ResponseWriter, r *http.Request) {
 	custom, err := h.SettingsRepo.Get(store.SettingLanguageList)
 	if err != nil {
-		h.Logger.Error("Failed to get language list", "error", err)
+		h.log(r).Error("Failed to get language list", "error", err)
 		http.Error(w, "Internal server error", http.StatusInternalServerError)
 		return
 	}
@@ -620,7 +841,7 @@ func (h *Handler) GetLanguageListHTMX(w http.ResponseWriter, r *http.Request) {
 	if custom != "" {
 		var list map[string]string
 		if err := json.Unmarshal([]byte(custom), &list); err != nil {
-			h.Logger.Error("Failed to unmarshal custom language list", "error", err)
+			h.log(r).Error("Failed to unmarshal custom language list", "error", err)
 		} else {
 			result["custom"] = list
 		}
@@ -628,7 +849,7 @@ func (h *Handler) GetLanguageListHTMX(w http.ResponseWriter, r *http.Request) {
 
 	w.Header().Set("Content-Type", "application/json")
 	if err := json.NewEncoder(w).Encode(result); err != nil {
-		h.Logger.Error("Failed to encode language list response", "error", err)
+		h.log(r).Error("Failed to encode language list response", "error", err)
 		http.Error(w, "Internal server error", http.StatusInternalServerError)
 	}
 }
@@ -645,13 +866,13 @@ func (h *Handler) SetLanguageListHTMX(w http.ResponseWriter, r *http.Request) {
 
 	data, err := json.Marshal(body.LanguageList)
 	if err != nil {
-		h.Logger.Error("Failed to marshal language list", "error", err)
+		h.log(r).Error("Failed to marshal language list", "error", err)
 		http.Error(w, "Internal server error", http.StatusInternalServerError)
 		return
 	}
 
 	if err := h.SettingsRepo.Set(store.SettingLanguageList, string(data)); err != nil {
-		h.Logger.Error("Failed to save language list", "error", err)
+		h.log(r).Error("Failed to save language list", "error", err)
 		http.Error(w, "Internal server error", http.StatusInternalServerError)
 		return
 	}
@@ -661,7 +882,7 @@ func (h *Handler) SetLanguageListHTMX(w http.ResponseWriter, r *http.Request) {
 
 func (h *Handler) ResetLanguageListHTMX(w http.ResponseWriter, r *http.Request) {
 	if err := h.SettingsRepo.Delete(store.SettingLanguageList); err != nil {
-		h.Logger.Error("Failed to reset language list", "error", err)
+		h.log(r).Error("Failed to reset language list", "error", err)
 		http.Error(w, "Internal server error", http.StatusInternalServerError)
 		return
 	}
@@ -672,7 +893,7 @@ func (h *Handler) ResetLanguageListHTMX(w http.ResponseWriter, r *http.Request)
 func (h *Handler) GetGenreSeparatorHTMX(w http.ResponseWriter, r *http.Request) {
 	sep, err := h.SettingsRepo.Get(store.SettingGenreSeparator)
 	if err != nil {
-		h.Logger.Error("Failed to get genre separator", "error", err)
+		h.log(r).Error("Failed to get genre separator", "error", err)
 		http.Error(w, "Internal server error", http.StatusInternalServerError)
 		return
 	}
@@ -683,7 +904,7 @@ func (h *Handler) GetGenreSeparatorHTMX(w http.ResponseWriter, r *http.Request)
 
 	w.Header().Set("Content-Type", "application/json")
 	if err := json.NewEncoder(w).Encode(map[string]string{"separator": sep}); err != nil {
-		h.Logger.Error("Failed to encode genre separator response", "error", err)
+		h.log(r).Error("Failed to encode genre separator response", "error", err)
 		http.Error(w, "Internal server error", http.StatusInternalServerError)
 	}
 }
@@ -699,7 +920,7 @@ func (h *Handler) SetGenreSeparatorHTMX(w http.ResponseWriter, r *http.Request)
 	}
 
 	if err := h.SettingsRepo.Set(store.SettingGenreSeparator, req.Separator); err != nil {
-		h.Logger.Error("Failed to save genre separator", "error", err)
+		h.log(r).Error("Failed to save genre separator", "error", err)
 		http.Error(w, "Internal server error", http.StatusInternalServerError)
 		return
 	}
@@ -718,6 +939,61 @@ func (h *Handler) SimilarAlbumsHTMX(w http.ResponseWriter, r *http.Request) {
 	h.RenderFragment(w, "similar_albums.html", albums)
 }
 
+// RadioPage shows the radio of a track, loading its tracks from RadioHTMX.
+func (h *Handler) RadioPage(w http.ResponseWriter, r *http.Request) {
+	data := map[string]interface{}{
+		"ActivePage": "search",
+		"SeedID":     chi.URLParam(r, "id"),
+	}
+	h.RenderPage(w, "radio.html", data)
+}
+
+// RadioHTMX builds a playlist from a seed track and its similar tracks, which can be
+// downloaded as a playlist job.
+func (h *Handler) RadioHTMX(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+	pl, err := app.BuildRadio(r.Context(), h.ProviderManager.GetMetadataProvider(), id)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	h.RenderFragment(w, "radio_tracks.html", pl)
+}
+
+// AlbumPreviewHTMX shows where each track of an album would be downloaded, which tracks
+// already exist and the estimated size, without enqueuing anything.
+func (h *Handler) AlbumPreviewHTMX(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+	album, err := h.ProviderManager.GetMetadataProvider().GetAlbum(r.Context(), id)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+
+	preview, err := h.DownloadsService.PreviewAlbum(album, h.downloadQuality())
+	if err != nil {
+		h.log(r).Error("Failed to preview album download", "error", err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	h.RenderFragment(w, "album_preview.html", preview)
+}
+
+// downloadQuality returns the preferred download quality tier: the runtime setting when
+// set, otherwise the first tier of QUALITY.
+func (h *Handler) downloadQuality() string {
+	quality, err := h.SettingsRepo.Get(store.SettingQuality)
+	if err != nil || quality == "" {
+		quality = h.Config.Quality
+	}
+	if tiers, err := config.ParseQualities(quality); err == nil {
+		return tiers[0]
+	}
+	return constants.DefaultQuality
+}
+
 func (h *Handler) SimilarArtistsHTMX(w http.ResponseWriter, r *http.Request) {
 	id := chi.URLParam(r, "id")
 	artists, err := h.ProviderManager.GetMetadataProvider().GetSimilarArtists(r.Context(), id)
@@ -741,7 +1017,7 @@ func (h *Handler) ClearHistoryHTMX(w http.ResponseWriter, r *http.Request) {
 func (h *Handler) DownloadsPage(w http.ResponseWriter, r *http.Request) {
 	genres, err := h.DownloadsService.GetAllGenres()
 	if err != nil {
-		h.Logger.Error("Failed to get genres", "error", err)
+		h.log(r).Error("Failed to get genres", "error", err)
 		genres = []string{}
 	}
 	h.RenderPage(w, "downloads.html", map[string]interface{}{
@@ -753,6 +1029,23 @@ func (h *Handler) DownloadsPage(w http.ResponseWriter, r *http.Request) {
 func (h *Handler) DownloadsHTMX(w http.ResponseWriter, r *http.Request) {
 	query := r.URL.Query().Get("q")
 	filter := r.URL.Query().Get("filter")
+	if filter == "recent" && query == "" {
+		h.recentDownloadsHTMX(w, r)
+		return
+	}
+	quality := r.URL.Query().Get("quality")
+	if !slices.Contains(downloadQualityFilters, quality) {
+		quality = ""
+	}
+	format := strings.ToLower(strings.TrimSpace(r.URL.Query().Get("format")))
+	if format != "" && !strings.HasPrefix(format, ".") {
+		format = "." + format
+	}
+	source := r.URL.Query().Get("provider")
+	if source != string(catalog.ProviderTypeHifi) && source != string(catalog.ProviderTypeQobuz) {
+		source = ""
+	}
+	sort := store.ParseTrackSort(r.URL.Query().Get("sort"), r.URL.Query().Get("order"))
 
 	page := 1
 	if p := r.URL.Query().Get("page"); p != "" {
@@ -763,37 +1056,135 @@ func (h *Handler) DownloadsHTMX(w http.ResponseWriter, r *http.Request) {
 	var total int
 	var err error
 
-	extraParams := ""
+	params := url.Values{}
 
 	switch {
+	case filter == "" && (quality != "" || format != "" || source != ""):
+		tracks, total, err = h.DownloadsService.ListFilteredDownloads(store.TrackFilter{
+			Search:         query,
+			AudioQuality:   quality,
+			FileExtension:  format,
+			SourceProvider: source,
+		}, sort, page, constants.MaxSearchResults)
+		for k, v := range map[string]string{"q": query, "quality": quality, "format": format, "provider": source} {
+			if v != "" {
+				params.Set(k, v)
+			}
+		}
 	case query != "":
-		tracks, total, err = h.DownloadsService.SearchDownloads(query, page, constants.MaxSearchResults)
-		extraParams = "q=" + query
+		tracks, total, err = h.DownloadsService.SearchDownloads(query, sort, page, constants.MaxSearchResults)
+		params.Set("q", query)
 	case filter != "":
-		tracks, total, err = h.DownloadsService.FilterDownloads(filter, page, constants.MaxSearchResults)
-		extraParams = "filter=" + filter
+		tracks, total, err = h.DownloadsService.FilterDownloads(filter, sort, page, constants.MaxSearchResults)
+		params.Set("filter", filter)
 	default:
-		tracks, total, err = h.DownloadsService.ListDownloads(page, constants.MaxSearchResults)
+		tracks, total, err = h.DownloadsService.ListDownloads(sort, page, constants.MaxSearchResults)
+	}
+	if sort.Key != "" {
+		params.Set("sort", sort.Key)
+		params.Set("order", sort.Order())
 	}
 	if err != nil {
-		h.Logger.Error("Failed to list downloads", "error", err)
+		h.log(r).Error("Failed to list downloads", "error", err)
 		http.Error(w, "Internal server error", http.StatusInternalServerError)
 		return
 	}
 
-	pagination := dto.NewPagination(page, constants.MaxSearchResults, total, "/htmx/downloads", "#downloads-list", extraParams)
+	pagination := dto.NewPagination(page, constants.MaxSearchResults, total, "/htmx/downloads", "#downloads-list", params.Encode())
 
 	h.RenderFragment(w, "components/downloads_list.html", map[string]interface{}{
 		"Downloads":  tracks,
 		"Filter":     filter,
+		"Sort":       sort.Key,
+		"Order":      sort.Order(),
 		"Pagination": pagination,
 	})
 }
 
+// recentDownloadsHTMX renders the "Recently added" view: tracks completed in the last
+// days days (constants.RecentDays by default), grouped by the date they finished.
+func (h *Handler) recentDownloadsHTMX(w http.ResponseWriter, r *http.Request) {
+	days := 0
+	if d := r.URL.Query().Get("days"); d != "" {
+		_, _ = fmt.Sscanf(d, "%d", &days)
+	}
+	groups, err := h.DownloadsService.ListRecent(days)
+	if err != nil {
+		h.log(r).Error("Failed to list recent downloads", "error", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+	if days < 1 {
+		days = constants.RecentDays
+	}
+
+	h.RenderFragment(w, "components/recent_downloads.html", map[string]interface{}{
+		"Days":   days,
+		"Groups": groups,
+	})
+}
+
+// downloadQualityFilters are the audio qualities the downloads list can be filtered by.
+var downloadQualityFilters = []string{
+	constants.QualityHiResLossless,
+	constants.QualityLossless,
+	constants.QualityHigh,
+	constants.QualityLow,
+}
+
 func (h *Handler) DeleteDownloadHTMX(w http.ResponseWriter, r *http.Request) {
 	id := chi.URLParam(r, "id")
 	if err := h.DownloadsService.DeleteDownload(id); err != nil {
-		h.Logger.Error("Failed to delete download", "error", err)
+		h.log(r).Error("Failed to delete download", "error", err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	h.DownloadsHTMX(w, r)
+}
+
+func (h *Handler) RestoreDownloadHTMX(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+	if err := h.DownloadsService.RestoreDownload(id); err != nil {
+		h.log(r).Error("Failed to restore download", "error", err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	h.DownloadsHTMX(w, r)
+}
+
+func (h *Handler) RedownloadHTMX(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+	if _, err := h.jobs(r).EnqueueJob(id, domain.JobTypeTrack); err != nil {
+		h.log(r).Error("Failed to enqueue re-download", "error", err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	h.DownloadsHTMX(w, r)
+}
+
+// RetryFailedHTMX re-queues every failed track and reports how many were queued.
+func (h *Handler) RetryFailedHTMX(w http.ResponseWriter, r *http.Request) {
+	requeued, err := h.jobs(r).RetryFailedTracks()
+	if err != nil {
+		h.log(r).Error("Failed to retry failed tracks", "error", err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	tracks, _, _ := h.DownloadsService.ListDownloads(store.TrackSort{}, 1, constants.MaxSearchResults)
+	h.RenderFragment(w, "components/downloads_list.html", map[string]interface{}{
+		"Downloads":      tracks,
+		"FailedRetried":  true,
+		"FailedRequeued": requeued,
+	})
+}
+
+func (h *Handler) EmptyTrashHTMX(w http.ResponseWriter, r *http.Request) {
+	if _, err := h.DownloadsService.EmptyTrash(); err != nil {
+		h.log(r).Error("Failed to empty trash", "error", err)
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
@@ -810,7 +1201,7 @@ func (h *Handler) BulkDeleteHTMX(w http.ResponseWriter, r *http.Request) {
 	ids := r.Form["ids[]"]
 	for _, id := range ids {
 		if err := h.DownloadsService.DeleteDownload(id); err != nil {
-			h.Logger.Error("Failed to delete download", "id", id, "error", err)
+			h.log(r).Error("Failed to delete download", "id", id, "error", err)
 		}
 	}
 
@@ -826,8 +1217,8 @@ func (h *Handler) BulkSyncHTMX(w http.ResponseWriter, r *http.Request) {
 	ids := r.Form["ids[]"]
 	count := 0
 	for _, id := range ids {
-		if err := h.DownloadsService.EnqueueSyncMetadataJob(id); err != nil {
-			h.Logger.Error("Failed to enqueue sync job", "id", id, "error", err)
+		if err := h.downloads(r).EnqueueSyncMetadataJob(id); err != nil {
+			h.log(r).Error("Failed to enqueue sync job", "id", id, "error", err)
 			continue
 		}
 		count++
@@ -836,9 +1227,9 @@ func (h *Handler) BulkSyncHTMX(w http.ResponseWriter, r *http.Request) {
 	var tracks []*domain.Track
 	query := r.URL.Query().Get("q")
 	if query != "" {
-		tracks, _, _ = h.DownloadsService.SearchDownloads(query, 1, constants.MaxSearchResults)
+		tracks, _, _ = h.DownloadsService.SearchDownloads(query, store.TrackSort{}, 1, constants.MaxSearchResults)
 	} else {
-		tracks, _, _ = h.DownloadsService.ListDownloads(1, constants.MaxSearchResults)
+		tracks, _, _ = h.DownloadsService.ListDownloads(store.TrackSort{}, 1, constants.MaxSearchResults)
 	}
 
 	h.RenderFragment(w, "components/downloads_list.html", map[string]interface{}{
@@ -861,8 +1252,9 @@ func (h *Handler) BulkUpdateGenreHTMX(w http.ResponseWriter, r *http.Request) {
 	pathArtist := r.FormValue("path_artist")
 	artists := r.FormValue("artists")
 	albumArtists := r.FormValue("album_artists")
+	locked := r.FormValue("locked")
 
-	if year == "" && genre == "" && mood == "" && language == "" && pathArtist == "" && artists == "" && albumArtists == "" {
+	if year == "" && genre == "" && mood == "" && language == "" && pathArtist == "" && artists == "" && albumArtists == "" && locked == "" {
 		http.Error(w, "At least one field is required", http.StatusBadRequest)
 		return
 	}
@@ -870,7 +1262,7 @@ func (h *Handler) BulkUpdateGenreHTMX(w http.ResponseWriter, r *http.Request) {
 	for _, providerID := range ids {
 		track, err := h.DownloadsService.GetDownloadByProviderID(providerID)
 		if err != nil || track == nil {
-			h.Logger.Error("Failed to get track for metadata update", "provider_id", providerID, "error", err)
+			h.log(r).Error("Failed to get track for metadata update", "provider_id", providerID, "error", err)
 			continue
 		}
 
@@ -909,17 +1301,25 @@ func (h *Handler) BulkUpdateGenreHTMX(w http.ResponseWriter, r *http.Request) {
 			updates["album_artists"] = albumArtistList
 		}
 
+		// Locking alone changes nothing in the file, so it needs no sync.
+		retag := len(updates) > 0
+		if locked != "" {
+			updates["locked"] = locked == "true"
+		}
 		if len(updates) == 0 {
 			continue
 		}
 
 		if err := h.DownloadsService.UpdateTrackPartial(track.ID, updates); err != nil {
-			h.Logger.Error("Failed to update metadata", "track_id", track.ID, "error", err)
+			h.log(r).Error("Failed to update metadata", "track_id", track.ID, "error", err)
 			continue
 		}
 
-		if err := h.DownloadsService.EnqueueSyncFileJob(providerID); err != nil {
-			h.Logger.Error("Failed to enqueue sync job", "provider_id", providerID, "error", err)
+		if !retag {
+			continue
+		}
+		if err := h.downloads(r).EnqueueSyncFileJob(providerID); err != nil {
+			h.log(r).Error("Failed to enqueue sync job", "provider_id", providerID, "error", err)
 		}
 	}
 
@@ -936,7 +1336,7 @@ func (h *Handler) TrackPage(w http.ResponseWriter, r *http.Request) {
 
 	track, err := h.DownloadsService.GetTrackByID(trackID)
 	if err != nil {
-		h.Logger.Error("Failed to get track", "error", err)
+		h.log(r).Error("Failed to get track", "error", err)
 		http.Error(w, "Track not found", http.StatusNotFound)
 		return
 	}
@@ -944,9 +1344,40 @@ func (h *Handler) TrackPage(w http.ResponseWriter, r *http.Request) {
 	h.RenderPage(w, "track.html", map[string]interface{}{
 		"ActivePage": "downloads",
 		"Track":      track,
+		"FilePath":   h.DownloadsService.RevealedPath(track.FilePath),
 	})
 }
 
+// TrackPathAPI returns the absolute path of a track's file and its folder, when the file is
+// under one of the download directories.
+func (h *Handler) TrackPathAPI(w http.ResponseWriter, r *http.Request) {
+	var trackID int
+	if _, err := fmt.Sscanf(chi.URLParam(r, "id"), "%d", &trackID); err != nil {
+		http.Error(w, "Invalid track ID", http.StatusBadRequest)
+		return
+	}
+
+	path, err := h.DownloadsService.TrackFilePath(trackID)
+	switch {
+	case errors.Is(err, sql.ErrNoRows), errors.Is(err, app.ErrNoTrackFile):
+		http.Error(w, "Track file not found", http.StatusNotFound)
+		return
+	case errors.Is(err, app.ErrPathNotRevealed):
+		h.log(r).Warn("Refused to reveal a track path outside the downloads directories", "id", trackID)
+		http.Error(w, "Track file is outside the downloads directories", http.StatusForbidden)
+		return
+	case err != nil:
+		h.log(r).Error("Failed to get track path", "id", trackID, "error", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(path); err != nil {
+		h.log(r).Error("Failed to encode track path", "error", err)
+	}
+}
+
 func (h *Handler) TrackHTMX(w http.ResponseWriter, r *http.Request) {
 	id := chi.URLParam(r, "id")
 	var trackID int
@@ -957,7 +1388,7 @@ func (h *Handler) TrackHTMX(w http.ResponseWriter, r *http.Request) {
 
 	track, err := h.DownloadsService.GetTrackByID(trackID)
 	if err != nil {
-		h.Logger.Error("Failed to get track", "error", err)
+		h.log(r).Error("Failed to get track", "error", err)
 		http.Error(w, "Track not found", http.StatusNotFound)
 		return
 	}
@@ -967,6 +1398,20 @@ func (h *Handler) TrackHTMX(w http.ResponseWriter, r *http.Request) {
 	})
 }
 
+// trackFormCheckboxes are the track form's checkboxes. Each follows a hidden "false" input
+// so that unticking it is saved.
+var trackFormCheckboxes = []string{"compilation", "locked"}
+
+// lastFormValues keeps only the last value of each of names in form. A ticked checkbox
+// sends "true" after its hidden "false", and the form decoder takes the first value.
+func lastFormValues(form url.Values, names ...string) {
+	for _, name := range names {
+		if values := form[name]; len(values) > 1 {
+			form[name] = values[len(values)-1:]
+		}
+	}
+}
+
 func (h *Handler) SaveTrackHTMX(w http.ResponseWriter, r *http.Request) {
 	id := chi.URLParam(r, "id")
 	var trackID int
@@ -977,7 +1422,7 @@ func (h *Handler) SaveTrackHTMX(w http.ResponseWriter, r *http.Request) {
 
 	track, err := h.DownloadsService.GetTrackByID(trackID)
 	if err != nil {
-		h.Logger.Error("Failed to get track", "error", err)
+		h.log(r).Error("Failed to get track", "error", err)
 		http.Error(w, "Track not found", http.StatusNotFound)
 		return
 	}
@@ -987,16 +1432,17 @@ func (h *Handler) SaveTrackHTMX(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	lastFormValues(r.PostForm, trackFormCheckboxes...)
 	var d dto.TrackUpdateRequest
 	if decodeErr := h.FormDecoder.Decode(&d, r.PostForm); decodeErr != nil {
-		h.Logger.Error("Failed to decode form", "error", decodeErr)
+		h.log(r).Error("Failed to decode form", "error", decodeErr)
 		http.Error(w, "Failed to decode form", http.StatusBadRequest)
 		return
 	}
 
 	validationErrs := d.Validate()
 	if len(validationErrs) > 0 {
-		h.Logger.Warn("Track validation failed", "errors", validationErrs)
+		h.log(r).Warn("Track validation failed", "errors", validationErrs)
 		h.RenderFragment(w, "components/track_form.html", map[string]interface{}{
 			"Track":            track,
 			"ValidationErrors": dto.ToMap(validationErrs),
@@ -1013,14 +1459,14 @@ func (h *Handler) SaveTrackHTMX(w http.ResponseWriter, r *http.Request) {
 	}
 
 	if updateErr := h.DownloadsService.UpdateTrackPartial(trackID, updates); updateErr != nil {
-		h.Logger.Error("Failed to update track", "error", updateErr)
+		h.log(r).Error("Failed to update track", "error", updateErr)
 		http.Error(w, updateErr.Error(), http.StatusInternalServerError)
 		return
 	}
 
 	track, err = h.DownloadsService.GetTrackByID(trackID)
 	if err != nil {
-		h.Logger.Error("Failed to get track", "error", err)
+		h.log(r).Error("Failed to get track", "error", err)
 		http.Error(w, "Track not found", http.StatusNotFound)
 		return
 	}
@@ -1031,12 +1477,61 @@ func (h *Handler) SaveTrackHTMX(w http.ResponseWriter, r *http.Request) {
 	})
 }
 
+// CopyTrackMetadataHTMX copies the album-level fields of the track sourceId onto the track
+// id and queues a sync of its file.
+func (h *Handler) CopyTrackMetadataHTMX(w http.ResponseWriter, r *http.Request) {
+	var trackID, sourceID int
+	if _, err := fmt.Sscanf(chi.URLParam(r, "id"), "%d", &trackID); err != nil {
+		http.Error(w, "Invalid track ID", http.StatusBadRequest)
+		return
+	}
+	if _, err := fmt.Sscanf(chi.URLParam(r, "sourceId"), "%d", &sourceID); err != nil {
+		http.Error(w, "Invalid source track ID", http.StatusBadRequest)
+		return
+	}
+
+	track, err := h.DownloadsService.GetTrackByID(trackID)
+	if err != nil {
+		h.log(r).Error("Failed to get track", "error", err)
+		http.Error(w, "Track not found", http.StatusNotFound)
+		return
+	}
+	source, err := h.DownloadsService.GetTrackByID(sourceID)
+	if err != nil {
+		h.log(r).Error("Failed to get source track", "error", err)
+		http.Error(w, "Source track not found", http.StatusNotFound)
+		return
+	}
+
+	if err := h.DownloadsService.CopyTrackMetadata(track, source); err != nil {
+		h.log(r).Error("Failed to copy track metadata", "track_id", trackID, "source_id", sourceID, "error", err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	track, err = h.DownloadsService.GetTrackByID(trackID)
+	if err != nil {
+		h.log(r).Error("Failed to get track", "error", err)
+		http.Error(w, "Track not found", http.StatusNotFound)
+		return
+	}
+
+	h.RenderFragment(w, "components/track_form.html", map[string]interface{}{
+		"Track":           track,
+		"JobEnqueued":     true,
+		"JobEnqueuedType": string(enrichActionCopyMetadata),
+		"CopiedFrom":      source,
+	})
+}
+
 type enrichAction string
 
 const (
 	enrichActionSyncFile        enrichAction = "sync_file"
 	enrichActionSyncMusicBrainz enrichAction = "sync_musicbrainz"
 	enrichActionSyncHiFi        enrichAction = "sync_hifi"
+	enrichActionSyncLyrics      enrichAction = "sync_lyrics"
+	enrichActionCopyMetadata    enrichAction = "copy_metadata"
 )
 
 func (h *Handler) handleTrackEnrich(w http.ResponseWriter, r *http.Request) (*domain.Track, bool) {
@@ -1049,7 +1544,7 @@ func (h *Handler) handleTrackEnrich(w http.ResponseWriter, r *http.Request) (*do
 
 	track, err := h.DownloadsService.GetTrackByID(trackID)
 	if err != nil {
-		h.Logger.Error("Failed to get track", "error", err)
+		h.log(r).Error("Failed to get track", "error", err)
 		http.Error(w, "Track not found", http.StatusNotFound)
 		return nil, false
 	}
@@ -1059,16 +1554,17 @@ func (h *Handler) handleTrackEnrich(w http.ResponseWriter, r *http.Request) (*do
 		return nil, false
 	}
 
+	lastFormValues(r.PostForm, trackFormCheckboxes...)
 	var d dto.TrackUpdateRequest
 	if decodeErr := h.FormDecoder.Decode(&d, r.PostForm); decodeErr != nil {
-		h.Logger.Error("Failed to decode form", "error", decodeErr)
+		h.log(r).Error("Failed to decode form", "error", decodeErr)
 		http.Error(w, "Failed to decode form", http.StatusBadRequest)
 		return nil, false
 	}
 
 	validationErrs := d.Validate()
 	if len(validationErrs) > 0 {
-		h.Logger.Warn("Track validation failed", "errors", validationErrs)
+		h.log(r).Warn("Track validation failed", "errors", validationErrs)
 		h.RenderFragment(w, "components/track_form.html", map[string]interface{}{
 			"Track":            track,
 			"ValidationErrors": dto.ToMap(validationErrs),
@@ -1079,7 +1575,7 @@ func (h *Handler) handleTrackEnrich(w http.ResponseWriter, r *http.Request) (*do
 	updates := d.ToUpdates()
 	if len(updates) > 0 {
 		if updateErr := h.DownloadsService.UpdateTrackPartial(trackID, updates); updateErr != nil {
-			h.Logger.Error("Failed to update track", "error", updateErr)
+			h.log(r).Error("Failed to update track", "error", updateErr)
 			http.Error(w, updateErr.Error(), http.StatusInternalServerError)
 			return nil, false
 		}
@@ -1103,8 +1599,8 @@ func (h *Handler) SyncTrackHTMX(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if err := h.DownloadsService.EnqueueSyncFileJob(track.ProviderID); err != nil {
-		h.Logger.Error("Failed to enqueue sync job", "error", err)
+	if err := h.downloads(r).EnqueueSyncFileJob(track.ProviderID); err != nil {
+		h.log(r).Error("Failed to enqueue sync job", "error", err)
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
@@ -1118,8 +1614,8 @@ func (h *Handler) EnrichTrackHTMX(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if err := h.DownloadsService.EnqueueSyncMetadataJob(track.ProviderID); err != nil {
-		h.Logger.Error("Failed to enqueue enrich job", "error", err)
+	if err := h.downloads(r).EnqueueSyncMetadataJob(track.ProviderID); err != nil {
+		h.log(r).Error("Failed to enqueue enrich job", "error", err)
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
@@ -1133,8 +1629,8 @@ func (h *Handler) EnrichHiFiHTMX(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if err := h.DownloadsService.EnqueueSyncHiFiJob(track.ProviderID); err != nil {
-		h.Logger.Error("Failed to enqueue enrich Hi-Fi job", "error", err)
+	if err := h.downloads(r).EnqueueSyncHiFiJob(track.ProviderID); err != nil {
+		h.log(r).Error("Failed to enqueue enrich Hi-Fi job", "error", err)
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
@@ -1142,21 +1638,52 @@ func (h *Handler) EnrichHiFiHTMX(w http.ResponseWriter, r *http.Request) {
 	h.renderEnrichResponse(w, track, enrichActionSyncHiFi)
 }
 
+func (h *Handler) RefetchLyricsHTMX(w http.ResponseWriter, r *http.Request) {
+	track, ok := h.handleTrackEnrich(w, r)
+	if !ok {
+		return
+	}
+
+	if err := h.downloads(r).EnqueueSyncLyricsJob(track.ProviderID); err != nil {
+		h.log(r).Error("Failed to enqueue lyrics job", "error", err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	h.renderEnrichResponse(w, track, enrichActionSyncLyrics)
+}
+
 func (h *Handler) SyncAllHTMX(w http.ResponseWriter, r *http.Request) {
-	count, err := h.DownloadsService.EnqueueSyncJobs()
+	h.startSyncUpgradeSummary()
+	count, err := h.downloads(r).EnqueueSyncJobs()
 	if err != nil {
-		h.Logger.Error("Failed to enqueue sync jobs", "error", err)
+		h.log(r).Error("Failed to enqueue sync jobs", "error", err)
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
 
-	tracks, _, _ := h.DownloadsService.ListDownloads(1, constants.MaxSearchResults)
+	tracks, _, _ := h.DownloadsService.ListDownloads(store.TrackSort{}, 1, constants.MaxSearchResults)
 	h.RenderFragment(w, "components/downloads_list.html", map[string]interface{}{
 		"Downloads":    tracks,
 		"SyncEnqueued": count,
 	})
 }
 
+func (h *Handler) RetagAllHTMX(w http.ResponseWriter, r *http.Request) {
+	count, err := h.downloads(r).EnqueueRetagJobs()
+	if err != nil {
+		h.log(r).Error("Failed to enqueue retag jobs", "error", err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	tracks, _, _ := h.DownloadsService.ListDownloads(store.TrackSort{}, 1, constants.MaxSearchResults)
+	h.RenderFragment(w, "components/downloads_list.html", map[string]interface{}{
+		"Downloads":     tracks,
+		"RetagEnqueued": count,
+	})
+}
+
 func (h *Handler) BulkEnrichHiFiHTMX(w http.ResponseWriter, r *http.Request) {
 	if err := r.ParseForm(); err != nil {
 		http.Error(w, "Failed to parse form", http.StatusBadRequest)
@@ -1167,13 +1694,14 @@ func (h *Handler) BulkEnrichHiFiHTMX(w http.ResponseWriter, r *http.Request) {
 	var count int
 	var err error
 
+	h.startSyncUpgradeSummary()
 	if len(ids) == 0 {
-		count, err = h.DownloadsService.EnqueueSyncJobs()
+		count, err = h.downloads(r).EnqueueSyncJobs()
 	} else {
 		count = 0
 		for _, id := range ids {
-			if e := h.DownloadsService.EnqueueSyncHiFiJob(id); e != nil {
-				h.Logger.Error("Failed to enqueue HiFi job", "id", id, "error", e)
+			if e := h.downloads(r).EnqueueSyncHiFiJob(id); e != nil {
+				h.log(r).Error("Failed to enqueue HiFi job", "id", id, "error", e)
 				continue
 			}
 			count++
@@ -1181,12 +1709,12 @@ func (h *Handler) BulkEnrichHiFiHTMX(w http.ResponseWriter, r *http.Request) {
 	}
 
 	if err != nil {
-		h.Logger.Error("Failed to enqueue HiFi jobs", "error", err)
+		h.log(r).Error("Failed to enqueue HiFi jobs", "error", err)
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
 
-	tracks, _, _ := h.DownloadsService.ListDownloads(1, constants.MaxSearchResults)
+	tracks, _, _ := h.DownloadsService.ListDownloads(store.TrackSort{}, 1, constants.MaxSearchResults)
 	h.RenderFragment(w, "components/downloads_list.html", map[string]interface{}{
 		"Downloads":    tracks,
 		"SyncEnqueued": count,
@@ -1204,12 +1732,48 @@ func (h *Handler) BulkEnrichMusicBrainzHTMX(w http.ResponseWriter, r *http.Reque
 	var err error
 
 	if len(ids) == 0 {
-		count, err = h.DownloadsService.EnqueueSyncMetadataJobs()
+		count, err = h.downloads(r).EnqueueSyncMetadataJobs()
+	} else {
+		count = 0
+		for _, id := range ids {
+			if e := h.downloads(r).EnqueueSyncMetadataJob(id); e != nil {
+				h.log(r).Error("Failed to enqueue MusicBrainz job", "id", id, "error", e)
+				continue
+			}
+			count++
+		}
+	}
+
+	if err != nil {
+		h.log(r).Error("Failed to enqueue MusicBrainz jobs", "error", err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	tracks, _, _ := h.DownloadsService.ListDownloads(store.TrackSort{}, 1, constants.MaxSearchResults)
+	h.RenderFragment(w, "components/downloads_list.html", map[string]interface{}{
+		"Downloads":    tracks,
+		"SyncEnqueued": count,
+	})
+}
+
+func (h *Handler) BulkRefetchLyricsHTMX(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, "Failed to parse form", http.StatusBadRequest)
+		return
+	}
+
+	ids := r.Form["ids[]"]
+	var count int
+	var err error
+
+	if len(ids) == 0 {
+		count, err = h.downloads(r).EnqueueSyncLyricsJobs()
 	} else {
 		count = 0
 		for _, id := range ids {
-			if e := h.DownloadsService.EnqueueSyncMetadataJob(id); e != nil {
-				h.Logger.Error("Failed to enqueue MusicBrainz job", "id", id, "error", e)
+			if e := h.downloads(r).EnqueueSyncLyricsJob(id); e != nil {
+				h.log(r).Error("Failed to enqueue lyrics job", "id", id, "error", e)
 				continue
 			}
 			count++
@@ -1217,12 +1781,12 @@ func (h *Handler) BulkEnrichMusicBrainzHTMX(w http.ResponseWriter, r *http.Reque
 	}
 
 	if err != nil {
-		h.Logger.Error("Failed to enqueue MusicBrainz jobs", "error", err)
+		h.log(r).Error("Failed to enqueue lyrics jobs", "error", err)
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
 
-	tracks, _, _ := h.DownloadsService.ListDownloads(1, constants.MaxSearchResults)
+	tracks, _, _ := h.DownloadsService.ListDownloads(store.TrackSort{}, 1, constants.MaxSearchResults)
 	h.RenderFragment(w, "components/downloads_list.html", map[string]interface{}{
 		"Downloads":    tracks,
 		"SyncEnqueued": count,
@@ -1232,7 +1796,7 @@ func (h *Handler) BulkEnrichMusicBrainzHTMX(w http.ResponseWriter, r *http.Reque
 func (h *Handler) GetThemeHTMX(w http.ResponseWriter, r *http.Request) {
 	theme, err := h.SettingsRepo.Get(store.SettingTheme)
 	if err != nil {
-		h.Logger.Error("Failed to get theme", "error", err)
+		h.log(r).Error("Failed to get theme", "error", err)
 		http.Error(w, "Internal server error", http.StatusInternalServerError)
 		return
 	}
@@ -1248,7 +1812,7 @@ func (h *Handler) GetThemeHTMX(w http.ResponseWriter, r *http.Request) {
 
 	w.Header().Set("Content-Type", "application/json")
 	if err := json.NewEncoder(w).Encode(response); err != nil {
-		h.Logger.Error("Failed to encode theme response", "error", err)
+		h.log(r).Error("Failed to encode theme response", "error", err)
 		http.Error(w, "Internal server error", http.StatusInternalServerError)
 	}
 }
@@ -1264,7 +1828,7 @@ func (h *Handler) SetThemeHTMX(w http.ResponseWriter, r *http.Request) {
 	}
 
 	if err := h.SettingsRepo.Set(store.SettingTheme, req.Theme); err != nil {
-		h.Logger.Error("Failed to save theme setting", "error", err)
+		h.log(r).Error("Failed to save theme setting", "error", err)
 		http.Error(w, "Internal server error", http.StatusInternalServerError)
 		return
 	}
@@ -1275,13 +1839,13 @@ func (h *Handler) SetThemeHTMX(w http.ResponseWriter, r *http.Request) {
 
 	w.Header().Set("Content-Type", "application/json")
 	if err := json.NewEncoder(w).Encode(resp); err != nil {
-		h.Logger.Error("Failed to encode response", "error", err)
+		h.log(r).Error("Failed to encode response", "error", err)
 	}
 }
 
 func (h *Handler) ResetThemeHTMX(w http.ResponseWriter, r *http.Request) {
 	if err := h.SettingsRepo.Delete(store.SettingTheme); err != nil {
-		h.Logger.Error("Failed to reset theme setting", "error", err)
+		h.log(r).Error("Failed to reset theme setting", "error", err)
 		http.Error(w, "Internal server error", http.StatusInternalServerError)
 		return
 	}
@@ -1293,14 +1857,14 @@ func (h *Handler) ResetThemeHTMX(w http.ResponseWriter, r *http.Request) {
 
 	w.Header().Set("Content-Type", "application/json")
 	if err := json.NewEncoder(w).Encode(resp); err != nil {
-		h.Logger.Error("Failed to encode response", "error", err)
+		h.log(r).Error("Failed to encode response", "error", err)
 	}
 }
 
 func (h *Handler) GetForceDownloadHTMX(w http.ResponseWriter, r *http.Request) {
 	force, err := h.SettingsRepo.Get(store.SettingForceDownload)
 	if err != nil {
-		h.Logger.Error("Failed to get force download setting", "error", err)
+		h.log(r).Error("Failed to get force download setting", "error", err)
 		http.Error(w, "Internal server error", http.StatusInternalServerError)
 		return
 	}
@@ -1311,7 +1875,7 @@ func (h *Handler) GetForceDownloadHTMX(w http.ResponseWriter, r *http.Request) {
 
 	w.Header().Set("Content-Type", "application/json")
 	if err := json.NewEncoder(w).Encode(resp); err != nil {
-		h.Logger.Error("Failed to encode response", "error", err)
+		h.log(r).Error("Failed to encode response", "error", err)
 	}
 }
 
@@ -1321,7 +1885,7 @@ func (h *Handler) SetForceDownloadHTMX(w http.ResponseWriter, r *http.Request) {
 	}
 
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		h.Logger.Error("Failed to decode request", "error", err)
+		h.log(r).Error("Failed to decode request", "error", err)
 		http.Error(w, "Bad request", http.StatusBadRequest)
 		return
 	}
@@ -1332,7 +1896,7 @@ func (h *Handler) SetForceDownloadHTMX(w http.ResponseWriter, r *http.Request) {
 	}
 
 	if err := h.SettingsRepo.Set(store.SettingForceDownload, value); err != nil {
-		h.Logger.Error("Failed to set force download setting", "error", err)
+		h.log(r).Error("Failed to set force download setting", "error", err)
 		http.Error(w, "Internal server error", http.StatusInternalServerError)
 		return
 	}
@@ -1344,14 +1908,14 @@ func (h *Handler) SetForceDownloadHTMX(w http.ResponseWriter, r *http.Request) {
 
 	w.Header().Set("Content-Type", "application/json")
 	if err := json.NewEncoder(w).Encode(resp); err != nil {
-		h.Logger.Error("Failed to encode response", "error", err)
+		h.log(r).Error("Failed to encode response", "error", err)
 	}
 }
 
 func (h *Handler) GetQualityHTMX(w http.ResponseWriter, r *http.Request) {
 	quality, err := h.SettingsRepo.Get(store.SettingQuality)
 	if err != nil {
-		h.Logger.Error("Failed to get quality", "error", err)
+		h.log(r).Error("Failed to get quality", "error", err)
 		http.Error(w, "Internal server error", http.StatusInternalServerError)
 		return
 	}
@@ -1367,7 +1931,7 @@ func (h *Handler) GetQualityHTMX(w http.ResponseWriter, r *http.Request) {
 
 	w.Header().Set("Content-Type", "application/json")
 	if err := json.NewEncoder(w).Encode(response); err != nil {
-		h.Logger.Error("Failed to encode quality response", "error", err)
+		h.log(r).Error("Failed to encode quality response", "error", err)
 		http.Error(w, "Internal server error", http.StatusInternalServerError)
 	}
 }
@@ -1387,8 +1951,14 @@ func (h *Handler) SetQualityHTMX(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if err := h.SettingsRepo.Set(store.SettingQuality, req.Quality); err != nil {
-		h.Logger.Error("Failed to save quality setting", "error", err)
+	qualities, err := config.ParseQualities(req.Quality)
+	if err != nil {
+		http.Error(w, "Quality "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if err := h.SettingsRepo.Set(store.SettingQuality, strings.Join(qualities, ",")); err != nil {
+		h.log(r).Error("Failed to save quality setting", "error", err)
 		http.Error(w, "Internal server error", http.StatusInternalServerError)
 		return
 	}
@@ -1399,13 +1969,13 @@ func (h *Handler) SetQualityHTMX(w http.ResponseWriter, r *http.Request) {
 
 	w.Header().Set("Content-Type", "application/json")
 	if err := json.NewEncoder(w).Encode(resp); err != nil {
-		h.Logger.Error("Failed to encode response", "error", err)
+		h.log(r).Error("Failed to encode response", "error", err)
 	}
 }
 
 func (h *Handler) ResetQualityHTMX(w http.ResponseWriter, r *http.Request) {
 	if err := h.SettingsRepo.Delete(store.SettingQuality); err != nil {
-		h.Logger.Error("Failed to reset quality setting", "error", err)
+		h.log(r).Error("Failed to reset quality setting", "error", err)
 		http.Error(w, "Internal server error", http.StatusInternalServerError)
 		return
 	}
@@ -1417,7 +1987,7 @@ func (h *Handler) ResetQualityHTMX(w http.ResponseWriter, r *http.Request) {
 
 	w.Header().Set("Content-Type", "application/json")
 	if err := json.NewEncoder(w).Encode(resp); err != nil {
-		h.Logger.Error("Failed to encode response", "error", err)
+		h.log(r).Error("Failed to encode response", "error", err)
 	}
 }
 
@@ -1425,7 +1995,7 @@ func (h *Handler) GetMoodsHTMX(w http.ResponseWriter, r *http.Request) {
 	moods := app.GetMoods(h.SettingsRepo)
 	w.Header().Set("Content-Type", "application/json")
 	if err := json.NewEncoder(w).Encode(map[string][]string{"moods": moods}); err != nil {
-		h.Logger.Error("Failed to encode moods response", "error", err)
+		h.log(r).Error("Failed to encode moods response", "error", err)
 	}
 }
 
@@ -1437,6 +2007,6 @@ func (h *Handler) GetLanguagesHTMX(w http.ResponseWriter, r *http.Request) {
 	}
 	w.Header().Set("Content-Type", "application/json")
 	if err := json.NewEncoder(w).Encode(map[string][]string{"languages": languages}); err != nil {
-		h.Logger.Error("Failed to encode languages response", "error", err)
+		h.log(r).Error("Failed to encode languages response", "error", err)
 	}
 }
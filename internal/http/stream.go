@@ -27,13 +27,13 @@ func (h *Handler) StreamTrack(w http.ResponseWriter, r *http.Request) {
 	provider := h.ProviderManager.GetStreamingProvider()
 	stream, mimeType, err := provider.GetStream(r.Context(), trackID, isrc, quality)
 	if err != nil {
-		h.Logger.Error("failed to get stream", "error", err, "trackID", trackID)
+		h.log(r).Error("failed to get stream", "error", err, "trackID", trackID)
 		http.Error(w, "failed to get stream", http.StatusInternalServerError)
 		return
 	}
 	defer func() {
 		if closeErr := stream.Close(); closeErr != nil {
-			h.Logger.Error("stream close error", "error", closeErr)
+			h.log(r).Error("stream close error", "error", closeErr)
 		}
 	}()
 
@@ -43,6 +43,6 @@ func (h *Handler) StreamTrack(w http.ResponseWriter, r *http.Request) {
 
 	_, err = io.Copy(w, stream)
 	if err != nil {
-		h.Logger.Error("stream copy error", "error", err)
+		h.log(r).Error("stream copy error", "error", err)
 	}
 }
@@ -0,0 +1,362 @@
+package httpapp
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+
+	"github.com/cesargomez89/navidrums/internal/app"
+	"github.com/cesargomez89/navidrums/internal/config"
+	"github.com/cesargomez89/navidrums/internal/domain"
+	"github.com/cesargomez89/navidrums/internal/logger"
+	"github.com/cesargomez89/navidrums/internal/store"
+)
+
+func TestHandler_TrackTags(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	cfg := &config.Config{Theme: "golden"}
+	h := &Handler{
+		DownloadsService: app.NewDownloadsService(db, cfg, logger.Default()),
+		SettingsRepo:     store.NewSettingsRepo(db),
+		Config:           cfg,
+		Logger:           logger.Default(),
+	}
+	track := &domain.Track{
+		ProviderID: "tags_test",
+		Title:      "Song",
+		Artist:     "Artist",
+		Album:      "Album",
+		Genre:      "rock",
+		Tags:       []string{"indie rock", "shoegaze"},
+		Status:     domain.TrackStatusCompleted,
+	}
+	if err := db.CreateTrack(track); err != nil {
+		t.Fatalf("CreateTrack failed: %v", err)
+	}
+
+	r := chi.NewRouter()
+	r.Get("/track/{id}", h.TrackPage)
+	r.Get("/htmx/track/{id}", h.TrackHTMX)
+
+	tests := []struct {
+		name string
+		path string
+		want string
+	}{
+		{"track page lists the tags", "/track/%d", "shoegaze"},
+		{"edit form offers them as genres", "/htmx/track/%d", `class="tag tag-pick" onclick="document.getElementById('genre').value = this.textContent">shoegaze</button>`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			r.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, fmt.Sprintf(tt.path, track.ID), nil))
+			if rec.Code != http.StatusOK {
+				t.Fatalf("status = %d, want %d: %s", rec.Code, http.StatusOK, rec.Body.String())
+			}
+			if !strings.Contains(rec.Body.String(), tt.want) {
+				t.Errorf("body does not contain %q", tt.want)
+			}
+		})
+	}
+}
+
+func TestHandler_CopyTrackMetadata(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	cfg := &config.Config{Theme: "golden"}
+	h := &Handler{
+		DownloadsService: app.NewDownloadsService(db, cfg, logger.Default()),
+		Config:           cfg,
+		Logger:           logger.Default(),
+	}
+	source := &domain.Track{ProviderID: "source", Title: "Right", Artist: "Artist", Album: "Album", Year: 2001, Status: domain.TrackStatusCompleted}
+	target := &domain.Track{ProviderID: "target", Title: "Odd One", Artist: "Artist", Album: "Album (Deluxe)", Status: domain.TrackStatusCompleted}
+	for _, tr := range []*domain.Track{source, target} {
+		if err := db.CreateTrack(tr); err != nil {
+			t.Fatalf("CreateTrack failed: %v", err)
+		}
+	}
+
+	r := chi.NewRouter()
+	r.Post("/htmx/track/{id}/copy-from/{sourceId}", h.CopyTrackMetadataHTMX)
+
+	tests := []struct {
+		name     string
+		path     string
+		wantCode int
+		want     string
+	}{
+		{"copies from the source", fmt.Sprintf("/htmx/track/%d/copy-from/%d", target.ID, source.ID), http.StatusOK, `Album fields copied from "Right"`},
+		{"missing source", fmt.Sprintf("/htmx/track/%d/copy-from/9999", target.ID), http.StatusNotFound, "Source track not found"},
+		{"missing target", fmt.Sprintf("/htmx/track/9999/copy-from/%d", source.ID), http.StatusNotFound, "Track not found"},
+		{"invalid source ID", fmt.Sprintf("/htmx/track/%d/copy-from/abc", target.ID), http.StatusBadRequest, "Invalid source track ID"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			r.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, tt.path, nil))
+			if rec.Code != tt.wantCode {
+				t.Fatalf("status = %d, want %d: %s", rec.Code, tt.wantCode, rec.Body.String())
+			}
+			if !strings.Contains(rec.Body.String(), tt.want) {
+				t.Errorf("body does not contain %q: %s", tt.want, rec.Body.String())
+			}
+		})
+	}
+
+	got, err := db.GetTrackByID(target.ID)
+	if err != nil {
+		t.Fatalf("GetTrackByID failed: %v", err)
+	}
+	if got.Album != "Album" || got.Year != 2001 || got.Title != "Odd One" {
+		t.Errorf("target = %q %d %q, want the source's album and year with its own title", got.Album, got.Year, got.Title)
+	}
+}
+
+func TestHandler_AlbumArtistOverride(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	cfg := &config.Config{Theme: "golden"}
+	h := &Handler{
+		DownloadsService: app.NewDownloadsService(db, cfg, logger.Default()),
+		Config:           cfg,
+		Logger:           logger.Default(),
+	}
+	track := &domain.Track{ProviderID: "ost_1", Title: "Theme", Artist: "Composer", AlbumID: "ost", Status: domain.TrackStatusCompleted, FilePath: "/music/theme.flac"}
+	if err := db.CreateTrack(track); err != nil {
+		t.Fatalf("CreateTrack failed: %v", err)
+	}
+
+	r := chi.NewRouter()
+	r.Get("/htmx/album/{id}/album-artist", h.AlbumArtistOverrideHTMX)
+	r.Post("/htmx/album/{id}/album-artist", h.SaveAlbumArtistOverrideHTMX)
+
+	req := httptest.NewRequest(http.MethodPost, "/htmx/album/ost/album-artist", strings.NewReader("album_artist=+Various+Artists+"))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	rec := httptest.NewRecorder()
+	r.ServeHTTP(rec, req)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("POST status = %d, want %d: %s", rec.Code, http.StatusOK, rec.Body.String())
+	}
+	if want := "1 downloaded track(s) queued"; !strings.Contains(rec.Body.String(), want) {
+		t.Errorf("POST body does not contain %q", want)
+	}
+
+	rec = httptest.NewRecorder()
+	r.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/htmx/album/ost/album-artist", nil))
+	if want := `value="Various Artists"`; !strings.Contains(rec.Body.String(), want) {
+		t.Errorf("GET body does not contain %q: %s", want, rec.Body.String())
+	}
+}
+
+func TestHandler_TrackPath(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	root := t.TempDir()
+	downloads := filepath.Join(root, "music")
+	cfg := &config.Config{DownloadsDir: downloads, Theme: "golden"}
+	h := &Handler{
+		DownloadsService: app.NewDownloadsService(db, cfg, logger.Default()),
+		SettingsRepo:     store.NewSettingsRepo(db),
+		Config:           cfg,
+		Logger:           logger.Default(),
+	}
+	r := chi.NewRouter()
+	r.Get("/api/v1/track/{id}/path", h.TrackPathAPI)
+	r.Get("/track/{id}", h.TrackPage)
+
+	inside := filepath.Join(downloads, "Artist", "Album", "01 - Song.flac")
+	tests := []struct {
+		name     string
+		filePath string
+		wantCode int
+	}{
+		{"inside the downloads dir", inside, http.StatusOK},
+		{"outside", "/etc/passwd", http.StatusForbidden},
+		{"sibling with the same prefix", filepath.Join(root, "music-private", "song.flac"), http.StatusForbidden},
+		{"escaping with ..", filepath.Join(downloads, "..", "secret.flac"), http.StatusForbidden},
+		{"the downloads dir itself", downloads, http.StatusForbidden},
+		{"no file", "", http.StatusNotFound},
+	}
+	for i, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			track := &domain.Track{ProviderID: fmt.Sprintf("path_%d", i), Title: "Song", FilePath: tt.filePath, Status: domain.TrackStatusCompleted}
+			if err := db.CreateTrack(track); err != nil {
+				t.Fatalf("CreateTrack failed: %v", err)
+			}
+
+			// The track page shows the path only when the API would reveal it.
+			page := httptest.NewRecorder()
+			r.ServeHTTP(page, httptest.NewRequest(http.MethodGet, fmt.Sprintf("/track/%d", track.ID), nil))
+			if page.Code != http.StatusOK {
+				t.Fatalf("track page status = %d, want %d", page.Code, http.StatusOK)
+			}
+			if shown := tt.filePath != "" && strings.Contains(page.Body.String(), tt.filePath); shown != (tt.wantCode == http.StatusOK) {
+				t.Errorf("track page shows the path: %v, want %v", shown, tt.wantCode == http.StatusOK)
+			}
+
+			rec := httptest.NewRecorder()
+			r.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, fmt.Sprintf("/api/v1/track/%d/path", track.ID), nil))
+			if rec.Code != tt.wantCode {
+				t.Fatalf("status = %d, want %d: %s", rec.Code, tt.wantCode, rec.Body.String())
+			}
+			if tt.wantCode != http.StatusOK {
+				if tt.filePath != "" && strings.Contains(rec.Body.String(), tt.filePath) {
+					t.Errorf("response reveals the path: %s", rec.Body.String())
+				}
+				return
+			}
+			var got app.TrackFilePath
+			if err := json.Unmarshal(rec.Body.Bytes(), &got); err != nil {
+				t.Fatalf("failed to decode response: %v", err)
+			}
+			if got.Path != inside || got.Folder != filepath.Dir(inside) {
+				t.Errorf("got %+v, want path %s in %s", got, inside, filepath.Dir(inside))
+			}
+		})
+	}
+
+	t.Run("unknown track", func(t *testing.T) {
+		rec := httptest.NewRecorder()
+		r.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/v1/track/9999/path", nil))
+		if rec.Code != http.StatusNotFound {
+			t.Errorf("status = %d, want %d", rec.Code, http.StatusNotFound)
+		}
+	})
+}
+
+// formInput is an <input> of a rendered form, as far as a browser submitting it cares.
+type formInput struct {
+	name, value, kind string
+	checked, disabled bool
+}
+
+var (
+	inputTagRe  = regexp.MustCompile(`(?s)<input\b[^>]*>`)
+	inputAttrRe = regexp.MustCompile(`(\w+)(?:="([^"]*)")?`)
+)
+
+func parseFormInputs(body string) []*formInput {
+	var inputs []*formInput
+	for _, tag := range inputTagRe.FindAllString(body, -1) {
+		in := &formInput{}
+		for _, m := range inputAttrRe.FindAllStringSubmatch(strings.TrimPrefix(tag, "<input"), -1) {
+			switch m[1] {
+			case "name":
+				in.name = m[2]
+			case "value":
+				in.value = m[2]
+			case "type":
+				in.kind = m[2]
+			case "checked":
+				in.checked = true
+			case "disabled":
+				in.disabled = true
+			}
+		}
+		if in.name != "" {
+			inputs = append(inputs, in)
+		}
+	}
+	return inputs
+}
+
+// submitValues returns what a browser posts for inputs: disabled inputs and unticked
+// checkboxes are left out.
+func submitValues(inputs []*formInput) url.Values {
+	values := url.Values{}
+	for _, in := range inputs {
+		if in.disabled || (in.kind == "checkbox" && !in.checked) {
+			continue
+		}
+		values.Add(in.name, in.value)
+	}
+	return values
+}
+
+func TestHandler_SaveTrackLocked(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	cfg := &config.Config{Theme: "golden"}
+	h := NewHandler(app.NewJobService(db, logger.Default()), app.NewDownloadsService(db, cfg, logger.Default()), nil, store.NewSettingsRepo(db), nil, cfg)
+	track := &domain.Track{ProviderID: "lock_test", Title: "Song", Artist: "Artist", Year: 2020, TrackNumber: 1, DiscNumber: 1, TotalTracks: 1, TotalDiscs: 1, Status: domain.TrackStatusCompleted}
+	if err := db.CreateTrack(track); err != nil {
+		t.Fatalf("CreateTrack failed: %v", err)
+	}
+
+	r := chi.NewRouter()
+	r.Get("/htmx/track/{id}", h.TrackHTMX)
+	r.Post("/htmx/track/{id}/save", h.SaveTrackHTMX)
+
+	form := func() []*formInput {
+		t.Helper()
+		rec := httptest.NewRecorder()
+		r.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, fmt.Sprintf("/htmx/track/%d", track.ID), nil))
+		if rec.Code != http.StatusOK {
+			t.Fatalf("GET status = %d: %s", rec.Code, rec.Body.String())
+		}
+		return parseFormInputs(rec.Body.String())
+	}
+	save := func(inputs []*formInput) {
+		t.Helper()
+		req := httptest.NewRequest(http.MethodPost, fmt.Sprintf("/htmx/track/%d/save", track.ID), strings.NewReader(submitValues(inputs).Encode()))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		rec := httptest.NewRecorder()
+		r.ServeHTTP(rec, req)
+		if rec.Code != http.StatusOK {
+			t.Fatalf("POST status = %d: %s", rec.Code, rec.Body.String())
+		}
+	}
+	locked := func() bool {
+		t.Helper()
+		got, err := db.GetTrackByID(track.ID)
+		if err != nil {
+			t.Fatalf("GetTrackByID failed: %v", err)
+		}
+		return got.Locked
+	}
+	setLocked := func(inputs []*formInput, on bool) {
+		t.Helper()
+		for _, in := range inputs {
+			if in.name == "locked" && in.kind == "checkbox" {
+				in.checked = on
+				return
+			}
+		}
+		t.Fatal("form has no locked checkbox")
+	}
+
+	inputs := form()
+	setLocked(inputs, true)
+	save(inputs)
+	if !locked() {
+		t.Fatal("ticking the box did not lock the track")
+	}
+
+	// A save of other fields keeps the lock.
+	save(form())
+	if !locked() {
+		t.Fatal("an unrelated save unlocked the track")
+	}
+
+	inputs = form()
+	setLocked(inputs, false)
+	save(inputs)
+	if locked() {
+		t.Fatal("unticking the box did not unlock the track")
+	}
+}
@@ -0,0 +1,32 @@
+package httpapp
+
+import (
+	"encoding/json"
+	"net/http"
+)
+
+// startSyncUpgradeSummary zeroes the upgrade counts when a bulk Hi-Fi sync starts.
+func (h *Handler) startSyncUpgradeSummary() {
+	if !h.Config.UpgradeQualityOnSync {
+		return
+	}
+	if err := h.SettingsRepo.ResetSyncUpgradeSummary(); err != nil {
+		h.Logger.Warn("Failed to reset sync upgrade summary", "error", err)
+	}
+}
+
+// SyncUpgradesAPI returns how many tracks the last bulk Hi-Fi sync queued for a quality
+// upgrade and how many it left unchanged.
+func (h *Handler) SyncUpgradesAPI(w http.ResponseWriter, r *http.Request) {
+	summary, err := h.SettingsRepo.GetSyncUpgradeSummary()
+	if err != nil {
+		h.log(r).Error("Failed to load sync upgrade summary", "error", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(summary); err != nil {
+		h.log(r).Error("Failed to encode sync upgrade summary", "error", err)
+	}
+}
@@ -0,0 +1,62 @@
+package httpapp
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/cesargomez89/navidrums/internal/constants"
+	"github.com/cesargomez89/navidrums/internal/domain"
+	"github.com/cesargomez89/navidrums/internal/store"
+)
+
+// VerifyAPI enqueues an integrity check of the library, or of one album when album_id is set.
+func (h *Handler) VerifyAPI(w http.ResponseWriter, r *http.Request) {
+	albumID := r.URL.Query().Get("album_id")
+	if err := h.downloads(r).EnqueueVerifyJob(albumID); err != nil {
+		h.log(r).Error("Failed to enqueue verify job", "album_id", albumID, "error", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusAccepted)
+	_, _ = w.Write([]byte(`{"success":true}`))
+}
+
+// VerifySummaryAPI returns the OK/changed/missing counts of the last finished verify job.
+func (h *Handler) VerifySummaryAPI(w http.ResponseWriter, r *http.Request) {
+	raw, err := h.SettingsRepo.Get(store.SettingVerifySummary)
+	if err != nil {
+		h.log(r).Error("Failed to load verify summary", "error", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	summary := &domain.VerifySummary{}
+	if raw != "" {
+		if err := json.Unmarshal([]byte(raw), summary); err != nil {
+			h.log(r).Error("Failed to parse verify summary", "error", err)
+			http.Error(w, "Internal server error", http.StatusInternalServerError)
+			return
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(summary); err != nil {
+		h.log(r).Error("Failed to encode verify summary", "error", err)
+	}
+}
+
+func (h *Handler) VerifyLibraryHTMX(w http.ResponseWriter, r *http.Request) {
+	if err := h.downloads(r).EnqueueVerifyJob(""); err != nil {
+		h.log(r).Error("Failed to enqueue verify job", "error", err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	tracks, _, _ := h.DownloadsService.ListDownloads(store.TrackSort{}, 1, constants.MaxSearchResults)
+	h.RenderFragment(w, "components/downloads_list.html", map[string]interface{}{
+		"Downloads":      tracks,
+		"VerifyEnqueued": true,
+	})
+}
@@ -0,0 +1,34 @@
+package httpapp
+
+import (
+	"encoding/json"
+	"net/http"
+)
+
+// WorkerStatusAPI returns the worker's slots, the queued and running job counts, whether the
+// queue is paused and how many jobs completed in the last hour.
+func (h *Handler) WorkerStatusAPI(w http.ResponseWriter, r *http.Request) {
+	status, err := h.JobService.WorkerStatus()
+	if err != nil {
+		h.log(r).Error("Failed to load worker status", "error", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(status); err != nil {
+		h.log(r).Error("Failed to encode worker status", "error", err)
+	}
+}
+
+// QueueStatusHTMX renders the worker summary shown above the queue.
+func (h *Handler) QueueStatusHTMX(w http.ResponseWriter, r *http.Request) {
+	status, err := h.JobService.WorkerStatus()
+	if err != nil {
+		h.log(r).Error("Failed to load worker status", "error", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	h.RenderFragment(w, "components/worker_status.html", status)
+}
@@ -5,12 +5,21 @@ import (
 	"net/http"
 	"sync"
 	"time"
+
+	"github.com/cesargomez89/navidrums/internal/metrics"
 )
 
 // Client wraps an http.Client to provide rate limiting.
 type Client struct {
+	httpClient *http.Client
+	limit      *rateLimit
+	name       string
+}
+
+// rateLimit spaces requests at least minRequestInterval apart. Clients made by Sharing
+// hold the same one.
+type rateLimit struct {
 	lastRequest        time.Time
-	httpClient         *http.Client
 	minRequestInterval time.Duration
 	mu                 sync.Mutex
 }
@@ -29,26 +38,41 @@ func NewClient(httpClient *http.Client, minRequestInterval time.Duration) *Clien
 		}
 	}
 	return &Client{
-		httpClient:         httpClient,
-		minRequestInterval: minRequestInterval,
+		httpClient: httpClient,
+		limit:      &rateLimit{minRequestInterval: minRequestInterval},
 	}
 }
 
+// Sharing returns a client that sends requests through httpClient but waits on c's rate
+// limit, so requests made through either client count against the same interval.
+func (c *Client) Sharing(httpClient *http.Client) *Client {
+	return &Client{httpClient: httpClient, limit: c.limit, name: c.name}
+}
+
+// WithName labels the client for throttling metrics and returns it.
+func (c *Client) WithName(name string) *Client {
+	c.name = name
+	return c
+}
+
 // Do executes an HTTP request with rate-limiting. No retries - failures are returned immediately.
 func (c *Client) Do(ctx context.Context, req *http.Request) (*http.Response, error) {
-	c.mu.Lock()
+	c.limit.mu.Lock()
 	now := time.Now()
-	nextAllowed := c.lastRequest.Add(c.minRequestInterval)
+	nextAllowed := c.limit.lastRequest.Add(c.limit.minRequestInterval)
 	var waitTime time.Duration
 	if now.Before(nextAllowed) {
 		waitTime = nextAllowed.Sub(now)
-		c.lastRequest = nextAllowed
+		c.limit.lastRequest = nextAllowed
 	} else {
-		c.lastRequest = now
+		c.limit.lastRequest = now
 	}
-	c.mu.Unlock()
+	c.limit.mu.Unlock()
 
 	if waitTime > 0 {
+		if c.name != "" {
+			metrics.RateLimitWait.WithLabelValues(c.name).Observe(waitTime.Seconds())
+		}
 		timer := time.NewTimer(waitTime)
 		select {
 		case <-ctx.Done():
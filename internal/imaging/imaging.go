@@ -0,0 +1,93 @@
+// Package imaging decodes and re-encodes cover art, so the saved cover file and the
+// picture embedded in tags are converted the same way.
+package imaging
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"image"
+	"image/jpeg"
+	_ "image/png" // register the PNG decoder for ToJPEG
+	"net/http"
+	"time"
+
+	"github.com/cesargomez89/navidrums/internal/constants"
+	"github.com/cesargomez89/navidrums/internal/ffmpeg"
+)
+
+// convertTimeout bounds an ffmpeg conversion of a cover Go can't decode itself.
+const convertTimeout = 30 * time.Second
+
+// MimeType returns the sniffed image type of data: JPEG, PNG, WebP, AVIF, or "" for
+// anything else.
+func MimeType(data []byte) string {
+	if isAVIF(data) {
+		return constants.MimeTypeAVIF
+	}
+	switch mime := http.DetectContentType(data); mime {
+	case constants.MimeTypeJPEG, constants.MimeTypePNG, constants.MimeTypeWebP:
+		return mime
+	}
+	return ""
+}
+
+// isAVIF reports whether data starts with an ISO-BMFF ftyp box whose major brand is an
+// AVIF image or image sequence.
+func isAVIF(data []byte) bool {
+	if len(data) < 12 || string(data[4:8]) != "ftyp" {
+		return false
+	}
+	brand := string(data[8:12])
+	return brand == "avif" || brand == "avis"
+}
+
+// NeedsConversion reports whether data is an image players don't reliably show as
+// embedded cover art, WebP or AVIF, and should be converted to JPEG before embedding.
+func NeedsConversion(data []byte) bool {
+	mime := MimeType(data)
+	return mime == constants.MimeTypeWebP || mime == constants.MimeTypeAVIF
+}
+
+// ToJPEG re-encodes image data as JPEG at quality (1-100, where 0 means
+// constants.DefaultCoverJPEGQuality). PNG is decoded in-process; WebP and AVIF, which Go
+// has no decoder for, are converted with ffmpeg. JPEG data, and data that isn't an image
+// type MimeType recognises, is returned unchanged.
+func ToJPEG(data []byte, quality int) ([]byte, error) {
+	if quality == 0 {
+		quality = constants.DefaultCoverJPEGQuality
+	}
+	switch MimeType(data) {
+	case constants.MimeTypePNG:
+		img, _, err := image.Decode(bytes.NewReader(data))
+		if err != nil {
+			return nil, fmt.Errorf("failed to decode image: %w", err)
+		}
+		return EncodeJPEG(img, quality)
+	case constants.MimeTypeWebP, constants.MimeTypeAVIF:
+		ctx, cancel := context.WithTimeout(context.Background(), convertTimeout)
+		defer cancel()
+		converted, err := ffmpeg.ImageToJPEG(ctx, data, quality)
+		if err != nil {
+			return nil, fmt.Errorf("failed to convert image: %w", err)
+		}
+		if MimeType(converted) != constants.MimeTypeJPEG {
+			return nil, fmt.Errorf("failed to convert image: ffmpeg did not produce a JPEG")
+		}
+		return converted, nil
+	}
+	return data, nil
+}
+
+// EncodeJPEG encodes img as JPEG at quality (1-100, where 0 means
+// constants.DefaultCoverJPEGQuality).
+func EncodeJPEG(img image.Image, quality int) ([]byte, error) {
+	if quality == 0 {
+		quality = constants.DefaultCoverJPEGQuality
+	}
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, img, &jpeg.Options{Quality: quality}); err != nil {
+		return nil, fmt.Errorf("failed to encode JPEG: %w", err)
+	}
+	return buf.Bytes(), nil
+}
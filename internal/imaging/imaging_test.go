@@ -0,0 +1,129 @@
+package imaging
+
+import (
+	"bytes"
+	"encoding/base64"
+	"image"
+	"image/color"
+	"image/png"
+	"net/http"
+	"os/exec"
+	"testing"
+
+	"github.com/cesargomez89/navidrums/internal/constants"
+)
+
+// webpCover is a 1x1 lossless WebP image.
+var webpCover, _ = base64.StdEncoding.DecodeString("UklGRhoAAABXRUJQVlA4TA0AAAAvAAAAEAcQERGIiP4HAA==")
+
+// avifHeader is the start of an AVIF file: its ftyp box.
+var avifHeader = []byte("\x00\x00\x00\x1cftypavif\x00\x00\x00\x00avifmif1miaf")
+
+// testPNG returns a PNG with enough detail that JPEG quality changes its encoded size.
+func testPNG(t *testing.T) []byte {
+	t.Helper()
+	img := image.NewRGBA(image.Rect(0, 0, 64, 64))
+	for x := 0; x < 64; x++ {
+		for y := 0; y < 64; y++ {
+			img.Set(x, y, color.RGBA{R: uint8(x * 4), G: uint8(y * 4), B: uint8((x * y) % 256), A: 255})
+		}
+	}
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		t.Fatalf("failed to encode PNG: %v", err)
+	}
+	return buf.Bytes()
+}
+
+func TestToJPEG(t *testing.T) {
+	pngData := testPNG(t)
+
+	low, err := ToJPEG(pngData, 20)
+	if err != nil {
+		t.Fatalf("ToJPEG(20) error = %v", err)
+	}
+	high, err := ToJPEG(pngData, 95)
+	if err != nil {
+		t.Fatalf("ToJPEG(95) error = %v", err)
+	}
+	for _, data := range [][]byte{low, high} {
+		if got := http.DetectContentType(data); got != constants.MimeTypeJPEG {
+			t.Fatalf("content type = %s, want %s", got, constants.MimeTypeJPEG)
+		}
+	}
+	if len(low) >= len(high) {
+		t.Errorf("quality 20 is %d bytes, quality 95 is %d bytes, want the lower quality smaller", len(low), len(high))
+	}
+
+	defaulted, err := ToJPEG(pngData, 0)
+	if err != nil {
+		t.Fatalf("ToJPEG(0) error = %v", err)
+	}
+	atDefault, _ := ToJPEG(pngData, constants.DefaultCoverJPEGQuality)
+	if !bytes.Equal(defaulted, atDefault) {
+		t.Error("ToJPEG(0) did not use the default quality")
+	}
+
+	tests := []struct {
+		name string
+		data []byte
+	}{
+		{"jpeg unchanged", high},
+		{"unknown unchanged", []byte("fake image")},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ToJPEG(tt.data, 20)
+			if err != nil {
+				t.Fatalf("ToJPEG() error = %v", err)
+			}
+			if !bytes.Equal(got, tt.data) {
+				t.Error("ToJPEG() changed the data")
+			}
+		})
+	}
+}
+
+func TestMimeType(t *testing.T) {
+	pngData := testPNG(t)
+	jpegData, err := ToJPEG(pngData, 0)
+	if err != nil {
+		t.Fatalf("ToJPEG() error = %v", err)
+	}
+
+	tests := []struct {
+		name           string
+		data           []byte
+		want           string
+		wantConversion bool
+	}{
+		{"jpeg", jpegData, constants.MimeTypeJPEG, false},
+		{"png", pngData, constants.MimeTypePNG, false},
+		{"webp", webpCover, constants.MimeTypeWebP, true},
+		{"avif", avifHeader, constants.MimeTypeAVIF, true},
+		{"unknown", []byte("fake image"), "", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := MimeType(tt.data); got != tt.want {
+				t.Errorf("MimeType() = %q, want %q", got, tt.want)
+			}
+			if got := NeedsConversion(tt.data); got != tt.wantConversion {
+				t.Errorf("NeedsConversion() = %v, want %v", got, tt.wantConversion)
+			}
+		})
+	}
+}
+
+func TestToJPEG_WebP(t *testing.T) {
+	if _, err := exec.LookPath("ffmpeg"); err != nil {
+		t.Skip("ffmpeg not installed")
+	}
+	got, err := ToJPEG(webpCover, 0)
+	if err != nil {
+		t.Fatalf("ToJPEG() error = %v", err)
+	}
+	if _, format, err := image.Decode(bytes.NewReader(got)); err != nil || format != "jpeg" {
+		t.Errorf("ToJPEG() gave format %q, error %v, want a valid JPEG", format, err)
+	}
+}
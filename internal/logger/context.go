@@ -0,0 +1,26 @@
+package logger
+
+import "context"
+
+type requestIDKey struct{}
+
+// WithRequestID returns a copy of ctx carrying the ID of the HTTP request being served
+func WithRequestID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, requestIDKey{}, id)
+}
+
+// RequestID returns the request ID stored in ctx, or "" when there is none
+func RequestID(ctx context.Context) string {
+	id, _ := ctx.Value(requestIDKey{}).(string)
+	return id
+}
+
+// WithRequest returns a logger that adds the request ID stored in ctx to every line, or l
+// itself when ctx carries none
+func (l *Logger) WithRequest(ctx context.Context) *Logger {
+	id := RequestID(ctx)
+	if id == "" {
+		return l
+	}
+	return &Logger{Logger: l.With("request_id", id)}
+}
@@ -1,6 +1,10 @@
 package logger
 
 import (
+	"bytes"
+	"context"
+	"log/slog"
+	"strings"
 	"testing"
 )
 
@@ -106,3 +110,17 @@ func TestLogFormats(t *testing.T) {
 		}
 	}
 }
+
+func TestWithRequest(t *testing.T) {
+	var buf bytes.Buffer
+	base := &Logger{Logger: slog.New(slog.NewTextHandler(&buf, nil))}
+
+	if got := base.WithRequest(context.Background()); got != base {
+		t.Error("Expected the same logger for a context without a request ID")
+	}
+
+	base.WithRequest(WithRequestID(context.Background(), "req-1")).Info("handled")
+	if !strings.Contains(buf.String(), "request_id=req-1") {
+		t.Errorf("Expected the request ID in the log line, got %q", buf.String())
+	}
+}
@@ -0,0 +1,133 @@
+// Package metrics exposes Prometheus instrumentation for the worker and providers.
+package metrics
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const namespace = "navidrums"
+
+var (
+	// JobsFinished counts jobs that reached a terminal state, by type and status.
+	JobsFinished = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "jobs_finished_total",
+		Help:      "Jobs that reached a terminal state, by type and status.",
+	}, []string{"type", "status"})
+
+	// DownloadsCompleted counts tracks that were downloaded and tagged successfully.
+	DownloadsCompleted = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "downloads_completed_total",
+		Help:      "Tracks downloaded and tagged successfully.",
+	})
+
+	// DownloadBytes counts audio bytes written to disk by downloads.
+	DownloadBytes = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "download_bytes_total",
+		Help:      "Audio bytes written to disk by downloads.",
+	})
+
+	// ProviderRequestDuration observes provider API request latency.
+	ProviderRequestDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "provider_request_duration_seconds",
+		Help:      "Provider API request latency, by provider and outcome.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"provider", "outcome"})
+
+	// RateLimitWait observes time spent waiting on outbound client-side throttling.
+	RateLimitWait = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "rate_limit_wait_seconds",
+		Help:      "Time spent waiting on outbound request throttling, by client.",
+		Buckets:   []float64{0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30},
+	}, []string{"client"})
+
+	// WorkerActiveSlots reports how many worker slots are currently busy.
+	WorkerActiveSlots = prometheus.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "worker_active_slots",
+		Help:      "Worker slots currently processing a job.",
+	})
+
+	// WorkerSlots reports the configured worker concurrency.
+	WorkerSlots = prometheus.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "worker_slots",
+		Help:      "Configured worker concurrency.",
+	})
+)
+
+// JobCounter reports the current number of jobs per status.
+type JobCounter interface {
+	CountJobsByStatus() (map[string]int, error)
+}
+
+// Registry holds the application collectors.
+var Registry = prometheus.NewRegistry()
+
+func init() {
+	Registry.MustRegister(
+		JobsFinished,
+		DownloadsCompleted,
+		DownloadBytes,
+		ProviderRequestDuration,
+		RateLimitWait,
+		WorkerActiveSlots,
+		WorkerSlots,
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+	)
+}
+
+// RegisterJobCounter exposes a jobs-by-status gauge backed by the given source.
+// The source is queried on every scrape.
+func RegisterJobCounter(src JobCounter) error {
+	return Registry.Register(&jobsCollector{src: src})
+}
+
+// Handler returns the HTTP handler serving the metrics registry.
+func Handler() http.Handler {
+	return promhttp.HandlerFor(Registry, promhttp.HandlerOpts{})
+}
+
+// ObserveProviderRequest records the duration of a provider request started at start.
+func ObserveProviderRequest(provider string, start time.Time, err error) {
+	outcome := "success"
+	if err != nil {
+		outcome = "error"
+	}
+	ProviderRequestDuration.WithLabelValues(provider, outcome).Observe(time.Since(start).Seconds())
+}
+
+var jobsDesc = prometheus.NewDesc(
+	prometheus.BuildFQName(namespace, "", "jobs"),
+	"Current number of jobs, by status.",
+	[]string{"status"}, nil,
+)
+
+type jobsCollector struct {
+	src JobCounter
+}
+
+func (c *jobsCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- jobsDesc
+}
+
+func (c *jobsCollector) Collect(ch chan<- prometheus.Metric) {
+	counts, err := c.src.CountJobsByStatus()
+	if err != nil {
+		ch <- prometheus.NewInvalidMetric(jobsDesc, err)
+		return
+	}
+	for status, n := range counts {
+		ch <- prometheus.MustNewConstMetric(jobsDesc, prometheus.GaugeValue, float64(n), status)
+	}
+}
@@ -0,0 +1,112 @@
+package metrics
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+)
+
+type fakeJobCounter struct {
+	counts map[string]int
+	err    error
+}
+
+func (f fakeJobCounter) CountJobsByStatus() (map[string]int, error) {
+	return f.counts, f.err
+}
+
+func collect(t *testing.T, c prometheus.Collector) []*dto.Metric {
+	t.Helper()
+	ch := make(chan prometheus.Metric, 16)
+	c.Collect(ch)
+	close(ch)
+
+	var out []*dto.Metric
+	for m := range ch {
+		var pb dto.Metric
+		if err := m.Write(&pb); err != nil {
+			t.Fatalf("Write failed: %v", err)
+		}
+		out = append(out, &pb)
+	}
+	return out
+}
+
+func TestJobsCollector(t *testing.T) {
+	c := &jobsCollector{src: fakeJobCounter{counts: map[string]int{"queued": 3, "failed": 1}}}
+
+	got := make(map[string]float64)
+	for _, m := range collect(t, c) {
+		got[m.GetLabel()[0].GetValue()] = m.GetGauge().GetValue()
+	}
+	if len(got) != 2 || got["queued"] != 3 || got["failed"] != 1 {
+		t.Errorf("jobs gauges = %v, want queued=3 failed=1", got)
+	}
+}
+
+func TestJobsCollector_Error(t *testing.T) {
+	c := &jobsCollector{src: fakeJobCounter{err: errors.New("db closed")}}
+
+	ch := make(chan prometheus.Metric, 1)
+	c.Collect(ch)
+	close(ch)
+
+	m, ok := <-ch
+	if !ok {
+		t.Fatal("expected an invalid metric")
+	}
+	var pb dto.Metric
+	if err := m.Write(&pb); err == nil {
+		t.Error("expected the metric to report the source error")
+	}
+}
+
+func TestObserveProviderRequest(t *testing.T) {
+	tests := []struct {
+		name    string
+		err     error
+		outcome string
+	}{
+		{"success", nil, "success"},
+		{"error", errors.New("timeout"), "error"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			provider := "test-" + tt.name
+			ObserveProviderRequest(provider, time.Now(), tt.err)
+
+			var pb dto.Metric
+			h := ProviderRequestDuration.WithLabelValues(provider, tt.outcome).(prometheus.Metric)
+			if err := h.Write(&pb); err != nil {
+				t.Fatalf("Write failed: %v", err)
+			}
+			if n := pb.GetHistogram().GetSampleCount(); n != 1 {
+				t.Errorf("sample count = %d, want 1", n)
+			}
+		})
+	}
+}
+
+func TestHandler(t *testing.T) {
+	DownloadsCompleted.Inc()
+
+	rec := httptest.NewRecorder()
+	Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, want %d", rec.Code, http.StatusOK)
+	}
+	body := rec.Body.String()
+	for _, name := range []string{"navidrums_downloads_completed_total", "navidrums_worker_slots", "go_goroutines"} {
+		if !strings.Contains(body, name) {
+			t.Errorf("response is missing %s", name)
+		}
+	}
+}
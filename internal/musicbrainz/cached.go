@@ -58,8 +58,17 @@ func (c *CachedClient) GetRecording(ctx context.Context, recordingID, isrc, albu
 	return nil, nil
 }
 
+// recordingKey caches recordings fetched with credits apart from those without, so turning
+// credits on doesn't serve cached recordings that lack them.
+func (c *CachedClient) recordingKey(mbid string) string {
+	if c.client != nil && c.client.credits {
+		return "mb:recording:credits:" + mbid
+	}
+	return "mb:recording:" + mbid
+}
+
 func (c *CachedClient) getRecordingByMBID(ctx context.Context, mbid, albumName string) (*RecordingMetadata, error) {
-	cacheKey := "mb:recording:" + mbid
+	cacheKey := c.recordingKey(mbid)
 
 	data, err := c.cache.GetCache(cacheKey)
 	if err != nil {
@@ -99,7 +108,7 @@ func (c *CachedClient) getRecordingByISRC(ctx context.Context, isrc, albumName s
 	if meta != nil && meta.RecordingID != "" {
 		cached := cachedMetadata{Metadata: meta}
 		if data, marshalErr := json.Marshal(cached); marshalErr == nil {
-			cacheKey := "mb:recording:" + meta.RecordingID
+			cacheKey := c.recordingKey(meta.RecordingID)
 			_ = c.cache.SetCache(cacheKey, data, c.ttl)
 		}
 	}
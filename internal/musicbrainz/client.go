@@ -6,17 +6,23 @@ import (
 	"fmt"
 	"net/http"
 	"net/url"
+	"slices"
 	"sort"
 	"strings"
 	"time"
 
+	"github.com/cesargomez89/navidrums/internal/constants"
 	"github.com/cesargomez89/navidrums/internal/httpclient"
 )
 
 const (
-	DefaultUserAgent   = "navidrums/1.0 (https://github.com/cesargomez89/navidrums)"
+	DefaultUserAgent   = constants.DefaultMusicBrainzUserAgent
 	requestTimeout     = 10 * time.Second
-	minRequestInterval = 1250 * time.Millisecond
+	minRequestInterval = constants.DefaultMusicBrainzRateLimit
+
+	// creditIncludes asks a recording lookup for its artist relationships and those of the
+	// work it performs, which hold the composer.
+	creditIncludes = "artist-rels+work-rels+work-level-rels"
 )
 
 // --------------------------------------------------------------------------
@@ -24,16 +30,36 @@ const (
 // --------------------------------------------------------------------------
 
 type Client struct {
-	httpClient *httpclient.Client
-	genreMap   map[string]string
-	baseURL    string
-	userAgent  string
+	httpClient  *httpclient.Client
+	genreMap    map[string]string
+	baseURL     string
+	userAgent   string
+	releasePref ReleasePreference
+	credits     bool
+}
+
+// ReleasePreference tunes which of a recording's releases supplies album metadata.
+type ReleasePreference struct {
+	// Country is an ISO 3166 code (e.g. "US") used to break ties between releases.
+	Country string
+	// PreferOriginal picks the earliest official release instead of the first match,
+	// so remasters and reissues do not override the original year.
+	PreferOriginal bool
 }
 
-func NewClient(baseURL string) *Client {
+// NewClient creates a MusicBrainz client. An empty userAgent falls back to DefaultUserAgent
+// and a negative rateLimit to the public server's polite interval; 0 disables throttling,
+// which is only appropriate for a local mirror.
+func NewClient(baseURL, userAgent string, rateLimit time.Duration) *Client {
+	if userAgent == "" {
+		userAgent = DefaultUserAgent
+	}
+	if rateLimit < 0 {
+		rateLimit = minRequestInterval
+	}
 	return &Client{
 		baseURL:   strings.TrimSuffix(baseURL, "/"),
-		userAgent: DefaultUserAgent,
+		userAgent: userAgent,
 		httpClient: httpclient.NewClient(&http.Client{
 			Timeout: requestTimeout,
 			Transport: &http.Transport{
@@ -42,7 +68,7 @@ func NewClient(baseURL string) *Client {
 				IdleConnTimeout:     30 * time.Second,
 				TLSHandshakeTimeout: 5 * time.Second,
 			},
-		}, minRequestInterval),
+		}, rateLimit).WithName("musicbrainz"),
 		genreMap: DefaultGenreMap,
 	}
 }
@@ -57,6 +83,16 @@ func (c *Client) GetGenreMap() map[string]string {
 	return c.genreMap
 }
 
+func (c *Client) SetReleasePreference(p ReleasePreference) {
+	c.releasePref = p
+}
+
+// SetIncludeCredits makes recording lookups also fetch performer, producer, engineer and
+// mixer credits. It costs a larger response, and an extra request for ISRC lookups.
+func (c *Client) SetIncludeCredits(enabled bool) {
+	c.credits = enabled
+}
+
 // --------------------------------------------------------------------------
 // Public API
 // --------------------------------------------------------------------------
@@ -109,6 +145,33 @@ func (c *Client) GetGenresByISRC(ctx context.Context, isrc string) (GenreResult,
 	return GenreResult{MainGenre: mainGenre}, nil
 }
 
+// GetTagsByISRC fetches the tags of the recordings identified by an ISRC, summed across
+// recordings and most voted first, as GetGenresByISRC weighs them.
+func (c *Client) GetTagsByISRC(ctx context.Context, isrc string) ([]TagCount, error) {
+	if isrc == "" {
+		return nil, nil
+	}
+	u := fmt.Sprintf("%s/recording?query=isrc:%s&inc=tags&fmt=json", c.baseURL, url.QueryEscape(isrc))
+	resp, err := c.doGet(ctx, u)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	if resp.StatusCode == http.StatusBadRequest {
+		return nil, nil
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("musicbrainz returned status %d", resp.StatusCode)
+	}
+
+	var result searchResponse
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return nil, fmt.Errorf("failed to decode response: %w", err)
+	}
+	return sumTags(result.Recordings), nil
+}
+
 // GetGenresByMBID fetches genre data for a recording identified by MusicBrainz ID.
 func (c *Client) GetGenresByMBID(ctx context.Context, mbid string) (GenreResult, error) {
 	if mbid == "" {
@@ -164,7 +227,15 @@ func (c *Client) GetRecordingByISRC(ctx context.Context, isrc string, albumName
 		return nil, nil
 	}
 
-	return buildMetadata(result.Recordings[0], result.Recordings, c.genreMap, albumName, isrc), nil
+	meta := buildMetadata(result.Recordings[0], result.Recordings, c.genreMap, c.releasePref, albumName, isrc)
+	if c.credits && meta.RecordingID != "" {
+		// Searches don't return relationships, so credits take a lookup of their own.
+		// They are optional; the metadata found so far is kept if it fails.
+		if rec, err := c.getRelationships(ctx, meta.RecordingID); err == nil && rec != nil {
+			populateCredits(meta, rec.Relations)
+		}
+	}
+	return meta, nil
 }
 
 // GetRecordingByMBID fetches full metadata for a recording identified by MusicBrainz ID.
@@ -172,7 +243,11 @@ func (c *Client) GetRecordingByMBID(ctx context.Context, mbid string, albumName
 	if mbid == "" {
 		return nil, nil
 	}
-	u := fmt.Sprintf("%s/recording/%s?inc=artists+releases+release-groups+artist-credits+tags+isrcs&fmt=json", c.baseURL, url.PathEscape(mbid))
+	inc := "artists+releases+release-groups+artist-credits+tags+isrcs+media"
+	if c.credits {
+		inc += "+" + creditIncludes
+	}
+	u := fmt.Sprintf("%s/recording/%s?inc=%s&fmt=json", c.baseURL, url.PathEscape(mbid), inc)
 	resp, err := c.doGet(ctx, u)
 	if err != nil {
 		return nil, err
@@ -191,7 +266,29 @@ func (c *Client) GetRecordingByMBID(ctx context.Context, mbid string, albumName
 		return nil, fmt.Errorf("failed to decode response: %w", err)
 	}
 
-	return buildMetadata(rec, []recording{rec}, c.genreMap, albumName, ""), nil
+	meta := buildMetadata(rec, []recording{rec}, c.genreMap, c.releasePref, albumName, "")
+	populateCredits(meta, rec.Relations)
+	return meta, nil
+}
+
+// getRelationships looks up only the credit relationships of a recording.
+func (c *Client) getRelationships(ctx context.Context, mbid string) (*recording, error) {
+	u := fmt.Sprintf("%s/recording/%s?inc=%s&fmt=json", c.baseURL, url.PathEscape(mbid), creditIncludes)
+	resp, err := c.doGet(ctx, u)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("musicbrainz returned status %d", resp.StatusCode)
+	}
+
+	var rec recording
+	if err := json.NewDecoder(resp.Body).Decode(&rec); err != nil {
+		return nil, fmt.Errorf("failed to decode response: %w", err)
+	}
+	return &rec, nil
 }
 
 // --------------------------------------------------------------------------
@@ -216,7 +313,7 @@ func (c *Client) doGet(ctx context.Context, rawURL string) (*http.Response, erro
 // buildMetadata constructs a RecordingMetadata from a decoded recording and its sibling
 // recordings (used for tag aggregation). Pass the known ISRC when available (ISRC search);
 // leave empty when doing an MBID lookup (it will be read from the recording itself).
-func buildMetadata(rec recording, recordings []recording, genreMap map[string]string, albumName, isrc string) *RecordingMetadata {
+func buildMetadata(rec recording, recordings []recording, genreMap map[string]string, pref ReleasePreference, albumName, isrc string) *RecordingMetadata {
 	mainGenre := extractMainGenre(recordings, genreMap)
 	meta := &RecordingMetadata{
 		RecordingID: rec.ID,
@@ -232,16 +329,36 @@ func buildMetadata(rec recording, recordings []recording, genreMap map[string]st
 	}
 
 	populateArtists(meta, rec.ArtistCredit)
-	populateRelease(meta, selectBestRelease(rec.Releases, albumName))
+	best := selectBestRelease(rec.Releases, albumName, pref)
+	populateRelease(meta, best)
+	populateOriginalDate(meta, rec, best)
 	return meta
 }
 
+// populateOriginalDate fills the original release date on meta: the first release of the
+// chosen release's group when MusicBrainz returned it, otherwise the recording's first
+// release. For a reissue it differs from the release date, which stays the edition's.
+func populateOriginalDate(meta *RecordingMetadata, rec recording, rel *release) {
+	date := rec.FirstReleaseDate
+	if rel != nil && rel.ReleaseGroup.FirstReleaseDate != "" {
+		date = rel.ReleaseGroup.FirstReleaseDate
+	}
+	if date == "" {
+		return
+	}
+	meta.OriginalDate = date
+	if len(date) >= 4 {
+		_, _ = fmt.Sscanf(date[:4], "%d", &meta.OriginalYear)
+	}
+}
+
 // populateArtists fills artist-related fields on meta from a list of artist credits.
 func populateArtists(meta *RecordingMetadata, credits []artistCredit) {
 	if len(credits) == 0 {
 		return
 	}
 	meta.Artist = credits[0].Artist.Name
+	meta.ArtistSort = credits[0].Artist.SortName
 	meta.Artists = make([]string, len(credits))
 	meta.ArtistIDs = make([]string, len(credits))
 	for i, ac := range credits {
@@ -253,6 +370,68 @@ func populateArtists(meta *RecordingMetadata, credits []artistCredit) {
 	}
 }
 
+// Recording-artist relationship types grouped by the credit they fill.
+var (
+	producerRelations  = []string{"producer"}
+	engineerRelations  = []string{"engineer", "audio", "recording", "sound"}
+	mixerRelations     = []string{"mix"}
+	performerRelations = []string{"instrument", "vocal", "performer"}
+)
+
+// populateCredits fills the credit fields on meta from a recording's relationships, and the
+// composer from the work it performs when the artist credits named none. Performers are
+// written "Name (instrument)", as the PERFORMER tag convention expects; a name is listed
+// once per role however many relationships repeat it.
+func populateCredits(meta *RecordingMetadata, relations []relation) {
+	for _, rel := range relations {
+		if rel.Work != nil && rel.Type == "performance" {
+			for _, wr := range rel.Work.Relations {
+				if wr.Type == "composer" && wr.Artist != nil && meta.Composer == "" {
+					meta.Composer = wr.Artist.Name
+				}
+			}
+			continue
+		}
+		if rel.Artist == nil || rel.Artist.Name == "" {
+			continue
+		}
+		name := rel.Artist.Name
+		switch {
+		case slices.Contains(producerRelations, rel.Type):
+			meta.Producers = appendUnique(meta.Producers, name)
+		case slices.Contains(engineerRelations, rel.Type):
+			meta.Engineers = appendUnique(meta.Engineers, name)
+		case slices.Contains(mixerRelations, rel.Type):
+			meta.Mixers = appendUnique(meta.Mixers, name)
+		case slices.Contains(performerRelations, rel.Type):
+			meta.Performers = appendUnique(meta.Performers, name+" ("+performerRole(rel)+")")
+		}
+	}
+}
+
+// performerRole describes what a performer relationship credits: its instruments or vocal
+// parts when listed, otherwise a generic role for its type.
+func performerRole(rel relation) string {
+	if len(rel.Attributes) > 0 {
+		return strings.Join(rel.Attributes, ", ")
+	}
+	switch rel.Type {
+	case "vocal":
+		return "vocals"
+	case "instrument":
+		return "instruments"
+	default:
+		return "performer"
+	}
+}
+
+func appendUnique(values []string, v string) []string {
+	if slices.Contains(values, v) {
+		return values
+	}
+	return append(values, v)
+}
+
 // populateRelease fills release-related fields on meta. No-ops when rel is nil.
 func populateRelease(meta *RecordingMetadata, rel *release) {
 	if rel == nil {
@@ -261,6 +440,8 @@ func populateRelease(meta *RecordingMetadata, rel *release) {
 	meta.Album = rel.Title
 	meta.ReleaseDate = rel.Date
 	meta.ReleaseID = rel.ReleaseGroup.ID
+	meta.AlbumID = rel.ID
+	meta.ReleaseTrackID = releaseTrackID(rel)
 	meta.Barcode = rel.Barcode
 	meta.CatalogNumber = rel.CatalogNumber
 	meta.ReleaseType = rel.ReleaseGroup.PrimaryType
@@ -278,27 +459,91 @@ func populateRelease(meta *RecordingMetadata, rel *release) {
 			meta.AlbumArtistIDs[i] = ac.Artist.ID
 		}
 		meta.AlbumArtist = meta.AlbumArtists[0]
+		meta.AlbumArtistSort = rel.ArtistCredit[0].Artist.SortName
+	}
+}
+
+// releaseTrackID returns the ID of the recording's track on rel. Search results list it
+// under "track" and lookups under "tracks"; both only carry the tracks for this recording.
+func releaseTrackID(rel *release) string {
+	for _, m := range rel.Media {
+		for _, t := range append(m.Track, m.Tracks...) {
+			if t.ID != "" {
+				return t.ID
+			}
+		}
 	}
+	return ""
 }
 
 // --------------------------------------------------------------------------
 // Release selection
 // --------------------------------------------------------------------------
 
-func selectBestRelease(releases []release, albumName string) *release {
+// selectBestRelease picks the release whose title matches albumName, falling back to all
+// releases when none match. Among candidates the first one wins unless pref ranks another higher.
+func selectBestRelease(releases []release, albumName string, pref ReleasePreference) *release {
 	if len(releases) == 0 {
 		return nil
 	}
+
 	albumNorm := normalizeString(albumName)
+	var candidates []*release
 	for i := range releases {
 		r := &releases[i]
 		releaseNorm := normalizeString(r.Title)
 		if albumNorm != "" && releaseNorm != "" &&
 			(strings.Contains(releaseNorm, albumNorm) || strings.Contains(albumNorm, releaseNorm)) {
-			return r
+			candidates = append(candidates, r)
+		}
+	}
+	if len(candidates) == 0 {
+		for i := range releases {
+			candidates = append(candidates, &releases[i])
+		}
+	}
+
+	best := candidates[0]
+	for _, r := range candidates[1:] {
+		if pref.prefers(r, best) {
+			best = r
+		}
+	}
+	return best
+}
+
+// prefers reports whether a should be chosen over b.
+func (p ReleasePreference) prefers(a, b *release) bool {
+	if p.PreferOriginal {
+		if ao, bo := isOfficial(a), isOfficial(b); ao != bo {
+			return ao
+		}
+		if ay, by := releaseYear(a), releaseYear(b); ay != by {
+			return ay != 0 && (by == 0 || ay < by)
+		}
+	}
+
+	if p.Country != "" {
+		ac, bc := strings.EqualFold(a.Country, p.Country), strings.EqualFold(b.Country, p.Country)
+		if ac != bc {
+			return ac
 		}
 	}
-	return &releases[0]
+
+	// Same year: a full date that sorts earlier is the better guess for the original.
+	return p.PreferOriginal && len(a.Date) == len(b.Date) && a.Date < b.Date
+}
+
+func isOfficial(r *release) bool {
+	return strings.EqualFold(r.Status, "Official")
+}
+
+func releaseYear(r *release) int {
+	var year int
+	if len(r.Date) >= 4 {
+		_, _ = fmt.Sscanf(r.Date[:4], "%d", &year)
+	}
+	return year
 }
 
 // --------------------------------------------------------------------------
@@ -320,6 +565,18 @@ func normalizeString(s string) string {
 // --------------------------------------------------------------------------
 
 func extractMainGenre(recordings []recording, genreMap map[string]string) string {
+	return MapGenre(sumTags(recordings), genreMap)
+}
+
+// TagCount is a MusicBrainz tag and the number of votes it has.
+type TagCount struct {
+	Name  string `json:"name"`
+	Count int    `json:"count"`
+}
+
+// sumTags adds up the votes for each tag across recordings, case-insensitively, and
+// returns the tags with votes, most voted first and ties in name order.
+func sumTags(recordings []recording) []TagCount {
 	tagCounts := make(map[string]int)
 	for _, rec := range recordings {
 		for _, t := range rec.Tags {
@@ -332,57 +589,44 @@ func extractMainGenre(recordings []recording, genreMap map[string]string) string
 			}
 		}
 	}
-	if len(tagCounts) == 0 {
-		return ""
-	}
 
-	type tagInfo struct {
-		name  string
-		count int
-	}
-	tags := make([]tagInfo, 0, len(tagCounts))
+	tags := make([]TagCount, 0, len(tagCounts))
 	for name, count := range tagCounts {
-		tags = append(tags, tagInfo{name: name, count: count})
+		tags = append(tags, TagCount{Name: name, Count: count})
 	}
 	sort.SliceStable(tags, func(i, j int) bool {
-		if tags[i].count == tags[j].count {
-			return tags[i].name < tags[j].name
+		if tags[i].Count == tags[j].Count {
+			return tags[i].Name < tags[j].Name
 		}
-		return tags[i].count > tags[j].count
+		return tags[i].Count > tags[j].Count
 	})
+	return tags
+}
 
-	highestTag := tags[0].name
-	var maxGenre string
+// MapGenre picks the genre for tags ordered as sumTags orders them: the first tag the
+// genre map knows, mapped, or the first tag itself when the map knows none.
+func MapGenre(tags []TagCount, genreMap map[string]string) string {
+	if len(tags) == 0 {
+		return ""
+	}
 	for _, t := range tags {
-		if mapped, ok := genreMap[strings.ToLower(t.name)]; ok {
-			maxGenre = mapped
-			break
+		if mapped, ok := genreMap[strings.ToLower(t.Name)]; ok {
+			return mapped
 		}
 	}
-	if maxGenre == "" {
-		maxGenre = highestTag
-	}
-
-	return maxGenre
+	return tags[0].Name
 }
 
+// extractTags returns every tag with votes across recordings, most voted first, so the
+// tags kept on a track show which of them drove its genre.
 func extractTags(recordings []recording) []string {
-	tagsSet := make(map[string]struct{})
-	for _, rec := range recordings {
-		for _, t := range rec.Tags {
-			if t.Count > 0 {
-				if name := strings.TrimSpace(t.Name); name != "" {
-					tagsSet[name] = struct{}{}
-				}
-			}
-		}
-	}
-	if len(tagsSet) == 0 {
+	counts := sumTags(recordings)
+	if len(counts) == 0 {
 		return nil
 	}
-	tags := make([]string, 0, len(tagsSet))
-	for t := range tagsSet {
-		tags = append(tags, t)
+	tags := make([]string, len(counts))
+	for i, t := range counts {
+		tags[i] = t.Name
 	}
 	return tags
 }
@@ -396,13 +640,28 @@ type searchResponse struct {
 }
 
 type recording struct {
-	ID           string         `json:"id"`
-	Title        string         `json:"title"`
-	Tags         []tag          `json:"tags"`
-	Releases     []release      `json:"releases"`
-	ArtistCredit []artistCredit `json:"artist-credit"`
-	ISRCs        []string       `json:"isrcs"`
-	Length       int            `json:"length"`
+	ID               string         `json:"id"`
+	Title            string         `json:"title"`
+	FirstReleaseDate string         `json:"first-release-date"`
+	Tags             []tag          `json:"tags"`
+	Releases         []release      `json:"releases"`
+	ArtistCredit     []artistCredit `json:"artist-credit"`
+	ISRCs            []string       `json:"isrcs"`
+	Relations        []relation     `json:"relations"`
+	Length           int            `json:"length"`
+}
+
+type relation struct {
+	Type       string   `json:"type"`
+	Attributes []string `json:"attributes"`
+	Artist     *artist  `json:"artist"`
+	Work       *work    `json:"work"`
+}
+
+type work struct {
+	ID        string     `json:"id"`
+	Title     string     `json:"title"`
+	Relations []relation `json:"relations"`
 }
 
 type release struct {
@@ -427,12 +686,19 @@ type artistCredit struct {
 }
 
 type releaseGroup struct {
-	ID          string `json:"id"`
-	PrimaryType string `json:"primary-type"`
+	ID               string `json:"id"`
+	PrimaryType      string `json:"primary-type"`
+	FirstReleaseDate string `json:"first-release-date"`
 }
 
 type media struct {
-	TrackCount int `json:"trackCount"`
+	TrackCount int          `json:"trackCount"`
+	Track      []mediaTrack `json:"track"`
+	Tracks     []mediaTrack `json:"tracks"`
+}
+
+type mediaTrack struct {
+	ID string `json:"id"`
 }
 
 type artist struct {
@@ -459,25 +725,35 @@ type label struct {
 // --------------------------------------------------------------------------
 
 type RecordingMetadata struct {
-	ReleaseType    string
-	AlbumArtist    string
-	ISRC           string
-	Title          string
-	Genre          string
-	Artist         string
-	CatalogNumber  string
-	Barcode        string
-	Label          string
-	ReleaseID      string
-	Album          string
-	Composer       string
-	RecordingID    string
-	ReleaseDate    string
-	AlbumArtistIDs []string
-	AlbumArtists   []string
-	ArtistIDs      []string
-	Artists        []string
-	Tags           []string
-	Year           int
-	Duration       int
+	ReleaseType     string
+	AlbumArtist     string
+	AlbumArtistSort string
+	ArtistSort      string
+	ISRC            string
+	Title           string
+	Genre           string
+	Artist          string
+	CatalogNumber   string
+	Barcode         string
+	Label           string
+	ReleaseID       string
+	AlbumID         string
+	ReleaseTrackID  string
+	Album           string
+	Composer        string
+	RecordingID     string
+	ReleaseDate     string
+	OriginalDate    string // first release of the release group, or of the recording
+	AlbumArtistIDs  []string
+	AlbumArtists    []string
+	ArtistIDs       []string
+	Artists         []string
+	Tags            []string
+	Producers       []string
+	Engineers       []string
+	Mixers          []string
+	Performers      []string
+	Year            int
+	OriginalYear    int
+	Duration        int
 }
@@ -4,7 +4,9 @@ import (
 	"context"
 	"net/http"
 	"net/http/httptest"
+	"reflect"
 	"sort"
+	"strings"
 	"sync"
 	"testing"
 	"time"
@@ -154,6 +156,46 @@ func TestExtractMainGenre(t *testing.T) {
 	}
 }
 
+func TestExtractTags(t *testing.T) {
+	recordings := []recording{
+		{Tags: []tag{{Name: "shoegaze", Count: 2}, {Name: "Indie Rock", Count: 3}, {Name: "noise", Count: 0}}},
+		{Tags: []tag{{Name: "indie rock", Count: 1}, {Name: "dream pop", Count: 2}}},
+	}
+	want := []string{"indie rock", "dream pop", "shoegaze"}
+	if got := extractTags(recordings); !reflect.DeepEqual(got, want) {
+		t.Errorf("extractTags() = %v, want %v", got, want)
+	}
+	if got := extractTags(nil); got != nil {
+		t.Errorf("extractTags(nil) = %v, want nil", got)
+	}
+}
+
+func TestMapGenre(t *testing.T) {
+	custom := map[string]string{"shoegaze": "dream pop"}
+
+	tests := []struct {
+		name     string
+		tags     []TagCount
+		genreMap map[string]string
+		want     string
+	}{
+		{"mapped tag", []TagCount{{Name: "indie rock", Count: 1}}, DefaultGenreMap, "rock"},
+		{"mapping ignores case", []TagCount{{Name: "Indie Rock", Count: 1}}, DefaultGenreMap, "rock"},
+		{"first mapped tag wins", []TagCount{{Name: "shoegaze", Count: 5}, {Name: "indie rock", Count: 3}}, DefaultGenreMap, "rock"},
+		{"unmapped falls back to first tag", []TagCount{{Name: "chiptune-ish", Count: 2}, {Name: "8-bit", Count: 1}}, DefaultGenreMap, "chiptune-ish"},
+		{"custom map", []TagCount{{Name: "shoegaze", Count: 5}, {Name: "indie rock", Count: 3}}, custom, "dream pop"},
+		{"no tags", nil, DefaultGenreMap, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := MapGenre(tt.tags, tt.genreMap); got != tt.want {
+				t.Errorf("MapGenre() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
 func TestDefaultGenreMapContainsExpectedMappings(t *testing.T) {
 	tests := []struct {
 		input    string
@@ -203,7 +245,7 @@ func TestClient_ConcurrentRateLimiting(t *testing.T) {
 	}))
 	defer ts.Close()
 
-	client := NewClient(ts.URL)
+	client := NewClient(ts.URL, "", minRequestInterval)
 
 	// We'll spin up 10 goroutines that all try to make a request at the exact same time.
 	numRequests := 10
@@ -269,7 +311,7 @@ func TestClient_RetryAfterHeader(t *testing.T) {
 	}))
 	defer ts.Close()
 
-	client := NewClient(ts.URL)
+	client := NewClient(ts.URL, "", minRequestInterval)
 
 	start := time.Now()
 	resp, err := client.doGet(context.Background(), ts.URL)
@@ -303,3 +345,328 @@ func TestClient_RetryAfterHeader(t *testing.T) {
 		t.Errorf("Expected fast response, got %v", elapsed)
 	}
 }
+
+func TestNewClient_Options(t *testing.T) {
+	tests := []struct {
+		name      string
+		userAgent string
+		wantUA    string
+	}{
+		{"default user agent", "", DefaultUserAgent},
+		{"custom user agent", "my-mirror/2.0 (ops@example.com)", "my-mirror/2.0 (ops@example.com)"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var gotUA string
+			ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				gotUA = r.Header.Get("User-Agent")
+				w.WriteHeader(http.StatusOK)
+			}))
+			defer ts.Close()
+
+			// A zero rate limit disables throttling, so back-to-back requests must not wait.
+			client := NewClient(ts.URL, tt.userAgent, 0)
+			start := time.Now()
+			for i := 0; i < 3; i++ {
+				resp, err := client.doGet(context.Background(), ts.URL)
+				if err != nil {
+					t.Fatalf("doGet failed: %v", err)
+				}
+				_ = resp.Body.Close()
+			}
+			if elapsed := time.Since(start); elapsed > minRequestInterval {
+				t.Errorf("Expected unthrottled requests, took %v", elapsed)
+			}
+			if gotUA != tt.wantUA {
+				t.Errorf("Expected User-Agent %q, got %q", tt.wantUA, gotUA)
+			}
+		})
+	}
+}
+
+func TestSelectBestRelease(t *testing.T) {
+	original := release{ID: "orig", Title: "Hot Fuss", Status: "Official", Date: "2004-06-07", Country: "GB"}
+	reissue := release{ID: "reissue", Title: "Hot Fuss (Deluxe Remaster)", Status: "Official", Date: "2016-03-04", Country: "US"}
+	bootleg := release{ID: "bootleg", Title: "Hot Fuss", Status: "Bootleg", Date: "2003", Country: "XW"}
+	usOriginal := release{ID: "us", Title: "Hot Fuss", Status: "Official", Date: "2004-06-15", Country: "US"}
+	other := release{ID: "other", Title: "Sam's Town", Status: "Official", Date: "2006"}
+
+	tests := []struct {
+		name      string
+		releases  []release
+		albumName string
+		pref      ReleasePreference
+		wantID    string
+	}{
+		{
+			name:      "no releases",
+			releases:  nil,
+			albumName: "Hot Fuss",
+			wantID:    "",
+		},
+		{
+			name:      "first match without preference",
+			releases:  []release{reissue, original},
+			albumName: "Hot Fuss",
+			wantID:    "reissue",
+		},
+		{
+			name:      "original beats reissue",
+			releases:  []release{reissue, original},
+			albumName: "Hot Fuss",
+			pref:      ReleasePreference{PreferOriginal: true},
+			wantID:    "orig",
+		},
+		{
+			name:      "official beats earlier bootleg",
+			releases:  []release{reissue, bootleg, original},
+			albumName: "Hot Fuss",
+			pref:      ReleasePreference{PreferOriginal: true},
+			wantID:    "orig",
+		},
+		{
+			name:      "country breaks same-year tie",
+			releases:  []release{original, usOriginal},
+			albumName: "Hot Fuss",
+			pref:      ReleasePreference{PreferOriginal: true, Country: "US"},
+			wantID:    "us",
+		},
+		{
+			name:      "earliest date within same year",
+			releases:  []release{usOriginal, original},
+			albumName: "Hot Fuss",
+			pref:      ReleasePreference{PreferOriginal: true},
+			wantID:    "orig",
+		},
+		{
+			name:      "title match beats earlier unrelated release",
+			releases:  []release{other, reissue},
+			albumName: "Hot Fuss",
+			pref:      ReleasePreference{PreferOriginal: true},
+			wantID:    "reissue",
+		},
+		{
+			name:      "falls back to all releases when nothing matches",
+			releases:  []release{reissue, original},
+			albumName: "Unknown",
+			pref:      ReleasePreference{PreferOriginal: true},
+			wantID:    "orig",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := selectBestRelease(tt.releases, tt.albumName, tt.pref)
+			gotID := ""
+			if got != nil {
+				gotID = got.ID
+			}
+			if gotID != tt.wantID {
+				t.Errorf("selectBestRelease() = %q, want %q", gotID, tt.wantID)
+			}
+		})
+	}
+}
+
+func TestBuildMetadata_PrefersOriginalYear(t *testing.T) {
+	rec := recording{
+		ID:    "rec-1",
+		Title: "Mr. Brightside",
+		Releases: []release{
+			{ID: "r-2016", Title: "Hot Fuss", Status: "Official", Date: "2016-11-11"},
+			{ID: "r-2004", Title: "Hot Fuss", Status: "Official", Date: "2004-06-07"},
+		},
+	}
+
+	meta := buildMetadata(rec, []recording{rec}, DefaultGenreMap, ReleasePreference{PreferOriginal: true}, "Hot Fuss", "")
+	if meta.Year != 2004 {
+		t.Errorf("Expected year 2004, got %d", meta.Year)
+	}
+	if meta.ReleaseDate != "2004-06-07" {
+		t.Errorf("Expected release date 2004-06-07, got %s", meta.ReleaseDate)
+	}
+}
+
+func TestBuildMetadata_SortNames(t *testing.T) {
+	rec := recording{
+		ID:    "rec-1",
+		Title: "Wonderwall",
+		ArtistCredit: []artistCredit{
+			{Artist: artist{ID: "a-1", Name: "Oasis", SortName: "Oasis"}},
+		},
+		Releases: []release{
+			{
+				ID:     "r-1",
+				Title:  "(What's the Story) Morning Glory?",
+				Status: "Official",
+				ArtistCredit: []artistCredit{
+					{Artist: artist{ID: "a-2", Name: "The Beatles", SortName: "Beatles, The"}},
+				},
+			},
+		},
+	}
+
+	meta := buildMetadata(rec, []recording{rec}, DefaultGenreMap, ReleasePreference{}, "", "")
+	if meta.ArtistSort != "Oasis" {
+		t.Errorf("Expected artist sort Oasis, got %q", meta.ArtistSort)
+	}
+	if meta.AlbumArtistSort != "Beatles, The" {
+		t.Errorf("Expected album artist sort %q, got %q", "Beatles, The", meta.AlbumArtistSort)
+	}
+}
+
+func TestBuildMetadata_ReleaseIDs(t *testing.T) {
+	tests := []struct {
+		name      string
+		media     []media
+		wantTrack string
+	}{
+		{"search results", []media{{Track: []mediaTrack{{ID: "track-search"}}}}, "track-search"},
+		{"lookup results", []media{{Tracks: []mediaTrack{{ID: "track-lookup"}}}}, "track-lookup"},
+		{"no media", nil, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := recording{
+				ID:       "rec-1",
+				Releases: []release{{ID: "release-1", Title: "Album", Media: tt.media}},
+			}
+			meta := buildMetadata(rec, []recording{rec}, DefaultGenreMap, ReleasePreference{}, "Album", "")
+			if meta.AlbumID != "release-1" {
+				t.Errorf("Expected album ID release-1, got %q", meta.AlbumID)
+			}
+			if meta.ReleaseTrackID != tt.wantTrack {
+				t.Errorf("Expected release track ID %q, got %q", tt.wantTrack, meta.ReleaseTrackID)
+			}
+		})
+	}
+}
+
+// creditsRecording is a recording lookup with inc=artist-rels+work-rels+work-level-rels.
+const creditsRecording = `{
+	"id": "rec-1",
+	"title": "Song",
+	"artist-credit": [{"name": "Band", "artist": {"id": "a-1", "name": "Band", "sort-name": "Band"}}],
+	"relations": [
+		{"type": "producer", "target-type": "artist", "attributes": [], "artist": {"id": "p-1", "name": "Pat Producer"}},
+		{"type": "producer", "target-type": "artist", "attributes": ["co"], "artist": {"id": "p-1", "name": "Pat Producer"}},
+		{"type": "engineer", "target-type": "artist", "attributes": [], "artist": {"id": "e-1", "name": "Eve Engineer"}},
+		{"type": "recording", "target-type": "artist", "attributes": [], "artist": {"id": "e-2", "name": "Rob Recorder"}},
+		{"type": "mix", "target-type": "artist", "attributes": [], "artist": {"id": "m-1", "name": "Max Mixer"}},
+		{"type": "instrument", "target-type": "artist", "attributes": ["bass guitar"], "artist": {"id": "i-1", "name": "Bo Bass"}},
+		{"type": "vocal", "target-type": "artist", "attributes": ["lead vocals"], "artist": {"id": "v-1", "name": "Vi Voice"}},
+		{"type": "vocal", "target-type": "artist", "attributes": [], "artist": {"id": "v-2", "name": "Bea Backing"}},
+		{"type": "performance", "target-type": "work", "work": {"id": "w-1", "title": "Song", "relations": [
+			{"type": "composer", "target-type": "artist", "artist": {"id": "c-1", "name": "Cy Composer"}},
+			{"type": "lyricist", "target-type": "artist", "artist": {"id": "l-1", "name": "Lu Lyricist"}}
+		]}}
+	]
+}`
+
+func TestGetRecordingByMBID_Credits(t *testing.T) {
+	tests := []struct {
+		name    string
+		credits bool
+		wantInc bool
+	}{
+		{"credits enabled", true, true},
+		{"credits disabled", false, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var gotInc string
+			ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				gotInc = r.URL.Query().Get("inc")
+				if !strings.Contains(gotInc, "artist-rels") {
+					_, _ = w.Write([]byte(`{"id": "rec-1", "title": "Song"}`))
+					return
+				}
+				_, _ = w.Write([]byte(creditsRecording))
+			}))
+			defer ts.Close()
+
+			client := NewClient(ts.URL, "", 0)
+			client.SetIncludeCredits(tt.credits)
+			meta, err := client.GetRecordingByMBID(context.Background(), "rec-1", "")
+			if err != nil {
+				t.Fatalf("GetRecordingByMBID failed: %v", err)
+			}
+			if got := strings.Contains(gotInc, "artist-rels") && strings.Contains(gotInc, "work-rels"); got != tt.wantInc {
+				t.Fatalf("inc = %q, want credit relationships requested: %v", gotInc, tt.wantInc)
+			}
+			if !tt.credits {
+				if meta.Producers != nil || meta.Performers != nil || meta.Composer != "" {
+					t.Errorf("credits = %+v, want none", meta)
+				}
+				return
+			}
+
+			checks := []struct {
+				field string
+				got   []string
+				want  []string
+			}{
+				{"Producers", meta.Producers, []string{"Pat Producer"}},
+				{"Engineers", meta.Engineers, []string{"Eve Engineer", "Rob Recorder"}},
+				{"Mixers", meta.Mixers, []string{"Max Mixer"}},
+				{"Performers", meta.Performers, []string{"Bo Bass (bass guitar)", "Vi Voice (lead vocals)", "Bea Backing (vocals)"}},
+			}
+			for _, c := range checks {
+				if !reflect.DeepEqual(c.got, c.want) {
+					t.Errorf("%s = %q, want %q", c.field, c.got, c.want)
+				}
+			}
+			if meta.Composer != "Cy Composer" {
+				t.Errorf("Composer = %q, want %q", meta.Composer, "Cy Composer")
+			}
+		})
+	}
+}
+
+func TestBuildMetadata_OriginalDate(t *testing.T) {
+	reissue := release{ID: "r2", Title: "Album", Date: "2011-09-26", ReleaseGroup: releaseGroup{ID: "rg"}}
+
+	tests := []struct {
+		name             string
+		rec              recording
+		wantReleaseDate  string
+		wantOriginalDate string
+		wantYear         int
+		wantOriginalYear int
+	}{
+		{
+			name:             "release group first release",
+			rec:              recording{FirstReleaseDate: "1990", Releases: []release{{ID: "r2", Title: "Album", Date: "2011-09-26", ReleaseGroup: releaseGroup{ID: "rg", FirstReleaseDate: "1991-09-24"}}}},
+			wantReleaseDate:  "2011-09-26",
+			wantOriginalDate: "1991-09-24",
+			wantYear:         2011,
+			wantOriginalYear: 1991,
+		},
+		{
+			name:             "recording first release",
+			rec:              recording{FirstReleaseDate: "1991-09-24", Releases: []release{reissue}},
+			wantReleaseDate:  "2011-09-26",
+			wantOriginalDate: "1991-09-24",
+			wantYear:         2011,
+			wantOriginalYear: 1991,
+		},
+		{
+			name:            "unknown",
+			rec:             recording{Releases: []release{reissue}},
+			wantReleaseDate: "2011-09-26",
+			wantYear:        2011,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			meta := buildMetadata(tt.rec, []recording{tt.rec}, nil, ReleasePreference{}, "Album", "")
+			if meta.ReleaseDate != tt.wantReleaseDate || meta.Year != tt.wantYear {
+				t.Errorf("release date = %q (%d), want %q (%d)", meta.ReleaseDate, meta.Year, tt.wantReleaseDate, tt.wantYear)
+			}
+			if meta.OriginalDate != tt.wantOriginalDate || meta.OriginalYear != tt.wantOriginalYear {
+				t.Errorf("original date = %q (%d), want %q (%d)", meta.OriginalDate, meta.OriginalYear, tt.wantOriginalDate, tt.wantOriginalYear)
+			}
+		})
+	}
+}
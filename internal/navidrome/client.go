@@ -0,0 +1,125 @@
+package navidrome
+
+import (
+	"context"
+	"crypto/md5" //nolint:gosec // the Subsonic API's token auth is defined over MD5
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/cesargomez89/navidrums/internal/httpclient"
+)
+
+const (
+	requestTimeout = 10 * time.Second
+
+	// apiVersion is the Subsonic API version requests claim. search3 has been in the API
+	// since 1.8.0; Navidrome answers 1.16.1.
+	apiVersion = "1.16.1"
+	clientName = "navidrums"
+)
+
+// Client talks to a Navidrome server over its Subsonic API.
+type Client struct {
+	httpClient *httpclient.Client
+	baseURL    string
+	username   string
+	password   string
+}
+
+// NewClient creates a client for the Navidrome server at baseURL, signing in as username.
+func NewClient(baseURL, username, password string) *Client {
+	return &Client{
+		baseURL:  strings.TrimSuffix(baseURL, "/"),
+		username: username,
+		password: password,
+		httpClient: httpclient.NewClient(&http.Client{
+			Timeout: requestTimeout,
+		}, 0).WithName("navidrome"),
+	}
+}
+
+// Song is a track in the Navidrome library, as search3 returns it.
+type Song struct {
+	ID            string `json:"id"`
+	Title         string `json:"title"`
+	Artist        string `json:"artist"`
+	Album         string `json:"album"`
+	MusicBrainzID string `json:"musicBrainzId"`
+	// ISRC is an OpenSubsonic extension; servers without it leave it empty.
+	ISRC []string `json:"isrc"`
+}
+
+type subsonicResponse struct {
+	Response struct {
+		Status string `json:"status"`
+		Error  *struct {
+			Code    int    `json:"code"`
+			Message string `json:"message"`
+		} `json:"error"`
+		SearchResult3 struct {
+			Song []Song `json:"song"`
+		} `json:"searchResult3"`
+	} `json:"subsonic-response"`
+}
+
+// Search3 returns up to songCount songs matching query. Artists and albums aren't asked for.
+func (c *Client) Search3(ctx context.Context, query string, songCount int) ([]Song, error) {
+	params := c.authParams()
+	params.Set("query", query)
+	params.Set("songCount", strconv.Itoa(songCount))
+	params.Set("artistCount", "0")
+	params.Set("albumCount", "0")
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.baseURL+"/rest/search3?"+params.Encode(), nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := c.httpClient.Do(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("navidrome returned status %d", resp.StatusCode)
+	}
+
+	var result subsonicResponse
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return nil, fmt.Errorf("failed to decode navidrome response: %w", err)
+	}
+	if result.Response.Status != "ok" {
+		if e := result.Response.Error; e != nil {
+			return nil, fmt.Errorf("navidrome error %d: %s", e.Code, e.Message)
+		}
+		return nil, fmt.Errorf("navidrome returned status %q", result.Response.Status)
+	}
+	return result.Response.SearchResult3.Song, nil
+}
+
+// authParams holds the parameters every Subsonic request carries. The password is sent as
+// a salted token rather than in clear.
+func (c *Client) authParams() url.Values {
+	salt := make([]byte, 8)
+	_, _ = rand.Read(salt)
+	s := hex.EncodeToString(salt)
+	sum := md5.Sum([]byte(c.password + s)) //nolint:gosec // see import
+
+	params := url.Values{}
+	params.Set("u", c.username)
+	params.Set("t", hex.EncodeToString(sum[:]))
+	params.Set("s", s)
+	params.Set("v", apiVersion)
+	params.Set("c", clientName)
+	params.Set("f", "json")
+	return params
+}
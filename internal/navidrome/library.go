@@ -0,0 +1,133 @@
+package navidrome
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/sync/singleflight"
+
+	"github.com/cesargomez89/navidrums/internal/domain"
+)
+
+const (
+	// librarySearchTTL is how long an album's search results are reused. It covers the
+	// tracks of an album download, which are checked one after another.
+	librarySearchTTL = 10 * time.Minute
+
+	// librarySearchSongs caps the songs one search returns; enough for a box set.
+	librarySearchSongs = 500
+)
+
+// Library answers whether Navidrome already has a track. It searches once per album and
+// matches the album's tracks against the cached results, so a big album costs one request.
+type Library struct {
+	client   *Client
+	searches map[string]librarySearch
+	inflight singleflight.Group
+	now      func() time.Time
+	mu       sync.Mutex
+}
+
+type librarySearch struct {
+	fetched time.Time
+	songs   []Song
+}
+
+func NewLibrary(client *Client) *Library {
+	return &Library{
+		client:   client,
+		searches: make(map[string]librarySearch),
+		now:      time.Now,
+	}
+}
+
+// Has reports whether the library holds track, matching by ISRC, then MusicBrainz
+// recording ID, then title and artist.
+func (l *Library) Has(ctx context.Context, track *domain.Track) (bool, error) {
+	query := track.Album
+	if query == "" {
+		query = track.Title
+	}
+	songs, err := l.search(ctx, query)
+	if err != nil {
+		return false, err
+	}
+	for _, song := range songs {
+		if matchesSong(track, song) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// search returns the songs matching query, from the cache while it is fresh. Tracks of
+// the same album checked at once share one search, while other albums' searches run
+// alongside it. The shared request isn't cancelled when one caller gives up, since others
+// may be waiting for it; the client's timeout bounds it.
+func (l *Library) search(ctx context.Context, query string) ([]Song, error) {
+	key := normalize(query)
+	if songs, ok := l.cached(key); ok {
+		return songs, nil
+	}
+
+	ch := l.inflight.DoChan(key, func() (any, error) {
+		if songs, ok := l.cached(key); ok {
+			return songs, nil
+		}
+		songs, err := l.client.Search3(context.WithoutCancel(ctx), query, librarySearchSongs)
+		if err != nil {
+			return nil, err
+		}
+		l.mu.Lock()
+		defer l.mu.Unlock()
+		for k, cached := range l.searches {
+			if l.now().Sub(cached.fetched) >= librarySearchTTL {
+				delete(l.searches, k)
+			}
+		}
+		l.searches[key] = librarySearch{fetched: l.now(), songs: songs}
+		return songs, nil
+	})
+	select {
+	case res := <-ch:
+		if res.Err != nil {
+			return nil, res.Err
+		}
+		return res.Val.([]Song), nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// cached returns the fresh search results for key, if any.
+func (l *Library) cached(key string) ([]Song, bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	cached, ok := l.searches[key]
+	if !ok || l.now().Sub(cached.fetched) >= librarySearchTTL {
+		return nil, false
+	}
+	return cached.songs, true
+}
+
+func matchesSong(track *domain.Track, song Song) bool {
+	if track.ISRC != "" {
+		for _, isrc := range song.ISRC {
+			if strings.EqualFold(isrc, track.ISRC) {
+				return true
+			}
+		}
+	}
+	if track.RecordingID != nil && *track.RecordingID != "" && song.MusicBrainzID == *track.RecordingID {
+		return true
+	}
+	return track.Title != "" && normalize(song.Title) == normalize(track.Title) &&
+		normalize(song.Artist) == normalize(track.Artist)
+}
+
+// normalize folds case and whitespace so "The  Song" matches "the song".
+func normalize(s string) string {
+	return strings.Join(strings.Fields(strings.ToLower(s)), " ")
+}
@@ -0,0 +1,145 @@
+package navidrome
+
+import (
+	"context"
+	"crypto/md5" //nolint:gosec // checking the Subsonic token
+	"encoding/hex"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/cesargomez89/navidrums/internal/domain"
+)
+
+const searchResponse = `{"subsonic-response":{"status":"ok","version":"1.16.1","searchResult3":{"song":[
+	{"id":"1","title":"First Song","artist":"The Band","album":"The Album","isrc":["USABC1234567"]},
+	{"id":"2","title":"Second Song","artist":"The Band","album":"The Album","musicBrainzId":"mbid-2"},
+	{"id":"3","title":"Third  Song","artist":"the band","album":"The Album"}
+]}}}`
+
+// subsonicServer serves searchResponse to requests signed by user/secret and counts the
+// searches it answers.
+func subsonicServer(t *testing.T, searches *atomic.Int32) *httptest.Server {
+	t.Helper()
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		q := r.URL.Query()
+		sum := md5.Sum([]byte("secret" + q.Get("s"))) //nolint:gosec // see import
+		if r.URL.Path != "/rest/search3" || q.Get("u") != "user" || q.Get("t") != hex.EncodeToString(sum[:]) {
+			_, _ = w.Write([]byte(`{"subsonic-response":{"status":"failed","error":{"code":40,"message":"Wrong username or password"}}}`))
+			return
+		}
+		searches.Add(1)
+		_, _ = w.Write([]byte(searchResponse))
+	}))
+}
+
+func TestLibrary_Has(t *testing.T) {
+	var searches atomic.Int32
+	server := subsonicServer(t, &searches)
+	defer server.Close()
+
+	mbid := "mbid-2"
+	otherMBID := "mbid-9"
+	tests := []struct {
+		name  string
+		track domain.Track
+		want  bool
+	}{
+		{"by ISRC", domain.Track{Title: "Renamed", Artist: "Someone", Album: "The Album", ISRC: "usabc1234567"}, true},
+		{"by recording ID", domain.Track{Title: "Renamed", Artist: "Someone", Album: "The Album", RecordingID: &mbid}, true},
+		{"by title and artist", domain.Track{Title: "Third Song", Artist: "The Band", Album: "The Album"}, true},
+		{"different artist", domain.Track{Title: "First Song", Artist: "Cover Band", Album: "The Album"}, false},
+		{"not in library", domain.Track{Title: "Fourth Song", Artist: "The Band", Album: "The Album", ISRC: "USABC0000000", RecordingID: &otherMBID}, false},
+	}
+
+	library := NewLibrary(NewClient(server.URL+"/", "user", "secret"))
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := library.Has(context.Background(), &tt.track)
+			if err != nil {
+				t.Fatalf("Has() error = %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("Has() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	if n := searches.Load(); n != 1 {
+		t.Errorf("searches = %d, want 1 for an album's tracks", n)
+	}
+
+	now := time.Now()
+	library.now = func() time.Time { return now.Add(librarySearchTTL) }
+	if _, err := library.Has(context.Background(), &tests[0].track); err != nil {
+		t.Fatalf("Has() error = %v", err)
+	}
+	if n := searches.Load(); n != 2 {
+		t.Errorf("searches = %d, want 2 once the cached search expires", n)
+	}
+}
+
+func TestLibrary_HasConcurrent(t *testing.T) {
+	// Searches for the slow album block until it is released.
+	release := make(chan struct{})
+	var searches atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		searches.Add(1)
+		if r.URL.Query().Get("query") == "Slow Album" {
+			<-release
+		}
+		_, _ = w.Write([]byte(searchResponse))
+	}))
+	defer server.Close()
+	defer close(release)
+
+	library := NewLibrary(NewClient(server.URL+"/", "user", "secret"))
+	slow := make(chan error, 2)
+	for i := 0; i < 2; i++ {
+		go func() {
+			_, err := library.Has(context.Background(), &domain.Track{Title: "First Song", Album: "Slow Album"})
+			slow <- err
+		}()
+	}
+	for searches.Load() == 0 {
+		time.Sleep(time.Millisecond)
+	}
+
+	// Another album's search isn't held up by the slow one.
+	other := make(chan error, 1)
+	go func() {
+		_, err := library.Has(context.Background(), &domain.Track{Title: "First Song", Album: "The Album"})
+		other <- err
+	}()
+	select {
+	case err := <-other:
+		if err != nil {
+			t.Fatalf("Has() error = %v", err)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("Has() for another album waited for the slow album's search")
+	}
+
+	release <- struct{}{}
+	for i := 0; i < 2; i++ {
+		if err := <-slow; err != nil {
+			t.Fatalf("Has() error = %v", err)
+		}
+	}
+	if n := searches.Load(); n != 2 {
+		t.Errorf("searches = %d, want 2: one per album", n)
+	}
+}
+
+func TestClient_Search3Error(t *testing.T) {
+	var searches atomic.Int32
+	server := subsonicServer(t, &searches)
+	defer server.Close()
+
+	_, err := NewClient(server.URL, "user", "wrong").Search3(context.Background(), "The Album", 10)
+	if err == nil || err.Error() != "navidrome error 40: Wrong username or password" {
+		t.Errorf("Search3() error = %v, want the Subsonic error", err)
+	}
+}
@@ -0,0 +1,105 @@
+package storage
+
+import (
+	"archive/zip"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// WriteZip streams the given files into a zip archive written to w.
+// Entry names are relative to the first of baseDirs holding the file; files outside every
+// base dir or missing on disk are skipped.
+// Entries are stored without compression since audio and images are already compressed.
+func WriteZip(w io.Writer, baseDirs []string, paths []string) (int, error) {
+	bases := make([]string, 0, len(baseDirs))
+	for _, dir := range baseDirs {
+		base, err := filepath.Abs(dir)
+		if err != nil {
+			return 0, fmt.Errorf("failed to resolve base dir: %w", err)
+		}
+		bases = append(bases, base)
+	}
+
+	zw := zip.NewWriter(w)
+	written := 0
+	seen := make(map[string]bool)
+
+	for _, p := range paths {
+		name, ok := "", false
+		for _, base := range bases {
+			if name, ok = relativeEntryName(base, p); ok {
+				break
+			}
+		}
+		if !ok || seen[name] {
+			continue
+		}
+
+		added, err := addZipEntry(zw, p, name)
+		if err != nil {
+			_ = zw.Close()
+			return written, err
+		}
+		if added {
+			seen[name] = true
+			written++
+		}
+	}
+
+	if err := zw.Close(); err != nil {
+		return written, fmt.Errorf("failed to finalize zip: %w", err)
+	}
+	return written, nil
+}
+
+// relativeEntryName returns the slash-separated path of p relative to base,
+// rejecting anything that would escape base.
+func relativeEntryName(base, p string) (string, bool) {
+	abs, err := filepath.Abs(p)
+	if err != nil {
+		return "", false
+	}
+	rel, err := filepath.Rel(base, abs)
+	if err != nil || rel == "." || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return "", false
+	}
+	return filepath.ToSlash(rel), true
+}
+
+func addZipEntry(zw *zip.Writer, path, name string) (bool, error) {
+	f, err := os.Open(path) //nolint:gosec
+	if err != nil {
+		if os.IsNotExist(err) {
+			return false, nil
+		}
+		return false, fmt.Errorf("failed to open %s: %w", path, err)
+	}
+	defer func() { _ = f.Close() }()
+
+	info, err := f.Stat()
+	if err != nil {
+		return false, fmt.Errorf("failed to stat %s: %w", path, err)
+	}
+	if !info.Mode().IsRegular() {
+		return false, nil
+	}
+
+	header, err := zip.FileInfoHeader(info)
+	if err != nil {
+		return false, fmt.Errorf("failed to build zip header for %s: %w", path, err)
+	}
+	header.Name = name
+	header.Method = zip.Store
+
+	entry, err := zw.CreateHeader(header)
+	if err != nil {
+		return false, fmt.Errorf("failed to create zip entry %s: %w", name, err)
+	}
+	if _, err := io.Copy(entry, f); err != nil {
+		return false, fmt.Errorf("failed to write zip entry %s: %w", name, err)
+	}
+	return true, nil
+}
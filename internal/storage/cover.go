@@ -0,0 +1,99 @@
+package storage
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/cesargomez89/navidrums/internal/constants"
+	"github.com/cesargomez89/navidrums/internal/imaging"
+)
+
+// coverFileName is the name album art is saved under. Its extension is swapped to match
+// the actual image type, so PNG art configured as cover.jpg is saved as cover.png.
+var coverFileName = constants.CoverFileName
+
+// SetCoverFileName sets the file name album art is saved under. Empty keeps the default.
+func SetCoverFileName(name string) {
+	if name != "" {
+		coverFileName = name
+	}
+}
+
+// CoverFileName returns the configured cover file name.
+func CoverFileName() string {
+	return coverFileName
+}
+
+func coverStem() string {
+	return strings.TrimSuffix(coverFileName, filepath.Ext(coverFileName))
+}
+
+// imageExts are the extensions album art can be saved with, after the configured one.
+var imageExts = []string{constants.ExtJPG, constants.ExtJPEG, constants.ExtPNG, constants.ExtWebP, constants.ExtAVIF}
+
+// ImageExt returns the file extension matching the sniffed type of data (".jpg", ".png",
+// ".webp" or ".avif"), or "" when it is none of them.
+func ImageExt(data []byte) string {
+	switch imaging.MimeType(data) {
+	case constants.MimeTypeJPEG:
+		return constants.ExtJPG
+	case constants.MimeTypePNG:
+		return constants.ExtPNG
+	case constants.MimeTypeWebP:
+		return constants.ExtWebP
+	case constants.MimeTypeAVIF:
+		return constants.ExtAVIF
+	}
+	return ""
+}
+
+// CoverPath returns where album art data should be saved in dir: the configured cover
+// name with its extension matched to the image type.
+func CoverPath(dir string, data []byte) string {
+	return filepath.Join(dir, artFileName(coverFileName, data))
+}
+
+// FolderArtPath returns where the folder.jpg copy of album art data should be saved in dir,
+// with its extension matched to the image type like CoverPath.
+func FolderArtPath(dir string, data []byte) string {
+	return filepath.Join(dir, artFileName(constants.FolderFileName, data))
+}
+
+func artFileName(name string, data []byte) string {
+	ext := filepath.Ext(name)
+	switch dataExt := ImageExt(data); dataExt {
+	case constants.ExtPNG, constants.ExtWebP, constants.ExtAVIF:
+		ext = dataExt
+	case constants.ExtJPG:
+		if !strings.EqualFold(ext, constants.ExtJPG) && !strings.EqualFold(ext, constants.ExtJPEG) {
+			ext = constants.ExtJPG
+		}
+	}
+	return strings.TrimSuffix(name, filepath.Ext(name)) + ext
+}
+
+// FindCover returns the path of the album art saved in dir under the configured cover
+// name or one of its image-type variants, or "" when there is none.
+func FindCover(dir string) string {
+	for _, ext := range append([]string{filepath.Ext(coverFileName)}, imageExts...) {
+		path := filepath.Join(dir, coverStem()+ext)
+		if info, err := os.Stat(path); err == nil && !info.IsDir() {
+			return path
+		}
+	}
+	return ""
+}
+
+// isCoverArt reports whether name is the configured cover file or one of its variants.
+func isCoverArt(name string) bool {
+	return isImageNamed(name, coverStem())
+}
+
+func isImageNamed(name, stem string) bool {
+	if !slices.Contains(imageExts, strings.ToLower(filepath.Ext(name))) {
+		return false
+	}
+	return strings.TrimSuffix(name, filepath.Ext(name)) == stem
+}
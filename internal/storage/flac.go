@@ -0,0 +1,240 @@
+package storage
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+)
+
+// ErrCorruptFLAC reports a FLAC file whose audio stream is truncated or damaged.
+var ErrCorruptFLAC = errors.New("corrupt FLAC stream")
+
+// flacTailWindow is how far from the end of the file the last frame is searched for when
+// STREAMINFO does not record the maximum frame size.
+const flacTailWindow = 1 << 20
+
+// flacStreamInfoLen is the length of the STREAMINFO metadata block.
+const flacStreamInfoLen = 34
+
+// VerifyFLAC checks that the audio stream of a FLAC file is complete without decoding it:
+// the first frame header must parse, the last frame must pass its CRC-16, and the stream
+// must end at the total sample count STREAMINFO declares, when it declares one. The
+// STREAMINFO MD5 is not checked, as that needs a full decode.
+func VerifyFLAC(path string) error {
+	f, err := os.Open(path) //nolint:gosec // path is a downloaded track
+	if err != nil {
+		return err
+	}
+	defer func() { _ = f.Close() }()
+
+	info, err := f.Stat()
+	if err != nil {
+		return err
+	}
+	size := info.Size()
+
+	streamInfo, framesStart, err := readFLACMetadata(f)
+	if err != nil {
+		return err
+	}
+	if framesStart >= size {
+		return fmt.Errorf("%w: no audio frames", ErrCorruptFLAC)
+	}
+
+	first := make([]byte, min(16, size-framesStart))
+	if _, err := f.ReadAt(first, framesStart); err != nil {
+		return err
+	}
+	if _, ok := parseFLACFrameHeader(first); !ok {
+		return fmt.Errorf("%w: invalid first frame header", ErrCorruptFLAC)
+	}
+
+	window := int64(flacTailWindow)
+	if maxFrame := int64(streamInfo[7])<<16 | int64(streamInfo[8])<<8 | int64(streamInfo[9]); maxFrame > 0 {
+		window = maxFrame
+	}
+	window = min(window, size-framesStart)
+	tail := make([]byte, window)
+	if _, err := f.ReadAt(tail, size-window); err != nil {
+		return err
+	}
+
+	last, ok := findLastFLACFrame(tail)
+	if !ok {
+		return fmt.Errorf("%w: last frame is incomplete", ErrCorruptFLAC)
+	}
+
+	total := uint64(streamInfo[13]&0x0F)<<32 | uint64(binary.BigEndian.Uint32(streamInfo[14:]))
+	if total > 0 {
+		end := last.number + uint64(last.blockSize)
+		if !last.variable {
+			end = last.number*uint64(binary.BigEndian.Uint16(streamInfo[2:])) + uint64(last.blockSize)
+		}
+		if end != total {
+			return fmt.Errorf("%w: stream ends at sample %d of %d", ErrCorruptFLAC, end, total)
+		}
+	}
+	return nil
+}
+
+// readFLACMetadata checks the fLaC marker and walks the metadata blocks, returning the
+// STREAMINFO block and the offset of the first audio frame.
+func readFLACMetadata(r io.Reader) ([]byte, int64, error) {
+	marker := make([]byte, 4)
+	if _, err := io.ReadFull(r, marker); err != nil || string(marker) != "fLaC" {
+		return nil, 0, fmt.Errorf("%w: missing fLaC marker", ErrCorruptFLAC)
+	}
+
+	var streamInfo []byte
+	pos := int64(4)
+	header := make([]byte, 4)
+	for {
+		if _, err := io.ReadFull(r, header); err != nil {
+			return nil, 0, fmt.Errorf("%w: truncated metadata", ErrCorruptFLAC)
+		}
+		length := int64(header[1])<<16 | int64(header[2])<<8 | int64(header[3])
+		block := make([]byte, length)
+		if _, err := io.ReadFull(r, block); err != nil {
+			return nil, 0, fmt.Errorf("%w: truncated metadata", ErrCorruptFLAC)
+		}
+		if header[0]&0x7F == 0 && length >= flacStreamInfoLen {
+			streamInfo = block
+		}
+		pos += 4 + length
+		if header[0]&0x80 != 0 {
+			break
+		}
+	}
+	if streamInfo == nil {
+		return nil, 0, fmt.Errorf("%w: missing STREAMINFO", ErrCorruptFLAC)
+	}
+	return streamInfo, pos, nil
+}
+
+// flacFrame is the part of a frame header needed to place the frame in the stream.
+type flacFrame struct {
+	number    uint64 // frame number, or first sample number when variable
+	blockSize int
+	variable  bool
+}
+
+// findLastFLACFrame scans tail backwards for the frame that runs exactly to its end.
+func findLastFLACFrame(tail []byte) (flacFrame, bool) {
+	if len(tail) < 2 {
+		return flacFrame{}, false
+	}
+	want := binary.BigEndian.Uint16(tail[len(tail)-2:])
+	for i := len(tail) - 2; i >= 0; i-- {
+		if tail[i] != 0xFF || i+1 >= len(tail) || tail[i+1]&0xFE != 0xF8 {
+			continue
+		}
+		frame, ok := parseFLACFrameHeader(tail[i:])
+		if ok && flacCRC16(tail[i:len(tail)-2]) == want {
+			return frame, true
+		}
+	}
+	return flacFrame{}, false
+}
+
+// parseFLACFrameHeader decodes a frame header at the start of b and checks its CRC-8.
+func parseFLACFrameHeader(b []byte) (flacFrame, bool) {
+	if len(b) < 6 || b[0] != 0xFF || b[1]&0xFE != 0xF8 || b[3]&0x01 != 0 {
+		return flacFrame{}, false
+	}
+	frame := flacFrame{variable: b[1]&0x01 != 0}
+	blockCode := b[2] >> 4
+	rateCode := b[2] & 0x0F
+	if blockCode == 0 || rateCode == 15 || b[3]>>4 > 10 || (b[3]>>1)&0x07 == 3 {
+		return flacFrame{}, false
+	}
+
+	// The frame or sample number is coded like UTF-8, extended to seven bytes.
+	pos := 4
+	lead := b[pos]
+	n := 0
+	for n < 8 && lead&(0x80>>n) != 0 {
+		n++
+	}
+	if n == 1 || n > 7 {
+		return flacFrame{}, false
+	}
+	if n == 0 {
+		frame.number = uint64(lead)
+		pos++
+	} else {
+		frame.number = uint64(lead & (0xFF >> (n + 1)))
+		for j := 1; j < n; j++ {
+			if pos+j >= len(b) || b[pos+j]&0xC0 != 0x80 {
+				return flacFrame{}, false
+			}
+			frame.number = frame.number<<6 | uint64(b[pos+j]&0x3F)
+		}
+		pos += n
+	}
+
+	switch {
+	case blockCode == 1:
+		frame.blockSize = 192
+	case blockCode <= 5:
+		frame.blockSize = 576 << (blockCode - 2)
+	case blockCode == 6:
+		if pos >= len(b) {
+			return flacFrame{}, false
+		}
+		frame.blockSize = int(b[pos]) + 1
+		pos++
+	case blockCode == 7:
+		if pos+1 >= len(b) {
+			return flacFrame{}, false
+		}
+		frame.blockSize = int(binary.BigEndian.Uint16(b[pos:])) + 1
+		pos += 2
+	default:
+		frame.blockSize = 256 << (blockCode - 8)
+	}
+
+	switch rateCode {
+	case 12:
+		pos++
+	case 13, 14:
+		pos += 2
+	}
+	if pos >= len(b) || flacCRC8(b[:pos]) != b[pos] {
+		return flacFrame{}, false
+	}
+	return frame, true
+}
+
+// flacCRC8 is the frame header CRC: polynomial x^8 + x^2 + x + 1, initialised to zero.
+func flacCRC8(data []byte) byte {
+	var crc byte
+	for _, b := range data {
+		crc ^= b
+		for range 8 {
+			if crc&0x80 != 0 {
+				crc = crc<<1 ^ 0x07
+			} else {
+				crc <<= 1
+			}
+		}
+	}
+	return crc
+}
+
+// flacCRC16 is the frame CRC: polynomial x^16 + x^15 + x^2 + 1, initialised to zero.
+func flacCRC16(data []byte) uint16 {
+	var crc uint16
+	for _, b := range data {
+		crc ^= uint16(b) << 8
+		for range 8 {
+			if crc&0x8000 != 0 {
+				crc = crc<<1 ^ 0x8005
+			} else {
+				crc <<= 1
+			}
+		}
+	}
+	return crc
+}
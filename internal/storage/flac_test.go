@@ -0,0 +1,65 @@
+package storage
+
+import (
+	"encoding/binary"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// buildTestFLAC returns a FLAC stream with the given number of 4096-sample frames whose
+// STREAMINFO declares totalSamples. The subframe payloads are filler: only the framing is real.
+func buildTestFLAC(frames int, totalSamples uint64) []byte {
+	streamInfo := make([]byte, flacStreamInfoLen)
+	binary.BigEndian.PutUint16(streamInfo[0:], 4096)
+	binary.BigEndian.PutUint16(streamInfo[2:], 4096)
+	packed := uint64(44100)<<44 | uint64(1)<<41 | uint64(15)<<36 | totalSamples
+	binary.BigEndian.PutUint64(streamInfo[10:], packed)
+
+	data := []byte("fLaC")
+	data = append(data, 0x80, 0, 0, flacStreamInfoLen)
+	data = append(data, streamInfo...)
+	for i := range frames {
+		// Fixed blocking, 4096 samples, 44.1 kHz, stereo, 16-bit, frame number i.
+		frame := []byte{0xFF, 0xF8, 0xC9, 0x18, byte(i)}
+		frame = append(frame, flacCRC8(frame))
+		frame = append(frame, make([]byte, 100)...)
+		frame = binary.BigEndian.AppendUint16(frame, flacCRC16(frame))
+		data = append(data, frame...)
+	}
+	return data
+}
+
+func TestVerifyFLAC(t *testing.T) {
+	valid := buildTestFLAC(3, 3*4096)
+
+	tests := []struct {
+		name    string
+		data    []byte
+		wantErr bool
+	}{
+		{"complete stream", valid, false},
+		{"unknown total samples", buildTestFLAC(3, 0), false},
+		{"truncated mid-frame", valid[:len(valid)-40], true},
+		{"truncated at a frame boundary", buildTestFLAC(2, 3*4096), true},
+		{"no frames", buildTestFLAC(0, 3*4096), true},
+		{"not a FLAC file", []byte("ID3\x04\x00\x00\x00\x00\x00\x00"), true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "track.flac")
+			if err := os.WriteFile(path, tt.data, 0o600); err != nil {
+				t.Fatalf("WriteFile failed: %v", err)
+			}
+			err := VerifyFLAC(path)
+			if tt.wantErr {
+				if !errors.Is(err, ErrCorruptFLAC) {
+					t.Errorf("VerifyFLAC() = %v, want ErrCorruptFLAC", err)
+				}
+			} else if err != nil {
+				t.Errorf("VerifyFLAC() = %v, want nil", err)
+			}
+		})
+	}
+}
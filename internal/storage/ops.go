@@ -29,6 +29,33 @@ func EnsureDir(path string) error {
 	return os.MkdirAll(path, constants.DirPermissions)
 }
 
+// EnsureWritableDirs creates every directory that doesn't exist yet and checks each can be
+// written to, so a mistyped mount fails at startup rather than on the first download.
+func EnsureWritableDirs(dirs []string) error {
+	for _, dir := range dirs {
+		if err := EnsureDir(dir); err != nil {
+			return fmt.Errorf("failed to create directory %s: %w", dir, err)
+		}
+		f, err := os.CreateTemp(dir, ".navidrums-write-check-*")
+		if err != nil {
+			return fmt.Errorf("directory %s is not writable: %w", dir, err)
+		}
+		_ = f.Close()
+		_ = os.Remove(f.Name())
+	}
+	return nil
+}
+
+// TempPath returns a path named name inside a new temporary directory, for a file that
+// another component writes. cleanup removes the directory and whatever was written there.
+func TempPath(name string) (path string, cleanup func(), err error) {
+	dir, err := os.MkdirTemp("", "navidrums-")
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to create temp directory: %w", err)
+	}
+	return filepath.Join(dir, name), func() { _ = os.RemoveAll(dir) }, nil
+}
+
 func MoveFile(src, dst string) error {
 	// Rename first
 	if err := os.Rename(src, dst); err == nil {
@@ -76,6 +103,8 @@ func DeleteFolderIfEmpty(dirPath string) error {
 	return nil
 }
 
+// DeleteFolderWithCover removes dirPath when it is empty or holds nothing but folder art
+// (the cover image, folder.jpg, artist.jpg, or their PNG variants).
 func DeleteFolderWithCover(dirPath string) error {
 	entries, err := os.ReadDir(dirPath)
 	if err != nil {
@@ -85,24 +114,51 @@ func DeleteFolderWithCover(dirPath string) error {
 		return err
 	}
 
-	if len(entries) == 1 && entries[0].Name() == constants.CoverFileName {
-		coverPath := filepath.Join(dirPath, constants.CoverFileName)
-		if err := os.Remove(coverPath); err != nil && !os.IsNotExist(err) {
+	for _, e := range entries {
+		if !isFolderArt(e.Name()) {
+			return nil
+		}
+	}
+	for _, e := range entries {
+		if err := os.Remove(filepath.Join(dirPath, e.Name())); err != nil && !os.IsNotExist(err) {
 			return err
 		}
-		return os.Remove(dirPath)
 	}
+	return os.Remove(dirPath)
+}
 
-	if len(entries) == 0 {
-		return os.Remove(dirPath)
+func isFolderArt(name string) bool {
+	return isCoverArt(name) ||
+		isImageNamed(name, strings.TrimSuffix(constants.FolderFileName, constants.ExtJPG)) ||
+		isImageNamed(name, strings.TrimSuffix(constants.ArtistFileName, constants.ExtJPG))
+}
+
+// WriteImageIfMissing writes data to path unless a file is already there, so repeated
+// tracks of the same album don't rewrite its art. It reports whether it wrote the file.
+func WriteImageIfMissing(path string, data []byte) (bool, error) {
+	if len(data) == 0 || FileExists(path) {
+		return false, nil
 	}
-	return nil
+	if err := EnsureDir(filepath.Dir(path)); err != nil {
+		return false, err
+	}
+	if err := WriteFile(path, data); err != nil {
+		return false, err
+	}
+	return true, nil
 }
 
 func IsNotExist(err error) bool {
 	return os.IsNotExist(err)
 }
 
+// FileExists reports whether path exists. Errors other than not-exist count as existing
+// so transient permission or I/O problems are not mistaken for deletions.
+func FileExists(path string) bool {
+	_, err := os.Stat(path)
+	return !os.IsNotExist(err)
+}
+
 func HashFile(path string) (string, error) {
 	f, err := os.Open(path) //nolint:gosec
 	if err != nil {
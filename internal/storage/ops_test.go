@@ -1,6 +1,11 @@
 package storage
 
 import (
+	"archive/zip"
+	"bytes"
+	"image"
+	"image/jpeg"
+	"image/png"
 	"os"
 	"path/filepath"
 	"testing"
@@ -203,6 +208,25 @@ func TestDeleteFolderWithCover(t *testing.T) {
 		t.Error("Expected folder with only cover.jpg to be deleted")
 	}
 
+	// Test deleting folder holding only folder art
+	artOnlyDir := filepath.Join(tmpDir, "artonly")
+	if mkdirErr := os.MkdirAll(artOnlyDir, constants.DirPermissions); mkdirErr != nil {
+		t.Fatalf("MkdirAll failed: %v", mkdirErr)
+	}
+	for _, name := range []string{constants.CoverFileName, constants.FolderFileName, constants.ArtistFileName} {
+		if writeErr := os.WriteFile(filepath.Join(artOnlyDir, name), []byte("fake image"), constants.FilePermissions); writeErr != nil {
+			t.Fatalf("WriteFile failed: %v", writeErr)
+		}
+	}
+
+	if err = DeleteFolderWithCover(artOnlyDir); err != nil {
+		t.Fatalf("DeleteFolderWithCover failed: %v", err)
+	}
+
+	if _, statErr := os.Stat(artOnlyDir); !os.IsNotExist(statErr) {
+		t.Error("Expected folder with only folder art to be deleted")
+	}
+
 	// Test keeping folder with cover.jpg and other files
 	multiDir := filepath.Join(tmpDir, "multi")
 	if mkdirErr := os.MkdirAll(multiDir, constants.DirPermissions); mkdirErr != nil {
@@ -231,6 +255,51 @@ func TestDeleteFolderWithCover(t *testing.T) {
 	}
 }
 
+func TestDeleteFolderWithCover_PNGArt(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "pngart")
+	if err := os.MkdirAll(dir, constants.DirPermissions); err != nil {
+		t.Fatalf("MkdirAll failed: %v", err)
+	}
+	for _, name := range []string{"cover.png", "folder.png"} {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte("fake image"), constants.FilePermissions); err != nil {
+			t.Fatalf("WriteFile failed: %v", err)
+		}
+	}
+
+	if err := DeleteFolderWithCover(dir); err != nil {
+		t.Fatalf("DeleteFolderWithCover failed: %v", err)
+	}
+	if _, err := os.Stat(dir); !os.IsNotExist(err) {
+		t.Error("Expected folder with only PNG art to be deleted")
+	}
+}
+
+func TestWriteImageIfMissing(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "Artist", constants.FolderFileName)
+
+	wrote, err := WriteImageIfMissing(path, []byte("first"))
+	if err != nil || !wrote {
+		t.Fatalf("expected first write to succeed, got wrote=%v err=%v", wrote, err)
+	}
+
+	wrote, err = WriteImageIfMissing(path, []byte("second"))
+	if err != nil || wrote {
+		t.Fatalf("expected existing file to be kept, got wrote=%v err=%v", wrote, err)
+	}
+
+	data, err := os.ReadFile(path) //nolint:gosec // test temp file
+	if err != nil {
+		t.Fatalf("ReadFile failed: %v", err)
+	}
+	if string(data) != "first" {
+		t.Errorf("expected original content, got %q", data)
+	}
+
+	if wrote, _ := WriteImageIfMissing(filepath.Join(t.TempDir(), "empty.jpg"), nil); wrote {
+		t.Error("expected empty data not to be written")
+	}
+}
+
 func TestIsNotExist(t *testing.T) {
 	// Test with existing file
 	tmpFile := filepath.Join(t.TempDir(), "exists.txt")
@@ -293,3 +362,182 @@ func TestMoveFile(t *testing.T) {
 		t.Errorf("Expected content 'move test', got %s", string(content))
 	}
 }
+
+func TestWriteZip(t *testing.T) {
+	base := t.TempDir()
+	albumDir := filepath.Join(base, "Artist", "Album")
+	if err := os.MkdirAll(albumDir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	trackPath := filepath.Join(albumDir, "01 Track.flac")
+	if err := os.WriteFile(trackPath, []byte("audio"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	outside := filepath.Join(t.TempDir(), "secret.txt")
+	if err := os.WriteFile(outside, []byte("secret"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name      string
+		paths     []string
+		wantNames []string
+	}{
+		{"single file", []string{trackPath}, []string{"Artist/Album/01 Track.flac"}},
+		{"missing file skipped", []string{trackPath, filepath.Join(albumDir, "cover.jpg")}, []string{"Artist/Album/01 Track.flac"}},
+		{"outside base skipped", []string{outside, trackPath}, []string{"Artist/Album/01 Track.flac"}},
+		{"traversal skipped", []string{filepath.Join(base, "..", filepath.Base(outside))}, nil},
+		{"duplicates collapsed", []string{trackPath, trackPath}, []string{"Artist/Album/01 Track.flac"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			n, err := WriteZip(&buf, []string{base}, tt.paths)
+			if err != nil {
+				t.Fatalf("WriteZip failed: %v", err)
+			}
+			if n != len(tt.wantNames) {
+				t.Errorf("WriteZip wrote %d entries, want %d", n, len(tt.wantNames))
+			}
+
+			zr, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+			if err != nil {
+				t.Fatalf("failed to read zip: %v", err)
+			}
+			if len(zr.File) != len(tt.wantNames) {
+				t.Fatalf("zip has %d entries, want %d", len(zr.File), len(tt.wantNames))
+			}
+			for i, f := range zr.File {
+				if f.Name != tt.wantNames[i] {
+					t.Errorf("entry %d = %q, want %q", i, f.Name, tt.wantNames[i])
+				}
+			}
+		})
+	}
+}
+
+func TestCoverPath(t *testing.T) {
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, image.NewRGBA(image.Rect(0, 0, 1, 1))); err != nil {
+		t.Fatalf("failed to encode PNG: %v", err)
+	}
+	pngData := buf.Bytes()
+	var jpegBuf bytes.Buffer
+	if err := jpeg.Encode(&jpegBuf, image.NewRGBA(image.Rect(0, 0, 1, 1)), nil); err != nil {
+		t.Fatalf("failed to encode JPEG: %v", err)
+	}
+	jpegData := jpegBuf.Bytes()
+
+	tests := []struct {
+		name     string
+		fileName string
+		data     []byte
+		want     string
+	}{
+		{"jpeg keeps default", "", jpegData, "cover.jpg"},
+		{"png swaps extension", "", pngData, "cover.png"},
+		{"unknown keeps configured", "", []byte("fake image"), "cover.jpg"},
+		{"custom name png", "albumart.jpg", pngData, "albumart.png"},
+		{"custom jpeg name kept", "albumart.jpeg", jpegData, "albumart.jpeg"},
+		{"png name with jpeg data", "albumart.png", jpegData, "albumart.jpg"},
+		{"webp swaps extension", "", []byte("RIFF\x1a\x00\x00\x00WEBPVP8L"), "cover.webp"},
+		{"avif swaps extension", "", []byte("\x00\x00\x00\x1cftypavif\x00\x00\x00\x00"), "cover.avif"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			defer SetCoverFileName(constants.CoverFileName)
+			SetCoverFileName(tt.fileName)
+
+			if got := CoverPath("dir", tt.data); got != filepath.Join("dir", tt.want) {
+				t.Errorf("CoverPath() = %s, want %s", got, filepath.Join("dir", tt.want))
+			}
+		})
+	}
+}
+
+func TestFindCover(t *testing.T) {
+	tests := []struct {
+		name     string
+		fileName string
+		files    []string
+		want     string
+	}{
+		{"none", "", []string{"track.flac"}, ""},
+		{"default jpg", "", []string{constants.CoverFileName}, constants.CoverFileName},
+		{"png variant", "", []string{"cover.png"}, "cover.png"},
+		{"configured name", "albumart.jpg", []string{constants.CoverFileName, "albumart.png"}, "albumart.png"},
+		{"folder art is not the cover", "", []string{constants.FolderFileName}, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			defer SetCoverFileName(constants.CoverFileName)
+			SetCoverFileName(tt.fileName)
+
+			dir := t.TempDir()
+			for _, name := range tt.files {
+				if err := os.WriteFile(filepath.Join(dir, name), []byte("fake image"), constants.FilePermissions); err != nil {
+					t.Fatalf("WriteFile failed: %v", err)
+				}
+			}
+
+			want := ""
+			if tt.want != "" {
+				want = filepath.Join(dir, tt.want)
+			}
+			if got := FindCover(dir); got != want {
+				t.Errorf("FindCover() = %q, want %q", got, want)
+			}
+		})
+	}
+}
+
+func TestEnsureWritableDirs(t *testing.T) {
+	base := t.TempDir()
+	nested := filepath.Join(base, "hires", "nested")
+
+	if err := EnsureWritableDirs([]string{base, nested}); err != nil {
+		t.Fatalf("EnsureWritableDirs failed: %v", err)
+	}
+	if info, err := os.Stat(nested); err != nil || !info.IsDir() {
+		t.Errorf("expected %s to be created, stat error: %v", nested, err)
+	}
+	entries, err := os.ReadDir(nested)
+	if err != nil {
+		t.Fatalf("ReadDir failed: %v", err)
+	}
+	if len(entries) != 0 {
+		t.Errorf("write check left %d files behind", len(entries))
+	}
+
+	file := filepath.Join(base, "file")
+	if err := os.WriteFile(file, nil, 0o600); err != nil {
+		t.Fatalf("WriteFile failed: %v", err)
+	}
+	if err := EnsureWritableDirs([]string{filepath.Join(file, "lossy")}); err == nil {
+		t.Error("EnsureWritableDirs() = nil, want an error for a path under a file")
+	}
+}
+
+func TestTempPath(t *testing.T) {
+	path, cleanup, err := TempPath("snapshot.db")
+	if err != nil {
+		t.Fatalf("TempPath failed: %v", err)
+	}
+	if filepath.Base(path) != "snapshot.db" {
+		t.Errorf("TempPath() = %s, want a file named snapshot.db", path)
+	}
+	if FileExists(path) {
+		t.Errorf("TempPath() created %s, want only its directory", path)
+	}
+	if err := os.WriteFile(path, []byte("data"), 0o600); err != nil {
+		t.Fatalf("WriteFile failed: %v", err)
+	}
+
+	cleanup()
+	if _, err := os.Stat(filepath.Dir(path)); !os.IsNotExist(err) {
+		t.Errorf("cleanup left %s behind, stat error: %v", filepath.Dir(path), err)
+	}
+}
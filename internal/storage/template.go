@@ -7,16 +7,50 @@ import (
 	"strconv"
 	"strings"
 	"text/template"
+
+	"github.com/cesargomez89/navidrums/internal/constants"
 )
 
 // PathTemplateData holds the data for path template execution
 type PathTemplateData struct {
 	AlbumArtist  string
+	Artist       string
 	Album        string
 	Disc         string
 	Track        string
 	Title        string
+	Genre        string
+	Label        string
+	Quality      string
 	OriginalYear int
+	Year         int
+	TotalDiscs   int
+	MultiDisc    bool
+}
+
+// PathDetails are the optional template values BuildPathTemplateData leaves empty.
+type PathDetails struct {
+	Artist     string
+	Genre      string
+	Label      string
+	Quality    string
+	TotalDiscs int
+}
+
+// WithDetails sets the optional template values and returns d. Genre is the first of a
+// multi-valued genre, and MultiDisc is set when the release has more than one disc or the
+// track is on a disc past the first.
+func (d *PathTemplateData) WithDetails(details PathDetails) *PathTemplateData {
+	genre, _, _ := strings.Cut(details.Genre, ";")
+	genre, _, _ = strings.Cut(genre, ",")
+
+	d.Artist = Sanitize(details.Artist)
+	d.Genre = Sanitize(strings.TrimSpace(genre))
+	d.Label = Sanitize(details.Label)
+	d.Quality = Sanitize(details.Quality)
+	d.TotalDiscs = details.TotalDiscs
+	d.MultiDisc = details.TotalDiscs > 1 || SafeAtoi(d.Disc) > 1
+	return d
 }
 
 // BuildPath executes the template and returns the full path (without extension)
@@ -48,6 +82,7 @@ func BuildPathTemplateData(albumArtist string, year int, album string, discNum,
 	return &PathTemplateData{
 		AlbumArtist:  sanitizedAlbumArtist,
 		OriginalYear: year,
+		Year:         year,
 		Album:        sanitizedAlbum,
 		Disc:         discStr,
 		Track:        trackStr,
@@ -76,6 +111,38 @@ func BuildFullPath(downloadsDir, templateStr string, data *PathTemplateData, ext
 	return fullPath, nil
 }
 
+// ArtistDir returns the top-level folder the subdir template puts an artist's albums in,
+// or "" when the template has no folder of its own for the artist (for example when the
+// first path segment doesn't depend on the album artist).
+func ArtistDir(downloadsDir, templateStr, artist string) (string, error) {
+	// Placeholders keep the other segments non-empty so the folder structure survives.
+	withArtist, err := BuildPath(templateStr, BuildPathTemplateData(artist, 0, "_", 1, 1, "_"))
+	if err != nil {
+		return "", err
+	}
+	withoutArtist, err := BuildPath(templateStr, BuildPathTemplateData("", 0, "_", 1, 1, "_"))
+	if err != nil {
+		return "", err
+	}
+
+	first := func(p string) (string, bool) {
+		p = filepath.ToSlash(filepath.Clean(p))
+		seg, _, found := strings.Cut(p, "/")
+		return seg, found
+	}
+	seg, isDir := first(withArtist)
+	base, _ := first(withoutArtist)
+	if !isDir || seg == "" || seg == base {
+		return "", nil
+	}
+	return filepath.Join(downloadsDir, seg), nil
+}
+
+// LyricsSidecarPath returns the .lrc file that sits beside the audio file at audioPath.
+func LyricsSidecarPath(audioPath string) string {
+	return strings.TrimSuffix(audioPath, filepath.Ext(audioPath)) + constants.ExtLRC
+}
+
 // GetDirectoryAndFilename splits a full path into directory and filename (without ext)
 func GetDirectoryAndFilename(fullPath string) (dir, filename string) {
 	dir = filepath.Dir(fullPath)
@@ -3,6 +3,8 @@ package storage
 import (
 	"path/filepath"
 	"testing"
+
+	"github.com/cesargomez89/navidrums/internal/constants"
 )
 
 func TestBuildPath(t *testing.T) {
@@ -143,6 +145,46 @@ func TestBuildPathTemplateData_Sanitization(t *testing.T) {
 	}
 }
 
+func TestBuildPath_Details(t *testing.T) {
+	const discTemplate = "{{.AlbumArtist}}/{{.Album}}/{{if .MultiDisc}}CD{{.Disc}}/{{end}}{{.Track}} {{.Title}}"
+	const genreTemplate = "{{or .Genre \"Unknown\"}}/{{.AlbumArtist}}/{{.Year}} - {{.Album}} [{{.Label}}, {{.Quality}}]/{{.Track}} {{.Artist}} - {{.Title}}"
+
+	tests := []struct {
+		name     string
+		template string
+		disc     int
+		details  PathDetails
+		want     string
+	}{
+		{"single disc", discTemplate, 1, PathDetails{TotalDiscs: 1}, "Artist/Album/03 Song"},
+		{"multi-disc, first disc", discTemplate, 1, PathDetails{TotalDiscs: 2}, "Artist/Album/CD01/03 Song"},
+		{"multi-disc, later disc", discTemplate, 2, PathDetails{TotalDiscs: 2}, "Artist/Album/CD02/03 Song"},
+		{"disc count unknown", discTemplate, 2, PathDetails{}, "Artist/Album/CD02/03 Song"},
+		{
+			"genre folder",
+			genreTemplate,
+			1,
+			PathDetails{Artist: "Guest", Genre: "rock; indie", Label: "Harvest", Quality: "LOSSLESS"},
+			"rock/Artist/1973 - Album [Harvest, LOSSLESS]/03 Guest - Song",
+		},
+		{"comma-separated genres", "{{.Genre}}/{{.Title}}", 1, PathDetails{Genre: "hip hop, rap"}, "hip hop/Song"},
+		{"genre sanitized", "{{.Genre}}/{{.Title}}", 1, PathDetails{Genre: "AC/DC-core"}, "ACDC-core/Song"},
+		{"no genre", genreTemplate, 1, PathDetails{}, "Unknown/Artist/1973 - Album [, ]/03  - Song"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data := BuildPathTemplateData("Artist", 1973, "Album", tt.disc, 3, "Song").WithDetails(tt.details)
+			got, err := BuildPath(tt.template, data)
+			if err != nil {
+				t.Fatalf("BuildPath() error = %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("BuildPath() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
 func TestBuildFullPath(t *testing.T) {
 	data := &PathTemplateData{
 		AlbumArtist:  "Artist",
@@ -316,3 +358,29 @@ func containsAt(s, substr string, start int) bool {
 	}
 	return containsAt(s, substr, start+1)
 }
+
+func TestArtistDir(t *testing.T) {
+	tests := []struct {
+		name     string
+		template string
+		artist   string
+		want     string
+	}{
+		{"default template", constants.DefaultSubdirTemplate, "Artist", filepath.Join("/music", "Artist")},
+		{"sanitized artist", "{{.AlbumArtist}}/{{.Album}}/{{.Title}}", "AC/DC", filepath.Join("/music", "ACDC")},
+		{"flat template", "{{.AlbumArtist}} - {{.Title}}", "Artist", ""},
+		{"artist not first", "Music/{{.AlbumArtist}}/{{.Title}}", "Artist", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ArtistDir("/music", tt.template, tt.artist)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("ArtistDir() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
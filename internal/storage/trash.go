@@ -0,0 +1,98 @@
+package storage
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/cesargomez89/navidrums/internal/constants"
+)
+
+// TrashPath returns where path is kept while trashed: mirrored under root's trash folder,
+// or flattened to its base name when path lives outside root.
+func TrashPath(root, path string) string {
+	trashRoot := filepath.Join(root, constants.TrashDir)
+	rel, err := filepath.Rel(root, path)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return filepath.Join(trashRoot, filepath.Base(path))
+	}
+	return filepath.Join(trashRoot, rel)
+}
+
+// MoveToTrash moves path into root's trash folder. When the source folder is left holding
+// only its cover image, the cover is moved along so a later restore brings it back.
+func MoveToTrash(root, path string) (string, error) {
+	if _, err := os.Stat(path); err != nil {
+		return "", err
+	}
+	dst := TrashPath(root, path)
+	if err := EnsureDir(filepath.Dir(dst)); err != nil {
+		return "", err
+	}
+	if err := MoveFile(path, dst); err != nil {
+		return "", err
+	}
+
+	srcDir := filepath.Dir(path)
+	entries, err := os.ReadDir(srcDir)
+	if err == nil && len(entries) == 1 && isCoverArt(entries[0].Name()) {
+		coverSrc := filepath.Join(srcDir, entries[0].Name())
+		coverDst := filepath.Join(filepath.Dir(dst), entries[0].Name())
+		if _, statErr := os.Stat(coverDst); os.IsNotExist(statErr) {
+			_ = MoveFile(coverSrc, coverDst)
+		} else {
+			_ = os.Remove(coverSrc)
+		}
+	}
+
+	return dst, nil
+}
+
+// RestoreFromTrash moves a trashed file back to path, along with its cover image
+// when the destination folder has none.
+func RestoreFromTrash(root, path string) error {
+	src := TrashPath(root, path)
+	if err := EnsureDir(filepath.Dir(path)); err != nil {
+		return err
+	}
+	if err := MoveFile(src, path); err != nil {
+		return err
+	}
+
+	trashDir := filepath.Dir(src)
+	if coverSrc := FindCover(trashDir); coverSrc != "" {
+		if FindCover(filepath.Dir(path)) == "" {
+			coverDst := filepath.Join(filepath.Dir(path), filepath.Base(coverSrc))
+			if err := CopyFile(coverSrc, coverDst); err != nil {
+				return err
+			}
+		}
+	}
+
+	// Prune trash folders left empty (or holding only a cover) by the restore.
+	pruneTrashDirs(root, trashDir)
+	return nil
+}
+
+// DeleteFromTrash permanently removes the trashed copy of path, then the trash folders it
+// leaves empty or holding only a cover. Other files in the trash are left alone, so a file
+// trashed while the trash is being emptied survives.
+func DeleteFromTrash(root, path string) error {
+	src := TrashPath(root, path)
+	if err := os.Remove(src); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	pruneTrashDirs(root, filepath.Dir(src))
+	return DeleteFolderIfEmpty(filepath.Join(root, constants.TrashDir))
+}
+
+// pruneTrashDirs removes dir and its parents up to root's trash folder while they are empty
+// or hold only a cover.
+func pruneTrashDirs(root, dir string) {
+	trashRoot := filepath.Join(root, constants.TrashDir)
+	for ; dir != trashRoot && strings.HasPrefix(dir, trashRoot); dir = filepath.Dir(dir) {
+		if err := DeleteFolderWithCover(dir); err != nil {
+			break
+		}
+	}
+}
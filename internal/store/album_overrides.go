@@ -0,0 +1,32 @@
+package store
+
+import (
+	"database/sql"
+	"time"
+)
+
+// GetAlbumArtistOverride returns the album artist set for every track of an album, or ""
+// when none is.
+func (db *DB) GetAlbumArtistOverride(albumID string) (string, error) {
+	var albumArtist string
+	err := db.Get(&albumArtist, `SELECT album_artist FROM album_overrides WHERE album_id = ?`, albumID)
+	if err == sql.ErrNoRows {
+		return "", nil
+	}
+	return albumArtist, err
+}
+
+// SetAlbumArtistOverride sets the album artist for every track of an album. An empty
+// albumArtist removes the override.
+func (db *DB) SetAlbumArtistOverride(albumID, albumArtist string) error {
+	if albumArtist == "" {
+		_, err := db.Exec(`DELETE FROM album_overrides WHERE album_id = ?`, albumID)
+		return err
+	}
+	_, err := db.Exec(`
+		INSERT INTO album_overrides (album_id, album_artist, updated_at)
+		VALUES (?, ?, ?)
+		ON CONFLICT(album_id) DO UPDATE SET album_artist = excluded.album_artist, updated_at = excluded.updated_at
+	`, albumID, albumArtist, time.Now())
+	return err
+}
@@ -0,0 +1,117 @@
+package store
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/cesargomez89/navidrums/internal/domain"
+)
+
+// ErrAPIKeyNotFound is returned when revoking a key that does not exist.
+var ErrAPIKeyNotFound = errors.New("API key not found")
+
+const (
+	// apiKeyPrefix starts every API key so leaked keys are easy to recognise.
+	apiKeyPrefix = "ndk_"
+	// apiKeyShownLen is how much of a key is kept in clear as its Prefix.
+	apiKeyShownLen = len(apiKeyPrefix) + 6
+)
+
+// ListAPIKeys returns the stored API keys, oldest first.
+func (r *SettingsRepo) ListAPIKeys() ([]domain.APIKey, error) {
+	raw, err := r.Get(SettingAPIKeys)
+	if err != nil || raw == "" {
+		return nil, err
+	}
+	var keys []domain.APIKey
+	if err := json.Unmarshal([]byte(raw), &keys); err != nil {
+		return nil, fmt.Errorf("failed to parse API keys: %w", err)
+	}
+	return keys, nil
+}
+
+func (r *SettingsRepo) saveAPIKeys(keys []domain.APIKey) error {
+	data, err := json.Marshal(keys)
+	if err != nil {
+		return err
+	}
+	return r.Set(SettingAPIKeys, string(data))
+}
+
+// CreateAPIKey generates and stores a new key. The key itself is returned once and only its
+// hash is kept.
+func (r *SettingsRepo) CreateAPIKey(name string) (string, *domain.APIKey, error) {
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		return "", nil, fmt.Errorf("failed to generate API key: %w", err)
+	}
+	key := apiKeyPrefix + base64.RawURLEncoding.EncodeToString(secret)
+	apiKey := domain.APIKey{
+		ID:        uuid.New().String(),
+		Name:      name,
+		Prefix:    key[:apiKeyShownLen],
+		Hash:      hashAPIKey(key),
+		CreatedAt: time.Now(),
+	}
+
+	err := r.db.RunInTx(func(txDB *DB) error {
+		tx := NewSettingsRepo(txDB)
+		keys, err := tx.ListAPIKeys()
+		if err != nil {
+			return err
+		}
+		return tx.saveAPIKeys(append(keys, apiKey))
+	})
+	if err != nil {
+		return "", nil, err
+	}
+	return key, &apiKey, nil
+}
+
+// RevokeAPIKey deletes the key with the given ID.
+func (r *SettingsRepo) RevokeAPIKey(id string) error {
+	return r.db.RunInTx(func(txDB *DB) error {
+		tx := NewSettingsRepo(txDB)
+		keys, err := tx.ListAPIKeys()
+		if err != nil {
+			return err
+		}
+		for i, k := range keys {
+			if k.ID == id {
+				return tx.saveAPIKeys(append(keys[:i], keys[i+1:]...))
+			}
+		}
+		return ErrAPIKeyNotFound
+	})
+}
+
+// VerifyAPIKey returns the stored key matching key, or nil when there is none.
+func (r *SettingsRepo) VerifyAPIKey(key string) (*domain.APIKey, error) {
+	keys, err := r.ListAPIKeys()
+	if err != nil {
+		return nil, err
+	}
+	hash := []byte(hashAPIKey(key))
+	for i := range keys {
+		if subtle.ConstantTimeCompare(hash, []byte(keys[i].Hash)) == 1 {
+			return &keys[i], nil
+		}
+	}
+	return nil, nil
+}
+
+// hashAPIKey hashes a key for storage. Keys are 256 random bits, so a plain SHA-256 is
+// enough; there is nothing to brute-force the way there is with a password.
+func hashAPIKey(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
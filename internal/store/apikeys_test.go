@@ -0,0 +1,62 @@
+package store
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestSettingsRepo_APIKeys(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+	repo := NewSettingsRepo(db)
+
+	key, created, err := repo.CreateAPIKey("beets")
+	if err != nil {
+		t.Fatalf("CreateAPIKey failed: %v", err)
+	}
+	if !strings.HasPrefix(key, apiKeyPrefix) || created.Prefix != key[:apiKeyShownLen] {
+		t.Errorf("unexpected key %q with prefix %q", key, created.Prefix)
+	}
+	if strings.Contains(created.Hash, key) || created.Hash == "" {
+		t.Errorf("expected a hash of the key, got %q", created.Hash)
+	}
+	other, _, err := repo.CreateAPIKey("cron")
+	if err != nil {
+		t.Fatalf("CreateAPIKey failed: %v", err)
+	}
+
+	tests := []struct {
+		name   string
+		key    string
+		wantID string
+	}{
+		{"valid key", key, created.ID},
+		{"wrong key", key + "x", ""},
+		{"empty key", "", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := repo.VerifyAPIKey(tt.key)
+			if err != nil {
+				t.Fatalf("VerifyAPIKey failed: %v", err)
+			}
+			if (got == nil && tt.wantID != "") || (got != nil && got.ID != tt.wantID) {
+				t.Errorf("VerifyAPIKey() = %+v, want ID %q", got, tt.wantID)
+			}
+		})
+	}
+
+	if err := repo.RevokeAPIKey(created.ID); err != nil {
+		t.Fatalf("RevokeAPIKey failed: %v", err)
+	}
+	if got, _ := repo.VerifyAPIKey(key); got != nil {
+		t.Errorf("revoked key still verifies: %+v", got)
+	}
+	if got, _ := repo.VerifyAPIKey(other); got == nil {
+		t.Error("revoking one key should keep the others")
+	}
+	if err := repo.RevokeAPIKey(created.ID); !errors.Is(err, ErrAPIKeyNotFound) {
+		t.Errorf("RevokeAPIKey(unknown) = %v, want ErrAPIKeyNotFound", err)
+	}
+}
@@ -0,0 +1,124 @@
+package store
+
+import (
+	"errors"
+	"fmt"
+)
+
+// ErrUnknownDataFix is returned by RunDataFix for a name DataFixes does not list.
+var ErrUnknownDataFix = errors.New("unknown data fix")
+
+// DataFix is a data-only repair a migration once applied at startup, kept so it can be
+// re-run on demand after an import or a bug left rows needing it again. It never alters
+// the schema and is not recorded in schema_migrations. Running it twice changes nothing
+// the second time.
+type DataFix struct {
+	Name        string `json:"name"`
+	Description string `json:"description"`
+	// run applies the fix and returns the number of rows it changed.
+	run func(tx *DB) (int64, error)
+}
+
+// DataFixResult reports a RunDataFix run.
+type DataFixResult struct {
+	Name         string `json:"name"`
+	RowsAffected int64  `json:"rows_affected"`
+}
+
+// The patterns the lyrics cleanup removes: literal "\n" escapes, carriage returns, blank
+// lines in subtitles and more than one blank line in lyrics.
+const (
+	escapedNewline = `'\n'`
+	twoNewlines    = `CHAR(10) || CHAR(10)`
+	threeNewlines  = `CHAR(10) || CHAR(10) || CHAR(10)`
+)
+
+var dataFixes = []DataFix{
+	{
+		Name:        "year-from-date",
+		Description: "Fill year from release_date (migration 6)",
+		run: func(tx *DB) (int64, error) {
+			return execRows(tx, `
+				UPDATE tracks
+				SET year = CAST(SUBSTR(release_date, 1, 4) AS INTEGER)
+				WHERE release_date IS NOT NULL
+				  AND LENGTH(release_date) >= 4
+				  AND SUBSTR(release_date, 1, 4) GLOB '[0-9][0-9][0-9][0-9]'
+				  AND COALESCE(year, 0) != CAST(SUBSTR(release_date, 1, 4) AS INTEGER)
+			`)
+		},
+	},
+	{
+		Name:        "lyrics-cleanup",
+		Description: "Remove carriage returns and extra blank lines from lyrics and subtitles (migration 4)",
+		run: func(tx *DB) (int64, error) {
+			// Count each track once, then collapse runs of newlines until none are left:
+			// one REPLACE pass only shortens a long run.
+			n, err := execRows(tx, `
+				UPDATE tracks
+				SET lyrics = REPLACE(REPLACE(lyrics, `+escapedNewline+`, CHAR(10)), CHAR(13), ''),
+				    subtitles = REPLACE(REPLACE(subtitles, `+escapedNewline+`, CHAR(10)), CHAR(13), '')
+				WHERE INSTR(lyrics, `+escapedNewline+`) > 0 OR INSTR(lyrics, CHAR(13)) > 0 OR INSTR(lyrics, `+threeNewlines+`) > 0
+				   OR INSTR(subtitles, `+escapedNewline+`) > 0 OR INSTR(subtitles, CHAR(13)) > 0 OR INSTR(subtitles, `+twoNewlines+`) > 0
+			`)
+			if err != nil {
+				return 0, err
+			}
+			for _, q := range []string{
+				`UPDATE tracks SET lyrics = REPLACE(lyrics, ` + threeNewlines + `, ` + twoNewlines + `) WHERE INSTR(lyrics, ` + threeNewlines + `) > 0`,
+				`UPDATE tracks SET subtitles = REPLACE(subtitles, ` + twoNewlines + `, CHAR(10)) WHERE INSTR(subtitles, ` + twoNewlines + `) > 0`,
+			} {
+				for {
+					changed, err := execRows(tx, q)
+					if err != nil {
+						return 0, err
+					}
+					if changed == 0 {
+						break
+					}
+				}
+			}
+			return n, nil
+		},
+	},
+	{
+		Name:        "genre-normalize",
+		Description: "Lowercase and trim genres, as tracks are saved",
+		run: func(tx *DB) (int64, error) {
+			return execRows(tx, `UPDATE tracks SET genre = LOWER(TRIM(genre)) WHERE genre != LOWER(TRIM(genre))`)
+		},
+	},
+}
+
+// DataFixes lists the data fixes RunDataFix can run.
+func DataFixes() []DataFix {
+	return dataFixes
+}
+
+// RunDataFix runs the named data fix in a transaction and reports the rows it changed.
+func (db *DB) RunDataFix(name string) (*DataFixResult, error) {
+	for _, fix := range dataFixes {
+		if fix.Name != name {
+			continue
+		}
+		var n int64
+		err := db.RunInTx(func(tx *DB) error {
+			var runErr error
+			n, runErr = fix.run(tx)
+			return runErr
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to run data fix %s: %w", name, err)
+		}
+		return &DataFixResult{Name: name, RowsAffected: n}, nil
+	}
+	return nil, fmt.Errorf("%w: %s", ErrUnknownDataFix, name)
+}
+
+func execRows(tx *DB, query string) (int64, error) {
+	res, err := tx.Exec(query)
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
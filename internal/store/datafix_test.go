@@ -0,0 +1,68 @@
+package store
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/cesargomez89/navidrums/internal/domain"
+)
+
+func TestDB_RunDataFix(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	now := time.Now()
+	for _, id := range []string{"broken", "clean"} {
+		track := &domain.Track{ProviderID: id, Title: "Track", ReleaseDate: "2001-05-02", Year: 2001, Genre: "rock", Lyrics: "a\n\nb", Subtitles: "[00:01]a\n[00:02]b", Status: domain.TrackStatusCompleted, CreatedAt: now, UpdatedAt: now}
+		if err := db.CreateTrack(track); err != nil {
+			t.Fatalf("CreateTrack failed: %v", err)
+		}
+	}
+	// What an import or an old bug leaves behind, bypassing CreateTrack's normalization.
+	if _, err := db.Exec(`UPDATE tracks SET year = 0, genre = ' Rock ',
+		lyrics = 'a' || CHAR(13) || CHAR(10) || CHAR(10) || CHAR(10) || CHAR(10) || CHAR(10) || CHAR(10) || 'b\nc',
+		subtitles = '[00:01]a' || CHAR(10) || CHAR(10) || CHAR(10) || '[00:02]b'
+		WHERE provider_id = 'broken'`); err != nil {
+		t.Fatalf("failed to break track: %v", err)
+	}
+
+	tests := []struct {
+		name string
+		want int64
+	}{
+		{"year-from-date", 1},
+		{"lyrics-cleanup", 1},
+		{"genre-normalize", 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := db.RunDataFix(tt.name)
+			if err != nil {
+				t.Fatalf("RunDataFix failed: %v", err)
+			}
+			if result.RowsAffected != tt.want {
+				t.Errorf("first run changed %d rows, want %d", result.RowsAffected, tt.want)
+			}
+			result, err = db.RunDataFix(tt.name)
+			if err != nil {
+				t.Fatalf("RunDataFix failed: %v", err)
+			}
+			if result.RowsAffected != 0 {
+				t.Errorf("second run changed %d rows, want 0", result.RowsAffected)
+			}
+		})
+	}
+
+	track, err := db.GetTrackByProviderID("broken")
+	if err != nil {
+		t.Fatalf("GetTrackByProviderID failed: %v", err)
+	}
+	if track.Year != 2001 || track.Genre != "rock" || track.Lyrics != "a\n\nb\nc" || track.Subtitles != "[00:01]a\n[00:02]b" {
+		t.Errorf("track = %d %q %q %q, want the fixed values", track.Year, track.Genre, track.Lyrics, track.Subtitles)
+	}
+
+	if _, err := db.RunDataFix("drop-tables"); !errors.Is(err, ErrUnknownDataFix) {
+		t.Errorf("RunDataFix(unknown) error = %v, want ErrUnknownDataFix", err)
+	}
+}
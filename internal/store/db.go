@@ -435,6 +435,286 @@ var migrations = []migration{
 			return err
 		},
 	},
+	{
+		version:     16,
+		description: "Add deleted_at column for soft-deleted tracks",
+		up: func(tx *sqlx.Tx) error {
+			_, err := tx.Exec("ALTER TABLE tracks ADD COLUMN deleted_at DATETIME")
+			if err != nil && !strings.Contains(err.Error(), "duplicate column name") {
+				return err
+			}
+			return nil
+		},
+	},
+	{
+		version:     17,
+		description: "Add artist_sort and album_artist_sort columns to tracks",
+		up: func(tx *sqlx.Tx) error {
+			columns := []string{
+				"ALTER TABLE tracks ADD COLUMN artist_sort TEXT",
+				"ALTER TABLE tracks ADD COLUMN album_artist_sort TEXT",
+			}
+			for _, q := range columns {
+				if _, err := tx.Exec(q); err != nil {
+					if !strings.Contains(err.Error(), "duplicate column name") {
+						return err
+					}
+				}
+			}
+			// Backfill NULL values to empty strings to avoid scan errors
+			_, err := tx.Exec(`
+				UPDATE tracks SET artist_sort = COALESCE(artist_sort, ''), album_artist_sort = COALESCE(album_artist_sort, '')
+			`)
+			return err
+		},
+	},
+	{
+		version:     18,
+		description: "Add MusicBrainz release and release track ID columns to tracks",
+		up: func(tx *sqlx.Tx) error {
+			columns := []string{
+				"ALTER TABLE tracks ADD COLUMN musicbrainz_album_id TEXT",
+				"ALTER TABLE tracks ADD COLUMN release_track_id TEXT",
+			}
+			for _, q := range columns {
+				if _, err := tx.Exec(q); err != nil {
+					if !strings.Contains(err.Error(), "duplicate column name") {
+						return err
+					}
+				}
+			}
+			// Backfill NULL values to empty strings to avoid scan errors
+			_, err := tx.Exec(`
+				UPDATE tracks SET musicbrainz_album_id = COALESCE(musicbrainz_album_id, ''),
+					release_track_id = COALESCE(release_track_id, '')
+			`)
+			return err
+		},
+	},
+	{
+		version:     19,
+		description: "Add job_events table for per-job logs",
+		up: func(tx *sqlx.Tx) error {
+			queries := []string{
+				`CREATE TABLE IF NOT EXISTS job_events (
+					id INTEGER PRIMARY KEY AUTOINCREMENT,
+					job_id TEXT NOT NULL,
+					level TEXT NOT NULL,
+					message TEXT NOT NULL,
+					created_at DATETIME DEFAULT CURRENT_TIMESTAMP
+				)`,
+				`CREATE INDEX IF NOT EXISTS idx_job_events_job_id ON job_events(job_id)`,
+			}
+			for _, q := range queries {
+				if _, err := tx.Exec(q); err != nil {
+					return err
+				}
+			}
+			return nil
+		},
+	},
+	{
+		version:     20,
+		description: "Add ISRC index to tracks for duplicate detection",
+		up: func(tx *sqlx.Tx) error {
+			_, err := tx.Exec(`CREATE INDEX IF NOT EXISTS idx_tracks_isrc ON tracks(isrc)`)
+			return err
+		},
+	},
+	{
+		version:     21,
+		description: "Add audio technical properties to tracks",
+		up: func(tx *sqlx.Tx) error {
+			columns := []string{
+				"ALTER TABLE tracks ADD COLUMN sample_rate INTEGER DEFAULT 0",
+				"ALTER TABLE tracks ADD COLUMN bit_depth INTEGER DEFAULT 0",
+				"ALTER TABLE tracks ADD COLUMN channels INTEGER DEFAULT 0",
+				"ALTER TABLE tracks ADD COLUMN bitrate INTEGER DEFAULT 0",
+			}
+			for _, q := range columns {
+				if _, err := tx.Exec(q); err != nil {
+					if !strings.Contains(err.Error(), "duplicate column name") {
+						return err
+					}
+				}
+			}
+			return nil
+		},
+	},
+	{
+		version:     22,
+		description: "Add source_provider column to tracks",
+		up: func(tx *sqlx.Tx) error {
+			// Existing tracks get an empty source and keep resolving to the active metadata
+			// provider, as they always did.
+			_, err := tx.Exec("ALTER TABLE tracks ADD COLUMN source_provider TEXT DEFAULT ''")
+			if err != nil && !strings.Contains(err.Error(), "duplicate column name") {
+				return err
+			}
+			return nil
+		},
+	},
+	{
+		version:     23,
+		description: "Add headers column to providers",
+		up: func(tx *sqlx.Tx) error {
+			_, err := tx.Exec("ALTER TABLE providers ADD COLUMN headers TEXT NOT NULL DEFAULT ''")
+			if err != nil && !strings.Contains(err.Error(), "duplicate column name") {
+				return err
+			}
+			return nil
+		},
+	},
+	{
+		version:     24,
+		description: "Add credit columns to tracks",
+		up: func(tx *sqlx.Tx) error {
+			columns := []string{
+				"ALTER TABLE tracks ADD COLUMN producer TEXT DEFAULT ''",
+				"ALTER TABLE tracks ADD COLUMN engineer TEXT DEFAULT ''",
+				"ALTER TABLE tracks ADD COLUMN mixer TEXT DEFAULT ''",
+				"ALTER TABLE tracks ADD COLUMN performers TEXT DEFAULT ''",
+			}
+			for _, q := range columns {
+				if _, err := tx.Exec(q); err != nil {
+					if !strings.Contains(err.Error(), "duplicate column name") {
+						return err
+					}
+				}
+			}
+			return nil
+		},
+	},
+	{
+		version:     25,
+		description: "Add original release date columns to tracks",
+		up: func(tx *sqlx.Tx) error {
+			columns := []string{
+				"ALTER TABLE tracks ADD COLUMN original_date TEXT DEFAULT ''",
+				"ALTER TABLE tracks ADD COLUMN original_year INTEGER DEFAULT 0",
+			}
+			for _, q := range columns {
+				if _, err := tx.Exec(q); err != nil {
+					if !strings.Contains(err.Error(), "duplicate column name") {
+						return err
+					}
+				}
+			}
+			return nil
+		},
+	},
+	{
+		version:     26,
+		description: "Add completed_at index to tracks for the recently added view",
+		up: func(tx *sqlx.Tx) error {
+			_, err := tx.Exec(`CREATE INDEX IF NOT EXISTS idx_tracks_completed_at ON tracks(completed_at DESC)`)
+			return err
+		},
+	},
+	{
+		version:     27,
+		description: "Add min_duration column to jobs",
+		up: func(tx *sqlx.Tx) error {
+			_, err := tx.Exec("ALTER TABLE jobs ADD COLUMN min_duration INTEGER")
+			if err != nil && !strings.Contains(err.Error(), "duplicate column name") {
+				return err
+			}
+			return nil
+		},
+	},
+	{
+		version:     28,
+		description: "Add track_ids column to jobs for partial album downloads",
+		up: func(tx *sqlx.Tx) error {
+			_, err := tx.Exec("ALTER TABLE jobs ADD COLUMN track_ids TEXT")
+			if err != nil && !strings.Contains(err.Error(), "duplicate column name") {
+				return err
+			}
+			return nil
+		},
+	},
+	{
+		version:     29,
+		description: "Add target_quality column to tracks",
+		up: func(tx *sqlx.Tx) error {
+			_, err := tx.Exec("ALTER TABLE tracks ADD COLUMN target_quality TEXT DEFAULT ''")
+			if err != nil && !strings.Contains(err.Error(), "duplicate column name") {
+				return err
+			}
+			return nil
+		},
+	},
+	{
+		version:     30,
+		description: "Add album_overrides table for per-album album artist overrides",
+		up: func(tx *sqlx.Tx) error {
+			_, err := tx.Exec(`CREATE TABLE IF NOT EXISTS album_overrides (
+				album_id TEXT PRIMARY KEY,
+				album_artist TEXT NOT NULL,
+				updated_at DATETIME DEFAULT CURRENT_TIMESTAMP
+			)`)
+			return err
+		},
+	},
+	{
+		version:     31,
+		description: "Add locked column to tracks",
+		up: func(tx *sqlx.Tx) error {
+			_, err := tx.Exec("ALTER TABLE tracks ADD COLUMN locked BOOLEAN DEFAULT 0")
+			if err != nil && !strings.Contains(err.Error(), "duplicate column name") {
+				return err
+			}
+			return nil
+		},
+	},
+	{
+		version:     32,
+		description: "Add job_duplicate_tracks table linking skipped duplicates to existing tracks",
+		up: func(tx *sqlx.Tx) error {
+			_, err := tx.Exec(`CREATE TABLE IF NOT EXISTS job_duplicate_tracks (
+				job_id TEXT NOT NULL,
+				provider_id TEXT NOT NULL,
+				track_id INTEGER NOT NULL,
+				PRIMARY KEY (job_id, provider_id)
+			)`)
+			return err
+		},
+	},
+	{
+		version:     33,
+		description: "Add album_gain and album_peak columns to tracks",
+		up: func(tx *sqlx.Tx) error {
+			for _, col := range []string{"album_gain", "album_peak"} {
+				_, err := tx.Exec("ALTER TABLE tracks ADD COLUMN " + col + " REAL DEFAULT 0")
+				if err != nil && !strings.Contains(err.Error(), "duplicate column name") {
+					return err
+				}
+			}
+			return nil
+		},
+	},
+	{
+		version:     34,
+		description: "Add quality column to jobs for upgrade targets",
+		up: func(tx *sqlx.Tx) error {
+			_, err := tx.Exec("ALTER TABLE jobs ADD COLUMN quality TEXT")
+			if err != nil && !strings.Contains(err.Error(), "duplicate column name") {
+				return err
+			}
+			return nil
+		},
+	},
+	{
+		version:     35,
+		description: "Add request_id column to jobs",
+		up: func(tx *sqlx.Tx) error {
+			_, err := tx.Exec("ALTER TABLE jobs ADD COLUMN request_id TEXT")
+			if err != nil && !strings.Contains(err.Error(), "duplicate column name") {
+				return err
+			}
+			return nil
+		},
+	},
 }
 
 type dbOps interface {
@@ -2,14 +2,19 @@ package store
 
 import (
 	"database/sql"
+	"errors"
+	"fmt"
 	"os"
+	"path/filepath"
+	"slices"
 	"testing"
 	"time"
 
+	"github.com/cesargomez89/navidrums/internal/constants"
 	"github.com/cesargomez89/navidrums/internal/domain"
 )
 
-func setupTestDB(t *testing.T) (*DB, func()) {
+func setupTestDB(t testing.TB) (*DB, func()) {
 	tmpFile := "test.db"
 	db, err := NewSQLiteDB(tmpFile)
 	if err != nil {
@@ -240,7 +245,7 @@ func TestDB_Tracks(t *testing.T) {
 	}
 
 	// Test SearchTracks
-	results, err := db.SearchTracks("Test", 0, 10)
+	results, err := db.SearchTracks("Test", TrackSort{}, 0, 10)
 	if err != nil {
 		t.Errorf("SearchTracks failed: %v", err)
 	}
@@ -248,7 +253,7 @@ func TestDB_Tracks(t *testing.T) {
 		t.Errorf("Expected 1 result, got %d", len(results))
 	}
 
-	results, err = db.SearchTracks("Nonexistent", 0, 10)
+	results, err = db.SearchTracks("Nonexistent", TrackSort{}, 0, 10)
 	if err != nil {
 		t.Errorf("SearchTracks failed: %v", err)
 	}
@@ -313,7 +318,7 @@ func TestDB_TrackListOperations(t *testing.T) {
 	}
 
 	// Test ListCompletedTracks
-	completed, err := db.ListCompletedTracks(0, 10)
+	completed, err := db.ListCompletedTracks(TrackSort{}, 0, 10)
 	if err != nil {
 		t.Errorf("ListCompletedTracks failed: %v", err)
 	}
@@ -322,7 +327,7 @@ func TestDB_TrackListOperations(t *testing.T) {
 	}
 
 	// Test ListTracksByStatus
-	queued, err := db.ListTracksByStatus(domain.TrackStatusQueued, 0, 10)
+	queued, err := db.ListTracksByStatus(domain.TrackStatusQueued, TrackSort{}, 0, 10)
 	if err != nil {
 		t.Errorf("ListTracksByStatus failed: %v", err)
 	}
@@ -438,6 +443,42 @@ func TestDB_JobStats(t *testing.T) {
 	}
 }
 
+func TestDB_CountJobsCompletedSince(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	now := time.Now()
+	for i, j := range []struct {
+		status domain.JobStatus
+		age    time.Duration
+	}{
+		{domain.JobStatusCompleted, time.Minute},
+		{domain.JobStatusCompleted, 30 * time.Minute},
+		{domain.JobStatusCompleted, 2 * time.Hour},
+		{domain.JobStatusFailed, time.Minute},
+	} {
+		job := &domain.Job{
+			ID:        fmt.Sprintf("job_%d", i),
+			Type:      domain.JobTypeTrack,
+			Status:    j.status,
+			SourceID:  sql.NullString{String: fmt.Sprintf("s%d", i), Valid: true},
+			CreatedAt: now.Add(-j.age),
+			UpdatedAt: now.Add(-j.age),
+		}
+		if err := db.CreateJob(job); err != nil {
+			t.Fatalf("CreateJob failed: %v", err)
+		}
+	}
+
+	count, err := db.CountJobsCompletedSince(now.Add(-time.Hour))
+	if err != nil {
+		t.Fatalf("CountJobsCompletedSince failed: %v", err)
+	}
+	if count != 2 {
+		t.Errorf("count = %d, want 2", count)
+	}
+}
+
 func TestDB_GetActiveJobBySourceID(t *testing.T) {
 	db, cleanup := setupTestDB(t)
 	defer cleanup()
@@ -504,6 +545,7 @@ func TestDB_TrackWithJsonFields(t *testing.T) {
 		Artists:      []string{"Primary Artist", "Featuring 1", "Featuring 2"},
 		AlbumArtist:  "Album Artist",
 		AlbumArtists: []string{"Album Artist", "Guest Album Artist"},
+		Tags:         []string{"indie rock", "shoegaze"},
 		Album:        "Test Album",
 		AlbumID:      "album_123",
 		Status:       domain.TrackStatusMissing,
@@ -541,6 +583,19 @@ func TestDB_TrackWithJsonFields(t *testing.T) {
 		t.Errorf("AlbumArtists[0] = %s, want 'Album Artist'", fetched.AlbumArtists[0])
 	}
 
+	// Verify Tags array, and that updating replaces it
+	if !slices.Equal(fetched.Tags, []string{"indie rock", "shoegaze"}) {
+		t.Errorf("Tags = %v, want [indie rock shoegaze]", fetched.Tags)
+	}
+	fetched.Tags = []string{"dream pop"}
+	if err := db.UpdateTrack(fetched); err != nil {
+		t.Fatalf("UpdateTrack failed: %v", err)
+	}
+	updated, _ := db.GetTrackByID(track.ID)
+	if !slices.Equal(updated.Tags, []string{"dream pop"}) {
+		t.Errorf("Tags after update = %v, want [dream pop]", updated.Tags)
+	}
+
 	// Test track with empty Artists JSON
 	emptyTrack := &domain.Track{
 		ProviderID: "empty_json_test",
@@ -590,6 +645,10 @@ func TestDB_UpdateTrack(t *testing.T) {
 	track.TrackNumber = 2
 	track.Year = 2023
 	track.Status = domain.TrackStatusCompleted
+	track.SampleRate = 96000
+	track.BitDepth = 24
+	track.Channels = 2
+	track.Bitrate = 2950
 
 	err := db.UpdateTrack(track)
 	if err != nil {
@@ -616,6 +675,10 @@ func TestDB_UpdateTrack(t *testing.T) {
 	if fetched.Year != 2023 {
 		t.Errorf("Year = %d, want 2023", fetched.Year)
 	}
+	if fetched.SampleRate != 96000 || fetched.BitDepth != 24 || fetched.Channels != 2 || fetched.Bitrate != 2950 {
+		t.Errorf("audio properties = %d Hz, %d-bit, %d ch, %d kbps, want 96000 Hz, 24-bit, 2 ch, 2950 kbps",
+			fetched.SampleRate, fetched.BitDepth, fetched.Channels, fetched.Bitrate)
+	}
 
 	err = db.UpdateTrack(&domain.Track{ID: 99999})
 	if err == nil {
@@ -728,6 +791,49 @@ func TestDB_ListTracksByParentJobID(t *testing.T) {
 	}
 }
 
+func TestDB_GetTrackByISRC(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	tracks := []*domain.Track{
+		{ProviderID: "isrc_single", Title: "Single", Artist: "Artist", ISRC: "USABC1234567", Status: domain.TrackStatusCompleted, CreatedAt: time.Now(), UpdatedAt: time.Now()},
+		{ProviderID: "isrc_queued", Title: "Queued", Artist: "Artist", ISRC: "USABC7654321", Status: domain.TrackStatusQueued, CreatedAt: time.Now(), UpdatedAt: time.Now()},
+	}
+	for _, tr := range tracks {
+		if err := db.CreateTrack(tr); err != nil {
+			t.Fatalf("CreateTrack failed: %v", err)
+		}
+	}
+
+	tests := []struct {
+		name      string
+		isrc      string
+		wantFound bool
+	}{
+		{"completed track", "USABC1234567", true},
+		{"track not completed", "USABC7654321", false},
+		{"unknown isrc", "USABC0000000", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			found, err := db.GetTrackByISRC(tt.isrc)
+			if !tt.wantFound {
+				if err == nil {
+					t.Errorf("Expected no track for ISRC %s, got %q", tt.isrc, found.Title)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("GetTrackByISRC failed: %v", err)
+			}
+			if found.ProviderID != "isrc_single" {
+				t.Errorf("ProviderID = %q, want %q", found.ProviderID, "isrc_single")
+			}
+		})
+	}
+}
+
 func TestDB_FindInterruptedTracks(t *testing.T) {
 	db, cleanup := setupTestDB(t)
 	defer cleanup()
@@ -806,6 +912,61 @@ func TestDB_ClearJobError(t *testing.T) {
 	}
 }
 
+func TestDB_JobEvents(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	job := &domain.Job{
+		ID:        "events_job",
+		Type:      domain.JobTypeAlbum,
+		Status:    domain.JobStatusFailed,
+		SourceID:  sql.NullString{String: "album_events", Valid: true},
+		CreatedAt: time.Now(),
+		UpdatedAt: time.Now(),
+	}
+	if err := db.CreateJob(job); err != nil {
+		t.Fatalf("CreateJob failed: %v", err)
+	}
+
+	total := constants.MaxJobEvents + 5
+	for i := 0; i < total; i++ {
+		level := domain.JobEventInfo
+		if i == total-1 {
+			level = domain.JobEventError
+		}
+		if err := db.AddJobEvent(job.ID, level, fmt.Sprintf("event %d", i)); err != nil {
+			t.Fatalf("AddJobEvent failed: %v", err)
+		}
+	}
+
+	// Appending prunes as it goes: the job never holds more than MaxJobEvents.
+	events, err := db.ListJobEvents(job.ID)
+	if err != nil {
+		t.Fatalf("ListJobEvents failed: %v", err)
+	}
+	if len(events) != constants.MaxJobEvents {
+		t.Fatalf("Expected %d events after pruning, got %d", constants.MaxJobEvents, len(events))
+	}
+	if events[0].Message != "event 5" {
+		t.Errorf("Expected oldest kept event to be %q, got %q", "event 5", events[0].Message)
+	}
+	last := events[len(events)-1]
+	if last.Level != domain.JobEventError || last.Message != fmt.Sprintf("event %d", total-1) {
+		t.Errorf("Unexpected last event: %+v", last)
+	}
+
+	if err := db.ClearFinishedJobs(); err != nil {
+		t.Fatalf("ClearFinishedJobs failed: %v", err)
+	}
+	events, err = db.ListJobEvents(job.ID)
+	if err != nil {
+		t.Fatalf("ListJobEvents failed: %v", err)
+	}
+	if len(events) != 0 {
+		t.Errorf("Expected events to be pruned with the job, got %d", len(events))
+	}
+}
+
 func TestDB_ResetStuckJobs(t *testing.T) {
 	db, cleanup := setupTestDB(t)
 	defer cleanup()
@@ -1012,6 +1173,16 @@ func TestDB_CountJobsForParent(t *testing.T) {
 		_ = db.UpdateJobStatus(j.ID, domain.JobStatusCompleted, 100)
 	}
 
+	// A decomposed child container is still pending.
+	decomposed := &domain.Job{ID: "child-4", Type: domain.JobTypeAlbum, Status: domain.JobStatusDecomposed, SourceID: sql.NullString{String: "a2", Valid: true}, ParentJobID: sql.NullString{String: parentID, Valid: true}, CreatedAt: time.Now(), UpdatedAt: time.Now()}
+	if err := db.CreateJob(decomposed); err != nil {
+		t.Fatalf("CreateJob failed: %v", err)
+	}
+	if _, pendingDecomposed, _ := db.CountJobsForParent(parentID); pendingDecomposed != 1 {
+		t.Errorf("Expected pending 1 with a decomposed child, got %d", pendingDecomposed)
+	}
+	_ = db.UpdateJobStatus(decomposed.ID, domain.JobStatusCompleted, 100)
+
 	// Count after completion
 	_, pendingAfter, _ := db.CountJobsForParent(parentID)
 	if pendingAfter != 0 {
@@ -1078,3 +1249,402 @@ func TestDB_CreateTrackBatch(t *testing.T) {
 		}
 	}
 }
+
+func TestDB_RunInTx_Batches(t *testing.T) {
+	newBatch := func(prefix string) ([]*domain.Track, []*domain.Job) {
+		var tracks []*domain.Track
+		var jobs []*domain.Job
+		for i := range 3 {
+			id := fmt.Sprintf("%s-%d", prefix, i)
+			tracks = append(tracks, &domain.Track{ProviderID: id, Title: id, Status: domain.TrackStatusQueued, ParentJobID: prefix})
+			jobs = append(jobs, &domain.Job{ID: "job-" + id, Type: domain.JobTypeTrack, Status: domain.JobStatusQueued, SourceID: sql.NullString{String: id, Valid: true}})
+		}
+		return tracks, jobs
+	}
+
+	tests := []struct {
+		name      string
+		fail      bool
+		wantCount int
+	}{
+		{"commit", false, 3},
+		{"rollback", true, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, cleanup := setupTestDB(t)
+			defer cleanup()
+
+			tracks, jobs := newBatch(tt.name)
+			err := db.RunInTx(func(txDB *DB) error {
+				if _, err := txDB.CreateTrackBatch(tracks); err != nil {
+					return err
+				}
+				if err := txDB.CreateJobBatch(jobs); err != nil {
+					return err
+				}
+				if tt.fail {
+					return errors.New("job creation failed")
+				}
+				return nil
+			})
+			if (err != nil) != tt.fail {
+				t.Fatalf("RunInTx error = %v, want failure %v", err, tt.fail)
+			}
+
+			trackCount, err := db.CountTracksByStatus(domain.TrackStatusQueued)
+			if err != nil {
+				t.Fatalf("CountTracksByStatus failed: %v", err)
+			}
+			if trackCount != tt.wantCount {
+				t.Errorf("tracks = %d, want %d", trackCount, tt.wantCount)
+			}
+			active, _ := db.ListActiveJobs(0, 10)
+			if len(active) != tt.wantCount {
+				t.Errorf("jobs = %d, want %d", len(active), tt.wantCount)
+			}
+		})
+	}
+}
+
+// BenchmarkCreateTracksAndJobs compares queueing an album's tracks with one transaction per
+// insert against a single transaction for the whole batch.
+func BenchmarkCreateTracksAndJobs(b *testing.B) {
+	const albumSize = 50
+	newBatch := func(n int) ([]*domain.Track, []*domain.Job) {
+		var tracks []*domain.Track
+		var jobs []*domain.Job
+		for i := range albumSize {
+			id := fmt.Sprintf("bench-%d-%d", n, i)
+			tracks = append(tracks, &domain.Track{ProviderID: id, Title: id, Status: domain.TrackStatusQueued})
+			jobs = append(jobs, &domain.Job{ID: id, Type: domain.JobTypeTrack, Status: domain.JobStatusQueued, SourceID: sql.NullString{String: id, Valid: true}})
+		}
+		return tracks, jobs
+	}
+
+	b.Run("per insert", func(b *testing.B) {
+		db, cleanup := setupTestDB(b)
+		defer cleanup()
+		for n := 0; b.Loop(); n++ {
+			tracks, jobs := newBatch(n)
+			for i := range tracks {
+				if err := db.CreateTrack(tracks[i]); err != nil {
+					b.Fatalf("CreateTrack failed: %v", err)
+				}
+				if err := db.CreateJob(jobs[i]); err != nil {
+					b.Fatalf("CreateJob failed: %v", err)
+				}
+			}
+		}
+	})
+
+	b.Run("single transaction", func(b *testing.B) {
+		db, cleanup := setupTestDB(b)
+		defer cleanup()
+		for n := 0; b.Loop(); n++ {
+			tracks, jobs := newBatch(n)
+			err := db.RunInTx(func(txDB *DB) error {
+				if _, err := txDB.CreateTrackBatch(tracks); err != nil {
+					return err
+				}
+				return txDB.CreateJobBatch(jobs)
+			})
+			if err != nil {
+				b.Fatalf("RunInTx failed: %v", err)
+			}
+		}
+	})
+}
+
+func TestDB_ListCompletedTracksFiltered(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	now := time.Now()
+	for _, tr := range []*domain.Track{
+		{ProviderID: "f1", Title: "Blue", Artist: "A", AudioQuality: "HI_RES_LOSSLESS", FileExtension: ".flac", SourceProvider: "qobuz"},
+		{ProviderID: "f2", Title: "Blue Moon", Artist: "B", AudioQuality: "LOSSLESS", FileExtension: ".flac", SourceProvider: "hifi"},
+		{ProviderID: "f3", Title: "Red", Artist: "C", AudioQuality: "HIGH", FileExtension: ".m4a"},
+		{ProviderID: "f4", Title: "Blue Deleted", Artist: "D", AudioQuality: "LOSSLESS", FileExtension: ".flac", DeletedAt: &now},
+	} {
+		tr.Status = domain.TrackStatusCompleted
+		tr.CompletedAt = &now
+		tr.CreatedAt = now
+		tr.UpdatedAt = now
+		if err := db.CreateTrack(tr); err != nil {
+			t.Fatalf("CreateTrack failed: %v", err)
+		}
+	}
+
+	tests := []struct {
+		name   string
+		filter TrackFilter
+		want   []string
+	}{
+		{"quality", TrackFilter{AudioQuality: "LOSSLESS"}, []string{"f2"}},
+		{"extension, any case", TrackFilter{FileExtension: ".FLAC"}, []string{"f1", "f2"}},
+		{"search and extension", TrackFilter{Search: "blue", FileExtension: ".flac"}, []string{"f1", "f2"}},
+		{"search and quality", TrackFilter{Search: "moon", AudioQuality: "HI_RES_LOSSLESS"}, nil},
+		{"no match", TrackFilter{FileExtension: ".mp3"}, nil},
+		{"source provider", TrackFilter{SourceProvider: "qobuz"}, []string{"f1"}},
+		{"search and source provider", TrackFilter{Search: "blue", SourceProvider: "hifi"}, []string{"f2"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tracks, err := db.ListCompletedTracksFiltered(tt.filter, TrackSort{}, 0, 10)
+			if err != nil {
+				t.Fatalf("ListCompletedTracksFiltered failed: %v", err)
+			}
+			var got []string
+			for _, tr := range tracks {
+				got = append(got, tr.ProviderID)
+			}
+			slices.Sort(got)
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+
+			count, err := db.CountCompletedTracksFiltered(tt.filter)
+			if err != nil {
+				t.Fatalf("CountCompletedTracksFiltered failed: %v", err)
+			}
+			if count != len(tt.want) {
+				t.Errorf("count = %d, want %d", count, len(tt.want))
+			}
+		})
+	}
+}
+
+func TestDB_ListTracksCompletedBetween(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	now := time.Now()
+	at := func(d time.Duration) *time.Time {
+		ts := now.Add(-d)
+		return &ts
+	}
+	for _, tr := range []*domain.Track{
+		{ProviderID: "r1", Status: domain.TrackStatusCompleted, CompletedAt: at(time.Hour)},
+		{ProviderID: "r2", Status: domain.TrackStatusCompleted, CompletedAt: at(26 * time.Hour)},
+		{ProviderID: "r3", Status: domain.TrackStatusCompleted, CompletedAt: at(10 * 24 * time.Hour)},
+		{ProviderID: "r4", Status: domain.TrackStatusCompleted, CompletedAt: at(2 * time.Hour), DeletedAt: &now},
+		{ProviderID: "r5", Status: domain.TrackStatusFailed, CompletedAt: at(time.Hour)},
+		{ProviderID: "r6", Status: domain.TrackStatusCompleted},
+		{ProviderID: "r7", Status: domain.TrackStatusCompleted, CompletedAt: at(-time.Hour)},
+	} {
+		tr.CreatedAt = now
+		tr.UpdatedAt = now
+		if err := db.CreateTrack(tr); err != nil {
+			t.Fatalf("CreateTrack failed: %v", err)
+		}
+		// CreateTrack leaves completed_at unset; downloads record it on completion.
+		if err := db.UpdateTrack(tr); err != nil {
+			t.Fatalf("UpdateTrack failed: %v", err)
+		}
+	}
+
+	tests := []struct {
+		name string
+		from time.Time
+		to   time.Time
+		want []string
+	}{
+		{"last day", now.Add(-24 * time.Hour), now, []string{"r1"}},
+		{"last week, newest first", now.Add(-7 * 24 * time.Hour), now, []string{"r1", "r2"}},
+		{"older window", now.Add(-30 * 24 * time.Hour), now.Add(-2 * 24 * time.Hour), []string{"r3"}},
+		{"empty window", now.Add(-5 * 24 * time.Hour), now.Add(-3 * 24 * time.Hour), nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tracks, err := db.ListTracksCompletedBetween(tt.from, tt.to)
+			if err != nil {
+				t.Fatalf("ListTracksCompletedBetween failed: %v", err)
+			}
+			var got []string
+			for _, tr := range tracks {
+				got = append(got, tr.ProviderID)
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDB_ListCompletedTracksSorted(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	base := time.Now().Add(-time.Hour)
+	for i, tr := range []*domain.Track{
+		{ProviderID: "s1", Title: "charlie", Artist: "Beta", Album: "Zeta", Year: 2001, TrackNumber: 2},
+		{ProviderID: "s2", Title: "Alpha", Artist: "alpha", Album: "Omega", Year: 1999, TrackNumber: 1},
+		{ProviderID: "s3", Title: "bravo", Artist: "Beta", Album: "Zeta", Year: 2001, TrackNumber: 1},
+		{ProviderID: "s4", Title: "Delta", Artist: "Gamma", Album: "alpha", Year: 2010, TrackNumber: 1},
+	} {
+		completed := base.Add(time.Duration(i) * time.Minute)
+		tr.Status = domain.TrackStatusCompleted
+		tr.DiscNumber = 1
+		tr.CompletedAt = &completed
+		tr.CreatedAt = completed
+		tr.UpdatedAt = completed
+		if err := db.CreateTrack(tr); err != nil {
+			t.Fatalf("CreateTrack failed: %v", err)
+		}
+		// CreateTrack does not write completed_at.
+		if err := db.UpdateTrack(tr); err != nil {
+			t.Fatalf("UpdateTrack failed: %v", err)
+		}
+	}
+
+	tests := []struct {
+		name string
+		sort TrackSort
+		want []string
+	}{
+		{"default", TrackSort{}, []string{"s4", "s3", "s2", "s1"}},
+		{"artist", TrackSort{Key: SortArtist}, []string{"s2", "s3", "s1", "s4"}},
+		{"artist desc keeps track order", TrackSort{Key: SortArtist, Desc: true}, []string{"s4", "s3", "s1", "s2"}},
+		{"album", TrackSort{Key: SortAlbum}, []string{"s4", "s2", "s3", "s1"}},
+		{"title", TrackSort{Key: SortTitle}, []string{"s2", "s3", "s1", "s4"}},
+		{"title desc", TrackSort{Key: SortTitle, Desc: true}, []string{"s4", "s1", "s3", "s2"}},
+		{"year", TrackSort{Key: SortYear}, []string{"s2", "s3", "s1", "s4"}},
+		{"year desc", TrackSort{Key: SortYear, Desc: true}, []string{"s4", "s3", "s1", "s2"}},
+		{"added", TrackSort{Key: SortAdded}, []string{"s1", "s2", "s3", "s4"}},
+		{"added desc", TrackSort{Key: SortAdded, Desc: true}, []string{"s4", "s3", "s2", "s1"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tracks, err := db.ListCompletedTracks(tt.sort, 0, 10)
+			if err != nil {
+				t.Fatalf("ListCompletedTracks failed: %v", err)
+			}
+			var got []string
+			for _, tr := range tracks {
+				got = append(got, tr.ProviderID)
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	t.Run("search and pagination", func(t *testing.T) {
+		tracks, err := db.SearchTracks("beta", TrackSort{Key: SortTitle}, 1, 1)
+		if err != nil {
+			t.Fatalf("SearchTracks failed: %v", err)
+		}
+		if len(tracks) != 1 || tracks[0].ProviderID != "s1" {
+			t.Errorf("expected second page to hold s1, got %v", tracks)
+		}
+	})
+}
+
+func TestParseTrackSort(t *testing.T) {
+	tests := []struct {
+		key, order string
+		want       TrackSort
+	}{
+		{"artist", "", TrackSort{Key: SortArtist}},
+		{"year", "desc", TrackSort{Key: SortYear, Desc: true}},
+		{"added", "DESC", TrackSort{Key: SortAdded, Desc: true}},
+		{"title", "asc", TrackSort{Key: SortTitle}},
+		{"file_path", "desc", TrackSort{}},
+		{"", "desc", TrackSort{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.key+"/"+tt.order, func(t *testing.T) {
+			if got := ParseTrackSort(tt.key, tt.order); got != tt.want {
+				t.Errorf("ParseTrackSort(%q, %q) = %+v, want %+v", tt.key, tt.order, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDB_Optimize(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	now := time.Now()
+	for i := range 200 {
+		track := &domain.Track{ProviderID: fmt.Sprintf("opt-%d", i), Title: "Track", Lyrics: string(make([]byte, 2048)), Status: domain.TrackStatusCompleted, CreatedAt: now, UpdatedAt: now}
+		if err := db.CreateTrack(track); err != nil {
+			t.Fatalf("CreateTrack failed: %v", err)
+		}
+		if i%2 == 0 {
+			if err := db.DeleteTrack(track.ID); err != nil {
+				t.Fatalf("DeleteTrack failed: %v", err)
+			}
+		}
+	}
+
+	result, err := db.Optimize()
+	if err != nil {
+		t.Fatalf("Optimize failed: %v", err)
+	}
+	if result.SizeBefore == 0 || result.SizeAfter == 0 {
+		t.Errorf("expected sizes to be reported, got %+v", result)
+	}
+	if result.SizeAfter >= result.SizeBefore {
+		t.Errorf("expected the database to shrink, got %d -> %d bytes", result.SizeBefore, result.SizeAfter)
+	}
+
+	count, err := db.CountTracksByStatus(domain.TrackStatusCompleted)
+	if err != nil {
+		t.Fatalf("CountTracksByStatus failed: %v", err)
+	}
+	if count != 100 {
+		t.Errorf("expected 100 tracks to survive, got %d", count)
+	}
+
+	if err := db.RunInTx(func(txDB *DB) error {
+		_, err := txDB.Optimize()
+		return err
+	}); err == nil {
+		t.Error("expected Optimize to refuse to run inside a transaction")
+	}
+}
+
+func TestDB_Backup(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	now := time.Now()
+	for i := range 5 {
+		track := &domain.Track{ProviderID: fmt.Sprintf("bak-%d", i), Title: "Track", Status: domain.TrackStatusCompleted, CreatedAt: now, UpdatedAt: now}
+		if err := db.CreateTrack(track); err != nil {
+			t.Fatalf("CreateTrack failed: %v", err)
+		}
+	}
+
+	path := filepath.Join(t.TempDir(), "backup.db")
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+	if err := db.Backup(f, filepath.Join(t.TempDir(), "snapshot.db")); err != nil {
+		t.Fatalf("Backup failed: %v", err)
+	}
+	if err := f.Close(); err != nil {
+		t.Fatalf("Close failed: %v", err)
+	}
+
+	restored, err := NewSQLiteDB(path)
+	if err != nil {
+		t.Fatalf("backup does not open as a database: %v", err)
+	}
+	defer func() { _ = restored.Close() }()
+
+	var integrity string
+	if err := restored.Get(&integrity, `PRAGMA integrity_check`); err != nil || integrity != "ok" {
+		t.Fatalf("integrity_check = %q, %v", integrity, err)
+	}
+	count, err := restored.CountTracksByStatus(domain.TrackStatusCompleted)
+	if err != nil {
+		t.Fatalf("CountTracksByStatus failed: %v", err)
+	}
+	if count != 5 {
+		t.Errorf("backup has %d tracks, want 5", count)
+	}
+}
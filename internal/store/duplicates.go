@@ -0,0 +1,96 @@
+package store
+
+import (
+	"github.com/cesargomez89/navidrums/internal/domain"
+)
+
+// Duplicate kinds, naming what a DuplicateGroup's tracks share.
+const (
+	DuplicateByISRC  = "isrc"
+	DuplicateByTitle = "title"
+)
+
+// DuplicateGroup is a set of downloaded tracks that look like copies of one recording.
+type DuplicateGroup struct {
+	Kind   string          `json:"kind"`
+	Key    string          `json:"key"`
+	Tracks []*domain.Track `json:"tracks"`
+}
+
+// titleKey folds case and surrounding spaces so "Song " by "artist" matches "song" by
+// "Artist". The unit separator keeps "a b"+"c" apart from "a"+"b c".
+const titleKey = `lower(trim(title)) || char(31) || lower(trim(artist)) || char(31) || lower(trim(album))`
+
+type duplicateRow struct {
+	domain.Track
+	DuplicateKey string `db:"duplicate_key"`
+}
+
+// ListDuplicateTracks groups the completed tracks sharing an ISRC, then those sharing a
+// title, artist and album. A title group whose tracks all share an ISRC is left out, as
+// the ISRC group already lists them.
+func (db *DB) ListDuplicateTracks() ([]DuplicateGroup, error) {
+	byISRC, err := db.duplicateGroups(DuplicateByISRC, `isrc`, `isrc != ''`)
+	if err != nil {
+		return nil, err
+	}
+	byTitle, err := db.duplicateGroups(DuplicateByTitle, titleKey, `title != ''`)
+	if err != nil {
+		return nil, err
+	}
+
+	isrcGroup := make(map[int]int, len(byISRC))
+	for i, g := range byISRC {
+		for _, t := range g.Tracks {
+			isrcGroup[t.ID] = i
+		}
+	}
+	groups := byISRC
+	for _, g := range byTitle {
+		if !sameGroup(g.Tracks, isrcGroup) {
+			groups = append(groups, g)
+		}
+	}
+	return groups, nil
+}
+
+// duplicateGroups returns the groups of completed tracks with the same key expression,
+// among the tracks where is true.
+func (db *DB) duplicateGroups(kind, key, where string) ([]DuplicateGroup, error) {
+	query := `SELECT *, ` + key + ` AS duplicate_key FROM tracks
+		WHERE status = ? AND deleted_at IS NULL AND ` + where + ` AND ` + key + ` IN (
+			SELECT ` + key + ` FROM tracks
+			WHERE status = ? AND deleted_at IS NULL AND ` + where + `
+			GROUP BY ` + key + ` HAVING COUNT(*) > 1
+		)
+		ORDER BY duplicate_key, id`
+	var rows []duplicateRow
+	if err := db.Select(&rows, query, domain.TrackStatusCompleted, domain.TrackStatusCompleted); err != nil {
+		return nil, err
+	}
+
+	var groups []DuplicateGroup
+	for i := range rows {
+		row := &rows[i]
+		if len(groups) == 0 || groups[len(groups)-1].Key != row.DuplicateKey {
+			groups = append(groups, DuplicateGroup{Kind: kind, Key: row.DuplicateKey})
+		}
+		last := &groups[len(groups)-1]
+		last.Tracks = append(last.Tracks, &row.Track)
+	}
+	return groups, nil
+}
+
+// sameGroup reports whether every track belongs to one of the groups indexed by track ID.
+func sameGroup(tracks []*domain.Track, groupOf map[int]int) bool {
+	first, ok := groupOf[tracks[0].ID]
+	if !ok {
+		return false
+	}
+	for _, t := range tracks[1:] {
+		if g, ok := groupOf[t.ID]; !ok || g != first {
+			return false
+		}
+	}
+	return true
+}
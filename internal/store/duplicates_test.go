@@ -0,0 +1,74 @@
+package store
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/cesargomez89/navidrums/internal/domain"
+)
+
+func TestDB_ListDuplicateTracks(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	completed := func(providerID, title, artist, album, isrc string) *domain.Track {
+		return &domain.Track{
+			ProviderID: providerID, Title: title, Artist: artist, Album: album, ISRC: isrc,
+			FilePath: "/music/" + providerID + ".flac", Status: domain.TrackStatusCompleted,
+			CreatedAt: time.Now(), UpdatedAt: time.Now(),
+		}
+	}
+	deleted := completed("deleted", "Song", "Artist", "Album", "USABC1111111")
+	now := time.Now()
+	deleted.DeletedAt = &now
+	queued := completed("queued", "Other", "Artist", "Album", "")
+	queued.Status = domain.TrackStatusQueued
+
+	tracks := []*domain.Track{
+		// Same ISRC, also same title: reported once, as an ISRC group.
+		completed("isrc_a", "Song", "Artist", "Album", "USABC1111111"),
+		completed("isrc_b", "Song", "Artist", "Album", "USABC1111111"),
+		deleted,
+		// Same title, artist and album despite case and spaces; no ISRC.
+		completed("title_a", "Other", "Artist", "Album", ""),
+		completed("title_b", " other", "ARTIST", "album ", ""),
+		queued,
+		// Same title on another album, and a unique track.
+		completed("live", "Other", "Artist", "Live", ""),
+		completed("unique", "Unique", "Artist", "Album", "USABC2222222"),
+	}
+	for _, tr := range tracks {
+		if err := db.CreateTrack(tr); err != nil {
+			t.Fatalf("CreateTrack failed: %v", err)
+		}
+	}
+
+	groups, err := db.ListDuplicateTracks()
+	if err != nil {
+		t.Fatalf("ListDuplicateTracks failed: %v", err)
+	}
+
+	type group struct {
+		kind string
+		ids  []string
+	}
+	var got []group
+	for _, g := range groups {
+		var ids []string
+		for _, tr := range g.Tracks {
+			ids = append(ids, tr.ProviderID)
+			if tr.FilePath == "" {
+				t.Errorf("track %s has no file path", tr.ProviderID)
+			}
+		}
+		got = append(got, group{g.Kind, ids})
+	}
+	want := []group{
+		{DuplicateByISRC, []string{"isrc_a", "isrc_b"}},
+		{DuplicateByTitle, []string{"title_a", "title_b"}},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("ListDuplicateTracks() = %+v, want %+v", got, want)
+	}
+}
@@ -2,22 +2,25 @@ package store
 
 import (
 	"database/sql"
+	"errors"
 	"fmt"
+	"strings"
 	"time"
 
+	"github.com/cesargomez89/navidrums/internal/constants"
 	"github.com/cesargomez89/navidrums/internal/domain"
 )
 
 func (db *DB) CreateJob(job *domain.Job) error {
-	query := `INSERT OR IGNORE INTO jobs (id, type, status, progress, source_id, parent_job_id, created_at, updated_at)
-		VALUES (:id, :type, :status, :progress, :source_id, :parent_job_id, :created_at, :updated_at)`
+	query := `INSERT OR IGNORE INTO jobs (id, type, status, progress, source_id, parent_job_id, min_duration, track_ids, quality, request_id, created_at, updated_at)
+		VALUES (:id, :type, :status, :progress, :source_id, :parent_job_id, :min_duration, :track_ids, :quality, :request_id, :created_at, :updated_at)`
 
 	_, err := db.NamedExec(query, job)
 	return err
 }
 
 func (db *DB) GetJob(id string) (*domain.Job, error) {
-	query := `SELECT id, type, status, progress, source_id, parent_job_id, created_at, updated_at, error FROM jobs WHERE id = ?`
+	query := `SELECT id, type, status, progress, source_id, parent_job_id, min_duration, track_ids, quality, request_id, created_at, updated_at, error FROM jobs WHERE id = ?`
 
 	job := &domain.Job{}
 	err := db.Get(job, query, id)
@@ -46,7 +49,7 @@ func (db *DB) ClearJobError(id string) error {
 }
 
 func (db *DB) ListJobs(limit int) ([]*domain.Job, error) {
-	query := `SELECT id, type, status, progress, source_id, parent_job_id, created_at, updated_at, error FROM jobs ORDER BY created_at DESC LIMIT ?`
+	query := `SELECT id, type, status, progress, source_id, parent_job_id, min_duration, track_ids, quality, request_id, created_at, updated_at, error FROM jobs ORDER BY created_at DESC LIMIT ?`
 
 	var jobs []*domain.Job
 	err := db.Select(&jobs, query, limit)
@@ -54,13 +57,52 @@ func (db *DB) ListJobs(limit int) ([]*domain.Job, error) {
 }
 
 func (db *DB) ListActiveJobs(offset, limit int) ([]*domain.Job, error) {
-	query := `SELECT id, type, status, progress, source_id, parent_job_id, created_at, updated_at FROM jobs WHERE status IN (?, ?) ORDER BY created_at ASC LIMIT ? OFFSET ?`
+	query := `SELECT id, type, status, progress, source_id, parent_job_id, min_duration, track_ids, quality, request_id, created_at, updated_at FROM jobs WHERE status IN (?, ?) ORDER BY created_at ASC LIMIT ? OFFSET ?`
 
 	var jobs []*domain.Job
 	err := db.Select(&jobs, query, domain.JobStatusQueued, domain.JobStatusRunning, limit, offset)
 	return jobs, err
 }
 
+// ListQueuedJobs lists up to limit queued jobs, oldest first, whose type is in types, or
+// with exclude set, whose type is not.
+func (db *DB) ListQueuedJobs(types []domain.JobType, exclude bool, limit int) ([]*domain.Job, error) {
+	typeClause, args := jobTypeClause(types, exclude)
+	query := `SELECT id, type, status, progress, source_id, parent_job_id, min_duration, track_ids, quality, request_id, created_at, updated_at FROM jobs WHERE status = ? AND ` + typeClause + ` ORDER BY created_at ASC LIMIT ?`
+
+	var jobs []*domain.Job
+	err := db.Select(&jobs, query, append(append([]interface{}{domain.JobStatusQueued}, args...), limit)...)
+	return jobs, err
+}
+
+// CountRunningJobs counts running jobs whose type is in types, or with exclude set, whose
+// type is not.
+func (db *DB) CountRunningJobs(types []domain.JobType, exclude bool) (int, error) {
+	typeClause, args := jobTypeClause(types, exclude)
+	query := `SELECT COUNT(*) FROM jobs WHERE status = ? AND ` + typeClause
+	var count int
+	err := db.Get(&count, query, append([]interface{}{domain.JobStatusRunning}, args...)...)
+	return count, err
+}
+
+func jobTypeClause(types []domain.JobType, exclude bool) (string, []interface{}) {
+	if len(types) == 0 {
+		if exclude {
+			return "1 = 1", nil
+		}
+		return "1 = 0", nil
+	}
+	args := make([]interface{}, len(types))
+	for i, t := range types {
+		args[i] = t
+	}
+	op := "IN"
+	if exclude {
+		op = "NOT IN"
+	}
+	return "type " + op + " (?" + strings.Repeat(", ?", len(types)-1) + ")", args
+}
+
 func (db *DB) CountActiveJobs() (int, error) {
 	query := `SELECT COUNT(*) FROM jobs WHERE status IN (?, ?)`
 	var count int
@@ -69,7 +111,7 @@ func (db *DB) CountActiveJobs() (int, error) {
 }
 
 func (db *DB) ListFinishedJobs(offset, limit int) ([]*domain.Job, error) {
-	query := `SELECT id, type, status, progress, source_id, parent_job_id, created_at, updated_at, error FROM jobs WHERE status IN (?, ?, ?) ORDER BY updated_at DESC LIMIT ? OFFSET ?`
+	query := `SELECT id, type, status, progress, source_id, parent_job_id, min_duration, track_ids, quality, request_id, created_at, updated_at, error FROM jobs WHERE status IN (?, ?, ?) ORDER BY updated_at DESC LIMIT ? OFFSET ?`
 
 	var jobs []*domain.Job
 	err := db.Select(&jobs, query, domain.JobStatusCompleted, domain.JobStatusFailed, domain.JobStatusCancelled, limit, offset)
@@ -84,7 +126,7 @@ func (db *DB) CountFinishedJobs() (int, error) {
 }
 
 func (db *DB) GetActiveJobBySourceID(sourceID string, jobType domain.JobType) (*domain.Job, error) {
-	query := `SELECT id, type, status, progress, source_id, parent_job_id, created_at, updated_at 
+	query := `SELECT id, type, status, progress, source_id, parent_job_id, min_duration, track_ids, quality, request_id, created_at, updated_at 
 		FROM jobs 
 		WHERE source_id = ? AND type = ? AND status IN (?, ?)
 		LIMIT 1`
@@ -100,10 +142,29 @@ func (db *DB) GetActiveJobBySourceID(sourceID string, jobType domain.JobType) (*
 	return job, nil
 }
 
+// IsTrackActive reports whether a download or quality upgrade of the track is queued or running.
 func (db *DB) IsTrackActive(providerID string) (bool, error) {
-	query := `SELECT COUNT(*) FROM jobs WHERE source_id = ? AND type = ? AND status IN (?, ?)`
+	query := `SELECT COUNT(*) FROM jobs WHERE source_id = ? AND type IN (?, ?) AND status IN (?, ?)`
 	var count int
-	err := db.Get(&count, query, providerID, domain.JobTypeTrack, domain.JobStatusQueued, domain.JobStatusRunning)
+	err := db.Get(&count, query, providerID, domain.JobTypeTrack, domain.JobTypeUpgrade, domain.JobStatusQueued, domain.JobStatusRunning)
+	return count > 0, err
+}
+
+// HasCompletedJob reports whether a job of the type for the source completed, as long as
+// it is still in the history.
+func (db *DB) HasCompletedJob(sourceID string, jobType domain.JobType) (bool, error) {
+	query := `SELECT COUNT(*) FROM jobs WHERE source_id = ? AND type = ? AND status = ?`
+	var count int
+	err := db.Get(&count, query, sourceID, jobType, domain.JobStatusCompleted)
+	return count > 0, err
+}
+
+// HasCompletedUpgrade reports whether a quality upgrade of the track to quality completed,
+// as long as it is still in the history.
+func (db *DB) HasCompletedUpgrade(providerID, quality string) (bool, error) {
+	query := `SELECT COUNT(*) FROM jobs WHERE source_id = ? AND type = ? AND quality = ? AND status = ?`
+	var count int
+	err := db.Get(&count, query, providerID, domain.JobTypeUpgrade, quality, domain.JobStatusCompleted)
 	return count > 0, err
 }
 
@@ -115,10 +176,84 @@ func (db *DB) ResetStuckJobs() error {
 
 func (db *DB) ClearFinishedJobs() error {
 	query := `DELETE FROM jobs WHERE status IN (?, ?, ?)`
-	_, err := db.Exec(query, domain.JobStatusCompleted, domain.JobStatusFailed, domain.JobStatusCancelled)
+	if _, err := db.Exec(query, domain.JobStatusCompleted, domain.JobStatusFailed, domain.JobStatusCancelled); err != nil {
+		return err
+	}
+	if _, err := db.Exec(`DELETE FROM job_events WHERE job_id NOT IN (SELECT id FROM jobs)`); err != nil {
+		return err
+	}
+	_, err := db.Exec(`DELETE FROM job_duplicate_tracks WHERE job_id NOT IN (SELECT id FROM jobs)`)
+	return err
+}
+
+// AddJobDuplicateTrack records that the container job skipped the catalog track
+// providerID because the library already holds it as track trackID.
+func (db *DB) AddJobDuplicateTrack(jobID, providerID string, trackID int) error {
+	_, err := db.Exec(`INSERT OR REPLACE INTO job_duplicate_tracks (job_id, provider_id, track_id) VALUES (?, ?, ?)`,
+		jobID, providerID, trackID)
+	return err
+}
+
+// ListJobDuplicateTracks returns the existing tracks standing in for the catalog tracks the
+// job skipped as duplicates, keyed by the catalog track ID. Links to tracks deleted since
+// are left out.
+func (db *DB) ListJobDuplicateTracks(jobID string) (map[string]*domain.Track, error) {
+	var links []struct {
+		ProviderID string `db:"provider_id"`
+		TrackID    int    `db:"track_id"`
+	}
+	if err := db.Select(&links, `SELECT provider_id, track_id FROM job_duplicate_tracks WHERE job_id = ? ORDER BY rowid`, jobID); err != nil {
+		return nil, err
+	}
+	duplicates := make(map[string]*domain.Track, len(links))
+	for _, l := range links {
+		track, err := db.GetTrackByID(l.TrackID)
+		if errors.Is(err, sql.ErrNoRows) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		duplicates[l.ProviderID] = track
+	}
+	return duplicates, nil
+}
+
+// AddJobEvent appends an entry to the job's log and drops the oldest ones past
+// constants.MaxJobEvents, so a long-running job's log stays bounded while it runs.
+func (db *DB) AddJobEvent(jobID string, level domain.JobEventLevel, message string) error {
+	if _, err := db.Exec(`INSERT INTO job_events (job_id, level, message, created_at) VALUES (?, ?, ?, ?)`,
+		jobID, level, message, time.Now()); err != nil {
+		return err
+	}
+	return db.PruneJobEvents(jobID)
+}
+
+// PruneJobEvents drops the job's oldest events so it keeps at most constants.MaxJobEvents.
+func (db *DB) PruneJobEvents(jobID string) error {
+	_, err := db.Exec(`
+		DELETE FROM job_events
+		WHERE job_id = ? AND id NOT IN (
+			SELECT id FROM job_events WHERE job_id = ? ORDER BY id DESC LIMIT ?
+		)`, jobID, jobID, constants.MaxJobEvents)
 	return err
 }
 
+// ListJobEvents returns the job's log, oldest first.
+func (db *DB) ListJobEvents(jobID string) ([]*domain.JobEvent, error) {
+	var events []*domain.JobEvent
+	err := db.Select(&events, `SELECT id, job_id, level, message, created_at FROM job_events WHERE job_id = ? ORDER BY id ASC`, jobID)
+	return events, err
+}
+
+// ListJobEventsAfter returns the job's events newer than the one with ID afterID, oldest
+// first.
+func (db *DB) ListJobEventsAfter(jobID string, afterID int) ([]*domain.JobEvent, error) {
+	var events []*domain.JobEvent
+	err := db.Select(&events, `SELECT id, job_id, level, message, created_at FROM job_events WHERE job_id = ? AND id > ? ORDER BY id ASC`, jobID, afterID)
+	return events, err
+}
+
 type JobStats struct {
 	Total     int `db:"total"`
 	Completed int `db:"completed"`
@@ -142,16 +277,40 @@ func (db *DB) GetJobStats() (*JobStats, error) {
 	return stats, err
 }
 
+// CountJobsCompletedSince counts the jobs that completed at or after since.
+func (db *DB) CountJobsCompletedSince(since time.Time) (int, error) {
+	var count int
+	err := db.Get(&count, `SELECT COUNT(*) FROM jobs WHERE status = ? AND updated_at >= ?`, domain.JobStatusCompleted, since)
+	return count, err
+}
+
+// CountJobsByStatus returns the number of jobs in each status.
+func (db *DB) CountJobsByStatus() (map[string]int, error) {
+	var rows []struct {
+		Status string `db:"status"`
+		Count  int    `db:"count"`
+	}
+	if err := db.Select(&rows, `SELECT status, COUNT(*) as count FROM jobs GROUP BY status`); err != nil {
+		return nil, err
+	}
+	counts := make(map[string]int, len(rows))
+	for _, r := range rows {
+		counts[r.Status] = r.Count
+	}
+	return counts, nil
+}
+
 func (db *DB) CountJobsForParent(parentID string) (total int, pending int, err error) {
 	row := db.QueryRow(`SELECT COUNT(*) FROM jobs WHERE parent_job_id = ?`, parentID)
 	if err := row.Scan(&total); err != nil {
 		return 0, 0, err
 	}
 
+	// A decomposed child (an album of a discography) is still waiting on its own children.
 	row = db.QueryRow(`
 		SELECT COUNT(*) FROM jobs 
-		WHERE parent_job_id = ? AND status IN (?, ?)`,
-		parentID, domain.JobStatusQueued, domain.JobStatusRunning)
+		WHERE parent_job_id = ? AND status IN (?, ?, ?)`,
+		parentID, domain.JobStatusQueued, domain.JobStatusRunning, domain.JobStatusDecomposed)
 	if err := row.Scan(&pending); err != nil {
 		return 0, 0, err
 	}
@@ -164,36 +323,36 @@ func (db *DB) UpdateJobProgress(id string, progress float64) error {
 	return err
 }
 
-// CreateJobBatch creates multiple jobs in a single transaction.
-// It uses an all-or-nothing approach: if any insertion fails (besides IGNORE), the whole batch is rolled back.
+// CreateJobBatch creates multiple jobs in a single transaction, or in the caller's
+// transaction when db is one. It uses an all-or-nothing approach: if any insertion fails
+// (besides IGNORE), the whole batch is rolled back.
 func (db *DB) CreateJobBatch(jobs []*domain.Job) error {
-	tx, err := db.root.Beginx()
-	if err != nil {
-		return fmt.Errorf("failed to begin transaction: %w", err)
-	}
-	defer tx.Rollback() //nolint:errcheck // rollback is best-effort
-
-	query := `INSERT OR IGNORE INTO jobs (id, type, status, progress, source_id, parent_job_id, created_at, updated_at)
-		VALUES (:id, :type, :status, :progress, :source_id, :parent_job_id, :created_at, :updated_at)`
-
-	for _, job := range jobs {
-		if job.CreatedAt.IsZero() {
-			job.CreatedAt = time.Now()
+	query := `INSERT OR IGNORE INTO jobs (id, type, status, progress, source_id, parent_job_id, min_duration, track_ids, quality, request_id, created_at, updated_at)
+		VALUES (:id, :type, :status, :progress, :source_id, :parent_job_id, :min_duration, :track_ids, :quality, :request_id, :created_at, :updated_at)`
+
+	return db.RunInTx(func(txDB *DB) error {
+		for _, job := range jobs {
+			if job.CreatedAt.IsZero() {
+				job.CreatedAt = time.Now()
+			}
+			if job.UpdatedAt.IsZero() {
+				job.UpdatedAt = time.Now()
+			}
+
+			if _, err := txDB.NamedExec(query, job); err != nil {
+				return fmt.Errorf("failed to create job %s: %w", job.ID, err)
+			}
 		}
-		if job.UpdatedAt.IsZero() {
-			job.UpdatedAt = time.Now()
-		}
-
-		if _, err := tx.NamedExec(query, job); err != nil {
-			return fmt.Errorf("failed to create job %s: %w", job.ID, err)
-		}
-	}
+		return nil
+	})
+}
 
-	if err := tx.Commit(); err != nil {
-		return fmt.Errorf("failed to commit transaction: %w", err)
-	}
+func (db *DB) ListJobsByParentID(parentID string) ([]*domain.Job, error) {
+	query := `SELECT id, type, status, progress, source_id, parent_job_id, min_duration, track_ids, quality, request_id, created_at, updated_at, error FROM jobs WHERE parent_job_id = ? ORDER BY created_at ASC`
 
-	return nil
+	var jobs []*domain.Job
+	err := db.Select(&jobs, query, parentID)
+	return jobs, err
 }
 
 func (db *DB) CancelJobsByParentID(parentID string) error {
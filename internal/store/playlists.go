@@ -83,7 +83,7 @@ func (db *DB) GetTracksByPlaylistID(playlistID int64) ([]*domain.Track, error) {
 	query := `
 		SELECT t.* FROM tracks t
 		INNER JOIN playlist_tracks pt ON t.id = pt.track_id
-		WHERE pt.playlist_id = ?
+		WHERE pt.playlist_id = ? AND t.deleted_at IS NULL
 		ORDER BY pt.position ASC`
 	return selectTracks(db, query, playlistID)
 }
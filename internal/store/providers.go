@@ -1,12 +1,58 @@
 package store
 
+import (
+	"database/sql/driver"
+	"encoding/json"
+)
+
 // ProviderRecord represents a music provider stored in the database for fallback support.
 type ProviderRecord struct {
-	ID       int64  `json:"id"`
-	Type     string `json:"type"`
-	Position int    `json:"position"`
-	URL      string `json:"url"`
-	Name     string `json:"name"`
+	ID       int64           `json:"id"`
+	Type     string          `json:"type"`
+	Position int             `json:"position"`
+	URL      string          `json:"url"`
+	Name     string          `json:"name"`
+	Headers  ProviderHeaders `json:"headers,omitempty"`
+}
+
+// ProviderHeaders are extra HTTP headers, such as an API key, sent with every request to
+// a provider's host. They are stored as a JSON object.
+type ProviderHeaders map[string]string
+
+func (h ProviderHeaders) Value() (driver.Value, error) {
+	if len(h) == 0 {
+		return "", nil
+	}
+	data, err := json.Marshal(h)
+	return string(data), err
+}
+
+func (h *ProviderHeaders) Scan(value interface{}) error {
+	var data []byte
+	switch v := value.(type) {
+	case []byte:
+		data = v
+	case string:
+		data = []byte(v)
+	}
+	if len(data) == 0 {
+		*h = nil
+		return nil
+	}
+	return json.Unmarshal(data, h)
+}
+
+// Masked returns a copy with every value hidden, for showing which headers are set
+// without exposing credentials.
+func (h ProviderHeaders) Masked() ProviderHeaders {
+	if len(h) == 0 {
+		return nil
+	}
+	masked := make(ProviderHeaders, len(h))
+	for name := range h {
+		masked[name] = "********"
+	}
+	return masked
 }
 
 type ProvidersRepo struct {
@@ -18,6 +64,12 @@ func NewProvidersRepo(db *DB) *ProvidersRepo {
 }
 
 func (r *ProvidersRepo) Create(providerType, url, name string) (int64, error) {
+	return r.CreateWithHeaders(providerType, url, name, nil)
+}
+
+// CreateWithHeaders adds a provider at the end of its type's fallback order together
+// with its request headers, so a provider is never saved without the API key it needs.
+func (r *ProvidersRepo) CreateWithHeaders(providerType, url, name string, headers ProviderHeaders) (int64, error) {
 	var id int64
 	err := r.db.RunInTx(func(txDB *DB) error {
 		var maxPos int
@@ -25,8 +77,8 @@ func (r *ProvidersRepo) Create(providerType, url, name string) (int64, error) {
 		if err != nil {
 			return err
 		}
-		query := `INSERT INTO providers (type, url, name, position) VALUES (?, ?, ?, ?) RETURNING id`
-		row := txDB.QueryRowx(query, providerType, url, name, maxPos+1)
+		query := `INSERT INTO providers (type, url, name, position, headers) VALUES (?, ?, ?, ?, ?) RETURNING id`
+		row := txDB.QueryRowx(query, providerType, url, name, maxPos+1, headers)
 		return row.Scan(&id)
 	})
 	return id, err
@@ -39,13 +91,13 @@ func (r *ProvidersRepo) Delete(id int64) error {
 
 func (r *ProvidersRepo) ListByType(providerType string) ([]ProviderRecord, error) {
 	var providers []ProviderRecord
-	query := `SELECT id, type, url, name, position FROM providers WHERE type = ? ORDER BY position ASC`
+	query := `SELECT id, type, url, name, position, headers FROM providers WHERE type = ? ORDER BY position ASC`
 	err := r.db.Select(&providers, query, providerType)
 	return providers, err
 }
 
 func (r *ProvidersRepo) GetByPosition(providerType string, pos int) (*ProviderRecord, error) {
-	query := `SELECT id, type, url, name, position FROM providers WHERE type = ? AND position = ?`
+	query := `SELECT id, type, url, name, position, headers FROM providers WHERE type = ? AND position = ?`
 	var provider ProviderRecord
 	err := r.db.Get(&provider, query, providerType, pos)
 	if err != nil {
@@ -84,6 +136,15 @@ func (r *ProvidersRepo) Update(id int64, url, name string) error {
 	return checkRowsAffected(result, "provider", id)
 }
 
+// SetHeaders replaces the custom headers sent to a provider.
+func (r *ProvidersRepo) SetHeaders(id int64, headers ProviderHeaders) error {
+	result, err := r.db.Exec(`UPDATE providers SET headers = ? WHERE id = ?`, headers, id)
+	if err != nil {
+		return err
+	}
+	return checkRowsAffected(result, "provider", id)
+}
+
 func (r *ProvidersRepo) Exists(url string) bool {
 	var count int
 	query := `SELECT COUNT(*) FROM providers WHERE url = ?`
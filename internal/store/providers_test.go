@@ -407,3 +407,59 @@ func TestProvidersRepo_Reorder_Empty(t *testing.T) {
 		t.Fatalf("Reorder with empty slice should not error: %v", err)
 	}
 }
+
+func TestProvidersRepo_SetHeaders(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+	repo := NewProvidersRepo(db)
+
+	id, _ := repo.Create("hifi", "http://proxy.example", "Proxy")
+	headers := ProviderHeaders{"X-Api-Key": "secret"}
+	if err := repo.SetHeaders(id, headers); err != nil {
+		t.Fatalf("SetHeaders failed: %v", err)
+	}
+
+	p, err := repo.GetByPosition("hifi", 0)
+	if err != nil {
+		t.Fatalf("GetByPosition failed: %v", err)
+	}
+	if p.Headers["X-Api-Key"] != "secret" {
+		t.Errorf("Headers = %v, want %v", p.Headers, headers)
+	}
+	if masked := p.Headers.Masked(); masked["X-Api-Key"] == "secret" {
+		t.Errorf("Masked() exposed the header value: %v", masked)
+	}
+
+	if err := repo.SetHeaders(99999, headers); err == nil {
+		t.Error("Expected error for non-existent provider")
+	}
+}
+
+func TestProvidersRepo_CreateWithHeaders(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+	repo := NewProvidersRepo(db)
+
+	id, err := repo.CreateWithHeaders("hifi", "http://keyed.example", "Keyed", ProviderHeaders{"X-API-Key": "secret"})
+	if err != nil || id == 0 {
+		t.Fatalf("CreateWithHeaders() = %d, %v", id, err)
+	}
+	p, err := repo.GetByPosition("hifi", 0)
+	if err != nil {
+		t.Fatalf("GetByPosition failed: %v", err)
+	}
+	if p.ID != id || p.Headers["X-API-Key"] != "secret" {
+		t.Errorf("stored provider = %+v, want id %d with its API key", p, id)
+	}
+
+	if id, err := repo.CreateWithHeaders("hifi", "http://keyed.example", "Again", ProviderHeaders{"X-API-Key": "other"}); err == nil || id != 0 {
+		t.Errorf("CreateWithHeaders() for a duplicate URL = %d, %v, want an error", id, err)
+	}
+	list, err := repo.ListByType("hifi")
+	if err != nil {
+		t.Fatalf("ListByType failed: %v", err)
+	}
+	if len(list) != 1 || list[0].Headers["X-API-Key"] != "secret" {
+		t.Errorf("providers after the failed create = %+v, want only the first", list)
+	}
+}
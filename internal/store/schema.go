@@ -10,7 +10,11 @@ CREATE TABLE IF NOT EXISTS jobs (
 	parent_job_id TEXT,
 	created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
 	updated_at DATETIME DEFAULT CURRENT_TIMESTAMP,
-	error TEXT
+	error TEXT,
+	min_duration INTEGER,
+	track_ids TEXT,
+	quality TEXT,
+	request_id TEXT
 );
 
 -- Prevent duplicate active jobs for same source
@@ -19,6 +23,25 @@ WHERE status IN ('queued', 'running');
 
 CREATE INDEX IF NOT EXISTS idx_jobs_status ON jobs(status);
 
+CREATE TABLE IF NOT EXISTS job_events (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	job_id TEXT NOT NULL,
+	level TEXT NOT NULL,
+	message TEXT NOT NULL,
+	created_at DATETIME DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_job_events_job_id ON job_events(job_id);
+
+-- Catalog tracks a container job skipped as ISRC duplicates, and the track already in the
+-- library that stands in for each in the job's playlist
+CREATE TABLE IF NOT EXISTS job_duplicate_tracks (
+	job_id TEXT NOT NULL,
+	provider_id TEXT NOT NULL,
+	track_id INTEGER NOT NULL,
+	PRIMARY KEY (job_id, provider_id)
+);
+
 CREATE TABLE IF NOT EXISTS playlists (
 	id INTEGER PRIMARY KEY AUTOINCREMENT,
 	provider_id TEXT UNIQUE NOT NULL,
@@ -48,6 +71,7 @@ CREATE INDEX IF NOT EXISTS idx_playlist_tracks_track ON playlist_tracks(track_id
 CREATE TABLE IF NOT EXISTS tracks (
 	id INTEGER PRIMARY KEY AUTOINCREMENT,
 	provider_id TEXT UNIQUE NOT NULL,
+	source_provider TEXT DEFAULT '',  -- provider type whose catalog provider_id belongs to
 	
 	-- Metadata
 	title TEXT NOT NULL,
@@ -59,6 +83,8 @@ CREATE TABLE IF NOT EXISTS tracks (
 	album_artists TEXT,  -- JSON array
 	artist_ids TEXT,     -- JSON array
 	album_artist_ids TEXT, -- JSON array
+	artist_sort TEXT,
+	album_artist_sort TEXT,
 	track_number INTEGER,
 	disc_number INTEGER,
 	total_tracks INTEGER,
@@ -71,6 +97,10 @@ CREATE TABLE IF NOT EXISTS tracks (
 	isrc TEXT,
 	copyright TEXT,
 	composer TEXT,
+	producer TEXT DEFAULT '',
+	engineer TEXT DEFAULT '',
+	mixer TEXT DEFAULT '',
+	performers TEXT DEFAULT '',  -- JSON array
 	duration INTEGER,
 	explicit BOOLEAN,
 	compilation BOOLEAN,
@@ -84,21 +114,29 @@ CREATE TABLE IF NOT EXISTS tracks (
 	key_scale TEXT,
 	replay_gain REAL,
 	peak REAL,
+	album_gain REAL DEFAULT 0,  -- ReplayGain over the whole album, shared by its tracks
+	album_peak REAL DEFAULT 0,
 	version TEXT,
 	description TEXT,
 	url TEXT,
 	audio_quality TEXT,
 	audio_modes TEXT,
+	target_quality TEXT DEFAULT '',
 	release_date TEXT,
+	original_date TEXT DEFAULT '',
+	original_year INTEGER DEFAULT 0,
 	barcode TEXT,
 	catalog_number TEXT,
 	release_type TEXT,
 	release_id TEXT,
 	recording_id TEXT,
+	musicbrainz_album_id TEXT,
+	release_track_id TEXT,
 	tags TEXT,  -- JSON array
 	
 	-- Processing
 	status TEXT NOT NULL DEFAULT 'missing',
+	locked BOOLEAN DEFAULT 0,  -- edited by hand: syncs re-tag from the stored metadata only
 	error TEXT,
 	parent_job_id TEXT,
 	
@@ -108,11 +146,18 @@ CREATE TABLE IF NOT EXISTS tracks (
 	file_hash TEXT,
 	etag TEXT,
 
+	-- Audio properties, read from the downloaded file
+	sample_rate INTEGER DEFAULT 0,
+	bit_depth INTEGER DEFAULT 0,
+	channels INTEGER DEFAULT 0,
+	bitrate INTEGER DEFAULT 0,
+
 	-- Timestamps
 	created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
 	updated_at DATETIME DEFAULT CURRENT_TIMESTAMP,
 	completed_at DATETIME,
 	last_verified_at DATETIME,
+	deleted_at DATETIME,
 	
 	FOREIGN KEY (parent_job_id) REFERENCES jobs(id) ON DELETE SET NULL
 );
@@ -122,6 +167,14 @@ CREATE INDEX IF NOT EXISTS idx_tracks_parent_job_id ON tracks(parent_job_id);
 CREATE INDEX IF NOT EXISTS idx_tracks_status ON tracks(status);
 CREATE INDEX IF NOT EXISTS idx_tracks_album_id ON tracks(album_id);
 CREATE INDEX IF NOT EXISTS idx_tracks_created_at ON tracks(created_at DESC);
+CREATE INDEX IF NOT EXISTS idx_tracks_completed_at ON tracks(completed_at DESC);
+CREATE INDEX IF NOT EXISTS idx_tracks_isrc ON tracks(isrc);
+
+CREATE TABLE IF NOT EXISTS album_overrides (
+	album_id TEXT PRIMARY KEY,
+	album_artist TEXT NOT NULL,
+	updated_at DATETIME DEFAULT CURRENT_TIMESTAMP
+);
 
 CREATE TABLE IF NOT EXISTS cache (
 	key TEXT PRIMARY KEY,
@@ -147,6 +200,7 @@ CREATE TABLE IF NOT EXISTS providers (
 	url TEXT UNIQUE NOT NULL,
 	name TEXT,
 	position INTEGER DEFAULT 0,
+	headers TEXT NOT NULL DEFAULT '',  -- JSON object of custom request headers
 	created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
 	updated_at DATETIME DEFAULT CURRENT_TIMESTAMP
 );
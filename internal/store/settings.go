@@ -49,4 +49,7 @@ const (
 	SettingQuality                 = "quality"
 	SettingMoodList                = "mood_list"
 	SettingLanguageList            = "language_list"
+	SettingVerifySummary           = "verify_summary"
+	SettingAPIKeys                 = "api_keys"
+	SettingSyncUpgradeSummary      = "sync_upgrade_summary"
 )
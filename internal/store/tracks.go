@@ -15,23 +15,23 @@ func (db *DB) CreateTrack(track *domain.Track) error {
 	track.Normalize()
 
 	query := `INSERT INTO tracks (
-		provider_id, title, artist, artists, album, album_id, album_artist, album_artists, path_artist, artist_ids, album_artist_ids,
+		provider_id, source_provider, title, artist, artists, album, album_id, album_artist, album_artists, path_artist, artist_ids, album_artist_ids, artist_sort, album_artist_sort,
 		track_number, disc_number, total_tracks, total_discs,
-		year, genre, mood, language, label, isrc, copyright, composer,
+		year, genre, mood, language, label, isrc, copyright, composer, producer, engineer, mixer, performers,
 		duration, explicit, compilation, album_art_url, lyrics, subtitles,
-		bpm, key_name, key_scale, replay_gain, peak, version, description, url, audio_quality, audio_modes, release_date,
-		barcode, catalog_number, release_type, release_id, recording_id, tags,
-		status, error, parent_job_id, file_path, file_extension,
-		created_at, updated_at, etag, file_hash, last_verified_at
+		bpm, key_name, key_scale, replay_gain, peak, album_gain, album_peak, version, description, url, audio_quality, audio_modes, target_quality, release_date, original_date, original_year,
+		barcode, catalog_number, release_type, release_id, recording_id, musicbrainz_album_id, release_track_id, tags,
+		status, error, parent_job_id, file_path, file_extension, sample_rate, bit_depth, channels, bitrate,
+		created_at, updated_at, etag, file_hash, last_verified_at, deleted_at
 	) VALUES (
-		:provider_id, :title, :artist, :artists, :album, :album_id, :album_artist, :album_artists, :path_artist, :artist_ids, :album_artist_ids,
+		:provider_id, :source_provider, :title, :artist, :artists, :album, :album_id, :album_artist, :album_artists, :path_artist, :artist_ids, :album_artist_ids, :artist_sort, :album_artist_sort,
 		:track_number, :disc_number, :total_tracks, :total_discs,
-		:year, :genre, :mood, :language, :label, :isrc, :copyright, :composer,
+		:year, :genre, :mood, :language, :label, :isrc, :copyright, :composer, :producer, :engineer, :mixer, :performers,
 		:duration, :explicit, :compilation, :album_art_url, :lyrics, :subtitles,
-		:bpm, :key_name, :key_scale, :replay_gain, :peak, :version, :description, :url, :audio_quality, :audio_modes, :release_date,
-		:barcode, :catalog_number, :release_type, :release_id, :recording_id, :tags,
-		:status, :error, :parent_job_id, :file_path, :file_extension,
-		:created_at, :updated_at, :etag, :file_hash, :last_verified_at
+		:bpm, :key_name, :key_scale, :replay_gain, :peak, :album_gain, :album_peak, :version, :description, :url, :audio_quality, :audio_modes, :target_quality, :release_date, :original_date, :original_year,
+		:barcode, :catalog_number, :release_type, :release_id, :recording_id, :musicbrainz_album_id, :release_track_id, :tags,
+		:status, :error, :parent_job_id, :file_path, :file_extension, :sample_rate, :bit_depth, :channels, :bitrate,
+		:created_at, :updated_at, :etag, :file_hash, :last_verified_at, :deleted_at
 	) RETURNING id`
 
 	rows, err := db.NamedQuery(query, track)
@@ -77,17 +77,22 @@ func (db *DB) UpdateTrack(track *domain.Track) error {
 	track.Normalize()
 
 	query := `UPDATE tracks SET
-		provider_id = :provider_id, title = :title, artist = :artist, artists = :artists,
+		provider_id = :provider_id, source_provider = :source_provider, title = :title, artist = :artist, artists = :artists,
 		album = :album, album_id = :album_id, album_artist = :album_artist, album_artists = :album_artists, path_artist = :path_artist,
-		artist_ids = :artist_ids, album_artist_ids = :album_artist_ids,
+		artist_ids = :artist_ids, album_artist_ids = :album_artist_ids, artist_sort = :artist_sort, album_artist_sort = :album_artist_sort,
 		track_number = :track_number, disc_number = :disc_number, total_tracks = :total_tracks, total_discs = :total_discs,
 		year = :year, genre = :genre, mood = :mood, label = :label, isrc = :isrc, copyright = :copyright, composer = :composer,
+		producer = :producer, engineer = :engineer, mixer = :mixer, performers = :performers,
 		duration = :duration, explicit = :explicit, compilation = :compilation, album_art_url = :album_art_url, lyrics = :lyrics, subtitles = :subtitles,
-		bpm = :bpm, key_name = :key_name, key_scale = :key_scale, replay_gain = :replay_gain, peak = :peak,
-		version = :version, description = :description, url = :url, audio_quality = :audio_quality, audio_modes = :audio_modes, release_date = :release_date,
-		barcode = :barcode, catalog_number = :catalog_number, release_type = :release_type, release_id = :release_id, recording_id = :recording_id, tags = :tags,
+		bpm = :bpm, key_name = :key_name, key_scale = :key_scale, replay_gain = :replay_gain, peak = :peak, album_gain = :album_gain, album_peak = :album_peak,
+		version = :version, description = :description, url = :url, audio_quality = :audio_quality, audio_modes = :audio_modes, target_quality = :target_quality, release_date = :release_date,
+		original_date = :original_date, original_year = :original_year,
+		barcode = :barcode, catalog_number = :catalog_number, release_type = :release_type, release_id = :release_id, recording_id = :recording_id,
+		musicbrainz_album_id = :musicbrainz_album_id, release_track_id = :release_track_id, tags = :tags,
 		status = :status, error = :error, parent_job_id = :parent_job_id, file_path = :file_path, file_extension = :file_extension,
-		updated_at = :updated_at, etag = :etag, file_hash = :file_hash, completed_at = :completed_at, last_verified_at = :last_verified_at
+		sample_rate = :sample_rate, bit_depth = :bit_depth, channels = :channels, bitrate = :bitrate,
+		updated_at = :updated_at, etag = :etag, file_hash = :file_hash, completed_at = :completed_at, last_verified_at = :last_verified_at,
+		deleted_at = :deleted_at
 	WHERE id = :id`
 
 	track.UpdatedAt = time.Now()
@@ -115,46 +120,53 @@ func (db *DB) UpdateTrackPartial(id int, updates map[string]interface{}) error {
 	}
 
 	allowedColumns := map[string]bool{
-		"title":            true,
-		"artist":           true,
-		"artists":          true,
-		"album":            true,
-		"album_artist":     true,
-		"album_artists":    true,
-		"artist_ids":       true,
-		"album_artist_ids": true,
-		"path_artist":      true,
-		"genre":            true,
-		"mood":             true,
-		"tags":             true,
-		"label":            true,
-		"composer":         true,
-		"copyright":        true,
-		"isrc":             true,
-		"version":          true,
-		"description":      true,
-		"url":              true,
-		"audio_quality":    true,
-		"audio_modes":      true,
-		"lyrics":           true,
-		"subtitles":        true,
-		"barcode":          true,
-		"catalog_number":   true,
-		"release_type":     true,
-		"release_date":     true,
-		"key_name":         true,
-		"key_scale":        true,
-		"track_number":     true,
-		"disc_number":      true,
-		"total_tracks":     true,
-		"total_discs":      true,
-		"year":             true,
-		"bpm":              true,
-		"replay_gain":      true,
-		"peak":             true,
-		"compilation":      true,
-		"explicit":         true,
-		"language":         true,
+		"title":             true,
+		"artist":            true,
+		"artists":           true,
+		"album":             true,
+		"album_artist":      true,
+		"album_artists":     true,
+		"artist_ids":        true,
+		"album_artist_ids":  true,
+		"path_artist":       true,
+		"artist_sort":       true,
+		"album_artist_sort": true,
+		"genre":             true,
+		"mood":              true,
+		"tags":              true,
+		"label":             true,
+		"composer":          true,
+		"copyright":         true,
+		"isrc":              true,
+		"version":           true,
+		"description":       true,
+		"url":               true,
+		"audio_quality":     true,
+		"audio_modes":       true,
+		"lyrics":            true,
+		"subtitles":         true,
+		"barcode":           true,
+		"catalog_number":    true,
+		"release_type":      true,
+		"release_date":      true,
+		"original_date":     true,
+		"original_year":     true,
+		"key_name":          true,
+		"key_scale":         true,
+		"track_number":      true,
+		"disc_number":       true,
+		"total_tracks":      true,
+		"total_discs":       true,
+		"year":              true,
+		"bpm":               true,
+		"replay_gain":       true,
+		"peak":              true,
+		"album_gain":        true,
+		"album_peak":        true,
+		"compilation":       true,
+		"explicit":          true,
+		"language":          true,
+		"locked":            true,
 	}
 
 	setClauses := make([]string, 0, len(updates))
@@ -208,16 +220,76 @@ func (db *DB) MarkTrackFailed(id int, errorMsg string) error {
 	return checkRowsAffected(result, "track", id)
 }
 
+// MarkTrackUnavailable flags a track its provider can't stream, such as a region-locked
+// one. Unlike a failed track it is not picked up when failed tracks are retried.
+func (db *DB) MarkTrackUnavailable(id int, errorMsg string) error {
+	query := `UPDATE tracks SET status = ?, error = ?, updated_at = ? WHERE id = ?`
+	result, err := db.Exec(query, domain.TrackStatusUnavailable, errorMsg, time.Now(), id)
+	if err != nil {
+		return err
+	}
+	return checkRowsAffected(result, "track", id)
+}
+
+// MarkTrackSkipped flags a track that wasn't downloaded because the Navidrome library
+// already has it.
+func (db *DB) MarkTrackSkipped(id int, reason string) error {
+	query := `UPDATE tracks SET status = ?, error = ?, updated_at = ? WHERE id = ?`
+	result, err := db.Exec(query, domain.TrackStatusSkipped, reason, time.Now(), id)
+	if err != nil {
+		return err
+	}
+	return checkRowsAffected(result, "track", id)
+}
+
+// RequeueTrack returns a track to the queued state and clears its error, for a retried job.
+func (db *DB) RequeueTrack(id int) error {
+	query := `UPDATE tracks SET status = ?, error = '', updated_at = ? WHERE id = ?`
+	result, err := db.Exec(query, domain.TrackStatusQueued, time.Now(), id)
+	if err != nil {
+		return err
+	}
+	return checkRowsAffected(result, "track", id)
+}
+
+// MarkTrackMissingFile flags a completed track whose file is gone from disk.
+// The row is kept so the track can be re-downloaded.
+func (db *DB) MarkTrackMissingFile(id int) error {
+	query := `UPDATE tracks SET status = ?, error = ?, updated_at = ? WHERE id = ?`
+	result, err := db.Exec(query, domain.TrackStatusMissingFile, "File missing on disk", time.Now(), id)
+	if err != nil {
+		return err
+	}
+	return checkRowsAffected(result, "track", id)
+}
+
+// MarkTrackVerified records the outcome of an integrity check on a track's file.
+func (db *DB) MarkTrackVerified(id int, status domain.TrackStatus, fileHash, errorMsg string) error {
+	query := `UPDATE tracks SET status = ?, file_hash = ?, error = ?, last_verified_at = ?, updated_at = ? WHERE id = ?`
+	now := time.Now()
+	result, err := db.Exec(query, status, fileHash, errorMsg, now, now, id)
+	if err != nil {
+		return err
+	}
+	return checkRowsAffected(result, "track", id)
+}
+
 func (db *DB) ListTracks(limit int) ([]*domain.Track, error) {
 	query := `SELECT * FROM tracks ORDER BY created_at DESC LIMIT ?`
 	return selectTracks(db, query, limit)
 }
 
-func (db *DB) ListTracksByStatus(status domain.TrackStatus, offset, limit int) ([]*domain.Track, error) {
-	query := `SELECT * FROM tracks WHERE status = ? ORDER BY completed_at DESC LIMIT ? OFFSET ?`
+func (db *DB) ListTracksByStatus(status domain.TrackStatus, sort TrackSort, offset, limit int) ([]*domain.Track, error) {
+	query := `SELECT * FROM tracks WHERE status = ? AND deleted_at IS NULL ORDER BY ` + sort.orderBy("completed_at DESC") + ` LIMIT ? OFFSET ?`
 	return selectTracks(db, query, status, limit, offset)
 }
 
+func (db *DB) CountTracksByStatus(status domain.TrackStatus) (int, error) {
+	var count int
+	err := db.Get(&count, `SELECT COUNT(*) FROM tracks WHERE status = ? AND deleted_at IS NULL`, status)
+	return count, err
+}
+
 func (db *DB) ListTracksByParentJobID(parentJobID string) ([]*domain.Track, error) {
 	query := `SELECT * FROM tracks WHERE parent_job_id = ? ORDER BY track_number ASC`
 	return selectTracks(db, query, parentJobID)
@@ -230,57 +302,160 @@ func (db *DB) CountPendingTracksByParentJobID(parentJobID string) (int, error) {
 	return count, err
 }
 
-func (db *DB) ListCompletedTracks(offset, limit int) ([]*domain.Track, error) {
-	return db.ListTracksByStatus(domain.TrackStatusCompleted, offset, limit)
+func (db *DB) ListCompletedTracks(sort TrackSort, offset, limit int) ([]*domain.Track, error) {
+	return db.ListTracksByStatus(domain.TrackStatusCompleted, sort, offset, limit)
 }
 
 func (db *DB) CountCompletedTracks() (int, error) {
-	query := `SELECT COUNT(*) FROM tracks WHERE status = ?`
+	query := `SELECT COUNT(*) FROM tracks WHERE status = ? AND deleted_at IS NULL`
 	var count int
 	err := db.Get(&count, query, domain.TrackStatusCompleted)
 	return count, err
 }
 
-func (db *DB) SearchTracks(q string, offset, limit int) ([]*domain.Track, error) {
-	query := `SELECT * FROM tracks WHERE title LIKE ? OR artist LIKE ? OR album LIKE ? OR genre LIKE ? ORDER BY completed_at DESC LIMIT ? OFFSET ?`
+func (db *DB) SearchTracks(q string, sort TrackSort, offset, limit int) ([]*domain.Track, error) {
+	query := `SELECT * FROM tracks WHERE (title LIKE ? OR artist LIKE ? OR album LIKE ? OR genre LIKE ?) AND deleted_at IS NULL ORDER BY ` + sort.orderBy("completed_at DESC") + ` LIMIT ? OFFSET ?`
 	searchTerm := "%" + q + "%"
 	return selectTracks(db, query, searchTerm, searchTerm, searchTerm, searchTerm, limit, offset)
 }
 
 func (db *DB) CountSearchTracks(q string) (int, error) {
-	query := `SELECT COUNT(*) FROM tracks WHERE title LIKE ? OR artist LIKE ? OR album LIKE ? OR genre LIKE ?`
+	query := `SELECT COUNT(*) FROM tracks WHERE (title LIKE ? OR artist LIKE ? OR album LIKE ? OR genre LIKE ?) AND deleted_at IS NULL`
 	searchTerm := "%" + q + "%"
 	var count int
 	err := db.Get(&count, query, searchTerm, searchTerm, searchTerm, searchTerm)
 	return count, err
 }
 
-func (db *DB) ListCompletedTracksNoGenre(offset, limit int) ([]*domain.Track, error) {
-	query := `SELECT * FROM tracks WHERE status = ? AND (genre IS NULL OR TRIM(genre) = '') ORDER BY completed_at DESC LIMIT ? OFFSET ?`
+// TrackFilter narrows the completed tracks listed on the downloads page. Empty fields
+// match every track.
+type TrackFilter struct {
+	Search         string
+	AudioQuality   string
+	FileExtension  string
+	SourceProvider string
+}
+
+// Sort keys of the downloads list.
+const (
+	SortArtist = "artist"
+	SortAlbum  = "album"
+	SortTitle  = "title"
+	SortYear   = "year"
+	SortAdded  = "added"
+)
+
+// trackSortColumns are the ORDER BY terms of each sort key. The direction applies to the
+// first term only, so tracks of the same album stay in disc and track order.
+var trackSortColumns = map[string][]string{
+	SortArtist: {"LOWER(artist)", "LOWER(album)", "disc_number", "track_number"},
+	SortAlbum:  {"LOWER(album)", "disc_number", "track_number"},
+	SortTitle:  {"LOWER(title)"},
+	SortYear:   {"year", "LOWER(album)", "disc_number", "track_number"},
+	SortAdded:  {"completed_at"},
+}
+
+// TrackSort orders the tracks listed on the downloads page. The zero value keeps each
+// list's default order.
+type TrackSort struct {
+	Key  string
+	Desc bool
+}
+
+// ParseTrackSort builds a TrackSort from the sort and order query parameters. Unknown keys
+// yield the zero value.
+func ParseTrackSort(key, order string) TrackSort {
+	if _, ok := trackSortColumns[key]; !ok {
+		return TrackSort{}
+	}
+	return TrackSort{Key: key, Desc: strings.EqualFold(order, "desc")}
+}
+
+// Order returns the sort direction as the order query parameter.
+func (s TrackSort) Order() string {
+	if s.Desc {
+		return "desc"
+	}
+	return "asc"
+}
+
+func (s TrackSort) orderBy(fallback string) string {
+	columns, ok := trackSortColumns[s.Key]
+	if !ok {
+		return fallback
+	}
+	dir := " ASC"
+	if s.Desc {
+		dir = " DESC"
+	}
+	terms := []string{columns[0] + dir}
+	terms = append(terms, columns[1:]...)
+	terms = append(terms, "id"+dir)
+	return strings.Join(terms, ", ")
+}
+
+func (f TrackFilter) where() (string, []interface{}) {
+	clauses := []string{"status = ?", "deleted_at IS NULL"}
+	args := []interface{}{domain.TrackStatusCompleted}
+	if f.Search != "" {
+		searchTerm := "%" + f.Search + "%"
+		clauses = append(clauses, "(title LIKE ? OR artist LIKE ? OR album LIKE ? OR genre LIKE ?)")
+		args = append(args, searchTerm, searchTerm, searchTerm, searchTerm)
+	}
+	if f.AudioQuality != "" {
+		clauses = append(clauses, "audio_quality = ?")
+		args = append(args, f.AudioQuality)
+	}
+	if f.FileExtension != "" {
+		clauses = append(clauses, "LOWER(file_extension) = LOWER(?)")
+		args = append(args, f.FileExtension)
+	}
+	if f.SourceProvider != "" {
+		clauses = append(clauses, "source_provider = ?")
+		args = append(args, f.SourceProvider)
+	}
+	return strings.Join(clauses, " AND "), args
+}
+
+func (db *DB) ListCompletedTracksFiltered(f TrackFilter, sort TrackSort, offset, limit int) ([]*domain.Track, error) {
+	where, args := f.where()
+	query := `SELECT * FROM tracks WHERE ` + where + ` ORDER BY ` + sort.orderBy("completed_at DESC") + ` LIMIT ? OFFSET ?`
+	return selectTracks(db, query, append(args, limit, offset)...)
+}
+
+func (db *DB) CountCompletedTracksFiltered(f TrackFilter) (int, error) {
+	where, args := f.where()
+	var count int
+	err := db.Get(&count, `SELECT COUNT(*) FROM tracks WHERE `+where, args...)
+	return count, err
+}
+
+func (db *DB) ListCompletedTracksNoGenre(sort TrackSort, offset, limit int) ([]*domain.Track, error) {
+	query := `SELECT * FROM tracks WHERE status = ? AND deleted_at IS NULL AND (genre IS NULL OR TRIM(genre) = '') ORDER BY ` + sort.orderBy("completed_at DESC") + ` LIMIT ? OFFSET ?`
 	return selectTracks(db, query, domain.TrackStatusCompleted, limit, offset)
 }
 
 func (db *DB) CountCompletedTracksNoGenre() (int, error) {
-	query := `SELECT COUNT(*) FROM tracks WHERE status = ? AND (genre IS NULL OR TRIM(genre) = '')`
+	query := `SELECT COUNT(*) FROM tracks WHERE status = ? AND deleted_at IS NULL AND (genre IS NULL OR TRIM(genre) = '')`
 	var count int
 	err := db.Get(&count, query, domain.TrackStatusCompleted)
 	return count, err
 }
 
 func (db *DB) GetAllGenres() ([]string, error) {
-	query := `SELECT DISTINCT genre FROM tracks WHERE status = ? AND genre IS NOT NULL AND TRIM(genre) != '' ORDER BY genre ASC`
+	query := `SELECT DISTINCT genre FROM tracks WHERE status = ? AND deleted_at IS NULL AND genre IS NOT NULL AND TRIM(genre) != '' ORDER BY genre ASC`
 	var genres []string
 	err := db.Select(&genres, query, domain.TrackStatusCompleted)
 	return genres, err
 }
 
-func (db *DB) ListCompletedTracksByGenre(genre string, offset, limit int) ([]*domain.Track, error) {
-	query := `SELECT * FROM tracks WHERE status = ? AND LOWER(genre) = LOWER(?) ORDER BY completed_at DESC LIMIT ? OFFSET ?`
+func (db *DB) ListCompletedTracksByGenre(genre string, sort TrackSort, offset, limit int) ([]*domain.Track, error) {
+	query := `SELECT * FROM tracks WHERE status = ? AND deleted_at IS NULL AND LOWER(genre) = LOWER(?) ORDER BY ` + sort.orderBy("completed_at DESC") + ` LIMIT ? OFFSET ?`
 	return selectTracks(db, query, domain.TrackStatusCompleted, genre, limit, offset)
 }
 
 func (db *DB) CountCompletedTracksByGenre(genre string) (int, error) {
-	query := `SELECT COUNT(*) FROM tracks WHERE status = ? AND LOWER(genre) = LOWER(?)`
+	query := `SELECT COUNT(*) FROM tracks WHERE status = ? AND deleted_at IS NULL AND LOWER(genre) = LOWER(?)`
 	var count int
 	err := db.Get(&count, query, domain.TrackStatusCompleted, genre)
 	return count, err
@@ -291,20 +466,56 @@ func (db *DB) DeleteTrack(id int) error {
 	return err
 }
 
-func (db *DB) DeletePendingTracksByParentJobID(parentJobID string) error {
-	_, err := db.Exec(`DELETE FROM tracks WHERE parent_job_id = ? AND status = ?`, parentJobID, domain.TrackStatusQueued)
-	return err
+// SoftDeleteTrack marks a track as trashed without removing its row.
+func (db *DB) SoftDeleteTrack(id int) error {
+	now := time.Now()
+	result, err := db.Exec(`UPDATE tracks SET deleted_at = ?, updated_at = ? WHERE id = ?`, now, now, id)
+	if err != nil {
+		return err
+	}
+	return checkRowsAffected(result, "track", id)
+}
+
+// RestoreTrack clears the trashed marker of a track.
+func (db *DB) RestoreTrack(id int) error {
+	result, err := db.Exec(`UPDATE tracks SET deleted_at = NULL, updated_at = ? WHERE id = ?`, time.Now(), id)
+	if err != nil {
+		return err
+	}
+	return checkRowsAffected(result, "track", id)
+}
+
+func (db *DB) ListDeletedTracks(sort TrackSort, offset, limit int) ([]*domain.Track, error) {
+	query := `SELECT * FROM tracks WHERE deleted_at IS NOT NULL ORDER BY ` + sort.orderBy("deleted_at DESC") + ` LIMIT ? OFFSET ?`
+	return selectTracks(db, query, limit, offset)
+}
+
+// ListTracksCompletedBetween returns the completed tracks that finished downloading at or
+// after from and before to, most recent first.
+func (db *DB) ListTracksCompletedBetween(from, to time.Time) ([]*domain.Track, error) {
+	query := `SELECT * FROM tracks WHERE status = ? AND deleted_at IS NULL AND completed_at >= ? AND completed_at < ? ORDER BY completed_at DESC`
+	return selectTracks(db, query, domain.TrackStatusCompleted, from, to)
+}
+
+func (db *DB) CountDeletedTracks() (int, error) {
+	var count int
+	err := db.Get(&count, `SELECT COUNT(*) FROM tracks WHERE deleted_at IS NOT NULL`)
+	return count, err
+}
+
+func (db *DB) ListAllDeletedTracks() ([]*domain.Track, error) {
+	return selectTracks(db, `SELECT * FROM tracks WHERE deleted_at IS NOT NULL`)
 }
 
 func (db *DB) IsTrackDownloaded(providerID string) (bool, error) {
-	query := `SELECT COUNT(*) FROM tracks WHERE provider_id = ? AND status = ? AND file_path IS NOT NULL`
+	query := `SELECT COUNT(*) FROM tracks WHERE provider_id = ? AND status = ? AND file_path IS NOT NULL AND deleted_at IS NULL`
 	var count int
 	err := db.Get(&count, query, providerID, domain.TrackStatusCompleted)
 	return count > 0, err
 }
 
 func (db *DB) GetDownloadedTrack(providerID string) (*domain.Track, error) {
-	query := `SELECT * FROM tracks WHERE provider_id = ? AND status = ? AND file_path IS NOT NULL LIMIT 1`
+	query := `SELECT * FROM tracks WHERE provider_id = ? AND status = ? AND file_path IS NOT NULL AND deleted_at IS NULL LIMIT 1`
 
 	var track domain.Track
 	err := db.Get(&track, query, providerID, domain.TrackStatusCompleted)
@@ -317,7 +528,7 @@ func (db *DB) GetDownloadedTrack(providerID string) (*domain.Track, error) {
 func (db *DB) RecomputeAlbumState(albumID string) (string, error) {
 	query := `SELECT 
 		COUNT(*) as total, 
-		SUM(CASE WHEN status = ? AND file_path IS NOT NULL THEN 1 ELSE 0 END) as completed 
+		SUM(CASE WHEN status = ? AND file_path IS NOT NULL AND deleted_at IS NULL THEN 1 ELSE 0 END) as completed 
 	FROM tracks WHERE album_id = ?`
 
 	type result struct {
@@ -338,23 +549,66 @@ func (db *DB) RecomputeAlbumState(albumID string) (string, error) {
 	return "completed", nil
 }
 
+// GetTrackByFilePath returns the track recorded at the given file path, in the trash or not.
+func (db *DB) GetTrackByFilePath(path string) (*domain.Track, error) {
+	query := `SELECT * FROM tracks WHERE file_path = ? ORDER BY id LIMIT 1`
+
+	var track domain.Track
+	err := db.Get(&track, query, path)
+	if err != nil {
+		return nil, err
+	}
+	return &track, nil
+}
+
 func (db *DB) FindInterruptedTracks() ([]*domain.Track, error) {
 	query := `SELECT * FROM tracks WHERE status IN (?, ?)`
 	return selectTracks(db, query, domain.TrackStatusDownloading, domain.TrackStatusProcessing)
 }
 
+// GetTrackByISRC returns the most recently completed track with the given ISRC that is
+// still in the library.
+func (db *DB) GetTrackByISRC(isrc string) (*domain.Track, error) {
+	query := `SELECT * FROM tracks WHERE isrc = ? AND status = ? AND deleted_at IS NULL ORDER BY completed_at DESC LIMIT 1`
+
+	var track domain.Track
+	err := db.Get(&track, query, isrc, domain.TrackStatusCompleted)
+	if err != nil {
+		return nil, err
+	}
+	return &track, nil
+}
+
 func (db *DB) ListCompletedTracksWithISRC() ([]*domain.Track, error) {
-	query := `SELECT * FROM tracks WHERE status = ? AND isrc != '' ORDER BY created_at DESC`
+	query := `SELECT * FROM tracks WHERE status = ? AND deleted_at IS NULL AND isrc != '' ORDER BY created_at DESC`
 	return selectTracks(db, query, domain.TrackStatusCompleted)
 }
 
+// ListFailedTracks returns every failed track not in the trash, oldest first.
+func (db *DB) ListFailedTracks() ([]*domain.Track, error) {
+	query := `SELECT * FROM tracks WHERE status = ? AND deleted_at IS NULL ORDER BY created_at ASC`
+	return selectTracks(db, query, domain.TrackStatusFailed)
+}
+
 func (db *DB) ListAllCompletedTracks() ([]*domain.Track, error) {
-	query := `SELECT * FROM tracks WHERE status = ? ORDER BY created_at DESC`
+	query := `SELECT * FROM tracks WHERE status = ? AND deleted_at IS NULL ORDER BY created_at DESC`
 	return selectTracks(db, query, domain.TrackStatusCompleted)
 }
 
+func (db *DB) ListCompletedTracksByAlbumID(albumID string) ([]*domain.Track, error) {
+	query := `SELECT * FROM tracks WHERE status = ? AND deleted_at IS NULL AND album_id = ? ORDER BY disc_number ASC, track_number ASC`
+	return selectTracks(db, query, domain.TrackStatusCompleted, albumID)
+}
+
+// ListCompletedTracksAfterID returns completed tracks with an ID greater than afterID, ordered by ID.
+// Keyset pagination keeps long exports stable while new tracks complete.
+func (db *DB) ListCompletedTracksAfterID(afterID, limit int) ([]*domain.Track, error) {
+	query := `SELECT * FROM tracks WHERE status = ? AND deleted_at IS NULL AND id > ? ORDER BY id ASC LIMIT ?`
+	return selectTracks(db, query, domain.TrackStatusCompleted, afterID, limit)
+}
+
 func (db *DB) GetRandomTrack() (*domain.Track, error) {
-	query := `SELECT * FROM tracks WHERE status = ? ORDER BY RANDOM() LIMIT 1`
+	query := `SELECT * FROM tracks WHERE status = ? AND deleted_at IS NULL ORDER BY RANDOM() LIMIT 1`
 	var track domain.Track
 	err := db.Get(&track, query, domain.TrackStatusCompleted)
 	if err != nil {
@@ -365,7 +619,7 @@ func (db *DB) GetRandomTrack() (*domain.Track, error) {
 }
 
 func (db *DB) GetRandomAlbum() (*domain.Track, error) {
-	query := `SELECT * FROM tracks WHERE status = ? AND LOWER(release_type) = 'album' GROUP BY album_id ORDER BY RANDOM() LIMIT 1`
+	query := `SELECT * FROM tracks WHERE status = ? AND deleted_at IS NULL AND LOWER(release_type) = 'album' GROUP BY album_id ORDER BY RANDOM() LIMIT 1`
 	var track domain.Track
 	err := db.Get(&track, query, domain.TrackStatusCompleted)
 	if err != nil {
@@ -376,7 +630,7 @@ func (db *DB) GetRandomAlbum() (*domain.Track, error) {
 }
 
 func (db *DB) GetRandomArtist() (*domain.Track, error) {
-	query := `SELECT * FROM tracks WHERE status = ? AND artist_ids IS NOT NULL AND artist_ids != '[]' GROUP BY artist_ids ORDER BY RANDOM() LIMIT 1`
+	query := `SELECT * FROM tracks WHERE status = ? AND deleted_at IS NULL AND artist_ids IS NOT NULL AND artist_ids != '[]' GROUP BY artist_ids ORDER BY RANDOM() LIMIT 1`
 	var track domain.Track
 	err := db.Get(&track, query, domain.TrackStatusCompleted)
 	if err != nil {
@@ -392,55 +646,52 @@ func selectTracks(q sqlx.Queryer, query string, args ...interface{}) ([]*domain.
 	return tracks, err
 }
 
-// CreateTrackBatch creates multiple tracks in a single transaction.
-// It uses an all-or-nothing approach: if any insertion fails (besides IGNORE), the whole batch is rolled back.
+// CreateTrackBatch creates multiple tracks in a single transaction, or in the caller's
+// transaction when db is one. It uses an all-or-nothing approach: if any insertion fails
+// (besides IGNORE), the whole batch is rolled back.
 func (db *DB) CreateTrackBatch(tracks []*domain.Track) (int, error) {
-	tx, err := db.root.Beginx()
-	if err != nil {
-		return 0, fmt.Errorf("failed to begin transaction: %w", err)
-	}
-	defer tx.Rollback() //nolint:errcheck // rollback is best-effort
-
 	createdCount := 0
 	query := `INSERT OR IGNORE INTO tracks (
-		provider_id, title, artist, artists, album, album_id, album_artist, album_artists, path_artist, artist_ids, album_artist_ids,
+		provider_id, source_provider, title, artist, artists, album, album_id, album_artist, album_artists, path_artist, artist_ids, album_artist_ids, artist_sort, album_artist_sort,
 		track_number, disc_number, total_tracks, total_discs,
-		year, genre, mood, language, label, isrc, copyright, composer,
+		year, genre, mood, language, label, isrc, copyright, composer, producer, engineer, mixer, performers,
 		duration, explicit, compilation, album_art_url, lyrics, subtitles,
-		bpm, key_name, key_scale, replay_gain, peak, version, description, url, audio_quality, audio_modes, release_date,
-		barcode, catalog_number, release_type, release_id, recording_id, tags,
-		status, error, parent_job_id, file_path, file_extension,
+		bpm, key_name, key_scale, replay_gain, peak, album_gain, album_peak, version, description, url, audio_quality, audio_modes, target_quality, release_date, original_date, original_year,
+		barcode, catalog_number, release_type, release_id, recording_id, musicbrainz_album_id, release_track_id, tags,
+		status, error, parent_job_id, file_path, file_extension, sample_rate, bit_depth, channels, bitrate,
 		created_at, updated_at, etag, file_hash, last_verified_at
 	) VALUES (
-		:provider_id, :title, :artist, :artists, :album, :album_id, :album_artist, :album_artists, :path_artist, :artist_ids, :album_artist_ids,
+		:provider_id, :source_provider, :title, :artist, :artists, :album, :album_id, :album_artist, :album_artists, :path_artist, :artist_ids, :album_artist_ids, :artist_sort, :album_artist_sort,
 		:track_number, :disc_number, :total_tracks, :total_discs,
-		:year, :genre, :mood, :language, :label, :isrc, :copyright, :composer,
+		:year, :genre, :mood, :language, :label, :isrc, :copyright, :composer, :producer, :engineer, :mixer, :performers,
 		:duration, :explicit, :compilation, :album_art_url, :lyrics, :subtitles,
-		:bpm, :key_name, :key_scale, :replay_gain, :peak, :version, :description, :url, :audio_quality, :audio_modes, :release_date,
-		:barcode, :catalog_number, :release_type, :release_id, :recording_id, :tags,
-		:status, :error, :parent_job_id, :file_path, :file_extension,
+		:bpm, :key_name, :key_scale, :replay_gain, :peak, :album_gain, :album_peak, :version, :description, :url, :audio_quality, :audio_modes, :target_quality, :release_date, :original_date, :original_year,
+		:barcode, :catalog_number, :release_type, :release_id, :recording_id, :musicbrainz_album_id, :release_track_id, :tags,
+		:status, :error, :parent_job_id, :file_path, :file_extension, :sample_rate, :bit_depth, :channels, :bitrate,
 		:created_at, :updated_at, :etag, :file_hash, :last_verified_at
 	)`
 
-	for _, track := range tracks {
-		track.Normalize()
-		if track.CreatedAt.IsZero() {
-			track.CreatedAt = time.Now()
-		}
-		if track.UpdatedAt.IsZero() {
-			track.UpdatedAt = time.Now()
-		}
+	err := db.RunInTx(func(txDB *DB) error {
+		for _, track := range tracks {
+			track.Normalize()
+			if track.CreatedAt.IsZero() {
+				track.CreatedAt = time.Now()
+			}
+			if track.UpdatedAt.IsZero() {
+				track.UpdatedAt = time.Now()
+			}
 
-		result, err := tx.NamedExec(query, track)
-		if err != nil {
-			return 0, fmt.Errorf("failed to create track %s: %w", track.ProviderID, err)
+			result, err := txDB.NamedExec(query, track)
+			if err != nil {
+				return fmt.Errorf("failed to create track %s: %w", track.ProviderID, err)
+			}
+			affected, _ := result.RowsAffected()
+			createdCount += int(affected)
 		}
-		affected, _ := result.RowsAffected()
-		createdCount += int(affected)
-	}
-
-	if err := tx.Commit(); err != nil {
-		return 0, fmt.Errorf("failed to commit transaction: %w", err)
+		return nil
+	})
+	if err != nil {
+		return 0, err
 	}
 
 	return createdCount, nil
@@ -0,0 +1,55 @@
+package store
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/cesargomez89/navidrums/internal/domain"
+)
+
+// GetSyncUpgradeSummary returns the quality upgrade counts of the last bulk Hi-Fi sync.
+func (r *SettingsRepo) GetSyncUpgradeSummary() (*domain.SyncUpgradeSummary, error) {
+	raw, err := r.Get(SettingSyncUpgradeSummary)
+	if err != nil {
+		return nil, err
+	}
+	summary := &domain.SyncUpgradeSummary{}
+	if raw == "" {
+		return summary, nil
+	}
+	if err := json.Unmarshal([]byte(raw), summary); err != nil {
+		return nil, fmt.Errorf("failed to parse sync upgrade summary: %w", err)
+	}
+	return summary, nil
+}
+
+func (r *SettingsRepo) saveSyncUpgradeSummary(summary *domain.SyncUpgradeSummary) error {
+	data, err := json.Marshal(summary)
+	if err != nil {
+		return err
+	}
+	return r.Set(SettingSyncUpgradeSummary, string(data))
+}
+
+// ResetSyncUpgradeSummary zeroes the counts at the start of a bulk Hi-Fi sync.
+func (r *SettingsRepo) ResetSyncUpgradeSummary() error {
+	return r.saveSyncUpgradeSummary(&domain.SyncUpgradeSummary{StartedAt: time.Now()})
+}
+
+// RecordSyncUpgrade counts one Hi-Fi sync as upgraded or unchanged.
+func (r *SettingsRepo) RecordSyncUpgrade(upgraded bool) error {
+	return r.db.RunInTx(func(txDB *DB) error {
+		tx := NewSettingsRepo(txDB)
+		summary, err := tx.GetSyncUpgradeSummary()
+		if err != nil {
+			return err
+		}
+		if upgraded {
+			summary.Upgraded++
+		} else {
+			summary.Unchanged++
+		}
+		return tx.saveSyncUpgradeSummary(summary)
+	})
+}
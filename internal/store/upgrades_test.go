@@ -0,0 +1,33 @@
+package store
+
+import "testing"
+
+func TestSettingsRepo_SyncUpgradeSummary(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+	repo := NewSettingsRepo(db)
+
+	for _, upgraded := range []bool{true, false, false} {
+		if err := repo.RecordSyncUpgrade(upgraded); err != nil {
+			t.Fatalf("RecordSyncUpgrade failed: %v", err)
+		}
+	}
+	summary, err := repo.GetSyncUpgradeSummary()
+	if err != nil {
+		t.Fatalf("GetSyncUpgradeSummary failed: %v", err)
+	}
+	if summary.Upgraded != 1 || summary.Unchanged != 2 {
+		t.Errorf("got %d upgraded, %d unchanged, want 1 and 2", summary.Upgraded, summary.Unchanged)
+	}
+
+	if err := repo.ResetSyncUpgradeSummary(); err != nil {
+		t.Fatalf("ResetSyncUpgradeSummary failed: %v", err)
+	}
+	summary, err = repo.GetSyncUpgradeSummary()
+	if err != nil {
+		t.Fatalf("GetSyncUpgradeSummary failed: %v", err)
+	}
+	if summary.Upgraded != 0 || summary.Unchanged != 0 || summary.StartedAt.IsZero() {
+		t.Errorf("expected a fresh summary after reset, got %+v", summary)
+	}
+}
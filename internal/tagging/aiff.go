@@ -0,0 +1,45 @@
+package tagging
+
+import (
+	"encoding/binary"
+	"fmt"
+	"strings"
+)
+
+// ── AIFF Strategy ────────────────────────────────────────────────────────────
+
+// AIFFTagger writes the AIFF NAME/AUTH/(c) text chunks plus an ID3 chunk, which is
+// where players such as Navidrome and iTunes look for the rest of the tags.
+type AIFFTagger struct{}
+
+func (t *AIFFTagger) WriteTags(filePath string, tags *TagMap) error {
+	id3Data, err := id3Chunk(tags)
+	if err != nil {
+		return err
+	}
+
+	return rewriteIFF(filePath, "FORM", binary.BigEndian, func(f *iffFile) error {
+		if f.form != "AIFF" && f.form != "AIFC" {
+			return fmt.Errorf("not an AIFF file: %s", f.form)
+		}
+		var add []iffChunk
+		for _, c := range []iffChunk{
+			{id: "NAME", data: []byte(tags.Title)},
+			{id: "AUTH", data: []byte(strings.Join(tags.Artists, "; "))},
+			{id: "(c) ", data: []byte(tags.Copyright)},
+		} {
+			if len(c.data) > 0 {
+				add = append(add, c)
+			}
+		}
+		add = append(add, iffChunk{id: "ID3 ", data: id3Data})
+		f.replaceChunks(func(c iffChunk) bool {
+			switch c.id {
+			case "NAME", "AUTH", "(c) ", "ID3 ", "id3 ":
+				return true
+			}
+			return false
+		}, add...)
+		return nil
+	})
+}
@@ -0,0 +1,297 @@
+package tagging
+
+import (
+	"bufio"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/go-flac/go-flac"
+)
+
+// ── Audio Properties ─────────────────────────────────────────────────────────
+
+// AudioProperties are the technical properties of an audio stream. Zero means unknown;
+// BitDepth is always zero for lossy codecs.
+type AudioProperties struct {
+	SampleRate int // Hz
+	BitDepth   int // bits per sample
+	Channels   int
+	Bitrate    int // average kbps
+}
+
+// ReadAudioProperties reads the stream properties of a FLAC, MP3 or MP4 file from its
+// headers: FLAC STREAMINFO, the first MPEG audio frame, or the MP4 sample description.
+// The bitrate is averaged over the file size and duration when the headers carry none.
+func ReadAudioProperties(filePath string) (*AudioProperties, error) {
+	f, err := os.Open(filePath) //nolint:gosec // path is a downloaded track
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = f.Close() }()
+
+	info, err := f.Stat()
+	if err != nil {
+		return nil, err
+	}
+
+	var props *AudioProperties
+	var seconds float64
+	switch strings.ToLower(filepath.Ext(filePath)) {
+	case ".flac":
+		props, seconds, err = readFLACProperties(f)
+	case ".mp3":
+		props, err = readMP3Properties(f)
+	case ".mp4", ".m4a":
+		props, seconds, err = readMP4Properties(f, info.Size())
+	default:
+		return nil, ErrUnsupportedFormat
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if props.Bitrate == 0 && seconds > 0 {
+		props.Bitrate = int(float64(info.Size()) * 8 / seconds / 1000)
+	}
+	return props, nil
+}
+
+func readFLACProperties(r io.Reader) (*AudioProperties, float64, error) {
+	f, err := flac.ParseMetadata(bufio.NewReader(r))
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to parse FLAC metadata: %w", err)
+	}
+	si, err := f.GetStreamInfo()
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to read FLAC stream info: %w", err)
+	}
+
+	var seconds float64
+	if si.SampleRate > 0 {
+		seconds = float64(si.SampleCount) / float64(si.SampleRate)
+	}
+	return &AudioProperties{
+		SampleRate: si.SampleRate,
+		BitDepth:   si.BitDepth,
+		Channels:   si.ChannelCount,
+	}, seconds, nil
+}
+
+// mp3ScanLimit bounds how far past the ID3 tag the first frame header is searched for.
+const mp3ScanLimit = 64 * 1024
+
+var (
+	// mp3Bitrates are the layer III bitrates in kbps by bitrate index, for MPEG-1 and
+	// for MPEG-2/2.5.
+	mp3BitratesV1 = [16]int{0, 32, 40, 48, 56, 64, 80, 96, 112, 128, 160, 192, 224, 256, 320, 0}
+	mp3BitratesV2 = [16]int{0, 8, 16, 24, 32, 40, 48, 56, 64, 80, 96, 112, 128, 144, 160, 0}
+	// mp3SampleRates are the MPEG-1 sample rates; MPEG-2 halves and MPEG-2.5 quarters them.
+	mp3SampleRates = [3]int{44100, 48000, 32000}
+)
+
+// readMP3Properties decodes the first layer III frame header after any ID3v2 tag. For
+// VBR files this is the first frame's bitrate, not the average.
+func readMP3Properties(r io.ReadSeeker) (*AudioProperties, error) {
+	header := make([]byte, 10)
+	if _, err := io.ReadFull(r, header); err != nil {
+		return nil, fmt.Errorf("failed to read MP3 header: %w", err)
+	}
+	offset := int64(0)
+	if string(header[:3]) == "ID3" {
+		// The tag size is a 28-bit syncsafe integer excluding the 10-byte header.
+		size := int64(header[6])<<21 | int64(header[7])<<14 | int64(header[8])<<7 | int64(header[9])
+		offset = 10 + size
+	}
+	if _, err := r.Seek(offset, io.SeekStart); err != nil {
+		return nil, err
+	}
+
+	buf := make([]byte, mp3ScanLimit)
+	n, err := io.ReadFull(r, buf)
+	if err != nil && err != io.ErrUnexpectedEOF {
+		return nil, fmt.Errorf("failed to read MP3 frames: %w", err)
+	}
+	buf = buf[:n]
+
+	for i := 0; i+4 <= len(buf); i++ {
+		if props, ok := parseMP3FrameHeader(buf[i : i+4]); ok {
+			return props, nil
+		}
+	}
+	return nil, fmt.Errorf("no MPEG audio frame found")
+}
+
+func parseMP3FrameHeader(h []byte) (*AudioProperties, bool) {
+	if h[0] != 0xFF || h[1]&0xE0 != 0xE0 {
+		return nil, false
+	}
+	version := (h[1] >> 3) & 0x03 // 0 = MPEG-2.5, 2 = MPEG-2, 3 = MPEG-1
+	layer := (h[1] >> 1) & 0x03   // 1 = layer III
+	bitrateIdx := h[2] >> 4
+	rateIdx := (h[2] >> 2) & 0x03
+	if version == 1 || layer != 1 || bitrateIdx == 0 || bitrateIdx == 15 || rateIdx == 3 {
+		return nil, false
+	}
+
+	props := &AudioProperties{Channels: 2}
+	switch version {
+	case 3:
+		props.Bitrate = mp3BitratesV1[bitrateIdx]
+		props.SampleRate = mp3SampleRates[rateIdx]
+	case 2:
+		props.Bitrate = mp3BitratesV2[bitrateIdx]
+		props.SampleRate = mp3SampleRates[rateIdx] / 2
+	default:
+		props.Bitrate = mp3BitratesV2[bitrateIdx]
+		props.SampleRate = mp3SampleRates[rateIdx] / 4
+	}
+	if h[3]>>6 == 3 {
+		props.Channels = 1
+	}
+	return props, true
+}
+
+// readMP4Properties reads the first audio sample description (mp4a, alac or fLaC) and the
+// media duration of its track.
+func readMP4Properties(r io.ReadSeeker, size int64) (*AudioProperties, float64, error) {
+	moov, err := readMoov(r, size)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	for start := 8; start < len(moov); {
+		trakOff, trakSize, err := findAtom(moov, start, len(moov), "trak")
+		if err != nil {
+			break
+		}
+		start = trakOff + trakSize
+		mdiaOff, mdiaSize, err := findAtom(moov, trakOff+8, trakOff+trakSize, "mdia")
+		if err != nil {
+			continue
+		}
+		props, ok := mp4SampleEntry(moov, mdiaOff, mdiaSize)
+		if !ok {
+			continue
+		}
+		return props, mp4MediaSeconds(moov, mdiaOff, mdiaSize), nil
+	}
+	return nil, 0, fmt.Errorf("no audio track found")
+}
+
+// readMoov returns the moov atom, reading only the top-level atom headers up to it so the
+// audio data is not loaded.
+func readMoov(r io.ReadSeeker, size int64) ([]byte, error) {
+	header := make([]byte, 16)
+	for pos := int64(0); pos+8 <= size; {
+		if _, err := r.Seek(pos, io.SeekStart); err != nil {
+			return nil, err
+		}
+		if _, err := io.ReadFull(r, header[:8]); err != nil {
+			return nil, err
+		}
+		atomSize := int64(binary.BigEndian.Uint32(header))
+		switch atomSize {
+		case 0:
+			atomSize = size - pos
+		case 1:
+			if _, err := io.ReadFull(r, header[8:16]); err != nil {
+				return nil, err
+			}
+			atomSize = int64(binary.BigEndian.Uint64(header[8:])) //nolint:gosec // bounds checked below
+		}
+		if atomSize < 8 || pos+atomSize > size {
+			return nil, fmt.Errorf("invalid %s atom size %d", header[4:8], atomSize)
+		}
+		if string(header[4:8]) == "moov" {
+			moov := make([]byte, atomSize)
+			if _, err := r.Seek(pos, io.SeekStart); err != nil {
+				return nil, err
+			}
+			if _, err := io.ReadFull(r, moov); err != nil {
+				return nil, err
+			}
+			return moov, nil
+		}
+		pos += atomSize
+	}
+	return nil, fmt.Errorf("moov: %w", errAtomNotFound)
+}
+
+// mp4SampleEntry parses the first sample entry of mdia/minf/stbl/stsd when it is audio.
+func mp4SampleEntry(moov []byte, mdiaOff, mdiaSize int) (*AudioProperties, bool) {
+	off, size := mdiaOff, mdiaSize
+	for _, typ := range []string{"minf", "stbl", "stsd"} {
+		var err error
+		off, size, err = findAtom(moov, off+8, off+size, typ)
+		if err != nil {
+			return nil, false
+		}
+	}
+	// stsd is a full box with an entry count before the first entry, and an audio sample
+	// entry has 28 bytes of fields before its child boxes.
+	entry := off + 16
+	if entry+36 > off+size {
+		return nil, false
+	}
+	entrySize := int(binary.BigEndian.Uint32(moov[entry:]))
+	if entrySize < 36 || entry+entrySize > off+size {
+		return nil, false
+	}
+	props := &AudioProperties{
+		Channels:   int(binary.BigEndian.Uint16(moov[entry+24:])),
+		SampleRate: int(binary.BigEndian.Uint32(moov[entry+32:]) >> 16),
+	}
+
+	switch string(moov[entry+4 : entry+8]) {
+	case "mp4a":
+		return props, true
+	case "alac":
+		// The alac box holds the real sample rate, which the 16.16 field above cannot
+		// represent above 65535 Hz.
+		if cfgOff, cfgSize, err := findAtom(moov, entry+36, entry+entrySize, "alac"); err == nil && cfgSize >= 36 {
+			cfg := moov[cfgOff+12:]
+			props.BitDepth = int(cfg[5])
+			props.Channels = int(cfg[9])
+			props.Bitrate = int(binary.BigEndian.Uint32(cfg[16:]) / 1000)
+			props.SampleRate = int(binary.BigEndian.Uint32(cfg[20:]))
+		}
+		return props, true
+	case "fLaC":
+		if cfgOff, cfgSize, err := findAtom(moov, entry+36, entry+entrySize, "dfLa"); err == nil && cfgSize >= 34 {
+			// dfLa is a full box wrapping FLAC metadata blocks; STREAMINFO comes first.
+			si := moov[cfgOff+16:]
+			props.SampleRate = int(si[10])<<12 | int(si[11])<<4 | int(si[12])>>4
+			props.Channels = int((si[12]>>1)&0x07) + 1
+			props.BitDepth = int((si[12]&0x01)<<4|si[13]>>4) + 1
+		}
+		return props, true
+	}
+	return nil, false
+}
+
+// mp4MediaSeconds returns the duration from the mdhd box of a track, or 0 when unknown.
+func mp4MediaSeconds(moov []byte, mdiaOff, mdiaSize int) float64 {
+	off, size, err := findAtom(moov, mdiaOff+8, mdiaOff+mdiaSize, "mdhd")
+	if err != nil || size < 32 {
+		return 0
+	}
+	var timescale, duration uint64
+	if moov[off+8] == 1 {
+		if size < 44 {
+			return 0
+		}
+		timescale = uint64(binary.BigEndian.Uint32(moov[off+28:]))
+		duration = binary.BigEndian.Uint64(moov[off+32:])
+	} else {
+		timescale = uint64(binary.BigEndian.Uint32(moov[off+20:]))
+		duration = uint64(binary.BigEndian.Uint32(moov[off+24:]))
+	}
+	if timescale == 0 {
+		return 0
+	}
+	return float64(duration) / float64(timescale)
+}
@@ -19,25 +19,28 @@ func (t *FFmpegFallbackTagger) WriteTags(filePath string, tags *TagMap) error {
 	defer cancel()
 
 	meta := &ffmpeg.Metadata{
-		Title:        tags.Title,
-		Artists:      tags.Artists,
-		Album:        tags.Album,
-		AlbumArtists: tags.AlbumArtists,
-		Genre:        tags.Genre,
-		Mood:         tags.Mood,
-		Language:     tags.Language,
-		Year:         tags.Year,
-		TrackNum:     tags.TrackNum,
-		TrackTotal:   tags.TrackTotal,
-		DiscNum:      tags.DiscNum,
-		DiscTotal:    tags.DiscTotal,
-		BPM:          tags.BPM,
-		Composer:     tags.Composer,
-		Copyright:    tags.Copyright,
-		Lyrics:       tags.Lyrics,
-		CoverArt:     tags.CoverArt,
-		CoverMime:    tags.CoverMime,
-		Custom:       tags.Custom,
+		Title:           tags.Title,
+		Artists:         tags.Artists,
+		Album:           tags.Album,
+		AlbumArtists:    tags.AlbumArtists,
+		ArtistSort:      tags.ArtistSort,
+		AlbumArtistSort: tags.AlbumArtistSort,
+		AlbumSort:       tags.AlbumSort,
+		Genre:           tags.Genre,
+		Mood:            tags.Mood,
+		Language:        tags.Language,
+		Year:            tags.Year,
+		TrackNum:        tags.TrackNum,
+		TrackTotal:      tags.TrackTotal,
+		DiscNum:         tags.DiscNum,
+		DiscTotal:       tags.DiscTotal,
+		BPM:             tags.BPM,
+		Composer:        tags.Composer,
+		Copyright:       tags.Copyright,
+		Lyrics:          tags.Lyrics,
+		CoverArt:        tags.CoverArt,
+		CoverMime:       tags.CoverMime,
+		Custom:          tags.Custom,
 	}
 
 	tempPath, err := ffmpeg.WriteTags(ctx, filePath, meta)
@@ -45,6 +48,11 @@ func (t *FFmpegFallbackTagger) WriteTags(filePath string, tags *TagMap) error {
 		return fmt.Errorf("failed to write tags via ffmpeg: %w", err)
 	}
 
+	if err := addMP4FreeformTags(tempPath, mp4FreeformTags(tags.Custom)); err != nil {
+		_ = os.Remove(tempPath)
+		return fmt.Errorf("failed to write freeform tags: %w", err)
+	}
+
 	if err := os.Rename(tempPath, filePath); err != nil {
 		_ = os.Remove(tempPath)
 		return fmt.Errorf("failed to rename temp file: %w", err)
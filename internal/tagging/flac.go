@@ -23,73 +23,72 @@ func (t *FLACTagger) WriteTags(filePath string, tags *TagMap) error {
 		return fmt.Errorf("failed to parse FLAC file: %w", err)
 	}
 
-	vc := t.newVorbisComment(tags)
-	newVCMeta := vc.Marshal()
-
-	var newPicMeta *flac.MetaDataBlock
-	if len(tags.CoverArt) > 0 {
-		pic, err := flacpicture.NewFromImageData(
-			flacpicture.PictureTypeFrontCover,
-			"Front Cover",
-			tags.CoverArt,
-			tags.CoverMime,
-		)
-		if err != nil {
-			return fmt.Errorf("failed to create picture: %w", err)
-		}
-		pm := pic.Marshal()
-		newPicMeta = &pm
-	}
-
+	var existingVC *flacvorbis.MetaDataBlockVorbisComment
 	var currentVC []byte
-	var currentPic []byte
-	var vorbisIdx = -1
-	var pictureIdx = -1
+	var currentPics [][]byte
 
-	for i, b := range f.Meta {
+	for _, b := range f.Meta {
 		switch b.Type {
 		case flac.VorbisComment:
-			vorbisIdx = i
 			vcBlock, err := flacvorbis.ParseFromMetaDataBlock(*b)
 			if err == nil {
+				existingVC = vcBlock
 				currentVC = vcBlock.Marshal().Data
 			}
 		case flac.Picture:
-			pictureIdx = i
-			picBlock, err := flacpicture.ParseFromMetaDataBlock(*b)
-			if err == nil {
-				currentPic = picBlock.ImageData
-			}
+			currentPics = append(currentPics, b.Data)
 		}
 	}
 
-	changed := !bytes.Equal(currentVC, newVCMeta.Data)
-	if newPicMeta != nil && !bytes.Equal(currentPic, newPicMeta.Data) {
-		changed = true
-	} else if len(tags.CoverArt) == 0 && pictureIdx >= 0 {
-		changed = true
+	vc := t.newVorbisComment(tags)
+	keepPictures := false
+	if tags.MergeStrategy == MergeFillMissing {
+		if existingVC != nil {
+			vc = fillMissingComments(existingVC, vc)
+		}
+		keepPictures = len(currentPics) > 0
 	}
+	newVCMeta := vc.Marshal()
 
-	if !changed {
-		return nil
+	var newPics []*flac.MetaDataBlock
+	if !keepPictures {
+		var err error
+		if newPics, err = buildPictureBlocks(tags); err != nil {
+			return err
+		}
 	}
 
-	if pictureIdx >= 0 {
-		f.Meta = append(f.Meta[:pictureIdx], f.Meta[pictureIdx+1:]...)
-		if vorbisIdx > pictureIdx {
-			vorbisIdx--
+	changed := !bytes.Equal(currentVC, newVCMeta.Data)
+	if !keepPictures {
+		changed = changed || len(newPics) != len(currentPics)
+		for i := 0; !changed && i < len(newPics); i++ {
+			changed = !bytes.Equal(currentPics[i], newPics[i].Data)
 		}
 	}
 
-	if newPicMeta != nil {
-		f.Meta = append(f.Meta, newPicMeta)
+	if !changed {
+		return nil
 	}
 
-	if vorbisIdx >= 0 {
-		f.Meta[vorbisIdx] = &newVCMeta
-	} else {
-		f.Meta = append(f.Meta, &newVCMeta)
+	meta := make([]*flac.MetaDataBlock, 0, len(f.Meta)+len(newPics)+1)
+	wroteVC := false
+	for _, b := range f.Meta {
+		switch {
+		case b.Type == flac.VorbisComment:
+			if !wroteVC {
+				meta = append(meta, &newVCMeta)
+				wroteVC = true
+			}
+		case b.Type == flac.Picture && !keepPictures:
+		default:
+			meta = append(meta, b)
+		}
+	}
+	meta = append(meta, newPics...)
+	if !wroteVC {
+		meta = append(meta, &newVCMeta)
 	}
+	f.Meta = meta
 
 	tempFile := filePath + ".tmp"
 	if err := f.Save(tempFile); err != nil {
@@ -116,6 +115,59 @@ func (t *FLACTagger) WriteTags(filePath string, tags *TagMap) error {
 	return nil
 }
 
+// buildPictureBlocks returns the PICTURE blocks to embed: the front cover, then each extra
+// picture in order.
+func buildPictureBlocks(tags *TagMap) ([]*flac.MetaDataBlock, error) {
+	pictures := tags.Pictures
+	if len(tags.CoverArt) > 0 {
+		front := Picture{Type: PictureFrontCover, Data: tags.CoverArt, Mime: tags.CoverMime}
+		pictures = append([]Picture{front}, pictures...)
+	}
+
+	blocks := make([]*flac.MetaDataBlock, 0, len(pictures))
+	for _, p := range pictures {
+		if len(p.Data) == 0 {
+			continue
+		}
+		mime := p.Mime
+		if mime == "" {
+			mime = imageMime(p.Data)
+		}
+		pic, err := flacpicture.NewFromImageData(flacpicture.PictureType(p.Type), p.Type.String(), p.Data, mime)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create %s picture: %w", strings.ToLower(p.Type.String()), err)
+		}
+		block := pic.Marshal()
+		blocks = append(blocks, &block)
+	}
+	return blocks, nil
+}
+
+// fillMissingComments keeps every existing comment and adds only the fields from fresh
+// that the file does not already carry, so values edited in another tagger survive.
+func fillMissingComments(existing, fresh *flacvorbis.MetaDataBlockVorbisComment) *flacvorbis.MetaDataBlockVorbisComment {
+	present := make(map[string]bool)
+	for _, c := range existing.Comments {
+		if name, value, ok := strings.Cut(c, "="); ok && value != "" {
+			present[strings.ToUpper(name)] = true
+		}
+	}
+
+	merged := &flacvorbis.MetaDataBlockVorbisComment{Vendor: existing.Vendor}
+	for _, c := range existing.Comments {
+		if _, value, ok := strings.Cut(c, "="); ok && value != "" {
+			merged.Comments = append(merged.Comments, c)
+		}
+	}
+	for _, c := range fresh.Comments {
+		name, _, _ := strings.Cut(c, "=")
+		if !present[strings.ToUpper(name)] {
+			merged.Comments = append(merged.Comments, c)
+		}
+	}
+	return merged
+}
+
 func (t *FLACTagger) newVorbisComment(tags *TagMap) *flacvorbis.MetaDataBlockVorbisComment {
 	vc := flacvorbis.New()
 
@@ -129,10 +181,16 @@ func (t *FLACTagger) newVorbisComment(tags *TagMap) *flacvorbis.MetaDataBlockVor
 	for _, a := range tags.Artists {
 		add("ARTIST", a)
 	}
+	for _, a := range tags.ArtistCredits {
+		add("ARTISTS", a)
+	}
 	for _, a := range tags.AlbumArtists {
 		add("ALBUMARTIST", a)
 	}
 	add("ALBUM", tags.Album)
+	add("ARTISTSORT", tags.ArtistSort)
+	add("ALBUMARTISTSORT", tags.AlbumArtistSort)
+	add("ALBUMSORT", tags.AlbumSort)
 
 	if tags.TrackNum > 0 {
 		add("TRACKNUMBER", fmt.Sprintf("%d", tags.TrackNum))
@@ -149,6 +207,11 @@ func (t *FLACTagger) newVorbisComment(tags *TagMap) *flacvorbis.MetaDataBlockVor
 	if tags.Year > 0 {
 		add("DATE", fmt.Sprintf("%d", tags.Year))
 	}
+	add("RELEASEDATE", tags.ReleaseDate)
+	add("ORIGINALDATE", tags.OriginalDate)
+	if tags.OriginalYear > 0 {
+		add("ORIGINALYEAR", fmt.Sprintf("%d", tags.OriginalYear))
+	}
 
 	if tags.Genre != "" {
 		genres := strings.Split(tags.Genre, GenreSeparator)
@@ -161,6 +224,12 @@ func (t *FLACTagger) newVorbisComment(tags *TagMap) *flacvorbis.MetaDataBlockVor
 	}
 	add("COPYRIGHT", tags.Copyright)
 	add("COMPOSER", tags.Composer)
+	add("PRODUCER", tags.Producer)
+	add("ENGINEER", tags.Engineer)
+	add("MIXER", tags.Mixer)
+	for _, p := range tags.Performers {
+		add("PERFORMER", p)
+	}
 
 	if tags.BPM > 0 {
 		add("BPM", fmt.Sprintf("%d", tags.BPM))
@@ -0,0 +1,123 @@
+package tagging
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"os"
+
+	"github.com/bogem/id3v2/v2"
+
+	"github.com/cesargomez89/navidrums/internal/constants"
+)
+
+// ── IFF Containers ───────────────────────────────────────────────────────────
+
+// WAV (RIFF) and AIFF (FORM) share the IFF layout: a 12-byte header naming the form
+// type, followed by chunks of a 4-byte ID, a 4-byte size and word-aligned data. They
+// differ only in byte order.
+
+type iffChunk struct {
+	id   string
+	data []byte
+}
+
+type iffFile struct {
+	order  binary.ByteOrder
+	magic  string
+	form   string
+	chunks []iffChunk
+}
+
+// parseIFF splits data into its chunks. A final chunk whose size runs past the end of
+// the file (as written by some streaming encoders) is truncated rather than rejected.
+func parseIFF(data []byte, magic string, order binary.ByteOrder) (*iffFile, error) {
+	if len(data) < 12 || string(data[:4]) != magic {
+		return nil, fmt.Errorf("not a %s file", magic)
+	}
+	f := &iffFile{order: order, magic: magic, form: string(data[8:12])}
+	for i := 12; i+8 <= len(data); {
+		id := string(data[i : i+4])
+		size := int(order.Uint32(data[i+4:]))
+		start := i + 8
+		end := start + size
+		if end > len(data) {
+			end = len(data)
+		}
+		f.chunks = append(f.chunks, iffChunk{id: id, data: data[start:end]})
+		i = end + (end-start)%2
+	}
+	return f, nil
+}
+
+// replaceChunks drops every chunk matched by drop and appends add.
+func (f *iffFile) replaceChunks(drop func(iffChunk) bool, add ...iffChunk) {
+	kept := f.chunks[:0]
+	for _, c := range f.chunks {
+		if !drop(c) {
+			kept = append(kept, c)
+		}
+	}
+	f.chunks = append(kept, add...)
+}
+
+func (f *iffFile) bytes() []byte {
+	var body bytes.Buffer
+	body.WriteString(f.form)
+	for _, c := range f.chunks {
+		writeIFFChunk(&body, f.order, c.id, c.data)
+	}
+	out := make([]byte, 8, 8+body.Len())
+	copy(out, f.magic)
+	f.order.PutUint32(out[4:], uint32(body.Len())) //nolint:gosec // audio files stay under 4 GiB, as the format requires
+	return append(out, body.Bytes()...)
+}
+
+func writeIFFChunk(buf *bytes.Buffer, order binary.ByteOrder, id string, data []byte) {
+	var hdr [8]byte
+	copy(hdr[:4], id)
+	order.PutUint32(hdr[4:], uint32(len(data))) //nolint:gosec // chunk sizes are bounded by the file size
+	buf.Write(hdr[:])
+	buf.Write(data)
+	if len(data)%2 == 1 {
+		buf.WriteByte(0)
+	}
+}
+
+// id3Chunk serializes tags as a standalone ID3v2 tag for embedding in an id3 chunk.
+func id3Chunk(tags *TagMap) ([]byte, error) {
+	tag := id3v2.NewEmptyTag()
+	writeID3Frames(tag, tags)
+	var buf bytes.Buffer
+	if _, err := tag.WriteTo(&buf); err != nil {
+		return nil, fmt.Errorf("failed to build ID3 chunk: %w", err)
+	}
+	return buf.Bytes(), nil
+}
+
+// rewriteIFF parses the file at filePath, lets edit replace its tag chunks and saves
+// the result through a temp file so a failed write never leaves a truncated file.
+func rewriteIFF(filePath, magic string, order binary.ByteOrder, edit func(*iffFile) error) error {
+	data, err := os.ReadFile(filePath) //nolint:gosec // path comes from the download pipeline
+	if err != nil {
+		return fmt.Errorf("failed to read file: %w", err)
+	}
+	f, err := parseIFF(data, magic, order)
+	if err != nil {
+		return err
+	}
+	if err := edit(f); err != nil {
+		return err
+	}
+
+	tempFile := filePath + ".tmp"
+	if err := os.WriteFile(tempFile, f.bytes(), constants.FilePermissions); err != nil {
+		_ = os.Remove(tempFile)
+		return fmt.Errorf("failed to write temp file: %w", err)
+	}
+	if err := os.Rename(tempFile, filePath); err != nil {
+		_ = os.Remove(tempFile)
+		return fmt.Errorf("failed to rename temp file: %w", err)
+	}
+	return nil
+}
@@ -2,13 +2,17 @@ package tagging
 
 import (
 	"fmt"
+	"slices"
 	"strings"
+	"time"
 
 	"github.com/bogem/id3v2/v2"
 )
 
 // ── MP3 Strategy ─────────────────────────────────────────────────────────────
 
+const musicBrainzUFIDOwner = "http://musicbrainz.org"
+
 type MP3Tagger struct{}
 
 func (t *MP3Tagger) WriteTags(filePath string, tags *TagMap) error {
@@ -18,33 +22,83 @@ func (t *MP3Tagger) WriteTags(filePath string, tags *TagMap) error {
 	}
 	defer func() { _ = tag.Close() }()
 
-	tag.SetVersion(4)
+	if tags.MergeStrategy == MergeFillMissing {
+		fillMissingID3Frames(tag, tags)
+	} else {
+		writeID3Frames(tag, tags)
+	}
+	return tag.Save()
+}
+
+// fillMissingID3Frames adds only the frames tag does not already have. Frames that may
+// repeat (TXXX, APIC, USLT, ...) are matched by their description or type, the way
+// id3v2 tells them apart.
+func fillMissingID3Frames(tag *id3v2.Tag, tags *TagMap) {
+	fresh := id3v2.NewEmptyTag()
+	writeID3Frames(fresh, tags)
+
+	setID3Version(tag, tags.ID3v23)
+	for id, frames := range fresh.AllFrames() {
+		existing := make(map[string]bool)
+		for _, f := range tag.GetFrames(id) {
+			existing[f.UniqueIdentifier()] = true
+		}
+		for _, f := range frames {
+			if !existing[f.UniqueIdentifier()] {
+				tag.AddFrame(id, f)
+			}
+		}
+	}
+}
+
+// writeID3Frames sets the frames for tags on tag, in ID3v2.4 or, when tags.ID3v23 is set,
+// ID3v2.3. It is shared by every format that carries an ID3 tag (MP3, and the id3 chunk of
+// WAV and AIFF).
+func writeID3Frames(tag *id3v2.Tag, tags *TagMap) {
+	w := newID3Writer(tag, tags.ID3v23)
 
 	if tags.Title != "" {
-		tag.SetTitle(tags.Title)
+		w.text(tag.CommonID("Title"), tags.Title)
 	}
 	if len(tags.Artists) > 0 {
-		tag.AddTextFrame("TPE1", tag.DefaultEncoding(), strings.Join(tags.Artists, "\x00"))
+		w.text("TPE1", w.join(tags.Artists))
+	}
+	if len(tags.ArtistCredits) > 0 {
+		w.userText("ARTISTS", w.join(tags.ArtistCredits))
 	}
 	if tags.Album != "" {
-		tag.SetAlbum(tags.Album)
+		w.text(tag.CommonID("Album/Movie/Show title"), tags.Album)
 	}
 	if tags.Year > 0 {
-		tag.SetYear(fmt.Sprintf("%d", tags.Year))
+		w.date(tags.Year, tags.ReleaseDate)
+	}
+	if tags.OriginalDate != "" {
+		w.originalDate(tags.OriginalDate)
+	} else if tags.OriginalYear > 0 {
+		w.originalDate(fmt.Sprintf("%d", tags.OriginalYear))
 	}
 	if tags.Genre != "" {
 		genres := strings.Split(tags.Genre, GenreSeparator)
 		for _, g := range genres {
 			g = strings.TrimSpace(g)
 			if g != "" {
-				tag.AddTextFrame("TCON", tag.DefaultEncoding(), g)
+				w.text("TCON", g)
 			}
 		}
 	}
 	tag.DeleteFrames("TIT3")
 
 	if len(tags.AlbumArtists) > 0 {
-		tag.AddTextFrame("TPE2", tag.DefaultEncoding(), strings.Join(tags.AlbumArtists, "\x00"))
+		w.text("TPE2", w.join(tags.AlbumArtists))
+	}
+	if tags.ArtistSort != "" {
+		w.text("TSOP", tags.ArtistSort)
+	}
+	if tags.AlbumArtistSort != "" {
+		w.text("TSO2", tags.AlbumArtistSort)
+	}
+	if tags.AlbumSort != "" {
+		w.text("TSOA", tags.AlbumSort)
 	}
 
 	if tags.TrackNum > 0 {
@@ -52,37 +106,38 @@ func (t *MP3Tagger) WriteTags(filePath string, tags *TagMap) error {
 		if tags.TrackTotal > 0 {
 			trackStr = fmt.Sprintf("%d/%d", tags.TrackNum, tags.TrackTotal)
 		}
-		tag.AddTextFrame(tag.CommonID("Track number/Position in set"), tag.DefaultEncoding(), trackStr)
+		w.text(tag.CommonID("Track number/Position in set"), trackStr)
 	}
 	if tags.DiscNum > 0 {
 		discStr := fmt.Sprintf("%d", tags.DiscNum)
 		if tags.DiscTotal > 0 {
 			discStr = fmt.Sprintf("%d/%d", tags.DiscNum, tags.DiscTotal)
 		}
-		tag.AddTextFrame(tag.CommonID("Part of a set"), tag.DefaultEncoding(), discStr)
+		w.text(tag.CommonID("Part of a set"), discStr)
 	}
 
 	if tags.Composer != "" {
-		tag.AddTextFrame(tag.CommonID("Composer"), tag.DefaultEncoding(), tags.Composer)
+		w.text(tag.CommonID("Composer"), tags.Composer)
 	}
+	w.credits(involvedPeople(tags), musicianCredits(tags.Performers))
 	if tags.Copyright != "" {
-		tag.AddTextFrame(tag.CommonID("Copyright message"), tag.DefaultEncoding(), tags.Copyright)
+		w.text(tag.CommonID("Copyright message"), tags.Copyright)
 	}
 	if tags.BPM > 0 {
-		tag.AddTextFrame(tag.CommonID("BPM"), tag.DefaultEncoding(), fmt.Sprintf("%d", tags.BPM))
+		w.text(tag.CommonID("BPM"), fmt.Sprintf("%d", tags.BPM))
 	}
 	if tags.Lyrics != "" {
-		tag.AddTextFrame(tag.CommonID("Lyrics"), tag.DefaultEncoding(), tags.Lyrics)
+		w.text(tag.CommonID("Lyrics"), tags.Lyrics)
 	}
 	if tags.Language != "" {
-		tag.AddTextFrame("TLAN", tag.DefaultEncoding(), tags.Language)
+		w.text("TLAN", tags.Language)
 	}
 
 	// Apply Custom Metadata Mapping
 	for k, v := range tags.Custom {
 		if k == "LYRICS" {
 			tag.AddUnsynchronisedLyricsFrame(id3v2.UnsynchronisedLyricsFrame{
-				Encoding:          id3v2.EncodingUTF8,
+				Encoding:          w.encoding("LRC" + v),
 				Language:          "eng",
 				ContentDescriptor: "LRC",
 				Lyrics:            v,
@@ -92,41 +147,249 @@ func (t *MP3Tagger) WriteTags(filePath string, tags *TagMap) error {
 		// Map known custom fields to common IDs if applicable, else UserDefined
 		switch k {
 		case "LABEL":
-			tag.AddTextFrame(tag.CommonID("Publisher"), tag.DefaultEncoding(), v)
+			w.text(tag.CommonID("Publisher"), v)
 		case "ISRC":
-			tag.AddTextFrame(tag.CommonID("ISRC"), tag.DefaultEncoding(), v)
+			w.text(tag.CommonID("ISRC"), v)
 		case "KEY":
-			tag.AddTextFrame(tag.CommonID("Key"), tag.DefaultEncoding(), v)
+			w.text(tag.CommonID("Key"), v)
 		case "VERSION":
-			tag.AddTextFrame(tag.CommonID("Version"), tag.DefaultEncoding(), v)
+			w.text(tag.CommonID("Version"), v)
 		case "URL":
-			tag.AddTextFrame(tag.CommonID("WWWAudioSource"), tag.DefaultEncoding(), v)
+			w.text(tag.CommonID("WWWAudioSource"), v)
 		case "COMPILATION":
-			tag.AddTextFrame("TCMP", tag.DefaultEncoding(), v)
-		case "COUNTRY":
-			tag.AddUserDefinedTextFrame(id3v2.UserDefinedTextFrame{
-				Encoding:    id3v2.EncodingUTF8,
-				Description: "COUNTRY",
-				Value:       v,
+			w.text("TCMP", v)
+		case "MUSICBRAINZ_TRACKID":
+			// Picard and Navidrome read the recording ID from the MusicBrainz UFID frame.
+			tag.AddUFIDFrame(id3v2.UFIDFrame{
+				OwnerIdentifier: musicBrainzUFIDOwner,
+				Identifier:      []byte(v),
 			})
+		case "MUSICBRAINZ_ALBUMID", "MUSICBRAINZ_RELEASETRACKID":
+			w.userText(musicBrainzIDNames[k], v)
+		case "COUNTRY":
+			w.userText("COUNTRY", v)
 		default:
-			tag.AddUserDefinedTextFrame(id3v2.UserDefinedTextFrame{
-				Encoding:    id3v2.EncodingUTF8,
-				Description: k,
-				Value:       v,
-			})
+			w.userText(k, v)
 		}
 	}
 
 	if len(tags.CoverArt) > 0 {
 		tag.AddAttachedPicture(id3v2.PictureFrame{
-			Encoding:    id3v2.EncodingUTF8,
+			Encoding:    w.encoding("Front Cover"),
 			MimeType:    tags.CoverMime,
 			PictureType: id3v2.PTFrontCover,
 			Description: "Front Cover",
 			Picture:     tags.CoverArt,
 		})
 	}
+}
 
-	return tag.Save()
+// involvedPeople lists the production credits of tags as the role/name pairs of a TIPL
+// frame, with Picard's role names.
+func involvedPeople(tags *TagMap) []string {
+	var people []string
+	for _, credit := range [][2]string{{"producer", tags.Producer}, {"engineer", tags.Engineer}, {"mix", tags.Mixer}} {
+		if credit[1] != "" {
+			people = append(people, credit[0], credit[1])
+		}
+	}
+	return people
+}
+
+// musicianCredits lists "Name (instrument)" performers as the instrument/name pairs of a
+// TMCL frame. A performer without an instrument is credited as "performer".
+func musicianCredits(performers []string) []string {
+	var musicians []string
+	for _, p := range performers {
+		name, role := p, "performer"
+		if i := strings.LastIndex(p, " ("); i > 0 && strings.HasSuffix(p, ")") {
+			name, role = p[:i], p[i+2:len(p)-1]
+		}
+		musicians = append(musicians, role, name)
+	}
+	return musicians
+}
+
+// id3Writer adds frames to tag in the encoding and layout of its ID3 version.
+type id3Writer struct {
+	tag *id3v2.Tag
+	v23 bool
+}
+
+func newID3Writer(tag *id3v2.Tag, v23 bool) *id3Writer {
+	setID3Version(tag, v23)
+	return &id3Writer{tag: tag, v23: v23}
+}
+
+func (w *id3Writer) encoding(text string) id3v2.Encoding {
+	return id3Encoding(text, w.v23)
+}
+
+// join joins the values of a multi-valued frame: null-separated in ID3v2.4, and with "/"
+// in ID3v2.3, which has no multi-valued text frames.
+func (w *id3Writer) join(values []string) string {
+	if w.v23 {
+		return strings.Join(values, "/")
+	}
+	return strings.Join(values, "\x00")
+}
+
+func (w *id3Writer) text(id, value string) {
+	w.tag.AddTextFrame(id, w.encoding(value), value)
+}
+
+func (w *id3Writer) userText(description, value string) {
+	w.tag.AddUserDefinedTextFrame(id3v2.UserDefinedTextFrame{
+		Encoding:    w.encoding(description + value),
+		Description: description,
+		Value:       value,
+	})
+}
+
+// date writes the release year as TDRC in ID3v2.4. ID3v2.3 splits it into TYER and a DDMM
+// TDAT, written when releaseDate is a full date in that year.
+func (w *id3Writer) date(year int, releaseDate string) {
+	yearStr := fmt.Sprintf("%d", year)
+	if !w.v23 {
+		w.text("TDRC", yearStr)
+		return
+	}
+	w.text("TYER", yearStr)
+	w.tag.DeleteFrames("TDAT")
+	if d, err := time.Parse("2006-01-02", releaseDate); err == nil && d.Year() == year {
+		w.text("TDAT", d.Format("0201"))
+	}
+}
+
+// originalDate writes the original release date as TDOR in ID3v2.4, and its year as TORY
+// in ID3v2.3.
+func (w *id3Writer) originalDate(date string) {
+	if !w.v23 {
+		w.text("TDOR", date)
+	} else if len(date) >= 4 {
+		w.text("TORY", date[:4])
+	}
+}
+
+// credits writes the role/name pairs of the production credits and the musicians as TIPL
+// and TMCL in ID3v2.4. ID3v2.3 has only IPLS, which takes both.
+func (w *id3Writer) credits(people, musicians []string) {
+	if w.v23 {
+		if all := slices.Concat(people, musicians); len(all) > 0 {
+			w.text("IPLS", strings.Join(all, "\x00"))
+		}
+		return
+	}
+	if len(people) > 0 {
+		w.text("TIPL", strings.Join(people, "\x00"))
+	}
+	if len(musicians) > 0 {
+		w.text("TMCL", strings.Join(musicians, "\x00"))
+	}
+}
+
+// id3Encoding is UTF-8 in ID3v2.4. ID3v2.3 has no UTF-8, so text that fits in Latin-1 is
+// written as ISO-8859-1 and anything else as UTF-16.
+func id3Encoding(text string, v23 bool) id3v2.Encoding {
+	if !v23 {
+		return id3v2.EncodingUTF8
+	}
+	for _, r := range text {
+		if r > 0xFF {
+			return id3v2.EncodingUTF16
+		}
+	}
+	return id3v2.EncodingISO
+}
+
+// id3v24OnlyFrames are dropped when a tag moves to ID3v2.3, which has no equivalent.
+var id3v24OnlyFrames = []string{"TDEN", "TDRL", "TDTG", "TMOO", "TPRO", "TSST"}
+
+// setID3Version sets tag to ID3v2.3 or 2.4. Moving a tag read from a file to 2.3 converts
+// the frames it already has: dates and credits move to their 2.3 frames, other 2.4-only
+// frames are dropped, and text in an encoding 2.3 lacks is re-encoded.
+func setID3Version(tag *id3v2.Tag, v23 bool) {
+	if !v23 {
+		tag.SetVersion(4)
+		return
+	}
+	tag.SetVersion(3)
+
+	if date := id3Text(tag, "TDRC"); len(date) >= 4 && tag.GetLastFrame("TYER") == nil {
+		tag.AddTextFrame("TYER", id3v2.EncodingISO, date[:4])
+		if d, err := time.Parse("2006-01-02", date); err == nil {
+			tag.AddTextFrame("TDAT", id3v2.EncodingISO, d.Format("0201"))
+		}
+	}
+	if date := id3Text(tag, "TDOR"); len(date) >= 4 && tag.GetLastFrame("TORY") == nil {
+		tag.AddTextFrame("TORY", id3v2.EncodingISO, date[:4])
+	}
+	if tag.GetLastFrame("IPLS") == nil {
+		var pairs []string
+		for _, id := range []string{"TIPL", "TMCL"} {
+			if text := id3Text(tag, id); text != "" {
+				pairs = append(pairs, text)
+			}
+		}
+		if len(pairs) > 0 {
+			text := strings.Join(pairs, "\x00")
+			tag.AddTextFrame("IPLS", id3Encoding(text, true), text)
+		}
+	}
+	for _, id := range append([]string{"TDRC", "TDOR", "TIPL", "TMCL"}, id3v24OnlyFrames...) {
+		tag.DeleteFrames(id)
+	}
+
+	for id, frames := range tag.AllFrames() {
+		for _, f := range frames {
+			switch f := f.(type) {
+			case id3v2.TextFrame:
+				text := f.Text
+				if id != "IPLS" {
+					text = strings.ReplaceAll(text, "\x00", "/")
+				}
+				if text != f.Text || !isID3v23Encoding(f.Encoding) {
+					f.Text = text
+					if !isID3v23Encoding(f.Encoding) {
+						f.Encoding = id3Encoding(text, true)
+					}
+					tag.AddFrame(id, f)
+				}
+			case id3v2.UserDefinedTextFrame:
+				if !isID3v23Encoding(f.Encoding) {
+					f.Value = strings.ReplaceAll(f.Value, "\x00", "/")
+					f.Encoding = id3Encoding(f.Description+f.Value, true)
+					tag.AddFrame(id, f)
+				}
+			case id3v2.UnsynchronisedLyricsFrame:
+				if !isID3v23Encoding(f.Encoding) {
+					f.Encoding = id3Encoding(f.ContentDescriptor+f.Lyrics, true)
+					tag.AddFrame(id, f)
+				}
+			case id3v2.CommentFrame:
+				if !isID3v23Encoding(f.Encoding) {
+					f.Encoding = id3Encoding(f.Description+f.Text, true)
+					tag.AddFrame(id, f)
+				}
+			case id3v2.PictureFrame:
+				if !isID3v23Encoding(f.Encoding) {
+					f.Encoding = id3Encoding(f.Description, true)
+					tag.AddFrame(id, f)
+				}
+			}
+		}
+	}
+}
+
+// id3Text returns the text of tag's id frame, or "" when it has none.
+func id3Text(tag *id3v2.Tag, id string) string {
+	if f, ok := tag.GetLastFrame(id).(id3v2.TextFrame); ok {
+		return f.Text
+	}
+	return ""
+}
+
+// isID3v23Encoding reports whether ID3v2.3 defines enc: ISO-8859-1 and UTF-16 with a BOM.
+func isID3v23Encoding(enc id3v2.Encoding) bool {
+	return enc.Equals(id3v2.EncodingISO) || enc.Equals(id3v2.EncodingUTF16)
 }
@@ -0,0 +1,207 @@
+package tagging
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+
+	"github.com/cesargomez89/navidrums/internal/constants"
+)
+
+// ── MP4 Freeform Atoms ───────────────────────────────────────────────────────
+
+// freeformMean is the namespace iTunes-style freeform ("----") atoms are written under.
+const freeformMean = "com.apple.iTunes"
+
+var errAtomNotFound = errors.New("atom not found")
+
+// mp4FreeformTags picks the custom tags that have a freeform atom name.
+func mp4FreeformTags(custom map[string]string) map[string]string {
+	out := make(map[string]string)
+	for k, v := range custom {
+		if name, ok := musicBrainzIDNames[k]; ok && v != "" {
+			out[name] = v
+		}
+	}
+	return out
+}
+
+// addMP4FreeformTags appends a freeform atom per tag, then the extra atoms, to the ilst of
+// the MP4 file at path. ffmpeg has no way to write these, so the atoms are spliced in after
+// it has muxed the file.
+func addMP4FreeformTags(path string, tags map[string]string, extra ...[]byte) error {
+	if len(tags) == 0 && len(extra) == 0 {
+		return nil
+	}
+	data, err := os.ReadFile(path) //nolint:gosec // path is the tagger's own temp file
+	if err != nil {
+		return err
+	}
+	out, err := insertFreeformAtoms(data, tags, extra...)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, out, constants.FilePermissions)
+}
+
+// insertFreeformAtoms returns a copy of the MP4 data with the tags, then the extra atoms,
+// appended to moov/udta/meta/ilst. When moov precedes mdat (faststart), the stco/co64 chunk offsets
+// are shifted by the inserted size so the audio stays addressable.
+func insertFreeformAtoms(data []byte, tags map[string]string, extra ...[]byte) ([]byte, error) {
+	moovOff, moovSize, err := findAtom(data, 0, len(data), "moov")
+	if err != nil {
+		return nil, err
+	}
+	moov := data[moovOff : moovOff+moovSize]
+
+	udtaOff, udtaSize, err := findAtom(moov, 8, len(moov), "udta")
+	if err != nil {
+		return nil, err
+	}
+	metaOff, metaSize, err := findAtom(moov, udtaOff+8, udtaOff+udtaSize, "meta")
+	if err != nil {
+		return nil, err
+	}
+	// meta is a full box: version and flags precede its children.
+	ilstOff, ilstSize, err := findAtom(moov, metaOff+12, metaOff+metaSize, "ilst")
+	if err != nil {
+		return nil, err
+	}
+
+	names := make([]string, 0, len(tags))
+	for name := range tags {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	var payload []byte
+	for _, name := range names {
+		payload = append(payload, freeformAtom(name, tags[name])...)
+	}
+	for _, a := range extra {
+		payload = append(payload, a...)
+	}
+
+	insertAt := ilstOff + ilstSize
+	newMoov := make([]byte, 0, len(moov)+len(payload))
+	newMoov = append(newMoov, moov[:insertAt]...)
+	newMoov = append(newMoov, payload...)
+	newMoov = append(newMoov, moov[insertAt:]...)
+	for _, off := range []int{0, udtaOff, metaOff, ilstOff} {
+		size := binary.BigEndian.Uint32(newMoov[off:])
+		binary.BigEndian.PutUint32(newMoov[off:], size+uint32(len(payload))) //nolint:gosec // tag payloads are small
+	}
+
+	mdatOff, _, mdatErr := findAtom(data, 0, len(data), "mdat")
+	if mdatErr == nil && moovOff < mdatOff {
+		if err := shiftChunkOffsets(newMoov, len(payload)); err != nil {
+			return nil, err
+		}
+	}
+
+	out := make([]byte, 0, len(data)+len(payload))
+	out = append(out, data[:moovOff]...)
+	out = append(out, newMoov...)
+	out = append(out, data[moovOff+moovSize:]...)
+	return out, nil
+}
+
+// findAtom returns the offset and size of the first atom of type typ between start and end.
+func findAtom(buf []byte, start, end int, typ string) (int, int, error) {
+	for i := start; i+8 <= end; {
+		size := int(binary.BigEndian.Uint32(buf[i:]))
+		name := string(buf[i+4 : i+8])
+		switch size {
+		case 0:
+			size = end - i
+		case 1:
+			if i+16 > end {
+				return 0, 0, fmt.Errorf("truncated %s atom", name)
+			}
+			size = int(binary.BigEndian.Uint64(buf[i+8:])) //nolint:gosec // bounds checked below
+		}
+		if size < 8 || i+size > end {
+			return 0, 0, fmt.Errorf("invalid %s atom size %d", name, size)
+		}
+		if name == typ {
+			return i, size, nil
+		}
+		i += size
+	}
+	return 0, 0, fmt.Errorf("%s: %w", typ, errAtomNotFound)
+}
+
+// shiftChunkOffsets adds delta to every stco/co64 entry of every track in moov.
+func shiftChunkOffsets(moov []byte, delta int) error {
+	var walk func(start, end int) error
+	walk = func(start, end int) error {
+		for i := start; i+8 <= end; {
+			size := int(binary.BigEndian.Uint32(moov[i:]))
+			if size < 8 || i+size > end {
+				return fmt.Errorf("invalid atom size %d", size)
+			}
+			switch string(moov[i+4 : i+8]) {
+			case "trak", "mdia", "minf", "stbl":
+				if err := walk(i+8, i+size); err != nil {
+					return err
+				}
+			case "stco":
+				count := int(binary.BigEndian.Uint32(moov[i+12:]))
+				for j := 0; j < count && i+16+j*4+4 <= i+size; j++ {
+					p := i + 16 + j*4
+					binary.BigEndian.PutUint32(moov[p:], binary.BigEndian.Uint32(moov[p:])+uint32(delta)) //nolint:gosec // delta is small
+				}
+			case "co64":
+				count := int(binary.BigEndian.Uint32(moov[i+12:]))
+				for j := 0; j < count && i+16+j*8+8 <= i+size; j++ {
+					p := i + 16 + j*8
+					binary.BigEndian.PutUint64(moov[p:], binary.BigEndian.Uint64(moov[p:])+uint64(delta)) //nolint:gosec // delta is small
+				}
+			}
+			i += size
+		}
+		return nil
+	}
+	return walk(8, len(moov))
+}
+
+// freeformAtom builds a "----" atom holding a UTF-8 value under freeformMean.
+func freeformAtom(name, value string) []byte {
+	fullBox := func(payload []byte) []byte {
+		return append([]byte{0, 0, 0, 0}, payload...)
+	}
+	// Data atoms carry a type indicator (1 = UTF-8) followed by a zero locale.
+	data := append([]byte{0, 0, 0, 1, 0, 0, 0, 0}, value...)
+	return atom("----",
+		atom("mean", fullBox([]byte(freeformMean))),
+		atom("name", fullBox([]byte(name))),
+		atom("data", data),
+	)
+}
+
+// pgapAtom builds the iTunes gapless playback flag: a data atom of type 21 (integer)
+// holding 1.
+func pgapAtom() []byte {
+	return atom("pgap", atom("data", []byte{0, 0, 0, 21, 0, 0, 0, 0, 1}))
+}
+
+// rtngAtom builds the iTunes content advisory marking a track explicit: a data atom of
+// type 21 (integer) holding 1 (2 would mark it clean).
+func rtngAtom() []byte {
+	return atom("rtng", atom("data", []byte{0, 0, 0, 21, 0, 0, 0, 0, 1}))
+}
+
+func atom(typ string, children ...[]byte) []byte {
+	size := 8
+	for _, c := range children {
+		size += len(c)
+	}
+	buf := make([]byte, 8, size)
+	binary.BigEndian.PutUint32(buf, uint32(size)) //nolint:gosec // atoms built here are small
+	copy(buf[4:], typ)
+	for _, c := range children {
+		buf = append(buf, c...)
+	}
+	return buf
+}
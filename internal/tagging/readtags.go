@@ -0,0 +1,383 @@
+package tagging
+
+import (
+	"bufio"
+	"encoding/binary"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/bogem/id3v2/v2"
+	"github.com/go-flac/flacvorbis"
+	"github.com/go-flac/go-flac"
+
+	"github.com/cesargomez89/navidrums/internal/domain"
+)
+
+// ── Reading Tags ─────────────────────────────────────────────────────────────
+
+// ReadTags reads the tags of a FLAC, MP3 or MP4 file into a track, for files that were
+// not downloaded here. Every format is read into Vorbis comment names first, so the
+// names this package writes, and Picard's, map back to the same fields.
+func ReadTags(filePath string) (*domain.Track, error) {
+	var fields map[string][]string
+	var err error
+	switch strings.ToLower(filepath.Ext(filePath)) {
+	case ".flac":
+		fields, err = readFLACTags(filePath)
+	case ".mp3":
+		fields, err = readID3Tags(filePath)
+	case ".mp4", ".m4a":
+		fields, err = readMP4Tags(filePath)
+	default:
+		return nil, ErrUnsupportedFormat
+	}
+	if err != nil {
+		return nil, err
+	}
+	return trackFromTags(fields), nil
+}
+
+func readFLACTags(filePath string) (map[string][]string, error) {
+	f, err := os.Open(filePath) //nolint:gosec // path is a file being imported
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = f.Close() }()
+
+	meta, err := flac.ParseMetadata(bufio.NewReader(f))
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse FLAC metadata: %w", err)
+	}
+	fields := make(map[string][]string)
+	for _, b := range meta.Meta {
+		if b.Type != flac.VorbisComment {
+			continue
+		}
+		vc, err := flacvorbis.ParseFromMetaDataBlock(*b)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse Vorbis comment: %w", err)
+		}
+		for _, c := range vc.Comments {
+			if name, value, ok := strings.Cut(c, "="); ok && value != "" {
+				name = strings.ToUpper(name)
+				fields[name] = append(fields[name], value)
+			}
+		}
+	}
+	return fields, nil
+}
+
+// id3TextFields maps ID3 text frames to the Vorbis comments they carry. Dates, which
+// ID3v2.3 and 2.4 store in different frames, are read by readID3Dates.
+var id3TextFields = map[string]string{
+	"TIT2": "TITLE",
+	"TPE1": "ARTIST",
+	"TPE2": "ALBUMARTIST",
+	"TALB": "ALBUM",
+	"TRCK": "TRACKNUMBER",
+	"TPOS": "DISCNUMBER",
+	"TCON": "GENRE",
+	"TSRC": "ISRC",
+	"TPUB": "LABEL",
+	"TCOM": "COMPOSER",
+	"TCOP": "COPYRIGHT",
+	"TBPM": "BPM",
+	"TLAN": "LANGUAGE",
+	"TSOP": "ARTISTSORT",
+	"TSO2": "ALBUMARTISTSORT",
+	"TCMP": "COMPILATION",
+}
+
+// picardIDNames maps the TXXX descriptions and freeform atom names Picard writes for
+// MusicBrainz IDs back to their Vorbis comments.
+var picardIDNames = map[string]string{
+	"MusicBrainz Track Id":         "MUSICBRAINZ_TRACKID",
+	"MusicBrainz Album Id":         "MUSICBRAINZ_ALBUMID",
+	"MusicBrainz Release Track Id": "MUSICBRAINZ_RELEASETRACKID",
+	"MusicBrainz Release Group Id": "MUSICBRAINZ_RELEASEGROUPID",
+	"MusicBrainz Artist Id":        "MUSICBRAINZ_ARTISTID",
+	"MusicBrainz Album Artist Id":  "MUSICBRAINZ_ALBUMARTISTID",
+}
+
+// userFieldName returns the Vorbis comment a TXXX frame or freeform atom carries.
+func userFieldName(description string) string {
+	if name, ok := picardIDNames[description]; ok {
+		return name
+	}
+	return strings.ToUpper(description)
+}
+
+func readID3Tags(filePath string) (map[string][]string, error) {
+	tag, err := id3v2.Open(filePath, id3v2.Options{Parse: true})
+	if err != nil {
+		return nil, fmt.Errorf("failed to open MP3 file: %w", err)
+	}
+	defer func() { _ = tag.Close() }()
+
+	fields := make(map[string][]string)
+	add := func(name, value string) {
+		// ID3v2.4 separates values with nulls.
+		for _, v := range strings.Split(value, "\x00") {
+			if v = strings.TrimSpace(v); v != "" {
+				fields[name] = append(fields[name], v)
+			}
+		}
+	}
+	for id, name := range id3TextFields {
+		for _, f := range tag.GetFrames(id) {
+			if tf, ok := f.(id3v2.TextFrame); ok {
+				add(name, tf.Text)
+			}
+		}
+	}
+	readID3Dates(tag, add)
+	for _, f := range tag.GetFrames("TXXX") {
+		if udf, ok := f.(id3v2.UserDefinedTextFrame); ok {
+			add(userFieldName(udf.Description), udf.Value)
+		}
+	}
+	for _, f := range tag.GetFrames("UFID") {
+		if uf, ok := f.(id3v2.UFIDFrame); ok && uf.OwnerIdentifier == musicBrainzUFIDOwner {
+			add("MUSICBRAINZ_TRACKID", string(uf.Identifier))
+		}
+	}
+	return fields, nil
+}
+
+// readID3Dates reads the release and original dates in a fixed order: the ID3v2.4 TDRC and
+// TDOR frames first, then ID3v2.3's TYER, completed by its DDMM TDAT, and TORY.
+func readID3Dates(tag *id3v2.Tag, add func(name, value string)) {
+	if date := id3Text(tag, "TDRC"); date != "" {
+		add("DATE", date)
+	} else if year := id3Text(tag, "TYER"); year != "" {
+		add("DATE", id3v23Date(year, id3Text(tag, "TDAT")))
+	}
+	if date := id3Text(tag, "TDOR"); date != "" {
+		add("ORIGINALDATE", date)
+	} else if year := id3Text(tag, "TORY"); year != "" {
+		add("ORIGINALDATE", year)
+	}
+}
+
+// id3v23Date joins a TYER year and a DDMM TDAT into a YYYY-MM-DD date, or returns the year
+// alone when the TDAT is missing or malformed.
+func id3v23Date(year, ddmm string) string {
+	d, err := time.Parse("0201", strings.TrimSpace(ddmm))
+	if err != nil || len(strings.TrimSpace(year)) != 4 {
+		return year
+	}
+	return strings.TrimSpace(year) + "-" + d.Format("01-02")
+}
+
+// mp4TextFields maps iTunes metadata atoms to the Vorbis comments they carry.
+var mp4TextFields = map[string]string{
+	"\xa9nam": "TITLE",
+	"\xa9ART": "ARTIST",
+	"aART":    "ALBUMARTIST",
+	"\xa9alb": "ALBUM",
+	"\xa9day": "DATE",
+	"\xa9gen": "GENRE",
+	"\xa9wrt": "COMPOSER",
+	"cprt":    "COPYRIGHT",
+	"soar":    "ARTISTSORT",
+	"soaa":    "ALBUMARTISTSORT",
+}
+
+func readMP4Tags(filePath string) (map[string][]string, error) {
+	f, err := os.Open(filePath) //nolint:gosec // path is a file being imported
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = f.Close() }()
+
+	info, err := f.Stat()
+	if err != nil {
+		return nil, err
+	}
+	moov, err := readMoov(f, info.Size())
+	if err != nil {
+		return nil, err
+	}
+
+	fields := make(map[string][]string)
+	off, size := 0, len(moov)
+	for _, typ := range []string{"udta", "meta", "ilst"} {
+		start := off + 8
+		if typ == "ilst" {
+			start += 4 // meta is a full box
+		}
+		if off, size, err = findAtom(moov, start, off+size, typ); err != nil {
+			return fields, nil //nolint:nilerr // a file without an ilst has no tags
+		}
+	}
+
+	for i := off + 8; i+8 <= off+size; {
+		itemSize := int(binary.BigEndian.Uint32(moov[i:]))
+		if itemSize < 8 || i+itemSize > off+size {
+			break
+		}
+		item := moov[i : i+itemSize]
+		i += itemSize
+
+		name := string(item[4:8])
+		freeform := name == "----"
+		if freeform {
+			name = mp4FreeformName(item)
+		}
+		data := mp4AtomData(item)
+		if data == nil || name == "" {
+			continue
+		}
+
+		switch name {
+		case "trkn", "disk":
+			if len(data) < 6 {
+				continue
+			}
+			field := "TRACKNUMBER"
+			if name == "disk" {
+				field = "DISCNUMBER"
+			}
+			num, total := binary.BigEndian.Uint16(data[2:]), binary.BigEndian.Uint16(data[4:])
+			fields[field] = append(fields[field], fmt.Sprintf("%d/%d", num, total))
+		case "tmpo":
+			if len(data) >= 2 {
+				fields["BPM"] = append(fields["BPM"], strconv.Itoa(int(binary.BigEndian.Uint16(data))))
+			}
+		case "cpil":
+			if len(data) >= 1 && data[0] == 1 {
+				fields["COMPILATION"] = append(fields["COMPILATION"], "1")
+			}
+		default:
+			field, ok := mp4TextFields[name]
+			if freeform {
+				field, ok = userFieldName(name), true
+			}
+			if v := string(data); ok && v != "" {
+				fields[field] = append(fields[field], v)
+			}
+		}
+	}
+	return fields, nil
+}
+
+// mp4FreeformName returns the name child of a freeform ("----") atom.
+func mp4FreeformName(item []byte) string {
+	off, size, err := findAtom(item, 8, len(item), "name")
+	if err != nil || size < 12 {
+		return ""
+	}
+	return string(item[off+12 : off+size]) // name is a full box
+}
+
+// mp4AtomData returns the value of the data child of a metadata item, past its type and
+// locale fields.
+func mp4AtomData(item []byte) []byte {
+	off, size, err := findAtom(item, 8, len(item), "data")
+	if err != nil || size < 16 {
+		return nil
+	}
+	return item[off+16 : off+size]
+}
+
+// trackFromTags fills a track from Vorbis comments by name.
+func trackFromTags(fields map[string][]string) *domain.Track {
+	first := func(names ...string) string {
+		for _, name := range names {
+			if values := fields[name]; len(values) > 0 {
+				return strings.TrimSpace(values[0])
+			}
+		}
+		return ""
+	}
+	number := func(names ...string) int {
+		n, _ := strconv.Atoi(first(names...))
+		return n
+	}
+
+	t := &domain.Track{
+		Title:           first("TITLE"),
+		Artists:         fields["ARTISTS"],
+		Artist:          first("ARTIST"),
+		AlbumArtists:    fields["ALBUMARTIST"],
+		AlbumArtist:     first("ALBUMARTIST"),
+		Album:           first("ALBUM"),
+		ArtistSort:      first("ARTISTSORT"),
+		AlbumArtistSort: first("ALBUMARTISTSORT"),
+		Genre:           strings.Join(fields["GENRE"], GenreSeparator),
+		Mood:            first("MOOD"),
+		Language:        first("LANGUAGE"),
+		Label:           first("LABEL", "ORGANIZATION", "PUBLISHER"),
+		ISRC:            first("ISRC"),
+		Copyright:       first("COPYRIGHT"),
+		Composer:        first("COMPOSER"),
+		Producer:        first("PRODUCER"),
+		Engineer:        first("ENGINEER"),
+		Mixer:           first("MIXER"),
+		Performers:      fields["PERFORMER"],
+		BPM:             number("BPM"),
+		Lyrics:          first("UNSYNCEDLYRICS"),
+		OriginalDate:    first("ORIGINALDATE"),
+		OriginalYear:    number("ORIGINALYEAR"),
+		Barcode:         first("BARCODE"),
+		CatalogNumber:   first("CATALOGNUMBER"),
+		ReleaseType:     first("RELEASETYPE"),
+		ReleaseID:       first("MUSICBRAINZ_RELEASEGROUPID"),
+		MBAlbumID:       first("MUSICBRAINZ_ALBUMID"),
+		ReleaseTrackID:  first("MUSICBRAINZ_RELEASETRACKID"),
+		ArtistIDs:       splitIDs(fields["MUSICBRAINZ_ARTISTID"]),
+		AlbumArtistIDs:  splitIDs(fields["MUSICBRAINZ_ALBUMARTISTID"]),
+		Compilation:     first("COMPILATION") == "1",
+	}
+	if len(t.Artists) == 0 {
+		t.Artists = fields["ARTIST"]
+	}
+	if id := first("MUSICBRAINZ_TRACKID"); id != "" {
+		t.RecordingID = &id
+	}
+	t.TrackNumber, t.TotalTracks = numberAndTotal(first("TRACKNUMBER"), number("TRACKTOTAL", "TOTALTRACKS"))
+	t.DiscNumber, t.TotalDiscs = numberAndTotal(first("DISCNUMBER"), number("DISCTOTAL", "TOTALDISCS"))
+
+	date := first("RELEASEDATE", "DATE")
+	if len(date) > 4 {
+		t.ReleaseDate = date
+	}
+	if len(date) >= 4 {
+		t.Year, _ = strconv.Atoi(date[:4])
+	}
+
+	t.PathArtist = t.AlbumArtist
+	if t.PathArtist == "" {
+		t.PathArtist = t.Artist
+	}
+	return t
+}
+
+// numberAndTotal splits an "n/total" position, taking the total from total when the
+// position has none.
+func numberAndTotal(position string, total int) (int, int) {
+	num, of, _ := strings.Cut(position, "/")
+	n, _ := strconv.Atoi(strings.TrimSpace(num))
+	if t, err := strconv.Atoi(strings.TrimSpace(of)); err == nil && t > 0 {
+		total = t
+	}
+	return n, total
+}
+
+// splitIDs splits MusicBrainz IDs written as one "; "-joined value, as this package does,
+// or as one value each, as Picard does.
+func splitIDs(values []string) []string {
+	var ids []string
+	for _, v := range values {
+		for _, id := range strings.Split(v, ";") {
+			if id = strings.TrimSpace(id); id != "" {
+				ids = append(ids, id)
+			}
+		}
+	}
+	return ids
+}
@@ -5,9 +5,13 @@ import (
 	"fmt"
 	"net/http"
 	"path/filepath"
+	"regexp"
+	"strconv"
 	"strings"
 
+	"github.com/cesargomez89/navidrums/internal/constants"
 	"github.com/cesargomez89/navidrums/internal/domain"
+	"github.com/cesargomez89/navidrums/internal/storage"
 )
 
 var ErrUnsupportedFormat = errors.New("unsupported file format")
@@ -20,29 +24,145 @@ func SetGenreSeparator(sep string) {
 	}
 }
 
+// MergeStrategy decides what happens to tags already present in a file.
+type MergeStrategy string
+
+const (
+	// MergeOverwrite rewrites every field from the database.
+	MergeOverwrite MergeStrategy = "overwrite"
+	// MergeFillMissing keeps existing values and only writes fields the file lacks.
+	MergeFillMissing MergeStrategy = "fill-missing"
+)
+
+// ArtistMode decides which of a track's artists its ARTIST tag carries.
+type ArtistMode string
+
+const (
+	// ArtistAll writes every credited artist as its own ARTIST value, and again as ARTISTS
+	// the way Picard does.
+	ArtistAll ArtistMode = "all"
+	// ArtistPrimary writes only the track's primary artist.
+	ArtistPrimary ArtistMode = "primary"
+	// ArtistPrimaryPlusFeatures writes one ARTIST value crediting the other artists as
+	// features: "Primary feat. Guest 1, Guest 2".
+	ArtistPrimaryPlusFeatures ArtistMode = "primary-plus-features"
+)
+
+// TagOptions controls how TagFile writes a track's tags. The zero value overwrites the
+// file's tags and embeds the art and synced lyrics it has.
+type TagOptions struct {
+	// MergeStrategy decides what happens to tags already in the file; an unknown or empty
+	// strategy overwrites them. Fill-missing is honored by the FLAC and MP3 taggers; other
+	// formats are always rewritten.
+	MergeStrategy MergeStrategy
+	// ArtistMode decides which artists ARTIST carries; an unknown or empty mode writes all.
+	ArtistMode ArtistMode
+	// Art is the front cover to embed.
+	Art []byte
+	// Pictures are embedded after the front cover. Only FLAC files carry them.
+	Pictures []Picture
+	// SkipArt embeds no pictures at all, leaving the cover to the external file.
+	SkipArt bool
+	// SkipSyncedLyrics leaves synced lyrics out of the file's LYRICS tag.
+	SkipSyncedLyrics bool
+	// SkipOriginalDate leaves out the original release date tags (ORIGINALDATE,
+	// ORIGINALYEAR and TDOR), writing only the edition's date.
+	SkipOriginalDate bool
+	// Gapless marks a track that belongs to an album for gapless playback: GAPLESS=1, and
+	// the iTunes pgap atom in MP4 files.
+	Gapless bool
+	// ID3v23 writes ID3 tags as version 2.3 instead of 2.4, for players that can't read
+	// 2.4: UTF-16 text, "/"-joined values and TYER/TDAT dates.
+	ID3v23 bool
+}
+
 // ── Models & Interfaces ──────────────────────────────────────────────────────
 
 // TagMap represents the normalized metadata payload for all audio formats.
 type TagMap struct {
-	Custom       map[string]string
-	Lyrics       string
-	Title        string
-	Album        string
-	Genre        string
-	Mood         string
-	Language     string
-	Composer     string
-	Copyright    string
-	CoverMime    string
-	AlbumArtists []string
-	CoverArt     []byte
-	Artists      []string
-	Year         int
-	TrackTotal   int
-	DiscNum      int
-	DiscTotal    int
-	BPM          int
-	TrackNum     int
+	MergeStrategy   MergeStrategy
+	Custom          map[string]string
+	Lyrics          string
+	Title           string
+	Album           string
+	AlbumSort       string
+	ArtistSort      string
+	AlbumArtistSort string
+	Genre           string
+	Mood            string
+	Language        string
+	Composer        string
+	Producer        string
+	Engineer        string
+	Mixer           string
+	Copyright       string
+	ReleaseDate     string // full date of the edition; DATE/TDRC carry only its year
+	OriginalDate    string
+	CoverMime       string
+	Performers      []string // "Name (instrument)"; FLAC and ID3 only, like the other credits
+	AlbumArtists    []string
+	ArtistCredits   []string // ARTISTS, one value per artist; FLAC and ID3 only
+	CoverArt        []byte
+	Pictures        []Picture // embedded after the front cover; FLAC only
+	Artists         []string
+	Year            int
+	OriginalYear    int
+	TrackTotal      int
+	DiscNum         int
+	DiscTotal       int
+	BPM             int
+	TrackNum        int
+	Gapless         bool // the iTunes pgap atom; MP4 only, other formats get GAPLESS=1
+	Explicit        bool // the iTunes rtng atom; MP4 only, other formats get ITUNESADVISORY=1
+	ID3v23          bool // write ID3v2.3 rather than 2.4; MP3, WAV and AIFF only
+}
+
+// PictureType is the ID3/FLAC picture type of an embedded image.
+type PictureType uint32
+
+const (
+	PictureFrontCover PictureType = 3
+	PictureBackCover  PictureType = 4
+	PictureArtist     PictureType = 8
+)
+
+// String returns the description an embedded picture of this type is written with.
+func (t PictureType) String() string {
+	switch t {
+	case PictureFrontCover:
+		return "Front Cover"
+	case PictureBackCover:
+		return "Back Cover"
+	case PictureArtist:
+		return "Artist"
+	default:
+		return "Other"
+	}
+}
+
+// Picture is an image to embed besides the front cover, such as a back cover or a photo
+// of the artist.
+type Picture struct {
+	Mime string
+	Data []byte
+	Type PictureType
+}
+
+// imageMime sniffs the content type of image data, without parameters.
+func imageMime(data []byte) string {
+	mime := http.DetectContentType(data)
+	if idx := strings.Index(mime, ";"); idx != -1 {
+		mime = strings.TrimSpace(mime[:idx])
+	}
+	return mime
+}
+
+// musicBrainzIDNames maps MusicBrainz ID tags to the descriptions Picard uses for
+// ID3 TXXX frames and MP4 freeform atoms.
+var musicBrainzIDNames = map[string]string{
+	"MUSICBRAINZ_TRACKID":        "MusicBrainz Track Id",
+	"MUSICBRAINZ_ALBUMID":        "MusicBrainz Album Id",
+	"MUSICBRAINZ_RELEASETRACKID": "MusicBrainz Release Track Id",
 }
 
 // AudioTagger defines the Strategy interface for our format adapters.
@@ -53,20 +173,24 @@ type AudioTagger interface {
 // ── Factory & Normalizer ─────────────────────────────────────────────────────
 
 // TagFile writes metadata tags to the audio file using the Strategy pattern.
-func TagFile(filePath string, track *domain.Track, albumArtData []byte) error {
+func TagFile(filePath string, track *domain.Track, opts TagOptions) error {
 	// 1. Normalize the data ONCE
-	tags := buildTagMap(track, albumArtData)
+	tags := buildTagMap(track, opts)
 
 	// 2. Select the strategy based on extension
 	var tagger AudioTagger
 	ext := strings.ToLower(filepath.Ext(filePath))
 	switch ext {
-	case ".flac":
+	case constants.ExtFLAC:
 		tagger = &FLACTagger{}
-	case ".mp3":
+	case constants.ExtMP3:
 		tagger = &MP3Tagger{}
-	case ".mp4", ".m4a":
+	case constants.ExtMP4, constants.ExtM4A:
 		tagger = &MP4Tagger{}
+	case constants.ExtWAV:
+		tagger = &WAVTagger{}
+	case constants.ExtAIFF, constants.ExtAIF:
+		tagger = &AIFFTagger{}
 	default:
 		// Fallback: try FFmpeg for unknown formats
 		tagger = &FFmpegFallbackTagger{}
@@ -76,33 +200,86 @@ func TagFile(filePath string, track *domain.Track, albumArtData []byte) error {
 	return tagger.WriteTags(filePath, tags)
 }
 
+// artistTags returns the ARTIST and ARTISTS values mode writes for a track whose primary
+// artist is primary and whose credited artists are artists.
+func artistTags(primary string, artists []string, mode ArtistMode) (artist, credits []string) {
+	if len(artists) == 0 {
+		return nil, nil
+	}
+	if primary == "" {
+		primary = artists[0]
+	}
+	switch mode {
+	case ArtistPrimary:
+		return []string{primary}, nil
+	case ArtistPrimaryPlusFeatures:
+		var features []string
+		for _, a := range artists {
+			if a != primary {
+				features = append(features, a)
+			}
+		}
+		if len(features) == 0 {
+			return []string{primary}, nil
+		}
+		return []string{primary + " feat. " + strings.Join(features, ", ")}, nil
+	default:
+		return artists, artists
+	}
+}
+
 // buildTagMap normalizes the domain.Track into a standard map, resolving fallbacks.
-func buildTagMap(track *domain.Track, art []byte) *TagMap {
+func buildTagMap(track *domain.Track, opts TagOptions) *TagMap {
 	tm := &TagMap{
-		Title:        track.Title,
-		Artists:      track.Artists,
-		Album:        track.Album,
-		AlbumArtists: track.AlbumArtists,
-		Genre:        track.Genre,
-		Mood:         track.Mood,
-		Language:     track.Language,
-		Year:         track.Year,
-		TrackNum:     track.TrackNumber,
-		TrackTotal:   track.TotalTracks,
-		DiscNum:      track.DiscNumber,
-		DiscTotal:    track.TotalDiscs,
-		BPM:          track.BPM,
-		Composer:     track.Composer,
-		Copyright:    track.Copyright,
-		Lyrics:       track.Lyrics,
-		CoverArt:     art,
-		Custom:       make(map[string]string),
+		Title:           track.Title,
+		Artists:         track.Artists,
+		Album:           track.Album,
+		AlbumSort:       albumSortName(track.Album),
+		AlbumArtists:    track.AlbumArtists,
+		ArtistSort:      track.ArtistSort,
+		AlbumArtistSort: track.AlbumArtistSort,
+		Genre:           track.Genre,
+		Mood:            track.Mood,
+		Language:        track.Language,
+		Year:            track.Year,
+		ReleaseDate:     track.ReleaseDate,
+		TrackNum:        track.TrackNumber,
+		TrackTotal:      track.TotalTracks,
+		DiscNum:         track.DiscNumber,
+		DiscTotal:       track.TotalDiscs,
+		BPM:             track.BPM,
+		Composer:        track.Composer,
+		Producer:        track.Producer,
+		Engineer:        track.Engineer,
+		Mixer:           track.Mixer,
+		Performers:      track.Performers,
+		Copyright:       track.Copyright,
+		Lyrics:          track.Lyrics,
+		Custom:          make(map[string]string),
+		MergeStrategy:   MergeOverwrite,
+		ID3v23:          opts.ID3v23,
+	}
+
+	if opts.MergeStrategy == MergeFillMissing {
+		tm.MergeStrategy = MergeFillMissing
+	}
+	if !opts.SkipOriginalDate {
+		tm.OriginalDate = track.OriginalDate
+		tm.OriginalYear = track.OriginalYear
+		if tm.OriginalYear == 0 && len(tm.OriginalDate) >= 4 {
+			tm.OriginalYear, _ = strconv.Atoi(tm.OriginalDate[:4])
+		}
+	}
+	if !opts.SkipArt {
+		tm.CoverArt = opts.Art
+		tm.Pictures = opts.Pictures
 	}
 
 	// Array Fallbacks
 	if len(tm.Artists) == 0 && track.Artist != "" {
 		tm.Artists = []string{track.Artist}
 	}
+	tm.Artists, tm.ArtistCredits = artistTags(track.Artist, tm.Artists, opts.ArtistMode)
 	if len(tm.AlbumArtists) == 0 && track.AlbumArtist != "" {
 		tm.AlbumArtists = []string{track.AlbumArtist}
 	}
@@ -113,7 +290,7 @@ func buildTagMap(track *domain.Track, art []byte) *TagMap {
 	}
 
 	// Subtitles -> LRC
-	if track.Subtitles != "" {
+	if track.Subtitles != "" && !opts.SkipSyncedLyrics {
 		tm.Custom["LYRICS"] = formatToLRC(track.Subtitles)
 	}
 
@@ -132,6 +309,11 @@ func buildTagMap(track *domain.Track, art []byte) *TagMap {
 	addCustom("CATALOGNUMBER", track.CatalogNumber)
 	addCustom("RELEASETYPE", track.ReleaseType)
 	addCustom("MUSICBRAINZ_RELEASEGROUPID", track.ReleaseID)
+	addCustom("MUSICBRAINZ_ALBUMID", track.MBAlbumID)
+	addCustom("MUSICBRAINZ_RELEASETRACKID", track.ReleaseTrackID)
+	if track.RecordingID != nil {
+		addCustom("MUSICBRAINZ_TRACKID", *track.RecordingID)
+	}
 	addCustom("AUDIO_QUALITY", track.AudioQuality)
 	addCustom("AUDIO_MODE", track.AudioModes)
 	addCustom("KEY", track.Key)
@@ -144,17 +326,28 @@ func buildTagMap(track *domain.Track, art []byte) *TagMap {
 	if track.Peak != 0 {
 		addCustom("REPLAYGAIN_TRACK_PEAK", fmt.Sprintf("%.6f", track.Peak))
 	}
+	if track.AlbumGain != 0 {
+		addCustom("REPLAYGAIN_ALBUM_GAIN", fmt.Sprintf("%.2f dB", track.AlbumGain))
+	}
+	if track.AlbumPeak != 0 {
+		addCustom("REPLAYGAIN_ALBUM_PEAK", fmt.Sprintf("%.6f", track.AlbumPeak))
+	}
 	if track.Compilation {
 		addCustom("COMPILATION", "1")
 	}
+	if opts.Gapless && track.Album != "" {
+		tm.Gapless = true
+		addCustom("GAPLESS", "1")
+	}
+	// RATING is left alone: players read it as a star rating.
+	if track.Explicit {
+		tm.Explicit = true
+		addCustom("ITUNESADVISORY", "1")
+	}
 
 	// Mime Type Detection
-	if len(art) > 0 {
-		mime := http.DetectContentType(art)
-		if idx := strings.Index(mime, ";"); idx != -1 {
-			mime = strings.TrimSpace(mime[:idx])
-		}
-		tm.CoverMime = mime
+	if len(tm.CoverArt) > 0 {
+		tm.CoverMime = imageMime(tm.CoverArt)
 	}
 
 	// Array Joins for Custom Maps
@@ -171,9 +364,38 @@ func buildTagMap(track *domain.Track, art []byte) *TagMap {
 	return tm
 }
 
+// WriteLyricsSidecar writes the track's synced lyrics to a .lrc file beside the audio
+// file, in the same LRC form that is embedded in the tags. For a track without synced
+// lyrics, a sidecar left from earlier lyrics is removed.
+func WriteLyricsSidecar(filePath string, track *domain.Track) error {
+	path := storage.LyricsSidecarPath(filePath)
+	if track.Subtitles == "" {
+		if err := storage.RemoveFile(path); err != nil && !storage.IsNotExist(err) {
+			return err
+		}
+		return nil
+	}
+	return storage.WriteFile(path, []byte(formatToLRC(track.Subtitles)))
+}
+
 // ── Utilities ────────────────────────────────────────────────────────────────
 
-// formatToLRC converts subtitle lines to LRC format.
+// albumSortName moves a leading English article out of the way so "The Wall" sorts
+// under W. It returns "" when the sort name would equal the album title.
+func albumSortName(album string) string {
+	const article = "the "
+	if len(album) <= len(article) || !strings.EqualFold(album[:len(article)], article) {
+		return ""
+	}
+	return strings.TrimSpace(album[len(article):])
+}
+
+// lrcTimestamps matches the run of [mm:ss.xx] timestamps that opens a synced lyric line.
+var lrcTimestamps = regexp.MustCompile(`^(?:\s*\[\d+:\d{2}(?:[.:]\d{1,3})?\])+`)
+
+// formatToLRC converts subtitle lines to LRC format: each synced line is its timestamps
+// followed by one space and the trimmed lyric. Lines without a leading timestamp, such
+// as [ar:...] metadata, are kept as they are apart from trimming.
 func formatToLRC(subtitles string) string {
 	var sb strings.Builder
 	for _, line := range strings.Split(subtitles, "\n") {
@@ -181,6 +403,14 @@ func formatToLRC(subtitles string) string {
 		if line == "" {
 			continue
 		}
+		if loc := lrcTimestamps.FindStringIndex(line); loc != nil {
+			stamps := strings.Join(strings.Fields(line[:loc[1]]), "")
+			text := strings.TrimSpace(line[loc[1]:])
+			line = stamps
+			if text != "" {
+				line += " " + text
+			}
+		}
 		sb.WriteString(line)
 		sb.WriteByte('\n')
 	}
@@ -1,10 +1,22 @@
 package tagging
 
 import (
+	"bytes"
+	"encoding/binary"
 	"fmt"
+	"image"
+	"image/png"
+	"os"
+	"path/filepath"
+	"slices"
 	"strings"
 	"testing"
 
+	"github.com/bogem/id3v2/v2"
+	"github.com/go-flac/flacpicture"
+	"github.com/go-flac/flacvorbis"
+	"github.com/go-flac/go-flac"
+
 	"github.com/cesargomez89/navidrums/internal/domain"
 )
 
@@ -17,6 +29,121 @@ func TestFormatToLRC(t *testing.T) {
 	}
 }
 
+func TestFormatToLRC_Normalization(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected string
+	}{
+		{"missing space", "[00:12.34]text", "[00:12.34] text\n"},
+		{"extra spaces", "[00:12.34]    text  ", "[00:12.34] text\n"},
+		{"multiple timestamps", "[00:12.34][01:02.03]chorus", "[00:12.34][01:02.03] chorus\n"},
+		{"spaced timestamps", "[00:12.34] [01:02.03]  chorus", "[00:12.34][01:02.03] chorus\n"},
+		{"timestamp only", "[00:12.34]  ", "[00:12.34]\n"},
+		{"millisecond timestamp", "[00:12.345]text", "[00:12.345] text\n"},
+		{"metadata line", "[ar:Artist]\n[00:01.00]hi", "[ar:Artist]\n[00:01.00] hi\n"},
+		{"continuation line", "[00:01.00]first\n  second half ", "[00:01.00] first\nsecond half\n"},
+		{"bracketed lyric", "[00:01.00][chorus]", "[00:01.00] [chorus]\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := formatToLRC(tt.input); got != tt.expected {
+				t.Errorf("formatToLRC(%q) = %q, want %q", tt.input, got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestWriteLyricsSidecar(t *testing.T) {
+	track := &domain.Track{Subtitles: "[00:10.00] Line 1\n\n[00:20.00] Line 2 "}
+	audioPath := filepath.Join(t.TempDir(), "01 Track.flac")
+
+	if err := WriteLyricsSidecar(audioPath, track); err != nil {
+		t.Fatalf("WriteLyricsSidecar failed: %v", err)
+	}
+
+	got, err := os.ReadFile(filepath.Join(filepath.Dir(audioPath), "01 Track.lrc")) //nolint:gosec // test temp file
+	if err != nil {
+		t.Fatalf("expected sidecar file: %v", err)
+	}
+	embedded := buildTagMap(track, TagOptions{}).Custom["LYRICS"]
+	if string(got) != embedded {
+		t.Errorf("sidecar does not match embedded lyrics.\nGot: %q\nWant: %q", got, embedded)
+	}
+
+	noLyricsPath := filepath.Join(t.TempDir(), "02 Track.flac")
+	if err := WriteLyricsSidecar(noLyricsPath, &domain.Track{}); err != nil {
+		t.Fatalf("WriteLyricsSidecar failed: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(filepath.Dir(noLyricsPath), "02 Track.lrc")); !os.IsNotExist(err) {
+		t.Error("expected no sidecar for a track without synced lyrics")
+	}
+}
+
+func TestWriteLyricsSidecar_ClearedLyrics(t *testing.T) {
+	audioPath := filepath.Join(t.TempDir(), "01 Track.flac")
+	if err := WriteLyricsSidecar(audioPath, &domain.Track{Subtitles: "[00:10.00] Line 1"}); err != nil {
+		t.Fatalf("WriteLyricsSidecar failed: %v", err)
+	}
+
+	// The lyrics were cleared, so the old sidecar must not outlive them.
+	if err := WriteLyricsSidecar(audioPath, &domain.Track{}); err != nil {
+		t.Fatalf("WriteLyricsSidecar failed: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(filepath.Dir(audioPath), "01 Track.lrc")); !os.IsNotExist(err) {
+		t.Errorf("expected the sidecar to be removed, stat error: %v", err)
+	}
+}
+
+func TestBuildTagMap_EmbedSyncedLyrics(t *testing.T) {
+	track := &domain.Track{Subtitles: "[00:10.00] Line 1"}
+
+	if _, ok := buildTagMap(track, TagOptions{}).Custom["LYRICS"]; !ok {
+		t.Error("expected synced lyrics to be embedded by default")
+	}
+
+	if _, ok := buildTagMap(track, TagOptions{SkipSyncedLyrics: true}).Custom["LYRICS"]; ok {
+		t.Error("expected synced lyrics not to be embedded when disabled")
+	}
+}
+
+func TestBuildTagMap_Options(t *testing.T) {
+	art := []byte("\x89PNG\r\n\x1a\n")
+	extra := []Picture{{Type: PictureBackCover, Data: art}}
+
+	tests := []struct {
+		name         string
+		opts         TagOptions
+		wantStrategy MergeStrategy
+		wantArt      bool
+		wantPictures int
+	}{
+		{"zero value", TagOptions{}, MergeOverwrite, false, 0},
+		{"art and pictures", TagOptions{Art: art, Pictures: extra}, MergeOverwrite, true, 1},
+		{"art skipped", TagOptions{Art: art, Pictures: extra, SkipArt: true}, MergeOverwrite, false, 0},
+		{"fill missing", TagOptions{MergeStrategy: MergeFillMissing}, MergeFillMissing, false, 0},
+		{"unknown strategy", TagOptions{MergeStrategy: "merge"}, MergeOverwrite, false, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tags := buildTagMap(&domain.Track{Title: "Song"}, tt.opts)
+			if tags.MergeStrategy != tt.wantStrategy {
+				t.Errorf("MergeStrategy = %q, want %q", tags.MergeStrategy, tt.wantStrategy)
+			}
+			if got := len(tags.CoverArt) > 0; got != tt.wantArt {
+				t.Errorf("cover art embedded = %v, want %v", got, tt.wantArt)
+			}
+			if tt.wantArt && tags.CoverMime != "image/png" {
+				t.Errorf("CoverMime = %q, want image/png", tags.CoverMime)
+			}
+			if len(tags.Pictures) != tt.wantPictures {
+				t.Errorf("Pictures = %d, want %d", len(tags.Pictures), tt.wantPictures)
+			}
+		})
+	}
+}
+
 func TestNewVorbisComment(t *testing.T) {
 	track := &domain.Track{
 		Title:       "Test Title",
@@ -30,7 +157,7 @@ func TestNewVorbisComment(t *testing.T) {
 		ArtistIDs:   []string{"id1", "id2"},
 	}
 
-	tags := buildTagMap(track, nil)
+	tags := buildTagMap(track, TagOptions{})
 	tagger := &FLACTagger{}
 	vc := tagger.newVorbisComment(tags)
 
@@ -73,7 +200,7 @@ func TestNewVorbisComment_MultiArtist(t *testing.T) {
 		AlbumArtists: []string{"Album Artist 1"},
 	}
 
-	tags := buildTagMap(track, nil)
+	tags := buildTagMap(track, TagOptions{})
 	tagger := &FLACTagger{}
 	vc := tagger.newVorbisComment(tags)
 
@@ -102,7 +229,7 @@ func TestNewVorbisComment_MultiGenre(t *testing.T) {
 		Genre: "hip-hop; spanish rap",
 	}
 
-	tags := buildTagMap(track, nil)
+	tags := buildTagMap(track, TagOptions{})
 	tagger := &FLACTagger{}
 	vc := tagger.newVorbisComment(tags)
 
@@ -130,7 +257,7 @@ func TestNewVorbisComment_MultiGenre_CustomSeparator(t *testing.T) {
 		Genre: "rock|pop|electronic",
 	}
 
-	tags := buildTagMap(track, nil)
+	tags := buildTagMap(track, TagOptions{})
 	tagger := &FLACTagger{}
 	vc := tagger.newVorbisComment(tags)
 
@@ -177,7 +304,7 @@ func TestNewVorbisComment_Language(t *testing.T) {
 		Language: "eng",
 	}
 
-	tags := buildTagMap(track, nil)
+	tags := buildTagMap(track, TagOptions{})
 	tagger := &FLACTagger{}
 	vc := tagger.newVorbisComment(tags)
 
@@ -206,7 +333,7 @@ func TestBuildTagMap_Language(t *testing.T) {
 		Language: "spa",
 	}
 
-	tags := buildTagMap(track, nil)
+	tags := buildTagMap(track, TagOptions{})
 
 	if tags.Language != "spa" {
 		t.Errorf("Language = %q, want %q", tags.Language, "spa")
@@ -222,3 +349,1128 @@ func TestBuildTagMap_Language(t *testing.T) {
 		t.Error("LANGUAGE not found in custom tags")
 	}
 }
+
+func TestNewVorbisComment_SortTags(t *testing.T) {
+	track := &domain.Track{
+		Title:           "Comfortably Numb",
+		Artist:          "Pink Floyd",
+		Album:           "The Wall",
+		ArtistSort:      "Pink Floyd",
+		AlbumArtistSort: "Floyd, Pink",
+	}
+
+	tags := buildTagMap(track, TagOptions{})
+	tagger := &FLACTagger{}
+	vc := tagger.newVorbisComment(tags)
+
+	want := map[string]bool{
+		"ARTISTSORT=Pink Floyd":       false,
+		"ALBUMARTISTSORT=Floyd, Pink": false,
+		"ALBUMSORT=Wall":              false,
+	}
+	for _, entry := range vc.Comments {
+		if _, ok := want[entry]; ok {
+			want[entry] = true
+		}
+	}
+	for entry, found := range want {
+		if !found {
+			t.Errorf("Field %s not found in VorbisComment", entry)
+		}
+	}
+}
+
+func TestMP3Tagger_SortFrames(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "track.mp3")
+	if err := os.WriteFile(path, []byte{}, 0o600); err != nil {
+		t.Fatalf("WriteFile failed: %v", err)
+	}
+
+	track := &domain.Track{
+		Title:           "Test",
+		Album:           "The Test Album",
+		ArtistSort:      "Artist, The",
+		AlbumArtistSort: "Album Artist, The",
+	}
+	if err := (&MP3Tagger{}).WriteTags(path, buildTagMap(track, TagOptions{})); err != nil {
+		t.Fatalf("WriteTags failed: %v", err)
+	}
+
+	tag, err := id3v2.Open(path, id3v2.Options{Parse: true})
+	if err != nil {
+		t.Fatalf("Open failed: %v", err)
+	}
+	defer func() { _ = tag.Close() }()
+
+	tests := []struct {
+		frame string
+		want  string
+	}{
+		{"TSOP", "Artist, The"},
+		{"TSO2", "Album Artist, The"},
+		{"TSOA", "Test Album"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.frame, func(t *testing.T) {
+			if got := tag.GetTextFrame(tt.frame).Text; got != tt.want {
+				t.Errorf("%s = %q, want %q", tt.frame, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMP3Tagger_ID3Version(t *testing.T) {
+	track := &domain.Track{
+		Title:        "Ωμέγα",
+		Artists:      []string{"Artist A", "Artist B"},
+		Album:        "Album",
+		Year:         2011,
+		ReleaseDate:  "2011-09-26",
+		OriginalDate: "1991-09-24",
+		Producer:     "Pat Producer",
+		Performers:   []string{"Bo Bass (bass guitar)"},
+	}
+
+	tests := []struct {
+		name    string
+		v23     bool
+		version byte
+		frames  map[string]string
+		absent  []string
+	}{
+		{
+			name:    "2.4",
+			version: 4,
+			frames: map[string]string{
+				"TIT2": "Ωμέγα",
+				"TPE1": "Artist A\x00Artist B",
+				"TDRC": "2011",
+				"TDOR": "1991-09-24",
+				"TIPL": "producer\x00Pat Producer",
+				"TMCL": "bass guitar\x00Bo Bass",
+			},
+			absent: []string{"TYER", "TDAT", "TORY", "IPLS"},
+		},
+		{
+			name:    "2.3",
+			v23:     true,
+			version: 3,
+			frames: map[string]string{
+				"TIT2": "Ωμέγα",
+				"TPE1": "Artist A/Artist B",
+				"TYER": "2011",
+				"TDAT": "2609",
+				"TORY": "1991",
+			},
+			absent: []string{"TDRC", "TDOR", "TIPL", "TMCL"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "track.mp3")
+			if err := os.WriteFile(path, []byte{}, 0o600); err != nil {
+				t.Fatalf("WriteFile failed: %v", err)
+			}
+			if err := (&MP3Tagger{}).WriteTags(path, buildTagMap(track, TagOptions{ID3v23: tt.v23})); err != nil {
+				t.Fatalf("WriteTags failed: %v", err)
+			}
+
+			tag, err := id3v2.Open(path, id3v2.Options{Parse: true})
+			if err != nil {
+				t.Fatalf("Open failed: %v", err)
+			}
+			defer func() { _ = tag.Close() }()
+
+			if tag.Version() != tt.version {
+				t.Errorf("Version() = %d, want %d", tag.Version(), tt.version)
+			}
+			for id, want := range tt.frames {
+				if got := tag.GetTextFrame(id).Text; got != want {
+					t.Errorf("%s = %q, want %q", id, got, want)
+				}
+			}
+			for _, id := range tt.absent {
+				if tag.GetLastFrame(id) != nil {
+					t.Errorf("%s written, want none", id)
+				}
+			}
+			if tt.v23 {
+				if enc := tag.GetTextFrame("TIT2").Encoding; !enc.Equals(id3v2.EncodingUTF16) {
+					t.Errorf("TIT2 encoding = %s, want UTF-16", enc)
+				}
+				if enc := tag.GetTextFrame("TALB").Encoding; !enc.Equals(id3v2.EncodingISO) {
+					t.Errorf("TALB encoding = %s, want ISO-8859-1", enc)
+				}
+			}
+		})
+	}
+}
+
+func TestMP3Tagger_DowngradeToID3v23(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "track.mp3")
+	if err := os.WriteFile(path, []byte{}, 0o600); err != nil {
+		t.Fatalf("WriteFile failed: %v", err)
+	}
+	track := &domain.Track{Title: "Song", Album: "Ålbum", Year: 2011, Producer: "Pat Producer"}
+	if err := (&MP3Tagger{}).WriteTags(path, buildTagMap(track, TagOptions{})); err != nil {
+		t.Fatalf("WriteTags failed: %v", err)
+	}
+	// Fill-missing keeps the 2.4 file's values, but must carry them over to 2.3 frames.
+	opts := TagOptions{MergeStrategy: MergeFillMissing, ID3v23: true}
+	if err := (&MP3Tagger{}).WriteTags(path, buildTagMap(&domain.Track{Title: "Other"}, opts)); err != nil {
+		t.Fatalf("WriteTags failed: %v", err)
+	}
+
+	tag, err := id3v2.Open(path, id3v2.Options{Parse: true})
+	if err != nil {
+		t.Fatalf("Open failed: %v", err)
+	}
+	defer func() { _ = tag.Close() }()
+
+	if tag.Version() != 3 {
+		t.Errorf("Version() = %d, want 3", tag.Version())
+	}
+	for id, want := range map[string]string{"TIT2": "Song", "TALB": "Ålbum", "TYER": "2011"} {
+		f := tag.GetTextFrame(id)
+		if f.Text != want {
+			t.Errorf("%s = %q, want %q", id, f.Text, want)
+		}
+		if f.Encoding.Equals(id3v2.EncodingUTF8) {
+			t.Errorf("%s is UTF-8, which ID3v2.3 lacks", id)
+		}
+	}
+	for _, id := range []string{"TDRC", "TIPL"} {
+		if tag.GetLastFrame(id) != nil {
+			t.Errorf("%s kept, want it converted", id)
+		}
+	}
+	if tag.GetLastFrame("IPLS") == nil {
+		t.Error("IPLS not written from TIPL")
+	}
+}
+
+func TestAlbumSortName(t *testing.T) {
+	tests := []struct {
+		album string
+		want  string
+	}{
+		{"The Wall", "Wall"},
+		{"the dark side", "dark side"},
+		{"THE Album", "Album"},
+		{"Theory of Everything", ""},
+		{"The", ""},
+		{"Abbey Road", ""},
+		{"", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.album, func(t *testing.T) {
+			if got := albumSortName(tt.album); got != tt.want {
+				t.Errorf("albumSortName(%q) = %q, want %q", tt.album, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestBuildTagMap_MusicBrainzIDs(t *testing.T) {
+	recordingID := "rec-mbid"
+	tests := []struct {
+		name  string
+		track *domain.Track
+		want  map[string]string
+	}{
+		{
+			name: "all ids",
+			track: &domain.Track{
+				RecordingID:    &recordingID,
+				MBAlbumID:      "album-mbid",
+				ReleaseTrackID: "track-mbid",
+			},
+			want: map[string]string{
+				"MUSICBRAINZ_TRACKID":        "rec-mbid",
+				"MUSICBRAINZ_ALBUMID":        "album-mbid",
+				"MUSICBRAINZ_RELEASETRACKID": "track-mbid",
+			},
+		},
+		{
+			name:  "empty ids skipped",
+			track: &domain.Track{RecordingID: new(string)},
+			want:  map[string]string{},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tags := buildTagMap(tt.track, TagOptions{})
+			for key := range musicBrainzIDNames {
+				got, ok := tags.Custom[key]
+				want, wantOK := tt.want[key]
+				if ok != wantOK || got != want {
+					t.Errorf("%s = %q (present %v), want %q (present %v)", key, got, ok, want, wantOK)
+				}
+			}
+		})
+	}
+}
+
+func TestMP3Tagger_MusicBrainzIDs(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "track.mp3")
+	if err := os.WriteFile(path, []byte{}, 0o600); err != nil {
+		t.Fatalf("WriteFile failed: %v", err)
+	}
+
+	recordingID := "rec-mbid"
+	track := &domain.Track{Title: "Test", RecordingID: &recordingID, MBAlbumID: "album-mbid"}
+	if err := (&MP3Tagger{}).WriteTags(path, buildTagMap(track, TagOptions{})); err != nil {
+		t.Fatalf("WriteTags failed: %v", err)
+	}
+
+	tag, err := id3v2.Open(path, id3v2.Options{Parse: true})
+	if err != nil {
+		t.Fatalf("Open failed: %v", err)
+	}
+	defer func() { _ = tag.Close() }()
+
+	ufids := tag.GetFrames(tag.CommonID("Unique file identifier"))
+	if len(ufids) != 1 {
+		t.Fatalf("Expected 1 UFID frame, got %d", len(ufids))
+	}
+	ufid, ok := ufids[0].(id3v2.UFIDFrame)
+	if !ok || ufid.OwnerIdentifier != musicBrainzUFIDOwner || string(ufid.Identifier) != recordingID {
+		t.Errorf("Unexpected UFID frame: %+v", ufids[0])
+	}
+
+	var albumID, releaseTrackID bool
+	for _, f := range tag.GetFrames(tag.CommonID("User defined text information frame")) {
+		udtf, ok := f.(id3v2.UserDefinedTextFrame)
+		if !ok {
+			continue
+		}
+		switch udtf.Description {
+		case "MusicBrainz Album Id":
+			albumID = udtf.Value == "album-mbid"
+		case "MusicBrainz Release Track Id":
+			releaseTrackID = true
+		}
+	}
+	if !albumID {
+		t.Error("Expected TXXX:MusicBrainz Album Id frame")
+	}
+	if releaseTrackID {
+		t.Error("Empty release track ID should not be written")
+	}
+}
+
+func TestTagging_ExplicitAdvisory(t *testing.T) {
+	tests := []struct {
+		name     string
+		explicit bool
+	}{
+		{"explicit", true},
+		{"not explicit", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tags := buildTagMap(&domain.Track{Title: "Song", Explicit: tt.explicit}, TagOptions{})
+			if tags.Explicit != tt.explicit {
+				t.Errorf("Explicit = %v, want %v", tags.Explicit, tt.explicit)
+			}
+
+			vc := (&FLACTagger{}).newVorbisComment(tags)
+			if got := slices.Contains(vc.Comments, "ITUNESADVISORY=1"); got != tt.explicit {
+				t.Errorf("FLAC ITUNESADVISORY=1 present = %v, want %v", got, tt.explicit)
+			}
+
+			path := filepath.Join(t.TempDir(), "track.mp3")
+			if err := os.WriteFile(path, []byte{}, 0o600); err != nil {
+				t.Fatalf("WriteFile failed: %v", err)
+			}
+			if err := (&MP3Tagger{}).WriteTags(path, tags); err != nil {
+				t.Fatalf("WriteTags failed: %v", err)
+			}
+			tag, err := id3v2.Open(path, id3v2.Options{Parse: true})
+			if err != nil {
+				t.Fatalf("Open failed: %v", err)
+			}
+			defer func() { _ = tag.Close() }()
+			advisory := false
+			for _, f := range tag.GetFrames(tag.CommonID("User defined text information frame")) {
+				if udtf, ok := f.(id3v2.UserDefinedTextFrame); ok && udtf.Description == "ITUNESADVISORY" {
+					advisory = udtf.Value == "1"
+				}
+			}
+			if advisory != tt.explicit {
+				t.Errorf("TXXX:ITUNESADVISORY=1 present = %v, want %v", advisory, tt.explicit)
+			}
+		})
+	}
+
+	// rtng holds an integer data atom: 1 marks the track explicit.
+	want := []byte{0, 0, 0, 25, 'r', 't', 'n', 'g', 0, 0, 0, 17, 'd', 'a', 't', 'a', 0, 0, 0, 21, 0, 0, 0, 0, 1}
+	if got := rtngAtom(); !bytes.Equal(got, want) {
+		t.Errorf("rtngAtom() = %v, want %v", got, want)
+	}
+}
+
+func TestInsertFreeformAtoms(t *testing.T) {
+	stco := atom("stco", []byte{0, 0, 0, 0, 0, 0, 0, 1}, []byte{0, 0, 0, 0})
+	trak := atom("trak", atom("mdia", atom("minf", atom("stbl", stco))))
+	meta := atom("meta", []byte{0, 0, 0, 0}, atom("hdlr", make([]byte, 25)), atom("ilst"))
+	moov := atom("moov", trak, atom("udta", meta))
+	ftyp := atom("ftyp", []byte("M4A "))
+	mdat := atom("mdat", []byte("audio"))
+
+	// Point the single chunk at the start of the mdat payload.
+	chunkOffset := len(ftyp) + len(moov) + 8
+	stcoEntry := len(ftyp) + 8 + 8*4 + 16
+	input := append(append(append([]byte{}, ftyp...), moov...), mdat...)
+	binary.BigEndian.PutUint32(input[stcoEntry:], uint32(chunkOffset))
+
+	out, err := insertFreeformAtoms(input, map[string]string{"MusicBrainz Track Id": "rec-mbid"}, pgapAtom())
+	if err != nil {
+		t.Fatalf("insertFreeformAtoms failed: %v", err)
+	}
+
+	added := len(out) - len(input)
+	if added == 0 {
+		t.Fatal("Expected freeform atoms to be inserted")
+	}
+	if got := int(binary.BigEndian.Uint32(out[stcoEntry:])); got != chunkOffset+added {
+		t.Errorf("Chunk offset = %d, want %d", got, chunkOffset+added)
+	}
+	if got := string(out[chunkOffset+added : chunkOffset+added+5]); got != "audio" {
+		t.Errorf("Chunk offset points at %q, want audio", got)
+	}
+
+	moovOff, moovSize, err := findAtom(out, 0, len(out), "moov")
+	if err != nil {
+		t.Fatalf("findAtom moov failed: %v", err)
+	}
+	outMoov := out[moovOff : moovOff+moovSize]
+	udtaOff, udtaSize, err := findAtom(outMoov, 8, len(outMoov), "udta")
+	if err != nil {
+		t.Fatalf("findAtom udta failed: %v", err)
+	}
+	metaOff, metaSize, err := findAtom(outMoov, udtaOff+8, udtaOff+udtaSize, "meta")
+	if err != nil {
+		t.Fatalf("findAtom meta failed: %v", err)
+	}
+	ilstOff, ilstSize, err := findAtom(outMoov, metaOff+12, metaOff+metaSize, "ilst")
+	if err != nil {
+		t.Fatalf("findAtom ilst failed: %v", err)
+	}
+	ilst := outMoov[ilstOff : ilstOff+ilstSize]
+	if !bytes.Contains(ilst, []byte("----")) || !bytes.Contains(ilst, []byte("MusicBrainz Track Id")) || !bytes.Contains(ilst, []byte("rec-mbid")) {
+		t.Errorf("ilst does not hold the freeform atom: %q", ilst)
+	}
+	if !bytes.HasSuffix(ilst, pgapAtom()) {
+		t.Errorf("ilst does not end with the pgap atom: %q", ilst)
+	}
+}
+
+func TestWAVTagger_RoundTrip(t *testing.T) {
+	wav := &iffFile{order: binary.LittleEndian, magic: "RIFF", form: "WAVE", chunks: []iffChunk{
+		{id: "fmt ", data: make([]byte, 16)},
+		{id: "data", data: []byte{1, 2, 3}},
+	}}
+	path := filepath.Join(t.TempDir(), "track.wav")
+	if err := os.WriteFile(path, wav.bytes(), 0o600); err != nil {
+		t.Fatalf("WriteFile failed: %v", err)
+	}
+
+	tags := &TagMap{Title: "Song", Artists: []string{"Artist"}, Album: "Album", Genre: "Rock", Year: 2020, Custom: map[string]string{}}
+	// Tag twice to check that existing tag chunks are replaced, not duplicated.
+	for range 2 {
+		if err := (&WAVTagger{}).WriteTags(path, tags); err != nil {
+			t.Fatalf("WriteTags failed: %v", err)
+		}
+	}
+
+	data, err := os.ReadFile(path) //nolint:gosec // test temp file
+	if err != nil {
+		t.Fatalf("ReadFile failed: %v", err)
+	}
+	if got := int(binary.LittleEndian.Uint32(data[4:])); got != len(data)-8 {
+		t.Errorf("RIFF size = %d, want %d", got, len(data)-8)
+	}
+	f, err := parseIFF(data, "RIFF", binary.LittleEndian)
+	if err != nil {
+		t.Fatalf("parseIFF failed: %v", err)
+	}
+
+	var ids []string
+	chunks := make(map[string][]byte)
+	for _, c := range f.chunks {
+		ids = append(ids, c.id)
+		chunks[c.id] = c.data
+	}
+	if strings.Join(ids, ",") != "fmt ,data,LIST,id3 " {
+		t.Fatalf("unexpected chunk layout: %q", ids)
+	}
+	if !bytes.Equal(chunks["data"], []byte{1, 2, 3}) {
+		t.Errorf("audio data changed: %v", chunks["data"])
+	}
+	for _, want := range []string{"INAM\x05\x00\x00\x00Song\x00", "IART\x07\x00\x00\x00Artist\x00", "IPRD", "IGNR", "ICRD\x05\x00\x00\x002020\x00"} {
+		if !bytes.Contains(chunks["LIST"], []byte(want)) {
+			t.Errorf("INFO chunk missing %q", want)
+		}
+	}
+
+	tag, err := id3v2.ParseReader(bytes.NewReader(chunks["id3 "]), id3v2.Options{Parse: true})
+	if err != nil {
+		t.Fatalf("failed to parse id3 chunk: %v", err)
+	}
+	if tag.Title() != "Song" || tag.Album() != "Album" || tag.Artist() != "Artist" {
+		t.Errorf("unexpected id3 tags: title=%q album=%q artist=%q", tag.Title(), tag.Album(), tag.Artist())
+	}
+}
+
+func TestAIFFTagger_RoundTrip(t *testing.T) {
+	aiff := &iffFile{order: binary.BigEndian, magic: "FORM", form: "AIFF", chunks: []iffChunk{
+		{id: "COMM", data: make([]byte, 18)},
+		{id: "SSND", data: []byte{0, 0, 0, 0, 0, 0, 0, 0, 9}},
+	}}
+	path := filepath.Join(t.TempDir(), "track.aiff")
+	if err := os.WriteFile(path, aiff.bytes(), 0o600); err != nil {
+		t.Fatalf("WriteFile failed: %v", err)
+	}
+
+	track := &domain.Track{Title: "Song", Artist: "Artist", Album: "Album", Year: 2020}
+	for range 2 {
+		if err := TagFile(path, track, TagOptions{}); err != nil {
+			t.Fatalf("TagFile failed: %v", err)
+		}
+	}
+
+	data, err := os.ReadFile(path) //nolint:gosec // test temp file
+	if err != nil {
+		t.Fatalf("ReadFile failed: %v", err)
+	}
+	if got := int(binary.BigEndian.Uint32(data[4:])); got != len(data)-8 {
+		t.Errorf("FORM size = %d, want %d", got, len(data)-8)
+	}
+	f, err := parseIFF(data, "FORM", binary.BigEndian)
+	if err != nil {
+		t.Fatalf("parseIFF failed: %v", err)
+	}
+
+	var ids []string
+	chunks := make(map[string][]byte)
+	for _, c := range f.chunks {
+		ids = append(ids, c.id)
+		chunks[c.id] = c.data
+	}
+	if strings.Join(ids, ",") != "COMM,SSND,NAME,AUTH,ID3 " {
+		t.Fatalf("unexpected chunk layout: %q", ids)
+	}
+	if string(chunks["NAME"]) != "Song" || string(chunks["AUTH"]) != "Artist" {
+		t.Errorf("unexpected text chunks: NAME=%q AUTH=%q", chunks["NAME"], chunks["AUTH"])
+	}
+	if len(chunks["SSND"]) != 9 {
+		t.Errorf("odd-sized sound chunk was not preserved: %v", chunks["SSND"])
+	}
+
+	tag, err := id3v2.ParseReader(bytes.NewReader(chunks["ID3 "]), id3v2.Options{Parse: true})
+	if err != nil {
+		t.Fatalf("failed to parse ID3 chunk: %v", err)
+	}
+	if tag.Title() != "Song" || tag.Year() != "2020" {
+		t.Errorf("unexpected id3 tags: title=%q year=%q", tag.Title(), tag.Year())
+	}
+}
+
+// writeTestFLAC writes a minimal FLAC file (STREAMINFO plus a stub frame) carrying
+// the given Vorbis comments.
+func writeTestFLAC(t *testing.T, comments ...string) string {
+	t.Helper()
+	vc := flacvorbis.New()
+	vc.Comments = comments
+	vcMeta := vc.Marshal()
+	f := &flac.File{
+		Meta:   []*flac.MetaDataBlock{{Type: flac.StreamInfo, Data: make([]byte, 34)}, &vcMeta},
+		Frames: []byte{0xFF, 0xF8, 0x00, 0x00},
+	}
+	path := filepath.Join(t.TempDir(), "track.flac")
+	if err := f.Save(path); err != nil {
+		t.Fatalf("failed to write FLAC: %v", err)
+	}
+	return path
+}
+
+func readTestFLACComments(t *testing.T, path string) map[string][]string {
+	t.Helper()
+	f, err := flac.ParseFile(path)
+	if err != nil {
+		t.Fatalf("failed to parse FLAC: %v", err)
+	}
+	out := make(map[string][]string)
+	for _, b := range f.Meta {
+		if b.Type != flac.VorbisComment {
+			continue
+		}
+		vc, err := flacvorbis.ParseFromMetaDataBlock(*b)
+		if err != nil {
+			t.Fatalf("failed to parse Vorbis comment: %v", err)
+		}
+		for _, c := range vc.Comments {
+			name, value, _ := strings.Cut(c, "=")
+			out[name] = append(out[name], value)
+		}
+	}
+	return out
+}
+
+func TestFLACTagger_MergeStrategy(t *testing.T) {
+	tests := []struct {
+		name      string
+		strategy  MergeStrategy
+		wantTitle string
+		wantGenre string
+	}{
+		{"overwrite", MergeOverwrite, "DB Title", "Rock"},
+		{"fill missing", MergeFillMissing, "My Title", "Rock"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := writeTestFLAC(t, "TITLE=My Title", "COMMENT=hand edited", "GENRE=")
+			tags := &TagMap{Title: "DB Title", Album: "DB Album", Genre: "Rock", MergeStrategy: tt.strategy, Custom: map[string]string{}}
+
+			if err := (&FLACTagger{}).WriteTags(path, tags); err != nil {
+				t.Fatalf("WriteTags failed: %v", err)
+			}
+
+			got := readTestFLACComments(t, path)
+			if len(got["TITLE"]) != 1 || got["TITLE"][0] != tt.wantTitle {
+				t.Errorf("TITLE = %v, want %q", got["TITLE"], tt.wantTitle)
+			}
+			if len(got["ALBUM"]) != 1 || got["ALBUM"][0] != "DB Album" {
+				t.Errorf("ALBUM = %v, want missing field to be filled", got["ALBUM"])
+			}
+			if len(got["GENRE"]) != 1 || got["GENRE"][0] != tt.wantGenre {
+				t.Errorf("GENRE = %v, want empty field to be filled with %q", got["GENRE"], tt.wantGenre)
+			}
+			_, kept := got["COMMENT"]
+			if kept != (tt.strategy == MergeFillMissing) {
+				t.Errorf("COMMENT kept = %v under %s", kept, tt.strategy)
+			}
+		})
+	}
+}
+
+// testPNG encodes a solid square PNG of the given size, so each picture has distinct bytes.
+func testPNG(t *testing.T, size int) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, image.NewGray(image.Rect(0, 0, size, size))); err != nil {
+		t.Fatalf("failed to encode PNG: %v", err)
+	}
+	return buf.Bytes()
+}
+
+func TestFLACTagger_Pictures(t *testing.T) {
+	front, back, artist := testPNG(t, 4), testPNG(t, 5), testPNG(t, 6)
+
+	tests := []struct {
+		name     string
+		pictures []Picture
+		want     []flacpicture.PictureType
+	}{
+		{"front cover only", nil, []flacpicture.PictureType{flacpicture.PictureTypeFrontCover}},
+		{"back cover and artist", []Picture{
+			{Type: PictureBackCover, Data: back},
+			{Type: PictureArtist, Data: artist},
+		}, []flacpicture.PictureType{flacpicture.PictureTypeFrontCover, flacpicture.PictureTypeBackCover, flacpicture.PictureTypeArtist}},
+		{"empty extra skipped", []Picture{{Type: PictureBackCover}}, []flacpicture.PictureType{flacpicture.PictureTypeFrontCover}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := writeTestFLAC(t)
+			tags := &TagMap{Title: "Title", CoverArt: front, CoverMime: "image/png", Pictures: tt.pictures, Custom: map[string]string{}}
+
+			// Written twice: the second write must replace the pictures, not add to them.
+			for range 2 {
+				if err := (&FLACTagger{}).WriteTags(path, tags); err != nil {
+					t.Fatalf("WriteTags failed: %v", err)
+				}
+			}
+
+			f, err := flac.ParseFile(path)
+			if err != nil {
+				t.Fatalf("failed to parse FLAC: %v", err)
+			}
+			var got []flacpicture.PictureType
+			images := map[flacpicture.PictureType][]byte{}
+			for _, b := range f.Meta {
+				if b.Type != flac.Picture {
+					continue
+				}
+				pic, err := flacpicture.ParseFromMetaDataBlock(*b)
+				if err != nil {
+					t.Fatalf("failed to parse picture: %v", err)
+				}
+				if pic.MIME != "image/png" {
+					t.Errorf("picture type %d MIME = %q, want image/png", pic.PictureType, pic.MIME)
+				}
+				got = append(got, pic.PictureType)
+				images[pic.PictureType] = pic.ImageData
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Fatalf("picture types = %v, want %v", got, tt.want)
+			}
+			for typ, want := range map[flacpicture.PictureType][]byte{
+				flacpicture.PictureTypeFrontCover: front,
+				flacpicture.PictureTypeBackCover:  back,
+				flacpicture.PictureTypeArtist:     artist,
+			} {
+				if data, ok := images[typ]; ok && !bytes.Equal(data, want) {
+					t.Errorf("picture type %d has the wrong image data", typ)
+				}
+			}
+			if title := readTestFLACComments(t, path)["TITLE"]; len(title) != 1 || title[0] != "Title" {
+				t.Errorf("TITLE = %v, want [Title]", title)
+			}
+		})
+	}
+}
+
+func TestMP3Tagger_MergeStrategy(t *testing.T) {
+	tests := []struct {
+		name      string
+		strategy  MergeStrategy
+		wantTitle string
+	}{
+		{"overwrite", MergeOverwrite, "DB Title"},
+		{"fill missing", MergeFillMissing, "My Title"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "track.mp3")
+			if err := os.WriteFile(path, []byte{}, 0o600); err != nil {
+				t.Fatalf("WriteFile failed: %v", err)
+			}
+			pre, err := id3v2.Open(path, id3v2.Options{Parse: true})
+			if err != nil {
+				t.Fatalf("failed to open MP3: %v", err)
+			}
+			pre.SetTitle("My Title")
+			pre.AddUserDefinedTextFrame(id3v2.UserDefinedTextFrame{Encoding: id3v2.EncodingUTF8, Description: "ISRC_NOTE", Value: "mine"})
+			if err := pre.Save(); err != nil {
+				t.Fatalf("failed to save MP3: %v", err)
+			}
+			_ = pre.Close()
+
+			tags := &TagMap{
+				Title:         "DB Title",
+				Album:         "DB Album",
+				MergeStrategy: tt.strategy,
+				Custom:        map[string]string{"ISRC_NOTE": "db", "BARCODE": "123"},
+			}
+			if err := (&MP3Tagger{}).WriteTags(path, tags); err != nil {
+				t.Fatalf("WriteTags failed: %v", err)
+			}
+
+			tag, err := id3v2.Open(path, id3v2.Options{Parse: true})
+			if err != nil {
+				t.Fatalf("failed to reopen MP3: %v", err)
+			}
+			defer func() { _ = tag.Close() }()
+
+			if tag.Title() != tt.wantTitle {
+				t.Errorf("title = %q, want %q", tag.Title(), tt.wantTitle)
+			}
+			if tag.Album() != "DB Album" {
+				t.Errorf("album = %q, want missing field to be filled", tag.Album())
+			}
+			txxx := make(map[string]string)
+			for _, f := range tag.GetFrames("TXXX") {
+				if udtf, ok := f.(id3v2.UserDefinedTextFrame); ok {
+					txxx[udtf.Description] = udtf.Value
+				}
+			}
+			wantNote := "db"
+			if tt.strategy == MergeFillMissing {
+				wantNote = "mine"
+			}
+			if txxx["ISRC_NOTE"] != wantNote {
+				t.Errorf("TXXX ISRC_NOTE = %q, want %q", txxx["ISRC_NOTE"], wantNote)
+			}
+			if txxx["BARCODE"] != "123" {
+				t.Errorf("TXXX BARCODE = %q, want missing frame to be added", txxx["BARCODE"])
+			}
+		})
+	}
+}
+
+// flacStreamInfo encodes a STREAMINFO block for the given stream properties.
+func flacStreamInfo(sampleRate, channels, bitDepth int, samples uint64) []byte {
+	data := make([]byte, 34)
+	packed := uint64(sampleRate)<<44 | uint64(channels-1)<<41 | uint64(bitDepth-1)<<36 | samples //nolint:gosec // test values are small
+	binary.BigEndian.PutUint64(data[10:], packed)
+	return data
+}
+
+func TestReadAudioProperties(t *testing.T) {
+	dir := t.TempDir()
+
+	flacPath := filepath.Join(dir, "track.flac")
+	flacFile := &flac.File{
+		Meta:   []*flac.MetaDataBlock{{Type: flac.StreamInfo, Data: flacStreamInfo(96000, 2, 24, 96000*10)}},
+		Frames: make([]byte, 1000),
+	}
+	if err := flacFile.Save(flacPath); err != nil {
+		t.Fatalf("failed to write FLAC: %v", err)
+	}
+	flacInfo, err := os.Stat(flacPath)
+	if err != nil {
+		t.Fatalf("Stat failed: %v", err)
+	}
+
+	// An ID3v2 header with an empty tag, then an MPEG-1 layer III frame header:
+	// 320 kbps, 44.1 kHz, mono.
+	mp3Path := filepath.Join(dir, "track.mp3")
+	mp3Data := append([]byte{'I', 'D', '3', 4, 0, 0, 0, 0, 0, 0}, 0xFF, 0xFB, 0xE0, 0xC0)
+	if err := os.WriteFile(mp3Path, mp3Data, 0o600); err != nil {
+		t.Fatalf("WriteFile failed: %v", err)
+	}
+
+	tests := []struct {
+		name string
+		path string
+		want AudioProperties
+	}{
+		{"flac hi-res", flacPath, AudioProperties{SampleRate: 96000, BitDepth: 24, Channels: 2, Bitrate: int(flacInfo.Size() * 8 / 10 / 1000)}},
+		{"mp3", mp3Path, AudioProperties{SampleRate: 44100, Channels: 1, Bitrate: 320}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ReadAudioProperties(tt.path)
+			if err != nil {
+				t.Fatalf("ReadAudioProperties failed: %v", err)
+			}
+			if *got != tt.want {
+				t.Errorf("got %+v, want %+v", *got, tt.want)
+			}
+		})
+	}
+
+	t.Run("unsupported format", func(t *testing.T) {
+		path := filepath.Join(dir, "track.ogg")
+		if err := os.WriteFile(path, []byte("OggS"), 0o600); err != nil {
+			t.Fatalf("WriteFile failed: %v", err)
+		}
+		if _, err := ReadAudioProperties(path); err != ErrUnsupportedFormat {
+			t.Errorf("err = %v, want ErrUnsupportedFormat", err)
+		}
+	})
+}
+
+func TestTagging_Credits(t *testing.T) {
+	track := &domain.Track{
+		Title:      "Song",
+		Producer:   "Pat Producer",
+		Engineer:   "Eve Engineer",
+		Mixer:      "Max Mixer",
+		Performers: domain.StringSlice{"Bo Bass (bass guitar)", "Vi Voice (lead vocals)"},
+	}
+
+	t.Run("vorbis comments", func(t *testing.T) {
+		vc := (&FLACTagger{}).newVorbisComment(buildTagMap(track, TagOptions{}))
+		want := []string{
+			"PRODUCER=Pat Producer",
+			"ENGINEER=Eve Engineer",
+			"MIXER=Max Mixer",
+			"PERFORMER=Bo Bass (bass guitar)",
+			"PERFORMER=Vi Voice (lead vocals)",
+		}
+		for _, entry := range want {
+			if !slices.Contains(vc.Comments, entry) {
+				t.Errorf("%s not found in VorbisComment", entry)
+			}
+		}
+	})
+
+	t.Run("id3 frames", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "track.mp3")
+		if err := os.WriteFile(path, []byte{}, 0o600); err != nil {
+			t.Fatalf("WriteFile failed: %v", err)
+		}
+		if err := (&MP3Tagger{}).WriteTags(path, buildTagMap(track, TagOptions{})); err != nil {
+			t.Fatalf("WriteTags failed: %v", err)
+		}
+		tag, err := id3v2.Open(path, id3v2.Options{Parse: true})
+		if err != nil {
+			t.Fatalf("Open failed: %v", err)
+		}
+		defer func() { _ = tag.Close() }()
+
+		if got, want := tag.GetTextFrame("TIPL").Text, "producer\x00Pat Producer\x00engineer\x00Eve Engineer\x00mix\x00Max Mixer"; got != want {
+			t.Errorf("TIPL = %q, want %q", got, want)
+		}
+		if got, want := tag.GetTextFrame("TMCL").Text, "bass guitar\x00Bo Bass\x00lead vocals\x00Vi Voice"; got != want {
+			t.Errorf("TMCL = %q, want %q", got, want)
+		}
+	})
+}
+
+func TestTagging_OriginalDate(t *testing.T) {
+	track := &domain.Track{
+		Title:        "Song",
+		Year:         2011,
+		ReleaseDate:  "2011-09-26",
+		OriginalDate: "1991-09-24",
+		OriginalYear: 1991,
+	}
+
+	t.Run("vorbis comments", func(t *testing.T) {
+		vc := (&FLACTagger{}).newVorbisComment(buildTagMap(track, TagOptions{}))
+		for _, entry := range []string{"DATE=2011", "RELEASEDATE=2011-09-26", "ORIGINALDATE=1991-09-24", "ORIGINALYEAR=1991"} {
+			if !slices.Contains(vc.Comments, entry) {
+				t.Errorf("%s not found in VorbisComment", entry)
+			}
+		}
+	})
+
+	t.Run("skipped", func(t *testing.T) {
+		vc := (&FLACTagger{}).newVorbisComment(buildTagMap(track, TagOptions{SkipOriginalDate: true}))
+		for _, c := range vc.Comments {
+			if strings.HasPrefix(c, "ORIGINAL") {
+				t.Errorf("%s written with SkipOriginalDate", c)
+			}
+		}
+		if !slices.Contains(vc.Comments, "DATE=2011") {
+			t.Error("DATE=2011 not found in VorbisComment")
+		}
+	})
+
+	t.Run("id3 frames", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "track.mp3")
+		if err := os.WriteFile(path, []byte{}, 0o600); err != nil {
+			t.Fatalf("WriteFile failed: %v", err)
+		}
+		if err := (&MP3Tagger{}).WriteTags(path, buildTagMap(track, TagOptions{})); err != nil {
+			t.Fatalf("WriteTags failed: %v", err)
+		}
+		tag, err := id3v2.Open(path, id3v2.Options{Parse: true})
+		if err != nil {
+			t.Fatalf("Open failed: %v", err)
+		}
+		defer func() { _ = tag.Close() }()
+
+		if got := tag.GetTextFrame("TDOR").Text; got != "1991-09-24" {
+			t.Errorf("TDOR = %q, want %q", got, "1991-09-24")
+		}
+		if got := tag.Year(); got != "2011" {
+			t.Errorf("year = %q, want %q", got, "2011")
+		}
+	})
+}
+
+func TestTagging_ArtistMode(t *testing.T) {
+	track := &domain.Track{
+		Title:   "Song",
+		Artist:  "Main",
+		Artists: []string{"Main", "Guest A", "Guest B"},
+	}
+
+	tests := []struct {
+		name        string
+		mode        ArtistMode
+		wantArtist  []string
+		wantArtists []string
+		wantTPE1    string
+	}{
+		{"default", "", []string{"Main", "Guest A", "Guest B"}, []string{"Main", "Guest A", "Guest B"}, "Main\x00Guest A\x00Guest B"},
+		{"all", ArtistAll, []string{"Main", "Guest A", "Guest B"}, []string{"Main", "Guest A", "Guest B"}, "Main\x00Guest A\x00Guest B"},
+		{"primary", ArtistPrimary, []string{"Main"}, nil, "Main"},
+		{"primary plus features", ArtistPrimaryPlusFeatures, []string{"Main feat. Guest A, Guest B"}, nil, "Main feat. Guest A, Guest B"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tags := buildTagMap(track, TagOptions{ArtistMode: tt.mode})
+
+			var gotArtist, gotArtists []string
+			for _, c := range (&FLACTagger{}).newVorbisComment(tags).Comments {
+				if v, ok := strings.CutPrefix(c, "ARTIST="); ok {
+					gotArtist = append(gotArtist, v)
+				}
+				if v, ok := strings.CutPrefix(c, "ARTISTS="); ok {
+					gotArtists = append(gotArtists, v)
+				}
+			}
+			if !slices.Equal(gotArtist, tt.wantArtist) {
+				t.Errorf("ARTIST = %q, want %q", gotArtist, tt.wantArtist)
+			}
+			if !slices.Equal(gotArtists, tt.wantArtists) {
+				t.Errorf("ARTISTS = %q, want %q", gotArtists, tt.wantArtists)
+			}
+
+			path := filepath.Join(t.TempDir(), "track.mp3")
+			if err := os.WriteFile(path, []byte{}, 0o600); err != nil {
+				t.Fatalf("WriteFile failed: %v", err)
+			}
+			if err := (&MP3Tagger{}).WriteTags(path, tags); err != nil {
+				t.Fatalf("WriteTags failed: %v", err)
+			}
+			tag, err := id3v2.Open(path, id3v2.Options{Parse: true})
+			if err != nil {
+				t.Fatalf("Open failed: %v", err)
+			}
+			defer func() { _ = tag.Close() }()
+
+			if got := tag.GetTextFrame("TPE1").Text; got != tt.wantTPE1 {
+				t.Errorf("TPE1 = %q, want %q", got, tt.wantTPE1)
+			}
+			var txxx string
+			for _, f := range tag.GetFrames("TXXX") {
+				if udf, ok := f.(id3v2.UserDefinedTextFrame); ok && udf.Description == "ARTISTS" {
+					txxx = udf.Value
+				}
+			}
+			if want := strings.Join(tt.wantArtists, "\x00"); txxx != want {
+				t.Errorf("TXXX:ARTISTS = %q, want %q", txxx, want)
+			}
+		})
+	}
+}
+
+func TestReadTags(t *testing.T) {
+	recordingID := "rec-mbid"
+	track := &domain.Track{
+		Title: "Song", Artist: "Artist", Artists: []string{"Artist", "Guest"}, Album: "Album",
+		AlbumArtist: "Artist", TrackNumber: 3, TotalTracks: 12, DiscNumber: 1, TotalDiscs: 2,
+		Year: 2020, ReleaseDate: "2020-05-01", Genre: "rock", ISRC: "USRC17607839", Label: "Label",
+		Composer: "Writer", RecordingID: &recordingID, MBAlbumID: "album-mbid", ReleaseID: "group-mbid",
+		ArtistIDs: []string{"a1", "a2"},
+	}
+	check := func(t *testing.T, got *domain.Track) {
+		t.Helper()
+		if got.Title != "Song" || got.Artist != "Artist" || !slices.Equal(got.Artists, []string{"Artist", "Guest"}) {
+			t.Errorf("title/artists = %q %q %v", got.Title, got.Artist, got.Artists)
+		}
+		if got.Album != "Album" || got.AlbumArtist != "Artist" || got.PathArtist != "Artist" {
+			t.Errorf("album = %q by %q (path artist %q)", got.Album, got.AlbumArtist, got.PathArtist)
+		}
+		if got.TrackNumber != 3 || got.TotalTracks != 12 || got.DiscNumber != 1 || got.TotalDiscs != 2 {
+			t.Errorf("position = %d/%d disc %d/%d, want 3/12 disc 1/2", got.TrackNumber, got.TotalTracks, got.DiscNumber, got.TotalDiscs)
+		}
+		if got.Year != 2020 || got.Genre != "rock" || got.ISRC != "USRC17607839" || got.Label != "Label" || got.Composer != "Writer" {
+			t.Errorf("year %d genre %q isrc %q label %q composer %q", got.Year, got.Genre, got.ISRC, got.Label, got.Composer)
+		}
+		if got.RecordingID == nil || *got.RecordingID != recordingID || got.MBAlbumID != "album-mbid" || got.ReleaseID != "group-mbid" {
+			t.Errorf("MusicBrainz IDs = %v %q %q", got.RecordingID, got.MBAlbumID, got.ReleaseID)
+		}
+		if !slices.Equal(got.ArtistIDs, []string{"a1", "a2"}) {
+			t.Errorf("ArtistIDs = %v, want [a1 a2]", got.ArtistIDs)
+		}
+	}
+
+	t.Run("flac", func(t *testing.T) {
+		path := writeTestFLAC(t)
+		if err := TagFile(path, track, TagOptions{}); err != nil {
+			t.Fatalf("TagFile failed: %v", err)
+		}
+		got, err := ReadTags(path)
+		if err != nil {
+			t.Fatalf("ReadTags failed: %v", err)
+		}
+		check(t, got)
+		if got.ReleaseDate != "2020-05-01" {
+			t.Errorf("ReleaseDate = %q, want 2020-05-01", got.ReleaseDate)
+		}
+	})
+
+	for _, v23 := range []bool{false, true} {
+		t.Run(fmt.Sprintf("mp3 v2.3=%v", v23), func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "track.mp3")
+			if err := os.WriteFile(path, []byte{}, 0o600); err != nil {
+				t.Fatalf("WriteFile failed: %v", err)
+			}
+			if err := TagFile(path, track, TagOptions{ID3v23: v23}); err != nil {
+				t.Fatalf("TagFile failed: %v", err)
+			}
+			got, err := ReadTags(path)
+			if err != nil {
+				t.Fatalf("ReadTags failed: %v", err)
+			}
+			if v23 {
+				// ID3v2.3 joins artists with "/", which can't be told apart from a name
+				// such as AC/DC, so they are read back as one.
+				if got.Artist != "Artist/Guest" || !slices.Equal(got.Artists, []string{"Artist/Guest"}) {
+					t.Errorf("artists = %q %v, want one joined value", got.Artist, got.Artists)
+				}
+				got.Artist, got.Artists = "Artist", []string{"Artist", "Guest"}
+			}
+			check(t, got)
+		})
+	}
+
+	t.Run("picard flac", func(t *testing.T) {
+		path := writeTestFLAC(t,
+			"title=Song", "ARTIST=Artist", "ALBUM=Album", "TRACKNUMBER=3/12", "DISCNUMBER=2",
+			"TOTALDISCS=2", "DATE=1999", "ORGANIZATION=Label",
+			"MUSICBRAINZ_ARTISTID=a1", "MUSICBRAINZ_ARTISTID=a2")
+		got, err := ReadTags(path)
+		if err != nil {
+			t.Fatalf("ReadTags failed: %v", err)
+		}
+		if got.Title != "Song" || got.TrackNumber != 3 || got.TotalTracks != 12 || got.DiscNumber != 2 || got.TotalDiscs != 2 {
+			t.Errorf("title %q position %d/%d disc %d/%d", got.Title, got.TrackNumber, got.TotalTracks, got.DiscNumber, got.TotalDiscs)
+		}
+		if got.Year != 1999 || got.ReleaseDate != "" || got.Label != "Label" || got.PathArtist != "Artist" {
+			t.Errorf("year %d release date %q label %q path artist %q", got.Year, got.ReleaseDate, got.Label, got.PathArtist)
+		}
+		if !slices.Equal(got.ArtistIDs, []string{"a1", "a2"}) {
+			t.Errorf("ArtistIDs = %v, want [a1 a2]", got.ArtistIDs)
+		}
+	})
+
+	if _, err := ReadTags(filepath.Join(t.TempDir(), "track.ogg")); err != ErrUnsupportedFormat {
+		t.Errorf("ReadTags(.ogg) error = %v, want ErrUnsupportedFormat", err)
+	}
+}
+
+func TestReadTags_ID3Dates(t *testing.T) {
+	tests := []struct {
+		name         string
+		frames       map[string]string
+		wantYear     int
+		wantDate     string
+		wantOriginal string
+	}{
+		{"TDRC over TYER", map[string]string{"TDRC": "2011", "TYER": "1999", "TDAT": "2609", "TDOR": "2001", "TORY": "1990"}, 2011, "", "2001"},
+		{"TYER with TDAT", map[string]string{"TYER": "1999", "TDAT": "2609", "TORY": "1990"}, 1999, "1999-09-26", "1990"},
+		{"TYER alone", map[string]string{"TYER": "1999"}, 1999, "", ""},
+		{"malformed TDAT", map[string]string{"TYER": "1999", "TDAT": "99"}, 1999, "", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "track.mp3")
+			if err := os.WriteFile(path, []byte{}, 0o600); err != nil {
+				t.Fatalf("WriteFile failed: %v", err)
+			}
+			tag, err := id3v2.Open(path, id3v2.Options{Parse: true})
+			if err != nil {
+				t.Fatalf("Open failed: %v", err)
+			}
+			for id, value := range tt.frames {
+				tag.AddTextFrame(id, id3v2.EncodingUTF8, value)
+			}
+			if err := tag.Save(); err != nil {
+				t.Fatalf("Save failed: %v", err)
+			}
+			_ = tag.Close()
+
+			// The frames are kept in a map, so read a few times to catch an unstable order.
+			for i := 0; i < 10; i++ {
+				got, err := ReadTags(path)
+				if err != nil {
+					t.Fatalf("ReadTags failed: %v", err)
+				}
+				if got.Year != tt.wantYear || got.ReleaseDate != tt.wantDate || got.OriginalDate != tt.wantOriginal {
+					t.Fatalf("year %d release date %q original date %q, want %d %q %q",
+						got.Year, got.ReleaseDate, got.OriginalDate, tt.wantYear, tt.wantDate, tt.wantOriginal)
+				}
+			}
+		})
+	}
+}
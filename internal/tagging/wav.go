@@ -0,0 +1,72 @@
+package tagging
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"strings"
+)
+
+// ── WAV Strategy ─────────────────────────────────────────────────────────────
+
+// WAVTagger writes a RIFF LIST/INFO chunk with the core fields, which every player
+// understands, plus an id3 chunk carrying the full tag set for players that read it.
+type WAVTagger struct{}
+
+func (t *WAVTagger) WriteTags(filePath string, tags *TagMap) error {
+	id3Data, err := id3Chunk(tags)
+	if err != nil {
+		return err
+	}
+
+	return rewriteIFF(filePath, "RIFF", binary.LittleEndian, func(f *iffFile) error {
+		if f.form != "WAVE" {
+			return fmt.Errorf("not a WAVE file: %s", f.form)
+		}
+		add := []iffChunk{{id: "id3 ", data: id3Data}}
+		if info := riffInfo(tags); info != nil {
+			add = append([]iffChunk{{id: "LIST", data: info}}, add...)
+		}
+		f.replaceChunks(func(c iffChunk) bool {
+			switch c.id {
+			case "id3 ", "ID3 ":
+				return true
+			case "LIST":
+				return bytes.HasPrefix(c.data, []byte("INFO"))
+			}
+			return false
+		}, add...)
+		return nil
+	})
+}
+
+// riffInfo builds the body of a LIST chunk of type INFO, or nil when there is nothing
+// to write. INFO values are NUL-terminated strings.
+func riffInfo(tags *TagMap) []byte {
+	fields := []struct{ id, value string }{
+		{"INAM", tags.Title},
+		{"IART", strings.Join(tags.Artists, "; ")},
+		{"IPRD", tags.Album},
+		{"IGNR", tags.Genre},
+		{"ICOP", tags.Copyright},
+	}
+	if tags.Year > 0 {
+		fields = append(fields, struct{ id, value string }{"ICRD", fmt.Sprintf("%d", tags.Year)})
+	}
+	if tags.TrackNum > 0 {
+		fields = append(fields, struct{ id, value string }{"ITRK", fmt.Sprintf("%d", tags.TrackNum)})
+	}
+
+	var buf bytes.Buffer
+	buf.WriteString("INFO")
+	for _, field := range fields {
+		if field.value == "" {
+			continue
+		}
+		writeIFFChunk(&buf, binary.LittleEndian, field.id, append([]byte(field.value), 0))
+	}
+	if buf.Len() == 4 {
+		return nil
+	}
+	return buf.Bytes()
+}
@@ -1,3 +1,39 @@
+// ─── CSRF ────────────────────────────────────────────────────────────────
+// Mutating requests must echo the navidrums_csrf cookie; add it to HTMX requests,
+// fetch calls and plain form posts.
+function csrfToken() {
+  const m = document.cookie.match(/(?:^|; )navidrums_csrf=([^;]*)/);
+  return m ? decodeURIComponent(m[1]) : '';
+}
+
+document.addEventListener('htmx:configRequest', (e) => {
+  e.detail.headers['X-CSRF-Token'] = csrfToken();
+});
+
+const nativeFetch = window.fetch;
+window.fetch = function (input, init) {
+  const opts = init || {};
+  const method = (opts.method || 'GET').toUpperCase();
+  if (method === 'GET' || method === 'HEAD') {
+    return nativeFetch(input, init);
+  }
+  const headers = new Headers(opts.headers || {});
+  headers.set('X-CSRF-Token', csrfToken());
+  return nativeFetch(input, { ...opts, headers });
+};
+
+document.addEventListener('submit', (e) => {
+  const form = e.target;
+  if (form.method.toLowerCase() !== 'post' || form.querySelector('input[name="csrf_token"]')) {
+    return;
+  }
+  const input = document.createElement('input');
+  input.type = 'hidden';
+  input.name = 'csrf_token';
+  input.value = csrfToken();
+  form.appendChild(input);
+}, true);
+
 function handleDownload(btn) {
   const originalText = btn.innerText;
   btn.disabled = true;